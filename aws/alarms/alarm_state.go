@@ -0,0 +1,43 @@
+package alarms
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/coinbase/odin/aws"
+)
+
+// States returns the state value of each named alarm, e.g. OK | ALARM | INSUFFICIENT_DATA
+func States(cwc aws.CWAPI, names []*string) (map[string]string, error) {
+	states := map[string]string{}
+	if len(names) == 0 {
+		return states, nil
+	}
+
+	output, err := cwc.DescribeAlarms(&cloudwatch.DescribeAlarmsInput{
+		AlarmNames: names,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, alarm := range output.MetricAlarms {
+		if alarm.AlarmName == nil || alarm.StateValue == nil {
+			continue
+		}
+		states[*alarm.AlarmName] = *alarm.StateValue
+	}
+
+	for _, name := range names {
+		if name == nil {
+			continue
+		}
+
+		if _, ok := states[*name]; !ok {
+			return nil, fmt.Errorf("Alarm '%v': not found", *name)
+		}
+	}
+
+	return states, nil
+}
@@ -0,0 +1,128 @@
+package alb
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////
+// Listener Rules
+//////
+
+// FindRule returns the rule of the listener with the priority, nil if the listener has no such rule
+func FindRule(albc aws.ALBAPI, listenerArn *string, priority *string) (*elbv2.Rule, error) {
+	input := &elbv2.DescribeRulesInput{ListenerArn: listenerArn}
+	for {
+		output, err := albc.DescribeRules(input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rule := range output.Rules {
+			if to.Strs(rule.Priority) == to.Strs(priority) {
+				return rule, nil
+			}
+		}
+
+		if output.NextMarker == nil {
+			return nil, nil
+		}
+
+		input.Marker = output.NextMarker
+	}
+}
+
+// Rules returns every rule of the listener, including its default rule
+func Rules(albc aws.ALBAPI, listenerArn *string) ([]*elbv2.Rule, error) {
+	rules := []*elbv2.Rule{}
+	input := &elbv2.DescribeRulesInput{ListenerArn: listenerArn}
+	for {
+		output, err := albc.DescribeRules(input)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, output.Rules...)
+
+		if output.NextMarker == nil {
+			return rules, nil
+		}
+
+		input.Marker = output.NextMarker
+	}
+}
+
+// RuleActions returns the current actions of the rule
+func RuleActions(albc aws.ALBAPI, ruleArn *string) ([]*elbv2.Action, error) {
+	output, err := albc.DescribeRules(&elbv2.DescribeRulesInput{RuleArns: []*string{ruleArn}})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output.Rules) != 1 {
+		return nil, fmt.Errorf("Listener rule %v not found", to.Strs(ruleArn))
+	}
+
+	return output.Rules[0].Actions, nil
+}
+
+// ForwardTargetGroups returns the target groups the forward action of the actions forwards to
+func ForwardTargetGroups(actions []*elbv2.Action) []*string {
+	arns := []*string{}
+	for _, action := range actions {
+		if action == nil || to.Strs(action.Type) != elbv2.ActionTypeEnumForward {
+			continue
+		}
+
+		if action.TargetGroupArn != nil {
+			arns = append(arns, action.TargetGroupArn)
+			continue
+		}
+
+		if action.ForwardConfig != nil {
+			for _, tg := range action.ForwardConfig.TargetGroups {
+				if tg != nil && tg.TargetGroupArn != nil {
+					arns = append(arns, tg.TargetGroupArn)
+				}
+			}
+		}
+	}
+
+	return arns
+}
+
+// ForwardTo returns the actions with the forward action only forwarding to the target group
+func ForwardTo(actions []*elbv2.Action, targetGroupArn *string) []*elbv2.Action {
+	forward := []*elbv2.Action{}
+	for _, action := range actions {
+		if action == nil {
+			continue
+		}
+
+		if to.Strs(action.Type) != elbv2.ActionTypeEnumForward {
+			forward = append(forward, action)
+			continue
+		}
+
+		forward = append(forward, &elbv2.Action{
+			Type:           action.Type,
+			Order:          action.Order,
+			TargetGroupArn: targetGroupArn,
+		})
+	}
+
+	return forward
+}
+
+// SetRuleActions replaces the actions of the rule
+func SetRuleActions(albc aws.ALBAPI, ruleArn *string, actions []*elbv2.Action) error {
+	_, err := albc.ModifyRule(&elbv2.ModifyRuleInput{
+		RuleArn: ruleArn,
+		Actions: actions,
+	})
+
+	return err
+}
@@ -0,0 +1,79 @@
+package alb
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FindRule(t *testing.T) {
+	albc := &mocks.ALBClient{}
+	albc.AddListenerRule("listener", "10", "api-blue")
+	arn := albc.AddListenerRule("listener", "20", "web-blue")
+
+	// The rules are paged through
+	rule, err := FindRule(albc, to.Strp("listener"), to.Strp("20"))
+	assert.NoError(t, err)
+	assert.Equal(t, arn, *rule.RuleArn)
+	assert.Equal(t, []*string{to.Strp("web-blue")}, ForwardTargetGroups(rule.Actions))
+
+	rule, err = FindRule(albc, to.Strp("listener"), to.Strp("30"))
+	assert.NoError(t, err)
+	assert.Nil(t, rule)
+
+	_, err = FindRule(albc, to.Strp("missing"), to.Strp("10"))
+	assert.Error(t, err)
+}
+
+func Test_Rules(t *testing.T) {
+	albc := &mocks.ALBClient{}
+	albc.FillListener("listener", 3, "api-blue")
+
+	// The rules are paged through
+	rules, err := Rules(albc, to.Strp("listener"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(rules))
+	assert.Equal(t, "1002", *rules[2].Priority)
+
+	_, err = Rules(albc, to.Strp("missing"))
+	assert.Error(t, err)
+}
+
+func Test_SetRuleActions(t *testing.T) {
+	albc := &mocks.ALBClient{}
+	arn := to.Strp(albc.AddListenerRule("listener", "10", "web-blue"))
+
+	actions, err := RuleActions(albc, arn)
+	assert.NoError(t, err)
+
+	assert.NoError(t, SetRuleActions(albc, arn, ForwardTo(actions, to.Strp("web-green"))))
+
+	actions, err = RuleActions(albc, arn)
+	assert.NoError(t, err)
+	assert.Equal(t, []*string{to.Strp("web-green")}, ForwardTargetGroups(actions))
+
+	_, err = RuleActions(albc, to.Strp("missing"))
+	assert.Error(t, err)
+}
+
+func Test_ForwardTo(t *testing.T) {
+	actions := []*elbv2.Action{
+		&elbv2.Action{Type: to.Strp(elbv2.ActionTypeEnumAuthenticateOidc), Order: to.Int64p(1)},
+		&elbv2.Action{Type: to.Strp(elbv2.ActionTypeEnumForward), Order: to.Int64p(2), ForwardConfig: &elbv2.ForwardActionConfig{
+			TargetGroups: []*elbv2.TargetGroupTuple{
+				&elbv2.TargetGroupTuple{TargetGroupArn: to.Strp("web-blue"), Weight: to.Int64p(90)},
+				&elbv2.TargetGroupTuple{TargetGroupArn: to.Strp("web-green"), Weight: to.Int64p(10)},
+			},
+		}},
+	}
+	assert.Equal(t, []*string{to.Strp("web-blue"), to.Strp("web-green")}, ForwardTargetGroups(actions))
+
+	// Only the forward action is replaced
+	forward := ForwardTo(actions, to.Strp("web-green"))
+	assert.Equal(t, actions[0], forward[0])
+	assert.Equal(t, int64(2), *forward[1].Order)
+	assert.Equal(t, []*string{to.Strp("web-green")}, ForwardTargetGroups(forward))
+}
@@ -11,13 +11,30 @@ import (
 
 // TargetGroup struct
 type TargetGroup struct {
-	ProjectNameTag    *string
-	ConfigNameTag     *string
-	ServiceNameTag    *string
-	AllowedServiceTag *string
-	TargetGroupArn    *string
-	TargetGroupName   *string
-	SlowStartDuration int
+	ProjectNameTag      *string
+	ConfigNameTag       *string
+	ServiceNameTag      *string
+	AllowedServiceTag   *string
+	TargetGroupArn      *string
+	TargetGroupName     *string
+	SlowStartDuration   int
+	DeregistrationDelay int
+
+	// Protocol is the protocol of the traffic to the targets e.g. HTTP or TCP for an NLB
+	Protocol *string
+
+	// Security groups of the load balancers forwarding to the target group
+	Port                       *int64
+	LoadBalancerSecurityGroups []*string
+
+	HealthCheckProtocol        *string
+	HealthCheckPort            *string
+	HealthCheckPath            *string
+	HealthCheckIntervalSeconds *int64
+	HealthCheckTimeoutSeconds  *int64
+	HealthyThresholdCount      *int64
+	UnhealthyThresholdCount    *int64
+	Matcher                    *string
 }
 
 // ProjectName returns tag
@@ -71,6 +88,33 @@ func GetInstances(albc aws.ALBAPI, arn *string, instances []string) (aws.Instanc
 	return tgInstances, nil
 }
 
+// DrainingInstances returns the instances still draining connections from the target group after being deregistered
+func DrainingInstances(albc aws.ALBAPI, arn *string, instances []string) ([]string, error) {
+	healthOutput, err := albc.DescribeTargetHealth(createDescribeTargetHealthInput(arn, instances))
+
+	if err != nil {
+		return nil, err
+	}
+
+	asked := map[string]bool{}
+	for _, id := range instances {
+		asked[id] = true
+	}
+
+	draining := []string{}
+	for _, thd := range healthOutput.TargetHealthDescriptions {
+		if thd.Target == nil || thd.Target.Id == nil || thd.TargetHealth == nil || !asked[*thd.Target.Id] {
+			continue
+		}
+
+		if to.Strs(thd.TargetHealth.State) == elbv2.TargetHealthStateEnumDraining {
+			draining = append(draining, *thd.Target.Id)
+		}
+	}
+
+	return draining, nil
+}
+
 func createDescribeTargetHealthInput(arn *string, instances []string) *elbv2.DescribeTargetHealthInput {
 	awsInstances := []*elbv2.TargetDescription{}
 	for _, id := range instances {
@@ -83,6 +127,50 @@ func createDescribeTargetHealthInput(arn *string, instances []string) *elbv2.Des
 	}
 }
 
+//////
+// Health Check
+//////
+
+// FindHealthCheck returns the target group with only its health check values
+func FindHealthCheck(albc aws.ALBAPI, arn *string) (*TargetGroup, error) {
+	output, err := albc.DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{
+		TargetGroupArns: []*string{arn},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output.TargetGroups) != 1 {
+		return nil, fmt.Errorf("TargetGroup Not Found")
+	}
+
+	awsTarget := output.TargetGroups[0]
+
+	tg := &TargetGroup{
+		TargetGroupArn:  awsTarget.TargetGroupArn,
+		TargetGroupName: awsTarget.TargetGroupName,
+		Protocol:        awsTarget.Protocol,
+	}
+	tg.setHealthCheck(awsTarget)
+
+	return tg, nil
+}
+
+func (tg *TargetGroup) setHealthCheck(awsTarget *elbv2.TargetGroup) {
+	tg.HealthCheckProtocol = awsTarget.HealthCheckProtocol
+	tg.HealthCheckPort = awsTarget.HealthCheckPort
+	tg.HealthCheckPath = awsTarget.HealthCheckPath
+	tg.HealthCheckIntervalSeconds = awsTarget.HealthCheckIntervalSeconds
+	tg.HealthCheckTimeoutSeconds = awsTarget.HealthCheckTimeoutSeconds
+	tg.HealthyThresholdCount = awsTarget.HealthyThresholdCount
+	tg.UnhealthyThresholdCount = awsTarget.UnhealthyThresholdCount
+
+	if awsTarget.Matcher != nil {
+		tg.Matcher = awsTarget.Matcher.HttpCode
+	}
+}
+
 //////
 // Find
 //////
@@ -112,17 +200,86 @@ func find(alb aws.ALBAPI, targetGroupName *string) (*TargetGroup, error) {
 		return nil, err
 	}
 
-	slowStartDuration := findSlowStartDuration(alb, awsTarget.TargetGroupArn)
+	attributes := findAttributes(alb, awsTarget.TargetGroupArn)
+
+	tg := &TargetGroup{
+		ProjectNameTag:      aws.FetchELBV2Tag(awsTags, to.Strp("ProjectName")),
+		ConfigNameTag:       aws.FetchELBV2Tag(awsTags, to.Strp("ConfigName")),
+		ServiceNameTag:      aws.FetchELBV2Tag(awsTags, to.Strp("ServiceName")),
+		AllowedServiceTag:   aws.FetchELBV2Tag(awsTags, to.Strp("AllowedService")),
+		TargetGroupArn:      awsTarget.TargetGroupArn,
+		TargetGroupName:     targetGroupName,
+		SlowStartDuration:   intAttribute(attributes, "slow_start.duration_seconds"),
+		DeregistrationDelay: intAttribute(attributes, "deregistration_delay.timeout_seconds"),
+		Port:                awsTarget.Port,
+		Protocol:            awsTarget.Protocol,
+	}
+	tg.setHealthCheck(awsTarget)
 
-	return &TargetGroup{
-		ProjectNameTag:    aws.FetchELBV2Tag(awsTags, to.Strp("ProjectName")),
-		ConfigNameTag:     aws.FetchELBV2Tag(awsTags, to.Strp("ConfigName")),
-		ServiceNameTag:    aws.FetchELBV2Tag(awsTags, to.Strp("ServiceName")),
-		AllowedServiceTag: aws.FetchELBV2Tag(awsTags, to.Strp("AllowedService")),
-		TargetGroupArn:    awsTarget.TargetGroupArn,
-		TargetGroupName:   targetGroupName,
-		SlowStartDuration: slowStartDuration,
-	}, nil
+	sgs, err := findLoadBalancerSecurityGroups(alb, awsTarget.LoadBalancerArns)
+	if err != nil {
+		return nil, err
+	}
+	tg.LoadBalancerSecurityGroups = sgs
+
+	return tg, nil
+}
+
+// networkProtocols are the target group protocols of Network Load Balancers
+var networkProtocols = []string{
+	elbv2.ProtocolEnumTcp,
+	elbv2.ProtocolEnumTls,
+	elbv2.ProtocolEnumUdp,
+	elbv2.ProtocolEnumTcpUdp,
+}
+
+// IsNetwork returns true if the target group is forwarded to by a Network Load Balancer
+func (tg *TargetGroup) IsNetwork() bool {
+	for _, p := range networkProtocols {
+		if to.Strs(tg.Protocol) == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HealthCheckPortNumber returns the port instances are health checked on, false if it is unknown
+func (tg *TargetGroup) HealthCheckPortNumber() (int64, bool) {
+	if tg.HealthCheckPort == nil || *tg.HealthCheckPort == "traffic-port" {
+		if tg.Port == nil {
+			return 0, false
+		}
+		return *tg.Port, true
+	}
+
+	port, err := strconv.ParseInt(*tg.HealthCheckPort, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return port, true
+}
+
+func findLoadBalancerSecurityGroups(alb aws.ALBAPI, arns []*string) ([]*string, error) {
+	if len(arns) == 0 {
+		return nil, nil
+	}
+
+	output, err := alb.DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{
+		LoadBalancerArns: arns,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sgs := []*string{}
+	for _, lb := range output.LoadBalancers {
+		sgs = append(sgs, lb.SecurityGroups...)
+	}
+
+	return sgs, nil
 }
 
 func findByName(alb aws.ALBAPI, targetGroupName *string) (*elbv2.TargetGroup, error) {
@@ -165,23 +322,26 @@ func findTagsByName(alb aws.ALBAPI, targetGroupARN *string) ([]*elbv2.Tag, error
 	return tagsOutput.TagDescriptions[0].Tags, nil
 }
 
-func findSlowStartDuration(alb aws.ALBAPI, targetGroupARN *string) int {
+func findAttributes(alb aws.ALBAPI, targetGroupARN *string) map[string]string {
+	attributes := map[string]string{}
 	output, err := alb.DescribeTargetGroupAttributes(&elbv2.DescribeTargetGroupAttributesInput{TargetGroupArn: targetGroupARN})
 	if err != nil {
-		return 0
+		return attributes
 	}
 	for _, attribute := range output.Attributes {
 		if attribute.Key == nil || attribute.Value == nil {
 			continue
 		}
 
-		if *attribute.Key == "slow_start.duration_seconds" {
-			duration, err := strconv.Atoi(*attribute.Value)
-			if err != nil {
-				continue
-			}
-			return duration
-		}
+		attributes[*attribute.Key] = *attribute.Value
+	}
+	return attributes
+}
+
+func intAttribute(attributes map[string]string, key string) int {
+	value, err := strconv.Atoi(attributes[key])
+	if err != nil {
+		return 0
 	}
-	return 0
+	return value
 }
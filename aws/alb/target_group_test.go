@@ -4,6 +4,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/coinbase/odin/aws/mocks"
 	"github.com/coinbase/step/utils/to"
 	"github.com/stretchr/testify/assert"
@@ -88,3 +89,84 @@ func Test_createDescribeTargetHealthInput(t *testing.T) {
 	assert.Equal(t, tgsIDs[0], "a")
 	assert.Equal(t, tgsIDs[1], "b")
 }
+
+func Test_FindHealthCheck(t *testing.T) {
+	albc := &mocks.ALBClient{}
+	albc.AddTargetGroup(mocks.MockTargetGroup{})
+
+	awsTarget := albc.DescribeTargetGroupsResp["tg_name"].Resp.TargetGroups[0]
+	awsTarget.HealthCheckPath = to.Strp("/health")
+	awsTarget.HealthCheckIntervalSeconds = to.Int64p(10)
+	awsTarget.HealthyThresholdCount = to.Int64p(2)
+	awsTarget.Matcher = &elbv2.Matcher{HttpCode: to.Strp("200-299")}
+
+	tg, err := FindHealthCheck(albc, to.Strp("tg_name"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/health", to.Strs(tg.HealthCheckPath))
+	assert.Equal(t, int64(10), *tg.HealthCheckIntervalSeconds)
+	assert.Equal(t, int64(2), *tg.HealthyThresholdCount)
+	assert.Equal(t, "200-299", to.Strs(tg.Matcher))
+	assert.Nil(t, tg.HealthCheckTimeoutSeconds)
+}
+
+func Test_TargetGroup_HealthCheckPortNumber(t *testing.T) {
+	tg := &TargetGroup{}
+	_, ok := tg.HealthCheckPortNumber()
+	assert.False(t, ok)
+
+	tg.Port = to.Int64p(8080)
+	tg.HealthCheckPort = to.Strp("traffic-port")
+	port, ok := tg.HealthCheckPortNumber()
+	assert.True(t, ok)
+	assert.Equal(t, int64(8080), port)
+
+	tg.HealthCheckPort = to.Strp("9000")
+	port, _ = tg.HealthCheckPortNumber()
+	assert.Equal(t, int64(9000), port)
+}
+
+func Test_DrainingInstances(t *testing.T) {
+	albc := &mocks.ALBClient{}
+	albc.AddTargetGroup(mocks.MockTargetGroup{})
+
+	draining, err := DrainingInstances(albc, to.Strp("tg_name"), []string{"InstanceId1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(draining))
+
+	albc.SetTargetHealth("tg_name", "InstanceId1", "draining")
+	albc.SetTargetHealth("tg_name", "InstanceId2", "draining")
+	draining, err = DrainingInstances(albc, to.Strp("tg_name"), []string{"InstanceId1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"InstanceId1"}, draining)
+
+	// The mock finishes draining after the polls
+	albc.DrainingPolls = map[string]int{"tg_name": 1}
+	draining, err = DrainingInstances(albc, to.Strp("tg_name"), []string{"InstanceId1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(draining))
+
+	draining, err = DrainingInstances(albc, to.Strp("tg_name"), []string{"InstanceId1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(draining))
+
+	_, err = DrainingInstances(albc, to.Strp("missing"), []string{"InstanceId1"})
+	assert.Error(t, err)
+}
+
+func Test_TargetGroup_IsNetwork(t *testing.T) {
+	assert.False(t, (&TargetGroup{}).IsNetwork())
+	assert.False(t, (&TargetGroup{Protocol: to.Strp("HTTPS")}).IsNetwork())
+	assert.True(t, (&TargetGroup{Protocol: to.Strp("TCP")}).IsNetwork())
+	assert.True(t, (&TargetGroup{Protocol: to.Strp("TLS")}).IsNetwork())
+
+	albc := &mocks.ALBClient{}
+	albc.AddTargetGroup(mocks.MockTargetGroup{Name: "tcp", Protocol: "TCP"})
+	tgs, err := FindAll(albc, []*string{to.Strp("tcp")})
+	assert.NoError(t, err)
+	assert.True(t, tgs[0].IsNetwork())
+	assert.Equal(t, "TCP", *tgs[0].HealthCheckProtocol)
+
+	tg, err := FindHealthCheck(albc, to.Strp("tcp"))
+	assert.NoError(t, err)
+	assert.True(t, tg.IsNetwork())
+}
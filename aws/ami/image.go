@@ -12,6 +12,12 @@ import (
 type Image struct {
 	ImageID       *string
 	DeployWithTag *string
+
+	Architecture       *string
+	VirtualizationType *string
+
+	// EnaSupport is true if the image has the drivers for ENA enhanced networking
+	EnaSupport bool
 }
 
 func isID(name string) bool {
@@ -64,8 +70,11 @@ func find(ec2c aws.EC2API, in *ec2.DescribeImagesInput) (*Image, error) {
 			return nil, fmt.Errorf("AMI Image nil")
 		}
 		return &Image{
-			im.ImageId,
-			aws.FetchEc2Tag(im.Tags, to.Strp("DeployWith")),
+			ImageID:            im.ImageId,
+			DeployWithTag:      aws.FetchEc2Tag(im.Tags, to.Strp("DeployWith")),
+			Architecture:       im.Architecture,
+			VirtualizationType: im.VirtualizationType,
+			EnaSupport:         im.EnaSupport != nil && *im.EnaSupport,
 		}, nil
 	default:
 		return nil, fmt.Errorf("Must be exactly 1 Image with tag Name, there are %v", len(output.Images))
@@ -21,6 +21,12 @@ func Test_Find_ID(t *testing.T) {
 	img, err := Find(ec2c, to.Strp("ami-000000"))
 	assert.NoError(t, err)
 	assert.Equal(t, "ami-000000", *img.ImageID)
+	assert.True(t, img.EnaSupport)
+
+	ec2c.SetImageEnaSupport("ami-000000", false)
+	img, err = Find(ec2c, to.Strp("ami-000000"))
+	assert.NoError(t, err)
+	assert.False(t, img.EnaSupport)
 }
 
 func Test_Find_Tag(t *testing.T) {
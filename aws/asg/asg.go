@@ -2,11 +2,14 @@ package asg
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/coinbase/odin/aws"
 	"github.com/coinbase/odin/aws/lc"
+	"github.com/coinbase/odin/aws/lt"
 	"github.com/coinbase/step/utils/is"
 	"github.com/coinbase/step/utils/to"
 )
@@ -25,11 +28,49 @@ type ASG struct {
 
 	AutoScalingGroupName    *string
 	LaunchConfigurationName *string
+	LaunchTemplateName      *string
+	LaunchTemplateVersion   *string
 
 	LoadBalancerNames []*string
 	TargetGroupARNs   []*string
 
+	WarmPool bool
+
 	instances []*autoscaling.Instance
+	tags      map[string]*string
+}
+
+// LifecycleStateIDs returns the IDs of the instances in the lifecycle state, e.g. Pending:Wait
+func (s *ASG) LifecycleStateIDs(state string) []string {
+	ids := []string{}
+	for _, i := range s.instances {
+		if i != nil && i.InstanceId != nil && to.Strs(i.LifecycleState) == state {
+			ids = append(ids, *i.InstanceId)
+		}
+	}
+	return ids
+}
+
+// HealthyIDs returns the IDs of the ASGs InService instances that are healthy
+func (s *ASG) HealthyIDs() []string {
+	ids := []string{}
+	for _, i := range s.instances {
+		if i != nil && i.InstanceId != nil && to.Strs(i.LifecycleState) == "InService" && to.Strs(i.HealthStatus) == "Healthy" {
+			ids = append(ids, *i.InstanceId)
+		}
+	}
+	return ids
+}
+
+// InstanceIDs returns the IDs of the ASGs instances
+func (s *ASG) InstanceIDs() []string {
+	ids := []string{}
+	for _, i := range s.instances {
+		if i != nil && i.InstanceId != nil {
+			ids = append(ids, *i.InstanceId)
+		}
+	}
+	return ids
 }
 
 // ProjectName returns tag
@@ -66,6 +107,19 @@ func (s *ASG) ServiceID() *string {
 	return s.AutoScalingGroupName
 }
 
+// Tags returns all tags on the ASG
+func (s *ASG) Tags() map[string]*string {
+	return s.tags
+}
+
+// RetainedAtTag marks the ASG of a previous release kept scaled to zero for a manual rollback, its value is when it was retained
+const RetainedAtTag = "RetainedAt"
+
+// RetainedAt returns when the ASG was retained, nil if it is not retained
+func (s *ASG) RetainedAt() *string {
+	return s.tags[RetainedAtTag]
+}
+
 //////
 // Init
 //////
@@ -80,18 +134,58 @@ func newASG(group *autoscaling.Group) *ASG {
 
 		AutoScalingGroupName:    group.AutoScalingGroupName,
 		LaunchConfigurationName: group.LaunchConfigurationName,
+		LaunchTemplateName:      launchTemplateSpec(group).LaunchTemplateName,
+		LaunchTemplateVersion:   launchTemplateSpec(group).Version,
 
 		LoadBalancerNames: group.LoadBalancerNames,
 		TargetGroupARNs:   group.TargetGroupARNs,
 
+		WarmPool: group.WarmPoolConfiguration != nil,
+
 		DesiredCapacity: group.DesiredCapacity,
 		MinSize:         group.MinSize,
 		MaxSize:         group.MaxSize,
 
 		instances: group.Instances,
+		tags:      tagMap(group.Tags),
 	}
 }
 
+// launchTemplateSpec returns the launch template of an ASG with a launch template or mixed instances policy
+func launchTemplateSpec(group *autoscaling.Group) *autoscaling.LaunchTemplateSpecification {
+	if group.LaunchTemplate != nil {
+		return group.LaunchTemplate
+	}
+
+	if group.MixedInstancesPolicy == nil || group.MixedInstancesPolicy.LaunchTemplate == nil {
+		return &autoscaling.LaunchTemplateSpecification{}
+	}
+
+	spec := group.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+	if spec == nil {
+		return &autoscaling.LaunchTemplateSpecification{}
+	}
+
+	return spec
+}
+
+// sharedLaunchTemplate is true if the ASG is pinned to a version of a launch template other releases also use
+func (s *ASG) sharedLaunchTemplate() bool {
+	version := to.Strs(s.LaunchTemplateVersion)
+	return version != "" && version != "$Latest" && version != "$Default"
+}
+
+func tagMap(tags []*autoscaling.TagDescription) map[string]*string {
+	m := map[string]*string{}
+	for _, tag := range tags {
+		if tag == nil || tag.Key == nil {
+			continue
+		}
+		m[*tag.Key] = tag.Value
+	}
+	return m
+}
+
 //////
 // Healthy
 //////
@@ -168,7 +262,7 @@ func ForProjectConfigNotReleaseIDServiceMap(asgc aws.ASGAPI, projectName *string
 
 }
 
-// ForProjectConfigNOTReleaseID returns all ASGs not with the release ID
+// ForProjectConfigNOTReleaseID returns all ASGs not with the release ID that are not retained
 func ForProjectConfigNOTReleaseID(asgc aws.ASGAPI, projectName *string, configName *string, releaseID *string) ([]*ASG, error) {
 	all, err := forProjectConfig(asgc, projectName, configName)
 	if err != nil {
@@ -177,7 +271,25 @@ func ForProjectConfigNOTReleaseID(asgc aws.ASGAPI, projectName *string, configNa
 
 	asgs := []*ASG{}
 	for _, asg := range all {
-		if !aws.HasReleaseID(asg, releaseID) {
+		// Retained ASGs are scaled to zero and no longer part of a deploy
+		if !aws.HasReleaseID(asg, releaseID) && asg.RetainedAt() == nil {
+			asgs = append(asgs, asg)
+		}
+	}
+
+	return asgs, nil
+}
+
+// ForProjectConfigRetained returns the ASGs of previous releases retained at zero
+func ForProjectConfigRetained(asgc aws.ASGAPI, projectName *string, configName *string) ([]*ASG, error) {
+	all, err := forProjectConfig(asgc, projectName, configName)
+	if err != nil {
+		return nil, err
+	}
+
+	asgs := []*ASG{}
+	for _, asg := range all {
+		if asg.RetainedAt() != nil {
 			asgs = append(asgs, asg)
 		}
 	}
@@ -202,6 +314,24 @@ func ForProjectConfigReleaseID(asgc aws.ASGAPI, projectName *string, configName
 	return asgs, nil
 }
 
+// ForProjectConfigReleaseUUID returns the ASGs tagged with the release UUID
+func ForProjectConfigReleaseUUID(asgc aws.ASGAPI, projectName *string, configName *string, releaseUUID *string) ([]*ASG, error) {
+	all, err := forProjectConfig(asgc, projectName, configName)
+	if err != nil {
+		return nil, err
+	}
+
+	asgs := []*ASG{}
+	for _, asg := range all {
+		uuid := asg.Tags()["ReleaseUUID"]
+		if uuid != nil && releaseUUID != nil && *uuid == *releaseUUID {
+			asgs = append(asgs, asg)
+		}
+	}
+
+	return asgs, nil
+}
+
 func forProjectConfig(asgc aws.ASGAPI, projectName *string, configName *string) ([]*ASG, error) {
 	all, err := findInAws(asgc, &autoscaling.DescribeAutoScalingGroupsInput{})
 	if err != nil {
@@ -244,6 +374,176 @@ func findInAws(asgc aws.ASGAPI, params *autoscaling.DescribeAutoScalingGroupsInp
 	return allGroups, nil
 }
 
+//////////
+// Update
+//////////
+
+// UpdateTags creates or updates the tags and deletes the removed tags
+// Tags are propagated to instances launched after the update
+func (s *ASG) UpdateTags(asgc aws.ASGAPI, tags map[string]*string, removed []string) error {
+	if len(removed) > 0 {
+		deleteTags := []*autoscaling.Tag{}
+		for _, key := range removed {
+			deleteTags = append(deleteTags, s.tag(key, nil))
+		}
+
+		if _, err := asgc.DeleteTags(&autoscaling.DeleteTagsInput{Tags: deleteTags}); err != nil {
+			return err
+		}
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	updateTags := []*autoscaling.Tag{}
+	for key, value := range tags {
+		updateTags = append(updateTags, s.tag(key, value))
+	}
+
+	_, err := asgc.CreateOrUpdateTags(&autoscaling.CreateOrUpdateTagsInput{Tags: updateTags})
+	return err
+}
+
+func (s *ASG) tag(key string, value *string) *autoscaling.Tag {
+	return &autoscaling.Tag{
+		Key:               to.Strp(key),
+		Value:             value,
+		PropagateAtLaunch: to.Boolp(true),
+		ResourceId:        s.AutoScalingGroupName,
+		ResourceType:      to.Strp("auto-scaling-group"),
+	}
+}
+
+// SuspendProcesses suspends the scaling processes on the ASG asgName.
+// AWS suspends every process if none are given, so no processes does nothing
+func SuspendProcesses(asgc aws.ASGAPI, asgName *string, processes []*string) error {
+	if len(processes) == 0 {
+		return nil
+	}
+
+	_, err := asgc.SuspendProcesses(&autoscaling.ScalingProcessQuery{
+		AutoScalingGroupName: asgName,
+		ScalingProcesses:     processes,
+	})
+
+	return err
+}
+
+// PutWarmPool creates a warm pool of pre-initialized instances for the ASG
+func PutWarmPool(asgc aws.ASGAPI, asgName *string, minSize *int64, poolState *string) error {
+	_, err := asgc.PutWarmPool(&autoscaling.PutWarmPoolInput{
+		AutoScalingGroupName: asgName,
+		MinSize:              minSize,
+		PoolState:            poolState,
+	})
+	return err
+}
+
+// SetLaunchConfiguration makes the ASG launch new instances with the launch configuration
+func SetLaunchConfiguration(asgc aws.ASGAPI, asgName *string, launchConfigurationName *string) error {
+	_, err := asgc.UpdateAutoScalingGroup(&autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName:    asgName,
+		LaunchConfigurationName: launchConfigurationName,
+	})
+	return err
+}
+
+// StartInstanceRefresh replaces the instances of the ASG with its current launch configuration,
+// keeping minHealthyPercentage of the ASG in service. It returns the refresh ID
+func StartInstanceRefresh(asgc aws.ASGAPI, asgName *string, minHealthyPercentage *int64) (*string, error) {
+	out, err := asgc.StartInstanceRefresh(&autoscaling.StartInstanceRefreshInput{
+		AutoScalingGroupName: asgName,
+		Strategy:             to.Strp(autoscaling.RefreshStrategyRolling),
+		Preferences: &autoscaling.RefreshPreferences{
+			MinHealthyPercentage: minHealthyPercentage,
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return out.InstanceRefreshId, nil
+}
+
+// DescribeInstanceRefresh returns the instance refresh refreshID of the ASG
+func DescribeInstanceRefresh(asgc aws.ASGAPI, asgName *string, refreshID *string) (*autoscaling.InstanceRefresh, error) {
+	out, err := asgc.DescribeInstanceRefreshes(&autoscaling.DescribeInstanceRefreshesInput{
+		AutoScalingGroupName: asgName,
+		InstanceRefreshIds:   []*string{refreshID},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, refresh := range out.InstanceRefreshes {
+		if refresh != nil && to.Strs(refresh.InstanceRefreshId) == to.Strs(refreshID) {
+			return refresh, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Instance refresh %v not found for %v", to.Strs(refreshID), to.Strs(asgName))
+}
+
+// CancelInstanceRefresh cancels the ASGs active instance refresh, there being none is not an error
+func CancelInstanceRefresh(asgc aws.ASGAPI, asgName *string) error {
+	_, err := asgc.CancelInstanceRefresh(&autoscaling.CancelInstanceRefreshInput{
+		AutoScalingGroupName: asgName,
+	})
+
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == autoscaling.ErrCodeActiveInstanceRefreshNotFoundFault {
+		return nil
+	}
+
+	return err
+}
+
+// ResumeProcesses resumes the suspended scaling processes on the ASG asgName.
+// AWS resumes every process if none are given, so no processes does nothing
+func ResumeProcesses(asgc aws.ASGAPI, asgName *string, processes []*string) error {
+	if len(processes) == 0 {
+		return nil
+	}
+
+	_, err := asgc.ResumeProcesses(&autoscaling.ScalingProcessQuery{
+		AutoScalingGroupName: asgName,
+		ScalingProcesses:     processes,
+	})
+
+	return err
+}
+
+// TeardownPolicies deletes the scaling policies and their alarms
+func (s *ASG) TeardownPolicies(asgc aws.ASGAPI, cwc aws.CWAPI) error {
+	output, err := asgc.DescribePolicies(&autoscaling.DescribePoliciesInput{AutoScalingGroupName: s.AutoScalingGroupName})
+	if err != nil {
+		return err
+	}
+
+	alarms := policyAlarmNames(output.ScalingPolicies)
+
+	if len(alarms) > 0 {
+		if err := s.teardownAlarms(cwc, alarms); err != nil {
+			return err
+		}
+	}
+
+	for _, sp := range output.ScalingPolicies {
+		_, err := asgc.DeletePolicy(&autoscaling.DeletePolicyInput{
+			AutoScalingGroupName: s.AutoScalingGroupName,
+			PolicyName:           sp.PolicyName,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 //////////
 // Destruction
 //////////
@@ -274,6 +574,34 @@ func (s *ASG) Detach(asgc aws.ASGAPI) error {
 	return nil
 }
 
+// Retain scales the ASG to zero and tags it retained rather than deleting it, it should already be detached
+func (s *ASG) Retain(asgc aws.ASGAPI, at time.Time) error {
+	// A warm pool would keep instances running
+	if s.WarmPool {
+		if err := s.deleteWarmPool(asgc); err != nil {
+			return err
+		}
+	}
+
+	if err := s.ScaleToZero(asgc); err != nil {
+		return err
+	}
+
+	return s.UpdateTags(asgc, map[string]*string{RetainedAtTag: to.Strp(at.UTC().Format(time.RFC3339))}, nil)
+}
+
+// ScaleToZero sets the min, max and desired capacity to zero so the ASG terminates its instances
+func (s *ASG) ScaleToZero(asgc aws.ASGAPI) error {
+	_, err := asgc.UpdateAutoScalingGroup(&autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: s.ServiceID(),
+		MinSize:              to.Int64p(0),
+		MaxSize:              to.Int64p(0),
+		DesiredCapacity:      to.Int64p(0),
+	})
+
+	return err
+}
+
 func (s *ASG) AttachedLBs(asgc aws.ASGAPI) ([]string, error) {
 	lbs := []string{}
 
@@ -337,8 +665,8 @@ func (s *ASG) attachedClassicLBs(asgc aws.ASGAPI) ([]string, error) {
 	return lbs, nil
 }
 
-// Teardown deletes the ASG with launch config and alarms
-func (s *ASG) Teardown(asgc aws.ASGAPI, cwc aws.CWAPI) error {
+// Teardown deletes the ASG with launch config or launch template and alarms
+func (s *ASG) Teardown(asgc aws.ASGAPI, ec2c aws.EC2API, cwc aws.CWAPI) error {
 	// Delete Alarms
 	alarms, err := s.alarmNames(asgc)
 	if err != nil {
@@ -349,11 +677,27 @@ func (s *ASG) Teardown(asgc aws.ASGAPI, cwc aws.CWAPI) error {
 		return err
 	}
 
+	// The warm pool's instances must be deleted with the group
+	if s.WarmPool {
+		if err := s.deleteWarmPool(asgc); err != nil {
+			return err
+		}
+	}
+
 	// Delete Group
 	if err := s.deleteGroup(asgc); err != nil {
 		return err
 	}
 
+	// Launch template and mixed instances ASGs have no launch config
+	// The versions of a shared launch template are pruned by the release that replaces it
+	if s.LaunchTemplateName != nil {
+		if s.sharedLaunchTemplate() {
+			return nil
+		}
+		return lt.Teardown(ec2c, s.LaunchTemplateName)
+	}
+
 	// Delete Launch Config as well
 	if err := lc.Teardown(asgc, s.LaunchConfigurationName); err != nil {
 		return err
@@ -367,14 +711,25 @@ func (s *ASG) alarmNames(asgc aws.ASGAPI) ([]*string, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	return policyAlarmNames(output.ScalingPolicies), nil
+}
+
+// policyAlarmNames returns the alarms Odin created for the policies. Target tracking
+// alarms are owned by their policy and deleted with it
+func policyAlarmNames(policies []*autoscaling.ScalingPolicy) []*string {
 	alarms := []*string{}
-	for _, sp := range output.ScalingPolicies {
+	for _, sp := range policies {
+		if sp.PolicyType != nil && *sp.PolicyType == "TargetTrackingScaling" {
+			continue
+		}
+
 		for _, alarm := range sp.Alarms {
 			alarms = append(alarms, alarm.AlarmName)
 		}
 	}
 
-	return alarms, nil
+	return alarms
 }
 
 func (s *ASG) teardownAlarms(cwc aws.CWAPI, alarms []*string) error {
@@ -382,6 +737,14 @@ func (s *ASG) teardownAlarms(cwc aws.CWAPI, alarms []*string) error {
 	return err
 }
 
+func (s *ASG) deleteWarmPool(asgc aws.ASGAPI) error {
+	_, err := asgc.DeleteWarmPool(&autoscaling.DeleteWarmPoolInput{
+		AutoScalingGroupName: s.ServiceID(),
+		ForceDelete:          to.Boolp(true),
+	})
+	return err
+}
+
 func (s *ASG) deleteGroup(asgc aws.ASGAPI) error {
 	_, err := asgc.DeleteAutoScalingGroup(&autoscaling.DeleteAutoScalingGroupInput{
 		AutoScalingGroupName: s.ServiceID(),
@@ -2,6 +2,7 @@ package asg
 
 import (
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/coinbase/odin/aws/mocks"
@@ -69,6 +70,50 @@ func Test_ForProjectConfigNOTReleaseID(t *testing.T) {
 	assert.Equal(t, 1, len(asgs))
 }
 
+func Test_ForProjectConfigRetained(t *testing.T) {
+	asgc := &mocks.ASGClient{}
+	asgc.AddPreviousRuntimeResources("project", "config", "service1", "live")
+	asgc.AddRetainedRuntimeResources("project", "config", "service1", "retained", "2026-01-01T00:00:00Z")
+	asgc.AddRetainedRuntimeResources("not_project", "config", "service1", "retained", "2026-01-01T00:00:00Z")
+
+	// Retained ASGs are not previous ASGs of a release
+	asgs, err := ForProjectConfigNOTReleaseID(asgc, to.Strp("project"), to.Strp("config"), to.Strp("release"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(asgs))
+	assert.Equal(t, "live", *asgs[0].ReleaseID())
+
+	asgs, err = ForProjectConfigRetained(asgc, to.Strp("project"), to.Strp("config"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(asgs))
+	assert.Equal(t, "retained", *asgs[0].ReleaseID())
+	assert.Equal(t, "2026-01-01T00:00:00Z", *asgs[0].RetainedAt())
+}
+
+func Test_ASG_Retain(t *testing.T) {
+	asgc := &mocks.ASGClient{}
+	name := asgc.AddPreviousRuntimeResources("project", "config", "service1", "live")
+	_, group, err := GetInstances(asgc, to.Strp(name))
+	assert.NoError(t, err)
+	assert.Nil(t, group.RetainedAt())
+
+	assert.NoError(t, group.Retain(asgc, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+	assert.Equal(t, 1, len(asgc.UpdateAutoScalingGroupInputs))
+	input := asgc.UpdateAutoScalingGroupInputs[0]
+	assert.Equal(t, name, *input.AutoScalingGroupName)
+	assert.Equal(t, int64(0), *input.MinSize)
+	assert.Equal(t, int64(0), *input.MaxSize)
+	assert.Equal(t, int64(0), *input.DesiredCapacity)
+
+	assert.Equal(t, 1, len(asgc.CreateOrUpdateTagsInputs))
+	tag := asgc.CreateOrUpdateTagsInputs[0].Tags[0]
+	assert.Equal(t, RetainedAtTag, *tag.Key)
+	assert.Equal(t, "2026-01-02T03:04:05Z", *tag.Value)
+
+	// Retained ASGs are never deleted by Retain
+	assert.Equal(t, 0, len(asgc.DeleteAutoScalingGroupInputs))
+}
+
 func Test_ForProjectConfigReleaseID(t *testing.T) {
 	// func ForProjectConfigReleaseID(asgc aws.ASGAPI, project_name *string, config_name *string, release_uuid *string) ([]*ASG, error) {
 	asgc := &mocks.ASGClient{}
@@ -88,8 +133,9 @@ func Test_ForProjectConfigReleaseID(t *testing.T) {
 }
 
 func Test_Teardown(t *testing.T) {
-	// func (s *ASG) Teardown(asgc aws.ASGAPI, cwc aws.CWAPI) error {
+	// func (s *ASG) Teardown(asgc aws.ASGAPI, ec2c aws.EC2API, cwc aws.CWAPI) error {
 	asgc := &mocks.ASGClient{}
+	ec2c := &mocks.EC2Client{}
 	cwc := &mocks.CWClient{}
 
 	asgc.AddPreviousRuntimeResources("project", "config", "service1", "not_release")
@@ -97,8 +143,60 @@ func Test_Teardown(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(asgs))
 
-	err = asgs[0].Teardown(asgc, cwc)
+	err = asgs[0].Teardown(asgc, ec2c, cwc)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(ec2c.DeleteLaunchTemplateInputs))
+
+	// Mixed instances ASGs delete their launch template
+	asgs[0].LaunchTemplateName = to.Strp("template")
+	err = asgs[0].Teardown(asgc, ec2c, cwc)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(ec2c.DeleteLaunchTemplateInputs))
+	assert.Equal(t, 0, len(asgc.DeleteWarmPoolInputs))
+
+	// ASGs with a warm pool delete it first
+	asgs[0].WarmPool = true
+	err = asgs[0].Teardown(asgc, ec2c, cwc)
 	assert.NoError(t, err)
+	assert.Equal(t, 1, len(asgc.DeleteWarmPoolInputs))
+	assert.True(t, *asgc.DeleteWarmPoolInputs[0].ForceDelete)
+
+	// ASGs pinned to a version of a shared launch template keep it
+	asgs[0].LaunchTemplateVersion = to.Strp("3")
+	err = asgs[0].Teardown(asgc, ec2c, cwc)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(ec2c.DeleteLaunchTemplateInputs))
+}
+
+func Test_TeardownPolicies_TargetTracking(t *testing.T) {
+	asgc := &mocks.ASGClient{}
+	cwc := &mocks.CWClient{}
+
+	asgc.AddPreviousRuntimeResources("project", "config", "service1", "not_release")
+	asgs, err := ForProjectConfigNOTReleaseID(asgc, to.Strp("project"), to.Strp("config"), to.Strp("release"))
+	assert.NoError(t, err)
+
+	asgc.DescribePoliciesResp[*asgs[0].AutoScalingGroupName] = &mocks.DescribePoliciesResponse{
+		Resp: &autoscaling.DescribePoliciesOutput{
+			ScalingPolicies: []*autoscaling.ScalingPolicy{
+				&autoscaling.ScalingPolicy{
+					PolicyName: to.Strp("step"),
+					Alarms:     []*autoscaling.Alarm{&autoscaling.Alarm{AlarmName: to.Strp("step-alarm")}},
+				},
+				&autoscaling.ScalingPolicy{
+					PolicyName: to.Strp("tracking"),
+					PolicyType: to.Strp("TargetTrackingScaling"),
+					Alarms:     []*autoscaling.Alarm{&autoscaling.Alarm{AlarmName: to.Strp("TargetTracking-alarm")}},
+				},
+			},
+		},
+	}
+
+	// Only the alarms Odin created are deleted
+	assert.NoError(t, asgs[0].TeardownPolicies(asgc, cwc))
+	assert.Equal(t, 1, len(cwc.DeleteAlarmsInputs))
+	assert.Equal(t, []*string{to.Strp("step-alarm")}, cwc.DeleteAlarmsInputs[0].AlarmNames)
+	assert.Equal(t, 2, len(asgc.DeletePolicyInputs))
 }
 
 func Test_AttachedLBs(t *testing.T) {
@@ -151,3 +249,21 @@ func Test_AttachedLBs(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 0, len(attached))
 }
+
+func Test_SuspendResumeProcesses(t *testing.T) {
+	asgc := &mocks.ASGClient{}
+	name := to.Strp("asg")
+
+	// No processes must not suspend every process
+	assert.NoError(t, SuspendProcesses(asgc, name, []*string{}))
+	assert.Equal(t, 0, len(asgc.SuspendedProcesses))
+
+	assert.NoError(t, SuspendProcesses(asgc, name, []*string{to.Strp("AZRebalance"), to.Strp("ReplaceUnhealthy")}))
+	assert.Equal(t, []string{"AZRebalance", "ReplaceUnhealthy"}, asgc.SuspendedProcesses["asg"])
+
+	assert.NoError(t, ResumeProcesses(asgc, name, []*string{}))
+	assert.Equal(t, []string{"AZRebalance", "ReplaceUnhealthy"}, asgc.SuspendedProcesses["asg"])
+
+	assert.NoError(t, ResumeProcesses(asgc, name, []*string{to.Strp("AZRebalance")}))
+	assert.Equal(t, []string{"ReplaceUnhealthy"}, asgc.SuspendedProcesses["asg"])
+}
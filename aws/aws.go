@@ -2,6 +2,12 @@ package aws
 
 import (
 	"fmt"
+	"net/http"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
@@ -16,12 +22,24 @@ import (
 	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/aws/aws-sdk-go/service/resourcegroups"
+	"github.com/aws/aws-sdk-go/service/resourcegroups/resourcegroupsiface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/sfn"
 	"github.com/aws/aws-sdk-go/service/sfn/sfniface"
 	"github.com/aws/aws-sdk-go/service/sns"
 	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
 	ar "github.com/coinbase/step/aws"
 )
 
@@ -178,12 +196,35 @@ type IAMAPI iamiface.IAMAPI
 // SNSAPI aws API
 type SNSAPI snsiface.SNSAPI
 
+// Route53API aws API
+type Route53API route53iface.Route53API
+
 // SFNAPI aws API
 type SFNAPI sfniface.SFNAPI
 
 // DynamoDBAPI aws API
 type DynamoDBAPI dynamodbiface.DynamoDBAPI
 
+// LambdaAPI aws API
+type LambdaAPI lambdaiface.LambdaAPI
+
+// SSMAPI aws API
+type SSMAPI ssmiface.SSMAPI
+
+// KMSAPI aws API
+type KMSAPI kmsiface.KMSAPI
+
+// STSAPI aws API
+type STSAPI stsiface.STSAPI
+
+// ResourceGroupsAPI aws API
+type ResourceGroupsAPI resourcegroupsiface.ResourceGroupsAPI
+
+// HTTPAPI sends HTTP requests, e.g. to instances
+type HTTPAPI interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
 // Clients for AWS
 type Clients interface {
 	S3Client(region *string, accountID *string, role *string) S3API
@@ -194,61 +235,151 @@ type Clients interface {
 	CWClient(region *string, accountID *string, role *string) CWAPI
 	IAMClient(region *string, accountID *string, role *string) IAMAPI
 	SNSClient(region *string, accountID *string, role *string) SNSAPI
+	Route53Client(region *string, accountID *string, role *string) Route53API
 	SFNClient(region *string, accountID *string, role *string) SFNAPI
 	DynamoDBClient(region *string, accountID *string, role *string) DynamoDBAPI
+	LambdaClient(region *string, accountID *string, role *string) LambdaAPI
+	SSMClient(region *string, accountID *string, role *string) SSMAPI
+	KMSClient(region *string, accountID *string, role *string) KMSAPI
+	STSClient(region *string, accountID *string, role *string) STSAPI
+	ResourceGroupsClient(region *string, accountID *string, role *string) ResourceGroupsAPI
+	HTTPClient() HTTPAPI
 }
 
+// Transient errors, e.g. throttling and 5xx, are retried by the SDK with exponential backoff and jitter
+const (
+	DefaultRetryMaxRetries = 4
+	DefaultRetryMinDelay   = 500 * time.Millisecond
+	DefaultRetryMaxDelay   = 20 * time.Second
+)
+
 // ClientsStr implementation
 type ClientsStr struct {
 	ar.Clients
+
+	// Retryer of every client, nil uses DefaultRetryer
+	Retryer request.Retryer
+}
+
+// DefaultRetryer retries transient errors DefaultRetryMaxRetries times, waiting at least DefaultRetryMinDelay
+func DefaultRetryer() request.Retryer {
+	return NewRetryer(DefaultRetryMaxRetries+1, DefaultRetryMinDelay)
+}
+
+// NewRetryer makes up to maxAttempts attempts of a request that fails with a transient error. The delay
+// between attempts doubles from baseDelay with jitter, up to DefaultRetryMaxDelay
+func NewRetryer(maxAttempts int, baseDelay time.Duration) request.Retryer {
+	retries := maxAttempts - 1
+	if retries < 0 {
+		retries = 0
+	}
+
+	maxDelay := DefaultRetryMaxDelay
+	if baseDelay > maxDelay {
+		maxDelay = baseDelay
+	}
+
+	return client.DefaultRetryer{
+		NumMaxRetries:    retries,
+		MinRetryDelay:    baseDelay,
+		MinThrottleDelay: baseDelay,
+		MaxRetryDelay:    maxDelay,
+		MaxThrottleDelay: maxDelay,
+	}
 }
 
 // S3Client returns client for region account and role
 func (awsc *ClientsStr) S3Client(region *string, accountID *string, role *string) S3API {
-	return s3.New(awsc.Session(), awsc.Config(region, accountID, role))
+	return s3.New(awsc.Session(), awsc.config(region, accountID, role))
 }
 
 // ASGClient returns client for region account and role
 func (awsc *ClientsStr) ASGClient(region *string, accountID *string, role *string) ASGAPI {
-	return autoscaling.New(awsc.Session(), awsc.Config(region, accountID, role))
+	return autoscaling.New(awsc.Session(), awsc.config(region, accountID, role))
 }
 
 // ELBClient returns client for region account and role
 func (awsc *ClientsStr) ELBClient(region *string, accountID *string, role *string) ELBAPI {
-	return elb.New(awsc.Session(), awsc.Config(region, accountID, role))
+	return elb.New(awsc.Session(), awsc.config(region, accountID, role))
 }
 
 // EC2Client returns client for region account and role
 func (awsc *ClientsStr) EC2Client(region *string, accountID *string, role *string) EC2API {
-	return ec2.New(awsc.Session(), awsc.Config(region, accountID, role))
+	return ec2.New(awsc.Session(), awsc.config(region, accountID, role))
 }
 
 // ALBClient returns client for region account and role
 func (awsc *ClientsStr) ALBClient(region *string, accountID *string, role *string) ALBAPI {
-	return elbv2.New(awsc.Session(), awsc.Config(region, accountID, role))
+	return elbv2.New(awsc.Session(), awsc.config(region, accountID, role))
 }
 
 // CWClient returns client for region account and role
 func (awsc *ClientsStr) CWClient(region *string, accountID *string, role *string) CWAPI {
-	return cloudwatch.New(awsc.Session(), awsc.Config(region, accountID, role))
+	return cloudwatch.New(awsc.Session(), awsc.config(region, accountID, role))
 }
 
 // IAMClient returns client for region account and role
 func (awsc *ClientsStr) IAMClient(region *string, accountID *string, role *string) IAMAPI {
-	return iam.New(awsc.Session(), awsc.Config(region, accountID, role))
+	return iam.New(awsc.Session(), awsc.config(region, accountID, role))
 }
 
 // SNSClient returns client for region account and role
 func (awsc *ClientsStr) SNSClient(region *string, accountID *string, role *string) SNSAPI {
-	return sns.New(awsc.Session(), awsc.Config(region, accountID, role))
+	return sns.New(awsc.Session(), awsc.config(region, accountID, role))
+}
+
+// Route53Client returns client for region account and role
+func (awsc *ClientsStr) Route53Client(region *string, accountID *string, role *string) Route53API {
+	return route53.New(awsc.Session(), awsc.config(region, accountID, role))
 }
 
 // SFNClient returns client for region account and role
 func (awsc *ClientsStr) SFNClient(region *string, accountID *string, role *string) SFNAPI {
-	return sfn.New(awsc.Session(), awsc.Config(region, accountID, role))
+	return sfn.New(awsc.Session(), awsc.config(region, accountID, role))
 }
 
 // DynamoDBClient returns client for region account and role
 func (awsc *ClientsStr) DynamoDBClient(region *string, account_id *string, role *string) DynamoDBAPI {
-	return dynamodb.New(awsc.Session(), awsc.Config(region, account_id, role))
+	return dynamodb.New(awsc.Session(), awsc.config(region, account_id, role))
+}
+
+// LambdaClient returns client for region account and role
+func (awsc *ClientsStr) LambdaClient(region *string, accountID *string, role *string) LambdaAPI {
+	return lambda.New(awsc.Session(), awsc.config(region, accountID, role))
+}
+
+// SSMClient returns client for region account and role
+func (awsc *ClientsStr) SSMClient(region *string, accountID *string, role *string) SSMAPI {
+	return ssm.New(awsc.Session(), awsc.config(region, accountID, role))
+}
+
+// KMSClient returns client for region account and role
+func (awsc *ClientsStr) KMSClient(region *string, accountID *string, role *string) KMSAPI {
+	return kms.New(awsc.Session(), awsc.config(region, accountID, role))
+}
+
+// STSClient returns client for region account and role
+func (awsc *ClientsStr) STSClient(region *string, accountID *string, role *string) STSAPI {
+	return sts.New(awsc.Session(), awsc.config(region, accountID, role))
+}
+
+// ResourceGroupsClient returns client for region account and role
+func (awsc *ClientsStr) ResourceGroupsClient(region *string, accountID *string, role *string) ResourceGroupsAPI {
+	return resourcegroups.New(awsc.Session(), awsc.config(region, accountID, role))
+}
+
+// config returns the config for region account and role with the Retryer. The config is copied as
+// ar.Clients caches it for each role
+func (awsc *ClientsStr) config(region *string, accountID *string, role *string) *awssdk.Config {
+	retryer := awsc.Retryer
+	if retryer == nil {
+		retryer = DefaultRetryer()
+	}
+
+	return request.WithRetryer(awsc.Config(region, accountID, role).Copy(), retryer)
+}
+
+// HTTPClient returns a client with a short timeout as instances that do not respond are not ready
+func (awsc *ClientsStr) HTTPClient() HTTPAPI {
+	return &http.Client{Timeout: 5 * time.Second}
 }
@@ -0,0 +1,136 @@
+package aws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+const describeASGsResponse = `<DescribeAutoScalingGroupsResponse>
+  <DescribeAutoScalingGroupsResult><AutoScalingGroups/></DescribeAutoScalingGroupsResult>
+</DescribeAutoScalingGroupsResponse>`
+
+func errorResponse(code string) string {
+	return `<ErrorResponse><Error><Type>Sender</Type><Code>` + code + `</Code><Message>` + code + `</Message></Error><RequestId>id</RequestId></ErrorResponse>`
+}
+
+type asgFailure struct {
+	status int
+	code   string
+}
+
+// asgServer fails the first len(failures) calls with the status and error code of each failure, then succeeds
+type asgServer struct {
+	mu       sync.Mutex
+	calls    int
+	failures []asgFailure
+}
+
+func (s *asgServer) fail(n int, status int, code string) {
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, asgFailure{status, code})
+	}
+}
+
+func (s *asgServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	call := s.calls
+	s.calls++
+
+	w.Header().Set("Content-Type", "text/xml")
+	if call < len(s.failures) {
+		w.WriteHeader(s.failures[call].status)
+		w.Write([]byte(errorResponse(s.failures[call].code)))
+		return
+	}
+
+	w.Write([]byte(describeASGsResponse))
+}
+
+// asgClient is a real SDK client of awsc sending its requests to the server
+func asgClient(awsc *ClientsStr, url string) *autoscaling.AutoScaling {
+	config := awsc.config(to.Strp("us-east-1"), nil, nil).
+		WithEndpoint(url).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", ""))
+
+	return autoscaling.New(awsc.Session(), config)
+}
+
+func Test_ClientsStr_Retryer(t *testing.T) {
+	awsc := &ClientsStr{}
+	ec2c := awsc.EC2Client(to.Strp("us-east-1"), nil, nil).(*ec2.EC2)
+	assert.Equal(t, DefaultRetryer(), ec2c.Retryer)
+	assert.Equal(t, DefaultRetryMaxRetries, ec2c.MaxRetries())
+
+	awsc.Retryer = client.DefaultRetryer{NumMaxRetries: 1}
+	ec2c = awsc.EC2Client(to.Strp("us-east-1"), nil, nil).(*ec2.EC2)
+	assert.Equal(t, 1, ec2c.MaxRetries())
+}
+
+func Test_NewRetryer(t *testing.T) {
+	retryer := NewRetryer(3, time.Second)
+	assert.Equal(t, 2, retryer.MaxRetries())
+
+	// A single attempt is never retried
+	assert.Equal(t, 0, NewRetryer(1, time.Second).MaxRetries())
+	assert.Equal(t, 0, NewRetryer(0, time.Second).MaxRetries())
+}
+
+func Test_ClientsStr_Retries_Transient_Errors(t *testing.T) {
+	for _, failure := range []asgFailure{
+		{400, "Throttling"},
+		{503, "ServiceUnavailable"},
+	} {
+		t.Run(failure.code, func(t *testing.T) {
+			srv := &asgServer{}
+			srv.fail(2, failure.status, failure.code)
+			ts := httptest.NewServer(srv)
+			defer ts.Close()
+
+			asgc := asgClient(&ClientsStr{Retryer: NewRetryer(3, time.Millisecond)}, ts.URL)
+
+			_, err := asgc.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{})
+			assert.NoError(t, err)
+			assert.Equal(t, 3, srv.calls)
+		})
+	}
+}
+
+func Test_ClientsStr_Retries_Transient_Errors_MaxAttempts(t *testing.T) {
+	srv := &asgServer{}
+	srv.fail(3, 400, "Throttling")
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	asgc := asgClient(&ClientsStr{Retryer: NewRetryer(3, time.Millisecond)}, ts.URL)
+
+	_, err := asgc.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{})
+	assert.Error(t, err)
+	assert.Equal(t, "Throttling", err.(awserr.Error).Code())
+	assert.Equal(t, 3, srv.calls)
+}
+
+func Test_ClientsStr_Does_Not_Retry_Validation_Errors(t *testing.T) {
+	srv := &asgServer{}
+	srv.fail(1, 400, "ValidationError")
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	asgc := asgClient(&ClientsStr{Retryer: NewRetryer(3, time.Millisecond)}, ts.URL)
+
+	_, err := asgc.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{})
+	assert.Error(t, err)
+	assert.Equal(t, "ValidationError", err.(awserr.Error).Code())
+	assert.Equal(t, 1, srv.calls)
+}
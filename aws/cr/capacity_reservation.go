@@ -0,0 +1,33 @@
+package cr
+
+import (
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// notFoundCode is the error code for a capacity reservation ID that does not exist
+const notFoundCode = "InvalidCapacityReservationId.NotFound"
+
+// Find returns the capacity reservation with the ID, or nil if it does not exist
+func Find(ec2c aws.EC2API, id *string) (*ec2.CapacityReservation, error) {
+	out, err := ec2c.DescribeCapacityReservations(&ec2.DescribeCapacityReservationsInput{
+		CapacityReservationIds: []*string{id},
+	})
+
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == notFoundCode {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	for _, reservation := range out.CapacityReservations {
+		if to.Strs(reservation.CapacityReservationId) == to.Strs(id) {
+			return reservation, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,27 @@
+package cr
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Find(t *testing.T) {
+	ec2c := &mocks.EC2Client{}
+	ec2c.AddCapacityReservation("cr-1234", "m5.large", "us-east-1a", "active")
+
+	reservation, err := Find(ec2c, to.Strp("cr-1234"))
+	assert.NoError(t, err)
+	assert.Equal(t, "m5.large", *reservation.InstanceType)
+	assert.Equal(t, "us-east-1a", *reservation.AvailabilityZone)
+
+	reservation, err = Find(ec2c, to.Strp("cr-5678"))
+	assert.NoError(t, err)
+	assert.Nil(t, reservation)
+
+	ec2c.AddThrottles("DescribeCapacityReservations", 1)
+	_, err = Find(ec2c, to.Strp("cr-1234"))
+	assert.Error(t, err)
+}
@@ -2,6 +2,8 @@ package elb
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	aws_elb "github.com/aws/aws-sdk-go/service/elb"
@@ -15,6 +17,12 @@ type LoadBalancer struct {
 	ConfigNameTag    *string
 	ServiceNameTag   *string
 	LoadBalancerName *string
+	DNSName          *string
+	SecurityGroups   []*string
+	HealthCheckPort  *int64
+
+	// ConnectionDrainingTimeout is the seconds deregistered instances keep their connections, 0 if draining is disabled
+	ConnectionDrainingTimeout int
 }
 
 // ProjectName returns tag
@@ -129,9 +137,51 @@ func find(elbc aws.ELBAPI, name *string) (*LoadBalancer, error) {
 		ConfigNameTag:    aws.FetchELBTag(tags, to.Strp("ConfigName")),
 		ServiceNameTag:   aws.FetchELBTag(tags, to.Strp("ServiceName")),
 		LoadBalancerName: elbDesc.LoadBalancerName,
+		DNSName:          elbDesc.DNSName,
+		SecurityGroups:   elbDesc.SecurityGroups,
+		HealthCheckPort:  healthCheckPort(elbDesc.HealthCheck),
+
+		ConnectionDrainingTimeout: connectionDrainingTimeout(elbc, name),
 	}, nil
 }
 
+// healthCheckPort returns the port of a health check target like "HTTP:80/ping" or "TCP:8080"
+func healthCheckPort(check *aws_elb.HealthCheck) *int64 {
+	if check == nil || check.Target == nil {
+		return nil
+	}
+
+	parts := strings.SplitN(*check.Target, ":", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+
+	port, err := strconv.ParseInt(strings.SplitN(parts[1], "/", 2)[0], 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	return &port
+}
+
+// connectionDrainingTimeout returns the connection draining timeout of the ELB, 0 if it is disabled or cannot be found
+func connectionDrainingTimeout(elbc aws.ELBAPI, name *string) int {
+	output, err := elbc.DescribeLoadBalancerAttributes(&aws_elb.DescribeLoadBalancerAttributesInput{
+		LoadBalancerName: name,
+	})
+
+	if err != nil || output.LoadBalancerAttributes == nil {
+		return 0
+	}
+
+	draining := output.LoadBalancerAttributes.ConnectionDraining
+	if draining == nil || draining.Enabled == nil || !*draining.Enabled || draining.Timeout == nil {
+		return 0
+	}
+
+	return int(*draining.Timeout)
+}
+
 func findAwsByName(elbc aws.ELBAPI, name *string) (*aws_elb.LoadBalancerDescription, error) {
 	elbsOutput, err := elbc.DescribeLoadBalancers(&aws_elb.DescribeLoadBalancersInput{
 		LoadBalancerNames: []*string{name},
@@ -4,6 +4,7 @@ import (
 	"sort"
 	"testing"
 
+	aws_elb "github.com/aws/aws-sdk-go/service/elb"
 	"github.com/coinbase/odin/aws/mocks"
 	"github.com/coinbase/step/utils/to"
 	"github.com/stretchr/testify/assert"
@@ -36,6 +37,18 @@ func Test_FindAll(t *testing.T) {
 	assert.Equal(t, 2, len(elbs))
 }
 
+func Test_FindAll_ConnectionDrainingTimeout(t *testing.T) {
+	elbc := &mocks.ELBClient{}
+	elbc.AddELB("asd", "project", "config", "service")
+	elbc.AddELB("das", "project", "config", "service")
+	elbc.ConnectionDrainingTimeouts = map[string]int64{"das": 45}
+
+	elbs, err := FindAll(elbc, []*string{to.Strp("asd"), to.Strp("das")})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, elbs[0].ConnectionDrainingTimeout)
+	assert.Equal(t, 45, elbs[1].ConnectionDrainingTimeout)
+}
+
 func Test_createDescribeInstanceHealthInput(t *testing.T) {
 	name := ""
 
@@ -59,3 +72,10 @@ func Test_createDescribeInstanceHealthInput(t *testing.T) {
 	assert.Equal(t, elbsIDs[0], "a")
 	assert.Equal(t, elbsIDs[1], "b")
 }
+
+func Test_healthCheckPort(t *testing.T) {
+	assert.Nil(t, healthCheckPort(nil))
+	assert.Equal(t, int64(80), *healthCheckPort(&aws_elb.HealthCheck{Target: to.Strp("HTTP:80/ping")}))
+	assert.Equal(t, int64(8080), *healthCheckPort(&aws_elb.HealthCheck{Target: to.Strp("TCP:8080")}))
+	assert.Nil(t, healthCheckPort(&aws_elb.HealthCheck{Target: to.Strp("bad")}))
+}
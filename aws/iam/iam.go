@@ -1,6 +1,11 @@
 package iam
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/coinbase/odin/aws"
 )
@@ -11,27 +16,109 @@ import (
 
 // Profile struct
 type Profile struct {
-	Path *string
-	Arn  *string
+	Path  *string
+	Arn   *string
+	Roles []*string
+
+	PolicyARNs []string // Managed policies attached to Roles, only fetched if required
+}
+
+var profileARNRegex = regexp.MustCompile(`^arn:aws[a-z-]*:iam::[0-9]{12}:instance-profile/([\x21-\x7e]*/)?[\w+=,.@-]{1,128}$`)
+var profileNameRegex = regexp.MustCompile(`^[\w+=,.@-]{1,128}$`)
+
+// IsProfileARN returns true if the profile reference is an instance profile ARN instead of a name
+func IsProfileARN(profile string) bool {
+	return strings.HasPrefix(profile, "arn:")
+}
+
+// ValidateProfile errors if the profile reference is not an instance profile name or ARN
+func ValidateProfile(profile string) error {
+	if IsProfileARN(profile) {
+		if !profileARNRegex.MatchString(profile) {
+			return fmt.Errorf("Iam Profile %q is not a valid instance profile ARN", profile)
+		}
+		return nil
+	}
+
+	if !profileNameRegex.MatchString(profile) {
+		return fmt.Errorf("Iam Profile %q is not a valid instance profile name", profile)
+	}
+
+	return nil
+}
+
+// ProfileName returns the name of the profile reference, which is either a name or an ARN
+func ProfileName(profile string) string {
+	if !IsProfileARN(profile) {
+		return profile
+	}
+
+	return profile[strings.LastIndex(profile, "/")+1:]
 }
 
-// Find returns profile with name
-func Find(iamClient aws.IAMAPI, profileName *string) (*Profile, error) {
+// Find returns profile with name or ARN. The profile of an ARN must have that ARN, e.g. not be in a different path
+func Find(iamClient aws.IAMAPI, profileRef *string) (*Profile, error) {
+	if err := ValidateProfile(*profileRef); err != nil {
+		return nil, err
+	}
+
+	profileName := ProfileName(*profileRef)
 	profileOutput, err := iamClient.GetInstanceProfile(&iam.GetInstanceProfileInput{
-		InstanceProfileName: profileName,
+		InstanceProfileName: &profileName,
 	})
 
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+		return nil, fmt.Errorf("Iam Profile %q Not Found", *profileRef)
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
 	awsProfile := profileOutput.InstanceProfile
+
+	if IsProfileARN(*profileRef) && (awsProfile.Arn == nil || *awsProfile.Arn != *profileRef) {
+		return nil, fmt.Errorf("Iam Profile %q Not Found", *profileRef)
+	}
+
+	roles := []*string{}
+	for _, role := range awsProfile.Roles {
+		if role != nil && role.RoleName != nil {
+			roles = append(roles, role.RoleName)
+		}
+	}
+
 	return &Profile{
-		Path: awsProfile.Path,
-		Arn:  awsProfile.Arn,
+		Path:  awsProfile.Path,
+		Arn:   awsProfile.Arn,
+		Roles: roles,
 	}, nil
 }
 
+// FetchPolicyARNs sets PolicyARNs to the managed policies attached to the profiles roles
+func (p *Profile) FetchPolicyARNs(iamc aws.IAMAPI) error {
+	arns := []string{}
+	for _, roleName := range p.Roles {
+		err := iamc.ListAttachedRolePoliciesPages(&iam.ListAttachedRolePoliciesInput{
+			RoleName: roleName,
+		}, func(page *iam.ListAttachedRolePoliciesOutput, lastPage bool) bool {
+			for _, policy := range page.AttachedPolicies {
+				if policy.PolicyArn != nil {
+					arns = append(arns, *policy.PolicyArn)
+				}
+			}
+			return true
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	p.PolicyARNs = arns
+	return nil
+}
+
 //////
 // ROLE
 //////
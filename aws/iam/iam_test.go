@@ -19,3 +19,50 @@ func Test_Find(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "/path/", *profile.Path)
 }
+
+func Test_Find_ARN(t *testing.T) {
+	iamc := &mocks.IAMClient{}
+	iamc.AddGetInstanceProfile("asd", "/path/")
+
+	profile, err := Find(iamc, to.Strp("arn:aws:iam::000000000000:instance-profile/path/asd"))
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:iam::000000000000:instance-profile/path/asd", *profile.Arn)
+
+	// The profile with the name must have the ARN
+	_, err = Find(iamc, to.Strp("arn:aws:iam::000000000000:instance-profile/other/asd"))
+	assert.EqualError(t, err, `Iam Profile "arn:aws:iam::000000000000:instance-profile/other/asd" Not Found`)
+
+	_, err = Find(iamc, to.Strp("arn:aws:iam::000000000000:instance-profile/path/missing"))
+	assert.EqualError(t, err, `Iam Profile "arn:aws:iam::000000000000:instance-profile/path/missing" Not Found`)
+
+	_, err = Find(iamc, to.Strp("arn:aws:iam::000000000000:role/asd"))
+	assert.Error(t, err)
+}
+
+func Test_ValidateProfile(t *testing.T) {
+	assert.NoError(t, ValidateProfile("web-profile"))
+	assert.NoError(t, ValidateProfile("arn:aws:iam::000000000000:instance-profile/web-profile"))
+	assert.NoError(t, ValidateProfile("arn:aws:iam::000000000000:instance-profile/odin/project/config/web/web-profile"))
+	assert.NoError(t, ValidateProfile("arn:aws-us-gov:iam::000000000000:instance-profile/web-profile"))
+
+	assert.Error(t, ValidateProfile(""))
+	assert.Error(t, ValidateProfile("odin/web-profile"))
+	assert.Error(t, ValidateProfile("arn:aws:iam::aws:instance-profile/web-profile"))
+	assert.Error(t, ValidateProfile("arn:aws:iam::000000000000:role/web-profile"))
+
+	assert.Equal(t, "web-profile", ProfileName("web-profile"))
+	assert.Equal(t, "web-profile", ProfileName("arn:aws:iam::000000000000:instance-profile/odin/web-profile"))
+}
+
+func Test_Profile_FetchPolicyARNs(t *testing.T) {
+	iamc := &mocks.IAMClient{}
+	iamc.AddGetInstanceProfile("asd", "/path/")
+	iamc.AddInstanceProfileRole("asd", "role", "arn:aws:iam::aws:policy/one", "arn:aws:iam::aws:policy/two")
+
+	profile, err := Find(iamc, to.Strp("asd"))
+	assert.NoError(t, err)
+	assert.Equal(t, []*string{to.Strp("role")}, profile.Roles)
+
+	assert.NoError(t, profile.FetchPolicyARNs(iamc))
+	assert.Equal(t, []string{"arn:aws:iam::aws:policy/one", "arn:aws:iam::aws:policy/two"}, profile.PolicyARNs)
+}
@@ -0,0 +1,148 @@
+package instance
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// spotInterruptionCode is the state reason of an instance reclaimed by a spot interruption
+const spotInterruptionCode = "Server.SpotInstanceTermination"
+
+// LaunchTimes returns the launch time of each found instance
+func LaunchTimes(ec2c aws.EC2API, instanceIDs []string) (map[string]time.Time, error) {
+	launchTimes := map[string]time.Time{}
+
+	err := describe(ec2c, instanceIDs, func(i *ec2.Instance) {
+		if i.LaunchTime != nil {
+			launchTimes[*i.InstanceId] = *i.LaunchTime
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return launchTimes, nil
+}
+
+// PrivateIPs returns the private IP address of each found instance
+func PrivateIPs(ec2c aws.EC2API, instanceIDs []string) (map[string]string, error) {
+	ips := map[string]string{}
+
+	err := describe(ec2c, instanceIDs, func(i *ec2.Instance) {
+		if i.PrivateIpAddress != nil {
+			ips[*i.InstanceId] = *i.PrivateIpAddress
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ips, nil
+}
+
+// SpotInterrupted returns the found instances that were terminated by a spot interruption
+func SpotInterrupted(ec2c aws.EC2API, instanceIDs []string) ([]string, error) {
+	interrupted := []string{}
+
+	err := describe(ec2c, instanceIDs, func(i *ec2.Instance) {
+		if i.StateReason != nil && to.Strs(i.StateReason.Code) == spotInterruptionCode {
+			interrupted = append(interrupted, *i.InstanceId)
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return interrupted, nil
+}
+
+// describe calls fn with each found instance
+func describe(ec2c aws.EC2API, instanceIDs []string, fn func(*ec2.Instance)) error {
+	if len(instanceIDs) == 0 {
+		return nil
+	}
+
+	ids := []*string{}
+	for _, id := range instanceIDs {
+		ids = append(ids, to.Strp(id))
+	}
+
+	pagefn := func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, i := range reservation.Instances {
+				if i.InstanceId != nil {
+					fn(i)
+				}
+			}
+		}
+		return !lastPage
+	}
+
+	return ec2c.DescribeInstancesPages(&ec2.DescribeInstancesInput{InstanceIds: ids}, pagefn)
+}
+
+// MissingOfferings returns the "instance_type/availability_zone" pairs that are not offered
+func MissingOfferings(ec2c aws.EC2API, instanceTypes []*string, azs []*string) ([]string, error) {
+	offered := map[string]bool{}
+
+	pagefn := func(page *ec2.DescribeInstanceTypeOfferingsOutput, lastPage bool) bool {
+		for _, o := range page.InstanceTypeOfferings {
+			offered[fmt.Sprintf("%v/%v", to.Strs(o.InstanceType), to.Strs(o.Location))] = true
+		}
+		return !lastPage
+	}
+
+	err := ec2c.DescribeInstanceTypeOfferingsPages(&ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: to.Strp("availability-zone"),
+		Filters: []*ec2.Filter{
+			&ec2.Filter{Name: to.Strp("instance-type"), Values: instanceTypes},
+			&ec2.Filter{Name: to.Strp("location"), Values: azs},
+		},
+	}, pagefn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	missing := []string{}
+	for _, instanceType := range instanceTypes {
+		for _, az := range azs {
+			key := fmt.Sprintf("%v/%v", to.Strs(instanceType), to.Strs(az))
+			if !offered[key] {
+				missing = append(missing, key)
+			}
+		}
+	}
+
+	return missing, nil
+}
+
+// DescribeTypes returns the description of each instance type by name
+func DescribeTypes(ec2c aws.EC2API, instanceTypes []*string) (map[string]*ec2.InstanceTypeInfo, error) {
+	types := map[string]*ec2.InstanceTypeInfo{}
+	if len(instanceTypes) == 0 {
+		return types, nil
+	}
+
+	pagefn := func(page *ec2.DescribeInstanceTypesOutput, lastPage bool) bool {
+		for _, it := range page.InstanceTypes {
+			if it != nil && it.InstanceType != nil {
+				types[*it.InstanceType] = it
+			}
+		}
+		return !lastPage
+	}
+
+	if err := ec2c.DescribeInstanceTypesPages(&ec2.DescribeInstanceTypesInput{InstanceTypes: instanceTypes}, pagefn); err != nil {
+		return nil, err
+	}
+
+	return types, nil
+}
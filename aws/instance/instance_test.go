@@ -0,0 +1,63 @@
+package instance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LaunchTimes(t *testing.T) {
+	ec2c := &mocks.EC2Client{}
+	launched := time.Now().Add(-time.Minute)
+	ec2c.AddInstance("i-1", launched)
+
+	launchTimes, err := LaunchTimes(ec2c, []string{"i-1", "i-2"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(launchTimes))
+	assert.Equal(t, launched, launchTimes["i-1"])
+
+	launchTimes, err = LaunchTimes(ec2c, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(launchTimes))
+}
+
+func Test_PrivateIPs(t *testing.T) {
+	ec2c := &mocks.EC2Client{}
+	ec2c.AddInstance("i-1", time.Now())
+	ec2c.SetPrivateIP("i-2", "10.0.0.2")
+
+	ips, err := PrivateIPs(ec2c, []string{"i-1", "i-2", "i-3"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"i-2": "10.0.0.2"}, ips)
+}
+
+func Test_MissingOfferings(t *testing.T) {
+	ec2c := &mocks.EC2Client{UnofferedInstanceTypes: []string{"p3.16xlarge"}}
+
+	missing, err := MissingOfferings(ec2c, []*string{to.Strp("m5.large"), to.Strp("p3.16xlarge")}, []*string{to.Strp("us-east-1a"), to.Strp("us-east-1b")})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"p3.16xlarge/us-east-1a", "p3.16xlarge/us-east-1b"}, missing)
+}
+
+func Test_DescribeTypes(t *testing.T) {
+	ec2c := &mocks.EC2Client{}
+	ec2c.AddInstanceType("m6g.large", []string{"arm64"}, []string{"hvm"})
+
+	types, err := DescribeTypes(ec2c, []*string{to.Strp("m5.large"), to.Strp("m6g.large")})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"x86_64"}, to.StrSlice(types["m5.large"].ProcessorInfo.SupportedArchitectures))
+	assert.Equal(t, []string{"arm64"}, to.StrSlice(types["m6g.large"].ProcessorInfo.SupportedArchitectures))
+}
+
+func Test_SpotInterrupted(t *testing.T) {
+	ec2c := &mocks.EC2Client{}
+	ec2c.AddInstance("i-1", time.Now())
+	ec2c.AddSpotInterruption("i-2")
+
+	interrupted, err := SpotInterrupted(ec2c, []string{"i-1", "i-2", "i-3"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"i-2"}, interrupted)
+}
@@ -1,6 +1,8 @@
 package aws
 
 import (
+	"strings"
+
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/aws/aws-sdk-go/service/elbv2"
@@ -9,6 +11,7 @@ import (
 const terminating = "terminating"
 const unhealthy = "unhealthy"
 const healthy = "healthy"
+const pending = "pending"
 
 // Instances Map of instance id to state
 type Instances map[string]string
@@ -28,6 +31,11 @@ func (all Instances) AddASGInstance(i *autoscaling.Instance) {
 		return
 	}
 
+	// Warm pool instances are intentionally stopped or idle, they are not part of the group's capacity
+	if strings.HasPrefix(*i.LifecycleState, "Warmed:") {
+		return
+	}
+
 	state := unhealthy
 
 	if i.HealthStatus != nil && *i.HealthStatus == "Healthy" && *i.LifecycleState == "InService" {
@@ -103,6 +111,51 @@ func (all Instances) HealthyIDs() []string {
 	return ids
 }
 
+// PendingIDs list of instances pending
+func (all Instances) PendingIDs() []string {
+	ids := []string{}
+	for id, state := range all {
+		if state == pending {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// SetPending marks the unhealthy instances in ids as pending, e.g. still warming up
+func (all Instances) SetPending(ids []string) {
+	for _, id := range ids {
+		if all[id] == unhealthy {
+			all[id] = pending
+		}
+	}
+}
+
+// SetNotReady marks healthy instances in ids as pending, e.g. they are not yet ready to serve
+func (all Instances) SetNotReady(ids []string) {
+	for _, id := range ids {
+		if all[id] == healthy {
+			all[id] = pending
+		}
+	}
+}
+
+// SetHealthy marks unhealthy instances in ids as healthy, e.g. their health is decided by another source
+func (all Instances) SetHealthy(ids []string) {
+	for _, id := range ids {
+		if all[id] == unhealthy {
+			all[id] = healthy
+		}
+	}
+}
+
+// Remove deletes the instances in ids
+func (all Instances) Remove(ids []string) {
+	for _, id := range ids {
+		delete(all, id)
+	}
+}
+
 // TerminatingIDs list of instances terminating
 func (all Instances) TerminatingIDs() []string {
 	ids := []string{}
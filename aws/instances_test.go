@@ -3,6 +3,8 @@ package aws
 import (
 	"testing"
 
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/coinbase/step/utils/to"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,3 +29,37 @@ func Test_MergeInstances(t *testing.T) {
 	i2 = Instances{"i": terminating}
 	assert.Equal(t, terminating, i2.MergeInstances(i1)["i"])
 }
+
+func Test_SetPending(t *testing.T) {
+	all := Instances{"h": healthy, "u": unhealthy, "t": terminating, "p": unhealthy}
+	all.SetPending([]string{"h", "u", "t", "missing"})
+
+	assert.Equal(t, healthy, all["h"])
+	assert.Equal(t, pending, all["u"])
+	assert.Equal(t, terminating, all["t"])
+	assert.Equal(t, unhealthy, all["p"])
+	assert.Equal(t, []string{"u"}, all.PendingIDs())
+	assert.Equal(t, 4, len(all))
+}
+
+func Test_SetNotReady(t *testing.T) {
+	all := Instances{"h": healthy, "u": unhealthy, "t": terminating, "r": healthy}
+	all.SetNotReady([]string{"h", "u", "t", "missing"})
+
+	assert.Equal(t, pending, all["h"])
+	assert.Equal(t, unhealthy, all["u"])
+	assert.Equal(t, terminating, all["t"])
+	assert.Equal(t, healthy, all["r"])
+	assert.Equal(t, []string{"r"}, all.HealthyIDs())
+	assert.Equal(t, 4, len(all))
+}
+
+func Test_AddASGInstance_WarmPool(t *testing.T) {
+	all := Instances{}
+	all.AddASGInstance(&autoscaling.Instance{InstanceId: to.Strp("h"), HealthStatus: to.Strp("Healthy"), LifecycleState: to.Strp("InService")})
+	all.AddASGInstance(&autoscaling.Instance{InstanceId: to.Strp("s"), HealthStatus: to.Strp("Healthy"), LifecycleState: to.Strp("Warmed:Stopped")})
+	all.AddASGInstance(&autoscaling.Instance{InstanceId: to.Strp("r"), HealthStatus: to.Strp("Healthy"), LifecycleState: to.Strp("Warmed:Running")})
+
+	assert.Equal(t, 1, len(all))
+	assert.Equal(t, healthy, all["h"])
+}
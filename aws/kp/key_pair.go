@@ -0,0 +1,33 @@
+package kp
+
+import (
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// notFoundCode is the error code for a key pair name that does not exist
+const notFoundCode = "InvalidKeyPair.NotFound"
+
+// Find returns the key pair with the name, or nil if it does not exist
+func Find(ec2c aws.EC2API, name *string) (*ec2.KeyPairInfo, error) {
+	out, err := ec2c.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{
+		KeyNames: []*string{name},
+	})
+
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == notFoundCode {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	for _, keyPair := range out.KeyPairs {
+		if to.Strs(keyPair.KeyName) == to.Strs(name) {
+			return keyPair, nil
+		}
+	}
+
+	return nil, nil
+}
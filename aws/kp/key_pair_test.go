@@ -0,0 +1,26 @@
+package kp
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Find(t *testing.T) {
+	ec2c := &mocks.EC2Client{}
+	ec2c.AddKeyPair("deploy-key")
+
+	keyPair, err := Find(ec2c, to.Strp("deploy-key"))
+	assert.NoError(t, err)
+	assert.Equal(t, "deploy-key", *keyPair.KeyName)
+
+	keyPair, err = Find(ec2c, to.Strp("missing-key"))
+	assert.NoError(t, err)
+	assert.Nil(t, keyPair)
+
+	ec2c.AddThrottles("DescribeKeyPairs", 1)
+	_, err = Find(ec2c, to.Strp("deploy-key"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,37 @@
+package lambda
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// Invoke synchronously invokes the function and returns its response payload,
+// a function error or a non 2xx status code is an error
+func Invoke(lambdac aws.LambdaAPI, functionName *string, payload []byte) ([]byte, error) {
+	out, err := lambdac.Invoke(&lambda.InvokeInput{
+		FunctionName:   functionName,
+		InvocationType: to.Strp(lambda.InvocationTypeRequestResponse),
+		Payload:        payload,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if out.FunctionError != nil {
+		return nil, fmt.Errorf("Lambda %v %v error %v", *functionName, *out.FunctionError, string(out.Payload))
+	}
+
+	if out.StatusCode == nil {
+		return nil, fmt.Errorf("Lambda %v status code not returned", *functionName)
+	}
+
+	if *out.StatusCode < 200 || *out.StatusCode > 299 {
+		return nil, fmt.Errorf("Lambda %v status code %v", *functionName, *out.StatusCode)
+	}
+
+	return out.Payload, nil
+}
@@ -18,3 +18,27 @@ func Teardown(asgc aws.ASGAPI, name *string) error {
 
 	return nil
 }
+
+// Exists is true if the launch configuration exists, launch configurations cannot be tagged
+// so one created for an ASG that was never created is found by name
+func Exists(asgc aws.ASGAPI, name *string) (bool, error) {
+	out, err := asgc.DescribeLaunchConfigurations(&autoscaling.DescribeLaunchConfigurationsInput{
+		LaunchConfigurationNames: []*string{name},
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return len(out.LaunchConfigurations) > 0, nil
+}
+
+// TeardownIfExists deletes the launch configuration if it exists
+func TeardownIfExists(asgc aws.ASGAPI, name *string) error {
+	exists, err := Exists(asgc, name)
+	if err != nil || !exists {
+		return err
+	}
+
+	return Teardown(asgc, name)
+}
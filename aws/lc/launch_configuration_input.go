@@ -64,8 +64,8 @@ func (s *LaunchConfigInput) Create(asgc aws.ASGAPI) error {
 	return nil
 }
 
-// AddBlockDevice adds an EBS block device to the LC
-func (s *LaunchConfigInput) AddBlockDevice(ebsVolumeSize *int64, ebsVolumeType *string, ebsDeviceType *string) {
+// AddBlockDevice adds an EBS block device to the LC, iops and throughput are optional
+func (s *LaunchConfigInput) AddBlockDevice(ebsVolumeSize *int64, ebsVolumeType *string, ebsDeviceType *string, ebsIOPS *int64, ebsThroughput *int64) {
 	if ebsVolumeSize == nil {
 		return
 	}
@@ -83,6 +83,8 @@ func (s *LaunchConfigInput) AddBlockDevice(ebsVolumeSize *int64, ebsVolumeType *
 		Ebs: &autoscaling.Ebs{
 			VolumeSize: ebsVolumeSize,
 			VolumeType: ebsVolumeType,
+			Iops:       ebsIOPS,
+			Throughput: ebsThroughput,
 		},
 	}
 
@@ -5,13 +5,23 @@ import (
 
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
 )
 
 func Test_AddBlockDevice(t *testing.T) {
 	input := &LaunchConfigInput{&autoscaling.CreateLaunchConfigurationInput{}}
 
-	input.AddBlockDevice(to.Int64p(10), nil, nil)
-	input.AddBlockDevice(to.Int64p(10), to.Strp("asd"), nil)
-	input.AddBlockDevice(to.Int64p(10), nil, to.Strp("asd"))
+	input.AddBlockDevice(to.Int64p(10), nil, nil, nil, nil)
+	input.AddBlockDevice(to.Int64p(10), to.Strp("asd"), nil, nil, nil)
+	input.AddBlockDevice(to.Int64p(10), nil, to.Strp("asd"), nil, nil)
+	input.AddBlockDevice(to.Int64p(10), to.Strp("gp3"), nil, to.Int64p(4000), to.Int64p(250))
+
+	assert.Equal(t, 4, len(input.BlockDeviceMappings))
+	assert.Equal(t, "gp2", *input.BlockDeviceMappings[0].Ebs.VolumeType)
+	assert.Equal(t, "/dev/xvda", *input.BlockDeviceMappings[0].DeviceName)
+	assert.Nil(t, input.BlockDeviceMappings[0].Ebs.Iops)
+	assert.Nil(t, input.BlockDeviceMappings[0].Ebs.Throughput)
+	assert.Equal(t, int64(4000), *input.BlockDeviceMappings[3].Ebs.Iops)
+	assert.Equal(t, int64(250), *input.BlockDeviceMappings[3].Ebs.Throughput)
 
 }
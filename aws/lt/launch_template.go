@@ -0,0 +1,547 @@
+package lt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// Input input struct
+type Input struct {
+	*ec2.CreateLaunchTemplateInput
+
+	// Data are launch template data query parameters the SDK does not have fields for yet,
+	// e.g. "PrivateDnsNameOptions.HostnameType", added to the request as LaunchTemplateData.<key>
+	Data map[string]string
+
+	// Overrides are flattened launch template data query parameters, they are only added to the request
+	// if neither the SDK fields nor Data set them
+	Overrides map[string]string
+}
+
+// FromLaunchConfig builds a launch template with the same launch values as the launch configuration
+// The launch template is named after the launch configuration
+func FromLaunchConfig(lc *autoscaling.CreateLaunchConfigurationInput) *Input {
+	data := &ec2.RequestLaunchTemplateData{
+		ImageId:      lc.ImageId,
+		InstanceType: lc.InstanceType,
+		UserData:     lc.UserData,
+		EbsOptimized: lc.EbsOptimized,
+		KeyName:      lc.KeyName,
+	}
+
+	if lc.IamInstanceProfile != nil {
+		data.IamInstanceProfile = &ec2.LaunchTemplateIamInstanceProfileSpecificationRequest{Arn: lc.IamInstanceProfile}
+	}
+
+	if lc.InstanceMonitoring != nil {
+		data.Monitoring = &ec2.LaunchTemplatesMonitoringRequest{Enabled: lc.InstanceMonitoring.Enabled}
+	}
+
+	if lc.MetadataOptions != nil {
+		data.MetadataOptions = &ec2.LaunchTemplateInstanceMetadataOptionsRequest{
+			HttpTokens:              lc.MetadataOptions.HttpTokens,
+			HttpPutResponseHopLimit: lc.MetadataOptions.HttpPutResponseHopLimit,
+			HttpEndpoint:            lc.MetadataOptions.HttpEndpoint,
+		}
+	}
+
+	if lc.PlacementTenancy != nil {
+		data.Placement = &ec2.LaunchTemplatePlacementRequest{Tenancy: lc.PlacementTenancy}
+	}
+
+	// Security groups must be on the network interface when it is configured
+	if lc.AssociatePublicIpAddress != nil {
+		data.NetworkInterfaces = []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+			&ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+				AssociatePublicIpAddress: lc.AssociatePublicIpAddress,
+				DeviceIndex:              to.Int64p(0),
+				Groups:                   lc.SecurityGroups,
+			},
+		}
+	} else {
+		data.SecurityGroupIds = lc.SecurityGroups
+	}
+
+	for _, bd := range lc.BlockDeviceMappings {
+		mapping := &ec2.LaunchTemplateBlockDeviceMappingRequest{DeviceName: bd.DeviceName}
+		if bd.Ebs != nil {
+			mapping.Ebs = &ec2.LaunchTemplateEbsBlockDeviceRequest{
+				VolumeSize:          bd.Ebs.VolumeSize,
+				VolumeType:          bd.Ebs.VolumeType,
+				Iops:                bd.Ebs.Iops,
+				Throughput:          bd.Ebs.Throughput,
+				Encrypted:           bd.Ebs.Encrypted,
+				DeleteOnTermination: bd.Ebs.DeleteOnTermination,
+			}
+		}
+		data.BlockDeviceMappings = append(data.BlockDeviceMappings, mapping)
+	}
+
+	return &Input{CreateLaunchTemplateInput: &ec2.CreateLaunchTemplateInput{
+		LaunchTemplateName: lc.LaunchConfigurationName,
+		LaunchTemplateData: data,
+	}}
+}
+
+// SetData sets a launch template data query parameter the SDK has no field for
+func (s *Input) SetData(key string, value string) {
+	if s.Data == nil {
+		s.Data = map[string]string{}
+	}
+
+	s.Data[key] = value
+}
+
+// SetOverrides flattens the overrides into launch template data query parameters, nested maps are
+// joined with "." and lists are numbered from 1, e.g. {"MaintenanceOptions": {"AutoRecovery": "disabled"}}
+// is "MaintenanceOptions.AutoRecovery"
+func (s *Input) SetOverrides(overrides map[string]interface{}) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	if s.Overrides == nil {
+		s.Overrides = map[string]string{}
+	}
+
+	return flatten("", overrides, s.Overrides)
+}
+
+// flatten adds the query parameters of value with the key prefix to params
+func flatten(prefix string, value interface{}, params map[string]string) error {
+	key := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return fmt.Sprintf("%v.%v", prefix, k)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return fmt.Errorf("Launch template override %v cannot be empty", prefix)
+		}
+
+		for k, nested := range v {
+			if k == "" {
+				return fmt.Errorf("Launch template override %v cannot have an empty key", prefix)
+			}
+
+			if err := flatten(key(k), nested, params); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return fmt.Errorf("Launch template override %v cannot be empty", prefix)
+		}
+
+		for i, nested := range v {
+			if err := flatten(key(strconv.Itoa(i+1)), nested, params); err != nil {
+				return err
+			}
+		}
+	case string:
+		params[prefix] = v
+	case bool:
+		params[prefix] = strconv.FormatBool(v)
+	case float64:
+		params[prefix] = strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		params[prefix] = strconv.Itoa(v)
+	case int64:
+		params[prefix] = strconv.FormatInt(v, 10)
+	default:
+		return fmt.Errorf("Launch template override %v must be a string, number, boolean, map or list", prefix)
+	}
+
+	return nil
+}
+
+// overrideField is the override parameter up to its first list index, a list set by the SDK fields
+// or Data is never merged with an overridden list
+func overrideField(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		if _, err := strconv.Atoi(part); err == nil {
+			return strings.Join(parts[:i], ".")
+		}
+	}
+
+	return key
+}
+
+// setParameter returns true if the query sets the field or a parameter nested in it
+func setParameter(values url.Values, field string) bool {
+	for key := range values {
+		if key == field || strings.HasPrefix(key, field+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// raw returns true if the request needs the Data or Overrides added to its query
+func (s *Input) raw() bool {
+	return len(s.Data) > 0 || len(s.Overrides) > 0
+}
+
+// buildData adds the Data and Overrides to the built query body of the request
+func (s *Input) buildData(r *request.Request) {
+	if r.Error != nil || r.Body == nil {
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		r.Error = err
+		return
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		r.Error = err
+		return
+	}
+
+	for key, value := range s.Data {
+		values.Set(fmt.Sprintf("LaunchTemplateData.%v", key), value)
+	}
+
+	// The SDK fields and Data take precedence over the overrides
+	set := map[string]bool{}
+	for key := range s.Overrides {
+		field := fmt.Sprintf("LaunchTemplateData.%v", overrideField(key))
+		if _, ok := set[field]; !ok {
+			set[field] = setParameter(values, field)
+		}
+	}
+
+	for key, value := range s.Overrides {
+		if !set[fmt.Sprintf("LaunchTemplateData.%v", overrideField(key))] {
+			values.Set(fmt.Sprintf("LaunchTemplateData.%v", key), value)
+		}
+	}
+
+	r.SetBufferBody([]byte(values.Encode()))
+}
+
+// SetPlacementGroup launches the instances in the placement group, launch configurations have no placement group
+func (s *Input) SetPlacementGroup(name *string) {
+	if name == nil {
+		return
+	}
+
+	if s.LaunchTemplateData.Placement == nil {
+		s.LaunchTemplateData.Placement = &ec2.LaunchTemplatePlacementRequest{}
+	}
+
+	s.LaunchTemplateData.Placement.GroupName = name
+}
+
+// SetHostResourceGroup sets the host resource group host tenancy instances launch onto
+func (s *Input) SetHostResourceGroup(arn *string) {
+	if arn == nil {
+		return
+	}
+
+	if s.LaunchTemplateData.Placement == nil {
+		s.LaunchTemplateData.Placement = &ec2.LaunchTemplatePlacementRequest{}
+	}
+
+	s.LaunchTemplateData.Placement.HostResourceGroupArn = arn
+}
+
+// SetCapacityReservation sets which capacity reservations the instances launch into, launch configurations have no capacity reservations
+func (s *Input) SetCapacityReservation(spec *ec2.LaunchTemplateCapacityReservationSpecificationRequest) {
+	if spec == nil {
+		return
+	}
+
+	s.LaunchTemplateData.CapacityReservationSpecification = spec
+}
+
+// SetNetworkInterfaces attaches the secondary network interfaces, launch configurations have one network interface.
+// With network interfaces the security groups must be on the primary interface, device index 0
+func (s *Input) SetNetworkInterfaces(secondary []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest) {
+	if len(secondary) == 0 {
+		return
+	}
+
+	data := s.LaunchTemplateData
+	if len(data.NetworkInterfaces) == 0 {
+		data.NetworkInterfaces = []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+			&ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+				DeviceIndex: to.Int64p(0),
+				Groups:      data.SecurityGroupIds,
+			},
+		}
+		data.SecurityGroupIds = nil
+	}
+
+	data.NetworkInterfaces = append(data.NetworkInterfaces, secondary...)
+}
+
+// AddTag tags the launch template, so it can be found if its ASG was never created
+func (s *Input) AddTag(key string, value *string) {
+	if len(s.TagSpecifications) == 0 {
+		s.TagSpecifications = []*ec2.TagSpecification{
+			&ec2.TagSpecification{ResourceType: to.Strp(ec2.ResourceTypeLaunchTemplate)},
+		}
+	}
+
+	spec := s.TagSpecifications[0]
+	for _, tag := range spec.Tags {
+		if *tag.Key == key {
+			tag.Value = value
+			return // Found the tag key already
+		}
+	}
+
+	spec.Tags = append(spec.Tags, &ec2.Tag{Key: &key, Value: value})
+}
+
+// Create tries to create the launch template
+func (s *Input) Create(ec2c aws.EC2API) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	if s.raw() {
+		req, _ := ec2c.CreateLaunchTemplateRequest(s.CreateLaunchTemplateInput)
+		req.Handlers.Build.PushBack(s.buildData)
+		return req.Send()
+	}
+
+	_, err := ec2c.CreateLaunchTemplate(s.CreateLaunchTemplateInput)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateVersion adds a version with the launch template data to the existing launch template
+// The version is returned so an ASG can be pinned to it
+func (s *Input) CreateVersion(ec2c aws.EC2API, description *string) (*int64, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	in := &ec2.CreateLaunchTemplateVersionInput{
+		LaunchTemplateName: s.LaunchTemplateName,
+		LaunchTemplateData: s.LaunchTemplateData,
+		VersionDescription: description,
+	}
+
+	var out *ec2.CreateLaunchTemplateVersionOutput
+	var err error
+	if s.raw() {
+		var req *request.Request
+		req, out = ec2c.CreateLaunchTemplateVersionRequest(in)
+		req.Handlers.Build.PushBack(s.buildData)
+		err = req.Send()
+	} else {
+		out, err = ec2c.CreateLaunchTemplateVersion(in)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if out.LaunchTemplateVersion == nil || out.LaunchTemplateVersion.VersionNumber == nil {
+		return nil, fmt.Errorf("Launch template %v version not returned", to.Strs(s.LaunchTemplateName))
+	}
+
+	return out.LaunchTemplateVersion.VersionNumber, nil
+}
+
+// Teardown deletes the launch template
+func Teardown(ec2c aws.EC2API, name *string) error {
+	_, err := ec2c.DeleteLaunchTemplate(&ec2.DeleteLaunchTemplateInput{
+		LaunchTemplateName: name,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ForReleaseUUID returns the names of the launch templates tagged with the release UUID
+func ForReleaseUUID(ec2c aws.EC2API, releaseUUID *string) ([]*string, error) {
+	names := []*string{}
+	err := ec2c.DescribeLaunchTemplatesPages(&ec2.DescribeLaunchTemplatesInput{
+		Filters: []*ec2.Filter{
+			&ec2.Filter{Name: to.Strp("tag:ReleaseUUID"), Values: []*string{releaseUUID}},
+		},
+	}, func(page *ec2.DescribeLaunchTemplatesOutput, _ bool) bool {
+		for _, template := range page.LaunchTemplates {
+			names = append(names, template.LaunchTemplateName)
+		}
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// ForProjectConfig returns the ReleaseID tag of the launch templates tagged with the project and config by name,
+// a shared launch template has no ReleaseID tag
+func ForProjectConfig(ec2c aws.EC2API, projectName *string, configName *string) (map[string]*string, error) {
+	releaseIDs := map[string]*string{}
+	err := ec2c.DescribeLaunchTemplatesPages(&ec2.DescribeLaunchTemplatesInput{
+		Filters: []*ec2.Filter{
+			&ec2.Filter{Name: to.Strp("tag:ProjectName"), Values: []*string{projectName}},
+			&ec2.Filter{Name: to.Strp("tag:ConfigName"), Values: []*string{configName}},
+		},
+	}, func(page *ec2.DescribeLaunchTemplatesOutput, _ bool) bool {
+		for _, template := range page.LaunchTemplates {
+			if template.LaunchTemplateName == nil {
+				continue
+			}
+			releaseIDs[*template.LaunchTemplateName] = aws.FetchEc2Tag(template.Tags, to.Strp("ReleaseID"))
+		}
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return releaseIDs, nil
+}
+
+// LatestVersion returns the latest version number of the launch template
+func LatestVersion(ec2c aws.EC2API, name *string) (*int64, error) {
+	var version *int64
+	err := ec2c.DescribeLaunchTemplatesPages(&ec2.DescribeLaunchTemplatesInput{
+		LaunchTemplateNames: []*string{name},
+	}, func(page *ec2.DescribeLaunchTemplatesOutput, _ bool) bool {
+		for _, template := range page.LaunchTemplates {
+			if to.Strs(template.LaunchTemplateName) == to.Strs(name) {
+				version = template.LatestVersionNumber
+			}
+		}
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// Versions returns the version numbers of the launch template in descending order and its default version
+func Versions(ec2c aws.EC2API, name *string) ([]int64, *int64, error) {
+	versions := []int64{}
+	var defaultVersion *int64
+
+	err := ec2c.DescribeLaunchTemplateVersionsPages(&ec2.DescribeLaunchTemplateVersionsInput{
+		LaunchTemplateName: name,
+	}, func(page *ec2.DescribeLaunchTemplateVersionsOutput, _ bool) bool {
+		for _, version := range page.LaunchTemplateVersions {
+			if version.VersionNumber == nil {
+				continue
+			}
+
+			versions = append(versions, *version.VersionNumber)
+			if version.DefaultVersion != nil && *version.DefaultVersion {
+				defaultVersion = version.VersionNumber
+			}
+		}
+		return true
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	return versions, defaultVersion, nil
+}
+
+// SetDefaultVersion makes the version the launch templates default version
+func SetDefaultVersion(ec2c aws.EC2API, name *string, version *int64) error {
+	_, err := ec2c.ModifyLaunchTemplate(&ec2.ModifyLaunchTemplateInput{
+		LaunchTemplateName: name,
+		DefaultVersion:     to.Strp(versionStr(version)),
+	})
+
+	return err
+}
+
+// versionStr returns the version number as a launch template version string
+func versionStr(version *int64) string {
+	if version == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", *version)
+}
+
+// maxDeleteVersions is the most versions DeleteLaunchTemplateVersions deletes in one call
+const maxDeleteVersions = 200
+
+// PruneVersions deletes all but the newest keep versions of the launch template, the default version is always kept
+// The deleted version numbers are returned
+func PruneVersions(ec2c aws.EC2API, name *string, keep int) ([]int64, error) {
+	versions, defaultVersion, err := Versions(ec2c, name)
+	if err != nil {
+		return nil, err
+	}
+
+	prune := []*string{}
+	deleted := []int64{}
+	for i, version := range versions {
+		if i < keep || (defaultVersion != nil && version == *defaultVersion) {
+			continue
+		}
+
+		prune = append(prune, to.Strp(versionStr(&version)))
+		deleted = append(deleted, version)
+	}
+
+	for len(prune) > 0 {
+		batch := prune
+		if len(batch) > maxDeleteVersions {
+			batch = prune[:maxDeleteVersions]
+		}
+		prune = prune[len(batch):]
+
+		out, err := ec2c.DeleteLaunchTemplateVersions(&ec2.DeleteLaunchTemplateVersionsInput{
+			LaunchTemplateName: name,
+			Versions:           batch,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, failed := range out.UnsuccessfullyDeletedLaunchTemplateVersions {
+			reason := ""
+			if failed.ResponseError != nil {
+				reason = to.Strs(failed.ResponseError.Message)
+			}
+			return nil, fmt.Errorf("Launch template %v version %v not deleted %v", to.Strs(name), versionStr(failed.VersionNumber), reason)
+		}
+	}
+
+	return deleted, nil
+}
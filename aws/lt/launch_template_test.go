@@ -0,0 +1,224 @@
+package lt
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FromLaunchConfig(t *testing.T) {
+	lc := &autoscaling.CreateLaunchConfigurationInput{
+		LaunchConfigurationName: to.Strp("name"),
+		ImageId:                 to.Strp("ami"),
+		InstanceType:            to.Strp("m5.large"),
+		SecurityGroups:          []*string{to.Strp("sg")},
+		IamInstanceProfile:      to.Strp("arn"),
+		BlockDeviceMappings: []*autoscaling.BlockDeviceMapping{
+			&autoscaling.BlockDeviceMapping{DeviceName: to.Strp("/dev/xvda"), Ebs: &autoscaling.Ebs{VolumeSize: to.Int64p(10)}},
+		},
+	}
+
+	input := FromLaunchConfig(lc)
+	assert.NoError(t, input.Validate())
+	assert.Equal(t, "name", *input.LaunchTemplateName)
+	assert.Equal(t, "m5.large", *input.LaunchTemplateData.InstanceType)
+	assert.Equal(t, "arn", *input.LaunchTemplateData.IamInstanceProfile.Arn)
+	assert.Equal(t, []*string{to.Strp("sg")}, input.LaunchTemplateData.SecurityGroupIds)
+	assert.Equal(t, int64(10), *input.LaunchTemplateData.BlockDeviceMappings[0].Ebs.VolumeSize)
+
+	// Security groups move to the network interface
+	lc.AssociatePublicIpAddress = to.Boolp(true)
+	input = FromLaunchConfig(lc)
+	assert.Nil(t, input.LaunchTemplateData.SecurityGroupIds)
+	assert.Equal(t, []*string{to.Strp("sg")}, input.LaunchTemplateData.NetworkInterfaces[0].Groups)
+
+	// Metadata options
+	lc.MetadataOptions = &autoscaling.InstanceMetadataOptions{HttpTokens: to.Strp("required"), HttpPutResponseHopLimit: to.Int64p(2)}
+	input = FromLaunchConfig(lc)
+	assert.Equal(t, "required", *input.LaunchTemplateData.MetadataOptions.HttpTokens)
+	assert.Equal(t, int64(2), *input.LaunchTemplateData.MetadataOptions.HttpPutResponseHopLimit)
+	assert.Nil(t, input.LaunchTemplateData.MetadataOptions.HttpEndpoint)
+}
+
+func Test_SetNetworkInterfaces(t *testing.T) {
+	lc := &autoscaling.CreateLaunchConfigurationInput{
+		LaunchConfigurationName: to.Strp("name"),
+		SecurityGroups:          []*string{to.Strp("sg")},
+	}
+
+	secondary := []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+		&ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{DeviceIndex: to.Int64p(1), SubnetId: to.Strp("subnet-2")},
+	}
+
+	input := FromLaunchConfig(lc)
+	input.SetNetworkInterfaces(nil)
+	assert.Equal(t, []*string{to.Strp("sg")}, input.LaunchTemplateData.SecurityGroupIds)
+	assert.Nil(t, input.LaunchTemplateData.NetworkInterfaces)
+
+	// Security groups move to the primary network interface
+	input.SetNetworkInterfaces(secondary)
+	assert.Nil(t, input.LaunchTemplateData.SecurityGroupIds)
+	assert.Equal(t, 2, len(input.LaunchTemplateData.NetworkInterfaces))
+	assert.Equal(t, int64(0), *input.LaunchTemplateData.NetworkInterfaces[0].DeviceIndex)
+	assert.Equal(t, []*string{to.Strp("sg")}, input.LaunchTemplateData.NetworkInterfaces[0].Groups)
+	assert.Equal(t, "subnet-2", *input.LaunchTemplateData.NetworkInterfaces[1].SubnetId)
+
+	// The public IP stays on the primary network interface
+	lc.AssociatePublicIpAddress = to.Boolp(false)
+	input = FromLaunchConfig(lc)
+	input.SetNetworkInterfaces(secondary)
+	assert.Equal(t, 2, len(input.LaunchTemplateData.NetworkInterfaces))
+	assert.False(t, *input.LaunchTemplateData.NetworkInterfaces[0].AssociatePublicIpAddress)
+}
+
+func Test_CreateVersion(t *testing.T) {
+	ec2c := &mocks.EC2Client{}
+	input := FromLaunchConfig(&autoscaling.CreateLaunchConfigurationInput{
+		LaunchConfigurationName: to.Strp("name"),
+		ImageId:                 to.Strp("ami"),
+		InstanceType:            to.Strp("m5.large"),
+	})
+
+	// The launch template must exist
+	_, err := input.CreateVersion(ec2c, to.Strp("release"))
+	assert.Error(t, err)
+
+	assert.NoError(t, input.Create(ec2c))
+	version, err := input.CreateVersion(ec2c, to.Strp("release"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), *version)
+
+	latest, err := LatestVersion(ec2c, to.Strp("name"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), *latest)
+}
+
+func Test_Create_Data(t *testing.T) {
+	ec2c := &mocks.EC2Client{}
+	input := FromLaunchConfig(&autoscaling.CreateLaunchConfigurationInput{
+		LaunchConfigurationName: to.Strp("name"),
+		ImageId:                 to.Strp("ami"),
+		InstanceType:            to.Strp("m5.large"),
+	})
+	input.SetData("PrivateDnsNameOptions.HostnameType", "resource-name")
+
+	// The data is added to the query with the SDK fields
+	assert.NoError(t, input.Create(ec2c))
+	assert.Equal(t, "resource-name", ec2c.LaunchTemplateData["name"]["PrivateDnsNameOptions.HostnameType"])
+	assert.Equal(t, "ami", ec2c.LaunchTemplateData["name"]["ImageId"])
+	assert.Equal(t, 1, len(ec2c.CreateLaunchTemplateInputs))
+
+	input.SetData("PrivateDnsNameOptions.HostnameType", "ip-name")
+	version, err := input.CreateVersion(ec2c, to.Strp("release"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), *version)
+	assert.Equal(t, "ip-name", ec2c.LaunchTemplateData["name"]["PrivateDnsNameOptions.HostnameType"])
+
+	// Errors are returned from the request
+	input.LaunchTemplateName = to.Strp("missing")
+	_, err = input.CreateVersion(ec2c, to.Strp("release"))
+	assert.Error(t, err)
+	assert.Nil(t, ec2c.LaunchTemplateData["missing"])
+}
+
+func Test_Create_Overrides(t *testing.T) {
+	ec2c := &mocks.EC2Client{}
+	input := FromLaunchConfig(&autoscaling.CreateLaunchConfigurationInput{
+		LaunchConfigurationName: to.Strp("name"),
+		ImageId:                 to.Strp("ami"),
+		InstanceType:            to.Strp("m5.large"),
+		SecurityGroups:          []*string{to.Strp("sg")},
+		InstanceMonitoring:      &autoscaling.InstanceMonitoring{Enabled: to.Boolp(true)},
+	})
+	input.SetData("PrivateDnsNameOptions.HostnameType", "resource-name")
+
+	assert.NoError(t, input.SetOverrides(map[string]interface{}{
+		"DisableApiStop":        true,
+		"MaintenanceOptions":    map[string]interface{}{"AutoRecovery": "disabled"},
+		"CpuOptions":            map[string]interface{}{"CoreCount": float64(2), "ThreadsPerCore": float64(1)},
+		"Monitoring":            map[string]interface{}{"Enabled": false},
+		"PrivateDnsNameOptions": map[string]interface{}{"HostnameType": "ip-name", "EnableResourceNameDnsARecord": true},
+		"SecurityGroupId":       []interface{}{"sg-override"},
+		"ElasticGpuSpecification": []interface{}{
+			map[string]interface{}{"Type": "eg1.medium"},
+		},
+	}))
+
+	assert.NoError(t, input.Create(ec2c))
+	data := ec2c.LaunchTemplateData["name"]
+
+	// Overrides set the fields the SDK does not have
+	assert.Equal(t, "true", data["DisableApiStop"])
+	assert.Equal(t, "disabled", data["MaintenanceOptions.AutoRecovery"])
+	assert.Equal(t, "2", data["CpuOptions.CoreCount"])
+	assert.Equal(t, "eg1.medium", data["ElasticGpuSpecification.1.Type"])
+	assert.Equal(t, "true", data["PrivateDnsNameOptions.EnableResourceNameDnsARecord"])
+
+	// The SDK fields and Data take precedence
+	assert.Equal(t, "true", data["Monitoring.Enabled"])
+	assert.Equal(t, "resource-name", data["PrivateDnsNameOptions.HostnameType"])
+	assert.Equal(t, "sg", data["SecurityGroupId.1"])
+}
+
+func Test_SetOverrides_Errors(t *testing.T) {
+	input := &Input{}
+	assert.NoError(t, input.SetOverrides(nil))
+	assert.Error(t, input.SetOverrides(map[string]interface{}{"CpuOptions": map[string]interface{}{}}))
+	assert.Error(t, input.SetOverrides(map[string]interface{}{"SecurityGroupId": []interface{}{}}))
+	assert.Error(t, input.SetOverrides(map[string]interface{}{"DisableApiStop": nil}))
+	assert.Error(t, input.SetOverrides(map[string]interface{}{"CpuOptions": map[string]interface{}{"": "1"}}))
+}
+
+func Test_PruneVersions(t *testing.T) {
+	ec2c := &mocks.EC2Client{}
+	ec2c.AddLaunchTemplate("name", 6)
+
+	// The default version 1 is kept with the newest versions
+	deleted, err := PruneVersions(ec2c, to.Strp("name"), 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 2}, deleted)
+	assert.Equal(t, []int64{1, 4, 5, 6}, ec2c.LaunchTemplateVersions["name"])
+
+	assert.NoError(t, SetDefaultVersion(ec2c, to.Strp("name"), to.Int64p(6)))
+	deleted, err = PruneVersions(ec2c, to.Strp("name"), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{5, 4, 1}, deleted)
+	assert.Equal(t, []int64{6}, ec2c.LaunchTemplateVersions["name"])
+
+	// Nothing to prune
+	deleted, err = PruneVersions(ec2c, to.Strp("name"), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(deleted))
+	assert.Equal(t, 2, len(ec2c.DeleteLaunchTemplateVersionsInputs))
+}
+
+func Test_ForProjectConfig(t *testing.T) {
+	ec2c := &mocks.EC2Client{}
+	for name, releaseID := range map[string]*string{"release": to.Strp("r1"), "shared": nil} {
+		input := FromLaunchConfig(&autoscaling.CreateLaunchConfigurationInput{
+			LaunchConfigurationName: to.Strp(name),
+			ImageId:                 to.Strp("ami"),
+			InstanceType:            to.Strp("m5.large"),
+		})
+		input.AddTag("ProjectName", to.Strp("project"))
+		input.AddTag("ConfigName", to.Strp("config"))
+		if releaseID != nil {
+			input.AddTag("ReleaseID", releaseID)
+		}
+		assert.NoError(t, input.Create(ec2c))
+	}
+
+	templates, err := ForProjectConfig(ec2c, to.Strp("project"), to.Strp("config"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(templates))
+	assert.Equal(t, "r1", *templates["release"])
+	assert.Nil(t, templates["shared"])
+
+	templates, err = ForProjectConfig(ec2c, to.Strp("project"), to.Strp("other"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(templates))
+}
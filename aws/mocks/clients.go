@@ -1,13 +1,16 @@
 package mocks
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/coinbase/odin/aws"
-	"github.com/coinbase/step/aws/mocks"
+	"github.com/coinbase/step/utils/to"
 )
 
 // MockClients struct
 type MockClients struct {
-	S3       *mocks.MockS3Client
+	S3       *S3Client
 	ASG      *ASGClient
 	ELB      *ELBClient
 	EC2      *EC2Client
@@ -15,14 +18,26 @@ type MockClients struct {
 	CW       *CWClient
 	IAM      *IAMClient
 	SNS      *SNSClient
-	SFN      *mocks.MockSFNClient
-	DynamoDB *mocks.MockDynamoDBClient
+	Route53  *Route53Client
+	SFN      *SFNClient
+	DynamoDB *DynamoDBClient
+	Lambda   *LambdaClient
+	SSM      *SSMClient
+	KMS      *KMSClient
+	STS      *STSClient
+	HTTP     *HTTPClient
+
+	ResourceGroups *ResourceGroupsClient
+
+	mu sync.Mutex
+	// AssumedRoles are the "account/role" of every client created with a role
+	AssumedRoles map[string]bool
 }
 
 // MockAWS mock clients
 func MockAWS() *MockClients {
 	return &MockClients{
-		S3:       &mocks.MockS3Client{},
+		S3:       &S3Client{},
 		ASG:      &ASGClient{},
 		ELB:      &ELBClient{},
 		EC2:      &EC2Client{},
@@ -30,57 +45,131 @@ func MockAWS() *MockClients {
 		CW:       &CWClient{},
 		IAM:      &IAMClient{},
 		SNS:      &SNSClient{},
-		SFN:      &mocks.MockSFNClient{},
-		DynamoDB: &mocks.MockDynamoDBClient{},
+		Route53:  &Route53Client{},
+		SFN:      &SFNClient{},
+		DynamoDB: &DynamoDBClient{},
+		Lambda:   &LambdaClient{},
+		SSM:      &SSMClient{},
+		KMS:      &KMSClient{},
+		STS:      &STSClient{},
+		HTTP:     &HTTPClient{},
+
+		ResourceGroups: &ResourceGroupsClient{},
 	}
 }
 
 // S3Client returns
-func (a *MockClients) S3Client(*string, *string, *string) aws.S3API {
+func (a *MockClients) S3Client(_ *string, accountID *string, role *string) aws.S3API {
+	a.assume(accountID, role)
 	return a.S3
 }
 
 // ASGClient returns
-func (a *MockClients) ASGClient(*string, *string, *string) aws.ASGAPI {
+func (a *MockClients) ASGClient(_ *string, accountID *string, role *string) aws.ASGAPI {
+	a.assume(accountID, role)
 	return a.ASG
 }
 
 // ELBClient returns
-func (a *MockClients) ELBClient(*string, *string, *string) aws.ELBAPI {
+func (a *MockClients) ELBClient(_ *string, accountID *string, role *string) aws.ELBAPI {
+	a.assume(accountID, role)
 	return a.ELB
 }
 
 // EC2Client returns
-func (a *MockClients) EC2Client(*string, *string, *string) aws.EC2API {
+func (a *MockClients) EC2Client(_ *string, accountID *string, role *string) aws.EC2API {
+	a.assume(accountID, role)
 	return a.EC2
 }
 
 // ALBClient returns
-func (a *MockClients) ALBClient(*string, *string, *string) aws.ALBAPI {
+func (a *MockClients) ALBClient(_ *string, accountID *string, role *string) aws.ALBAPI {
+	a.assume(accountID, role)
 	return a.ALB
 }
 
 // CWClient returns
-func (a *MockClients) CWClient(*string, *string, *string) aws.CWAPI {
+func (a *MockClients) CWClient(_ *string, accountID *string, role *string) aws.CWAPI {
+	a.assume(accountID, role)
 	return a.CW
 }
 
 // IAMClient returns
-func (a *MockClients) IAMClient(*string, *string, *string) aws.IAMAPI {
+func (a *MockClients) IAMClient(_ *string, accountID *string, role *string) aws.IAMAPI {
+	a.assume(accountID, role)
 	return a.IAM
 }
 
 // SNSClient returns
-func (a *MockClients) SNSClient(*string, *string, *string) aws.SNSAPI {
+func (a *MockClients) SNSClient(_ *string, accountID *string, role *string) aws.SNSAPI {
+	a.assume(accountID, role)
 	return a.SNS
 }
 
+// Route53Client returns
+func (a *MockClients) Route53Client(_ *string, accountID *string, role *string) aws.Route53API {
+	a.assume(accountID, role)
+	return a.Route53
+}
+
 // SFNClient returns
-func (a *MockClients) SFNClient(*string, *string, *string) aws.SFNAPI {
+func (a *MockClients) SFNClient(_ *string, accountID *string, role *string) aws.SFNAPI {
+	a.assume(accountID, role)
 	return a.SFN
 }
 
 // DynamoDBClient returns
-func (a *MockClients) DynamoDBClient(*string, *string, *string) aws.DynamoDBAPI {
+func (a *MockClients) DynamoDBClient(_ *string, accountID *string, role *string) aws.DynamoDBAPI {
+	a.assume(accountID, role)
 	return a.DynamoDB
 }
+
+// LambdaClient returns
+func (a *MockClients) LambdaClient(_ *string, accountID *string, role *string) aws.LambdaAPI {
+	a.assume(accountID, role)
+	return a.Lambda
+}
+
+// SSMClient returns
+func (a *MockClients) SSMClient(_ *string, accountID *string, role *string) aws.SSMAPI {
+	a.assume(accountID, role)
+	return a.SSM
+}
+
+// KMSClient returns
+func (a *MockClients) KMSClient(_ *string, accountID *string, role *string) aws.KMSAPI {
+	a.assume(accountID, role)
+	return a.KMS
+}
+
+// STSClient returns the STS mock as the accounts role
+func (a *MockClients) STSClient(_ *string, accountID *string, role *string) aws.STSAPI {
+	a.assume(accountID, role)
+	a.STS.assumeRole(accountID, role)
+	return a.STS
+}
+
+// ResourceGroupsClient returns
+func (a *MockClients) ResourceGroupsClient(_ *string, accountID *string, role *string) aws.ResourceGroupsAPI {
+	a.assume(accountID, role)
+	return a.ResourceGroups
+}
+
+// HTTPClient returns
+func (a *MockClients) HTTPClient() aws.HTTPAPI {
+	return a.HTTP
+}
+
+// assume records the role a client is created with
+func (a *MockClients) assume(accountID *string, role *string) {
+	if role == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.AssumedRoles == nil {
+		a.AssumedRoles = map[string]bool{}
+	}
+	a.AssumedRoles[fmt.Sprintf("%v/%v", to.Strs(accountID), *role)] = true
+}
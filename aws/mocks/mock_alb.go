@@ -2,6 +2,8 @@ package mocks
 
 import (
 	"fmt"
+	"strconv"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/elbv2"
@@ -12,10 +14,28 @@ import (
 // ALBClient return
 type ALBClient struct {
 	aws.ALBAPI
+	mu sync.Mutex // Services are deployed concurrently
+	Throttler
+
 	DescribeTargetGroupsResp          map[string]*DescribeTargetGroupsResponse
 	DescribeTagsResp                  map[string]*DescribeV2TagsResponse
 	DescribeTargetHealthResp          map[string]*DescribeTargetHealthResponse
 	DescribeTargetGroupAttributesResp map[string]*DescribeTargetGroupAttributesResponse
+
+	// Security groups of each load balancer by ARN
+	LoadBalancerSecurityGroups map[string][]*string
+
+	// ListenerRules are the rules of each listener by ARN
+	ListenerRules    map[string][]*elbv2.Rule
+	ModifyRuleInputs []*elbv2.ModifyRuleInput
+
+	// UnhealthyUntil makes every target of a target group unhealthy for its first that many DescribeTargetHealth calls
+	UnhealthyUntil            map[string]int
+	describeTargetHealthCalls map[string]int
+
+	// DrainingPolls is how many DescribeTargetHealth calls a target group reports its draining targets
+	// before they finish deregistering and are unused
+	DrainingPolls map[string]int
 }
 
 // DescribeTargetGroupsResponse return
@@ -49,6 +69,13 @@ type MockTargetGroup struct {
 	ConfigName     string
 	ServiceName    string
 	AllowedService string
+
+	// DeregistrationDelay is the seconds deregistering targets drain, 30 by default
+	DeregistrationDelay int
+
+	// Protocol is the target group protocol, HTTP by default. A Network Load Balancer protocol
+	// e.g. TCP or TLS makes an NLB target group that is health checked over TCP
+	Protocol string
 }
 
 func (tg MockTargetGroup) allowedService() string {
@@ -71,6 +98,23 @@ func (tg *MockTargetGroup) init() {
 	if tg.ServiceName == "" {
 		tg.ServiceName = "service_name"
 	}
+	if tg.DeregistrationDelay == 0 {
+		tg.DeregistrationDelay = 30
+	}
+	if tg.Protocol == "" {
+		tg.Protocol = "HTTP"
+	}
+}
+
+// healthCheckProtocol is the default health check protocol of the target groups protocol
+func (tg MockTargetGroup) healthCheckProtocol() string {
+	switch tg.Protocol {
+	case "TCP", "TLS", "UDP", "TCP_UDP":
+		return "TCP"
+	case "HTTPS":
+		return "HTTPS"
+	}
+	return "HTTP"
 }
 
 // AWSTargetGroupNotFoundError return
@@ -94,10 +138,24 @@ func (m *ALBClient) init() {
 	if m.DescribeTargetGroupAttributesResp == nil {
 		m.DescribeTargetGroupAttributesResp = map[string]*DescribeTargetGroupAttributesResponse{}
 	}
+
+	if m.LoadBalancerSecurityGroups == nil {
+		m.LoadBalancerSecurityGroups = map[string][]*string{}
+	}
+
+	if m.describeTargetHealthCalls == nil {
+		m.describeTargetHealthCalls = map[string]int{}
+	}
+
+	if m.ListenerRules == nil {
+		m.ListenerRules = map[string][]*elbv2.Rule{}
+	}
 }
 
 // AddTargetGroup return
 func (m *ALBClient) AddTargetGroup(parameters MockTargetGroup) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.init()
 	parameters.init()
 
@@ -105,7 +163,12 @@ func (m *ALBClient) AddTargetGroup(parameters MockTargetGroup) {
 	m.DescribeTargetGroupsResp[name] = &DescribeTargetGroupsResponse{
 		Resp: &elbv2.DescribeTargetGroupsOutput{
 			TargetGroups: []*elbv2.TargetGroup{
-				&elbv2.TargetGroup{TargetGroupName: &name, TargetGroupArn: &name},
+				&elbv2.TargetGroup{
+					TargetGroupName:     &name,
+					TargetGroupArn:      &name,
+					Protocol:            to.Strp(parameters.Protocol),
+					HealthCheckProtocol: to.Strp(parameters.healthCheckProtocol()),
+				},
 			},
 		},
 	}
@@ -144,14 +207,186 @@ func (m *ALBClient) AddTargetGroup(parameters MockTargetGroup) {
 					Key:   to.Strp("slow_start.duration_seconds"),
 					Value: to.Strp("42"),
 				},
+				&elbv2.TargetGroupAttribute{
+					Key:   to.Strp("deregistration_delay.timeout_seconds"),
+					Value: to.Strp(fmt.Sprintf("%v", parameters.DeregistrationDelay)),
+				},
 			},
 		},
 	}
 }
 
+// SetTargetHealth sets the state, e.g. "healthy" or "unhealthy", the target group reports for the instance
+func (m *ALBClient) SetTargetHealth(name string, instanceID string, state string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	resp := m.DescribeTargetHealthResp[name].Resp
+	for _, th := range resp.TargetHealthDescriptions {
+		if *th.Target.Id == instanceID {
+			th.TargetHealth = &elbv2.TargetHealth{State: to.Strp(state)}
+			return
+		}
+	}
+
+	resp.TargetHealthDescriptions = append(resp.TargetHealthDescriptions, &elbv2.TargetHealthDescription{
+		Target:       &elbv2.TargetDescription{Id: to.Strp(instanceID)},
+		TargetHealth: &elbv2.TargetHealth{State: to.Strp(state)},
+	})
+}
+
+// AddTargetGroupLoadBalancer sets the target groups port and a load balancer with security groups forwarding to it
+func (m *ALBClient) AddTargetGroupLoadBalancer(tgName string, port int64, lbArn string, securityGroupIDs ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	for _, tg := range m.DescribeTargetGroupsResp[tgName].Resp.TargetGroups {
+		tg.Port = to.Int64p(port)
+		tg.LoadBalancerArns = append(tg.LoadBalancerArns, to.Strp(lbArn))
+	}
+
+	m.LoadBalancerSecurityGroups[lbArn] = strps(securityGroupIDs)
+}
+
+// AddListenerRule adds a rule with the priority to the listener that forwards to the target group, its ARN is returned
+func (m *ALBClient) AddListenerRule(listenerArn string, priority string, targetGroupArn string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+
+	arn := fmt.Sprintf("%v/rule/%v", listenerArn, priority)
+	m.ListenerRules[listenerArn] = append(m.ListenerRules[listenerArn], &elbv2.Rule{
+		RuleArn:  to.Strp(arn),
+		Priority: to.Strp(priority),
+		Actions: []*elbv2.Action{
+			&elbv2.Action{Type: to.Strp(elbv2.ActionTypeEnumForward), TargetGroupArn: to.Strp(targetGroupArn)},
+		},
+	})
+
+	return arn
+}
+
+// FillListener adds count rules to the listener with priorities from 1000 that forward to the target group,
+// e.g. to make a listener near its rule limit
+func (m *ALBClient) FillListener(listenerArn string, count int, targetGroupArn string) {
+	for i := 0; i < count; i++ {
+		m.AddListenerRule(listenerArn, strconv.Itoa(1000+i), targetGroupArn)
+	}
+}
+
+// findRule returns the rule with the ARN
+func (m *ALBClient) findRule(arn *string) *elbv2.Rule {
+	for _, rules := range m.ListenerRules {
+		for _, rule := range rules {
+			if to.Strs(rule.RuleArn) == to.Strs(arn) {
+				return rule
+			}
+		}
+	}
+
+	return nil
+}
+
+// DescribeRules returns the rules of the listener, or the rules by ARN, one rule per page
+func (m *ALBClient) DescribeRules(in *elbv2.DescribeRulesInput) (*elbv2.DescribeRulesOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeRules"); err != nil {
+		return nil, err
+	}
+	m.init()
+
+	if len(in.RuleArns) > 0 {
+		rules := []*elbv2.Rule{}
+		for _, arn := range in.RuleArns {
+			rule := m.findRule(arn)
+			if rule == nil {
+				return nil, awserr.New(elbv2.ErrCodeRuleNotFoundException, "RuleNotFound", nil)
+			}
+			rules = append(rules, rule)
+		}
+		return &elbv2.DescribeRulesOutput{Rules: rules}, nil
+	}
+
+	rules, ok := m.ListenerRules[to.Strs(in.ListenerArn)]
+	if !ok {
+		return nil, awserr.New(elbv2.ErrCodeListenerNotFoundException, "ListenerNotFound", nil)
+	}
+
+	page := 0
+	if in.Marker != nil {
+		page, _ = strconv.Atoi(*in.Marker)
+	}
+
+	out := &elbv2.DescribeRulesOutput{}
+	if page < len(rules) {
+		out.Rules = []*elbv2.Rule{rules[page]}
+	}
+
+	if page+1 < len(rules) {
+		out.NextMarker = to.Strp(strconv.Itoa(page + 1))
+	}
+
+	return out, nil
+}
+
+// ModifyRule replaces the actions of the rule
+func (m *ALBClient) ModifyRule(in *elbv2.ModifyRuleInput) (*elbv2.ModifyRuleOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("ModifyRule"); err != nil {
+		return nil, err
+	}
+	m.init()
+	m.ModifyRuleInputs = append(m.ModifyRuleInputs, in)
+
+	rule := m.findRule(in.RuleArn)
+	if rule == nil {
+		return nil, awserr.New(elbv2.ErrCodeRuleNotFoundException, "RuleNotFound", nil)
+	}
+
+	if in.Actions != nil {
+		rule.Actions = in.Actions
+	}
+
+	return &elbv2.ModifyRuleOutput{Rules: []*elbv2.Rule{rule}}, nil
+}
+
+// DescribeLoadBalancers return
+func (m *ALBClient) DescribeLoadBalancers(in *elbv2.DescribeLoadBalancersInput) (*elbv2.DescribeLoadBalancersOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeLoadBalancers"); err != nil {
+		return nil, err
+	}
+	m.init()
+	lbs := []*elbv2.LoadBalancer{}
+	for _, arn := range in.LoadBalancerArns {
+		sgs, ok := m.LoadBalancerSecurityGroups[*arn]
+		if !ok {
+			return nil, awserr.New(elbv2.ErrCodeLoadBalancerNotFoundException, "LoadBalancerNotFound", nil)
+		}
+		lbs = append(lbs, &elbv2.LoadBalancer{LoadBalancerArn: arn, SecurityGroups: sgs})
+	}
+	return &elbv2.DescribeLoadBalancersOutput{LoadBalancers: lbs}, nil
+}
+
 // DescribeTargetGroups return
 func (m *ALBClient) DescribeTargetGroups(in *elbv2.DescribeTargetGroupsInput) (*elbv2.DescribeTargetGroupsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeTargetGroups"); err != nil {
+		return nil, err
+	}
 	m.init()
+	if len(in.TargetGroupArns) > 0 {
+		tg := m.findTargetGroupByArn(in.TargetGroupArns[0])
+		if tg == nil {
+			return nil, AWSTargetGroupNotFoundError()
+		}
+		return &elbv2.DescribeTargetGroupsOutput{TargetGroups: []*elbv2.TargetGroup{tg}}, nil
+	}
+
 	lbName := in.Names[0]
 	resp := m.DescribeTargetGroupsResp[*lbName]
 	if resp == nil {
@@ -162,6 +397,11 @@ func (m *ALBClient) DescribeTargetGroups(in *elbv2.DescribeTargetGroupsInput) (*
 
 // DescribeTags return
 func (m *ALBClient) DescribeTags(in *elbv2.DescribeTagsInput) (*elbv2.DescribeTagsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeTags"); err != nil {
+		return nil, err
+	}
 	m.init()
 	lbName := in.ResourceArns[0]
 	resp := m.DescribeTagsResp[*lbName]
@@ -173,6 +413,11 @@ func (m *ALBClient) DescribeTags(in *elbv2.DescribeTagsInput) (*elbv2.DescribeTa
 
 // DescribeTargetHealth return
 func (m *ALBClient) DescribeTargetHealth(in *elbv2.DescribeTargetHealthInput) (*elbv2.DescribeTargetHealthOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeTargetHealth"); err != nil {
+		return nil, err
+	}
 	m.init()
 	lbName := in.TargetGroupArn
 	resp := m.DescribeTargetHealthResp[*lbName]
@@ -184,11 +429,56 @@ func (m *ALBClient) DescribeTargetHealth(in *elbv2.DescribeTargetHealthInput) (*
 		return &elbv2.DescribeTargetHealthOutput{}, nil
 	}
 
+	m.describeTargetHealthCalls[*lbName]++
+	m.drain(in, resp.Resp)
+
+	if until, ok := m.UnhealthyUntil[*lbName]; ok && m.describeTargetHealthCalls[*lbName] <= until {
+		descriptions := []*elbv2.TargetHealthDescription{}
+		for _, thd := range resp.Resp.TargetHealthDescriptions {
+			descriptions = append(descriptions, &elbv2.TargetHealthDescription{
+				Target:       thd.Target,
+				TargetHealth: &elbv2.TargetHealth{State: to.Strp("initial")},
+			})
+		}
+		return &elbv2.DescribeTargetHealthOutput{TargetHealthDescriptions: descriptions}, resp.Error
+	}
+
 	return resp.Resp, resp.Error
 }
 
+// drain counts down the DrainingPolls of a target group asked about its draining targets, then makes them unused
+func (m *ALBClient) drain(in *elbv2.DescribeTargetHealthInput, resp *elbv2.DescribeTargetHealthOutput) {
+	polls, ok := m.DrainingPolls[*in.TargetGroupArn]
+	if !ok {
+		return
+	}
+
+	asked := map[string]bool{}
+	for _, target := range in.Targets {
+		asked[to.Strs(target.Id)] = true
+	}
+
+	for _, thd := range resp.TargetHealthDescriptions {
+		if !asked[to.Strs(thd.Target.Id)] || to.Strs(thd.TargetHealth.State) != elbv2.TargetHealthStateEnumDraining {
+			continue
+		}
+
+		if polls > 0 {
+			m.DrainingPolls[*in.TargetGroupArn] = polls - 1
+			return
+		}
+
+		thd.TargetHealth = &elbv2.TargetHealth{State: to.Strp(elbv2.TargetHealthStateEnumUnused)}
+	}
+}
+
 // DescribeTargetGroupAttributes return
 func (m *ALBClient) DescribeTargetGroupAttributes(in *elbv2.DescribeTargetGroupAttributesInput) (*elbv2.DescribeTargetGroupAttributesOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeTargetGroupAttributes"); err != nil {
+		return nil, err
+	}
 	m.init()
 	arn := in.TargetGroupArn
 	resp := m.DescribeTargetGroupAttributesResp[*arn]
@@ -197,3 +487,27 @@ func (m *ALBClient) DescribeTargetGroupAttributes(in *elbv2.DescribeTargetGroupA
 	}
 	return resp.Resp, resp.Error
 }
+
+func (m *ALBClient) findTargetGroupByArn(arn *string) *elbv2.TargetGroup {
+	for _, resp := range m.DescribeTargetGroupsResp {
+		if resp.Resp == nil {
+			continue
+		}
+
+		for _, tg := range resp.Resp.TargetGroups {
+			if tg.TargetGroupArn != nil && arn != nil && *tg.TargetGroupArn == *arn {
+				return tg
+			}
+		}
+	}
+
+	return nil
+}
+
+func strps(ss []string) []*string {
+	ptrs := []*string{}
+	for _, s := range ss {
+		ptrs = append(ptrs, to.Strp(s))
+	}
+	return ptrs
+}
@@ -2,7 +2,9 @@ package mocks
 
 import (
 	"fmt"
+	"sync"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/coinbase/odin/aws"
 	"github.com/coinbase/step/utils/to"
@@ -29,6 +31,9 @@ type DescribePoliciesResponse struct {
 // ASGClient returns
 type ASGClient struct {
 	aws.ASGAPI
+	mu sync.Mutex // Services are deployed concurrently
+	Throttler
+
 	DescribeAutoScalingGroupsPageResp []DescribeAutoScalingGroupResponse
 	DescribeLaunchConfigurationsResp  map[string]*DescribeLaunchConfigurationsResponse
 	DescribePoliciesResp              map[string]*DescribePoliciesResponse
@@ -37,7 +42,60 @@ type ASGClient struct {
 	DescribeLoadBalancersOutput            *autoscaling.DescribeLoadBalancersOutput
 
 	UpdateAutoScalingGroupLastInput *autoscaling.UpdateAutoScalingGroupInput
-	DetachLoadBalancersError        error
+	UpdateAutoScalingGroupInputs    []*autoscaling.UpdateAutoScalingGroupInput
+
+	// DescribeAccountLimitsOutput defaults to a max of 200 ASGs with one per added ASG
+	DescribeAccountLimitsOutput *autoscaling.DescribeAccountLimitsOutput
+	DetachLoadBalancersError    error
+
+	DetachLoadBalancersInputs            []*autoscaling.DetachLoadBalancersInput
+	DetachLoadBalancerTargetGroupsInputs []*autoscaling.DetachLoadBalancerTargetGroupsInput
+
+	CreateOrUpdateTagsInputs []*autoscaling.CreateOrUpdateTagsInput
+	DeleteTagsInputs         []*autoscaling.DeleteTagsInput
+	DeletePolicyInputs       []*autoscaling.DeletePolicyInput
+	PutScalingPolicyInputs   []*autoscaling.PutScalingPolicyInput
+
+	PutScheduledUpdateGroupActionInputs []*autoscaling.PutScheduledUpdateGroupActionInput
+
+	// SuspendedProcesses are the processes suspended on each ASG by name
+	SuspendedProcesses map[string][]string
+
+	// TerminationPolicies are the termination policies each ASG was created with by name
+	TerminationPolicies map[string][]string
+
+	// HealthCheckTypes and DefaultCooldowns each ASG was created with by name
+	HealthCheckTypes map[string]string
+	DefaultCooldowns map[string]int64
+
+	// MaxInstanceLifetimes each ASG was created with by name, ASGs without one are missing
+	MaxInstanceLifetimes map[string]int64
+
+	// CreateAutoScalingGroupErrors fails creating the ASG of a service by ServiceName tag
+	CreateAutoScalingGroupErrors map[string]error
+
+	// TrackCreated makes created ASGs and launch configurations visible to the describe calls until they are deleted
+	TrackCreated bool
+
+	// TrackCapacity makes UpdateAutoScalingGroup set the min size and desired capacity of a described ASG,
+	// launching healthy instances up to the desired capacity
+	TrackCapacity bool
+
+	PutWarmPoolInputs    []*autoscaling.PutWarmPoolInput
+	DeleteWarmPoolInputs []*autoscaling.DeleteWarmPoolInput
+
+	StartInstanceRefreshInputs  []*autoscaling.StartInstanceRefreshInput
+	CancelInstanceRefreshInputs []*autoscaling.CancelInstanceRefreshInput
+
+	// InstanceRefreshStatuses are the statuses an ASGs instance refresh reports, one per describe call
+	// with the last repeated, a started refresh with no statuses is Successful
+	InstanceRefreshStatuses map[string][]string
+	instanceRefreshes       map[string]*autoscaling.InstanceRefresh
+
+	CreateAutoScalingGroupInputs    []*autoscaling.CreateAutoScalingGroupInput
+	DeleteAutoScalingGroupInputs    []*autoscaling.DeleteAutoScalingGroupInput
+	CreateLaunchConfigurationInputs []*autoscaling.CreateLaunchConfigurationInput
+	DeleteLaunchConfigurationInputs []*autoscaling.DeleteLaunchConfigurationInput
 }
 
 func (m *ASGClient) init() {
@@ -107,8 +165,36 @@ func MakeMockASGInstances(healthy int, unhealthy int, terming int) []*autoscalin
 	return ins
 }
 
+// MakeMockASGWaitingInstances returns instances waiting in a launching lifecycle hook
+func MakeMockASGWaitingInstances(waiting int) []*autoscaling.Instance {
+	ins := []*autoscaling.Instance{}
+	for i := 0; i < waiting; i++ {
+		ins = append(ins, &autoscaling.Instance{
+			InstanceId:     to.Strp(fmt.Sprintf("WaitingInstanceId%v", i+1)),
+			HealthStatus:   to.Strp("Healthy"),
+			LifecycleState: to.Strp("Pending:Wait"),
+		})
+	}
+	return ins
+}
+
+// MakeMockASGWarmedInstances returns instances in the ASGs warm pool
+func MakeMockASGWarmedInstances(warmed int, state string) []*autoscaling.Instance {
+	ins := []*autoscaling.Instance{}
+	for i := 0; i < warmed; i++ {
+		ins = append(ins, &autoscaling.Instance{
+			InstanceId:     to.Strp(fmt.Sprintf("WarmedInstanceId%v", i+1)),
+			HealthStatus:   to.Strp("Healthy"),
+			LifecycleState: to.Strp(fmt.Sprintf("Warmed:%v", state)),
+		})
+	}
+	return ins
+}
+
 // AddASG returns
 func (m *ASGClient) AddASG(asg *autoscaling.Group) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.init()
 	m.DescribeAutoScalingGroupsPageResp = append(m.DescribeAutoScalingGroupsPageResp,
 		DescribeAutoScalingGroupResponse{
@@ -121,13 +207,35 @@ func (m *ASGClient) AddASG(asg *autoscaling.Group) {
 	)
 }
 
+// SetLifecycleState sets the lifecycle state of the instance in every described ASG
+func (m *ASGClient) SetLifecycleState(instanceID string, state string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	for _, page := range m.DescribeAutoScalingGroupsPageResp {
+		if page.Resp == nil {
+			continue
+		}
+
+		for _, group := range page.Resp.AutoScalingGroups {
+			for _, i := range group.Instances {
+				if to.Strs(i.InstanceId) == instanceID {
+					i.LifecycleState = to.Strp(state)
+				}
+			}
+		}
+	}
+}
+
 // AddPreviousRuntimeResources returns
 func (m *ASGClient) AddPreviousRuntimeResources(projectName string, configName string, serviceName string, releaseID string) string {
 	m.init()
 
 	name := fmt.Sprintf("%v-%v-%v-%v", projectName, configName, serviceName, releaseID)
 
-	m.AddASG(MakeMockASG(name, projectName, configName, serviceName, releaseID))
+	group := MakeMockASG(name, projectName, configName, serviceName, releaseID)
+	group.LaunchConfigurationName = to.Strp(name)
+	m.AddASG(group)
 
 	m.DescribeLaunchConfigurationsResp[name] = &DescribeLaunchConfigurationsResponse{
 		Resp: &autoscaling.DescribeLaunchConfigurationsOutput{
@@ -141,6 +249,7 @@ func (m *ASGClient) AddPreviousRuntimeResources(projectName string, configName s
 		Resp: &autoscaling.DescribePoliciesOutput{
 			ScalingPolicies: []*autoscaling.ScalingPolicy{
 				&autoscaling.ScalingPolicy{
+					PolicyName: to.Strp("VeryEmbeddedPolicy"),
 					Alarms: []*autoscaling.Alarm{
 						&autoscaling.Alarm{
 							AlarmName: to.Strp("VeryEmbeddedAlarm"),
@@ -154,8 +263,49 @@ func (m *ASGClient) AddPreviousRuntimeResources(projectName string, configName s
 	return name
 }
 
+// AddRetainedRuntimeResources adds the ASG of a previous release retained at zero
+func (m *ASGClient) AddRetainedRuntimeResources(projectName string, configName string, serviceName string, releaseID string, retainedAt string) string {
+	name := m.AddPreviousRuntimeResources(projectName, configName, serviceName, releaseID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	group := m.DescribeAutoScalingGroupsPageResp[len(m.DescribeAutoScalingGroupsPageResp)-1].Resp.AutoScalingGroups[0]
+	group.Instances = []*autoscaling.Instance{}
+	group.LoadBalancerNames = []*string{}
+	group.TargetGroupARNs = []*string{}
+	group.MinSize = to.Int64p(0)
+	group.MaxSize = to.Int64p(0)
+	group.DesiredCapacity = to.Int64p(0)
+	group.Tags = append(group.Tags, &autoscaling.TagDescription{Key: to.Strp("RetainedAt"), Value: to.Strp(retainedAt)})
+
+	return name
+}
+
+// DescribeAccountLimits returns
+func (m *ASGClient) DescribeAccountLimits(input *autoscaling.DescribeAccountLimitsInput) (*autoscaling.DescribeAccountLimitsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeAccountLimits"); err != nil {
+		return nil, err
+	}
+
+	if m.DescribeAccountLimitsOutput != nil {
+		return m.DescribeAccountLimitsOutput, nil
+	}
+
+	return &autoscaling.DescribeAccountLimitsOutput{
+		MaxNumberOfAutoScalingGroups: to.Int64p(200),
+		NumberOfAutoScalingGroups:    to.Int64p(int64(len(m.DescribeAutoScalingGroupsPageResp))),
+	}, nil
+}
+
 // DescribeAutoScalingGroupsPages returns
 func (m *ASGClient) DescribeAutoScalingGroupsPages(input *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeAutoScalingGroupsPages"); err != nil {
+		return err
+	}
 	m.init()
 	// Loop through all autoscaling groups, 1 per page
 	var cont bool
@@ -164,6 +314,10 @@ func (m *ASGClient) DescribeAutoScalingGroupsPages(input *autoscaling.DescribeAu
 			return page.Error
 		}
 
+		if m.TrackCreated && (m.deleted(page.Resp) || !m.named(page.Resp, input.AutoScalingGroupNames)) {
+			continue
+		}
+
 		cont = fn(page.Resp, false)
 
 		if !cont {
@@ -182,16 +336,159 @@ func (m *ASGClient) DescribeAutoScalingGroupsPages(input *autoscaling.DescribeAu
 
 // DeleteAutoScalingGroup returns
 func (m *ASGClient) DeleteAutoScalingGroup(input *autoscaling.DeleteAutoScalingGroupInput) (*autoscaling.DeleteAutoScalingGroupOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DeleteAutoScalingGroup"); err != nil {
+		return nil, err
+	}
+	m.DeleteAutoScalingGroupInputs = append(m.DeleteAutoScalingGroupInputs, input)
 	return nil, nil
 }
 
 // CreateAutoScalingGroup returns
 func (m *ASGClient) CreateAutoScalingGroup(input *autoscaling.CreateAutoScalingGroupInput) (*autoscaling.CreateAutoScalingGroupOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("CreateAutoScalingGroup"); err != nil {
+		return nil, err
+	}
+
+	tags := []*autoscaling.TagDescription{}
+	serviceName := ""
+	for _, tag := range input.Tags {
+		tags = append(tags, &autoscaling.TagDescription{Key: tag.Key, Value: tag.Value})
+		if to.Strs(tag.Key) == "ServiceName" {
+			serviceName = to.Strs(tag.Value)
+		}
+	}
+
+	if err := m.CreateAutoScalingGroupErrors[serviceName]; err != nil {
+		return nil, err
+	}
+
+	m.CreateAutoScalingGroupInputs = append(m.CreateAutoScalingGroupInputs, input)
+
+	if m.TerminationPolicies == nil {
+		m.TerminationPolicies = map[string][]string{}
+	}
+	m.TerminationPolicies[to.Strs(input.AutoScalingGroupName)] = to.StrSlice(input.TerminationPolicies)
+
+	if m.HealthCheckTypes == nil {
+		m.HealthCheckTypes = map[string]string{}
+	}
+	m.HealthCheckTypes[to.Strs(input.AutoScalingGroupName)] = to.Strs(input.HealthCheckType)
+
+	if m.DefaultCooldowns == nil {
+		m.DefaultCooldowns = map[string]int64{}
+	}
+	if input.DefaultCooldown != nil {
+		m.DefaultCooldowns[to.Strs(input.AutoScalingGroupName)] = *input.DefaultCooldown
+	}
+
+	if m.MaxInstanceLifetimes == nil {
+		m.MaxInstanceLifetimes = map[string]int64{}
+	}
+	if input.MaxInstanceLifetime != nil {
+		m.MaxInstanceLifetimes[to.Strs(input.AutoScalingGroupName)] = *input.MaxInstanceLifetime
+	}
+
+	if m.TrackCreated {
+		m.init()
+
+		// The created ASG launches its desired capacity of healthy instances
+		desired := 0
+		if input.DesiredCapacity != nil {
+			desired = int(*input.DesiredCapacity)
+		}
+
+		m.DescribeAutoScalingGroupsPageResp = append(m.DescribeAutoScalingGroupsPageResp, DescribeAutoScalingGroupResponse{
+			Resp: &autoscaling.DescribeAutoScalingGroupsOutput{
+				AutoScalingGroups: []*autoscaling.Group{
+					&autoscaling.Group{
+						AutoScalingGroupName:    input.AutoScalingGroupName,
+						LaunchConfigurationName: input.LaunchConfigurationName,
+						MixedInstancesPolicy:    input.MixedInstancesPolicy,
+						LaunchTemplate:          input.LaunchTemplate,
+						TargetGroupARNs:         input.TargetGroupARNs,
+						LoadBalancerNames:       input.LoadBalancerNames,
+						MinSize:                 input.MinSize,
+						MaxSize:                 input.MaxSize,
+						DesiredCapacity:         input.DesiredCapacity,
+						Instances:               MakeMockASGInstances(desired, 0, 0),
+						Tags:                    tags,
+					},
+				},
+			},
+		})
+	}
+
 	return nil, nil
 }
 
+// CreatedTags returns the tags the ASG was created with by key
+func (m *ASGClient) CreatedTags(asgName string) map[string]*autoscaling.Tag {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tags := map[string]*autoscaling.Tag{}
+	for _, input := range m.CreateAutoScalingGroupInputs {
+		if to.Strs(input.AutoScalingGroupName) != asgName {
+			continue
+		}
+
+		for _, tag := range input.Tags {
+			tags[to.Strs(tag.Key)] = tag
+		}
+	}
+
+	return tags
+}
+
+// named returns true if the page has a group in names, or names is empty
+func (m *ASGClient) named(page *autoscaling.DescribeAutoScalingGroupsOutput, names []*string) bool {
+	if page == nil || len(names) == 0 {
+		return true
+	}
+
+	for _, group := range page.AutoScalingGroups {
+		for _, name := range names {
+			if to.Strs(name) == to.Strs(group.AutoScalingGroupName) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// deleted returns true if every group in the page has been deleted
+func (m *ASGClient) deleted(page *autoscaling.DescribeAutoScalingGroupsOutput) bool {
+	if page == nil || len(page.AutoScalingGroups) == 0 {
+		return false
+	}
+
+	for _, group := range page.AutoScalingGroups {
+		found := false
+		for _, del := range m.DeleteAutoScalingGroupInputs {
+			if to.Strs(del.AutoScalingGroupName) == to.Strs(group.AutoScalingGroupName) {
+				found = true
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
 // DescribeLaunchConfigurations returns
 func (m *ASGClient) DescribeLaunchConfigurations(in *autoscaling.DescribeLaunchConfigurationsInput) (*autoscaling.DescribeLaunchConfigurationsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeLaunchConfigurations"); err != nil {
+		return nil, err
+	}
 	m.init()
 	lcName := in.LaunchConfigurationNames[0]
 	resp := m.DescribeLaunchConfigurationsResp[*lcName]
@@ -203,16 +500,50 @@ func (m *ASGClient) DescribeLaunchConfigurations(in *autoscaling.DescribeLaunchC
 
 // CreateLaunchConfiguration returns
 func (m *ASGClient) CreateLaunchConfiguration(input *autoscaling.CreateLaunchConfigurationInput) (*autoscaling.CreateLaunchConfigurationOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("CreateLaunchConfiguration"); err != nil {
+		return nil, err
+	}
+	m.CreateLaunchConfigurationInputs = append(m.CreateLaunchConfigurationInputs, input)
+
+	if m.TrackCreated {
+		m.init()
+		m.DescribeLaunchConfigurationsResp[to.Strs(input.LaunchConfigurationName)] = &DescribeLaunchConfigurationsResponse{
+			Resp: &autoscaling.DescribeLaunchConfigurationsOutput{
+				LaunchConfigurations: []*autoscaling.LaunchConfiguration{
+					&autoscaling.LaunchConfiguration{LaunchConfigurationName: input.LaunchConfigurationName},
+				},
+			},
+		}
+	}
+
 	return nil, nil
 }
 
 // DeleteLaunchConfiguration returns
 func (m *ASGClient) DeleteLaunchConfiguration(input *autoscaling.DeleteLaunchConfigurationInput) (*autoscaling.DeleteLaunchConfigurationOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DeleteLaunchConfiguration"); err != nil {
+		return nil, err
+	}
+	m.DeleteLaunchConfigurationInputs = append(m.DeleteLaunchConfigurationInputs, input)
+
+	if m.TrackCreated {
+		delete(m.DescribeLaunchConfigurationsResp, to.Strs(input.LaunchConfigurationName))
+	}
+
 	return nil, nil
 }
 
 // DescribePolicies returns
 func (m *ASGClient) DescribePolicies(in *autoscaling.DescribePoliciesInput) (*autoscaling.DescribePoliciesOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribePolicies"); err != nil {
+		return nil, err
+	}
 	m.init()
 	resp := m.DescribePoliciesResp[*in.AutoScalingGroupName]
 	if resp == nil {
@@ -223,23 +554,62 @@ func (m *ASGClient) DescribePolicies(in *autoscaling.DescribePoliciesInput) (*au
 
 // EnableMetricsCollection returns
 func (m *ASGClient) EnableMetricsCollection(input *autoscaling.EnableMetricsCollectionInput) (*autoscaling.EnableMetricsCollectionOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("EnableMetricsCollection"); err != nil {
+		return nil, err
+	}
 	return nil, nil
 }
 
 // PutScalingPolicy returns
 func (m *ASGClient) PutScalingPolicy(input *autoscaling.PutScalingPolicyInput) (*autoscaling.PutScalingPolicyOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("PutScalingPolicy"); err != nil {
+		return nil, err
+	}
+	m.PutScalingPolicyInputs = append(m.PutScalingPolicyInputs, input)
 	return &autoscaling.PutScalingPolicyOutput{PolicyARN: to.Strp("arn")}, nil
 }
 
+// PutScheduledUpdateGroupAction returns
+func (m *ASGClient) PutScheduledUpdateGroupAction(input *autoscaling.PutScheduledUpdateGroupActionInput) (*autoscaling.PutScheduledUpdateGroupActionOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("PutScheduledUpdateGroupAction"); err != nil {
+		return nil, err
+	}
+	m.PutScheduledUpdateGroupActionInputs = append(m.PutScheduledUpdateGroupActionInputs, input)
+	return &autoscaling.PutScheduledUpdateGroupActionOutput{}, nil
+}
+
 func (m *ASGClient) DetachLoadBalancers(input *autoscaling.DetachLoadBalancersInput) (*autoscaling.DetachLoadBalancersOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DetachLoadBalancers"); err != nil {
+		return nil, err
+	}
+	m.DetachLoadBalancersInputs = append(m.DetachLoadBalancersInputs, input)
 	return nil, m.DetachLoadBalancersError
 }
 
 func (m *ASGClient) DetachLoadBalancerTargetGroups(input *autoscaling.DetachLoadBalancerTargetGroupsInput) (*autoscaling.DetachLoadBalancerTargetGroupsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DetachLoadBalancerTargetGroups"); err != nil {
+		return nil, err
+	}
+	m.DetachLoadBalancerTargetGroupsInputs = append(m.DetachLoadBalancerTargetGroupsInputs, input)
 	return nil, nil
 }
 
 func (m *ASGClient) DescribeLoadBalancerTargetGroups(input *autoscaling.DescribeLoadBalancerTargetGroupsInput) (*autoscaling.DescribeLoadBalancerTargetGroupsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeLoadBalancerTargetGroups"); err != nil {
+		return nil, err
+	}
 	if m.DescribeLoadBalancerTargetGroupsOutput != nil {
 		return m.DescribeLoadBalancerTargetGroupsOutput, nil
 	}
@@ -247,6 +617,11 @@ func (m *ASGClient) DescribeLoadBalancerTargetGroups(input *autoscaling.Describe
 }
 
 func (m *ASGClient) DescribeLoadBalancers(input *autoscaling.DescribeLoadBalancersInput) (*autoscaling.DescribeLoadBalancersOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeLoadBalancers"); err != nil {
+		return nil, err
+	}
 	if m.DescribeLoadBalancersOutput != nil {
 		return m.DescribeLoadBalancersOutput, nil
 	}
@@ -254,6 +629,246 @@ func (m *ASGClient) DescribeLoadBalancers(input *autoscaling.DescribeLoadBalance
 }
 
 func (m *ASGClient) UpdateAutoScalingGroup(input *autoscaling.UpdateAutoScalingGroupInput) (*autoscaling.UpdateAutoScalingGroupOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("UpdateAutoScalingGroup"); err != nil {
+		return nil, err
+	}
 	m.UpdateAutoScalingGroupLastInput = input
+	m.UpdateAutoScalingGroupInputs = append(m.UpdateAutoScalingGroupInputs, input)
+
+	if m.TrackCapacity {
+		m.updateCapacity(input)
+	}
+
+	return nil, nil
+}
+
+// updateCapacity sets the min size and desired capacity of the last described ASG with the name
+func (m *ASGClient) updateCapacity(input *autoscaling.UpdateAutoScalingGroupInput) {
+	for i := len(m.DescribeAutoScalingGroupsPageResp) - 1; i >= 0; i-- {
+		page := m.DescribeAutoScalingGroupsPageResp[i].Resp
+		if page == nil {
+			continue
+		}
+
+		for _, group := range page.AutoScalingGroups {
+			if to.Strs(group.AutoScalingGroupName) != to.Strs(input.AutoScalingGroupName) {
+				continue
+			}
+
+			if input.MinSize != nil {
+				group.MinSize = input.MinSize
+			}
+
+			if input.DesiredCapacity != nil {
+				group.DesiredCapacity = input.DesiredCapacity
+				for x := len(group.Instances); int64(x) < *input.DesiredCapacity; x++ {
+					group.Instances = append(group.Instances, &autoscaling.Instance{
+						InstanceId:     to.Strp(fmt.Sprintf("InstanceId%v", x+1)),
+						HealthStatus:   to.Strp("Healthy"),
+						LifecycleState: to.Strp("InService"),
+					})
+				}
+			}
+
+			return
+		}
+	}
+}
+
+// CreateOrUpdateTags returns
+func (m *ASGClient) CreateOrUpdateTags(input *autoscaling.CreateOrUpdateTagsInput) (*autoscaling.CreateOrUpdateTagsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("CreateOrUpdateTags"); err != nil {
+		return nil, err
+	}
+	m.CreateOrUpdateTagsInputs = append(m.CreateOrUpdateTagsInputs, input)
+	return nil, nil
+}
+
+// DeleteTags returns
+func (m *ASGClient) DeleteTags(input *autoscaling.DeleteTagsInput) (*autoscaling.DeleteTagsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DeleteTags"); err != nil {
+		return nil, err
+	}
+	m.DeleteTagsInputs = append(m.DeleteTagsInputs, input)
 	return nil, nil
 }
+
+// DeletePolicy returns
+func (m *ASGClient) DeletePolicy(input *autoscaling.DeletePolicyInput) (*autoscaling.DeletePolicyOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DeletePolicy"); err != nil {
+		return nil, err
+	}
+	m.DeletePolicyInputs = append(m.DeletePolicyInputs, input)
+	return nil, nil
+}
+
+// SuspendProcesses records the processes as suspended on the ASG
+func (m *ASGClient) SuspendProcesses(input *autoscaling.ScalingProcessQuery) (*autoscaling.SuspendProcessesOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("SuspendProcesses"); err != nil {
+		return nil, err
+	}
+
+	if m.SuspendedProcesses == nil {
+		m.SuspendedProcesses = map[string][]string{}
+	}
+
+	name := to.Strs(input.AutoScalingGroupName)
+	for _, process := range to.StrSlice(input.ScalingProcesses) {
+		if !containsStr(m.SuspendedProcesses[name], process) {
+			m.SuspendedProcesses[name] = append(m.SuspendedProcesses[name], process)
+		}
+	}
+
+	return nil, nil
+}
+
+// ResumeProcesses removes the processes from the ASGs suspended processes
+func (m *ASGClient) ResumeProcesses(input *autoscaling.ScalingProcessQuery) (*autoscaling.ResumeProcessesOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("ResumeProcesses"); err != nil {
+		return nil, err
+	}
+
+	name := to.Strs(input.AutoScalingGroupName)
+	resumed := to.StrSlice(input.ScalingProcesses)
+
+	suspended := []string{}
+	for _, process := range m.SuspendedProcesses[name] {
+		if !containsStr(resumed, process) {
+			suspended = append(suspended, process)
+		}
+	}
+
+	if len(suspended) == 0 {
+		delete(m.SuspendedProcesses, name)
+	} else {
+		m.SuspendedProcesses[name] = suspended
+	}
+
+	return nil, nil
+}
+
+func containsStr(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// PutWarmPool returns
+func (m *ASGClient) PutWarmPool(input *autoscaling.PutWarmPoolInput) (*autoscaling.PutWarmPoolOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("PutWarmPool"); err != nil {
+		return nil, err
+	}
+	m.PutWarmPoolInputs = append(m.PutWarmPoolInputs, input)
+	return &autoscaling.PutWarmPoolOutput{}, nil
+}
+
+// DeleteWarmPool returns
+func (m *ASGClient) DeleteWarmPool(input *autoscaling.DeleteWarmPoolInput) (*autoscaling.DeleteWarmPoolOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DeleteWarmPool"); err != nil {
+		return nil, err
+	}
+	m.DeleteWarmPoolInputs = append(m.DeleteWarmPoolInputs, input)
+	return &autoscaling.DeleteWarmPoolOutput{}, nil
+}
+
+// StartInstanceRefresh starts a refresh of the ASG, only one can be active at a time
+func (m *ASGClient) StartInstanceRefresh(input *autoscaling.StartInstanceRefreshInput) (*autoscaling.StartInstanceRefreshOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("StartInstanceRefresh"); err != nil {
+		return nil, err
+	}
+	m.StartInstanceRefreshInputs = append(m.StartInstanceRefreshInputs, input)
+
+	if m.instanceRefreshes == nil {
+		m.instanceRefreshes = map[string]*autoscaling.InstanceRefresh{}
+	}
+
+	name := to.Strs(input.AutoScalingGroupName)
+	if active := m.instanceRefreshes[name]; active != nil && to.Strs(active.Status) == autoscaling.InstanceRefreshStatusInProgress {
+		return nil, awserr.New(autoscaling.ErrCodeInstanceRefreshInProgressFault, "Instance refresh in progress", nil)
+	}
+
+	refresh := &autoscaling.InstanceRefresh{
+		AutoScalingGroupName: input.AutoScalingGroupName,
+		InstanceRefreshId:    to.Strp(fmt.Sprintf("refresh-%v", len(m.StartInstanceRefreshInputs))),
+		Status:               to.Strp(autoscaling.InstanceRefreshStatusPending),
+	}
+	m.instanceRefreshes[name] = refresh
+
+	return &autoscaling.StartInstanceRefreshOutput{InstanceRefreshId: refresh.InstanceRefreshId}, nil
+}
+
+// DescribeInstanceRefreshes returns the ASGs started refresh with its next status
+func (m *ASGClient) DescribeInstanceRefreshes(input *autoscaling.DescribeInstanceRefreshesInput) (*autoscaling.DescribeInstanceRefreshesOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeInstanceRefreshes"); err != nil {
+		return nil, err
+	}
+
+	name := to.Strs(input.AutoScalingGroupName)
+	refresh := m.instanceRefreshes[name]
+	if refresh == nil {
+		return &autoscaling.DescribeInstanceRefreshesOutput{}, nil
+	}
+
+	if to.Strs(refresh.Status) != autoscaling.InstanceRefreshStatusCancelled {
+		statuses := m.InstanceRefreshStatuses[name]
+		switch len(statuses) {
+		case 0:
+			refresh.Status = to.Strp(autoscaling.InstanceRefreshStatusSuccessful)
+		case 1:
+			refresh.Status = to.Strp(statuses[0])
+		default:
+			refresh.Status = to.Strp(statuses[0])
+			m.InstanceRefreshStatuses[name] = statuses[1:]
+		}
+	}
+
+	return &autoscaling.DescribeInstanceRefreshesOutput{
+		InstanceRefreshes: []*autoscaling.InstanceRefresh{refresh},
+	}, nil
+}
+
+// CancelInstanceRefresh cancels the ASGs refresh, it errors if none is active
+func (m *ASGClient) CancelInstanceRefresh(input *autoscaling.CancelInstanceRefreshInput) (*autoscaling.CancelInstanceRefreshOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("CancelInstanceRefresh"); err != nil {
+		return nil, err
+	}
+	m.CancelInstanceRefreshInputs = append(m.CancelInstanceRefreshInputs, input)
+
+	refresh := m.instanceRefreshes[to.Strs(input.AutoScalingGroupName)]
+	if refresh == nil {
+		return nil, awserr.New(autoscaling.ErrCodeActiveInstanceRefreshNotFoundFault, "No active instance refresh", nil)
+	}
+
+	switch to.Strs(refresh.Status) {
+	case autoscaling.InstanceRefreshStatusPending, autoscaling.InstanceRefreshStatusInProgress:
+		refresh.Status = to.Strp(autoscaling.InstanceRefreshStatusCancelled)
+		return &autoscaling.CancelInstanceRefreshOutput{InstanceRefreshId: refresh.InstanceRefreshId}, nil
+	}
+
+	return nil, awserr.New(autoscaling.ErrCodeActiveInstanceRefreshNotFoundFault, "No active instance refresh", nil)
+}
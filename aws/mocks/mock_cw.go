@@ -3,19 +3,111 @@ package mocks
 import (
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+	"sync"
 )
 
 // CWClient struct
 type CWClient struct {
 	aws.CWAPI
+	mu sync.Mutex // Services are deployed concurrently
+	Throttler
+
+	AlarmStates map[string]string
+
+	// Each DescribeAlarms moves an alarm to its next state, the last state is kept
+	AlarmStateSequences map[string][]string
+
+	PutMetricAlarmInputs []*cloudwatch.PutMetricAlarmInput
+	PutMetricDataInputs  []*cloudwatch.PutMetricDataInput
+	DeleteAlarmsInputs   []*cloudwatch.DeleteAlarmsInput
+}
+
+func (m *CWClient) init() {
+	if m.AlarmStates == nil {
+		m.AlarmStates = map[string]string{}
+	}
+	if m.AlarmStateSequences == nil {
+		m.AlarmStateSequences = map[string][]string{}
+	}
+}
+
+// AddAlarm sets the state of an alarm
+func (m *CWClient) AddAlarm(name string, state string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.AlarmStates[name] = state
+}
+
+// AddAlarmStates sets the states an alarm moves through, e.g. to flip to ALARM mid-deploy
+func (m *CWClient) AddAlarmStates(name string, states ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.AlarmStateSequences[name] = states
+}
+
+// DescribeAlarms returns
+func (m *CWClient) DescribeAlarms(input *cloudwatch.DescribeAlarmsInput) (*cloudwatch.DescribeAlarmsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeAlarms"); err != nil {
+		return nil, err
+	}
+	m.init()
+	alarms := []*cloudwatch.MetricAlarm{}
+	for _, name := range input.AlarmNames {
+		if states := m.AlarmStateSequences[*name]; len(states) > 0 {
+			m.AlarmStates[*name] = states[0]
+			if len(states) > 1 {
+				m.AlarmStateSequences[*name] = states[1:]
+			}
+		}
+
+		state, ok := m.AlarmStates[*name]
+		if !ok {
+			continue
+		}
+
+		alarms = append(alarms, &cloudwatch.MetricAlarm{
+			AlarmName:  name,
+			StateValue: to.Strp(state),
+		})
+	}
+
+	return &cloudwatch.DescribeAlarmsOutput{MetricAlarms: alarms}, nil
 }
 
 // DeleteAlarms returns
 func (m *CWClient) DeleteAlarms(input *cloudwatch.DeleteAlarmsInput) (*cloudwatch.DeleteAlarmsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DeleteAlarms"); err != nil {
+		return nil, err
+	}
+	m.DeleteAlarmsInputs = append(m.DeleteAlarmsInputs, input)
 	return nil, nil
 }
 
 // PutMetricAlarm returns
 func (m *CWClient) PutMetricAlarm(input *cloudwatch.PutMetricAlarmInput) (*cloudwatch.PutMetricAlarmOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("PutMetricAlarm"); err != nil {
+		return nil, err
+	}
+	m.PutMetricAlarmInputs = append(m.PutMetricAlarmInputs, input)
 	return nil, nil
 }
+
+// PutMetricData records the metric data
+func (m *CWClient) PutMetricData(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("PutMetricData"); err != nil {
+		return nil, err
+	}
+	m.PutMetricDataInputs = append(m.PutMetricDataInputs, input)
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
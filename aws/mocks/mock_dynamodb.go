@@ -0,0 +1,68 @@
+package mocks
+
+import (
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/coinbase/odin/aws"
+)
+
+// DynamoDBClient returns
+type DynamoDBClient struct {
+	aws.DynamoDBAPI
+
+	PutItemInputs    []*dynamodb.PutItemInput
+	DeleteItemInputs []*dynamodb.DeleteItemInput
+
+	// Locks maps a lock key to the UUID holding it
+	Locks map[string]string
+}
+
+func (m *DynamoDBClient) init() {
+	if m.Locks == nil {
+		m.Locks = map[string]string{}
+	}
+}
+
+// AddLock adds a lock held by uuid
+func (m *DynamoDBClient) AddLock(key string, uuid string) {
+	m.init()
+	m.Locks[key] = uuid
+}
+
+// PutItem returns a conditional check error if the lock is held by another UUID
+func (m *DynamoDBClient) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	m.init()
+	m.PutItemInputs = append(m.PutItemInputs, input)
+
+	key, id := attributeS(input.Item["key"]), attributeS(input.Item["id"])
+	if held, ok := m.Locks[key]; ok && held != id {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "lock exists", nil)
+	}
+
+	m.Locks[key] = id
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// DeleteItem returns a conditional check error if the lock is not held by the UUID in the condition
+func (m *DynamoDBClient) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	m.init()
+	m.DeleteItemInputs = append(m.DeleteItemInputs, input)
+
+	key := attributeS(input.Key["key"])
+	if input.ConditionExpression != nil {
+		held, ok := m.Locks[key]
+		if !ok || held != attributeS(input.ExpressionAttributeValues[":0"]) {
+			return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "lock not held", nil)
+		}
+	}
+
+	delete(m.Locks, key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func attributeS(av *dynamodb.AttributeValue) string {
+	if av == nil || av.S == nil {
+		return ""
+	}
+	return *av.S
+}
@@ -2,7 +2,18 @@ package mocks
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/private/protocol/ec2query"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/coinbase/odin/aws"
 	"github.com/coinbase/step/utils/to"
@@ -29,10 +40,53 @@ type DescribeSecurityGroupsResponse struct {
 // EC2Client returns
 type EC2Client struct {
 	aws.EC2API
+	mu sync.Mutex // Services are deployed concurrently
+	Throttler
+
 	DescribeSecurityGroupsResp map[string]*DescribeSecurityGroupsResponse
 	DescribeSubnetsResp        *DescribeSubnetsResponse
 	DescribeImagesResp         *DescribeImagesResponse
 	PlacementGroups            []*ec2.PlacementGroup
+	CapacityReservations       []*ec2.CapacityReservation
+	KeyPairs                   []*ec2.KeyPairInfo
+	Instances                  map[string]*ec2.Instance
+
+	// Instance types not offered in any availability zone
+	UnofferedInstanceTypes []string
+
+	// Availability zones by instance type, an instance type listed is only offered in its availability zones
+	InstanceTypeOfferings map[string][]string
+
+	// Added instance types by name, others support x86_64 and hvm
+	InstanceTypes map[string]*ec2.InstanceTypeInfo
+
+	CreateLaunchTemplateInputs []*ec2.CreateLaunchTemplateInput
+	DeleteLaunchTemplateInputs []*ec2.DeleteLaunchTemplateInput
+
+	// Metadata options of created launch templates by name
+	LaunchTemplateMetadataOptions map[string]*ec2.LaunchTemplateInstanceMetadataOptionsRequest
+
+	// Detailed monitoring of created launch templates by name
+	LaunchTemplateMonitoring map[string]*ec2.LaunchTemplatesMonitoringRequest
+
+	// LaunchTemplatePlacements are the placements, e.g. the tenancy, of each launch template by name
+	LaunchTemplatePlacements map[string]*ec2.LaunchTemplatePlacementRequest
+
+	// LaunchTemplateNetworkInterfaces are the network interfaces of each launch template by name
+	LaunchTemplateNetworkInterfaces map[string][]*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest
+
+	// LaunchTemplateData are the LaunchTemplateData query parameters, without the prefix, of each launch template
+	// created or versioned with a request by name, e.g. "PrivateDnsNameOptions.HostnameType"
+	LaunchTemplateData map[string]map[string]string
+
+	CreateLaunchTemplateVersionInputs  []*ec2.CreateLaunchTemplateVersionInput
+	DeleteLaunchTemplateVersionsInputs []*ec2.DeleteLaunchTemplateVersionsInput
+
+	// LaunchTemplateVersions are the version numbers of each launch template by name
+	LaunchTemplateVersions map[string][]int64
+
+	// DefaultLaunchTemplateVersions are the default version of each launch template by name
+	DefaultLaunchTemplateVersions map[string]int64
 }
 
 func (m *EC2Client) init() {
@@ -42,10 +96,89 @@ func (m *EC2Client) init() {
 	if m.PlacementGroups == nil {
 		m.PlacementGroups = []*ec2.PlacementGroup{}
 	}
+	if m.Instances == nil {
+		m.Instances = map[string]*ec2.Instance{}
+	}
+	if m.InstanceTypes == nil {
+		m.InstanceTypes = map[string]*ec2.InstanceTypeInfo{}
+	}
+	if m.LaunchTemplateMetadataOptions == nil {
+		m.LaunchTemplateMetadataOptions = map[string]*ec2.LaunchTemplateInstanceMetadataOptionsRequest{}
+	}
+	if m.LaunchTemplateMonitoring == nil {
+		m.LaunchTemplateMonitoring = map[string]*ec2.LaunchTemplatesMonitoringRequest{}
+	}
+	if m.LaunchTemplatePlacements == nil {
+		m.LaunchTemplatePlacements = map[string]*ec2.LaunchTemplatePlacementRequest{}
+	}
+	if m.LaunchTemplateNetworkInterfaces == nil {
+		m.LaunchTemplateNetworkInterfaces = map[string][]*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{}
+	}
+	if m.LaunchTemplateData == nil {
+		m.LaunchTemplateData = map[string]map[string]string{}
+	}
+	if m.LaunchTemplateVersions == nil {
+		m.LaunchTemplateVersions = map[string][]int64{}
+	}
+	if m.DefaultLaunchTemplateVersions == nil {
+		m.DefaultLaunchTemplateVersions = map[string]int64{}
+	}
+}
+
+// AddInstance adds an instance launched at launchTime
+func (m *EC2Client) AddInstance(id string, launchTime time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.Instances[id] = &ec2.Instance{InstanceId: to.Strp(id), LaunchTime: to.Timep(launchTime)}
+}
+
+// SetPrivateIP sets the private IP address of the instance, adding it if it is missing
+func (m *EC2Client) SetPrivateIP(id string, ip string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	if _, ok := m.Instances[id]; !ok {
+		m.Instances[id] = &ec2.Instance{InstanceId: to.Strp(id), LaunchTime: to.Timep(time.Now())}
+	}
+	m.Instances[id].PrivateIpAddress = to.Strp(ip)
+}
+
+// AddSpotInterruption adds an instance terminated by a spot interruption
+func (m *EC2Client) AddSpotInterruption(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.Instances[id] = &ec2.Instance{
+		InstanceId:  to.Strp(id),
+		LaunchTime:  to.Timep(time.Now()),
+		StateReason: &ec2.StateReason{Code: to.Strp("Server.SpotInstanceTermination")},
+	}
+}
+
+// DescribeInstancesPages returns the added instances in one page
+func (m *EC2Client) DescribeInstancesPages(in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeInstancesPages"); err != nil {
+		return err
+	}
+	m.init()
+	instances := []*ec2.Instance{}
+	for _, id := range in.InstanceIds {
+		if i, ok := m.Instances[*id]; ok {
+			instances = append(instances, i)
+		}
+	}
+
+	fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{&ec2.Reservation{Instances: instances}}}, true)
+	return nil
 }
 
 // AddSecurityGroup returns
 func (m *EC2Client) AddSecurityGroup(name string, projectName string, configName string, serviceName string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.init()
 	m.DescribeSecurityGroupsResp[name] = &DescribeSecurityGroupsResponse{
 		Resp: &ec2.DescribeSecurityGroupsOutput{
@@ -57,13 +190,33 @@ func (m *EC2Client) AddSecurityGroup(name string, projectName string, configName
 	}
 }
 
+// AddSecurityGroupIngress allows TCP on port from the source security group to the added security group
+func (m *EC2Client) AddSecurityGroupIngress(name string, port int64, sourceGroupID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	for _, group := range m.DescribeSecurityGroupsResp[name].Resp.SecurityGroups {
+		group.IpPermissions = append(group.IpPermissions, &ec2.IpPermission{
+			IpProtocol:       to.Strp("tcp"),
+			FromPort:         to.Int64p(port),
+			ToPort:           to.Int64p(port),
+			UserIdGroupPairs: []*ec2.UserIdGroupPair{&ec2.UserIdGroupPair{GroupId: to.Strp(sourceGroupID)}},
+		})
+	}
+}
+
 // AddImage returns
 func (m *EC2Client) AddImage(nameTag string, id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.DescribeImagesResp = &DescribeImagesResponse{
 		Resp: &ec2.DescribeImagesOutput{
 			Images: []*ec2.Image{
 				&ec2.Image{
-					ImageId: to.Strp(id),
+					ImageId:            to.Strp(id),
+					Architecture:       to.Strp("x86_64"),
+					VirtualizationType: to.Strp("hvm"),
+					EnaSupport:         to.Boolp(true),
 					Tags: []*ec2.Tag{
 						&ec2.Tag{Key: to.Strp("Name"), Value: to.Strp(nameTag)},
 						&ec2.Tag{Key: to.Strp("DeployWith"), Value: to.Strp("odin")},
@@ -74,13 +227,47 @@ func (m *EC2Client) AddImage(nameTag string, id string) {
 	}
 }
 
+// SetImageArchitecture sets the architecture and virtualization type of the added image
+func (m *EC2Client) SetImageArchitecture(id string, architecture string, virtualizationType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.DescribeImagesResp == nil || m.DescribeImagesResp.Resp == nil {
+		return
+	}
+
+	for _, im := range m.DescribeImagesResp.Resp.Images {
+		if im != nil && to.Strs(im.ImageId) == id {
+			im.Architecture = to.Strp(architecture)
+			im.VirtualizationType = to.Strp(virtualizationType)
+		}
+	}
+}
+
+// SetImageEnaSupport sets if the added image supports ENA enhanced networking
+func (m *EC2Client) SetImageEnaSupport(id string, ena bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.DescribeImagesResp == nil || m.DescribeImagesResp.Resp == nil {
+		return
+	}
+
+	for _, im := range m.DescribeImagesResp.Resp.Images {
+		if im != nil && to.Strs(im.ImageId) == id {
+			im.EnaSupport = to.Boolp(ena)
+		}
+	}
+}
+
 // AddSubnet returns
 func (m *EC2Client) AddSubnet(nameTag string, id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.DescribeSubnetsResp = &DescribeSubnetsResponse{
 		Resp: &ec2.DescribeSubnetsOutput{
 			Subnets: []*ec2.Subnet{
 				&ec2.Subnet{
-					SubnetId: to.Strp(id),
+					SubnetId:         to.Strp(id),
+					AvailabilityZone: to.Strp("us-east-1a"),
 					Tags: []*ec2.Tag{
 						&ec2.Tag{Key: to.Strp("Name"), Value: to.Strp(nameTag)},
 						&ec2.Tag{Key: to.Strp("DeployWith"), Value: to.Strp("odin")},
@@ -91,8 +278,43 @@ func (m *EC2Client) AddSubnet(nameTag string, id string) {
 	}
 }
 
+// AddSubnetInAZ adds a subnet in the availability zone az to the subnets already added
+func (m *EC2Client) AddSubnetInAZ(nameTag string, id string, az string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.DescribeSubnetsResp == nil {
+		m.DescribeSubnetsResp = &DescribeSubnetsResponse{Resp: &ec2.DescribeSubnetsOutput{}}
+	}
+
+	m.DescribeSubnetsResp.Resp.Subnets = append(m.DescribeSubnetsResp.Resp.Subnets, &ec2.Subnet{
+		SubnetId:         to.Strp(id),
+		AvailabilityZone: to.Strp(az),
+		Tags: []*ec2.Tag{
+			&ec2.Tag{Key: to.Strp("Name"), Value: to.Strp(nameTag)},
+			&ec2.Tag{Key: to.Strp("DeployWith"), Value: to.Strp("odin")},
+		},
+	})
+}
+
+// AddSubnetWithTags adds a subnet in the availability zone az with extra tags to the subnets already added
+func (m *EC2Client) AddSubnetWithTags(nameTag string, id string, az string, tags map[string]string) {
+	m.AddSubnetInAZ(nameTag, id, az)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	added := m.DescribeSubnetsResp.Resp.Subnets[len(m.DescribeSubnetsResp.Resp.Subnets)-1]
+	for key, value := range tags {
+		added.Tags = append(added.Tags, &ec2.Tag{Key: to.Strp(key), Value: to.Strp(value)})
+	}
+}
+
 // DescribeSecurityGroups returns
 func (m *EC2Client) DescribeSecurityGroups(in *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeSecurityGroups"); err != nil {
+		return nil, err
+	}
 	m.init()
 	sgName := in.Filters[0].Values[0]
 	resp := m.DescribeSecurityGroupsResp[*sgName]
@@ -117,23 +339,157 @@ func MakeMockSecurityGroup(name string, projectName string, configName string, s
 
 // DescribeSubnets returns
 func (m *EC2Client) DescribeSubnets(in *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeSubnets"); err != nil {
+		return nil, err
+	}
 	if m.DescribeSubnetsResp == nil {
 		return nil, fmt.Errorf("Add Subnets")
 	}
 
+	// Looking up IDs or name tags only returns the subnets with those IDs or name tags
+	names := []string{}
+	for _, f := range in.Filters {
+		if to.Strs(f.Name) == "tag:Name" {
+			names = append(names, to.StrSlice(f.Values)...)
+		}
+	}
+
+	if (len(in.SubnetIds) > 0 || len(names) > 0) && m.DescribeSubnetsResp.Resp != nil {
+		subnets := []*ec2.Subnet{}
+		for _, sn := range m.DescribeSubnetsResp.Resp.Subnets {
+			if sn == nil {
+				continue
+			}
+
+			if containsStr(to.StrSlice(in.SubnetIds), to.Strs(sn.SubnetId)) || containsStr(names, to.Strs(aws.FetchEc2Tag(sn.Tags, to.Strp("Name")))) {
+				subnets = append(subnets, sn)
+			}
+		}
+		return &ec2.DescribeSubnetsOutput{Subnets: subnets}, m.DescribeSubnetsResp.Error
+	}
+
 	return m.DescribeSubnetsResp.Resp, m.DescribeSubnetsResp.Error
 }
 
 // DescribeImages returns
 func (m *EC2Client) DescribeImages(in *ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeImages"); err != nil {
+		return nil, err
+	}
 	if m.DescribeImagesResp == nil {
 		return nil, fmt.Errorf("Add Image")
 	}
 
+	// Looking up an ID only returns the image with that ID
+	if len(in.ImageIds) > 0 && m.DescribeImagesResp.Resp != nil {
+		images := []*ec2.Image{}
+		for _, im := range m.DescribeImagesResp.Resp.Images {
+			if im != nil && containsStr(to.StrSlice(in.ImageIds), to.Strs(im.ImageId)) {
+				images = append(images, im)
+			}
+		}
+		return &ec2.DescribeImagesOutput{Images: images}, m.DescribeImagesResp.Error
+	}
+
 	return m.DescribeImagesResp.Resp, m.DescribeImagesResp.Error
 }
 
+// AddCapacityReservation adds a capacity reservation with a state e.g. "active" or "expired"
+func (m *EC2Client) AddCapacityReservation(id string, instanceType string, az string, state string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.CapacityReservations = append(m.CapacityReservations, &ec2.CapacityReservation{
+		CapacityReservationId: to.Strp(id),
+		InstanceType:          to.Strp(instanceType),
+		AvailabilityZone:      to.Strp(az),
+		State:                 to.Strp(state),
+	})
+}
+
+// DescribeCapacityReservations returns the added capacity reservations, like AWS it errors if an ID is not found
+func (m *EC2Client) DescribeCapacityReservations(in *ec2.DescribeCapacityReservationsInput) (*ec2.DescribeCapacityReservationsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeCapacityReservations"); err != nil {
+		return nil, err
+	}
+
+	found := []*ec2.CapacityReservation{}
+	for _, id := range in.CapacityReservationIds {
+		var match *ec2.CapacityReservation
+		for _, reservation := range m.CapacityReservations {
+			if to.Strs(reservation.CapacityReservationId) == to.Strs(id) {
+				match = reservation
+			}
+		}
+
+		if match == nil {
+			return nil, awserr.New("InvalidCapacityReservationId.NotFound", fmt.Sprintf("The capacity reservation ID '%v' does not exist", to.Strs(id)), nil)
+		}
+		found = append(found, match)
+	}
+
+	return &ec2.DescribeCapacityReservationsOutput{CapacityReservations: found}, nil
+}
+
+// AddKeyPair adds a key pair
+func (m *EC2Client) AddKeyPair(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.KeyPairs = append(m.KeyPairs, &ec2.KeyPairInfo{
+		KeyName:   to.Strp(name),
+		KeyPairId: to.Strp(fmt.Sprintf("key-%v", name)),
+	})
+}
+
+// DescribeKeyPairs returns the added key pairs, like AWS it errors if a name is not found
+func (m *EC2Client) DescribeKeyPairs(in *ec2.DescribeKeyPairsInput) (*ec2.DescribeKeyPairsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeKeyPairs"); err != nil {
+		return nil, err
+	}
+
+	found := []*ec2.KeyPairInfo{}
+	for _, name := range in.KeyNames {
+		var match *ec2.KeyPairInfo
+		for _, keyPair := range m.KeyPairs {
+			if to.Strs(keyPair.KeyName) == to.Strs(name) {
+				match = keyPair
+			}
+		}
+
+		if match == nil {
+			return nil, awserr.New("InvalidKeyPair.NotFound", fmt.Sprintf("The key pair '%v' does not exist", to.Strs(name)), nil)
+		}
+		found = append(found, match)
+	}
+
+	return &ec2.DescribeKeyPairsOutput{KeyPairs: found}, nil
+}
+
+// AddPlacementGroup adds an available placement group
+func (m *EC2Client) AddPlacementGroup(name string, strategy string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.PlacementGroups = append(m.PlacementGroups, &ec2.PlacementGroup{
+		GroupName: to.Strp(name),
+		Strategy:  to.Strp(strategy),
+		State:     to.Strp("available"),
+	})
+}
+
 func (m *EC2Client) DescribePlacementGroups(in *ec2.DescribePlacementGroupsInput) (*ec2.DescribePlacementGroupsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribePlacementGroups"); err != nil {
+		return nil, err
+	}
 	m.init()
 	return &ec2.DescribePlacementGroupsOutput{
 		PlacementGroups: m.PlacementGroups,
@@ -141,6 +497,11 @@ func (m *EC2Client) DescribePlacementGroups(in *ec2.DescribePlacementGroupsInput
 }
 
 func (m *EC2Client) CreatePlacementGroup(in *ec2.CreatePlacementGroupInput) (*ec2.CreatePlacementGroupOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("CreatePlacementGroup"); err != nil {
+		return nil, err
+	}
 	m.init()
 	m.PlacementGroups = append(m.PlacementGroups, &ec2.PlacementGroup{
 		GroupName:      in.GroupName,
@@ -151,3 +512,383 @@ func (m *EC2Client) CreatePlacementGroup(in *ec2.CreatePlacementGroupInput) (*ec
 
 	return nil, nil
 }
+
+// DescribeInstanceTypeOfferingsPages offers every filtered instance type in every filtered location except UnofferedInstanceTypes,
+// and instance types in InstanceTypeOfferings only in their locations
+func (m *EC2Client) DescribeInstanceTypeOfferingsPages(in *ec2.DescribeInstanceTypeOfferingsInput, fn func(*ec2.DescribeInstanceTypeOfferingsOutput, bool) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeInstanceTypeOfferingsPages"); err != nil {
+		return err
+	}
+	filters := map[string][]*string{}
+	for _, f := range in.Filters {
+		filters[*f.Name] = f.Values
+	}
+
+	unoffered := map[string]bool{}
+	for _, it := range m.UnofferedInstanceTypes {
+		unoffered[it] = true
+	}
+
+	offerings := []*ec2.InstanceTypeOffering{}
+	for _, it := range filters["instance-type"] {
+		if unoffered[*it] {
+			continue
+		}
+		for _, location := range filters["location"] {
+			if locations, ok := m.InstanceTypeOfferings[*it]; ok && !containsStr(locations, *location) {
+				continue
+			}
+			offerings = append(offerings, &ec2.InstanceTypeOffering{InstanceType: it, Location: location, LocationType: in.LocationType})
+		}
+	}
+
+	fn(&ec2.DescribeInstanceTypeOfferingsOutput{InstanceTypeOfferings: offerings}, true)
+	return nil
+}
+
+// AddInstanceType adds an instance type supporting the architectures and virtualization types
+func (m *EC2Client) AddInstanceType(name string, architectures []string, virtualizationTypes []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.InstanceTypes[name] = &ec2.InstanceTypeInfo{
+		InstanceType:                 to.Strp(name),
+		ProcessorInfo:                &ec2.ProcessorInfo{SupportedArchitectures: strps(architectures)},
+		SupportedVirtualizationTypes: strps(virtualizationTypes),
+	}
+}
+
+// SetInstanceTypeNetworking sets the hypervisor, e.g. "nitro", and ENA support, e.g. "required", of an added instance type
+func (m *EC2Client) SetInstanceTypeNetworking(name string, hypervisor string, enaSupport string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	if it, ok := m.InstanceTypes[name]; ok {
+		it.Hypervisor = to.Strp(hypervisor)
+		it.NetworkInfo = &ec2.NetworkInfo{EnaSupport: to.Strp(enaSupport)}
+	}
+}
+
+// DescribeInstanceTypesPages returns the added instance types, other instance types support x86_64 and hvm
+func (m *EC2Client) DescribeInstanceTypesPages(in *ec2.DescribeInstanceTypesInput, fn func(*ec2.DescribeInstanceTypesOutput, bool) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeInstanceTypesPages"); err != nil {
+		return err
+	}
+	m.init()
+
+	types := []*ec2.InstanceTypeInfo{}
+	for _, name := range in.InstanceTypes {
+		if it, ok := m.InstanceTypes[to.Strs(name)]; ok {
+			types = append(types, it)
+			continue
+		}
+
+		types = append(types, &ec2.InstanceTypeInfo{
+			InstanceType:                 name,
+			ProcessorInfo:                &ec2.ProcessorInfo{SupportedArchitectures: []*string{to.Strp("x86_64")}},
+			SupportedVirtualizationTypes: []*string{to.Strp("hvm")},
+		})
+	}
+
+	fn(&ec2.DescribeInstanceTypesOutput{InstanceTypes: types}, true)
+	return nil
+}
+
+// CreateLaunchTemplate returns
+func (m *EC2Client) CreateLaunchTemplate(in *ec2.CreateLaunchTemplateInput) (*ec2.CreateLaunchTemplateOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("CreateLaunchTemplate"); err != nil {
+		return nil, err
+	}
+	m.init()
+	m.CreateLaunchTemplateInputs = append(m.CreateLaunchTemplateInputs, in)
+	if in.LaunchTemplateData != nil && in.LaunchTemplateName != nil {
+		m.LaunchTemplateMetadataOptions[*in.LaunchTemplateName] = in.LaunchTemplateData.MetadataOptions
+		m.LaunchTemplateMonitoring[*in.LaunchTemplateName] = in.LaunchTemplateData.Monitoring
+		m.LaunchTemplatePlacements[*in.LaunchTemplateName] = in.LaunchTemplateData.Placement
+		m.LaunchTemplateNetworkInterfaces[*in.LaunchTemplateName] = in.LaunchTemplateData.NetworkInterfaces
+	}
+	m.LaunchTemplateVersions[to.Strs(in.LaunchTemplateName)] = []int64{1}
+	m.DefaultLaunchTemplateVersions[to.Strs(in.LaunchTemplateName)] = 1
+	return &ec2.CreateLaunchTemplateOutput{LaunchTemplate: &ec2.LaunchTemplate{LaunchTemplateName: in.LaunchTemplateName, LatestVersionNumber: to.Int64p(1)}}, nil
+}
+
+// CreateLaunchTemplateRequest returns a request that builds the query and records its LaunchTemplateData
+func (m *EC2Client) CreateLaunchTemplateRequest(in *ec2.CreateLaunchTemplateInput) (*request.Request, *ec2.CreateLaunchTemplateOutput) {
+	out := &ec2.CreateLaunchTemplateOutput{}
+	req := m.queryRequest("CreateLaunchTemplate", in, out, func(data map[string]string) error {
+		res, err := m.CreateLaunchTemplate(in)
+		if err != nil {
+			return err
+		}
+
+		m.recordLaunchTemplateData(in.LaunchTemplateName, data)
+		*out = *res
+		return nil
+	})
+
+	return req, out
+}
+
+// CreateLaunchTemplateVersionRequest returns a request that builds the query and records its LaunchTemplateData
+func (m *EC2Client) CreateLaunchTemplateVersionRequest(in *ec2.CreateLaunchTemplateVersionInput) (*request.Request, *ec2.CreateLaunchTemplateVersionOutput) {
+	out := &ec2.CreateLaunchTemplateVersionOutput{}
+	req := m.queryRequest("CreateLaunchTemplateVersion", in, out, func(data map[string]string) error {
+		res, err := m.CreateLaunchTemplateVersion(in)
+		if err != nil {
+			return err
+		}
+
+		m.recordLaunchTemplateData(in.LaunchTemplateName, data)
+		*out = *res
+		return nil
+	})
+
+	return req, out
+}
+
+// queryRequest returns a request that is built as an EC2 query, sending it calls send with its LaunchTemplateData parameters
+func (m *EC2Client) queryRequest(operation string, in interface{}, out interface{}, send func(map[string]string) error) *request.Request {
+	handlers := request.Handlers{}
+	handlers.Build.PushBackNamed(ec2query.BuildHandler)
+	handlers.Send.PushBack(func(r *request.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			r.Error = err
+			return
+		}
+
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			r.Error = err
+			return
+		}
+
+		data := map[string]string{}
+		for key := range values {
+			if strings.HasPrefix(key, "LaunchTemplateData.") {
+				data[strings.TrimPrefix(key, "LaunchTemplateData.")] = values.Get(key)
+			}
+		}
+
+		r.Error = send(data)
+	})
+
+	info := metadata.ClientInfo{ServiceName: "ec2", APIVersion: "2016-11-15", Endpoint: "https://ec2.mock"}
+	return request.New(awssdk.Config{}, info, handlers, nil, &request.Operation{Name: operation, HTTPMethod: "POST", HTTPPath: "/"}, in, out)
+}
+
+// recordLaunchTemplateData records the LaunchTemplateData query parameters of the launch template
+func (m *EC2Client) recordLaunchTemplateData(name *string, data map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.LaunchTemplateData[to.Strs(name)] = data
+}
+
+// DeleteLaunchTemplate returns
+func (m *EC2Client) DeleteLaunchTemplate(in *ec2.DeleteLaunchTemplateInput) (*ec2.DeleteLaunchTemplateOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DeleteLaunchTemplate"); err != nil {
+		return nil, err
+	}
+	m.DeleteLaunchTemplateInputs = append(m.DeleteLaunchTemplateInputs, in)
+	return &ec2.DeleteLaunchTemplateOutput{}, nil
+}
+
+// DescribeLaunchTemplatesPages returns the created launch templates that are not deleted and match the tag filters
+func (m *EC2Client) DescribeLaunchTemplatesPages(in *ec2.DescribeLaunchTemplatesInput, fn func(*ec2.DescribeLaunchTemplatesOutput, bool) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeLaunchTemplatesPages"); err != nil {
+		return err
+	}
+
+	m.init()
+
+	deleted := map[string]bool{}
+	for _, del := range m.DeleteLaunchTemplateInputs {
+		deleted[to.Strs(del.LaunchTemplateName)] = true
+	}
+
+	templates := []*ec2.LaunchTemplate{}
+	for _, create := range m.CreateLaunchTemplateInputs {
+		if deleted[to.Strs(create.LaunchTemplateName)] || !launchTemplateMatches(create, in.Filters) {
+			continue
+		}
+
+		if len(in.LaunchTemplateNames) > 0 && !containsStr(to.StrSlice(in.LaunchTemplateNames), to.Strs(create.LaunchTemplateName)) {
+			continue
+		}
+
+		tags := []*ec2.Tag{}
+		for _, spec := range create.TagSpecifications {
+			tags = append(tags, spec.Tags...)
+		}
+
+		name := to.Strs(create.LaunchTemplateName)
+		templates = append(templates, &ec2.LaunchTemplate{
+			LaunchTemplateName:   create.LaunchTemplateName,
+			LatestVersionNumber:  to.Int64p(m.latestLaunchTemplateVersion(name)),
+			DefaultVersionNumber: to.Int64p(m.DefaultLaunchTemplateVersions[name]),
+			Tags:                 tags,
+		})
+	}
+
+	fn(&ec2.DescribeLaunchTemplatesOutput{LaunchTemplates: templates}, true)
+	return nil
+}
+
+// AddLaunchTemplate adds an existing launch template with the versions 1 to versions
+func (m *EC2Client) AddLaunchTemplate(name string, versions int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+
+	m.CreateLaunchTemplateInputs = append(m.CreateLaunchTemplateInputs, &ec2.CreateLaunchTemplateInput{LaunchTemplateName: to.Strp(name)})
+	m.LaunchTemplateVersions[name] = []int64{}
+	for v := int64(1); v <= versions; v++ {
+		m.LaunchTemplateVersions[name] = append(m.LaunchTemplateVersions[name], v)
+	}
+	m.DefaultLaunchTemplateVersions[name] = 1
+}
+
+func (m *EC2Client) latestLaunchTemplateVersion(name string) int64 {
+	latest := int64(1)
+	for _, v := range m.LaunchTemplateVersions[name] {
+		if v > latest {
+			latest = v
+		}
+	}
+	return latest
+}
+
+// CreateLaunchTemplateVersion adds a version after the latest version of the launch template
+func (m *EC2Client) CreateLaunchTemplateVersion(in *ec2.CreateLaunchTemplateVersionInput) (*ec2.CreateLaunchTemplateVersionOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("CreateLaunchTemplateVersion"); err != nil {
+		return nil, err
+	}
+	m.init()
+
+	name := to.Strs(in.LaunchTemplateName)
+	if _, ok := m.LaunchTemplateVersions[name]; !ok {
+		return nil, awserr.New("InvalidLaunchTemplateName.NotFoundException", "The specified launch template does not exist", nil)
+	}
+
+	m.CreateLaunchTemplateVersionInputs = append(m.CreateLaunchTemplateVersionInputs, in)
+	if in.LaunchTemplateData != nil {
+		m.LaunchTemplateMetadataOptions[name] = in.LaunchTemplateData.MetadataOptions
+		m.LaunchTemplateMonitoring[name] = in.LaunchTemplateData.Monitoring
+		m.LaunchTemplatePlacements[name] = in.LaunchTemplateData.Placement
+		m.LaunchTemplateNetworkInterfaces[name] = in.LaunchTemplateData.NetworkInterfaces
+	}
+
+	version := m.latestLaunchTemplateVersion(name) + 1
+	m.LaunchTemplateVersions[name] = append(m.LaunchTemplateVersions[name], version)
+
+	return &ec2.CreateLaunchTemplateVersionOutput{LaunchTemplateVersion: &ec2.LaunchTemplateVersion{
+		LaunchTemplateName: in.LaunchTemplateName,
+		VersionNumber:      to.Int64p(version),
+	}}, nil
+}
+
+// DescribeLaunchTemplateVersionsPages returns the versions of the launch template in one page
+func (m *EC2Client) DescribeLaunchTemplateVersionsPages(in *ec2.DescribeLaunchTemplateVersionsInput, fn func(*ec2.DescribeLaunchTemplateVersionsOutput, bool) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeLaunchTemplateVersionsPages"); err != nil {
+		return err
+	}
+	m.init()
+
+	name := to.Strs(in.LaunchTemplateName)
+	versions := []*ec2.LaunchTemplateVersion{}
+	for _, v := range m.LaunchTemplateVersions[name] {
+		versions = append(versions, &ec2.LaunchTemplateVersion{
+			LaunchTemplateName: in.LaunchTemplateName,
+			VersionNumber:      to.Int64p(v),
+			DefaultVersion:     to.Boolp(v == m.DefaultLaunchTemplateVersions[name]),
+		})
+	}
+
+	fn(&ec2.DescribeLaunchTemplateVersionsOutput{LaunchTemplateVersions: versions}, true)
+	return nil
+}
+
+// DeleteLaunchTemplateVersions deletes the versions, the default version cannot be deleted
+func (m *EC2Client) DeleteLaunchTemplateVersions(in *ec2.DeleteLaunchTemplateVersionsInput) (*ec2.DeleteLaunchTemplateVersionsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DeleteLaunchTemplateVersions"); err != nil {
+		return nil, err
+	}
+	m.init()
+	m.DeleteLaunchTemplateVersionsInputs = append(m.DeleteLaunchTemplateVersionsInputs, in)
+
+	name := to.Strs(in.LaunchTemplateName)
+	out := &ec2.DeleteLaunchTemplateVersionsOutput{}
+	for _, str := range in.Versions {
+		version, _ := strconv.ParseInt(to.Strs(str), 10, 64)
+		if version == m.DefaultLaunchTemplateVersions[name] {
+			out.UnsuccessfullyDeletedLaunchTemplateVersions = append(out.UnsuccessfullyDeletedLaunchTemplateVersions, &ec2.DeleteLaunchTemplateVersionsResponseErrorItem{
+				VersionNumber: to.Int64p(version),
+				ResponseError: &ec2.ResponseError{Code: to.Strp("launchTemplateVersionIsDefault"), Message: to.Strp("Cannot delete the default version")},
+			})
+			continue
+		}
+
+		kept := []int64{}
+		for _, v := range m.LaunchTemplateVersions[name] {
+			if v != version {
+				kept = append(kept, v)
+			}
+		}
+		m.LaunchTemplateVersions[name] = kept
+	}
+
+	return out, nil
+}
+
+// ModifyLaunchTemplate sets the default version
+func (m *EC2Client) ModifyLaunchTemplate(in *ec2.ModifyLaunchTemplateInput) (*ec2.ModifyLaunchTemplateOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("ModifyLaunchTemplate"); err != nil {
+		return nil, err
+	}
+	m.init()
+
+	version, err := strconv.ParseInt(to.Strs(in.DefaultVersion), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	m.DefaultLaunchTemplateVersions[to.Strs(in.LaunchTemplateName)] = version
+	return &ec2.ModifyLaunchTemplateOutput{}, nil
+}
+
+func launchTemplateMatches(create *ec2.CreateLaunchTemplateInput, filters []*ec2.Filter) bool {
+	tags := map[string]string{}
+	for _, spec := range create.TagSpecifications {
+		for _, tag := range spec.Tags {
+			tags[to.Strs(tag.Key)] = to.Strs(tag.Value)
+		}
+	}
+
+	for _, filter := range filters {
+		value, ok := tags[strings.TrimPrefix(to.Strs(filter.Name), "tag:")]
+		if !ok || !containsStr(to.StrSlice(filter.Values), value) {
+			return false
+		}
+	}
+
+	return true
+}
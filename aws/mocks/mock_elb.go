@@ -1,10 +1,12 @@
 package mocks
 
 import (
+	"fmt"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/coinbase/odin/aws"
 	"github.com/coinbase/step/utils/to"
+	"sync"
 )
 
 // DescribeLoadBalancersResponse returns
@@ -28,9 +30,23 @@ type DescribeInstanceHealthResponse struct {
 // ELBClient returns
 type ELBClient struct {
 	aws.ELBAPI
+	mu sync.Mutex // Services are deployed concurrently
+	Throttler
+
 	DescribeLoadBalancersResp  map[string]*DescribeLoadBalancersResponse
 	DescribeTagsResp           map[string]*DescribeTagsResponse
 	DescribeInstanceHealthResp map[string]*DescribeInstanceHealthResponse
+
+	// ConnectionDrainingTimeouts enables connection draining with the timeout for an ELB by name
+	ConnectionDrainingTimeouts map[string]int64
+
+	// OutOfServiceAfter makes every instance of an ELB OutOfService after that many DescribeInstanceHealth calls
+	OutOfServiceAfter map[string]int
+
+	// OutOfServiceUntil makes every instance of an ELB OutOfService for its first that many DescribeInstanceHealth calls
+	OutOfServiceUntil map[string]int
+
+	describeInstanceHealthCalls map[string]int
 }
 
 // AWSELBNotFoundError returns
@@ -50,15 +66,24 @@ func (m *ELBClient) init() {
 	if m.DescribeInstanceHealthResp == nil {
 		m.DescribeInstanceHealthResp = map[string]*DescribeInstanceHealthResponse{}
 	}
+
+	if m.describeInstanceHealthCalls == nil {
+		m.describeInstanceHealthCalls = map[string]int{}
+	}
 }
 
 // AddELB returns
 func (m *ELBClient) AddELB(name string, projectName string, configName string, serviceName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.init()
 	m.DescribeLoadBalancersResp[name] = &DescribeLoadBalancersResponse{
 		Resp: &elb.DescribeLoadBalancersOutput{
 			LoadBalancerDescriptions: []*elb.LoadBalancerDescription{
-				&elb.LoadBalancerDescription{LoadBalancerName: &name},
+				&elb.LoadBalancerDescription{
+					LoadBalancerName: &name,
+					DNSName:          to.Strp(fmt.Sprintf("%v.elb.amazonaws.com", name)),
+				},
 			},
 		},
 	}
@@ -91,8 +116,40 @@ func (m *ELBClient) AddELB(name string, projectName string, configName string, s
 
 }
 
+// SetInstanceHealth sets the state, e.g. "InService" or "OutOfService", the ELB reports for the instance
+func (m *ELBClient) SetInstanceHealth(name string, instanceID string, state string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	resp := m.DescribeInstanceHealthResp[name].Resp
+	for _, is := range resp.InstanceStates {
+		if *is.InstanceId == instanceID {
+			is.State = to.Strp(state)
+			return
+		}
+	}
+
+	resp.InstanceStates = append(resp.InstanceStates, &elb.InstanceState{InstanceId: to.Strp(instanceID), State: to.Strp(state)})
+}
+
+// SetELBHealthCheck sets the health check target e.g. "HTTP:80/ping" and the security groups of the ELB
+func (m *ELBClient) SetELBHealthCheck(name string, target string, securityGroupIDs ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	for _, lb := range m.DescribeLoadBalancersResp[name].Resp.LoadBalancerDescriptions {
+		lb.HealthCheck = &elb.HealthCheck{Target: to.Strp(target)}
+		lb.SecurityGroups = strps(securityGroupIDs)
+	}
+}
+
 // DescribeLoadBalancers returns
 func (m *ELBClient) DescribeLoadBalancers(in *elb.DescribeLoadBalancersInput) (*elb.DescribeLoadBalancersOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeLoadBalancers"); err != nil {
+		return nil, err
+	}
 	m.init()
 	lbName := in.LoadBalancerNames[0]
 	resp := m.DescribeLoadBalancersResp[*lbName]
@@ -104,6 +161,11 @@ func (m *ELBClient) DescribeLoadBalancers(in *elb.DescribeLoadBalancersInput) (*
 
 // DescribeTags returns
 func (m *ELBClient) DescribeTags(in *elb.DescribeTagsInput) (*elb.DescribeTagsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeTags"); err != nil {
+		return nil, err
+	}
 	m.init()
 	lbName := in.LoadBalancerNames[0]
 	resp := m.DescribeTagsResp[*lbName]
@@ -113,8 +175,35 @@ func (m *ELBClient) DescribeTags(in *elb.DescribeTagsInput) (*elb.DescribeTagsOu
 	return resp.Resp, resp.Error
 }
 
+// DescribeLoadBalancerAttributes returns
+func (m *ELBClient) DescribeLoadBalancerAttributes(in *elb.DescribeLoadBalancerAttributesInput) (*elb.DescribeLoadBalancerAttributesOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeLoadBalancerAttributes"); err != nil {
+		return nil, err
+	}
+	m.init()
+	if m.DescribeLoadBalancersResp[*in.LoadBalancerName] == nil {
+		return nil, AWSELBNotFoundError()
+	}
+
+	draining := &elb.ConnectionDraining{Enabled: to.Boolp(false), Timeout: to.Int64p(300)}
+	if timeout, ok := m.ConnectionDrainingTimeouts[*in.LoadBalancerName]; ok {
+		draining = &elb.ConnectionDraining{Enabled: to.Boolp(true), Timeout: to.Int64p(timeout)}
+	}
+
+	return &elb.DescribeLoadBalancerAttributesOutput{
+		LoadBalancerAttributes: &elb.LoadBalancerAttributes{ConnectionDraining: draining},
+	}, nil
+}
+
 // DescribeInstanceHealth returns
 func (m *ELBClient) DescribeInstanceHealth(in *elb.DescribeInstanceHealthInput) (*elb.DescribeInstanceHealthOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeInstanceHealth"); err != nil {
+		return nil, err
+	}
 	m.init()
 	lbName := in.LoadBalancerName
 	resp := m.DescribeInstanceHealthResp[*lbName]
@@ -124,5 +213,17 @@ func (m *ELBClient) DescribeInstanceHealth(in *elb.DescribeInstanceHealthInput)
 	if resp.Resp == nil {
 		return &elb.DescribeInstanceHealthOutput{}, nil
 	}
+
+	m.describeInstanceHealthCalls[*lbName]++
+	until, unhealthy := m.OutOfServiceUntil[*lbName]
+	unhealthy = unhealthy && m.describeInstanceHealthCalls[*lbName] <= until
+	if after, ok := m.OutOfServiceAfter[*lbName]; unhealthy || (ok && m.describeInstanceHealthCalls[*lbName] > after) {
+		states := []*elb.InstanceState{}
+		for _, state := range resp.Resp.InstanceStates {
+			states = append(states, &elb.InstanceState{InstanceId: state.InstanceId, State: to.Strp("OutOfService")})
+		}
+		return &elb.DescribeInstanceHealthOutput{InstanceStates: states}, resp.Error
+	}
+
 	return resp.Resp, resp.Error
 }
@@ -0,0 +1,63 @@
+package mocks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// HTTPClient responds to requests by URL, requests to an unknown URL fail to connect
+type HTTPClient struct {
+	mu sync.Mutex
+
+	// Statuses are the status codes returned by URL
+	Statuses map[string]int
+
+	Requests []string
+
+	// Bodies are the bodies of the requests that had one, by URL
+	Bodies map[string][]string
+}
+
+// SetStatus makes requests to the URL respond with the status code
+func (m *HTTPClient) SetStatus(url string, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Statuses == nil {
+		m.Statuses = map[string]int{}
+	}
+	m.Statuses[url] = status
+}
+
+// Do returns the URLs status, or a connection refused error
+func (m *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	url := req.URL.String()
+	m.Requests = append(m.Requests, url)
+
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		if m.Bodies == nil {
+			m.Bodies = map[string][]string{}
+		}
+		m.Bodies[url] = append(m.Bodies[url], string(body))
+	}
+
+	status, ok := m.Statuses[url]
+	if !ok {
+		return nil, fmt.Errorf("dial tcp %v: connect: connection refused", req.URL.Host)
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
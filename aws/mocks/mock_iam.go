@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/iam"
@@ -24,8 +25,12 @@ type GetRoleResponse struct {
 // IAMClient returns
 type IAMClient struct {
 	aws.IAMAPI
+	mu sync.Mutex // Services are deployed concurrently
+	Throttler
+
 	GetInstanceProfileResp map[string]*GetInstanceProfileResponse
 	GetRoleResp            map[string]*GetRoleResponse
+	AttachedRolePolicies   map[string][]string
 }
 
 func (m *IAMClient) init() {
@@ -36,6 +41,10 @@ func (m *IAMClient) init() {
 	if m.GetRoleResp == nil {
 		m.GetRoleResp = map[string]*GetRoleResponse{}
 	}
+
+	if m.AttachedRolePolicies == nil {
+		m.AttachedRolePolicies = map[string][]string{}
+	}
 }
 
 // AWSProfileNotFoundError returns
@@ -45,19 +54,33 @@ func AWSProfileNotFoundError() error {
 
 // AddGetInstanceProfile returns
 func (m *IAMClient) AddGetInstanceProfile(profileName string, path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.init()
 	m.GetInstanceProfileResp[profileName] = &GetInstanceProfileResponse{
 		Resp: &iam.GetInstanceProfileOutput{
 			InstanceProfile: &iam.InstanceProfile{
-				Arn:  to.Strp(fmt.Sprintf("%v%v", path, profileName)),
+				Arn:  to.Strp(fmt.Sprintf("arn:aws:iam::000000000000:instance-profile%v%v", path, profileName)),
 				Path: to.Strp(path),
 			},
 		},
 	}
 }
 
+// AddInstanceProfileRole adds a role with the attached managed policies to an added instance profile
+func (m *IAMClient) AddInstanceProfileRole(profileName string, roleName string, policyARNs ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	profile := m.GetInstanceProfileResp[profileName].Resp.InstanceProfile
+	profile.Roles = append(profile.Roles, &iam.Role{RoleName: to.Strp(roleName)})
+	m.AttachedRolePolicies[roleName] = append(m.AttachedRolePolicies[roleName], policyARNs...)
+}
+
 // AddGetRole returns
 func (m *IAMClient) AddGetRole(roleName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.init()
 	m.GetRoleResp[roleName] = &GetRoleResponse{
 		Resp: &iam.GetRoleOutput{
@@ -70,6 +93,11 @@ func (m *IAMClient) AddGetRole(roleName string) {
 
 // GetInstanceProfile returns
 func (m *IAMClient) GetInstanceProfile(in *iam.GetInstanceProfileInput) (*iam.GetInstanceProfileOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("GetInstanceProfile"); err != nil {
+		return nil, err
+	}
 	m.init()
 	resp := m.GetInstanceProfileResp[*in.InstanceProfileName]
 	if resp == nil {
@@ -80,6 +108,11 @@ func (m *IAMClient) GetInstanceProfile(in *iam.GetInstanceProfileInput) (*iam.Ge
 
 // GetRole returns
 func (m *IAMClient) GetRole(in *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("GetRole"); err != nil {
+		return nil, err
+	}
 	m.init()
 	resp := m.GetRoleResp[*in.RoleName]
 	if resp == nil {
@@ -87,3 +120,25 @@ func (m *IAMClient) GetRole(in *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
 	}
 	return resp.Resp, resp.Error
 }
+
+// ListAttachedRolePoliciesPages returns
+func (m *IAMClient) ListAttachedRolePoliciesPages(in *iam.ListAttachedRolePoliciesInput, fn func(*iam.ListAttachedRolePoliciesOutput, bool) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("ListAttachedRolePoliciesPages"); err != nil {
+		return err
+	}
+	m.init()
+	policies, ok := m.AttachedRolePolicies[*in.RoleName]
+	if !ok {
+		return AWSProfileNotFoundError()
+	}
+
+	output := &iam.ListAttachedRolePoliciesOutput{}
+	for _, arn := range policies {
+		output.AttachedPolicies = append(output.AttachedPolicies, &iam.AttachedPolicy{PolicyArn: to.Strp(arn)})
+	}
+
+	fn(output, true)
+	return nil
+}
@@ -0,0 +1,76 @@
+package mocks
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// KMSClient returns
+type KMSClient struct {
+	aws.KMSAPI
+	mu sync.Mutex
+	Throttler
+
+	DecryptInputs []*kms.DecryptInput
+
+	// Keys by ID, false if Decrypt is denied
+	Keys map[string]bool
+}
+
+func (m *KMSClient) init() {
+	if m.Keys == nil {
+		m.Keys = map[string]bool{}
+	}
+}
+
+// AddKey makes the key ID decrypt its ciphertexts
+func (m *KMSClient) AddKey(keyID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.Keys[keyID] = true
+}
+
+// DenyKey makes Decrypt of the key IDs ciphertexts return access denied
+func (m *KMSClient) DenyKey(keyID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.Keys[keyID] = false
+}
+
+// Ciphertext returns a ciphertext blob of the plaintext under the key ID
+func (m *KMSClient) Ciphertext(keyID string, plaintext string) []byte {
+	return []byte(keyID + "\x00" + plaintext)
+}
+
+// Decrypt returns the plaintext of a Ciphertext blob, an error if the key is denied or unknown
+func (m *KMSClient) Decrypt(in *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("Decrypt"); err != nil {
+		return nil, err
+	}
+	m.init()
+	m.DecryptInputs = append(m.DecryptInputs, in)
+
+	parts := bytes.SplitN(in.CiphertextBlob, []byte("\x00"), 2)
+	if len(parts) != 2 {
+		return nil, awserr.New(kms.ErrCodeInvalidCiphertextException, "Invalid ciphertext", nil)
+	}
+
+	allowed, ok := m.Keys[string(parts[0])]
+	switch {
+	case !ok:
+		return nil, awserr.New(kms.ErrCodeNotFoundException, "Key not found", nil)
+	case !allowed:
+		return nil, awserr.New("AccessDeniedException", "User is not authorized to perform kms:Decrypt", nil)
+	}
+
+	return &kms.DecryptOutput{KeyId: to.Strp(string(parts[0])), Plaintext: parts[1]}, nil
+}
@@ -0,0 +1,66 @@
+package mocks
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// LambdaClient returns
+type LambdaClient struct {
+	aws.LambdaAPI
+	mu sync.Mutex // Services are deployed concurrently
+	Throttler
+
+	InvokeInputs []*lambda.InvokeInput
+	InvokeResp   map[string]*lambda.InvokeOutput
+}
+
+func (m *LambdaClient) init() {
+	if m.InvokeResp == nil {
+		m.InvokeResp = map[string]*lambda.InvokeOutput{}
+	}
+}
+
+// AddInvokeResponse makes invoking functionName return statusCode and payload
+func (m *LambdaClient) AddInvokeResponse(functionName string, statusCode int64, payload string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.InvokeResp[functionName] = &lambda.InvokeOutput{
+		StatusCode: to.Int64p(statusCode),
+		Payload:    []byte(payload),
+	}
+}
+
+// AddAllow makes functionName respond {"allow": true}
+func (m *LambdaClient) AddAllow(functionName string) {
+	m.AddInvokeResponse(functionName, 200, `{"allow": true}`)
+}
+
+// AddDeny makes functionName respond {"allow": false} with message
+func (m *LambdaClient) AddDeny(functionName string, message string) {
+	m.AddInvokeResponse(functionName, 200, fmt.Sprintf(`{"allow": false, "message": %q}`, message))
+}
+
+// Invoke returns the added response, or a not found error
+func (m *LambdaClient) Invoke(in *lambda.InvokeInput) (*lambda.InvokeOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("Invoke"); err != nil {
+		return nil, err
+	}
+	m.init()
+	m.InvokeInputs = append(m.InvokeInputs, in)
+
+	resp := m.InvokeResp[to.Strs(in.FunctionName)]
+	if resp == nil {
+		return nil, awserr.New(lambda.ErrCodeResourceNotFoundException, "Function not found", nil)
+	}
+
+	return resp, nil
+}
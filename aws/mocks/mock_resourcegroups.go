@@ -0,0 +1,50 @@
+package mocks
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/resourcegroups"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// ResourceGroupsClient returns
+type ResourceGroupsClient struct {
+	aws.ResourceGroupsAPI
+	mu sync.Mutex
+	Throttler
+
+	GetGroupInputs []*resourcegroups.GetGroupInput
+
+	// Groups are the ARNs of the resource groups that exist
+	Groups map[string]bool
+}
+
+// AddGroup makes the resource group with the ARN exist
+func (m *ResourceGroupsClient) AddGroup(arn string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Groups == nil {
+		m.Groups = map[string]bool{}
+	}
+	m.Groups[arn] = true
+}
+
+// GetGroup returns the added group, or a not found error
+func (m *ResourceGroupsClient) GetGroup(in *resourcegroups.GetGroupInput) (*resourcegroups.GetGroupOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("GetGroup"); err != nil {
+		return nil, err
+	}
+	m.GetGroupInputs = append(m.GetGroupInputs, in)
+
+	if !m.Groups[to.Strs(in.Group)] {
+		return nil, awserr.New(resourcegroups.ErrCodeNotFoundException, "Cannot find group", nil)
+	}
+
+	return &resourcegroups.GetGroupOutput{
+		Group: &resourcegroups.Group{GroupArn: in.Group},
+	}, nil
+}
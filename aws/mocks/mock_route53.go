@@ -0,0 +1,132 @@
+package mocks
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// Route53Client returns
+type Route53Client struct {
+	aws.Route53API
+	mu sync.Mutex // Services are deployed concurrently
+	Throttler
+
+	Records                        map[string]*route53.ResourceRecordSet
+	ChangeResourceRecordSetsInputs []*route53.ChangeResourceRecordSetsInput
+}
+
+func (m *Route53Client) init() {
+	if m.Records == nil {
+		m.Records = map[string]*route53.ResourceRecordSet{}
+	}
+}
+
+func recordKey(zoneID *string, record *route53.ResourceRecordSet) string {
+	return fmt.Sprintf("%v|%v|%v|%v", to.Strs(zoneID), to.Strs(record.Name), to.Strs(record.Type), to.Strs(record.SetIdentifier))
+}
+
+// AddWeightedRecord adds a weighted CNAME record
+func (m *Route53Client) AddWeightedRecord(zoneID string, name string, setID string, target string, weight int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	record := &route53.ResourceRecordSet{
+		Name:            to.Strp(name),
+		Type:            to.Strp(route53.RRTypeCname),
+		SetIdentifier:   to.Strp(setID),
+		Weight:          &weight,
+		TTL:             to.Int64p(60),
+		ResourceRecords: []*route53.ResourceRecord{&route53.ResourceRecord{Value: to.Strp(target)}},
+	}
+	m.Records[recordKey(&zoneID, record)] = record
+}
+
+// WeightedRecords returns the weights of the records with name by set identifier
+func (m *Route53Client) WeightedRecords(zoneID string, name string) map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	weights := map[string]int64{}
+	for key, record := range m.Records {
+		if key == recordKey(&zoneID, record) && to.Strs(record.Name) == name && record.Weight != nil {
+			weights[to.Strs(record.SetIdentifier)] = *record.Weight
+		}
+	}
+	return weights
+}
+
+// ListResourceRecordSets returns the zones records sorted by name starting at StartRecordName
+func (m *Route53Client) ListResourceRecordSets(in *route53.ListResourceRecordSetsInput) (*route53.ListResourceRecordSetsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("ListResourceRecordSets"); err != nil {
+		return nil, err
+	}
+	m.init()
+
+	records := []*route53.ResourceRecordSet{}
+	for key, record := range m.Records {
+		if key != recordKey(in.HostedZoneId, record) {
+			continue
+		}
+
+		if in.StartRecordName != nil && *record.Name < *in.StartRecordName {
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return recordKey(nil, records[i]) < recordKey(nil, records[j])
+	})
+
+	return &route53.ListResourceRecordSetsOutput{
+		ResourceRecordSets: records,
+		IsTruncated:        to.Boolp(false),
+	}, nil
+}
+
+// ChangeResourceRecordSets applies all the changes or none of them
+func (m *Route53Client) ChangeResourceRecordSets(in *route53.ChangeResourceRecordSetsInput) (*route53.ChangeResourceRecordSetsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("ChangeResourceRecordSets"); err != nil {
+		return nil, err
+	}
+	m.init()
+
+	records := map[string]*route53.ResourceRecordSet{}
+	for key, record := range m.Records {
+		records[key] = record
+	}
+
+	for _, change := range in.ChangeBatch.Changes {
+		key := recordKey(in.HostedZoneId, change.ResourceRecordSet)
+		_, exists := records[key]
+
+		switch *change.Action {
+		case route53.ChangeActionCreate:
+			if exists {
+				return nil, awserr.New(route53.ErrCodeInvalidChangeBatch, "record already exists", nil)
+			}
+			records[key] = change.ResourceRecordSet
+		case route53.ChangeActionUpsert:
+			records[key] = change.ResourceRecordSet
+		case route53.ChangeActionDelete:
+			if !exists {
+				return nil, awserr.New(route53.ErrCodeInvalidChangeBatch, "record not found", nil)
+			}
+			delete(records, key)
+		}
+	}
+
+	m.Records = records
+	m.ChangeResourceRecordSetsInputs = append(m.ChangeResourceRecordSetsInputs, in)
+	return &route53.ChangeResourceRecordSetsOutput{}, nil
+}
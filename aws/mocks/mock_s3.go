@@ -0,0 +1,68 @@
+package mocks
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/coinbase/step/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+)
+
+// S3Client returns
+type S3Client struct {
+	mocks.MockS3Client
+	mu sync.Mutex // Services are deployed concurrently
+
+	PutObjectInputs []*s3.PutObjectInput
+}
+
+// PutObject records the input then stores the object
+func (m *S3Client) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.PutObjectInputs = append(m.PutObjectInputs, in)
+
+	out, err := m.MockS3Client.PutObject(in)
+
+	// Leave the body readable for assertions
+	if in.Body != nil {
+		in.Body.Seek(0, io.SeekStart)
+	}
+
+	return out, err
+}
+
+// PutObjectKeys returns the keys written in order
+func (m *S3Client) PutObjectKeys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := []string{}
+	for _, in := range m.PutObjectInputs {
+		keys = append(keys, *in.Key)
+	}
+	return keys
+}
+
+// ListObjectsV2 returns every stored object under the prefix in key order, in one page
+func (m *S3Client) ListObjectsV2(in *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := []string{}
+	for key := range m.GetObjectResp {
+		if in.Prefix == nil || strings.HasPrefix(key, *in.Prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	contents := []*s3.Object{}
+	for _, key := range keys {
+		contents = append(contents, &s3.Object{Key: to.Strp(key)})
+	}
+
+	return &s3.ListObjectsV2Output{Contents: contents, IsTruncated: to.Boolp(false)}, nil
+}
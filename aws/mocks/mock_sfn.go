@@ -0,0 +1,97 @@
+package mocks
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/coinbase/step/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+)
+
+// SFNClient returns the added executions, otherwise the step mock responses
+type SFNClient struct {
+	mocks.MockSFNClient
+	mu sync.Mutex
+	Throttler
+
+	// Executions by ARN
+	Executions map[string]*sfn.DescribeExecutionOutput
+
+	DescribeExecutionInputs []*sfn.DescribeExecutionInput
+}
+
+func (m *SFNClient) init() {
+	if m.Executions == nil {
+		m.Executions = map[string]*sfn.DescribeExecutionOutput{}
+	}
+}
+
+// AddExecution adds an execution with a status e.g. "RUNNING" or "FAILED" and input marshalled to JSON
+func (m *SFNClient) AddExecution(arn string, name string, status string, input interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+
+	raw, _ := json.Marshal(input)
+	m.Executions[arn] = &sfn.DescribeExecutionOutput{
+		ExecutionArn: to.Strp(arn),
+		Name:         to.Strp(name),
+		Status:       to.Strp(status),
+		Input:        to.Strp(string(raw)),
+	}
+}
+
+// ListExecutions returns the added executions with the StatusFilter
+func (m *SFNClient) ListExecutions(in *sfn.ListExecutionsInput) (*sfn.ListExecutionsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("ListExecutions"); err != nil {
+		return nil, err
+	}
+	m.init()
+
+	if len(m.Executions) == 0 {
+		return m.MockSFNClient.ListExecutions(in)
+	}
+
+	arns := []string{}
+	for arn := range m.Executions {
+		arns = append(arns, arn)
+	}
+	sort.Strings(arns)
+
+	items := []*sfn.ExecutionListItem{}
+	for _, arn := range arns {
+		exec := m.Executions[arn]
+		if in.StatusFilter != nil && *in.StatusFilter != *exec.Status {
+			continue
+		}
+
+		items = append(items, &sfn.ExecutionListItem{
+			ExecutionArn: exec.ExecutionArn,
+			Name:         exec.Name,
+			Status:       exec.Status,
+		})
+	}
+
+	return &sfn.ListExecutionsOutput{Executions: items}, nil
+}
+
+// DescribeExecution returns the added execution
+func (m *SFNClient) DescribeExecution(in *sfn.DescribeExecutionInput) (*sfn.DescribeExecutionOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("DescribeExecution"); err != nil {
+		return nil, err
+	}
+	m.init()
+	m.DescribeExecutionInputs = append(m.DescribeExecutionInputs, in)
+
+	if exec, ok := m.Executions[to.Strs(in.ExecutionArn)]; ok {
+		return exec, nil
+	}
+
+	return m.MockSFNClient.DescribeExecution(in)
+}
@@ -3,14 +3,47 @@ package mocks
 import (
 	"github.com/aws/aws-sdk-go/service/sns"
 	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+	"sync"
 )
 
 // SNSClient returns
 type SNSClient struct {
 	aws.SNSAPI
+	mu sync.Mutex // Services are deployed concurrently
+	Throttler
+
+	PublishInputs []*sns.PublishInput
 }
 
 // GetTopicAttributes returns
 func (m *SNSClient) GetTopicAttributes(in *sns.GetTopicAttributesInput) (*sns.GetTopicAttributesOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("GetTopicAttributes"); err != nil {
+		return nil, err
+	}
 	return nil, nil
 }
+
+// Publish records the published messages
+func (m *SNSClient) Publish(in *sns.PublishInput) (*sns.PublishOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("Publish"); err != nil {
+		return nil, err
+	}
+	m.PublishInputs = append(m.PublishInputs, in)
+	return &sns.PublishOutput{MessageId: to.Strp("id")}, nil
+}
+
+// Messages returns the published messages in order
+func (m *SNSClient) Messages() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	messages := []string{}
+	for _, in := range m.PublishInputs {
+		messages = append(messages, to.Strs(in.Message))
+	}
+	return messages
+}
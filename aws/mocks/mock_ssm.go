@@ -0,0 +1,54 @@
+package mocks
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// SSMClient returns
+type SSMClient struct {
+	aws.SSMAPI
+	mu sync.Mutex
+	Throttler
+
+	GetParameterInputs []*ssm.GetParameterInput
+	Parameters         map[string]string
+}
+
+func (m *SSMClient) init() {
+	if m.Parameters == nil {
+		m.Parameters = map[string]string{}
+	}
+}
+
+// AddParameter makes the parameter name return value
+func (m *SSMClient) AddParameter(name string, value string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.Parameters[name] = value
+}
+
+// GetParameter returns the added parameter, or a not found error
+func (m *SSMClient) GetParameter(in *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("GetParameter"); err != nil {
+		return nil, err
+	}
+	m.init()
+	m.GetParameterInputs = append(m.GetParameterInputs, in)
+
+	value, ok := m.Parameters[to.Strs(in.Name)]
+	if !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "Parameter not found", nil)
+	}
+
+	return &ssm.GetParameterOutput{
+		Parameter: &ssm.Parameter{Name: in.Name, Value: to.Strp(value)},
+	}, nil
+}
@@ -0,0 +1,62 @@
+package mocks
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// STSClient returns the identity of the role the client was last created with
+type STSClient struct {
+	aws.STSAPI
+	mu sync.Mutex
+	Throttler
+
+	accountID string
+	role      string
+
+	GetCallerIdentityInputs []*sts.GetCallerIdentityInput
+
+	// DeniedRoles cannot be assumed by role name
+	DeniedRoles map[string]bool
+}
+
+// DenyRole makes the role fail to be assumed
+func (m *STSClient) DenyRole(role string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.DeniedRoles == nil {
+		m.DeniedRoles = map[string]bool{}
+	}
+	m.DeniedRoles[role] = true
+}
+
+func (m *STSClient) assumeRole(accountID *string, role *string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accountID = to.Strs(accountID)
+	m.role = to.Strs(role)
+}
+
+// GetCallerIdentity returns the assumed role, or access denied if the role is denied
+func (m *STSClient) GetCallerIdentity(in *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.throttle("GetCallerIdentity"); err != nil {
+		return nil, err
+	}
+	m.GetCallerIdentityInputs = append(m.GetCallerIdentityInputs, in)
+
+	if m.DeniedRoles[m.role] {
+		return nil, awserr.New("AccessDenied", fmt.Sprintf("not authorized to perform sts:AssumeRole on %v", m.role), nil)
+	}
+
+	return &sts.GetCallerIdentityOutput{
+		Account: to.Strp(m.accountID),
+		Arn:     to.Strp(fmt.Sprintf("arn:aws:sts::%v:assumed-role/%v/odin", m.accountID, m.role)),
+	}, nil
+}
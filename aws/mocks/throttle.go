@@ -0,0 +1,47 @@
+package mocks
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// Throttler makes a mocks calls fail with a throttling error a number of times before succeeding
+type Throttler struct {
+	throttleMu sync.Mutex
+	throttles  map[string]int
+	calls      map[string]int
+}
+
+// AddThrottles makes the next n calls to method return a throttling error
+func (t *Throttler) AddThrottles(method string, n int) {
+	t.throttleMu.Lock()
+	defer t.throttleMu.Unlock()
+	if t.throttles == nil {
+		t.throttles = map[string]int{}
+	}
+	t.throttles[method] += n
+}
+
+// Calls returns how many times method was called, including throttled calls
+func (t *Throttler) Calls(method string) int {
+	t.throttleMu.Lock()
+	defer t.throttleMu.Unlock()
+	return t.calls[method]
+}
+
+func (t *Throttler) throttle(method string) error {
+	t.throttleMu.Lock()
+	defer t.throttleMu.Unlock()
+	if t.calls == nil {
+		t.calls = map[string]int{}
+	}
+	t.calls[method]++
+
+	if t.throttles[method] <= 0 {
+		return nil
+	}
+
+	t.throttles[method]--
+	return awserr.NewRequestFailure(awserr.New("Throttling", "Rate exceeded", nil), 400, "request-id")
+}
@@ -0,0 +1,117 @@
+package route53
+
+import (
+	"strings"
+
+	aws_route53 "github.com/aws/aws-sdk-go/service/route53"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// Record is a weighted CNAME record
+type Record struct {
+	Name          *string `json:"name,omitempty"`
+	SetIdentifier *string `json:"set_identifier,omitempty"`
+	Target        *string `json:"target,omitempty"`
+	Weight        *int64  `json:"weight,omitempty"`
+	TTL           *int64  `json:"ttl,omitempty"`
+}
+
+// FQDN returns name with the trailing dot Route53 returns record names with
+func FQDN(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// FindWeightedRecords returns the weighted CNAME records for name in the hosted zone
+func FindWeightedRecords(r53c aws.Route53API, zoneID *string, name *string) ([]*Record, error) {
+	fqdn := to.Strp(FQDN(*name))
+	input := &aws_route53.ListResourceRecordSetsInput{
+		HostedZoneId:    zoneID,
+		StartRecordName: fqdn,
+		StartRecordType: to.Strp(aws_route53.RRTypeCname),
+	}
+
+	records := []*Record{}
+	for {
+		output, err := r53c.ListResourceRecordSets(input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rs := range output.ResourceRecordSets {
+			if rs.Name == nil || *rs.Name != *fqdn {
+				// Records are sorted by name so every record for name has been seen
+				return records, nil
+			}
+
+			if to.Strs(rs.Type) != aws_route53.RRTypeCname || rs.SetIdentifier == nil || rs.Weight == nil {
+				continue
+			}
+
+			record := &Record{
+				Name:          rs.Name,
+				SetIdentifier: rs.SetIdentifier,
+				Weight:        rs.Weight,
+				TTL:           rs.TTL,
+			}
+
+			if len(rs.ResourceRecords) > 0 {
+				record.Target = rs.ResourceRecords[0].Value
+			}
+
+			records = append(records, record)
+		}
+
+		if output.IsTruncated == nil || !*output.IsTruncated {
+			return records, nil
+		}
+
+		input.StartRecordName = output.NextRecordName
+		input.StartRecordType = output.NextRecordType
+		input.StartRecordIdentifier = output.NextRecordIdentifier
+	}
+}
+
+// Upsert returns the change that creates or updates the record
+func (r *Record) Upsert() *aws_route53.Change {
+	return r.change(aws_route53.ChangeActionUpsert)
+}
+
+// Delete returns the change that deletes the record, it must match the record exactly
+func (r *Record) Delete() *aws_route53.Change {
+	return r.change(aws_route53.ChangeActionDelete)
+}
+
+func (r *Record) change(action string) *aws_route53.Change {
+	return &aws_route53.Change{
+		Action: to.Strp(action),
+		ResourceRecordSet: &aws_route53.ResourceRecordSet{
+			Name:            to.Strp(FQDN(to.Strs(r.Name))),
+			Type:            to.Strp(aws_route53.RRTypeCname),
+			SetIdentifier:   r.SetIdentifier,
+			Weight:          r.Weight,
+			TTL:             r.TTL,
+			ResourceRecords: []*aws_route53.ResourceRecord{&aws_route53.ResourceRecord{Value: r.Target}},
+		},
+	}
+}
+
+// Change applies all the changes to the hosted zone at once
+func Change(r53c aws.Route53API, zoneID *string, comment string, changes []*aws_route53.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	_, err := r53c.ChangeResourceRecordSets(&aws_route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: zoneID,
+		ChangeBatch: &aws_route53.ChangeBatch{
+			Comment: to.Strp(comment),
+			Changes: changes,
+		},
+	})
+
+	return err
+}
@@ -0,0 +1,41 @@
+package route53
+
+import (
+	"testing"
+
+	aws_route53 "github.com/aws/aws-sdk-go/service/route53"
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FindWeightedRecords_Change(t *testing.T) {
+	r53c := &mocks.Route53Client{}
+	r53c.AddWeightedRecord("zone", "a.example.com.", "old", "old-elb", 100)
+	r53c.AddWeightedRecord("zone", "b.example.com.", "other", "other-elb", 100)
+	r53c.AddWeightedRecord("other-zone", "a.example.com.", "other", "other-elb", 100)
+
+	records, err := FindWeightedRecords(r53c, to.Strp("zone"), to.Strp("a.example.com"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, "old", *records[0].SetIdentifier)
+	assert.Equal(t, "old-elb", *records[0].Target)
+
+	record := &Record{
+		Name:          to.Strp("a.example.com"),
+		SetIdentifier: to.Strp("new"),
+		Target:        to.Strp("new-elb"),
+		Weight:        to.Int64p(100),
+		TTL:           to.Int64p(60),
+	}
+
+	records[0].Weight = to.Int64p(0)
+	assert.NoError(t, Change(r53c, to.Strp("zone"), "cutover", []*aws_route53.Change{record.Upsert(), records[0].Upsert()}))
+	assert.Equal(t, map[string]int64{"old": 0, "new": 100}, r53c.WeightedRecords("zone", "a.example.com."))
+
+	assert.NoError(t, Change(r53c, to.Strp("zone"), "cleanup", []*aws_route53.Change{records[0].Delete()}))
+	assert.Equal(t, map[string]int64{"new": 100}, r53c.WeightedRecords("zone", "a.example.com."))
+
+	// Deleting a missing record fails
+	assert.Error(t, Change(r53c, to.Strp("zone"), "cleanup", []*aws_route53.Change{records[0].Delete()}))
+}
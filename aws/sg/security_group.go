@@ -15,6 +15,7 @@ type SecurityGroup struct {
 	ConfigNameTag  *string
 	ServiceNameTag *string
 	GroupID        *string
+	IpPermissions  []*ec2.IpPermission
 }
 
 // ProjectName returns tag
@@ -42,6 +43,41 @@ func (s *SecurityGroup) AllowedService() *string {
 	return to.Strp(fmt.Sprintf("%s::%s::%s", *s.ProjectName(), *s.ConfigName(), *s.ServiceName()))
 }
 
+// AllowsIngress returns true if an ingress rule allows TCP on port from one of the source
+// security groups, or from any IP range
+func (s *SecurityGroup) AllowsIngress(port int64, sourceGroupIDs []*string) bool {
+	for _, perm := range s.IpPermissions {
+		if !permitsTCPPort(perm, port) {
+			continue
+		}
+
+		if len(perm.IpRanges) > 0 || len(perm.Ipv6Ranges) > 0 {
+			return true
+		}
+
+		for _, pair := range perm.UserIdGroupPairs {
+			for _, id := range sourceGroupIDs {
+				if pair.GroupId != nil && id != nil && *pair.GroupId == *id {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func permitsTCPPort(perm *ec2.IpPermission, port int64) bool {
+	switch to.Strs(perm.IpProtocol) {
+	case "-1":
+		return true // All traffic
+	case "tcp", "6":
+		return perm.FromPort != nil && perm.ToPort != nil && *perm.FromPort <= port && port <= *perm.ToPort
+	}
+
+	return false
+}
+
 // Find returns the security groups with tags
 func Find(ec2Client aws.EC2API, nameTags []*string) ([]*SecurityGroup, error) {
 	output, err := ec2Client.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
@@ -98,6 +134,7 @@ func newSGs(output []*ec2.SecurityGroup) []*SecurityGroup {
 			ProjectNameTag: aws.FetchEc2Tag(sg.Tags, to.Strp("ProjectName")),
 			ConfigNameTag:  aws.FetchEc2Tag(sg.Tags, to.Strp("ConfigName")),
 			ServiceNameTag: aws.FetchEc2Tag(sg.Tags, to.Strp("ServiceName")),
+			IpPermissions:  sg.IpPermissions,
 		})
 	}
 
@@ -3,6 +3,7 @@ package sg
 import (
 	"testing"
 
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/coinbase/odin/aws/mocks"
 	"github.com/coinbase/step/utils/to"
 	"github.com/stretchr/testify/assert"
@@ -20,3 +21,32 @@ func Test_Find(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(sgs))
 }
+
+func Test_SecurityGroup_AllowsIngress(t *testing.T) {
+	group := &SecurityGroup{IpPermissions: []*ec2.IpPermission{
+		&ec2.IpPermission{
+			IpProtocol:       to.Strp("tcp"),
+			FromPort:         to.Int64p(8000),
+			ToPort:           to.Int64p(8100),
+			UserIdGroupPairs: []*ec2.UserIdGroupPair{&ec2.UserIdGroupPair{GroupId: to.Strp("lb-sg")}},
+		},
+		&ec2.IpPermission{
+			IpProtocol: to.Strp("udp"),
+			FromPort:   to.Int64p(9000),
+			ToPort:     to.Int64p(9000),
+			IpRanges:   []*ec2.IpRange{&ec2.IpRange{CidrIp: to.Strp("10.0.0.0/8")}},
+		},
+	}}
+
+	assert.True(t, group.AllowsIngress(8080, []*string{to.Strp("lb-sg")}))
+	assert.False(t, group.AllowsIngress(8080, []*string{to.Strp("other-sg")}))
+	assert.False(t, group.AllowsIngress(8200, []*string{to.Strp("lb-sg")}))
+	assert.False(t, group.AllowsIngress(9000, []*string{to.Strp("lb-sg")}))
+
+	// All traffic from an IP range
+	group.IpPermissions = append(group.IpPermissions, &ec2.IpPermission{
+		IpProtocol: to.Strp("-1"),
+		IpRanges:   []*ec2.IpRange{&ec2.IpRange{CidrIp: to.Strp("10.0.0.0/8")}},
+	})
+	assert.True(t, group.AllowsIngress(9000, []*string{to.Strp("other-sg")}))
+}
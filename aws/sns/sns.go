@@ -13,3 +13,13 @@ func TopicExists(snsc aws.SNSAPI, topicARN *string) error {
 
 	return err
 }
+
+// Publish publishes a message to the SNS topic
+func Publish(snsc aws.SNSAPI, topicARN *string, message string) error {
+	_, err := snsc.Publish(&sns.PublishInput{
+		TopicArn: topicARN,
+		Message:  &message,
+	})
+
+	return err
+}
@@ -0,0 +1,37 @@
+package ssm
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// GetParameter returns the value of the parameter, a missing parameter is an error
+func GetParameter(ssmc aws.SSMAPI, name *string) (*string, error) {
+	return getParameter(ssmc, &ssm.GetParameterInput{Name: name})
+}
+
+// GetSecureParameter returns the decrypted value of the parameter, e.g. a SecureString
+func GetSecureParameter(ssmc aws.SSMAPI, name *string) (*string, error) {
+	return getParameter(ssmc, &ssm.GetParameterInput{Name: name, WithDecryption: to.Boolp(true)})
+}
+
+func getParameter(ssmc aws.SSMAPI, input *ssm.GetParameterInput) (*string, error) {
+	out, err := ssmc.GetParameter(input)
+
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterNotFound {
+			return nil, fmt.Errorf("SSM parameter %v not found", *input.Name)
+		}
+		return nil, err
+	}
+
+	if out.Parameter == nil || out.Parameter.Value == nil {
+		return nil, fmt.Errorf("SSM parameter %v has no value", *input.Name)
+	}
+
+	return out.Parameter.Value, nil
+}
@@ -25,6 +25,19 @@ func Test_Find_IDs_and_Tags(t *testing.T) {
 	assert.Equal(t, 1, len(sgs))
 }
 
+func Test_Find_Tags(t *testing.T) {
+	ec2c := &mocks.EC2Client{}
+	ec2c.AddSubnetWithTags("private-subnet1", "subnet-asd1", "us-east-1a", map[string]string{"Tier": "private"})
+
+	sns, err := Find(ec2c, []*string{to.Strp("private-subnet1")})
+	assert.NoError(t, err)
+	assert.Equal(t, "private", sns[0].Tags["Tier"])
+	assert.True(t, sns[0].HasTags(map[string]string{"Tier": "private", "DeployWith": "odin"}))
+	assert.True(t, sns[0].HasTags(nil))
+	assert.False(t, sns[0].HasTags(map[string]string{"Tier": "public"}))
+	assert.False(t, sns[0].HasTags(map[string]string{"Zone": "private"}))
+}
+
 func Test_isID(t *testing.T) {
 	assert.True(t, isID("subnet-asfasf"))
 	assert.False(t, isID("ubuntu"))
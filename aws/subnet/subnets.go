@@ -10,8 +10,21 @@ import (
 
 // Subnet struct
 type Subnet struct {
-	SubnetID      *string
-	DeployWithTag *string
+	SubnetID         *string
+	DeployWithTag    *string
+	AvailabilityZone *string
+	Tags             map[string]string
+}
+
+// HasTags returns true if the subnet has every tag with its value
+func (s *Subnet) HasTags(tags map[string]string) bool {
+	for key, value := range tags {
+		if v, ok := s.Tags[key]; !ok || v != value {
+			return false
+		}
+	}
+
+	return true
 }
 
 // Find returns a list of subnets for either ids or tags NO MIXING , e.g. subnet-00000000 OR privatea
@@ -91,9 +104,18 @@ func find(ec2Client aws.EC2API, in *ec2.DescribeSubnetsInput) ([]*Subnet, error)
 
 	subnets := []*Subnet{}
 	for _, subnet := range output.Subnets {
+		tags := map[string]string{}
+		for _, tag := range subnet.Tags {
+			if tag != nil && tag.Key != nil {
+				tags[*tag.Key] = to.Strs(tag.Value)
+			}
+		}
+
 		subnets = append(subnets, &Subnet{
-			subnet.SubnetId,
-			aws.FetchEc2Tag(subnet.Tags, to.Strp("DeployWith")),
+			SubnetID:         subnet.SubnetId,
+			DeployWithTag:    aws.FetchEc2Tag(subnet.Tags, to.Strp("DeployWith")),
+			AvailabilityZone: subnet.AvailabilityZone,
+			Tags:             tags,
 		})
 	}
 
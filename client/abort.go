@@ -0,0 +1,31 @@
+package client
+
+import (
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/deployer/models"
+	"github.com/coinbase/step/bifrost"
+)
+
+// Abort cleans up the release with the UUID whose execution was stopped mid-deploy
+func Abort(step_fn *string, projectName *string, configName *string, releaseID *string, uuid *string) error {
+	return abort(&aws.ClientsStr{}, step_fn, projectName, configName, releaseID, uuid)
+}
+
+func abort(awsc aws.Clients, lambdaName *string, projectName *string, configName *string, releaseID *string, uuid *string) error {
+	// The deployer defaults the region, account and bucket to its own
+	input := &models.AbortInput{
+		Release: models.Release{Release: bifrost.Release{
+			ProjectName: projectName,
+			ConfigName:  configName,
+			ReleaseID:   releaseID,
+			UUID:        uuid,
+		}},
+	}
+
+	var result models.AbortResult
+	if err := invokeTask(awsc.LambdaClient(nil, nil, nil), lambdaName, "Abort", input, &result); err != nil {
+		return err
+	}
+
+	return printJSON(&result)
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/coinbase/odin/aws"
 	"github.com/coinbase/odin/deployer/models"
 	"github.com/coinbase/step/execution"
 	"github.com/coinbase/step/utils/is"
@@ -81,7 +82,7 @@ func parseUserData(releaseFile string) (*string, error) {
 	return to.Strp(string(rawUserData)), nil
 }
 
-func releaseFromFile(releaseFile *string, region *string, accountID *string) (*models.Release, error) {
+func releaseFromFile(awsc aws.Clients, releaseFile *string, region *string, accountID *string) (*models.Release, error) {
 	release, err := parseRelease(*releaseFile)
 	if err != nil {
 		return nil, err
@@ -93,7 +94,6 @@ func releaseFromFile(releaseFile *string, region *string, accountID *string) (*m
 	}
 
 	release.SetUserData(userdata)
-	release.UserDataSHA256 = to.Strp(to.SHA256Str(userdata))
 
 	prepareRelease(release, region, accountID)
 
@@ -101,9 +101,30 @@ func releaseFromFile(releaseFile *string, region *string, accountID *string) (*m
 		return nil, err
 	}
 
+	if err := setUserDataSHA256(awsc.KMSClient(region, nil, nil), release); err != nil {
+		return nil, err
+	}
+
 	return release, nil
 }
 
+// setUserDataSHA256 sets the SHA of the rendered user data, the deployer renders the same template to check it
+// KMS encrypted user data is uploaded as is, but its SHA is of the rendered plaintext
+func setUserDataSHA256(kmsc aws.KMSAPI, release *models.Release) error {
+	plaintext, err := models.DecryptUserData(kmsc, release.UserData())
+	if err != nil {
+		return err
+	}
+
+	rendered, err := release.RenderUserData(plaintext)
+	if err != nil {
+		return err
+	}
+
+	release.UserDataSHA256 = to.Strp(to.SHA256Str(rendered))
+	return nil
+}
+
 func stateName(sd *execution.StateDetails) string {
 	stateName := ""
 	if sd.LastTaskName != nil {
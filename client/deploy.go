@@ -14,14 +14,15 @@ import (
 // Deploy attempts to deploy release
 func Deploy(step_fn *string, releaseFile *string) error {
 	region, accountID := to.RegionAccount()
-	release, err := releaseFromFile(releaseFile, region, accountID)
+	awsc := &aws.ClientsStr{}
+	release, err := releaseFromFile(awsc, releaseFile, region, accountID)
 	if err != nil {
 		return err
 	}
 
 	deployerARN := to.StepArn(region, accountID, step_fn)
 
-	return deploy(&aws.ClientsStr{}, release, deployerARN)
+	return deploy(awsc, release, deployerARN)
 }
 
 func kMSKey() *string {
@@ -30,13 +31,7 @@ func kMSKey() *string {
 }
 
 func deploy(awsc aws.Clients, release *models.Release, deployerARN *string) error {
-	// Uploading the Release to S3 to match SHAs
-	if err := s3.PutStruct(awsc.S3Client(nil, nil, nil), release.Bucket, release.ReleasePath(), release); err != nil {
-		return err
-	}
-
-	// Uploading the encrypted Userdata to S3
-	if err := s3.PutSecure(awsc.S3Client(nil, nil, nil), release.Bucket, release.UserDataPath(), release.UserData(), kMSKey()); err != nil {
+	if err := uploadRelease(awsc, release); err != nil {
 		return err
 	}
 
@@ -51,6 +46,17 @@ func deploy(awsc aws.Clients, release *models.Release, deployerARN *string) erro
 	return nil
 }
 
+// uploadRelease uploads the release and its encrypted user data for the deployer to validate
+func uploadRelease(awsc aws.Clients, release *models.Release) error {
+	// Uploading the Release to S3 to match SHAs
+	if err := s3.PutStruct(awsc.S3Client(nil, nil, nil), release.Bucket, release.ReleasePath(), release); err != nil {
+		return err
+	}
+
+	// Uploading the encrypted Userdata to S3
+	return s3.PutSecure(awsc.S3Client(nil, nil, nil), release.Bucket, release.UserDataPath(), release.UserData(), kMSKey())
+}
+
 func findOrCreateExec(sfnc sfniface.SFNAPI, deployer *string, release *models.Release) (*execution.Execution, error) {
 	exec, err := execution.FindExecution(sfnc, deployer, release.ExecutionPrefix())
 	if err != nil {
@@ -12,14 +12,15 @@ import (
 // Halt attempts to halt release
 func Halt(step_fn *string, releaseFile *string) error {
 	region, accountID := to.RegionAccount()
-	release, err := releaseFromFile(releaseFile, region, accountID)
+	awsc := &aws.ClientsStr{}
+	release, err := releaseFromFile(awsc, releaseFile, region, accountID)
 	if err != nil {
 		return err
 	}
 
 	deployerARN := to.StepArn(region, accountID, step_fn)
 
-	return halt(&aws.ClientsStr{}, release, deployerARN)
+	return halt(awsc, release, deployerARN)
 }
 
 func halt(awsc aws.Clients, release *models.Release, deployerARN *string) error {
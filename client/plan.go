@@ -0,0 +1,33 @@
+package client
+
+import (
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/deployer/models"
+	"github.com/coinbase/step/utils/to"
+)
+
+// Plan prints what deploying the release would create, nothing is locked, created or changed
+func Plan(step_fn *string, releaseFile *string) error {
+	region, accountID := to.RegionAccount()
+	awsc := &aws.ClientsStr{}
+	release, err := releaseFromFile(awsc, releaseFile, region, accountID)
+	if err != nil {
+		return err
+	}
+
+	return plan(awsc, release, step_fn)
+}
+
+func plan(awsc aws.Clients, release *models.Release, lambdaName *string) error {
+	// The deployer validates the release against its uploaded SHA and user data, like a deploy
+	if err := uploadRelease(awsc, release); err != nil {
+		return err
+	}
+
+	var result models.Plan
+	if err := invokeTask(awsc.LambdaClient(nil, nil, nil), lambdaName, "Plan", release, &result); err != nil {
+		return err
+	}
+
+	return printJSON(&result)
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/deployer/models"
+	"github.com/coinbase/step/bifrost"
+)
+
+// Prune deletes the resources of the project config left by releases that died before they were cleaned up
+// With dryRun it only prints what would be deleted
+func Prune(step_fn *string, projectName *string, configName *string, dryRun bool) error {
+	return prune(&aws.ClientsStr{}, step_fn, projectName, configName, dryRun)
+}
+
+func prune(awsc aws.Clients, lambdaName *string, projectName *string, configName *string, dryRun bool) error {
+	// The deployer defaults the region, account and bucket to its own
+	input := &models.PruneInput{
+		Release: models.Release{Release: bifrost.Release{ProjectName: projectName, ConfigName: configName}},
+		DryRun:  dryRun,
+	}
+
+	var result models.PruneResult
+	if err := invokeTask(awsc.LambdaClient(nil, nil, nil), lambdaName, "Prune", input, &result); err != nil {
+		return err
+	}
+
+	return printJSON(&result)
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/deployer/models"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/bifrost"
+	"github.com/coinbase/step/utils/to"
+)
+
+// Rollback attempts to deploy the release replaced by the last successful release
+// The release is executed on the rollback state machine of the deployer, step_fn with a "-rollback" suffix
+func Rollback(step_fn *string, projectName *string, configName *string) error {
+	region, accountID := to.RegionAccount()
+	release, err := rollbackRelease(projectName, configName, region, accountID)
+	if err != nil {
+		return err
+	}
+
+	deployerARN := to.StepArn(region, accountID, rollbackStepFn(step_fn))
+
+	return rollback(&aws.ClientsStr{}, release, deployerARN)
+}
+
+// rollbackStepFn is the name of the rollback state machine of the deployer step_fn
+func rollbackStepFn(step_fn *string) *string {
+	return to.Strp(to.Strs(step_fn) + models.RollbackStateMachineSuffix)
+}
+
+// rollbackRelease returns a new release asking the deployer to roll back the project config
+// The deployer reads the previous release and its user data from the rollback plan
+func rollbackRelease(projectName *string, configName *string, region *string, accountID *string) (*models.Release, error) {
+	release := &models.Release{
+		Release: bifrost.Release{
+			ProjectName: projectName,
+			ConfigName:  configName,
+		},
+	}
+
+	prepareRelease(release, region, accountID)
+
+	if err := validateClientAttributes(release); err != nil {
+		return nil, err
+	}
+
+	return release, nil
+}
+
+func rollback(awsc aws.Clients, release *models.Release, deployerARN *string) error {
+	// Uploading the Release to S3 to match SHAs, there is no user data to upload
+	if err := s3.PutStruct(awsc.S3Client(nil, nil, nil), release.Bucket, release.ReleasePath(), release); err != nil {
+		return err
+	}
+
+	exec, err := findOrCreateExec(awsc.SFNClient(nil, nil, nil), deployerARN, release)
+	if err != nil {
+		return err
+	}
+
+	// Execute every second
+	exec.WaitForExecution(awsc.SFNClient(nil, nil, nil), 1, waiter)
+	fmt.Println("")
+	return nil
+}
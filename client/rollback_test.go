@@ -0,0 +1,39 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/odin/deployer/models"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RollbackRelease(t *testing.T) {
+	awsc := mocks.MockAWS()
+
+	release, err := rollbackRelease(to.Strp("project"), to.Strp("config"), to.Strp("region"), to.Strp("accountid"))
+	assert.NoError(t, err)
+
+	// A new release that only identifies the project config, the rollback state machine rolls it back
+	assert.False(t, release.Rollback)
+	assert.NotNil(t, release.ReleaseID)
+	assert.Nil(t, release.Services)
+
+	assert.NoError(t, rollback(awsc, release, to.Strp("deployerARN")))
+
+	var uploaded models.Release
+	assert.NoError(t, s3.GetStruct(awsc.S3, release.Bucket, release.ReleasePath(), &uploaded))
+	assert.Equal(t, release.ReleaseID, uploaded.ReleaseID)
+	assert.Nil(t, uploaded.Services)
+}
+
+func Test_RollbackStepFn(t *testing.T) {
+	assert.Equal(t, "coinbase-odin-rollback", *rollbackStepFn(to.Strp("coinbase-odin")))
+}
+
+func Test_RollbackRelease_NoProject(t *testing.T) {
+	_, err := rollbackRelease(nil, to.Strp("config"), to.Strp("region"), to.Strp("accountid"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/lambda"
+)
+
+// taskMessage is the message the deployers Lambda routes to a single task handler
+type taskMessage struct {
+	Task  string      `json:"Task"`
+	Input interface{} `json:"Input"`
+}
+
+// invokeTask invokes a task of the deployers Lambda outside of the state machine, the Lambda
+// has the same name as the state machine. The response is unmarshalled into output
+func invokeTask(lambdac aws.LambdaAPI, lambdaName *string, task string, input interface{}, output interface{}) error {
+	payload, err := json.Marshal(&taskMessage{task, input})
+	if err != nil {
+		return err
+	}
+
+	raw, err := lambda.Invoke(lambdac, lambdaName, payload)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, output)
+}
+
+// printJSON prints the indented JSON of v
+func printJSON(v interface{}) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(raw))
+	return nil
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func invokedTask(t *testing.T, awsc *mocks.MockClients) map[string]interface{} {
+	assert.Equal(t, 1, len(awsc.Lambda.InvokeInputs))
+	assert.Equal(t, "coinbase-odin", to.Strs(awsc.Lambda.InvokeInputs[0].FunctionName))
+
+	var message map[string]interface{}
+	assert.NoError(t, json.Unmarshal(awsc.Lambda.InvokeInputs[0].Payload, &message))
+	return message
+}
+
+func Test_Plan(t *testing.T) {
+	awsc := mocks.MockAWS()
+	awsc.Lambda.AddInvokeResponse("coinbase-odin", 200, `{"instance_count": 2}`)
+
+	r := minimalRelease(t)
+	r.Release.SetDefaults(to.Strp("region"), to.Strp("accountid"), "")
+	r.SetUserData(to.Strp("#cloud_config"))
+
+	assert.NoError(t, plan(awsc, r, to.Strp("coinbase-odin")))
+	assert.Equal(t, "Plan", invokedTask(t, awsc)["Task"])
+
+	// The release is uploaded for the deployer to validate
+	_, err := s3.GetStr(awsc.S3, r.Bucket, r.ReleasePath())
+	assert.NoError(t, err)
+}
+
+func Test_Prune(t *testing.T) {
+	awsc := mocks.MockAWS()
+	awsc.Lambda.AddInvokeResponse("coinbase-odin", 200, `{"dry_run": true}`)
+
+	assert.NoError(t, prune(awsc, to.Strp("coinbase-odin"), to.Strp("project"), to.Strp("config"), true))
+
+	message := invokedTask(t, awsc)
+	assert.Equal(t, "Prune", message["Task"])
+	input := message["Input"].(map[string]interface{})
+	assert.Equal(t, "project", input["project_name"])
+	assert.Equal(t, true, input["dry_run"])
+}
+
+func Test_Abort(t *testing.T) {
+	awsc := mocks.MockAWS()
+	awsc.Lambda.AddInvokeResponse("coinbase-odin", 200, `{"unlocked": true}`)
+
+	assert.NoError(t, abort(awsc, to.Strp("coinbase-odin"), to.Strp("project"), to.Strp("config"), to.Strp("rr"), to.Strp("uuid")))
+
+	message := invokedTask(t, awsc)
+	assert.Equal(t, "Abort", message["Task"])
+	assert.Equal(t, "uuid", message["Input"].(map[string]interface{})["uuid"])
+}
+
+func Test_InvokeTask_Error(t *testing.T) {
+	awsc := mocks.MockAWS()
+	assert.Error(t, prune(awsc, to.Strp("coinbase-odin"), to.Strp("project"), to.Strp("config"), false))
+}
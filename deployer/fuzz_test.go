@@ -11,7 +11,7 @@ import (
 
 func Test_Release_Basic_Fuzz(t *testing.T) {
 	for i := 0; i < 50; i++ {
-		f := fuzz.New()
+		f := newFuzzer()
 		var release models.Release
 		f.Fuzz(&release)
 
@@ -21,7 +21,7 @@ func Test_Release_Basic_Fuzz(t *testing.T) {
 
 func Test_Release_Basic_Service_Fuzz(t *testing.T) {
 	for i := 0; i < 50; i++ {
-		f := fuzz.New()
+		f := newFuzzer()
 		release := models.MockRelease(t)
 		f.Fuzz(release.Services["web"])
 
@@ -31,7 +31,7 @@ func Test_Release_Basic_Service_Fuzz(t *testing.T) {
 
 func Test_Release_Basic_Autoscaling_Fuzz(t *testing.T) {
 	for i := 0; i < 50; i++ {
-		f := fuzz.New()
+		f := newFuzzer()
 		release := models.MockRelease(t)
 		f.Fuzz(release.Services["web"].Autoscaling)
 
@@ -41,7 +41,7 @@ func Test_Release_Basic_Autoscaling_Fuzz(t *testing.T) {
 
 func Test_Release_Basic_Policies_Fuzz(t *testing.T) {
 	for i := 0; i < 25; i++ {
-		f := fuzz.New()
+		f := newFuzzer()
 		release := models.MockRelease(t)
 		f.Fuzz(release.Services["web"].Autoscaling.Policies[0])
 		release.Services["web"].Autoscaling.Policies[0].Type = to.Strp("cpu_scale_up")
@@ -49,7 +49,7 @@ func Test_Release_Basic_Policies_Fuzz(t *testing.T) {
 	}
 
 	for i := 0; i < 25; i++ {
-		f := fuzz.New()
+		f := newFuzzer()
 		release := models.MockRelease(t)
 		f.Fuzz(release.Services["web"].Autoscaling.Policies[0])
 		release.Services["web"].Autoscaling.Policies[0].Type = to.Strp("cpu_scale_down")
@@ -60,7 +60,7 @@ func Test_Release_Basic_Policies_Fuzz(t *testing.T) {
 
 func Test_Release_Basic_LifeCycle_Fuzz(t *testing.T) {
 	for i := 0; i < 50; i++ {
-		f := fuzz.New()
+		f := newFuzzer()
 		release := models.MockRelease(t)
 		f.Fuzz(release.LifeCycleHooks["TermHook"])
 
@@ -68,6 +68,23 @@ func Test_Release_Basic_LifeCycle_Fuzz(t *testing.T) {
 	}
 }
 
+// newFuzzer fuzzes the launch template overrides, which cannot be fuzzed as interface values
+func newFuzzer() *fuzz.Fuzzer {
+	return fuzz.New().Funcs(func(overrides *map[string]interface{}, c fuzz.Continue) {
+		*overrides = map[string]interface{}{}
+		for i := 0; i < c.Intn(3); i++ {
+			switch c.Intn(3) {
+			case 0:
+				(*overrides)[c.RandString()] = c.RandString()
+			case 1:
+				(*overrides)[c.RandString()] = c.RandBool()
+			default:
+				(*overrides)[c.RandString()] = map[string]interface{}{c.RandString(): c.Float64()}
+			}
+		}
+	})
+}
+
 func assertNoPanic(t *testing.T, release *models.Release) {
 	release.AwsAccountID = to.Strp("0000000")
 	stateMachine := createTestStateMachine(t, models.MockAwsClients(release))
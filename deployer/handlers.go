@@ -3,10 +3,12 @@ package deployer
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/deployer/metrics"
 	"github.com/coinbase/odin/deployer/models"
-	"github.com/coinbase/step/aws/dynamodb"
+	"github.com/coinbase/odin/deployer/progress"
 	"github.com/coinbase/step/errors"
 	"github.com/coinbase/step/utils/to"
 )
@@ -23,30 +25,102 @@ func (e DetachError) Error() string {
 	return fmt.Sprintf("DetachError: %v", e.Cause)
 }
 
+// haltError keeps a TimeoutError so the release is known to have timed out, other errors are a HaltError
+func haltError(err error) error {
+	if timeout, ok := err.(models.TimeoutError); ok {
+		return &timeout
+	}
+	return &errors.HaltError{err.Error()}
+}
+
 ////////////
 // HANDLERS
 ////////////
 
-var assumedRole = to.Strp("coinbase-odin-assumed")
-
 // Validate checks the release for issues
 func Validate(awsc aws.Clients) DeployHandler {
 	return func(ctx context.Context, release *models.Release) (*models.Release, error) {
-		// Assign the release its SHA before anything alters it
-		release.ReleaseSHA256 = to.SHA256Struct(release)
-		release.WipeControlledValues()
+		if err := validateRelease(ctx, awsc, release, false); err != nil {
+			return nil, err
+		}
 
-		// Default the releases Account and Region to where the Lambda is running
-		region, account := to.AwsRegionAccountFromContext(ctx)
-		release.Release.SetDefaults(region, account, "coinbase-odin-")
-		release.SetDefaults() // Fill in all the blank Attributes
+		return startRelease(awsc, release)
+	}
+}
 
-		if err := release.Validate(awsc.S3Client(release.AwsRegion, nil, nil)); err != nil {
-			return nil, &errors.BadReleaseError{err.Error()}
+// Rollback is the first state of the RollbackStateMachine, it replaces the release with the release
+// replaced by the last successful release then checks it like Validate
+func Rollback(awsc aws.Clients) DeployHandler {
+	return func(ctx context.Context, release *models.Release) (*models.Release, error) {
+		if err := validateRelease(ctx, awsc, release, true); err != nil {
+			return nil, err
 		}
 
-		return release, nil
+		return startRelease(awsc, release)
+	}
+}
+
+// startRelease replays a duplicate of a release that already succeeded, otherwise checks it can be deployed
+func startRelease(awsc aws.Clients, release *models.Release) (*models.Release, error) {
+	// A duplicate of a release that already succeeded returns its result without deploying
+	if !release.ValidateOnly {
+		replayed, err := release.ReplayIdempotentResult(awsc.S3Client(release.AwsRegion, nil, nil))
+		if err != nil {
+			return nil, &errors.BadReleaseError{fmt.Sprintf("%v %v", release.ErrorPrefix(), err.Error())}
+		}
+
+		if replayed {
+			return release, nil
+		}
+	}
+
+	if err := validateReleaseAccess(awsc, release); err != nil {
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// validateRelease defaults and validates the release, it only reads from AWS so Plan can use it
+// With rollback the release is first replaced with the release in the rollback plan
+func validateRelease(ctx context.Context, awsc aws.Clients, release *models.Release, rollback bool) error {
+	// Assign the release its SHA before anything alters it
+	release.ReleaseSHA256 = to.SHA256Struct(release)
+	release.WipeControlledValues()
+
+	// Default the releases Account and Region to where the Lambda is running
+	region, account := to.AwsRegionAccountFromContext(ctx)
+	release.Release.SetDefaults(region, account, "coinbase-odin-")
+
+	// Redeploy the release replaced by the last successful release, it is defaulted below like any release
+	if rollback {
+		if err := release.PrepareRollback(awsc.S3Client(release.AwsRegion, nil, nil), awsc.KMSClient(release.AwsRegion, nil, nil)); err != nil {
+			return &errors.BadReleaseError{err.Error()}
+		}
+	}
+
+	release.SetDefaults() // Fill in all the blank Attributes
+
+	if err := release.Validate(awsc.S3Client(release.AwsRegion, nil, nil), awsc.KMSClient(release.AwsRegion, nil, nil)); err != nil {
+		return &errors.BadReleaseError{err.Error()}
+	}
+
+	return nil
+}
+
+// validateReleaseAccess checks the deploy role can be assumed and resolves the releases image
+func validateReleaseAccess(awsc aws.Clients, release *models.Release) error {
+	// Fail before any resources are touched if the deploy role cannot be assumed
+	if err := release.ValidateDeployRole(awsc.STSClient(release.AwsRegion, release.AwsAccountID, release.DeployRole())); err != nil {
+		return &errors.BadReleaseError{fmt.Sprintf("%v %v", release.ErrorPrefix(), err.Error())}
+	}
+
+	// The AMI is in the release account, so is its SSM parameter
+	if err := release.ResolveImage(awsc.SSMClient(release.AwsRegion, release.AwsAccountID, release.DeployRole())); err != nil {
+		return &errors.BadReleaseError{fmt.Sprintf("%v %v", release.ErrorPrefix(), err.Error())}
 	}
+
+	return nil
 }
 
 // Lock Tries to Grab the Lock, if it fails for any reason, no cleanup is necessary
@@ -54,10 +128,37 @@ func Lock(awsc aws.Clients) DeployHandler {
 	return func(ctx context.Context, release *models.Release) (*models.Release, error) {
 		release.SetDefaults()
 
-		locker := dynamodb.NewDynamoDBLocker(awsc.DynamoDBClient(nil, nil, nil))
+		locker := release.Locker(awsc.S3Client(release.AwsRegion, nil, nil), awsc.DynamoDBClient(nil, nil, nil))
 		lockTableName := getLockTableNameFromContext(ctx, "-locks")
 
-		return release, release.GrabLocks(awsc.S3Client(release.AwsRegion, nil, nil), locker, lockTableName)
+		err := release.GrabLocks(awsc.S3Client(release.AwsRegion, nil, nil), locker, lockTableName)
+
+		if _, exists := err.(*errors.LockExistsError); exists && release.ForceUnlock {
+			// Take over the lock of a dead execution then try again
+			if err := release.TakeOverStaleLock(
+				awsc.S3Client(release.AwsRegion, nil, nil),
+				awsc.SFNClient(release.AwsRegion, nil, nil),
+				locker,
+				lockTableName,
+				getStateMachineArnFromContext(ctx),
+			); err != nil {
+				return release, err
+			}
+
+			err = release.GrabLocks(awsc.S3Client(release.AwsRegion, nil, nil), locker, lockTableName)
+		}
+
+		if err != nil {
+			return release, err
+		}
+
+		if err := release.UploadRollbackUserData(awsc.S3Client(release.AwsRegion, nil, nil)); err != nil {
+			return release, err
+		}
+
+		notify(awsc, release, models.NotifyStarted, "Lock")
+
+		return release, nil
 	}
 }
 
@@ -69,22 +170,40 @@ func ValidateResources(awsc aws.Clients) DeployHandler {
 
 		// Fetch all Resource Objecgs from AWS, i.e. Security Group, ELBs, Albs, IAM Profile
 		resources, err := release.FetchResources(
-			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, assumedRole),
-			awsc.EC2Client(release.AwsRegion, release.AwsAccountID, assumedRole),
-			awsc.ELBClient(release.AwsRegion, release.AwsAccountID, assumedRole),
-			awsc.ALBClient(release.AwsRegion, release.AwsAccountID, assumedRole),
-			awsc.IAMClient(release.AwsRegion, release.AwsAccountID, assumedRole),
-			awsc.SNSClient(release.AwsRegion, release.AwsAccountID, assumedRole),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.EC2Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.ELBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.ALBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.IAMClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.SNSClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
 		)
 
 		if err != nil {
 			return nil, &errors.BadReleaseError{err.Error()}
 		}
 
+		if err := release.ValidateListenerCapacity(
+			awsc.ALBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.BadReleaseError{err.Error()}
+		}
+
 		if err := release.ValidateResources(resources); err != nil {
 			return nil, &errors.BadReleaseError{err.Error()}
 		}
 
+		if err := release.ValidateHostResourceGroups(
+			awsc.ResourceGroupsClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.BadReleaseError{err.Error()}
+		}
+
+		if err := release.ValidateAlarms(
+			awsc.CWClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.BadReleaseError{err.Error()}
+		}
+
 		// If this flag is set Odin will fail a deploy if previous Release is dangerously different
 		if release.SafeRelease {
 			if err := release.ValidateSafeRelease(
@@ -95,32 +214,203 @@ func ValidateResources(awsc aws.Clients) DeployHandler {
 			}
 		}
 
+		// If this flag is set Odin will update the live ASGs when only tags or policies changed
+		if err := release.DetectInPlace(
+			awsc.S3Client(release.AwsRegion, nil, nil),
+			resources,
+		); err != nil {
+			return nil, &errors.BadReleaseError{err.Error()}
+		}
+
 		release.UpdateWithResources(resources)
 
+		if release.ValidateOnly {
+			release.Success = to.Boolp(true) // A validate only release ends here
+		}
+
 		return release, nil
 	}
 }
 
+// PreDeployHook invokes the releases PreDeployHook, the lock is held so concurrent deploys wait on its answer
+func PreDeployHook(awsc aws.Clients) DeployHandler {
+	return func(_ context.Context, release *models.Release) (*models.Release, error) {
+		release.SetDefaults() // Wire up non-serialized relationships
+
+		if err := release.RunPreDeployHook(awsc.LambdaClient(release.AwsRegion, nil, nil)); err != nil {
+			return nil, &errors.BadReleaseError{err.Error()}
+		}
+
+		return release, nil
+	}
+}
+
+// PlanHandler function type
+type PlanHandler func(context.Context, *models.Release) (*models.Plan, error)
+
+// Plan is a dry run of a release, it validates the release like Validate and ValidateResources and returns
+// what Deploy would create. The lock is not grabbed and no AWS resources are created or changed
+// A release with rollback plans what Rollback would deploy
+func Plan(awsc aws.Clients) PlanHandler {
+	return func(ctx context.Context, release *models.Release) (*models.Plan, error) {
+		if err := validateRelease(ctx, awsc, release, release.Rollback); err != nil {
+			return nil, err
+		}
+
+		if err := validateReleaseAccess(awsc, release); err != nil {
+			return nil, err
+		}
+
+		release, err := ValidateResources(awsc)(ctx, release)
+		if err != nil {
+			return nil, err
+		}
+
+		release.SetDefaults() // Strategy with the previous desired capacity
+
+		return release.Plan(), nil
+	}
+}
+
+// PruneHandler function type
+type PruneHandler func(context.Context, *models.PruneInput) (*models.PruneResult, error)
+
+// Prune deletes the resources of a project config left by releases that died before they were cleaned up.
+// The current release and releases with a running execution are never touched, with DryRun nothing is deleted
+func Prune(awsc aws.Clients) PruneHandler {
+	return func(ctx context.Context, input *models.PruneInput) (*models.PruneResult, error) {
+		release := &input.Release
+
+		// Default the releases Account and Region to where the Lambda is running
+		region, account := to.AwsRegionAccountFromContext(ctx)
+		release.Release.SetDefaults(region, account, "coinbase-odin-")
+
+		if err := release.ValidatePrune(); err != nil {
+			return nil, &errors.BadReleaseError{err.Error()}
+		}
+
+		if err := input.ValidatePruneLimits(); err != nil {
+			return nil, &errors.BadReleaseError{err.Error()}
+		}
+
+		result, err := input.Prune(
+			awsc.S3Client(release.AwsRegion, nil, nil),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.EC2Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.CWClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.SFNClient(release.AwsRegion, nil, nil),
+			getStateMachineArnFromContext(ctx),
+		)
+
+		if err != nil {
+			return nil, &errors.CleanUpError{err.Error()}
+		}
+
+		return result, nil
+	}
+}
+
+// AbortHandler function type
+type AbortHandler func(context.Context, *models.AbortInput) (*models.AbortResult, error)
+
+// Abort cleans up a release whose execution was stopped mid-deploy, like CleanUpFailure and ReleaseLockFailure do
+// for a failed one. It is safe to call on a release that is already clean or never created anything
+func Abort(awsc aws.Clients) AbortHandler {
+	return func(ctx context.Context, input *models.AbortInput) (*models.AbortResult, error) {
+		release := &input.Release
+
+		// Default the releases Account and Region to where the Lambda is running
+		// The UUID of the aborted release must be given, never generated
+		uuid := release.UUID
+		region, account := to.AwsRegionAccountFromContext(ctx)
+		release.Release.SetDefaults(region, account, "coinbase-odin-")
+		release.UUID = uuid
+
+		if err := release.ValidateAbort(); err != nil {
+			return nil, &errors.BadReleaseError{err.Error()}
+		}
+
+		result, err := release.Abort(
+			awsc.S3Client(release.AwsRegion, nil, nil),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.EC2Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.CWClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.Route53Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.SFNClient(release.AwsRegion, nil, nil),
+			awsc.DynamoDBClient(nil, nil, nil),
+			getLockTableNameFromContext(ctx, "-locks"),
+			getStateMachineArnFromContext(ctx),
+		)
+
+		if err != nil {
+			return nil, &errors.CleanUpError{err.Error()}
+		}
+
+		return result, nil
+	}
+}
+
 // Deploy receives release, fetches AWS cloud resources, and creates New resources
 // It returns the release with additional information including
 func Deploy(awsc aws.Clients) DeployHandler {
 	return func(_ context.Context, release *models.Release) (*models.Release, error) {
 		// Wire up non-serialized relationships with UserData
-		if err := release.SetDefaultsWithUserData(awsc.S3Client(release.AwsRegion, nil, nil)); err != nil {
+		if err := release.SetDefaultsWithUserData(awsc.S3Client(release.AwsRegion, nil, nil), awsc.KMSClient(release.AwsRegion, nil, nil)); err != nil {
 			return nil, &errors.HaltError{err.Error()}
 		}
 
 		if err := release.IsHalt(awsc.S3Client(release.AwsRegion, nil, nil)); err != nil {
-			return nil, &errors.HaltError{err.Error()}
+			return nil, haltError(err)
 		}
 
 		if err := release.CreateResources(
-			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, assumedRole),
-			awsc.CWClient(release.AwsRegion, release.AwsAccountID, assumedRole),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.EC2Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.CWClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.ALBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
 		); err != nil {
 			return nil, &errors.DeployError{err.Error()}
 		}
 
+		release.DeployedAt = to.Timep(time.Now())
+		release.SetCanaried()
+
+		return release, nil
+	}
+}
+
+// CheckCanary checks the canary instances are healthy and have baked
+func CheckCanary(awsc aws.Clients) DeployHandler {
+	return func(_ context.Context, release *models.Release) (*models.Release, error) {
+		release.SetDefaults() // Wire up non-serialized relationships
+
+		// The canary is the first health check, so its timeouts are classified like CheckHealthy
+		if err := release.IsHalt(awsc.S3Client(release.AwsRegion, nil, nil)); err != nil {
+			return nil, haltError(release.ClassifyTimeout(err))
+		}
+
+		if err := release.PhaseTimedOut(); err != nil {
+			return nil, haltError(release.ClassifyTimeout(err))
+		}
+
+		err := release.UpdateCanary(
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.EC2Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.ELBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.ALBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		)
+
+		if err != nil {
+			switch err.(type) {
+			case *models.HaltError:
+				// The canary failed, immediately stop deploying
+				return nil, &errors.HaltError{err.Error()}
+			default:
+				// This will retry a few times, as it might just be an AWS issue
+				return nil, &errors.HealthError{err.Error()}
+			}
+		}
+
 		return release, nil
 	}
 }
@@ -130,14 +420,29 @@ func CheckHealthy(awsc aws.Clients) DeployHandler {
 	return func(_ context.Context, release *models.Release) (*models.Release, error) {
 		release.SetDefaults() // Wire up non-serialized relationships
 
+		if release.WaitingOnDependencies() {
+			// Services waiting on their dependencies are created with UserData
+			if err := release.SetDefaultsWithUserData(awsc.S3Client(release.AwsRegion, nil, nil), awsc.KMSClient(release.AwsRegion, nil, nil)); err != nil {
+				return nil, &errors.HaltError{err.Error()}
+			}
+		}
+
+		// Timeouts are classified by the last health check
 		if err := release.IsHalt(awsc.S3Client(release.AwsRegion, nil, nil)); err != nil {
-			return nil, &errors.HaltError{err.Error()}
+			return nil, haltError(release.ClassifyTimeout(err))
+		}
+
+		if err := release.PhaseTimedOut(); err != nil {
+			return nil, haltError(release.ClassifyTimeout(err))
 		}
 
 		err := release.UpdateHealthy(
-			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, assumedRole),
-			awsc.ELBClient(release.AwsRegion, release.AwsAccountID, assumedRole),
-			awsc.ALBClient(release.AwsRegion, release.AwsAccountID, assumedRole),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.EC2Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.ELBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.ALBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.CWClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.HTTPClient(),
 		)
 
 		if err != nil {
@@ -151,6 +456,139 @@ func CheckHealthy(awsc aws.Clients) DeployHandler {
 			}
 		}
 
+		if release.Healthy != nil && *release.Healthy {
+			notify(awsc, release, models.NotifyHealthy, "CheckHealthy")
+		} else {
+			release.BackoffHealthPoll()
+		}
+
+		return release, nil
+	}
+}
+
+// CheckRefresh checks the instance refreshes of the live ASGs
+func CheckRefresh(awsc aws.Clients) DeployHandler {
+	return func(_ context.Context, release *models.Release) (*models.Release, error) {
+		release.SetDefaults() // Wire up non-serialized relationships
+
+		if err := release.IsHalt(awsc.S3Client(release.AwsRegion, nil, nil)); err != nil {
+			return nil, haltError(err)
+		}
+
+		err := release.UpdateRefreshed(
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		)
+
+		if err != nil {
+			switch err.(type) {
+			case *models.HaltError:
+				// This will immediately stop checking and cancel the refresh
+				return nil, &errors.HaltError{err.Error()}
+			default:
+				// This will retry a few times, as it might just be an AWS issue
+				return nil, &errors.HealthError{err.Error()}
+			}
+		}
+
+		if release.Refreshed != nil && *release.Refreshed {
+			notify(awsc, release, models.NotifyHealthy, "CheckRefresh")
+		}
+
+		return release, nil
+	}
+}
+
+// CancelRefresh cancels the instance refreshes and restores the live ASGs launch configurations
+func CancelRefresh(awsc aws.Clients) DeployHandler {
+	return func(_ context.Context, release *models.Release) (*models.Release, error) {
+		release.SetDefaults() // Wire up non-serialized relationships
+
+		release.Success = to.Boolp(false) // Quickly Mark Failure
+
+		if err := release.CancelRefresh(
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.CleanUpError{err.Error()}
+		}
+
+		return release, nil
+	}
+}
+
+// SmokeTest invokes the releases SmokeTest against the healthy new fleet, the old fleet is still attached and serving
+func SmokeTest(awsc aws.Clients) DeployHandler {
+	return func(_ context.Context, release *models.Release) (*models.Release, error) {
+		release.SetDefaults() // Wire up non-serialized relationships
+
+		if err := release.RunSmokeTest(
+			awsc.LambdaClient(release.AwsRegion, nil, nil),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.EC2Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.HealthError{err.Error()}
+		}
+
+		return release, nil
+	}
+}
+
+// CutoverDNS points the weighted DNS record and the listener rules at the healthy release
+func CutoverDNS(awsc aws.Clients) DeployHandler {
+	return func(_ context.Context, release *models.Release) (*models.Release, error) {
+		release.SetDefaults() // Wire up non-serialized relationships
+
+		if err := release.CutoverDNS(
+			awsc.S3Client(release.AwsRegion, nil, nil),
+			awsc.Route53Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.HealthError{err.Error()}
+		}
+
+		if err := release.CutoverListenerRules(
+			awsc.ALBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.HealthError{err.Error()}
+		}
+
+		return release, nil
+	}
+}
+
+// Soak watches the soak alarms and the release health after the release is healthy
+func Soak(awsc aws.Clients) DeployHandler {
+	return func(_ context.Context, release *models.Release) (*models.Release, error) {
+		release.SetDefaults() // Wire up non-serialized relationships
+
+		if err := release.IsHalt(awsc.S3Client(release.AwsRegion, nil, nil)); err != nil {
+			return nil, haltError(err)
+		}
+
+		err := release.UpdateSoaked(
+			awsc.CWClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		)
+
+		if err == nil {
+			err = release.CheckSoakHealthy(
+				awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+				awsc.EC2Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+				awsc.ELBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+				awsc.ALBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+				awsc.CWClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+				awsc.HTTPClient(),
+			)
+		}
+
+		if err != nil {
+			switch err.(type) {
+			case *models.HaltError:
+				// An alarm tripped or the release degraded, immediately roll back
+				return nil, &errors.HaltError{err.Error()}
+			default:
+				// This will retry a few times, as it might just be an AWS issue
+				return nil, &errors.HealthError{err.Error()}
+			}
+		}
+
 		return release, nil
 	}
 }
@@ -161,7 +599,7 @@ func DetachForSuccess(awsc aws.Clients) DeployHandler {
 		release.SetDefaults() // Wire up non-serialized relationships
 
 		if err := release.DetachForSuccess(
-			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, assumedRole),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
 		); err != nil {
 			switch err.(type) {
 			case models.DetachError:
@@ -180,14 +618,57 @@ func CleanUpSuccess(awsc aws.Clients) DeployHandler {
 	return func(ctx context.Context, release *models.Release) (*models.Release, error) {
 		release.SetDefaults() // Wire up non-serialized relationships
 
+		// Nothing is changed until the previous instances have drained, so retrying is safe
+		if err := release.CheckDrained(
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.ALBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			switch err.(type) {
+			case models.DrainError:
+				return nil, err
+			default:
+				return nil, &errors.CleanUpError{err.Error()}
+			}
+		}
+
+		// The plan is built from the previous ASGs and release so must be written before they are deleted
+		if err := release.WriteRollbackPlan(
+			awsc.S3Client(release.AwsRegion, nil, nil),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.CleanUpError{err.Error()}
+		}
+
+		if err := release.CleanUpDNS(
+			awsc.Route53Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.CleanUpError{err.Error()}
+		}
+
 		if err := release.SuccessfulTearDown(
-			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, assumedRole),
-			awsc.CWClient(release.AwsRegion, release.AwsAccountID, assumedRole),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.EC2Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.CWClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.CleanUpError{err.Error()}
+		}
+
+		// Only this releases ASGs are left after the tear down
+		if err := release.WriteDeployResult(
+			awsc.S3Client(release.AwsRegion, nil, nil),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.EC2Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
 		); err != nil {
 			return nil, &errors.CleanUpError{err.Error()}
 		}
 
-		locker := dynamodb.NewDynamoDBLocker(awsc.DynamoDBClient(nil, nil, nil))
+		if err := release.ResumeProcesses(
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.CleanUpError{err.Error()}
+		}
+
+		locker := release.Locker(awsc.S3Client(release.AwsRegion, nil, nil), awsc.DynamoDBClient(nil, nil, nil))
 		lockTableName := getLockTableNameFromContext(ctx, "-locks")
 
 		err := release.UnlockRoot(awsc.S3Client(release.AwsRegion, nil, nil), locker, lockTableName)
@@ -196,7 +677,7 @@ func CleanUpSuccess(awsc aws.Clients) DeployHandler {
 		}
 
 		if err := release.ResetDesiredCapacity(
-			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, assumedRole),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
 		); err != nil {
 			// We ignore this error as failing to reset the capacity should not cause a massive issue
 			// Log the error in case
@@ -211,13 +692,235 @@ func CleanUpSuccess(awsc aws.Clients) DeployHandler {
 	}
 }
 
+// NotifyFailure alerts the failure before the release ends in FailureClean
+func NotifyFailure(awsc aws.Clients) DeployHandler {
+	return alertFailure(awsc, "FailureClean")
+}
+
+// NotifyFailureDirty alerts the failure before the release ends in FailureDirty
+func NotifyFailureDirty(awsc aws.Clients) DeployHandler {
+	return alertFailure(awsc, "FailureDirty")
+}
+
+// alertFailure alerts the failure ending in endState, failing to alert never fails the release
+func alertFailure(awsc aws.Clients, endState string) DeployHandler {
+	return func(ctx context.Context, release *models.Release) (*models.Release, error) {
+		// A release that failed Validate never had its Account and Region defaulted
+		region, account := to.AwsRegionAccountFromContext(ctx)
+		if release.AwsRegion == nil {
+			release.AwsRegion = region
+		}
+
+		if release.AwsAccountID == nil {
+			release.AwsAccountID = account
+		}
+
+		if err := release.Alert(
+			awsc.SNSClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.SSMClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.HTTPClient(),
+			getStateMachineArnFromContext(ctx),
+			endState,
+		); err != nil {
+			fmt.Printf("IGNORED: %v \n", err)
+		}
+
+		return release, nil
+	}
+}
+
+// notify publishes the event, failing to notify never fails the release
+func notify(awsc aws.Clients, release *models.Release, event string, state string) {
+	if err := release.Notify(
+		awsc.SNSClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		event,
+		state,
+	); err != nil {
+		fmt.Printf("IGNORED: %v \n", err)
+	}
+}
+
+// withPath records each state the release completes in its execution path
+func withPath(state string, fn DeployHandler) DeployHandler {
+	return func(ctx context.Context, release *models.Release) (*models.Release, error) {
+		release, err := fn(ctx, release)
+		if release != nil {
+			release.RecordPath(state)
+		}
+		return release, err
+	}
+}
+
+// withEventLog writes the start and end of each state to the releases event log in S3
+// The start is written before the handler runs so a task that dies is still in the log
+func withEventLog(awsc aws.Clients, state string, fn DeployHandler) DeployHandler {
+	return func(ctx context.Context, release *models.Release) (*models.Release, error) {
+		putEvent(awsc, release, &models.Event{Time: time.Now(), State: state, Event: models.EventStart})
+
+		out, err := fn(ctx, release)
+
+		end := &models.Event{Time: time.Now(), State: state, Event: models.EventSuccess}
+		if err != nil {
+			end.Event = models.EventError
+			end.Error = to.Strp(err.Error())
+		}
+
+		logged := out
+		if logged == nil {
+			logged = release // Handlers return no release with an error
+		}
+
+		putEvent(awsc, logged, end)
+
+		return out, err
+	}
+}
+
+// putEvent writes the event, failing to log never fails the release
+func putEvent(awsc aws.Clients, release *models.Release, event *models.Event) {
+	if release == nil {
+		return
+	}
+
+	if err := release.PutEvent(awsc.S3Client(release.AwsRegion, nil, nil), event); err != nil {
+		fmt.Printf("IGNORED: %v \n", err)
+	}
+}
+
+// withMetrics emits the deploy outcome and phase duration metrics as the release reaches them
+func withMetrics(m metrics.Metrics, state string, fn DeployHandler) DeployHandler {
+	return func(ctx context.Context, release *models.Release) (*models.Release, error) {
+		start := time.Now()
+
+		out, err := fn(ctx, release)
+		if err != nil || out == nil {
+			return out, err
+		}
+
+		rl, ok := m.(metrics.ReleaseLabeled)
+		releaseLabeled := ok && rl.LabelReleaseUUID()
+
+		labels := func(key string, value string) map[string]string {
+			l := map[string]string{
+				metrics.LabelProject: to.Strs(out.ProjectName),
+				metrics.LabelConfig:  to.Strs(out.ConfigName),
+				key:                  value,
+			}
+
+			if releaseLabeled {
+				l[metrics.LabelReleaseUUID] = to.Strs(out.UUID)
+			}
+
+			return l
+		}
+
+		outcome := func(outcome string) {
+			m.IncCounter(metrics.DeploysTotal, labels(metrics.LabelOutcome, outcome))
+			if out.StartedAt != nil {
+				m.ObserveHistogram(metrics.DeployDuration, time.Since(*out.StartedAt).Seconds(), labels(metrics.LabelOutcome, outcome))
+			}
+		}
+
+		switch state {
+		case "Deploy":
+			m.ObserveHistogram(metrics.DeployPhaseDuration, time.Since(start).Seconds(), labels(metrics.LabelPhase, metrics.PhaseDeploy))
+		case "CheckHealthy":
+			if out.Healthy != nil && *out.Healthy && out.DeployedAt != nil {
+				m.ObserveHistogram(metrics.DeployPhaseDuration, time.Since(*out.DeployedAt).Seconds(), labels(metrics.LabelPhase, metrics.PhaseWaitForHealthy))
+			}
+		case "CleanUpSuccess":
+			outcome(metrics.OutcomeSuccess)
+		case "NotifyFailure":
+			if out.Error != nil && to.Strs(out.Error.Error) == "TimeoutError" {
+				outcome(metrics.OutcomeTimeout)
+			} else {
+				outcome(metrics.OutcomeCleanFailure)
+			}
+		case "NotifyFailureDirty":
+			outcome(metrics.OutcomeDirtyFailure)
+		}
+
+		return out, err
+	}
+}
+
+// withProgress reports the health of each service after every poll of CheckHealthy
+func withProgress(p progress.Progress, state string, fn DeployHandler) DeployHandler {
+	if state != "CheckHealthy" {
+		return fn
+	}
+
+	return func(ctx context.Context, release *models.Release) (*models.Release, error) {
+		out, err := fn(ctx, release)
+		if err != nil || out == nil {
+			return out, err
+		}
+
+		update := progress.Update{
+			ProjectName: to.Strs(out.ProjectName),
+			ConfigName:  to.Strs(out.ConfigName),
+			ReleaseID:   to.Strs(out.ReleaseID),
+			Healthy:     out.Healthy != nil && *out.Healthy,
+			Services:    map[string]progress.Service{},
+		}
+
+		for name, service := range out.Services {
+			if service == nil || service.HealthReport == nil {
+				continue
+			}
+
+			report := service.HealthReport
+			update.Services[name] = progress.Service{
+				Healthy:     intValue(report.Healthy),
+				Desired:     int64Value(report.TargetHealthy),
+				Launching:   intValue(report.Launching),
+				Terminating: intValue(report.Terminating),
+			}
+		}
+
+		p.Report(update)
+		return out, err
+	}
+}
+
+func intValue(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
+func int64Value(i *int64) int64 {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
 // DetachForFailure detach ASGs
 func DetachForFailure(awsc aws.Clients) DeployHandler {
 	return func(_ context.Context, release *models.Release) (*models.Release, error) {
 		release.SetDefaults() // Wire up non-serialized relationships
 
+		// Move traffic back to the previous release before the new instances are detached
+		if err := release.RevertTraffic(
+			awsc.S3Client(release.AwsRegion, nil, nil),
+			awsc.Route53Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.ALBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.CleanUpError{err.Error()}
+		}
+
+		// The live ASGs of an in place update are restored rather than detached
+		if err := release.RevertInPlace(
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.CWClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.CleanUpError{err.Error()}
+		}
+
 		if err := release.DetachForFailure(
-			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, assumedRole),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
 		); err != nil {
 			switch err.(type) {
 			case models.DetachError:
@@ -238,9 +941,19 @@ func CleanUpFailure(awsc aws.Clients) DeployHandler {
 
 		release.Success = to.Boolp(false) // Quickly Mark Failure
 
+		// Traffic is moved back in DetachForFailure, this retries it if that failed
+		if err := release.RevertTraffic(
+			awsc.S3Client(release.AwsRegion, nil, nil),
+			awsc.Route53Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.ALBClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.CleanUpError{err.Error()}
+		}
+
 		if err := release.UnsuccessfulTearDown(
-			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, assumedRole),
-			awsc.CWClient(release.AwsRegion, release.AwsAccountID, assumedRole),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.EC2Client(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+			awsc.CWClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
 		); err != nil {
 			switch err.(type) {
 			case models.DetachError:
@@ -250,6 +963,21 @@ func CleanUpFailure(awsc aws.Clients) DeployHandler {
 			}
 		}
 
+		// The previous ASGs are serving again so must stop suppressing their scaling processes
+		if err := release.ResumePreviousProcesses(
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			return nil, &errors.CleanUpError{err.Error()}
+		}
+
+		// Failing to write the report never fails the clean up
+		if err := release.WriteFailureReport(
+			awsc.S3Client(release.AwsRegion, nil, nil),
+			awsc.ASGClient(release.AwsRegion, release.AwsAccountID, release.DeployRole()),
+		); err != nil {
+			fmt.Printf("IGNORED: %v \n", err)
+		}
+
 		return release, nil
 	}
 }
@@ -259,7 +987,7 @@ func ReleaseLockFailure(awsc aws.Clients) DeployHandler {
 	return func(ctx context.Context, release *models.Release) (*models.Release, error) {
 		release.SetDefaults() // Wire up non-serialized relationships
 
-		locker := dynamodb.NewDynamoDBLocker(awsc.DynamoDBClient(nil, nil, nil))
+		locker := release.Locker(awsc.S3Client(release.AwsRegion, nil, nil), awsc.DynamoDBClient(nil, nil, nil))
 		lockTableName := getLockTableNameFromContext(ctx, "-locks")
 
 		err := release.UnlockRoot(awsc.S3Client(release.AwsRegion, nil, nil), locker, lockTableName)
@@ -277,3 +1005,9 @@ func getLockTableNameFromContext(ctx context.Context, postfix string) string {
 	_, _, lambdaName := to.AwsRegionAccountLambdaNameFromContext(ctx)
 	return fmt.Sprintf("%s%s", lambdaName, postfix)
 }
+
+// getStateMachineArnFromContext returns the deployers state machine, it has the same name as the lambda
+func getStateMachineArnFromContext(ctx context.Context) *string {
+	region, account, lambdaName := to.AwsRegionAccountLambdaNameFromContext(ctx)
+	return to.StepArn(&region, &account, &lambdaName)
+}
@@ -1,12 +1,19 @@
 package deployer
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/coinbase/odin/aws"
 	"github.com/coinbase/odin/aws/mocks"
 	"github.com/coinbase/odin/deployer/models"
+	"github.com/coinbase/odin/deployer/progress"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/errors"
 	"github.com/coinbase/step/utils/to"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,7 +28,7 @@ func Test_ValidateResources_FetchesCorrectResources(t *testing.T) {
 	assert.NoError(t, err)
 	res := rel.Services["web"].Resources
 	assert.Equal(t, "ami-123456", *res.Image)
-	assert.Equal(t, "/odin/project/config/web/web-profile", *res.Profile)
+	assert.Equal(t, "arn:aws:iam::000000000000:instance-profile/odin/project/config/web/web-profile", *res.Profile)
 	assert.Equal(t, "project-config-web-old-release", *res.PrevASG)
 	assert.Equal(t, []string{"group-id"}, to.StrSlice(res.SecurityGroups))
 	assert.Equal(t, []string{"web-elb"}, to.StrSlice(res.ELBs))
@@ -62,6 +69,63 @@ func Test_ValidateResources_BadProfile(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// Test that validate resources fails if the IAM profile does not exist
+func Test_ValidateResources_MissingProfile(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Services["web"].Profile = to.Strp("missing-profile")
+	models.MockPrepareRelease(release)
+
+	awsc := models.MockAwsClients(release)
+	_, err := ValidateResources(awsc)(nil, release)
+	assert.Error(t, err)
+	assert.IsType(t, &errors.BadReleaseError{}, err)
+	assert.Regexp(t, "missing-profile", err.Error())
+}
+
+// Test that validate resources fails unless the IAM profile has the required policies attached
+func Test_ValidateResources_RequiredProfilePolicies(t *testing.T) {
+	release := models.MockRelease(t)
+	release.RequiredProfilePolicies = []*string{to.Strp("arn:aws:iam::aws:policy/required")}
+	models.MockPrepareRelease(release)
+
+	awsc := models.MockAwsClients(release)
+	awsc.IAM.AddInstanceProfileRole("web-profile", "web-role", "arn:aws:iam::aws:policy/other")
+	_, err := ValidateResources(awsc)(nil, release)
+	assert.Error(t, err)
+	assert.IsType(t, &errors.BadReleaseError{}, err)
+	assert.Regexp(t, "missing required policies arn:aws:iam::aws:policy/required", err.Error())
+
+	awsc.IAM.AddInstanceProfileRole("web-profile", "web-role", "arn:aws:iam::aws:policy/required")
+	_, err = ValidateResources(awsc)(nil, release)
+	assert.NoError(t, err)
+}
+
+// Test that validate resources fails unless the security groups allow the load balancers to health check
+func Test_ValidateResources_HealthCheckIngress(t *testing.T) {
+	release := models.MockRelease(t)
+	models.MockPrepareRelease(release)
+
+	awsc := models.MockAwsClients(release)
+	awsc.ALB.AddTargetGroupLoadBalancer("web-elb-target", 8080, "web-alb-arn", "alb-sg-id")
+	_, err := ValidateResources(awsc)(nil, release)
+	assert.Error(t, err)
+	assert.IsType(t, &errors.BadReleaseError{}, err)
+	assert.Regexp(t, "TargetGroup\\(web-elb-target\\) load balancer security groups \\[alb-sg-id\\] to reach health check port 8080", err.Error())
+
+	awsc.EC2.AddSecurityGroupIngress("web-sg", 8080, "alb-sg-id")
+	_, err = ValidateResources(awsc)(nil, release)
+	assert.NoError(t, err)
+
+	awsc.ELB.SetELBHealthCheck("web-elb", "HTTP:9000/ping", "elb-sg-id")
+	_, err = ValidateResources(awsc)(nil, release)
+	assert.Error(t, err)
+	assert.Regexp(t, "ELB\\(web-elb\\) security groups \\[elb-sg-id\\] to reach health check port 9000", err.Error())
+
+	awsc.EC2.AddSecurityGroupIngress("web-sg", 9000, "elb-sg-id")
+	_, err = ValidateResources(awsc)(nil, release)
+	assert.NoError(t, err)
+}
+
 func Test_ValidateResources_BadTG(t *testing.T) {
 	release := models.MockRelease(t)
 	models.MockPrepareRelease(release)
@@ -124,6 +188,47 @@ func Test_CheckHealthy_CorrectReport(t *testing.T) {
 	assert.Equal(t, 0, *hr.Terminating)
 }
 
+func mockMinHealthyPercentageRelease(t *testing.T, healthy int, unhealthy int) (*models.Release, *mocks.MockClients) {
+	release := models.MockRelease(t)
+	release.Services["web"].Autoscaling.MinSize = to.Int64p(20)
+	release.Services["web"].Autoscaling.MaxSize = to.Int64p(40)
+	release.Services["web"].MinHealthyPercentage = to.Intp(95)
+	models.MockPrepareRelease(release)
+	release.Services["web"].Resources = &models.ServiceResourceNames{}
+	release.Services["web"].CreatedASG = to.Strp("asd")
+
+	awsc := mocks.MockAWS()
+	awsc.ASG.AddASG(&autoscaling.Group{
+		MinSize:         to.Int64p(20),
+		DesiredCapacity: to.Int64p(20),
+		Instances:       mocks.MakeMockASGInstances(healthy, unhealthy, 0),
+	})
+
+	return release, awsc
+}
+
+// Test Check Healthy passes at the MinHealthyPercentage of the desired capacity
+func Test_CheckHealthy_MinHealthyPercentage_AtThreshold(t *testing.T) {
+	release, awsc := mockMinHealthyPercentageRelease(t, 19, 1)
+
+	res, err := CheckHealthy(awsc)(nil, release)
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, *res.Healthy)
+	assert.EqualValues(t, 19, *res.Services["web"].HealthReport.TargetHealthy)
+}
+
+// Test Check Healthy is not healthy below the MinHealthyPercentage
+func Test_CheckHealthy_MinHealthyPercentage_BelowThreshold(t *testing.T) {
+	release, awsc := mockMinHealthyPercentageRelease(t, 18, 2)
+
+	res, err := CheckHealthy(awsc)(nil, release)
+	assert.NoError(t, err)
+
+	assert.Equal(t, false, *res.Healthy)
+	assert.Equal(t, false, res.Services["web"].Healthy)
+}
+
 // Test Check Healthy halts if terming
 func Test_CheckHealthy_Terming(t *testing.T) {
 	release := models.MockRelease(t)
@@ -141,3 +246,394 @@ func Test_CheckHealthy_Terming(t *testing.T) {
 	_, err := CheckHealthy(awsc)(nil, release)
 	assert.Error(t, err)
 }
+
+// Test Check Healthy halts if instances keep terminating
+func Test_CheckHealthy_CrashLoop(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Services["web"].Autoscaling.MaxTerminations = to.Int64p(1)
+	release.Services["web"].Autoscaling.MaxTerminationsPerInstance = to.Float64p(1)
+	models.MockPrepareRelease(release)
+	release.Services["web"].Resources = &models.ServiceResourceNames{}
+	release.Services["web"].CreatedASG = to.Strp("asd")
+
+	group := &autoscaling.Group{
+		MinSize:         to.Int64p(1),
+		DesiredCapacity: to.Int64p(1),
+		Instances:       mocks.MakeMockASGInstances(0, 0, 1),
+	}
+
+	awsc := mocks.MockAWS()
+	awsc.ASG.AddASG(group)
+
+	// One termination is below the threshold
+	release, err := CheckHealthy(awsc)(nil, release)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"InstanceId1"}, release.Services["web"].TerminatedIDs)
+
+	// The ASG replaced the instance and it is terminating again
+	group.Instances = mocks.MakeMockASGInstances(1, 0, 1)
+	_, err = CheckHealthy(awsc)(nil, release)
+	assert.Error(t, err)
+	assert.Regexp(t, "Crash loop detected", err.Error())
+}
+
+func mockLaunchExtensionRelease(t *testing.T) (*models.Release, *mocks.MockClients) {
+	release := models.MockRelease(t)
+	release.Timeout = to.Intp(600)
+	release.LaunchExtensionMax = to.Intp(300)
+	models.MockPrepareRelease(release)
+	release.Services["web"].Resources = &models.ServiceResourceNames{}
+	release.Services["web"].CreatedASG = to.Strp("asd")
+
+	// One instance took 200 seconds to reach InService and another is still launching
+	instances := mocks.MakeMockASGInstances(1, 0, 0)
+	instances = append(instances, &autoscaling.Instance{
+		InstanceId:     to.Strp("PendingInstanceId1"),
+		HealthStatus:   to.Strp("Healthy"),
+		LifecycleState: to.Strp("Pending"),
+	})
+
+	awsc := mocks.MockAWS()
+	awsc.ASG.AddASG(&autoscaling.Group{
+		MinSize:         to.Int64p(2),
+		DesiredCapacity: to.Int64p(2),
+		Instances:       instances,
+	})
+	awsc.EC2.AddInstance("InstanceId1", time.Now().Add(-200*time.Second))
+
+	return release, awsc
+}
+
+// Test Check Healthy extends the Timeout while slow launches are progressing
+func Test_CheckHealthy_LaunchExtension_Progressing(t *testing.T) {
+	release, awsc := mockLaunchExtensionRelease(t)
+	release.StartedAt = to.Timep(time.Now().Add(-590 * time.Second))
+
+	release, err := CheckHealthy(awsc)(nil, release)
+	assert.NoError(t, err)
+	assert.InDelta(t, 200, release.Services["web"].LaunchDurations["InstanceId1"], 2)
+	assert.NotNil(t, release.LaunchExtension)
+	assert.InDelta(t, 190, *release.LaunchExtension, 2)
+
+	// Past the Timeout the release is still checked
+	release.StartedAt = to.Timep(time.Now().Add(-700 * time.Second))
+	release, err = CheckHealthy(awsc)(nil, release)
+	assert.NoError(t, err)
+
+	// The extension is capped
+	assert.Equal(t, 300, *release.LaunchExtension)
+	release.StartedAt = to.Timep(time.Now().Add(-901 * time.Second))
+	_, err = CheckHealthy(awsc)(nil, release)
+	assert.Error(t, err)
+	assert.Regexp(t, "Timeout", err.Error())
+}
+
+// Test Check Healthy does not extend the Timeout for stalled launches
+func Test_CheckHealthy_LaunchExtension_Stalled(t *testing.T) {
+	release, awsc := mockLaunchExtensionRelease(t)
+	release.StartedAt = to.Timep(time.Now().Add(-590 * time.Second))
+
+	// No instance has reached InService for longer than the slowest launch took
+	release.Services["web"].LaunchDurations = map[string]int{"InstanceId1": 200}
+	release.LaunchProgressAt = to.Timep(time.Now().Add(-300 * time.Second))
+
+	release, err := CheckHealthy(awsc)(nil, release)
+	assert.NoError(t, err)
+	assert.Nil(t, release.LaunchExtension)
+
+	release.StartedAt = to.Timep(time.Now().Add(-601 * time.Second))
+	_, err = CheckHealthy(awsc)(nil, release)
+	assert.Error(t, err)
+	assert.Regexp(t, "Timeout", err.Error())
+}
+
+func Test_CleanUpSuccess_DrainError(t *testing.T) {
+	release := models.MockRelease(t)
+	release.DrainFirst = true
+	models.MockPrepareRelease(release)
+	awsc := models.MockAwsClients(release)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	release.UpdateWithResources(resources)
+
+	awsc.ALB.SetTargetHealth("web-elb-target", "InstanceId1", "draining")
+
+	_, err = CleanUpSuccess(awsc)(nil, release)
+	assert.IsType(t, models.DrainError{}, err)
+	assert.Regexp(t, "^DrainError: asg ", err.Error())
+}
+
+func Test_withEventLog_Writes_Start_Before_Handler(t *testing.T) {
+	release := models.MockRelease(t)
+	models.MockPrepareRelease(release)
+	awsc := models.MockAwsClients(release)
+
+	handler := withEventLog(awsc, "CheckHealthy", func(_ context.Context, r *models.Release) (*models.Release, error) {
+		// The start is logged even if the task never returns
+		assert.Equal(t, []string{"CheckHealthy:start"}, assertEvents(t, awsc, r))
+		return nil, fmt.Errorf("died")
+	})
+
+	_, err := handler(nil, release)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"CheckHealthy:start", "CheckHealthy:error"}, assertEvents(t, awsc, release))
+}
+
+func Test_Plan_DoesNotCreateResources(t *testing.T) {
+	release := models.MockRelease(t)
+	awsc := models.MockAwsClients(release)
+	s3Objects := len(awsc.S3.GetObjectResp)
+	puts := len(awsc.S3.PutObjectInputs)
+
+	plan, err := Plan(awsc)(context.Background(), release)
+	assert.NoError(t, err)
+
+	web := plan.Services["web"]
+	assert.Equal(t, release.Services["web"].ServiceID(), web.CreateASG)
+	assert.Equal(t, "project-config-web-old-release", to.Strs(web.PreviousASG))
+	assert.Equal(t, []string{"web-elb-target"}, to.StrSlice(web.TargetGroups))
+	assert.Equal(t, web.InstanceCount, plan.InstanceCount)
+	assert.True(t, plan.InstanceCount > 0)
+
+	_, err = json.Marshal(plan)
+	assert.NoError(t, err)
+
+	// Nothing was locked or created
+	assert.Equal(t, s3Objects, len(awsc.S3.GetObjectResp))
+	assert.Equal(t, puts, len(awsc.S3.PutObjectInputs))
+	assert.Equal(t, 0, len(awsc.DynamoDB.PutItemInputs))
+	assert.Equal(t, 0, len(awsc.ASG.PutScalingPolicyInputs))
+	assert.Equal(t, 0, len(awsc.EC2.CreateLaunchTemplateInputs))
+	assert.Nil(t, awsc.ASG.UpdateAutoScalingGroupLastInput)
+}
+
+func Test_Plan_Rollback_Writes_Nothing(t *testing.T) {
+	awsc := models.MockAwsClients(models.MockRelease(t))
+
+	previous := models.MockRelease(t)
+	previous.ReleaseID = to.Strp("rollback-release")
+	previous.SetUserData(to.Strp("#rollback_cloud_config"))
+	models.MockPrepareRelease(previous)
+	assert.NoError(t, s3.PutStruct(awsc.S3, previous.Bucket, previous.RollbackPlanPath(), &models.RollbackPlan{
+		PreviousReleaseID: previous.ReleaseID,
+		Previous:          &models.ReleaseRecord{Release: previous, UserData: previous.UserData()},
+	}))
+
+	release := models.MockMinimalRelease(t)
+	release.Bucket = previous.Bucket
+	release.AwsRegion = previous.AwsRegion
+	release.Rollback = true
+	release.Services = nil
+	models.AddReleaseS3Objects(awsc, release)
+	puts := len(awsc.S3.PutObjectInputs)
+
+	plan, err := Plan(awsc)(context.Background(), release)
+	assert.NoError(t, err)
+	assert.NotNil(t, plan.Services["web"])
+
+	// The rolled back user data is only uploaded once a deploy holds the lock
+	assert.Equal(t, puts, len(awsc.S3.PutObjectInputs))
+}
+
+func Test_TaskHandlers_Direct_Tasks(t *testing.T) {
+	tm := TaskHandlers()
+	assert.NoError(t, tm.Validate())
+
+	for _, task := range []string{"Plan", "Prune", "Abort", "Rollback"} {
+		assert.NotNil(t, (*tm)[task], task)
+	}
+}
+
+func Test_TaskHandlers_WithRetry(t *testing.T) {
+	tm := TaskHandlers(WithRetry(3, time.Millisecond))
+	assert.NoError(t, tm.Validate())
+
+	awsc := &aws.ClientsStr{}
+	WithRetry(3, time.Millisecond)(awsc)
+	assert.Equal(t, aws.NewRetryer(3, time.Millisecond), awsc.Retryer)
+	assert.Equal(t, 2, awsc.Retryer.MaxRetries())
+}
+
+func Test_Plan_BadRelease(t *testing.T) {
+	release := models.MockRelease(t)
+	awsc := models.MockAwsClients(release)
+	release.Services["web"].InstanceType = nil
+
+	_, err := Plan(awsc)(context.Background(), release)
+	assert.Error(t, err)
+	assert.IsType(t, &errors.BadReleaseError{}, err)
+}
+
+func Test_Plan_SSMImage(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Image = to.Strp("ssm:/odin/project/ami")
+	awsc := models.MockAwsClients(release)
+	awsc.SSM.AddParameter("/odin/project/ami", "ami-123456")
+
+	_, err := Plan(awsc)(context.Background(), release)
+	assert.NoError(t, err)
+	assert.Equal(t, "ami-123456", *release.Image)
+	assert.Equal(t, "ami-123456", *release.Services["web"].Resources.Image)
+}
+
+func Test_Plan_SSMImage_NotFound(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Image = to.Strp("ssm:/odin/project/ami")
+	awsc := models.MockAwsClients(release)
+
+	// Missing parameter
+	_, err := Plan(awsc)(context.Background(), release)
+	assert.Error(t, err)
+	assert.IsType(t, &errors.BadReleaseError{}, err)
+
+	// The parameter resolves to an AMI that does not exist
+	release = models.MockRelease(t)
+	release.Image = to.Strp("ssm:/odin/project/ami")
+	awsc = models.MockAwsClients(release)
+	awsc.SSM.AddParameter("/odin/project/ami", "ami-999999")
+
+	_, err = Plan(awsc)(context.Background(), release)
+	assert.Error(t, err)
+	assert.IsType(t, &errors.BadReleaseError{}, err)
+	assert.Contains(t, err.Error(), "Image is nil")
+}
+
+func Test_Prune_DryRun_And_Delete(t *testing.T) {
+	release := models.MockRelease(t)
+	awsc := models.MockAwsClients(release)
+	awsc.ASG.AddPreviousRuntimeResources(*release.ProjectName, *release.ConfigName, "web", "dead-release")
+
+	input := &models.PruneInput{Release: *release, DryRun: true}
+
+	// The current release must be known
+	_, err := Prune(awsc)(context.Background(), input)
+	assert.Error(t, err)
+	assert.IsType(t, &errors.CleanUpError{}, err)
+
+	current := &models.DeployResult{ReleaseID: to.Strp("old-release")}
+	assert.NoError(t, s3.PutStruct(awsc.S3, input.Bucket, input.CurrentDeployResultPath(), current))
+
+	result, err := Prune(awsc)(context.Background(), input)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"project-config-web-dead-release"}, to.StrSlice(result.AutoScalingGroups))
+	assert.Equal(t, 0, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+
+	input.DryRun = false
+	_, err = Prune(awsc)(context.Background(), input)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, "project-config-web-dead-release", *awsc.ASG.DeleteAutoScalingGroupInputs[0].AutoScalingGroupName)
+}
+
+func Test_Prune_BadInput(t *testing.T) {
+	release := models.MockRelease(t)
+	awsc := models.MockAwsClients(release)
+	release.ConfigName = nil
+
+	_, err := Prune(awsc)(context.Background(), &models.PruneInput{Release: *release})
+	assert.Error(t, err)
+	assert.IsType(t, &errors.BadReleaseError{}, err)
+
+	release.ConfigName = to.Strp("config")
+	_, err = Prune(awsc)(context.Background(), &models.PruneInput{Release: *release, Concurrency: to.Intp(100)})
+	assert.Error(t, err)
+	assert.IsType(t, &errors.BadReleaseError{}, err)
+}
+
+func Test_Abort_Releases_Lock(t *testing.T) {
+	release := models.MockRelease(t)
+	models.MockPrepareRelease(release)
+	awsc := models.MockAwsClients(release)
+
+	_, err := Lock(awsc)(context.Background(), release)
+	assert.NoError(t, err)
+
+	input := &models.AbortInput{}
+	input.AwsRegion = release.AwsRegion
+	input.AwsAccountID = release.AwsAccountID
+	input.Bucket = release.Bucket
+	input.ProjectName = release.ProjectName
+	input.ConfigName = release.ConfigName
+	input.ReleaseID = release.ReleaseID
+	input.UUID = release.UUID
+
+	result, err := Abort(awsc)(context.Background(), input)
+	assert.NoError(t, err)
+	assert.True(t, result.Unlocked)
+	assert.Equal(t, 0, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+
+	holder, err := InspectLock(awsc.S3, awsc.SFN, release.Bucket, release.AwsAccountID, release.ProjectName, release.ConfigName, nil)
+	assert.NoError(t, err)
+	assert.False(t, holder.Held)
+}
+
+func Test_Abort_BadInput(t *testing.T) {
+	release := models.MockRelease(t)
+	awsc := models.MockAwsClients(release)
+	release.UUID = nil
+
+	_, err := Abort(awsc)(context.Background(), &models.AbortInput{Release: *release})
+	assert.Error(t, err)
+	assert.IsType(t, &errors.BadReleaseError{}, err)
+}
+
+func Test_CheckHealthy_Reports_Progress(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Services["web"].Autoscaling.MinSize = to.Int64p(2)
+	release.Services["web"].Autoscaling.MaxSize = to.Int64p(2)
+	models.MockPrepareRelease(release)
+	release.Services["web"].Resources = &models.ServiceResourceNames{}
+	release.Services["web"].CreatedASG = to.Strp("asd")
+
+	group := &autoscaling.Group{
+		MinSize:         to.Int64p(2),
+		DesiredCapacity: to.Int64p(2),
+		Instances:       mocks.MakeMockASGInstances(0, 2, 0),
+	}
+
+	awsc := mocks.MockAWS()
+	awsc.ASG.AddASG(group)
+
+	p := progress.NewMemory()
+	checkHealthy := withProgress(p, "CheckHealthy", CheckHealthy(awsc))
+
+	// More instances come into service each poll
+	for healthy := 0; healthy <= 2; healthy++ {
+		group.Instances = mocks.MakeMockASGInstances(healthy, 2-healthy, 0)
+		_, err := checkHealthy(nil, release)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []int{0, 1, 2}, p.Healthy("web"))
+
+	updates := p.Updates()
+	assert.Equal(t, "project", updates[0].ProjectName)
+	assert.Equal(t, int64(2), updates[0].Services["web"].Desired)
+	assert.False(t, updates[0].Healthy)
+	assert.True(t, updates[2].Healthy)
+
+	// Other states are not instrumented
+	withProgress(p, "CheckRefresh", CheckHealthy(awsc))(nil, release)
+	assert.Equal(t, 3, len(p.Updates()))
+}
+
+func Test_InspectLock_Reads_Lock(t *testing.T) {
+	release := models.MockRelease(t)
+	models.MockPrepareRelease(release)
+	awsc := models.MockAwsClients(release)
+
+	holder, err := InspectLock(awsc.S3, awsc.SFN, release.Bucket, release.AwsAccountID, release.ProjectName, release.ConfigName, nil)
+	assert.NoError(t, err)
+	assert.False(t, holder.Held)
+
+	_, err = Lock(awsc)(context.Background(), release)
+	assert.NoError(t, err)
+	puts := len(awsc.S3.PutObjectInputs)
+
+	holder, err = InspectLock(awsc.S3, awsc.SFN, release.Bucket, release.AwsAccountID, release.ProjectName, release.ConfigName, nil)
+	assert.NoError(t, err)
+	assert.True(t, holder.Held)
+	assert.Equal(t, *release.UUID, *holder.UUID)
+	assert.Equal(t, puts, len(awsc.S3.PutObjectInputs))
+}
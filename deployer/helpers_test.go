@@ -1,11 +1,16 @@
 package deployer
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/coinbase/odin/aws"
 	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/odin/deployer/metrics"
 	"github.com/coinbase/odin/deployer/models"
+	"github.com/coinbase/odin/deployer/progress"
 	"github.com/coinbase/step/machine"
 	"github.com/coinbase/step/utils/to"
 	"github.com/stretchr/testify/assert"
@@ -34,16 +39,26 @@ func assertSuccessfulExecutionWithAWS(t *testing.T, release *models.Release, aws
 
 	assert.Equal(t, exec.Path(), []string{
 		"Validate",
+		"ValidateOnly?",
 		"Lock",
 		"ValidateResources",
+		"Validated?",
+		"PreDeployHook",
 		"Deploy",
+		"InstanceRefresh?",
 		"WaitForDeploy",
 		"WaitForHealthy",
+		"Canaried?",
 		"CheckHealthy",
 		"Healthy?",
+		"SmokeTest",
+		"CutoverDNS",
+		"Soak",
+		"Soaked?",
 		"WaitForDetach",
 		"DetachForSuccess",
 		"WaitDetachForSuccess",
+		"DrainForSuccess",
 		"CleanUpSuccess",
 		"Success",
 	})
@@ -62,3 +77,67 @@ func createTestStateMachine(t *testing.T, awsc aws.Clients) *machine.StateMachin
 
 	return stateMachine
 }
+
+func createTestRollbackStateMachine(t *testing.T, awsc aws.Clients) *machine.StateMachine {
+	stateMachine, err := RollbackStateMachine()
+	assert.NoError(t, err)
+
+	err = stateMachine.SetTaskFnHandlers(CreateRollbackTaskFunctinons(awsc))
+	assert.NoError(t, err)
+
+	return stateMachine
+}
+
+func createTestStateMachineWithMetrics(t *testing.T, awsc aws.Clients, m metrics.Metrics) *machine.StateMachine {
+	stateMachine, err := StateMachine()
+	assert.NoError(t, err)
+
+	err = stateMachine.SetTaskFnHandlers(CreateTaskFunctinonsWithMetrics(awsc, m))
+	assert.NoError(t, err)
+
+	return stateMachine
+}
+
+func createTestStateMachineWithProgress(t *testing.T, awsc aws.Clients, p progress.Progress) *machine.StateMachine {
+	stateMachine, err := StateMachine()
+	assert.NoError(t, err)
+
+	err = stateMachine.SetTaskFnHandlers(CreateTaskFunctinonsWithProgress(awsc, metrics.Nop{}, p))
+	assert.NoError(t, err)
+
+	return stateMachine
+}
+
+func assertNotifications(t *testing.T, awsc *mocks.MockClients, topicARN string) []*models.Notification {
+	notifications := []*models.Notification{}
+	for _, in := range awsc.SNS.PublishInputs {
+		assert.Equal(t, topicARN, *in.TopicArn)
+
+		var n models.Notification
+		assert.NoError(t, json.Unmarshal([]byte(*in.Message), &n))
+		assert.Equal(t, "project", *n.ProjectName)
+		assert.Equal(t, "config", *n.ConfigName)
+		assert.NotNil(t, n.ReleaseUUID)
+
+		notifications = append(notifications, &n)
+	}
+
+	return notifications
+}
+
+// assertEvents returns the event log written for the release as "State:event"
+func assertEvents(t *testing.T, awsc *mocks.MockClients, release *models.Release) []string {
+	events := []string{}
+	for _, key := range awsc.S3.PutObjectKeys() {
+		if !strings.HasPrefix(key, *release.EventLogDir()) {
+			continue
+		}
+
+		var e models.Event
+		assert.NoError(t, json.Unmarshal([]byte(awsc.S3.GetObjectResp[key].Body), &e))
+		assert.False(t, e.Time.IsZero())
+		events = append(events, fmt.Sprintf("%v:%v", e.State, e.Event))
+	}
+
+	return events
+}
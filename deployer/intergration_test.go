@@ -1,13 +1,29 @@
 package deployer
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/sts"
 
+	"github.com/coinbase/odin/aws"
 	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/odin/deployer/metrics"
 	"github.com/coinbase/odin/deployer/models"
+	"github.com/coinbase/odin/deployer/progress"
+	"github.com/coinbase/step/aws/s3"
 	"github.com/coinbase/step/utils/to"
 	"github.com/stretchr/testify/assert"
 )
@@ -22,6 +38,20 @@ func Test_Successful_Execution_Works(t *testing.T) {
 	assertSuccessfulExecution(t, release)
 }
 
+func Test_Successful_First_Deploy(t *testing.T) {
+	release := models.MockRelease(t)
+
+	// A new project config has no previous ASG, only the created ASG is found
+	awsc := models.MockAwsClients(release)
+	awsc.ASG = &mocks.ASGClient{TrackCreated: true}
+
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+	assert.Equal(t, 1, len(awsc.ASG.CreateAutoScalingGroupInputs))
+	assert.Equal(t, 0, len(awsc.ASG.DetachLoadBalancersInputs))
+	assert.Equal(t, 0, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+}
+
 func Test_Successful_Execution_Works_With_Minimal_Release(t *testing.T) {
 	// Should end in Alert Bad Thing Happened State
 	release := models.MockMinimalRelease(t)
@@ -36,355 +66,2531 @@ func Test_Successful_Execution_Works_With_SafeRelease(t *testing.T) {
 	assertSuccessfulExecution(t, release)
 }
 
+func Test_Successful_Execution_Works_With_Soak(t *testing.T) {
+	release := models.MockRelease(t)
+	release.SoakDuration = to.Intp(0)
+	release.SoakAlarms = []*string{to.Strp("web-5xx")}
+
+	awsc := models.MockAwsClients(release)
+	awsc.CW.AddAlarm("web-5xx", "OK")
+
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+}
+
+func Test_Successful_Execution_Writes_DeployResult(t *testing.T) {
+	release := models.MockRelease(t)
+	awsc := models.MockAwsClients(release)
+
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+	assert.NoError(t, err)
+	assert.Equal(t, true, exec.Output["success"])
+
+	// The result is also the state machine output
+	assert.NotNil(t, exec.Output["result"])
+
+	uuid := exec.Output["uuid"].(string)
+	result, err := FetchResult(awsc.S3, release.Bucket, release.AwsAccountID, release.ProjectName, release.ConfigName, &uuid)
+	assert.NoError(t, err)
+	assert.Equal(t, uuid, *result.ReleaseUUID)
+	assert.Equal(t, *release.ReleaseID, *result.ReleaseID)
+	assert.Regexp(t, "^project-config-.*-web$", *result.Services["web"].AutoScalingGroupName)
+	assert.Equal(t, []string{"web-elb"}, to.StrSlice(result.Services["web"].LoadBalancerNames))
+	assert.Equal(t, 1, len(result.Services["web"].TargetGroupARNs))
+}
+
+func Test_Successful_Execution_Works_With_Multiple_LoadBalancers(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Services["web"].ELBs = []*string{to.Strp("web-elb"), to.Strp("web-internal-elb")}
+	release.Services["web"].TargetGroups = []*string{to.Strp("web-elb-target"), to.Strp("web-internal-target")}
+
+	awsc := models.MockAwsClients(release)
+	awsc.ELB.AddELB("web-internal-elb", *release.ProjectName, *release.ConfigName, "web")
+	awsc.ALB.AddTargetGroup(mocks.MockTargetGroup{
+		Name:        "web-internal-target",
+		ProjectName: *release.ProjectName,
+		ConfigName:  *release.ConfigName,
+		ServiceName: "web",
+	})
+
+	old := awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0]
+	old.LoadBalancerNames = []*string{to.Strp("web-elb"), to.Strp("web-internal-elb")}
+	old.TargetGroupARNs = []*string{to.Strp("web-elb-target"), to.Strp("web-internal-target")}
+
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+	// The old ASG is detached from every load balancer
+	assert.Equal(t, 1, len(awsc.ASG.DetachLoadBalancersInputs))
+	assert.Equal(t, []string{"web-elb", "web-internal-elb"}, to.StrSlice(awsc.ASG.DetachLoadBalancersInputs[0].LoadBalancerNames))
+	assert.Equal(t, 1, len(awsc.ASG.DetachLoadBalancerTargetGroupsInputs))
+	assert.Equal(t, []string{"web-elb-target", "web-internal-target"}, to.StrSlice(awsc.ASG.DetachLoadBalancerTargetGroupsInputs[0].TargetGroupARNs))
+}
+
+func Test_Successful_Execution_Works_With_Network_LoadBalancer(t *testing.T) {
+	// A TCP service behind an NLB is health checked over TCP
+	release := models.MockRelease(t)
+	release.Services["web"].TargetGroups = []*string{to.Strp("web-elb-target"), to.Strp("web-tcp-target")}
+	release.Services["web"].TargetGroupHealth = map[string]*models.TargetGroupHealth{
+		"web-tcp-target": &models.TargetGroupHealth{Protocol: to.Strp("TCP")},
+	}
+
+	awsc := models.MockAwsClients(release)
+	awsc.ALB.AddTargetGroup(mocks.MockTargetGroup{
+		Name:        "web-tcp-target",
+		ProjectName: *release.ProjectName,
+		ConfigName:  *release.ConfigName,
+		ServiceName: "web",
+		Protocol:    "TCP",
+	})
+
+	// NLBs have no security groups so their health check ingress is not validated
+	awsc.ALB.AddTargetGroupLoadBalancer("web-tcp-target", 9000, "web-nlb-arn")
+
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+	assert.Equal(t, []string{"web-elb-target", "web-tcp-target"}, to.StrSlice(awsc.ASG.CreateAutoScalingGroupInputs[0].TargetGroupARNs))
+}
+
+func Test_Successful_Execution_Works_Without_LoadBalancers(t *testing.T) {
+	// A worker fleet is healthy when its instances are InService in the ASG
+	release := models.MockRelease(t)
+	release.Services["web"].ELBs = nil
+	release.Services["web"].TargetGroups = nil
+
+	awsc := models.MockAwsClients(release)
+	awsc.ASG = &mocks.ASGClient{TrackCreated: true}
+	awsc.ASG.AddPreviousRuntimeResources("project", "config", "web", "old-release")
+
+	old := awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0]
+	old.LoadBalancerNames = nil
+	old.TargetGroupARNs = nil
+
+	// The load balancers are never healthy so must not be checked
+	awsc.ELB.DescribeInstanceHealthResp["web-elb"] = &mocks.DescribeInstanceHealthResponse{}
+	awsc.ALB.DescribeTargetHealthResp["web-elb-target"] = &mocks.DescribeTargetHealthResponse{}
+
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+	assert.Equal(t, 1, len(awsc.ASG.CreateAutoScalingGroupInputs))
+	input := awsc.ASG.CreateAutoScalingGroupInputs[0]
+	assert.Equal(t, 0, len(input.LoadBalancerNames))
+	assert.Equal(t, 0, len(input.TargetGroupARNs))
+	assert.Equal(t, "EC2", *input.HealthCheckType)
+
+	// The old ASG has no load balancers to detach from but is still deleted
+	assert.Equal(t, 0, len(awsc.ASG.DetachLoadBalancersInputs))
+	assert.Equal(t, 0, len(awsc.ASG.DetachLoadBalancerTargetGroupsInputs))
+	assert.Equal(t, 1, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+}
+
+func Test_Successful_Execution_Works_With_DrainFirst(t *testing.T) {
+	release := models.MockRelease(t)
+	release.DrainFirst = true
+
+	awsc := models.MockAwsClients(release)
+	awsc.ASG = &mocks.ASGClient{TrackCreated: true}
+	awsc.ASG.AddPreviousRuntimeResources("project", "config", "web", "old-release")
+	awsc.ALB.AddTargetGroup(mocks.MockTargetGroup{
+		Name:                "web-elb-target",
+		ProjectName:         "project",
+		ConfigName:          "config",
+		ServiceName:         "web",
+		DeregistrationDelay: 120,
+	})
+
+	// The previous instance drains for a few polls after it is detached
+	old := awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0]
+	old.Instances[0].InstanceId = to.Strp("OldInstanceId1")
+	awsc.ALB.SetTargetHealth("web-elb-target", "OldInstanceId1", "draining")
+	awsc.ALB.DrainingPolls = map[string]int{"web-elb-target": 2}
+
+	stateMachine := createTestStateMachine(t, awsc)
+	exec, err := stateMachine.Execute(release)
+	assert.NoError(t, err)
+	assert.Equal(t, true, exec.Output["success"])
+
+	// Cleanup waited the deregistration delay, then retried until the instance drained
+	assert.Equal(t, 120.0, exec.Output["wait_for_drain"])
+	assert.Equal(t, 0, awsc.ALB.DrainingPolls["web-elb-target"])
+
+	path := exec.Path()
+	assert.Equal(t, []string{
+		"WaitForDetach",
+		"DetachForSuccess",
+		"WaitDetachForSuccess",
+		"DrainForSuccess",
+		"CleanUpSuccess",
+		"CleanUpSuccess",
+		"CleanUpSuccess",
+		"Success",
+	}, path[len(path)-8:])
+
+	// Then scaled the previous ASG to zero before deleting it
+	assert.Equal(t, 1, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	deleted := *awsc.ASG.DeleteAutoScalingGroupInputs[0].AutoScalingGroupName
+	assert.Equal(t, "project-config-web-old-release", deleted)
+
+	scaled := false
+	for _, input := range awsc.ASG.UpdateAutoScalingGroupInputs {
+		if *input.AutoScalingGroupName == deleted && *input.DesiredCapacity == 0 {
+			scaled = true
+		}
+	}
+	assert.True(t, scaled)
+}
+
+func Test_Successful_Execution_Works_With_DNS(t *testing.T) {
+	release := models.MockRelease(t)
+	release.DNS = &models.DNS{
+		HostedZoneID: to.Strp("zone"),
+		RecordName:   to.Strp("web.example.com"),
+		Service:      to.Strp("web"),
+	}
+
+	awsc := models.MockAwsClients(release)
+	awsc.Route53.AddWeightedRecord("zone", "web.example.com.", "old-release", "old-elb.elb.amazonaws.com", 100)
+
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+	// The old record is removed leaving only the new releases record
+	assert.Equal(t, map[string]int64{*release.ReleaseID: 100}, awsc.Route53.WeightedRecords("zone", "web.example.com."))
+	assert.Equal(t, 2, len(awsc.Route53.ChangeResourceRecordSetsInputs))
+}
+
+func Test_Successful_Execution_Works_With_Canary(t *testing.T) {
+	release := models.MockCanaryRelease(t)
+
+	stateMachine := createTestStateMachine(t, models.MockAwsClients(release))
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.NoError(t, err)
+	assert.Equal(t, true, exec.Output["success"])
+
+	// The canary bakes before the full count is launched and checked
+	assert.Equal(t, []string{
+		"Deploy",
+		"InstanceRefresh?",
+		"WaitForDeploy",
+		"WaitForHealthy",
+		"Canaried?",
+		"CheckCanary",
+		"WaitForHealthy",
+		"Canaried?",
+		"CheckHealthy",
+		"Healthy?",
+		"SmokeTest",
+	}, exec.Path()[6:17])
+}
+
+func Test_Successful_Execution_Works_With_Spot_Interruption(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Services["web"].OnDemandBaseCapacity = to.Int64p(0)
+
+	maws := models.MockAwsClients(release)
+	maws.ASG.DescribeAutoScalingGroupsPageResp = nil
+
+	// One instance is reclaimed by spot while the ASG launches its replacement
+	spotASG := mocks.MakeMockASG("odin", *release.ProjectName, *release.ConfigName, "web", "Old release")
+	spotASG.Instances = mocks.MakeMockASGInstances(1, 0, 1)
+	maws.ASG.AddASG(spotASG)
+	maws.EC2.AddSpotInterruption("InstanceId2")
+
+	assertSuccessfulExecutionWithAWS(t, release, maws)
+}
+
+// sdkSTSClients are the mocks except STS, which is a real SDK client sending its calls to a server
+type sdkSTSClients struct {
+	*mocks.MockClients
+	stsc aws.STSAPI
+}
+
+func (a *sdkSTSClients) STSClient(region *string, accountID *string, role *string) aws.STSAPI {
+	a.MockClients.STSClient(region, accountID, role)
+	return a.stsc
+}
+
+func Test_Successful_Execution_Works_With_Throttling(t *testing.T) {
+	release := models.MockRelease(t)
+	release.AwsAccountID = to.Strp("123456789012")
+	release.DeployRoleARN = to.Strp("arn:aws:iam::123456789012:role/odin/deployer")
+
+	// STS throttles the first two calls and 503s the third
+	var mu sync.Mutex
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+
+		w.Header().Set("Content-Type", "text/xml")
+		switch calls {
+		case 1, 2:
+			w.WriteHeader(400)
+			w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>Throttling</Code><Message>Rate exceeded</Message></Error></ErrorResponse>`))
+		case 3:
+			w.WriteHeader(503)
+		default:
+			w.Write([]byte(`<GetCallerIdentityResponse><GetCallerIdentityResult><Account>123456789012</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`))
+		}
+	}))
+	defer ts.Close()
+
+	config := awssdk.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(ts.URL).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", ""))
+
+	maws := models.MockAwsClients(release)
+	awsc := &sdkSTSClients{
+		MockClients: maws,
+		stsc:        sts.New(session.Must(session.NewSession()), request.WithRetryer(config, aws.NewRetryer(4, time.Millisecond))),
+	}
+
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.NoError(t, err)
+	assert.Equal(t, true, exec.Output["success"])
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, "Success", exec.Path()[len(exec.Path())-1])
+}
+
+func Test_UnsuccessfulDeploy_Throttled(t *testing.T) {
+	release := models.MockRelease(t)
+
+	// The SDK has already retried a throttling error that reaches a handler
+	maws := models.MockAwsClients(release)
+	maws.ASG.AddThrottles("CreateAutoScalingGroup", 1)
+
+	stateMachine := createTestStateMachine(t, maws)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+
+	ep := exec.Path()
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"Validated?",
+		"PreDeployHook",
+		"Deploy",
+		"DetachForFailure",
+	}, ep[0:8])
+
+	assert.Regexp(t, "Throttling", exec.LastOutputJSON)
+}
+
+func Test_Successful_Execution_Works_With_Notifications(t *testing.T) {
+	release := models.MockRelease(t)
+	release.NotificationTopicARN = to.Strp("arn:aws:sns:us-east-1:000000:deploys")
+
+	awsc := models.MockAwsClients(release)
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+	notifications := assertNotifications(t, awsc, "arn:aws:sns:us-east-1:000000:deploys")
+	assert.Equal(t, 2, len(notifications))
+
+	assert.Equal(t, models.NotifyStarted, notifications[0].Event)
+	assert.Equal(t, []string{"Validate", "Lock"}, notifications[0].Path)
+
+	assert.Equal(t, models.NotifyHealthy, notifications[1].Event)
+	assert.Equal(t, []string{"Validate", "Lock", "ValidateResources", "PreDeployHook", "Deploy", "CheckHealthy"}, notifications[1].Path)
+	assert.Nil(t, notifications[1].Error)
+}
+
+func Test_Successful_Execution_Works_With_EventLog(t *testing.T) {
+	release := models.MockRelease(t)
+	awsc := models.MockAwsClients(release)
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+	assert.Equal(t, []string{
+		"Validate:start", "Validate:success",
+		"Lock:start", "Lock:success",
+		"ValidateResources:start", "ValidateResources:success",
+		"PreDeployHook:start", "PreDeployHook:success",
+		"Deploy:start", "Deploy:success",
+		"CheckHealthy:start", "CheckHealthy:success",
+		"SmokeTest:start", "SmokeTest:success",
+		"CutoverDNS:start", "CutoverDNS:success",
+		"Soak:start", "Soak:success",
+		"DetachForSuccess:start", "DetachForSuccess:success",
+		"CleanUpSuccess:start", "CleanUpSuccess:success",
+	}, assertEvents(t, awsc, release))
+}
+
+func Test_Successful_Execution_Works_With_SuspendProcesses(t *testing.T) {
+	release := models.MockRelease(t)
+	awsc := models.MockAwsClients(release)
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+	// Only the deleted previous ASG is left suspended
+	assert.Equal(t, map[string][]string{
+		"project-config-web-old-release": []string{"AZRebalance", "ReplaceUnhealthy"},
+	}, awsc.ASG.SuspendedProcesses)
+
+	// None suspends nothing
+	release = models.MockRelease(t)
+	release.Services["web"].SuspendProcesses = []*string{}
+	awsc = models.MockAwsClients(release)
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+	assert.Equal(t, 0, len(awsc.ASG.SuspendedProcesses))
+}
+
+func Test_Successful_Execution_Works_With_Rollback(t *testing.T) {
+	awsc := models.MockAwsClients(models.MockRelease(t))
+
+	// The release to roll back to, and the plan written by the release that replaced it
+	previous := models.MockRelease(t)
+	previous.ReleaseID = to.Strp("rollback-release")
+	previous.Image = to.Strp("rollback-ami")
+	previous.SetUserData(to.Strp("#rollback_cloud_config"))
+	models.MockPrepareRelease(previous)
+	previous.MaxParallelServices = to.Intp(1)
+	assert.NoError(t, s3.PutStruct(awsc.S3, previous.Bucket, previous.RollbackPlanPath(), &models.RollbackPlan{
+		PreviousReleaseID: previous.ReleaseID,
+		Previous:          &models.ReleaseRecord{Release: previous, UserData: previous.UserData()},
+	}))
+	awsc.EC2.AddImage("rollback-ami", "ami-654321")
+
+	// The rollback request only identifies the project config
+	release := models.MockMinimalRelease(t)
+	release.ReleaseID = to.Strp("rollback")
+	release.Bucket = previous.Bucket
+	release.AwsRegion = previous.AwsRegion
+	release.Services = nil
+	models.AddReleaseS3Objects(awsc, release)
+
+	stateMachine := createTestRollbackStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+	assert.NoError(t, err)
+	assert.Equal(t, true, exec.Output["success"])
+	assert.Equal(t, []string{"Rollback", "ValidateOnly?", "Lock"}, exec.Path()[0:3])
+
+	assert.Regexp(t, "rollback-ami", exec.LastOutputJSON)
+	assert.Regexp(t, `"rollback_release_id": "rollback-release"`, exec.LastOutputJSON)
+	assert.Regexp(t, `"max_parallel_services": 1`, exec.LastOutputJSON)
+	assert.Regexp(t, to.SHA256Str(to.Strp("#rollback_cloud_config")), exec.LastOutputJSON)
+	assert.Equal(t, "Success", exec.Path()[len(exec.Path())-1])
+
+	// The previous user data is written for the release once it holds the lock
+	userdata, err := s3.GetStr(awsc.S3, release.Bucket, release.UserDataPath())
+	assert.NoError(t, err)
+	assert.Equal(t, "#rollback_cloud_config", *userdata)
+}
+
+func Test_Successful_Execution_Ignores_Rollback_Input(t *testing.T) {
+	// Only the rollback state machine rolls back, a deploy of a release with rollback set deploys the release
+	release := models.MockRelease(t)
+	release.Rollback = true
+	awsc := models.MockAwsClients(release)
+
+	previous := models.MockRelease(t)
+	previous.ReleaseID = to.Strp("rollback-release")
+	previous.Image = to.Strp("rollback-ami")
+	models.MockPrepareRelease(previous)
+	assert.NoError(t, s3.PutStruct(awsc.S3, previous.Bucket, previous.RollbackPlanPath(), &models.RollbackPlan{
+		PreviousReleaseID: previous.ReleaseID,
+		Previous:          &models.ReleaseRecord{Release: previous, UserData: previous.UserData()},
+	}))
+
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+	assert.NotEmpty(t, awsc.ASG.CreateAutoScalingGroupInputs)
+	for _, in := range awsc.ASG.CreateAutoScalingGroupInputs {
+		assert.NotRegexp(t, "rollback-release", in.GoString())
+	}
+}
+
 ///////////////
 // Unsuccessful Tests
 ///////////////
 
-func Test_Successful_Execution_Unsuccessful_With_SafeRelease_Change(t *testing.T) {
+func Test_UnsuccessfulDeploy_Rollback_No_Previous_Release(t *testing.T) {
 	release := models.MockRelease(t)
-	release.SafeRelease = true
-	release.Services["web"].ELBs = []*string{}
 
-	// Should end in Alert Bad Thing Happened State
+	stateMachine := createTestRollbackStateMachine(t, models.MockAwsClients(release))
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Regexp(t, "BadReleaseError", exec.LastOutputJSON)
+	assert.Regexp(t, "no previous release to roll back to", exec.LastOutputJSON)
+	assert.Equal(t, []string{"Rollback", "NotifyFailure", "FailureClean"}, exec.Path())
+}
+
+func Test_UnsuccessfulDeploy_SchemaVersion_Too_Old(t *testing.T) {
+	release := models.MockRelease(t)
+	release.SchemaVersion = to.Intp(models.MinSchemaVersion - 1)
+
+	stateMachine := createTestStateMachine(t, models.MockAwsClients(release))
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Regexp(t, "BadReleaseError", exec.LastOutputJSON)
+	assert.Regexp(t, "no longer supported, migrate the release", exec.LastOutputJSON)
+	assert.Equal(t, []string{"Validate", "NotifyFailure", "FailureClean"}, exec.Path())
+}
+
+func Test_UnsuccessfulDeploy_Missing_Required_Tags(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Tags = map[string]*string{"Team": to.Strp("payments")}
+
 	awsc := models.MockAwsClients(release)
+	assert.NoError(t, s3.PutStruct(awsc.S3, release.Bucket, release.DeployerPolicyDocumentPath(), &models.PolicyDocument{
+		RequiredTags: []*string{to.Strp("CostCenter"), to.Strp("Team")},
+	}))
+
 	stateMachine := createTestStateMachine(t, awsc)
 
-	previousRelease := models.MockRelease(t)
-	previousRelease.ReleaseID = to.Strp("old-release")
-	models.AddReleaseS3Objects(awsc, previousRelease)
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Regexp(t, "BadReleaseError", exec.LastOutputJSON)
+	assert.Regexp(t, `missing required tag \\"CostCenter\\"`, exec.LastOutputJSON)
+	assert.NotRegexp(t, `missing required tag \\"Team\\"`, exec.LastOutputJSON)
+	assert.Equal(t, []string{"Validate", "NotifyFailure", "FailureClean"}, exec.Path())
+}
+
+func Test_Successful_Execution_Works_With_Required_Tags(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Tags = map[string]*string{"CostCenter": to.Strp("1234"), "Team": to.Strp("payments")}
+
+	awsc := models.MockAwsClients(release)
+	assert.NoError(t, s3.PutStruct(awsc.S3, release.Bucket, release.DeployerPolicyDocumentPath(), &models.PolicyDocument{
+		RequiredTags: []*string{to.Strp("CostCenter"), to.Strp("Team")},
+	}))
+
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+}
+
+func Test_UnsuccessfulDeploy_SchemaVersion_Too_New(t *testing.T) {
+	release := models.MockRelease(t)
+	release.SchemaVersion = to.Intp(models.MaxSchemaVersion + 1)
+
+	stateMachine := createTestStateMachine(t, models.MockAwsClients(release))
 
 	exec, err := stateMachine.Execute(release)
 
 	assert.Error(t, err)
-	assert.Regexp(t, `SafeRelease Error\(web\)`, exec.LastOutputJSON)
+	assert.Regexp(t, "BadReleaseError", exec.LastOutputJSON)
+	assert.Regexp(t, "newer than the max supported", exec.LastOutputJSON)
+	assert.Equal(t, []string{"Validate", "NotifyFailure", "FailureClean"}, exec.Path())
+}
+
+func Test_UnsuccessfulDeploy_Canary_Never_Healthy(t *testing.T) {
+	release := models.MockCanaryRelease(t)
+
+	maws := models.MockAwsClients(release)
+	maws.ELB.DescribeInstanceHealthResp["web-elb"] = &mocks.DescribeInstanceHealthResponse{}
+
+	stateMachine := createTestStateMachine(t, maws)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+
+	ep := exec.Path()
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"Validated?",
+		"PreDeployHook",
+		"Deploy",
+		"InstanceRefresh?",
+		"WaitForDeploy",
+		"WaitForHealthy",
+		"Canaried?",
+		"CheckCanary"}, ep[0:12])
+
+	// The full count is never checked
+	assert.NotContains(t, ep, "CheckHealthy")
+
+	assert.Equal(t, []string{
+		"DetachForFailure",
+		"WaitDetachForFailure",
+		"DrainForFailure",
+		"CleanUpFailure",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	}, ep[len(ep)-7:len(ep)])
+
+	assert.NotRegexp(t, "baked", exec.LastOutputJSON)
+	assert.Regexp(t, "Timeout", exec.LastOutputJSON)
+	assert.Regexp(t, "success\": false", exec.LastOutputJSON)
+}
+
+func Test_UnsuccessfulDeploy_Canary_Terminating(t *testing.T) {
+	release := models.MockCanaryRelease(t)
+	maws := models.MockAwsClients(release)
+	maws.ASG.DescribeAutoScalingGroupsPageResp = nil
+
+	termingASG := mocks.MakeMockASG("odin", *release.ProjectName, *release.ConfigName, "web", "Old release")
+	termingASG.Instances[0].LifecycleState = to.Strp("Terminating")
+
+	maws.ASG.AddASG(termingASG)
+
+	stateMachine := createTestStateMachine(t, maws)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Regexp(t, "Canary failed", exec.LastOutputJSON)
+	assert.Regexp(t, "success\": false", exec.LastOutputJSON)
+
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"Validated?",
+		"PreDeployHook",
+		"Deploy",
+		"InstanceRefresh?",
+		"WaitForDeploy",
+		"WaitForHealthy",
+		"Canaried?",
+		"CheckCanary",
+		"DetachForFailure",
+		"WaitDetachForFailure",
+		"DrainForFailure",
+		"CleanUpFailure",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path())
+}
+
+func Test_UnsuccessfulDeploy_Soak_Alarm(t *testing.T) {
+	release := models.MockRelease(t)
+	release.SoakDuration = to.Intp(60)
+	release.SoakAlarms = []*string{to.Strp("web-5xx")}
+
+	awsc := models.MockAwsClients(release)
+	awsc.CW.AddAlarm("web-5xx", "ALARM")
+
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"Validated?",
+		"PreDeployHook",
+		"Deploy",
+		"InstanceRefresh?",
+		"WaitForDeploy",
+		"WaitForHealthy",
+		"Canaried?",
+		"CheckHealthy",
+		"Healthy?",
+		"SmokeTest",
+		"CutoverDNS",
+		"Soak",
+		"DetachForFailure",
+		"WaitDetachForFailure",
+		"DrainForFailure",
+		"CleanUpFailure",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path())
+
+	assert.Regexp(t, "Soak alarms in ALARM state web-5xx", exec.LastOutputJSON)
+}
+
+func Test_UnsuccessfulDeploy_Soak_Unhealthy(t *testing.T) {
+	release := models.MockRelease(t)
+	release.SoakDuration = to.Intp(60)
+
+	awsc := models.MockAwsClients(release)
+	// CheckHealthy sees the instance InService, then it goes OutOfService while soaking
+	awsc.ELB.OutOfServiceAfter = map[string]int{"web-elb": 1}
+
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"CutoverDNS",
+		"Soak",
+		"DetachForFailure",
+		"WaitDetachForFailure",
+		"DrainForFailure",
+		"CleanUpFailure",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path()[14:])
+
+	assert.Regexp(t, "Services unhealthy during soak web", exec.LastOutputJSON)
+
+	// Only a successful release writes its result
+	for key := range awsc.S3.GetObjectResp {
+		assert.NotContains(t, key, "/results/")
+	}
+
+	assert.Regexp(t, "success\": false", exec.LastOutputJSON)
+
+	// CleanUpFailure tears down the new release, the old ASG is kept
+	for _, input := range awsc.ASG.DeleteAutoScalingGroupInputs {
+		assert.NotEqual(t, "project-config-web-old-release", *input.AutoScalingGroupName)
+	}
+}
+
+func Test_UnsuccessfulDeploy_Soak_Alarm_Reverts_DNS(t *testing.T) {
+	release := models.MockRelease(t)
+	release.SoakDuration = to.Intp(60)
+	release.SoakAlarms = []*string{to.Strp("web-5xx")}
+	release.DNS = &models.DNS{
+		HostedZoneID: to.Strp("zone"),
+		RecordName:   to.Strp("web.example.com"),
+		Service:      to.Strp("web"),
+	}
+
+	awsc := models.MockAwsClients(release)
+	awsc.CW.AddAlarm("web-5xx", "ALARM")
+	awsc.Route53.AddWeightedRecord("zone", "web.example.com.", "old-release", "old-elb.elb.amazonaws.com", 100)
+
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"CutoverDNS",
+		"Soak",
+		"DetachForFailure",
+		"WaitDetachForFailure",
+		"DrainForFailure",
+		"CleanUpFailure",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path()[14:])
+
+	// The new record was created then removed, and the old record restored
+	assert.Equal(t, 2, len(awsc.Route53.ChangeResourceRecordSetsInputs))
+	assert.Equal(t, map[string]int64{"old-release": 100}, awsc.Route53.WeightedRecords("zone", "web.example.com."))
+}
+
+func Test_UnsuccessfulDeploy_ListenerRule_Cutover_Fails_Reverts_DNS(t *testing.T) {
+	listenerArn := "arn:aws:elasticloadbalancing:us-east-1:000000:listener/app/web/1234/5678"
+	release := models.MockRelease(t)
+	release.DNS = &models.DNS{
+		HostedZoneID: to.Strp("zone"),
+		RecordName:   to.Strp("web.example.com"),
+		Service:      to.Strp("web"),
+	}
+	release.Services["web"].TargetGroups = []*string{to.Strp("web-elb-target"), to.Strp("web-blue"), to.Strp("web-green")}
+	release.Services["web"].ListenerRule = &models.ListenerRule{
+		ListenerArn:  to.Strp(listenerArn),
+		Priority:     to.Int64p(20),
+		TargetGroups: []*string{to.Strp("web-blue"), to.Strp("web-green")},
+	}
+
+	awsc := models.MockAwsClients(release)
+	for _, name := range []string{"web-blue", "web-green"} {
+		awsc.ALB.AddTargetGroup(mocks.MockTargetGroup{
+			Name:        name,
+			ProjectName: *release.ProjectName,
+			ConfigName:  *release.ConfigName,
+			ServiceName: "web",
+		})
+	}
+	awsc.ALB.AddListenerRule(listenerArn, "20", "web-blue")
+	awsc.Route53.AddWeightedRecord("zone", "web.example.com.", "old-release", "old-elb.elb.amazonaws.com", 60)
+	awsc.Route53.AddWeightedRecord("zone", "web.example.com.", "older-release", "older-elb.elb.amazonaws.com", 40)
+
+	// The DNS is cut over then the listener rule fails every attempt of CutoverDNS
+	awsc.ALB.AddThrottles("ModifyRule", 4)
+
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"CutoverDNS",
+		"CutoverDNS",
+		"CutoverDNS",
+		"CutoverDNS",
+		"DetachForFailure",
+		"WaitDetachForFailure",
+		"DrainForFailure",
+		"CleanUpFailure",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path()[14:])
+
+	// The records were changed once by the cutover and once by the revert, restoring the old weights
+	assert.Equal(t, 2, len(awsc.Route53.ChangeResourceRecordSetsInputs))
+	assert.Equal(t, map[string]int64{"old-release": 60, "older-release": 40}, awsc.Route53.WeightedRecords("zone", "web.example.com."))
+}
+
+func Test_Successful_Execution_Unsuccessful_With_SafeRelease_Change(t *testing.T) {
+	release := models.MockRelease(t)
+	release.SafeRelease = true
+	release.Services["web"].ELBs = []*string{}
+
+	// Should end in Alert Bad Thing Happened State
+	awsc := models.MockAwsClients(release)
+	stateMachine := createTestStateMachine(t, awsc)
+
+	previousRelease := models.MockRelease(t)
+	previousRelease.ReleaseID = to.Strp("old-release")
+	models.AddReleaseS3Objects(awsc, previousRelease)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Regexp(t, `SafeRelease Error\(web\)`, exec.LastOutputJSON)
+
+	assert.Equal(t, exec.Path(), []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"LockHeld?",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	})
+}
+
+func Test_UnsuccessfulDeploy_Bad_Userdata_SHA(t *testing.T) {
+	// Should end in Alert Bad Thing Happened State
+	release := models.MockMinimalRelease(t)
+
+	// Should end in Alert Bad Thing Happened State
+	stateMachine := createTestStateMachine(t, models.MockAwsClients(release))
+	release.UserDataSHA256 = to.Strp("asfhjoias")
+
+	exec, err := stateMachine.Execute(release)
+	output := exec.Output
+
+	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", output["Error"])
+
+	assert.Equal(t, []string{
+		"Validate",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path())
+
+	// The SHA is of the rendered user data, not the template
+	release = models.MockMinimalRelease(t)
+	release.UserDataTemplate = true
+	release.SetUserData(to.Strp("#cloud_config {{.ProjectName}}"))
+
+	awsc := models.MockAwsClients(release)
+	stateMachine = createTestStateMachine(t, awsc)
+	release.UserDataSHA256 = to.Strp(to.SHA256Str(to.Strp("#cloud_config {{.ProjectName}}")))
+
+	raw, err := json.Marshal(release)
+	assert.NoError(t, err)
+	awsc.S3.AddGetObject(*release.ReleasePath(), string(raw), nil)
+
+	exec, err = stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Regexp(t, "UserData SHA incorrect", exec.LastOutputJSON)
+
+	assert.Equal(t, []string{
+		"Validate",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path())
+}
+
+func Test_UnsuccessfulDeploy_UserData_Too_Large(t *testing.T) {
+	release := models.MockRelease(t)
+	release.SetUserData(to.Strp("#cloud_config\n" + strings.Repeat("a", 16384)))
+
+	awsc := models.MockAwsClients(release)
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Regexp(t, "BadReleaseError", exec.LastOutputJSON)
+	assert.Regexp(t, "more than the AWS limit of 16384 bytes", exec.LastOutputJSON)
+
+	// Fails before the lock is grabbed or anything is created
+	assert.Equal(t, []string{
+		"Validate",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path())
+	assert.Equal(t, 0, len(awsc.ASG.CreateAutoScalingGroupInputs))
+}
+
+func Test_Successful_Execution_Works_With_UserData_Template(t *testing.T) {
+	release := models.MockRelease(t)
+	release.UserDataTemplate = true
+	release.SetUserData(to.Strp("#cloud_config {{.ProjectName}} {{.ReleaseUUID}} {{SERVICE_NAME}}"))
+
+	awsc := models.MockAwsClients(release)
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+}
+
+func Test_Successful_Execution_Works_With_UserData_Not_Template(t *testing.T) {
+	// User data that is not a Go template can contain braces, e.g. cloud-init jinja
+	release := models.MockRelease(t)
+	release.SetUserData(to.Strp("## template: jinja\n#cloud-config\nhostname: {{ ds.meta_data.local_hostname }}"))
+
+	awsc := models.MockAwsClients(release)
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+}
+
+func Test_UnsuccessfulDeploy_Execution_Works(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Timeout = to.Intp(-10) // This will cause immediate timeout
+
+	// Should end in Alert Bad Thing Happened State
+	stateMachine := createTestStateMachine(t, models.MockAwsClients(release))
+
+	exec, err := stateMachine.Execute(release)
+	output := exec.Output
+
+	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", output["Error"])
+
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"Validated?",
+		"PreDeployHook",
+		"Deploy",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path())
+}
+
+func Test_UnsuccessfulDeploy_Healthy_Timeout_Writes_FailureReport(t *testing.T) {
+	release := models.MockRelease(t)
+
+	awsc := models.MockAwsClients(release)
+	awsc.ELB.DescribeInstanceHealthResp["web-elb"] = &mocks.DescribeInstanceHealthResponse{}
+
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", exec.Output["Error"])
+
+	var output models.Release
+	assert.NoError(t, json.Unmarshal([]byte(exec.LastOutputJSON), &output))
+
+	report := output.FailureReport
+	assert.NotNil(t, report)
+	assert.Equal(t, output.UUID, report.ReleaseUUID)
+
+	assert.Equal(t, []string{
+		"Validate",
+		"Lock",
+		"ValidateResources",
+		"PreDeployHook",
+		"Deploy",
+		"CheckHealthy",
+		"DetachForFailure",
+		"CleanUpFailure",
+	}, report.Path)
+
+	assert.NotNil(t, report.Error)
+	assert.Equal(t, "TimeoutError", to.Strs(report.Error.Error))
+	assert.Regexp(t, "Timeout", to.Strs(report.Error.Cause))
+	assert.NotNil(t, report.CleanedUp)
+
+	assert.True(t, report.PreviousFleetIntact)
+	assert.Equal(t, "project-config-web-old-release", to.Strs(report.PreviousFleet["web"].AutoScalingGroupName))
+	assert.Equal(t, 1, report.PreviousFleet["web"].InService)
+
+	// The same report is written to S3
+	var written models.FailureReport
+	assert.NoError(t, s3.GetStruct(awsc.S3, output.Bucket, output.FailureReportPath(), &written))
+	assert.Equal(t, report, &written)
+}
+
+func Test_UnsuccessfulDeploy_Resumes_Previous_Processes(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Timeout = to.Intp(-10) // This will cause immediate timeout
+
+	awsc := models.MockAwsClients(release)
+	stateMachine := createTestStateMachine(t, awsc)
+
+	_, err := stateMachine.Execute(release)
+	assert.Error(t, err)
+
+	// The previous ASG keeps serving so can rebalance again
+	assert.Nil(t, awsc.ASG.SuspendedProcesses["project-config-web-old-release"])
+}
+
+func Test_UnsuccessfulDeploy_Execution_Writes_EventLog(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Timeout = to.Intp(-10) // This will cause immediate timeout
+
+	awsc := models.MockAwsClients(release)
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", exec.Output["Error"])
+
+	assert.Equal(t, []string{
+		"Validate:start", "Validate:success",
+		"Lock:start", "Lock:success",
+		"ValidateResources:start", "ValidateResources:success",
+		"PreDeployHook:start", "PreDeployHook:success",
+		"Deploy:start", "Deploy:error",
+		"ReleaseLockFailure:start", "ReleaseLockFailure:success",
+		"NotifyFailure:start", "NotifyFailure:success",
+	}, assertEvents(t, awsc, release))
+}
+
+func Test_Successful_Execution_Works_With_ValidateOnly(t *testing.T) {
+	release := models.MockRelease(t)
+	release.ValidateOnly = true
+
+	awsc := models.MockAwsClients(release)
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+	output := exec.Output
+
+	assert.NoError(t, err)
+	assert.Equal(t, true, output["success"])
+	assert.NotRegexp(t, "error", exec.LastOutputJSON)
+
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"ValidateResources",
+		"Validated?",
+		"ValidationSuccess",
+	}, exec.Path())
+
+	// Nothing is locked or deployed
+	assert.Equal(t, 0, len(awsc.DynamoDB.PutItemInputs))
+	assert.Equal(t, 0, len(awsc.ASG.CreateOrUpdateTagsInputs))
+}
+
+func Test_UnsuccessfulDeploy_ValidateOnly_Bad_Resources(t *testing.T) {
+	release := models.MockRelease(t)
+	release.ValidateOnly = true
+	release.Services["web"].Profile = to.Strp("missing-profile")
+
+	awsc := models.MockAwsClients(release)
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", exec.Output["Error"])
+
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"ValidateResources",
+		"LockHeld?",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path())
+
+	// The lock was never taken so is not released
+	assert.Equal(t, 0, len(awsc.DynamoDB.DeleteItemInputs))
+}
+
+func Test_Successful_Execution_Works_With_PreDeployHook(t *testing.T) {
+	hook := "arn:aws:lambda:us-east-1:000000:function:gate"
+	release := models.MockRelease(t)
+	release.PreDeployHook = to.Strp(hook)
+
+	awsc := models.MockAwsClients(release)
+	awsc.Lambda.AddAllow(hook)
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+	assert.NoError(t, err)
+	assert.Equal(t, true, exec.Output["success"])
+
+	assert.Equal(t, 1, len(awsc.Lambda.InvokeInputs))
+	assert.Equal(t, hook, *awsc.Lambda.InvokeInputs[0].FunctionName)
+	assert.Regexp(t, `"project_name":"project"`, string(awsc.Lambda.InvokeInputs[0].Payload))
+	assert.Regexp(t, `"services":\["web"\]`, string(awsc.Lambda.InvokeInputs[0].Payload))
+}
+
+func Test_UnsuccessfulDeploy_PreDeployHook_Deny(t *testing.T) {
+	hook := "arn:aws:lambda:us-east-1:000000:function:gate"
+
+	tests := map[string]struct {
+		respond func(*mocks.LambdaClient)
+		err     string
+	}{
+		"deny":      {func(l *mocks.LambdaClient) { l.AddDeny(hook, "change freeze") }, "denied the release: change freeze"},
+		"non 2xx":   {func(l *mocks.LambdaClient) { l.AddInvokeResponse(hook, 500, `{"allow": true}`) }, "status code 500"},
+		"no answer": {func(l *mocks.LambdaClient) { l.AddInvokeResponse(hook, 200, `{}`) }, "denied the release"},
+		"not found": {func(l *mocks.LambdaClient) {}, "ResourceNotFoundException"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			release := models.MockRelease(t)
+			release.PreDeployHook = to.Strp(hook)
+
+			awsc := models.MockAwsClients(release)
+			test.respond(awsc.Lambda)
+			stateMachine := createTestStateMachine(t, awsc)
+
+			exec, err := stateMachine.Execute(release)
+
+			assert.Error(t, err)
+			assert.Equal(t, "FailureClean", exec.Output["Error"])
+			assert.Regexp(t, "BadReleaseError", exec.LastOutputJSON)
+			assert.Regexp(t, test.err, exec.LastOutputJSON)
+
+			assert.Equal(t, []string{
+				"Validate",
+				"ValidateOnly?",
+				"Lock",
+				"ValidateResources",
+				"Validated?",
+				"PreDeployHook",
+				"ReleaseLockFailure",
+				"NotifyFailure",
+				"FailureClean",
+			}, exec.Path())
+
+			// The hook ran while the lock was held, and nothing was deployed
+			assert.Equal(t, 1, len(awsc.DynamoDB.PutItemInputs))
+			assert.Equal(t, 0, len(awsc.DynamoDB.Locks))
+			assert.Equal(t, 0, len(awsc.ASG.CreateOrUpdateTagsInputs))
+		})
+	}
+}
+
+func Test_Successful_Execution_Works_With_KeepPreviousReleases(t *testing.T) {
+	release := models.MockRelease(t)
+	release.KeepPreviousReleases = to.Intp(1)
+
+	awsc := models.MockAwsClients(release)
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+	// The previous release is detached and retained at zero rather than deleted
+	assert.Equal(t, 0, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, 1, len(awsc.ASG.DetachLoadBalancersInputs))
+	assert.Equal(t, "project-config-web-old-release", *awsc.ASG.DetachLoadBalancersInputs[0].AutoScalingGroupName)
+
+	retained := false
+	for _, input := range awsc.ASG.UpdateAutoScalingGroupInputs {
+		if *input.AutoScalingGroupName == "project-config-web-old-release" {
+			retained = true
+			assert.Equal(t, int64(0), *input.MinSize)
+			assert.Equal(t, int64(0), *input.MaxSize)
+			assert.Equal(t, int64(0), *input.DesiredCapacity)
+		}
+	}
+	assert.True(t, retained)
+}
+
+func Test_Successful_Execution_Works_With_SmokeTest(t *testing.T) {
+	smoke := "arn:aws:lambda:us-east-1:000000:function:smoke"
+	release := models.MockRelease(t)
+	release.SmokeTest = to.Strp(smoke)
+
+	awsc := models.MockAwsClients(release)
+	awsc.Lambda.AddInvokeResponse(smoke, 200, `{"passed": true}`)
+
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+	assert.Equal(t, 1, len(awsc.Lambda.InvokeInputs))
+}
+
+func Test_UnsuccessfulDeploy_SmokeTest_Failed(t *testing.T) {
+	smoke := "arn:aws:lambda:us-east-1:000000:function:smoke"
+	release := models.MockRelease(t)
+	release.SmokeTest = to.Strp(smoke)
+
+	awsc := models.MockAwsClients(release)
+	awsc.Lambda.AddInvokeResponse(smoke, 200, `{"passed": false, "message": "checkout returned 500"}`)
+
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", exec.Output["Error"])
+	assert.Regexp(t, "SmokeTest failed: checkout returned 500", exec.LastOutputJSON)
+
+	assert.Equal(t, []string{
+		"Healthy?",
+		"SmokeTest",
+		"DetachForFailure",
+		"WaitDetachForFailure",
+		"DrainForFailure",
+		"CleanUpFailure",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path()[12:])
+
+	// The DNS was never cut over and the old fleet kept serving
+	assert.Equal(t, 0, len(awsc.Route53.ChangeResourceRecordSetsInputs))
+	for _, input := range awsc.ASG.DetachLoadBalancersInputs {
+		assert.NotEqual(t, "project-config-web-old-release", *input.AutoScalingGroupName)
+	}
+	for _, input := range awsc.ASG.DeleteAutoScalingGroupInputs {
+		assert.NotEqual(t, "project-config-web-old-release", *input.AutoScalingGroupName)
+	}
+}
+
+///////////////
+// MACHINE FetchDeploy INTERGATION TESTS
+///////////////
+
+func Test_Execution_FetchDeploy_BadInputError(t *testing.T) {
+	// Should end in clean state as nothing has happened yet
+	stateMachine := createTestStateMachine(t, models.MockAwsClients(models.MockRelease(t)))
+
+	exec, err := stateMachine.Execute(struct{}{})
+	output := exec.Output
+
+	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", output["Error"])
+
+	assert.Equal(t, exec.Path(), []string{
+		"Validate",
+		"NotifyFailure",
+		"FailureClean",
+	})
+}
+
+func Test_Execution_FetchDeploy_UnkownKeyInput(t *testing.T) {
+	// Should end in clean state as nothing has happened yet
+	stateMachine := createTestStateMachine(t, models.MockAwsClients(models.MockRelease(t)))
+
+	exec, err := stateMachine.Execute(struct{ Unkown string }{Unkown: "asd"})
+	output := exec.Output
+
+	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", output["Error"])
+	assert.Regexp(t, "unknown field", exec.LastOutputJSON)
+
+	assert.Equal(t, exec.Path(), []string{
+		"Validate",
+		"NotifyFailure",
+		"FailureClean",
+	})
+}
+
+func Test_Execution_FetchDeploy_BadInputError_Unamarshalling(t *testing.T) {
+	// Should end in clean state as nothing has happened yet
+	stateMachine := createTestStateMachine(t, models.MockAwsClients(models.MockRelease(t)))
+
+	exec, err := stateMachine.Execute(struct{ Subnets string }{Subnets: ""})
+	output := exec.Output
+
+	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", output["Error"])
+
+	assert.Equal(t, exec.Path(), []string{
+		"Validate",
+		"NotifyFailure",
+		"FailureClean",
+	})
+}
+
+func Test_Execution_FetchDeploy_RootLockError(t *testing.T) {
+	for _, backend := range models.LOCK_BACKENDS {
+		release := models.MockRelease(t)
+		release.LockBackend = to.Strp(backend)
+
+		// Should retry a few times, then end in clean state as nothing was created
+		awsClients := models.MockAwsClients(release)
+
+		// Force a lock error by making it look like it was already aquired
+		switch backend {
+		case "s3":
+			awsClients.S3.AddGetObject(*release.RootLockPath(), `{"uuid": "already"}`, nil)
+		case "dynamodb":
+			awsClients.DynamoDB.AddLock(*release.RootLockPath(), "already")
+		}
+
+		stateMachine := createTestStateMachine(t, awsClients)
+
+		exec, err := stateMachine.Execute(release)
+		output := exec.Output
+
+		assert.Error(t, err, backend)
+		assert.Equal(t, "FailureClean", output["Error"], backend)
+
+		assert.Equal(t, exec.Path(), []string{
+			"Validate",
+			"ValidateOnly?",
+			"Lock",
+			"NotifyFailure",
+			"FailureClean",
+		}, backend)
+	}
+
+	// A release in another config holds the mutex group lock
+	for _, backend := range models.LOCK_BACKENDS {
+		release := models.MockRelease(t)
+		release.LockBackend = to.Strp(backend)
+		release.MutexGroup = to.Strp("shared-tg")
+
+		awsClients := models.MockAwsClients(release)
+
+		switch backend {
+		case "s3":
+			awsClients.S3.AddGetObject(*release.MutexLockPath(), `{"uuid": "already"}`, nil)
+		case "dynamodb":
+			awsClients.DynamoDB.AddLock(*release.MutexLockPath(), "already")
+		}
+
+		stateMachine := createTestStateMachine(t, awsClients)
+
+		exec, err := stateMachine.Execute(release)
+		output := exec.Output
+
+		assert.Error(t, err, backend)
+		assert.Equal(t, "FailureClean", output["Error"], backend)
+		assert.Regexp(t, "LockExistsError", exec.LastOutputJSON, backend)
+
+		assert.Equal(t, exec.Path(), []string{
+			"Validate",
+			"ValidateOnly?",
+			"Lock",
+			"NotifyFailure",
+			"FailureClean",
+		}, backend)
+
+		// The project config lock is released, the other releases mutex group lock is kept
+		assert.Nil(t, awsClients.S3.GetObjectResp[*release.RootLockPath()], backend)
+		if backend == "dynamodb" {
+			assert.Equal(t, map[string]string{*release.MutexLockPath(): "already"}, awsClients.DynamoDB.Locks)
+		} else {
+			assert.NotNil(t, awsClients.S3.GetObjectResp[*release.MutexLockPath()], backend)
+		}
+	}
+}
+
+func Test_Successful_Execution_Replays_IdempotencyKey(t *testing.T) {
+	release := models.MockRelease(t)
+	release.IdempotencyKey = to.Strp("build-1")
+
+	awsc := models.MockAwsClients(release)
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+	var prior models.DeployResult
+	assert.NoError(t, s3.GetStruct(awsc.S3, release.Bucket, release.IdempotencyResultPath(), &prior))
+
+	// The same deploy is triggered again
+	duplicate := models.MockRelease(t)
+	duplicate.IdempotencyKey = to.Strp("build-1")
+	models.AddReleaseS3Objects(awsc, duplicate)
+
+	stateMachine := createTestStateMachine(t, awsc)
+	exec, err := stateMachine.Execute(duplicate)
+	assert.NoError(t, err)
+	assert.Equal(t, true, exec.Output["success"])
+	assert.Equal(t, true, exec.Output["replayed"])
+
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"ReplaySuccess",
+	}, exec.Path())
+
+	// The prior result is returned and nothing is deployed again
+	result := exec.Output["result"].(map[string]interface{})
+	assert.Equal(t, *prior.ReleaseUUID, result["release_uuid"])
+	assert.Equal(t, 1, len(awsc.ASG.CreateAutoScalingGroupInputs))
+}
+
+func Test_UnsuccessfulDeploy_IdempotencyKey_Running_Duplicate(t *testing.T) {
+	release := models.MockRelease(t)
+	release.IdempotencyKey = to.Strp("build-1")
+
+	// The first deploy with the key is still running and holds the lock
+	awsc := models.MockAwsClients(release)
+	awsc.DynamoDB.AddLock(*release.RootLockPath(), "running")
+
+	stateMachine := createTestStateMachine(t, awsc)
+	exec, err := stateMachine.Execute(release)
+	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", exec.Output["Error"])
+	assert.Regexp(t, "LockExistsError", exec.LastOutputJSON)
+
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path())
+
+	assert.Equal(t, 0, len(awsc.ASG.CreateAutoScalingGroupInputs))
+	assert.Equal(t, map[string]string{*release.RootLockPath(): "running"}, awsc.DynamoDB.Locks)
+}
+
+func Test_Execution_ForceUnlock(t *testing.T) {
+	for _, backend := range models.LOCK_BACKENDS {
+		for _, status := range []string{"FAILED", "RUNNING"} {
+			release := models.MockRelease(t)
+			release.LockBackend = to.Strp(backend)
+			release.ForceUnlock = true
+
+			awsc := models.MockAwsClients(release)
+
+			// The lock is held by another release of this project config
+			awsc.S3.AddGetObject(*release.RootLockPath(), `{"uuid": "already"}`, nil)
+			if backend == "dynamodb" {
+				awsc.DynamoDB.AddLock(*release.RootLockPath(), "already")
+			}
+			awsc.SFN.AddExecution("arn:already", release.ExecutionPrefix()+"already", status, map[string]string{"release_id": "already"})
+
+			stateMachine := createTestStateMachine(t, awsc)
+
+			exec, err := stateMachine.Execute(release)
+
+			if status == "FAILED" {
+				// The execution is dead so the lock is taken over
+				assert.NoError(t, err, backend)
+				assert.Equal(t, true, exec.Output["success"], backend)
+				continue
+			}
+
+			// The execution is still running so the lock is kept
+			assert.Error(t, err, backend)
+			assert.Regexp(t, "held by running execution arn:already", exec.LastOutputJSON, backend)
+			assert.Equal(t, []string{
+				"Validate",
+				"ValidateOnly?",
+				"Lock",
+				"NotifyFailure",
+				"FailureClean",
+			}, exec.Path(), backend)
+
+			assert.NotNil(t, awsc.S3.GetObjectResp[*release.RootLockPath()], backend)
+			if backend == "dynamodb" {
+				assert.Equal(t, "already", awsc.DynamoDB.Locks[*release.RootLockPath()], backend)
+			}
+		}
+	}
+}
+
+func Test_Successful_Execution_Works_With_MutexGroup(t *testing.T) {
+	for _, backend := range models.LOCK_BACKENDS {
+		release := models.MockRelease(t)
+		release.LockBackend = to.Strp(backend)
+		release.MutexGroup = to.Strp("shared-tg")
+
+		awsc := models.MockAwsClients(release)
+		assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+		// Every lock is released by CleanUpSuccess
+		assert.Nil(t, awsc.S3.GetObjectResp[*release.MutexLockPath()], backend)
+		assert.Equal(t, 0, len(awsc.DynamoDB.Locks), backend)
+	}
+}
+
+func Test_UnsuccessfulDeploy_MutexGroup_Released(t *testing.T) {
+	release := models.MockRelease(t)
+	release.MutexGroup = to.Strp("shared-tg")
+
+	awsc := models.MockAwsClients(release)
+	awsc.ELB.DescribeInstanceHealthResp["web-elb"] = &mocks.DescribeInstanceHealthResponse{}
+
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", exec.Output["Error"])
+
+	// ReleaseLockFailure releases the mutex group lock before FailureClean
+	assert.Equal(t, 2, len(awsc.DynamoDB.PutItemInputs))
+	assert.Equal(t, 0, len(awsc.DynamoDB.Locks))
+}
+
+func Test_UnsuccessfulDeploy_ProjectConcurrency_Reached(t *testing.T) {
+	for _, backend := range models.LOCK_BACKENDS {
+		release := models.MockRelease(t)
+		release.LockBackend = to.Strp(backend)
+		release.ProjectConcurrency = to.Intp(2)
+
+		awsc := models.MockAwsClients(release)
+
+		// Two other configs of the project are deploying
+		for slot, uuid := range []string{"other-1", "other-2"} {
+			path := *release.ProjectSlotLockPath(slot)
+			switch backend {
+			case "s3":
+				awsc.S3.AddGetObject(path, `{"uuid": "`+uuid+`"}`, nil)
+			case "dynamodb":
+				awsc.DynamoDB.AddLock(path, uuid)
+			}
+		}
+
+		stateMachine := createTestStateMachine(t, awsc)
+
+		exec, err := stateMachine.Execute(release)
+
+		assert.Error(t, err, backend)
+		assert.Equal(t, "FailureClean", exec.Output["Error"], backend)
+		assert.Regexp(t, "LockExistsError", exec.LastOutputJSON, backend)
+		assert.Regexp(t, "retry when one finishes", exec.LastOutputJSON, backend)
+
+		assert.Equal(t, []string{
+			"Validate",
+			"ValidateOnly?",
+			"Lock",
+			"NotifyFailure",
+			"FailureClean",
+		}, exec.Path(), backend)
+
+		// The project config lock is released, the other deploys keep their slots
+		assert.Nil(t, awsc.S3.GetObjectResp[*release.RootLockPath()], backend)
+		if backend == "dynamodb" {
+			assert.Equal(t, map[string]string{
+				*release.ProjectSlotLockPath(0): "other-1",
+				*release.ProjectSlotLockPath(1): "other-2",
+			}, awsc.DynamoDB.Locks)
+		}
+	}
+}
+
+func Test_Successful_Execution_Works_With_ProjectConcurrency(t *testing.T) {
+	for _, backend := range models.LOCK_BACKENDS {
+		release := models.MockRelease(t)
+		release.LockBackend = to.Strp(backend)
+		release.ProjectConcurrency = to.Intp(2)
+
+		// One other config of the project is deploying
+		awsc := models.MockAwsClients(release)
+		awsc.DynamoDB.AddLock(*release.ProjectSlotLockPath(0), "other")
+		awsc.S3.AddGetObject(*release.ProjectSlotLockPath(0), `{"uuid": "other"}`, nil)
+
+		assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+		// CleanUpSuccess releases the slot this release took
+		assert.Nil(t, awsc.S3.GetObjectResp[*release.ProjectSlotLockPath(1)], backend)
+		assert.Equal(t, map[string]string{*release.ProjectSlotLockPath(0): "other"}, awsc.DynamoDB.Locks, backend)
+	}
+}
+
+func Test_UnsuccessfulDeploy_ProjectConcurrency_Released(t *testing.T) {
+	release := models.MockRelease(t)
+	release.ProjectConcurrency = to.Intp(2)
+
+	awsc := models.MockAwsClients(release)
+	awsc.ELB.DescribeInstanceHealthResp["web-elb"] = &mocks.DescribeInstanceHealthResponse{}
+
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", exec.Output["Error"])
+
+	// ReleaseLockFailure releases the slot before FailureClean
+	assert.Equal(t, 2, len(awsc.DynamoDB.PutItemInputs))
+	assert.Equal(t, 0, len(awsc.DynamoDB.Locks))
+}
+
+func Test_Successful_Execution_Works_With_S3LockBackend(t *testing.T) {
+	release := models.MockRelease(t)
+	release.LockBackend = to.Strp("s3")
+
+	// The DynamoDB lock is not used
+	awsc := models.MockAwsClients(release)
+	awsc.DynamoDB.AddLock(*release.RootLockPath(), "already")
+
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+	assert.Equal(t, 0, len(awsc.DynamoDB.PutItemInputs))
+}
+
+func Test_Execution_FetchDeploy_ReleaseLockError(t *testing.T) {
+	release := models.MockRelease(t)
+
+	// Should retry a few times, then end in clean state as nothing was created
+	awsClients := models.MockAwsClients(release)
+
+	// Force a lock error by making it look like it was already aquired
+	awsClients.S3.AddGetObject(*release.ReleaseLockPath(), `{"uuid": "already"}`, nil)
+
+	stateMachine := createTestStateMachine(t, awsClients)
+
+	exec, err := stateMachine.Execute(release)
+	output := exec.Output
+
+	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", output["Error"])
+
+	assert.Equal(t, exec.Path(), []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"NotifyFailure",
+		"FailureClean",
+	})
+}
+
+func Test_Execution_CheckHealthy_HaltError_WithTermination(t *testing.T) {
+	// Should end in Alert Bad Thing Happened State
+	release := models.MockRelease(t)
+	maws := models.MockAwsClients(release)
+	maws.ASG.DescribeAutoScalingGroupsPageResp = nil
+
+	termingASG := mocks.MakeMockASG("odin", *release.ProjectName, *release.ConfigName, "web", "Old release")
+	termingASG.Instances[0].LifecycleState = to.Strp("Terminating")
+
+	maws.ASG.AddASG(termingASG)
+
+	stateMachine := createTestStateMachine(t, maws)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Regexp(t, "HaltError", exec.LastOutputJSON)
+	assert.Regexp(t, "success\": false", exec.LastOutputJSON)
+
+	assert.Equal(t, exec.Path(), []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"Validated?",
+		"PreDeployHook",
+		"Deploy",
+		"InstanceRefresh?",
+		"WaitForDeploy",
+		"WaitForHealthy",
+		"Canaried?",
+		"CheckHealthy",
+		"DetachForFailure",
+		"WaitDetachForFailure",
+		"DrainForFailure",
+		"CleanUpFailure",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	})
+}
+
+func Test_Successful_Execution_Waits_For_ELB_And_TargetGroup(t *testing.T) {
+	// The service is attached to both a classic ELB and a target group which go healthy at different times
+	release := models.MockRelease(t)
+
+	awsc := models.MockAwsClients(release)
+	awsc.ELB.OutOfServiceUntil = map[string]int{"web-elb": 2}
+	awsc.ALB.UnhealthyUntil = map[string]int{"web-elb-target": 4}
+
+	old := awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0]
+	old.LoadBalancerNames = []*string{to.Strp("web-elb")}
+	old.TargetGroupARNs = []*string{to.Strp("web-elb-target")}
+
+	previousRelease := models.MockRelease(t)
+	previousRelease.ReleaseID = to.Strp("old-release")
+	models.AddReleaseS3Objects(awsc, previousRelease)
+
+	stateMachine := createTestStateMachine(t, awsc)
+	exec, err := stateMachine.Execute(release)
+
+	assert.NoError(t, err)
+	assert.Equal(t, true, exec.Output["success"])
+
+	// Healthy only once the slower target group is healthy
+	checks := 0
+	for _, state := range exec.Path() {
+		if state == "CheckHealthy" {
+			checks++
+		}
+	}
+	assert.Equal(t, 5, checks)
+
+	// The new ASG is attached to both
+	created := awsc.ASG.CreateAutoScalingGroupInputs[0]
+	assert.Equal(t, []string{"web-elb"}, to.StrSlice(created.LoadBalancerNames))
+	assert.Equal(t, []string{"web-elb-target"}, to.StrSlice(created.TargetGroupARNs))
+
+	// The old ASG is detached from both
+	assert.Equal(t, 1, len(awsc.ASG.DetachLoadBalancersInputs))
+	assert.Equal(t, []string{"web-elb"}, to.StrSlice(awsc.ASG.DetachLoadBalancersInputs[0].LoadBalancerNames))
+	assert.Equal(t, 1, len(awsc.ASG.DetachLoadBalancerTargetGroupsInputs))
+	assert.Equal(t, []string{"web-elb-target"}, to.StrSlice(awsc.ASG.DetachLoadBalancerTargetGroupsInputs[0].TargetGroupARNs))
+}
+
+func Test_Execution_CheckHealthy_Never_Healthy_ELB(t *testing.T) {
+	// Should end in Alert Bad Thing Happened State
+	release := models.MockRelease(t)
+
+	maws := models.MockAwsClients(release)
+	maws.ELB.DescribeInstanceHealthResp["web-elb"] = &mocks.DescribeInstanceHealthResponse{}
+
+	stateMachine := createTestStateMachine(t, maws)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+
+	ep := exec.Path()
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"Validated?",
+		"PreDeployHook",
+		"Deploy",
+		"InstanceRefresh?",
+		"WaitForDeploy",
+		"WaitForHealthy",
+		"Canaried?",
+		"CheckHealthy"}, ep[0:12])
+
+	assert.Equal(t, []string{
+		"DetachForFailure",
+		"WaitDetachForFailure",
+		"DrainForFailure",
+		"CleanUpFailure",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	}, ep[len(ep)-7:len(ep)])
+
+	assert.Regexp(t, "Timeout", exec.LastOutputJSON)
+
+	// The instances launched but were never healthy
+	assert.Regexp(t, "Timeout.*: InstancesUnhealthy \\(web 0/1 healthy 1/1 launched\\)", exec.LastOutputJSON)
+	assert.Regexp(t, "success\": false", exec.LastOutputJSON)
+}
+
+func Test_Execution_CheckHealthy_Never_Healthy_Notifications(t *testing.T) {
+	release := models.MockRelease(t)
+	release.NotificationTopicARN = to.Strp("arn:aws:sns:us-east-1:000000:deploys")
+
+	maws := models.MockAwsClients(release)
+	maws.ELB.DescribeInstanceHealthResp["web-elb"] = &mocks.DescribeInstanceHealthResponse{}
+
+	stateMachine := createTestStateMachine(t, maws)
+
+	_, err := stateMachine.Execute(release)
+	assert.Error(t, err)
+
+	notifications := assertNotifications(t, maws, "arn:aws:sns:us-east-1:000000:deploys")
+	assert.Equal(t, 2, len(notifications))
+
+	assert.Equal(t, models.NotifyStarted, notifications[0].Event)
+
+	assert.Equal(t, models.NotifyFailed, notifications[1].Event)
+	assert.Equal(t, []string{
+		"Validate",
+		"Lock",
+		"ValidateResources",
+		"PreDeployHook",
+		"Deploy",
+		"CheckHealthy",
+		"DetachForFailure",
+		"CleanUpFailure",
+		"ReleaseLockFailure",
+		"FailureClean",
+	}, notifications[1].Path)
+	assert.Regexp(t, "Timeout", *notifications[1].Error.Cause)
+	assert.Equal(t, "CheckHealthy", *notifications[1].FailedState)
+}
+
+func Test_Execution_Validate_Failure_Alerts(t *testing.T) {
+	// A release that fails before anything is deployed still alerts
+	release := models.MockRelease(t)
+	release.SchemaVersion = to.Intp(models.MinSchemaVersion - 1)
+	release.NotificationTopicARN = to.Strp("arn:aws:sns:us-east-1:000000:deploys")
+	release.AlertFormat = to.Strp("slack")
+	release.AlertWebhookURL = to.Strp("ssm:/odin/slack-webhook")
+
+	awsc := models.MockAwsClients(release)
+	awsc.SSM.AddParameter("/odin/slack-webhook", "https://hooks.example.com/odin")
+	awsc.HTTP.SetStatus("https://hooks.example.com/odin", 200)
+
+	stateMachine := createTestStateMachine(t, awsc)
+	exec, err := stateMachine.Execute(release)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"Validate", "NotifyFailure", "FailureClean"}, exec.Path())
+
+	// The topic and the webhook are both sent the Slack message
+	messages := awsc.SNS.Messages()
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, []string{messages[0]}, awsc.HTTP.Bodies["https://hooks.example.com/odin"])
+
+	var slack map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(messages[0]), &slack))
+	assert.Regexp(t, `^\*Deploy failed: project/config release 1\*\n`, slack["text"])
+	assert.Contains(t, slack["text"], "State: Validate\n")
+	assert.Contains(t, slack["text"], "Error: BadReleaseError: ")
+	assert.Contains(t, slack["text"], "no longer supported")
+}
+
+func Test_Execution_CheckHealthy_Never_Healthy_Alerts(t *testing.T) {
+	release := models.MockRelease(t)
+	release.NotificationTopicARN = to.Strp("arn:aws:sns:us-east-1:000000:deploys")
+	release.AlertFormat = to.Strp("text")
+	release.AlertWebhookURL = to.Strp("https://hooks.example.com/odin")
+
+	awsc := models.MockAwsClients(release)
+	awsc.ELB.DescribeInstanceHealthResp["web-elb"] = &mocks.DescribeInstanceHealthResponse{}
+	awsc.HTTP.SetStatus("https://hooks.example.com/odin", 200)
+
+	stateMachine := createTestStateMachine(t, awsc)
+	_, err := stateMachine.Execute(release)
+	assert.Error(t, err)
+
+	// Started then failed, only the failure is posted to the webhook
+	messages := awsc.SNS.Messages()
+	assert.Equal(t, 2, len(messages))
+	assert.Equal(t, "Deploy started: project/config release 1\nPath: Validate -> Lock", messages[0])
+
+	summary := messages[1]
+	assert.Regexp(t, "^Deploy failed: project/config release 1\n", summary)
+	assert.Contains(t, summary, "State: CheckHealthy\n")
+	assert.Contains(t, summary, "Error: TimeoutError: ")
+	assert.Contains(t, summary, "Path: Validate -> Lock -> ValidateResources -> PreDeployHook -> Deploy -> CheckHealthy -> DetachForFailure")
+
+	bodies := awsc.HTTP.Bodies["https://hooks.example.com/odin"]
+	assert.Equal(t, 1, len(bodies))
+	assert.Contains(t, bodies[0], "State: CheckHealthy")
+}
+
+func Test_Execution_CleanUpFailure_Fails_Alerts_Dirty(t *testing.T) {
+	release := models.MockRelease(t)
+	release.NotificationTopicARN = to.Strp("arn:aws:sns:us-east-1:000000:deploys")
+	release.AlertFormat = to.Strp("text")
+
+	awsc := models.MockAwsClients(release)
+	awsc.ELB.DescribeInstanceHealthResp["web-elb"] = &mocks.DescribeInstanceHealthResponse{}
+	awsc.ASG.TrackCreated = true
+	awsc.ASG.AddThrottles("DeleteAutoScalingGroup", 100)
+
+	stateMachine := createTestStateMachine(t, awsc)
+	exec, err := stateMachine.Execute(release)
+	assert.Error(t, err)
+
+	ep := exec.Path()
+	assert.Equal(t, []string{"CleanUpFailure", "NotifyFailureDirty", "FailureDirty"}, ep[len(ep)-3:])
+
+	messages := awsc.SNS.Messages()
+	assert.Equal(t, 2, len(messages))
+
+	summary := messages[1]
+	assert.Regexp(t, "^Deploy failed: project/config release 1\n", summary)
+	assert.Contains(t, summary, "State: CheckHealthy\n")
+	assert.Contains(t, summary, "CleanUpError: ")
+	assert.Contains(t, summary, "-> DetachForFailure -> FailureDirty\n")
+	assert.Contains(t, summary, "Resources were left behind")
+}
+
+func Test_Execution_CheckHealthy_HealthAlarm_Flips_To_Alarm(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Services["web"].HealthAlarms = []*string{to.Strp("web-5xx")}
+
+	maws := models.MockAwsClients(release)
+	// ValidateResources describes the alarm once before CheckHealthy
+	maws.CW.AddAlarmStates("web-5xx", "INSUFFICIENT_DATA", "INSUFFICIENT_DATA", "ALARM")
+
+	stateMachine := createTestStateMachine(t, maws)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Regexp(t, "Health alarms in ALARM state", exec.LastOutputJSON)
+	assert.Regexp(t, "success\": false", exec.LastOutputJSON)
+
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"Validated?",
+		"PreDeployHook",
+		"Deploy",
+		"InstanceRefresh?",
+		"WaitForDeploy",
+		"WaitForHealthy",
+		"Canaried?",
+		"CheckHealthy",
+		"Healthy?",
+		"WaitForHealthy",
+		"Canaried?",
+		"CheckHealthy",
+		"DetachForFailure",
+		"WaitDetachForFailure",
+		"DrainForFailure",
+		"CleanUpFailure",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path())
+}
+
+func Test_Execution_CheckHealthy_Never_Healthy_TG(t *testing.T) {
+	// Should end in Alert Bad Thing Happened State
+	release := models.MockRelease(t)
+
+	maws := models.MockAwsClients(release)
+	maws.ALB.DescribeTargetHealthResp["web-elb-target"] = &mocks.DescribeTargetHealthResponse{}
+
+	stateMachine := createTestStateMachine(t, maws)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+
+	ep := exec.Path()
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"Validated?",
+		"PreDeployHook",
+		"Deploy",
+		"InstanceRefresh?",
+		"WaitForDeploy",
+		"WaitForHealthy",
+		"Canaried?",
+		"CheckHealthy"}, ep[0:12])
+
+	assert.Equal(t, []string{
+		"CleanUpFailure",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	}, ep[len(ep)-4:len(ep)])
+
+	assert.Regexp(t, "Timeout", exec.LastOutputJSON)
+
+	// The instances launched but were never healthy
+	assert.Regexp(t, "Timeout.*: InstancesUnhealthy \\(web 0/1 healthy 1/1 launched\\)", exec.LastOutputJSON)
+	assert.Regexp(t, "success\": false", exec.LastOutputJSON)
+}
+
+func Test_Execution_CleanupSuccess_DetachError(t *testing.T) {
+	// Should try 10 times to detach
+	release := models.MockRelease(t)
+
+	maws := models.MockAwsClients(release)
+	maws.ASG.DescribeLoadBalancerTargetGroupsOutput = &autoscaling.DescribeLoadBalancerTargetGroupsOutput{
+		LoadBalancerTargetGroups: []*autoscaling.LoadBalancerTargetGroupState{
+			&autoscaling.LoadBalancerTargetGroupState{
+				LoadBalancerTargetGroupARN: to.Strp("arn"),
+				State:                      to.Strp("aaa"),
+			},
+		},
+	}
+
+	stateMachine := createTestStateMachine(t, maws)
+
+	exec, err := stateMachine.Execute(release)
+
+	// We force delete rather than suffer the consequences
+	assert.NoError(t, err)
+
+	ep := exec.Path()
+	steps := []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"Validated?",
+		"PreDeployHook",
+		"Deploy",
+		"InstanceRefresh?",
+		"WaitForDeploy",
+		"WaitForHealthy",
+		"Canaried?",
+		"CheckHealthy",
+		"Healthy?",
+		"SmokeTest",
+		"CutoverDNS",
+		"Soak",
+		"Soaked?",
+		"WaitForDetach",
+	}
+
+	// Check detach 60 times (for 10 minutes)
+	for i := 0; i <= 60; i++ {
+		steps = append(steps, "DetachForSuccess")
+	}
+
+	steps = append(steps, "WaitDetachForSuccess", "DrainForSuccess", "CleanUpSuccess", "Success")
+
+	assert.Equal(t, steps, ep)
+
+	assert.Regexp(t, "DetachError", exec.LastOutputJSON)
+}
+
+func Test_Execution_CleanupSuccess_Detach_SkipCheck(t *testing.T) {
+	// Should try 10 times to detach
+	release := models.MockRelease(t)
+	release.DetachStrategy = to.Strp("SkipDetachCheck")
+
+	maws := models.MockAwsClients(release)
+	maws.ASG.DescribeLoadBalancerTargetGroupsOutput = &autoscaling.DescribeLoadBalancerTargetGroupsOutput{
+		LoadBalancerTargetGroups: []*autoscaling.LoadBalancerTargetGroupState{
+			&autoscaling.LoadBalancerTargetGroupState{
+				LoadBalancerTargetGroupARN: to.Strp("arn"),
+				State:                      to.Strp("aaa"),
+			},
+		},
+	}
+
+	assertSuccessfulExecutionWithAWS(t, release, maws)
+}
+
+func Test_Execution_CleanupSuccess_Detach_SkipDetach(t *testing.T) {
+	// Should try 10 times to detach
+	release := models.MockRelease(t)
+	release.DetachStrategy = to.Strp("SkipDetach")
+
+	maws := models.MockAwsClients(release)
+	maws.ASG.DetachLoadBalancersError = fmt.Errorf("never reached")
+
+	assertSuccessfulExecutionWithAWS(t, release, maws)
+}
+
+func Test_Successful_Execution_Works_With_InstanceRefresh(t *testing.T) {
+	release := models.MockRelease(t)
+	release.DeployStrategy = to.Strp(models.DeployInstanceRefresh)
+
+	maws := models.MockAwsClients(release)
+	maws.ASG.InstanceRefreshStatuses = map[string][]string{
+		"project-config-web-old-release": []string{"InProgress", "Successful"},
+	}
+
+	stateMachine := createTestStateMachine(t, maws)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.NoError(t, err)
+	assert.Equal(t, true, exec.Output["success"])
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"Validated?",
+		"PreDeployHook",
+		"Deploy",
+		"InstanceRefresh?",
+		"WaitForRefresh",
+		"CheckRefresh",
+		"Refreshed?",
+		"WaitForRefresh",
+		"CheckRefresh",
+		"Refreshed?",
+		"CleanUpSuccess",
+		"Success",
+	}, exec.Path())
+
+	// The live ASG is refreshed rather than replaced
+	assert.Equal(t, 0, len(maws.ASG.CreateAutoScalingGroupInputs))
+	assert.Equal(t, 0, len(maws.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, 1, len(maws.ASG.StartInstanceRefreshInputs))
+}
+
+func Test_UnsuccessfulDeploy_InstanceRefresh_Failed(t *testing.T) {
+	release := models.MockRelease(t)
+	release.DeployStrategy = to.Strp(models.DeployInstanceRefresh)
+
+	maws := models.MockAwsClients(release)
+	maws.ASG.InstanceRefreshStatuses = map[string][]string{
+		"project-config-web-old-release": []string{"InProgress", "Failed"},
+	}
+
+	stateMachine := createTestStateMachine(t, maws)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Regexp(t, "Failed", exec.LastOutputJSON)
+	assert.Regexp(t, "success\": false", exec.LastOutputJSON)
+	assert.Equal(t, []string{
+		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"Validated?",
+		"PreDeployHook",
+		"Deploy",
+		"InstanceRefresh?",
+		"WaitForRefresh",
+		"CheckRefresh",
+		"Refreshed?",
+		"WaitForRefresh",
+		"CheckRefresh",
+		"CancelRefresh",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path())
+
+	// The live ASG is moved back to its launch configuration and never deleted
+	assert.Equal(t, 1, len(maws.ASG.CancelInstanceRefreshInputs))
+	assert.Equal(t, "project-config-web-old-release", *maws.ASG.UpdateAutoScalingGroupLastInput.LaunchConfigurationName)
+	assert.Equal(t, 0, len(maws.ASG.DeleteAutoScalingGroupInputs))
+}
+
+func Test_Execution_Metrics(t *testing.T) {
+	labels := func(key string, value string) map[string]string {
+		return map[string]string{"project": "project", "config": "config", key: value}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		release := models.MockRelease(t)
+		m := metrics.NewMemory()
+
+		_, err := createTestStateMachineWithMetrics(t, models.MockAwsClients(release), m).Execute(release)
+		assert.NoError(t, err)
+
+		assert.Equal(t, float64(1), m.Counter(metrics.DeploysTotal, labels("outcome", metrics.OutcomeSuccess)))
+		assert.Equal(t, float64(0), m.Counter(metrics.DeploysTotal, labels("outcome", metrics.OutcomeCleanFailure)))
+		assert.Equal(t, 1, len(m.Observations(metrics.DeployDuration, labels("outcome", metrics.OutcomeSuccess))))
+		assert.Equal(t, 1, len(m.Observations(metrics.DeployPhaseDuration, labels("phase", metrics.PhaseDeploy))))
+		assert.Equal(t, 1, len(m.Observations(metrics.DeployPhaseDuration, labels("phase", metrics.PhaseWaitForHealthy))))
+	})
+
+	t.Run("clean failure", func(t *testing.T) {
+		release := models.MockRelease(t)
+		release.PreDeployHook = to.Strp("arn:aws:lambda:us-east-1:000000:function:gate")
+		m := metrics.NewMemory()
+
+		awsc := models.MockAwsClients(release)
+		awsc.Lambda.AddDeny(*release.PreDeployHook, "change freeze")
+
+		_, err := createTestStateMachineWithMetrics(t, awsc, m).Execute(release)
+		assert.Error(t, err)
+
+		assert.Equal(t, float64(1), m.Counter(metrics.DeploysTotal, labels("outcome", metrics.OutcomeCleanFailure)))
+		assert.Equal(t, float64(0), m.Counter(metrics.DeploysTotal, labels("outcome", metrics.OutcomeSuccess)))
+		assert.Equal(t, 1, len(m.Observations(metrics.DeployDuration, labels("outcome", metrics.OutcomeCleanFailure))))
+		assert.Equal(t, 0, len(m.Observations(metrics.DeployPhaseDuration, labels("phase", metrics.PhaseDeploy))))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		release := models.MockRelease(t)
+		m := metrics.NewMemory()
+
+		awsc := models.MockAwsClients(release)
+		awsc.ELB.DescribeInstanceHealthResp["web-elb"] = &mocks.DescribeInstanceHealthResponse{}
+
+		exec, err := createTestStateMachineWithMetrics(t, awsc, m).Execute(release)
+		assert.Error(t, err)
+		assert.Regexp(t, "Timeout", exec.LastOutputJSON)
+
+		assert.Equal(t, float64(1), m.Counter(metrics.DeploysTotal, labels("outcome", metrics.OutcomeTimeout)))
+		assert.Equal(t, float64(0), m.Counter(metrics.DeploysTotal, labels("outcome", metrics.OutcomeCleanFailure)))
+		assert.Equal(t, 1, len(m.Observations(metrics.DeployPhaseDuration, labels("phase", metrics.PhaseDeploy))))
+		assert.Equal(t, 0, len(m.Observations(metrics.DeployPhaseDuration, labels("phase", metrics.PhaseWaitForHealthy))))
+	})
+
+	t.Run("halt mentioning timeout", func(t *testing.T) {
+		release := models.MockRelease(t)
+		release.PreDeployHook = to.Strp("arn:aws:lambda:us-east-1:000000:function:gate")
+		m := metrics.NewMemory()
+
+		awsc := models.MockAwsClients(release)
+		awsc.Lambda.AddDeny(*release.PreDeployHook, "Timeout waiting for approval")
+
+		_, err := createTestStateMachineWithMetrics(t, awsc, m).Execute(release)
+		assert.Error(t, err)
+
+		// Only a TimeoutError is a timeout
+		assert.Equal(t, float64(1), m.Counter(metrics.DeploysTotal, labels("outcome", metrics.OutcomeCleanFailure)))
+		assert.Equal(t, float64(0), m.Counter(metrics.DeploysTotal, labels("outcome", metrics.OutcomeTimeout)))
+	})
+
+	t.Run("dirty failure", func(t *testing.T) {
+		release := models.MockRelease(t)
+		m := metrics.NewMemory()
+
+		awsc := models.MockAwsClients(release)
+		awsc.ELB.DescribeInstanceHealthResp["web-elb"] = &mocks.DescribeInstanceHealthResponse{}
+		awsc.ASG.TrackCreated = true
+		awsc.ASG.AddThrottles("DeleteAutoScalingGroup", 100)
+
+		exec, err := createTestStateMachineWithMetrics(t, awsc, m).Execute(release)
+		assert.Error(t, err)
+		assert.Equal(t, "FailureDirty", exec.Path()[len(exec.Path())-1])
+
+		assert.Equal(t, float64(1), m.Counter(metrics.DeploysTotal, labels("outcome", metrics.OutcomeDirtyFailure)))
+		assert.Equal(t, float64(0), m.Counter(metrics.DeploysTotal, labels("outcome", metrics.OutcomeTimeout)))
+		assert.Equal(t, 1, len(m.Observations(metrics.DeployDuration, labels("outcome", metrics.OutcomeDirtyFailure))))
+	})
+
+	t.Run("cloudwatch", func(t *testing.T) {
+		release := models.MockRelease(t)
+		awsc := models.MockAwsClients(release)
+		cwc := &mocks.CWClient{}
+
+		m := metrics.NewCloudWatch(cwc, metrics.CloudWatchConfig{
+			Namespace:   "Acme/Deploys",
+			Dimensions:  []string{metrics.LabelProject, metrics.LabelConfig, metrics.LabelOutcome, metrics.LabelReleaseUUID},
+			ReleaseUUID: true,
+		})
+
+		exec, err := createTestStateMachineWithMetrics(t, awsc, m).Execute(release)
+		assert.NoError(t, err)
+		uuid := exec.Output["uuid"].(string)
+
+		var deploys *cloudwatch.MetricDatum
+		for _, input := range cwc.PutMetricDataInputs {
+			assert.Equal(t, "Acme/Deploys", *input.Namespace)
+			if *input.MetricData[0].MetricName == metrics.DeploysTotal {
+				deploys = input.MetricData[0]
+			}
+		}
+
+		dims := map[string]string{}
+		for _, d := range deploys.Dimensions {
+			dims[*d.Name] = *d.Value
+		}
+		assert.Equal(t, map[string]string{"project": "project", "config": "config", "outcome": metrics.OutcomeSuccess, "release_uuid": uuid}, dims)
+	})
+}
+
+func Test_Successful_Execution_Works_With_DeployRoleARN(t *testing.T) {
+	release := models.MockRelease(t)
+	release.AwsAccountID = to.Strp("123456789012")
+	release.DeployRoleARN = to.Strp("arn:aws:iam::123456789012:role/odin/deployer")
+
+	awsc := models.MockAwsClients(release)
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
+
+	// Every client in the release account is the deploy role
+	assert.Equal(t, map[string]bool{"123456789012/odin/deployer": true}, awsc.AssumedRoles)
+	assert.Equal(t, 1, len(awsc.STS.GetCallerIdentityInputs))
+}
+
+func Test_UnsuccessfulDeploy_DeployRoleARN_Cannot_Be_Assumed(t *testing.T) {
+	release := models.MockRelease(t)
+	release.AwsAccountID = to.Strp("123456789012")
+	release.DeployRoleARN = to.Strp("arn:aws:iam::123456789012:role/odin/deployer")
+
+	awsc := models.MockAwsClients(release)
+	awsc.STS.DenyRole("odin/deployer")
+
+	stateMachine := createTestStateMachine(t, awsc)
+
+	exec, err := stateMachine.Execute(release)
+
+	assert.Error(t, err)
+	assert.Regexp(t, "BadReleaseError", exec.LastOutputJSON)
+	assert.Regexp(t, "cannot be assumed", exec.LastOutputJSON)
+	assert.Equal(t, []string{"Validate", "NotifyFailure", "FailureClean"}, exec.Path())
+
+	// Nothing is touched in the release account
+	assert.Equal(t, 0, len(awsc.ASG.CreateAutoScalingGroupInputs))
+}
+
+func Test_Successful_Execution_Works_With_ReadinessCheck(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Services["web"].ReadinessCheck = &models.ReadinessCheck{Port: to.Int64p(8080)}
+
+	awsc := models.MockAwsClients(release)
+	awsc.EC2.SetPrivateIP("InstanceId1", "10.0.0.1")
+	awsc.HTTP.SetStatus("http://10.0.0.1:8080/ready", 200)
 
-	assert.Equal(t, exec.Path(), []string{
-		"Validate",
-		"Lock",
-		"ValidateResources",
-		"ReleaseLockFailure",
-		"FailureClean",
-	})
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
 }
 
-func Test_UnsuccessfulDeploy_Bad_Userdata_SHA(t *testing.T) {
-	// Should end in Alert Bad Thing Happened State
-	release := models.MockMinimalRelease(t)
+func Test_UnsuccessfulDeploy_ReadinessCheck_Never_Ready(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Services["web"].ReadinessCheck = &models.ReadinessCheck{Port: to.Int64p(8080)}
 
-	// Should end in Alert Bad Thing Happened State
-	stateMachine := createTestStateMachine(t, models.MockAwsClients(release))
-	release.UserDataSHA256 = to.Strp("asfhjoias")
+	awsc := models.MockAwsClients(release)
+	awsc.EC2.SetPrivateIP("InstanceId1", "10.0.0.1")
+	awsc.HTTP.SetStatus("http://10.0.0.1:8080/ready", 503)
 
-	exec, err := stateMachine.Execute(release)
-	output := exec.Output
+	stateMachine := createTestStateMachine(t, awsc)
 
+	exec, err := stateMachine.Execute(release)
 	assert.Error(t, err)
-	assert.Equal(t, "FailureClean", output["Error"])
 
+	ep := exec.Path()
 	assert.Equal(t, []string{
-		"Validate",
+		"DetachForFailure",
+		"WaitDetachForFailure",
+		"DrainForFailure",
+		"CleanUpFailure",
+		"ReleaseLockFailure",
+		"NotifyFailure",
 		"FailureClean",
-	}, exec.Path())
+	}, ep[len(ep)-7:])
+
+	assert.Regexp(t, "Timeout", exec.LastOutputJSON)
+	assert.NotEqual(t, 0, len(awsc.HTTP.Requests))
 }
 
-func Test_UnsuccessfulDeploy_Execution_Works(t *testing.T) {
+func Test_UnsuccessfulDeploy_Image_Architecture_Mismatch(t *testing.T) {
 	release := models.MockRelease(t)
-	release.Timeout = to.Intp(-10) // This will cause immediate timeout
 
-	// Should end in Alert Bad Thing Happened State
-	stateMachine := createTestStateMachine(t, models.MockAwsClients(release))
+	awsc := models.MockAwsClients(release)
+	awsc.EC2.SetImageArchitecture("ami-123456", "arm64", "hvm")
+
+	stateMachine := createTestStateMachine(t, awsc)
 
 	exec, err := stateMachine.Execute(release)
-	output := exec.Output
 
 	assert.Error(t, err)
-	assert.Equal(t, "FailureClean", output["Error"])
-
+	assert.Regexp(t, "BadReleaseError", exec.LastOutputJSON)
+	assert.Regexp(t, "architecture arm64 is not supported", exec.LastOutputJSON)
 	assert.Equal(t, []string{
 		"Validate",
+		"ValidateOnly?",
 		"Lock",
 		"ValidateResources",
-		"Deploy",
+		"LockHeld?",
 		"ReleaseLockFailure",
+		"NotifyFailure",
 		"FailureClean",
 	}, exec.Path())
-}
-
-///////////////
-// MACHINE FetchDeploy INTERGATION TESTS
-///////////////
-
-func Test_Execution_FetchDeploy_BadInputError(t *testing.T) {
-	// Should end in clean state as nothing has happened yet
-	stateMachine := createTestStateMachine(t, models.MockAwsClients(models.MockRelease(t)))
 
-	exec, err := stateMachine.Execute(struct{}{})
-	output := exec.Output
+	// Nothing was deployed
+	assert.Equal(t, 0, len(awsc.ASG.CreateAutoScalingGroupInputs))
+}
 
-	assert.Error(t, err)
-	assert.Equal(t, "FailureClean", output["Error"])
+func Test_UnsuccessfulDeploy_InstanceType_Not_Offered(t *testing.T) {
+	release := models.MockRelease(t)
 
-	assert.Equal(t, exec.Path(), []string{
-		"Validate",
-		"FailureClean",
-	})
-}
+	awsc := models.MockAwsClients(release)
+	awsc.EC2.InstanceTypeOfferings = map[string][]string{"t2.small": []string{"us-east-1b"}}
 
-func Test_Execution_FetchDeploy_UnkownKeyInput(t *testing.T) {
-	// Should end in clean state as nothing has happened yet
-	stateMachine := createTestStateMachine(t, models.MockAwsClients(models.MockRelease(t)))
+	stateMachine := createTestStateMachine(t, awsc)
 
-	exec, err := stateMachine.Execute(struct{ Unkown string }{Unkown: "asd"})
-	output := exec.Output
+	exec, err := stateMachine.Execute(release)
 
 	assert.Error(t, err)
-	assert.Equal(t, "FailureClean", output["Error"])
-	assert.Regexp(t, "unknown field", exec.LastOutputJSON)
-
-	assert.Equal(t, exec.Path(), []string{
+	assert.Regexp(t, "BadReleaseError", exec.LastOutputJSON)
+	assert.Regexp(t, "InstanceType not offered in t2.small/us-east-1a", exec.LastOutputJSON)
+	assert.Equal(t, []string{
 		"Validate",
+		"ValidateOnly?",
+		"Lock",
+		"ValidateResources",
+		"LockHeld?",
+		"ReleaseLockFailure",
+		"NotifyFailure",
 		"FailureClean",
-	})
+	}, exec.Path())
+
+	// Nothing was deployed
+	assert.Equal(t, 0, len(awsc.ASG.CreateAutoScalingGroupInputs))
 }
 
-func Test_Execution_FetchDeploy_BadInputError_Unamarshalling(t *testing.T) {
-	// Should end in clean state as nothing has happened yet
-	stateMachine := createTestStateMachine(t, models.MockAwsClients(models.MockRelease(t)))
+func Test_Successful_Execution_Works_With_Host_Tenancy(t *testing.T) {
+	release := models.MockRelease(t)
+	release.Services["web"].PlacementTenancy = to.Strp("host")
+	release.Services["web"].HostResourceGroupArn = to.Strp("arn:aws:resource-groups:us-east-1:000000:group/hosts")
 
-	exec, err := stateMachine.Execute(struct{ Subnets string }{Subnets: ""})
-	output := exec.Output
+	awsc := models.MockAwsClients(release)
+	awsc.ResourceGroups.AddGroup("arn:aws:resource-groups:us-east-1:000000:group/hosts")
 
-	assert.Error(t, err)
-	assert.Equal(t, "FailureClean", output["Error"])
+	assertSuccessfulExecutionWithAWS(t, release, awsc)
 
-	assert.Equal(t, exec.Path(), []string{
-		"Validate",
-		"FailureClean",
-	})
+	assert.Equal(t, 1, len(awsc.EC2.CreateLaunchTemplateInputs))
+	assert.Equal(t, "host", *awsc.EC2.CreateLaunchTemplateInputs[0].LaunchTemplateData.Placement.Tenancy)
 }
 
-func Test_Execution_FetchDeploy_RootLockError(t *testing.T) {
+func Test_UnsuccessfulDeploy_Host_Resource_Group_Not_Found(t *testing.T) {
 	release := models.MockRelease(t)
+	release.Services["web"].PlacementTenancy = to.Strp("host")
+	release.Services["web"].HostResourceGroupArn = to.Strp("arn:aws:resource-groups:us-east-1:000000:group/hosts")
 
-	// Should retry a few times, then end in clean state as nothing was created
-	awsClients := models.MockAwsClients(release)
-
-	// Force a lock error by making it look like it was already aquired
-	awsClients.S3.AddGetObject(*release.RootLockPath(), `{"uuid": "already"}`, nil)
+	awsc := models.MockAwsClients(release)
 
-	stateMachine := createTestStateMachine(t, awsClients)
+	stateMachine := createTestStateMachine(t, awsc)
 
 	exec, err := stateMachine.Execute(release)
-	output := exec.Output
 
 	assert.Error(t, err)
-	assert.Equal(t, "FailureClean", output["Error"])
-
-	assert.Equal(t, exec.Path(), []string{
+	assert.Regexp(t, "BadReleaseError", exec.LastOutputJSON)
+	assert.Regexp(t, "HostResourceGroupArn .* not found", exec.LastOutputJSON)
+	assert.Equal(t, []string{
 		"Validate",
+		"ValidateOnly?",
 		"Lock",
+		"ValidateResources",
+		"LockHeld?",
+		"ReleaseLockFailure",
+		"NotifyFailure",
 		"FailureClean",
-	})
+	}, exec.Path())
+
+	// Nothing was deployed
+	assert.Equal(t, 0, len(awsc.ASG.CreateAutoScalingGroupInputs))
 }
 
-func Test_Execution_FetchDeploy_ReleaseLockError(t *testing.T) {
+func Test_Execution_Progress(t *testing.T) {
 	release := models.MockRelease(t)
+	p := progress.NewMemory()
 
-	// Should retry a few times, then end in clean state as nothing was created
-	awsClients := models.MockAwsClients(release)
-
-	// Force a lock error by making it look like it was already aquired
-	awsClients.S3.AddGetObject(*release.ReleaseLockPath(), `{"uuid": "already"}`, nil)
-
-	stateMachine := createTestStateMachine(t, awsClients)
-
-	exec, err := stateMachine.Execute(release)
-	output := exec.Output
-
-	assert.Error(t, err)
-	assert.Equal(t, "FailureClean", output["Error"])
+	_, err := createTestStateMachineWithProgress(t, models.MockAwsClients(release), p).Execute(release)
+	assert.NoError(t, err)
 
-	assert.Equal(t, exec.Path(), []string{
-		"Validate",
-		"Lock",
-		"FailureClean",
-	})
+	// The mock instances are healthy at the first poll
+	updates := p.Updates()
+	assert.Equal(t, 1, len(updates))
+	assert.True(t, updates[0].Healthy)
+	assert.Equal(t, int64(1), updates[0].Services["web"].Desired)
+	assert.Equal(t, []int{1}, p.Healthy("web"))
 }
 
-func Test_Execution_CheckHealthy_HaltError_WithTermination(t *testing.T) {
-	// Should end in Alert Bad Thing Happened State
+func Test_UnsuccessfulDeploy_DependsOn_Cycle(t *testing.T) {
 	release := models.MockRelease(t)
-	maws := models.MockAwsClients(release)
-	maws.ASG.DescribeAutoScalingGroupsPageResp = nil
 
-	termingASG := mocks.MakeMockASG("odin", *release.ProjectName, *release.ConfigName, "web", "Old release")
-	termingASG.Instances[0].LifecycleState = to.Strp("Terminating")
+	raw, err := json.Marshal(release.Services["web"])
+	assert.NoError(t, err)
+	var api models.Service
+	assert.NoError(t, json.Unmarshal(raw, &api))
+	release.Services["api"] = &api
 
-	maws.ASG.AddASG(termingASG)
+	release.Services["web"].DependsOn = []*string{to.Strp("api")}
+	release.Services["api"].DependsOn = []*string{to.Strp("web")}
 
-	stateMachine := createTestStateMachine(t, maws)
+	awsc := models.MockAwsClients(release)
+	stateMachine := createTestStateMachine(t, awsc)
 
 	exec, err := stateMachine.Execute(release)
 
 	assert.Error(t, err)
-	assert.Regexp(t, "HaltError", exec.LastOutputJSON)
-	assert.Regexp(t, "success\": false", exec.LastOutputJSON)
+	assert.Equal(t, "FailureClean", exec.Output["Error"])
+	assert.Regexp(t, "DependsOn cycle api", exec.LastOutputJSON)
 
-	assert.Equal(t, exec.Path(), []string{
+	assert.Equal(t, []string{
 		"Validate",
+		"ValidateOnly?",
 		"Lock",
 		"ValidateResources",
-		"Deploy",
-		"WaitForDeploy",
-		"WaitForHealthy",
-		"CheckHealthy",
-		"DetachForFailure",
-		"WaitDetachForFailure",
-		"CleanUpFailure",
+		"LockHeld?",
 		"ReleaseLockFailure",
+		"NotifyFailure",
 		"FailureClean",
-	})
+	}, exec.Path())
+
+	// Nothing is deployed
+	assert.Equal(t, 0, len(awsc.ASG.CreateAutoScalingGroupInputs))
 }
 
-func Test_Execution_CheckHealthy_Never_Healthy_ELB(t *testing.T) {
-	// Should end in Alert Bad Thing Happened State
+func Test_UnsuccessfulDeploy_PreviousFleet_Unhealthy(t *testing.T) {
 	release := models.MockRelease(t)
+	release.PreviousFleetHealthyPercent = to.Intp(100)
 
-	maws := models.MockAwsClients(release)
-	maws.ELB.DescribeInstanceHealthResp["web-elb"] = &mocks.DescribeInstanceHealthResponse{}
+	awsc := models.MockAwsClients(release)
+	old := awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0]
+	old.DesiredCapacity = to.Int64p(2)
+	old.Instances = mocks.MakeMockASGInstances(1, 1, 0)
 
-	stateMachine := createTestStateMachine(t, maws)
+	stateMachine := createTestStateMachine(t, awsc)
 
 	exec, err := stateMachine.Execute(release)
 
 	assert.Error(t, err)
+	assert.Equal(t, "FailureClean", exec.Output["Error"])
+	assert.Regexp(t, "has 1 of 2 instances healthy", exec.LastOutputJSON)
 
-	ep := exec.Path()
 	assert.Equal(t, []string{
 		"Validate",
+		"ValidateOnly?",
 		"Lock",
 		"ValidateResources",
-		"Deploy",
-		"WaitForDeploy",
-		"WaitForHealthy",
-		"CheckHealthy"}, ep[0:7])
-
-	assert.Equal(t, []string{
-		"DetachForFailure",
-		"WaitDetachForFailure",
-		"CleanUpFailure",
+		"LockHeld?",
 		"ReleaseLockFailure",
+		"NotifyFailure",
 		"FailureClean",
-	}, ep[len(ep)-5:len(ep)])
+	}, exec.Path())
 
-	assert.Regexp(t, "Timeout", exec.LastOutputJSON)
-	assert.Regexp(t, "success\": false", exec.LastOutputJSON)
+	// Nothing is deployed and the previous fleet is untouched
+	assert.Equal(t, 0, len(awsc.ASG.CreateAutoScalingGroupInputs))
+	assert.Equal(t, 0, len(awsc.ASG.DeleteAutoScalingGroupInputs))
 }
 
-func Test_Execution_CheckHealthy_Never_Healthy_TG(t *testing.T) {
-	// Should end in Alert Bad Thing Happened State
+func Test_Execution_ValidateResources_Missing_HealthAlarm(t *testing.T) {
 	release := models.MockRelease(t)
+	release.Services["web"].HealthAlarms = []*string{to.Strp("missing")}
 
 	maws := models.MockAwsClients(release)
-	maws.ALB.DescribeTargetHealthResp["web-elb-target"] = &mocks.DescribeTargetHealthResponse{}
-
 	stateMachine := createTestStateMachine(t, maws)
 
 	exec, err := stateMachine.Execute(release)
 
 	assert.Error(t, err)
+	assert.Regexp(t, "HealthAlarms", exec.LastOutputJSON)
+	assert.Regexp(t, "not found", exec.LastOutputJSON)
 
-	ep := exec.Path()
 	assert.Equal(t, []string{
 		"Validate",
+		"ValidateOnly?",
 		"Lock",
 		"ValidateResources",
-		"Deploy",
-		"WaitForDeploy",
-		"WaitForHealthy",
-		"CheckHealthy"}, ep[0:7])
-
-	assert.Equal(t, []string{
-		"CleanUpFailure",
+		"LockHeld?",
 		"ReleaseLockFailure",
+		"NotifyFailure",
 		"FailureClean",
-	}, ep[len(ep)-3:len(ep)])
-
-	assert.Regexp(t, "Timeout", exec.LastOutputJSON)
-	assert.Regexp(t, "success\": false", exec.LastOutputJSON)
+	}, exec.Path())
 }
 
-func Test_Execution_CleanupSuccess_DetachError(t *testing.T) {
-	// Should try 10 times to detach
+func Test_Execution_ValidateResources_Full_Listener(t *testing.T) {
+	listenerArn := "arn:aws:elasticloadbalancing:us-east-1:000000:listener/app/web/1234/5678"
 	release := models.MockRelease(t)
+	release.Services["web"].TargetGroups = []*string{to.Strp("web-elb-target"), to.Strp("web-blue"), to.Strp("web-green")}
+	release.Services["web"].ListenerRule = &models.ListenerRule{
+		ListenerArn:  to.Strp(listenerArn),
+		Priority:     to.Int64p(20),
+		TargetGroups: []*string{to.Strp("web-blue"), to.Strp("web-green")},
+	}
 
 	maws := models.MockAwsClients(release)
-	maws.ASG.DescribeLoadBalancerTargetGroupsOutput = &autoscaling.DescribeLoadBalancerTargetGroupsOutput{
-		LoadBalancerTargetGroups: []*autoscaling.LoadBalancerTargetGroupState{
-			&autoscaling.LoadBalancerTargetGroupState{
-				LoadBalancerTargetGroupARN: to.Strp("arn"),
-				State:                      to.Strp("aaa"),
-			},
-		},
+	for _, name := range []string{"web-blue", "web-green"} {
+		maws.ALB.AddTargetGroup(mocks.MockTargetGroup{
+			Name:        name,
+			ProjectName: *release.ProjectName,
+			ConfigName:  *release.ConfigName,
+			ServiceName: "web",
+		})
 	}
-
+	maws.ALB.FillListener(listenerArn, 100, "api-target")
 	stateMachine := createTestStateMachine(t, maws)
 
 	exec, err := stateMachine.Execute(release)
 
-	// We force delete rather than suffer the consequences
-	assert.NoError(t, err)
+	assert.Error(t, err)
+	assert.Regexp(t, "BadReleaseError", exec.LastOutputJSON)
+	assert.Regexp(t, "would exceed the limit of 100 rules", exec.LastOutputJSON)
 
-	ep := exec.Path()
-	steps := []string{
+	assert.Equal(t, []string{
 		"Validate",
+		"ValidateOnly?",
 		"Lock",
 		"ValidateResources",
-		"Deploy",
-		"WaitForDeploy",
-		"WaitForHealthy",
-		"CheckHealthy",
-		"Healthy?",
-		"WaitForDetach",
-	}
-
-	// Check detach 60 times (for 10 minutes)
-	for i := 0; i <= 60; i++ {
-		steps = append(steps, "DetachForSuccess")
-	}
-
-	steps = append(steps, "WaitDetachForSuccess", "CleanUpSuccess", "Success")
-
-	assert.Equal(t, steps, ep)
-
-	assert.Regexp(t, "DetachError", exec.LastOutputJSON)
-}
-
-func Test_Execution_CleanupSuccess_Detach_SkipCheck(t *testing.T) {
-	// Should try 10 times to detach
-	release := models.MockRelease(t)
-	release.DetachStrategy = to.Strp("SkipDetachCheck")
-
-	maws := models.MockAwsClients(release)
-	maws.ASG.DescribeLoadBalancerTargetGroupsOutput = &autoscaling.DescribeLoadBalancerTargetGroupsOutput{
-		LoadBalancerTargetGroups: []*autoscaling.LoadBalancerTargetGroupState{
-			&autoscaling.LoadBalancerTargetGroupState{
-				LoadBalancerTargetGroupARN: to.Strp("arn"),
-				State:                      to.Strp("aaa"),
-			},
-		},
-	}
-
-	assertSuccessfulExecutionWithAWS(t, release, maws)
-}
-
-func Test_Execution_CleanupSuccess_Detach_SkipDetach(t *testing.T) {
-	// Should try 10 times to detach
-	release := models.MockRelease(t)
-	release.DetachStrategy = to.Strp("SkipDetach")
-
-	maws := models.MockAwsClients(release)
-	maws.ASG.DetachLoadBalancersError = fmt.Errorf("never reached")
-
-	assertSuccessfulExecutionWithAWS(t, release, maws)
+		"LockHeld?",
+		"ReleaseLockFailure",
+		"NotifyFailure",
+		"FailureClean",
+	}, exec.Path())
 }
@@ -0,0 +1,18 @@
+package deployer
+
+import (
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/deployer/models"
+)
+
+// InspectLock returns who holds the lock of the project config, the lock is only read
+// The stateMachineArn finds the holders execution and state, without it only the UUID is returned
+func InspectLock(s3c aws.S3API, sfnc aws.SFNAPI, bucket *string, accountID *string, projectName *string, configName *string, stateMachineArn *string) (*models.LockHolder, error) {
+	finder := &models.Release{}
+	finder.Bucket = bucket
+	finder.AwsAccountID = accountID
+	finder.ProjectName = projectName
+	finder.ConfigName = configName
+
+	return finder.InspectLock(s3c, sfnc, stateMachineArn)
+}
@@ -2,9 +2,14 @@ package deployer
 
 // StateMachine returns the StateMachine
 import (
+	"time"
+
 	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/deployer/metrics"
+	"github.com/coinbase/odin/deployer/progress"
 	"github.com/coinbase/step/handler"
 	"github.com/coinbase/step/machine"
+	"github.com/coinbase/step/utils/to"
 )
 
 // StateMachine returns
@@ -17,16 +22,33 @@ func StateMachine() (*machine.StateMachine, error) {
         "Type": "TaskFn",
         "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
         "Comment": "Validate and Set Defaults",
-        "Next": "Lock",
+        "Next": "ValidateOnly?",
         "Catch": [
           {
             "Comment": "Bad Input, straight to Failure Clean, dont pass go dont collect $200",
             "ErrorEquals": ["States.ALL"],
             "ResultPath": "$.error",
-            "Next": "FailureClean"
+            "Next": "NotifyFailure"
           }
         ]
       },
+      "ValidateOnly?": {
+        "Comment": "A $.validate_only release validates its resources without taking the lock, a $.replayed release already succeeded",
+        "Type": "Choice",
+        "Choices": [
+          {
+            "Variable": "$.replayed",
+            "BooleanEquals": true,
+            "Next": "ReplaySuccess"
+          },
+          {
+            "Variable": "$.validate_only",
+            "BooleanEquals": true,
+            "Next": "ValidateResources"
+          }
+        ],
+        "Default": "Lock"
+      },
       "Lock": {
         "Type": "TaskFn",
         "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
@@ -37,7 +59,7 @@ func StateMachine() (*machine.StateMachine, error) {
             "Comment": "Bad Input, straight to Failure Clean",
             "ErrorEquals": ["LockExistsError"],
             "ResultPath": "$.error",
-            "Next": "FailureClean"
+            "Next": "NotifyFailure"
           },
           {
             "Comment": "Release Lock if you created it",
@@ -51,12 +73,50 @@ func StateMachine() (*machine.StateMachine, error) {
         "Type": "TaskFn",
         "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
         "Comment": "Validate Resources",
-        "Next": "Deploy",
+        "Next": "Validated?",
         "Catch": [
           {
             "Comment": "Try to Release Locks",
             "ErrorEquals": ["States.ALL"],
             "ResultPath": "$.error",
+            "Next": "LockHeld?"
+          }
+        ]
+      },
+      "LockHeld?": {
+        "Comment": "A $.validate_only release never took the lock so has none to release",
+        "Type": "Choice",
+        "Choices": [
+          {
+            "Variable": "$.validate_only",
+            "BooleanEquals": true,
+            "Next": "NotifyFailure"
+          }
+        ],
+        "Default": "ReleaseLockFailure"
+      },
+      "Validated?": {
+        "Comment": "Stop a $.validate_only release before anything is deployed",
+        "Type": "Choice",
+        "Choices": [
+          {
+            "Variable": "$.validate_only",
+            "BooleanEquals": true,
+            "Next": "ValidationSuccess"
+          }
+        ],
+        "Default": "PreDeployHook"
+      },
+      "PreDeployHook": {
+        "Type": "TaskFn",
+        "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
+        "Comment": "Ask the $.pre_deploy_hook Lambda whether the release can be deployed",
+        "Next": "Deploy",
+        "Catch": [
+          {
+            "Comment": "Denied, Release Lock",
+            "ErrorEquals": ["States.ALL"],
+            "ResultPath": "$.error",
             "Next": "ReleaseLockFailure"
           }
         ]
@@ -65,11 +125,11 @@ func StateMachine() (*machine.StateMachine, error) {
         "Type": "TaskFn",
         "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
         "Comment": "Create Resources",
-        "Next": "WaitForDeploy",
+        "Next": "InstanceRefresh?",
         "Catch": [
           {
             "Comment": "Try to Release Locks",
-            "ErrorEquals": ["HaltError"],
+            "ErrorEquals": ["HaltError", "TimeoutError"],
             "ResultPath": "$.error",
             "Next": "ReleaseLockFailure"
           },
@@ -81,16 +141,126 @@ func StateMachine() (*machine.StateMachine, error) {
           }
         ]
       },
+      "InstanceRefresh?": {
+        "Comment": "Is the release refreshing the live ASGs rather than creating new ones?",
+        "Type": "Choice",
+        "Choices": [
+          {
+            "Variable": "$.deploy_strategy",
+            "StringEquals": "InstanceRefresh",
+            "Next": "WaitForRefresh"
+          }
+        ],
+        "Default": "WaitForDeploy"
+      },
+      "WaitForRefresh": {
+        "Comment": "Give the instance refresh time to replace instances",
+        "Type": "Wait",
+        "SecondsPath" : "$.wait_for_healthy",
+        "Next": "CheckRefresh"
+      },
+      "CheckRefresh": {
+        "Type": "TaskFn",
+        "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
+        "Comment": "Have the instance refreshes of the live ASGs completed?",
+        "Next": "Refreshed?",
+        "Retry": [{
+          "Comment": "Do not retry on HaltError or TimeoutError",
+          "ErrorEquals": ["HaltError", "TimeoutError"],
+          "MaxAttempts": 0
+        },
+        {
+          "Comment": "Errors might occur, just retry a few times",
+          "ErrorEquals": ["States.ALL"],
+          "MaxAttempts": 3,
+          "IntervalSeconds": 15
+        }],
+        "Catch": [{
+          "Comment": "Immediately cancel the refresh on Error",
+          "ErrorEquals": ["States.ALL"],
+          "ResultPath": "$.error",
+          "Next": "CancelRefresh"
+        }]
+      },
+      "Refreshed?": {
+        "Comment": "Check the release is $.refreshed",
+        "Type": "Choice",
+        "Choices": [
+          {
+            "Variable": "$.refreshed",
+            "BooleanEquals": true,
+            "Next": "CleanUpSuccess"
+          },
+          {
+            "Variable": "$.refreshed",
+            "BooleanEquals": false,
+            "Next": "WaitForRefresh"
+          }
+        ],
+        "Default": "CancelRefresh"
+      },
+      "CancelRefresh": {
+        "Type": "TaskFn",
+        "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
+        "Comment": "Cancel the instance refreshes, the live ASGs are never deleted",
+        "Next": "ReleaseLockFailure",
+        "Retry": [{
+          "Comment": "Keep trying to Cancel",
+          "ErrorEquals": ["States.ALL"],
+          "MaxAttempts": 3,
+          "IntervalSeconds": 30
+        }],
+        "Catch": [{
+          "ErrorEquals": ["States.ALL"],
+          "ResultPath": "$.error",
+          "Next": "NotifyFailureDirty"
+        }]
+      },
       "WaitForDeploy": {
         "Comment": "Give the Deploy time to boot instances",
         "Type": "Wait",
-        "Seconds" : 90,
+        "SecondsPath" : "$.wait_for_deploy",
         "Next": "WaitForHealthy"
       },
       "WaitForHealthy": {
         "Type": "Wait",
         "SecondsPath" : "$.wait_for_healthy",
-        "Next": "CheckHealthy"
+        "Next": "Canaried?"
+      },
+      "Canaried?": {
+        "Comment": "Check the release is $.canaried before checking the full count is healthy",
+        "Type": "Choice",
+        "Choices": [
+          {
+            "Variable": "$.canaried",
+            "BooleanEquals": false,
+            "Next": "CheckCanary"
+          }
+        ],
+        "Default": "CheckHealthy"
+      },
+      "CheckCanary": {
+        "Type": "TaskFn",
+        "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
+        "Comment": "Has the canary been healthy for its bake duration? The full count is not launched until it has.",
+        "Next": "WaitForHealthy",
+        "Retry": [{
+          "Comment": "Do not retry on HaltError or TimeoutError",
+          "ErrorEquals": ["HaltError", "TimeoutError"],
+          "MaxAttempts": 0
+        },
+        {
+          "Comment": "Errors might occur, just retry a few times",
+          "ErrorEquals": ["States.ALL"],
+          "MaxAttempts": 3,
+          "IntervalSeconds": 15
+        }],
+        "Catch": [{
+          "Comment": "Canary failed, immediately Clean up",
+          "ErrorEquals": ["States.ALL"],
+          "ResultPath": "$.error",
+          "Next": "DetachForFailure"
+        }]
       },
       "CheckHealthy": {
         "Type": "TaskFn",
@@ -98,8 +268,8 @@ func StateMachine() (*machine.StateMachine, error) {
         "Comment": "Is the new deploy healthy? Should we continue checking? Also, scale the instances according to the strategy.",
         "Next": "Healthy?",
         "Retry": [{
-          "Comment": "Do not retry on HaltError",
-          "ErrorEquals": ["HaltError"],
+          "Comment": "Do not retry on HaltError or TimeoutError",
+          "ErrorEquals": ["HaltError", "TimeoutError"],
           "MaxAttempts": 0
         },
         {
@@ -122,7 +292,7 @@ func StateMachine() (*machine.StateMachine, error) {
           {
             "Variable": "$.healthy",
             "BooleanEquals": true,
-            "Next": "WaitForDetach"
+            "Next": "SmokeTest"
           },
           {
             "Variable": "$.healthy",
@@ -132,6 +302,81 @@ func StateMachine() (*machine.StateMachine, error) {
         ],
         "Default": "DetachForFailure"
       },
+      "SmokeTest": {
+        "Type": "TaskFn",
+        "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
+        "Comment": "Run the smoke test against the new fleet while the old fleet still serves traffic",
+        "Next": "CutoverDNS",
+        "Catch": [{
+          "Comment": "Smoke test failed, clean up the new fleet",
+          "ErrorEquals": ["States.ALL"],
+          "ResultPath": "$.error",
+          "Next": "DetachForFailure"
+        }]
+      },
+      "CutoverDNS": {
+        "Type": "TaskFn",
+        "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
+        "Comment": "Point the weighted DNS record at the new release",
+        "Next": "Soak",
+        "Retry": [{
+          "Comment": "Errors might occur, just retry a few times",
+          "ErrorEquals": ["States.ALL"],
+          "MaxAttempts": 3,
+          "IntervalSeconds": 15
+        }],
+        "Catch": [{
+          "Comment": "Revert the DNS and clean up",
+          "ErrorEquals": ["States.ALL"],
+          "ResultPath": "$.error",
+          "Next": "DetachForFailure"
+        }]
+      },
+      "Soak": {
+        "Type": "TaskFn",
+        "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
+        "Comment": "Watch the soak alarms before removing the old release",
+        "Next": "Soaked?",
+        "Retry": [{
+          "Comment": "Do not retry on HaltError or TimeoutError",
+          "ErrorEquals": ["HaltError", "TimeoutError"],
+          "MaxAttempts": 0
+        },
+        {
+          "Comment": "Errors might occur, just retry a few times",
+          "ErrorEquals": ["States.ALL"],
+          "MaxAttempts": 3,
+          "IntervalSeconds": 15
+        }],
+        "Catch": [{
+          "Comment": "Alarm tripped, roll back to the old release",
+          "ErrorEquals": ["States.ALL"],
+          "ResultPath": "$.error",
+          "Next": "DetachForFailure"
+        }]
+      },
+      "Soaked?": {
+        "Comment": "Check the release is $.soaked",
+        "Type": "Choice",
+        "Choices": [
+          {
+            "Variable": "$.soaked",
+            "BooleanEquals": true,
+            "Next": "WaitForDetach"
+          },
+          {
+            "Variable": "$.soaked",
+            "BooleanEquals": false,
+            "Next": "WaitForSoak"
+          }
+        ],
+        "Default": "DetachForFailure"
+      },
+      "WaitForSoak": {
+        "Type": "Wait",
+        "SecondsPath" : "$.wait_for_healthy",
+        "Next": "Soak"
+      },
       "WaitForDetach": {
         "Type": "Wait",
         "SecondsPath" : "$.wait_for_detach",
@@ -165,6 +410,12 @@ func StateMachine() (*machine.StateMachine, error) {
         "Comment": "Give detach a little time to do what it does",
         "Type": "Wait",
         "Seconds" : 5,
+        "Next": "DrainForSuccess"
+      },
+      "DrainForSuccess": {
+        "Comment": "Wait for the detached instances to deregister from their target groups",
+        "Type": "Wait",
+        "SecondsPath" : "$.wait_for_drain",
         "Next": "CleanUpSuccess"
       },
       "CleanUpSuccess": {
@@ -173,6 +424,12 @@ func StateMachine() (*machine.StateMachine, error) {
         "Comment": "Promote New Resources & Delete Old Resources",
         "Next": "Success",
         "Retry": [{
+          "Comment": "Retry while DrainFirst instances drain, for 10 minutes",
+          "ErrorEquals": ["DrainError"],
+          "MaxAttempts": 60,
+          "IntervalSeconds": 10,
+          "BackoffRate": 1.0
+         },{
           "Comment": "Keep trying to Clean",
           "ErrorEquals": ["States.ALL"],
           "MaxAttempts": 3,
@@ -182,7 +439,7 @@ func StateMachine() (*machine.StateMachine, error) {
         "Catch": [{
           "ErrorEquals": ["States.ALL"],
           "ResultPath": "$.error",
-          "Next": "FailureDirty"
+          "Next": "NotifyFailureDirty"
         }]
       },
       "DetachForFailure": {
@@ -207,6 +464,12 @@ func StateMachine() (*machine.StateMachine, error) {
         "Comment": "Give detach a little time to do what it does",
         "Type": "Wait",
         "Seconds" : 60,
+        "Next": "DrainForFailure"
+      },
+      "DrainForFailure": {
+        "Comment": "Wait for the detached instances to deregister from their target groups",
+        "Type": "Wait",
+        "SecondsPath" : "$.wait_for_drain",
         "Next": "CleanUpFailure"
       },
       "CleanUpFailure": {
@@ -223,14 +486,14 @@ func StateMachine() (*machine.StateMachine, error) {
         "Catch": [{
           "ErrorEquals": ["States.ALL"],
           "ResultPath": "$.error",
-          "Next": "FailureDirty"
+          "Next": "NotifyFailureDirty"
         }]
       },
       "ReleaseLockFailure": {
         "Type": "TaskFn",
         "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
         "Comment": "Delete New Resources",
-        "Next": "FailureClean",
+        "Next": "NotifyFailure",
         "Retry": [ {
           "Comment": "Keep trying to Clean",
           "ErrorEquals": ["States.ALL"],
@@ -240,6 +503,28 @@ func StateMachine() (*machine.StateMachine, error) {
         "Catch": [{
           "ErrorEquals": ["States.ALL"],
           "ResultPath": "$.error",
+          "Next": "NotifyFailureDirty"
+        }]
+      },
+      "NotifyFailure": {
+        "Type": "TaskFn",
+        "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
+        "Comment": "Publish the failure to the notification topic",
+        "Next": "FailureClean",
+        "Catch": [{
+          "ErrorEquals": ["States.ALL"],
+          "ResultPath": "$.notify_error",
+          "Next": "FailureClean"
+        }]
+      },
+      "NotifyFailureDirty": {
+        "Type": "TaskFn",
+        "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
+        "Comment": "Publish the failure that left resources behind to the notification topic",
+        "Next": "FailureDirty",
+        "Catch": [{
+          "ErrorEquals": ["States.ALL"],
+          "ResultPath": "$.notify_error",
           "Next": "FailureDirty"
         }]
       },
@@ -253,6 +538,14 @@ func StateMachine() (*machine.StateMachine, error) {
         "Type": "Fail",
         "Error": "FailureDirty"
       },
+      "ValidationSuccess": {
+        "Comment": "Release and its resources are valid, nothing was deployed",
+        "Type": "Succeed"
+      },
+      "ReplaySuccess": {
+        "Comment": "A release with the idempotency key already succeeded, its result is returned",
+        "Type": "Succeed"
+      },
       "Success": {
         "Type": "Succeed"
       }
@@ -265,27 +558,125 @@ func StateMachine() (*machine.StateMachine, error) {
 	return stateMachine, nil
 }
 
+// RollbackStateMachine returns the StateMachine that deploys the release replaced by the last successful release
+// It starts at the Rollback state instead of Validate, then runs the states of the StateMachine
+func RollbackStateMachine() (*machine.StateMachine, error) {
+	stateMachine, err := StateMachine()
+	if err != nil {
+		return nil, err
+	}
+
+	rollback, err := machine.FromJSON([]byte(`{
+    "StartAt": "Rollback",
+    "States": {
+      "Rollback": {
+        "Type": "TaskFn",
+        "Resource": "arn:aws:lambda:{{aws_region}}:{{aws_account}}:function:{{lambda_name}}",
+        "Comment": "Replace the release with the previous release, then Validate and Set Defaults",
+        "Next": "ValidateOnly?",
+        "Catch": [
+          {
+            "Comment": "No previous release or Bad Input, straight to Failure Clean",
+            "ErrorEquals": ["States.ALL"],
+            "ResultPath": "$.error",
+            "Next": "NotifyFailure"
+          }
+        ]
+      }
+    }
+  }`))
+	if err != nil {
+		return nil, err
+	}
+
+	stateMachine.Comment = to.Strp("ASG Deployer Rollback")
+	stateMachine.StartAt = rollback.StartAt
+	stateMachine.States["Rollback"] = rollback.States["Rollback"]
+
+	return stateMachine, nil
+}
+
+// TaskHandlersOption configures the AWS clients of the TaskHandlers
+type TaskHandlersOption func(*aws.ClientsStr)
+
+// WithRetry makes up to maxAttempts attempts of every AWS call that fails with a transient error, e.g. throttling,
+// waiting from baseDelay between them. Without it the clients use aws.DefaultRetryer
+func WithRetry(maxAttempts int, baseDelay time.Duration) TaskHandlersOption {
+	return func(awsc *aws.ClientsStr) {
+		awsc.Retryer = aws.NewRetryer(maxAttempts, baseDelay)
+	}
+}
+
 // TaskHandlers returns
-func TaskHandlers() *handler.TaskHandlers {
-	return CreateTaskFunctinons(&aws.ClientsStr{})
+func TaskHandlers(options ...TaskHandlersOption) *handler.TaskHandlers {
+	awsc := &aws.ClientsStr{}
+	for _, option := range options {
+		option(awsc)
+	}
+
+	tm := CreateRollbackTaskFunctinons(awsc)
+
+	// Invoked directly on the Lambda by the client, they are not states of the state machine
+	(*tm)["Plan"] = Plan(awsc)
+	(*tm)["Prune"] = Prune(awsc)
+	(*tm)["Abort"] = Abort(awsc)
+
+	return tm
 }
 
 // CreateTaskFunctinons returns
 func CreateTaskFunctinons(awsc aws.Clients) *handler.TaskHandlers {
-	tm := handler.TaskHandlers{}
-	tm["Validate"] = Validate(awsc)
-	tm["Lock"] = Lock(awsc)
-	tm["ValidateResources"] = ValidateResources(awsc)
-	tm["Deploy"] = Deploy(awsc)
-	tm["CheckHealthy"] = CheckHealthy(awsc)
+	return CreateTaskFunctinonsWithMetrics(awsc, metrics.Nop{})
+}
+
+// CreateRollbackTaskFunctinons returns the handlers of the RollbackStateMachine, the StateMachine handlers and Rollback
+func CreateRollbackTaskFunctinons(awsc aws.Clients) *handler.TaskHandlers {
+	tm := CreateTaskFunctinons(awsc)
+	(*tm)["Rollback"] = wrapHandler(awsc, metrics.Nop{}, progress.Nop{}, "Rollback", Rollback(awsc))
+	return tm
+}
+
+// CreateTaskFunctinonsWithMetrics returns the handlers emitting the deploy metrics to m
+func CreateTaskFunctinonsWithMetrics(awsc aws.Clients, m metrics.Metrics) *handler.TaskHandlers {
+	return CreateTaskFunctinonsWithProgress(awsc, m, progress.Nop{})
+}
+
+// CreateTaskFunctinonsWithProgress returns the handlers emitting the deploy metrics to m
+// and reporting the health of the release to p each time CheckHealthy polls it
+func CreateTaskFunctinonsWithProgress(awsc aws.Clients, m metrics.Metrics, p progress.Progress) *handler.TaskHandlers {
+	fns := map[string]DeployHandler{}
+	fns["Validate"] = Validate(awsc)
+	fns["Lock"] = Lock(awsc)
+	fns["ValidateResources"] = ValidateResources(awsc)
+	fns["PreDeployHook"] = PreDeployHook(awsc)
+	fns["Deploy"] = Deploy(awsc)
+	fns["CheckCanary"] = CheckCanary(awsc)
+	fns["CheckHealthy"] = CheckHealthy(awsc)
+	fns["CheckRefresh"] = CheckRefresh(awsc)
+	fns["CancelRefresh"] = CancelRefresh(awsc)
+	fns["SmokeTest"] = SmokeTest(awsc)
+	fns["CutoverDNS"] = CutoverDNS(awsc)
+	fns["Soak"] = Soak(awsc)
 
 	// success
-	tm["DetachForSuccess"] = DetachForSuccess(awsc)
-	tm["CleanUpSuccess"] = CleanUpSuccess(awsc)
+	fns["DetachForSuccess"] = DetachForSuccess(awsc)
+	fns["CleanUpSuccess"] = CleanUpSuccess(awsc)
 
 	// Failure
-	tm["DetachForFailure"] = DetachForFailure(awsc)
-	tm["CleanUpFailure"] = CleanUpFailure(awsc)
-	tm["ReleaseLockFailure"] = ReleaseLockFailure(awsc)
+	fns["DetachForFailure"] = DetachForFailure(awsc)
+	fns["CleanUpFailure"] = CleanUpFailure(awsc)
+	fns["ReleaseLockFailure"] = ReleaseLockFailure(awsc)
+	fns["NotifyFailure"] = NotifyFailure(awsc)
+	fns["NotifyFailureDirty"] = NotifyFailureDirty(awsc)
+
+	tm := handler.TaskHandlers{}
+	for name, fn := range fns {
+		tm[name] = wrapHandler(awsc, m, p, name, fn)
+	}
 	return &tm
 }
+
+// wrapHandler logs, measures, reports the progress and records the path of the state name
+func wrapHandler(awsc aws.Clients, m metrics.Metrics, p progress.Progress, name string, fn DeployHandler) DeployHandler {
+	return withEventLog(awsc, name, withMetrics(m, name, withProgress(p, name, withPath(name, fn))))
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+// Labels of the metrics
+const (
+	LabelProject     = "project"
+	LabelConfig      = "config"
+	LabelOutcome     = "outcome"
+	LabelPhase       = "phase"
+	LabelReleaseUUID = "release_uuid"
+)
+
+// DefaultNamespace is the CloudWatch namespace if none is configured
+const DefaultNamespace = "Odin"
+
+// DefaultDimensions are the labels published as CloudWatch dimensions if none are configured
+var DefaultDimensions = []string{LabelProject, LabelConfig, LabelOutcome, LabelPhase}
+
+// ReleaseLabeled is implemented by a Metrics that wants each metric labelled with the release_uuid.
+// It is left off by default as every release would be a new series
+type ReleaseLabeled interface {
+	LabelReleaseUUID() bool
+}
+
+// CloudWatchConfig configures the metrics published to CloudWatch
+type CloudWatchConfig struct {
+	// Namespace of every metric, DefaultNamespace if empty
+	Namespace string
+
+	// Labels published as dimensions in this order, DefaultDimensions if empty.
+	// A label a metric does not have is left off its dimensions
+	Dimensions []string
+
+	// Label every metric with the release_uuid, it must also be in Dimensions to be published
+	ReleaseUUID bool
+}
+
+// CloudWatch publishes every metric to CloudWatch as a datum, a histogram observation is a datum of its value
+// Failing to publish never fails the deploy
+type CloudWatch struct {
+	cwc    aws.CWAPI
+	config CloudWatchConfig
+}
+
+// NewCloudWatch returns a CloudWatch publishing with the config
+func NewCloudWatch(cwc aws.CWAPI, config CloudWatchConfig) *CloudWatch {
+	if config.Namespace == "" {
+		config.Namespace = DefaultNamespace
+	}
+
+	if len(config.Dimensions) == 0 {
+		config.Dimensions = DefaultDimensions
+	}
+
+	return &CloudWatch{cwc, config}
+}
+
+// LabelReleaseUUID returns true if the metrics are labelled with the release_uuid
+func (c *CloudWatch) LabelReleaseUUID() bool {
+	return c.config.ReleaseUUID
+}
+
+// IncCounter publishes a count of one
+func (c *CloudWatch) IncCounter(name string, labels map[string]string) {
+	c.put(name, 1, cloudwatch.StandardUnitCount, labels)
+}
+
+// ObserveHistogram publishes the value
+func (c *CloudWatch) ObserveHistogram(name string, value float64, labels map[string]string) {
+	unit := cloudwatch.StandardUnitNone
+	if strings.HasSuffix(name, "_seconds") {
+		unit = cloudwatch.StandardUnitSeconds
+	}
+
+	c.put(name, value, unit, labels)
+}
+
+func (c *CloudWatch) put(name string, value float64, unit string, labels map[string]string) {
+	_, err := c.cwc.PutMetricData(&cloudwatch.PutMetricDataInput{
+		Namespace: to.Strp(c.config.Namespace),
+		MetricData: []*cloudwatch.MetricDatum{{
+			MetricName: to.Strp(name),
+			Value:      &value,
+			Unit:       to.Strp(unit),
+			Dimensions: c.dimensions(labels),
+		}},
+	})
+
+	if err != nil {
+		fmt.Printf("IGNORED: PutMetricData %v %v \n", name, err.Error())
+	}
+}
+
+// dimensions returns the configured labels the metric has as dimensions
+func (c *CloudWatch) dimensions(labels map[string]string) []*cloudwatch.Dimension {
+	dimensions := []*cloudwatch.Dimension{}
+	for _, key := range c.config.Dimensions {
+		value, ok := labels[key]
+		if !ok || value == "" {
+			continue
+		}
+
+		dimensions = append(dimensions, &cloudwatch.Dimension{Name: to.Strp(key), Value: to.Strp(value)})
+	}
+
+	return dimensions
+}
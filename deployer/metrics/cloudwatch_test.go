@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func dimensionsMap(datum *cloudwatch.MetricDatum) map[string]string {
+	dims := map[string]string{}
+	for _, d := range datum.Dimensions {
+		dims[*d.Name] = *d.Value
+	}
+	return dims
+}
+
+func Test_CloudWatch(t *testing.T) {
+	cwc := &mocks.CWClient{}
+	c := NewCloudWatch(cwc, CloudWatchConfig{
+		Namespace:  "Acme/Deploys",
+		Dimensions: []string{LabelProject, LabelConfig, LabelReleaseUUID, LabelOutcome},
+	})
+
+	labels := map[string]string{LabelProject: "coinbase/deploy-test", LabelConfig: "development", LabelOutcome: OutcomeSuccess, LabelReleaseUUID: "release-1"}
+	c.IncCounter(DeploysTotal, labels)
+	c.ObserveHistogram(DeployDuration, 1.5, labels)
+
+	assert.Equal(t, 2, len(cwc.PutMetricDataInputs))
+
+	counter := cwc.PutMetricDataInputs[0]
+	assert.Equal(t, "Acme/Deploys", *counter.Namespace)
+	assert.Equal(t, DeploysTotal, *counter.MetricData[0].MetricName)
+	assert.Equal(t, float64(1), *counter.MetricData[0].Value)
+	assert.Equal(t, cloudwatch.StandardUnitCount, *counter.MetricData[0].Unit)
+	assert.Equal(t, labels, dimensionsMap(counter.MetricData[0]))
+
+	// Dimensions are in the configured order
+	assert.Equal(t, LabelProject, *counter.MetricData[0].Dimensions[0].Name)
+	assert.Equal(t, LabelReleaseUUID, *counter.MetricData[0].Dimensions[2].Name)
+
+	histogram := cwc.PutMetricDataInputs[1]
+	assert.Equal(t, float64(1.5), *histogram.MetricData[0].Value)
+	assert.Equal(t, cloudwatch.StandardUnitSeconds, *histogram.MetricData[0].Unit)
+
+	// The release_uuid is only labelled when configured
+	assert.False(t, c.LabelReleaseUUID())
+	var _ ReleaseLabeled = c
+}
+
+func Test_CloudWatch_Defaults(t *testing.T) {
+	cwc := &mocks.CWClient{}
+	c := NewCloudWatch(cwc, CloudWatchConfig{ReleaseUUID: true})
+	assert.True(t, c.LabelReleaseUUID())
+
+	// Labels not in the dimensions are left off
+	c.IncCounter(DeploysTotal, map[string]string{LabelProject: "project", LabelConfig: "config", LabelOutcome: OutcomeTimeout, LabelReleaseUUID: "release-1", "other": "x"})
+
+	datum := cwc.PutMetricDataInputs[0].MetricData[0]
+	assert.Equal(t, DefaultNamespace, *cwc.PutMetricDataInputs[0].Namespace)
+	assert.Equal(t, map[string]string{LabelProject: "project", LabelConfig: "config", LabelOutcome: OutcomeTimeout}, dimensionsMap(datum))
+
+	// Failing to publish is ignored
+	cwc.AddThrottles("PutMetricData", 1)
+	c.IncCounter(DeploysTotal, nil)
+	assert.Equal(t, 1, len(cwc.PutMetricDataInputs))
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Metric names, labelled Prometheus style
+const (
+	DeploysTotal        = "odin_deploys_total"                 // counter by project, config and outcome
+	DeployDuration      = "odin_deploy_duration_seconds"       // histogram by project, config and outcome
+	DeployPhaseDuration = "odin_deploy_phase_duration_seconds" // histogram by project, config and phase
+)
+
+// Deploy outcomes
+const (
+	OutcomeSuccess      = "success"
+	OutcomeCleanFailure = "clean_failure"
+	OutcomeTimeout      = "timeout"
+	OutcomeDirtyFailure = "dirty_failure"
+)
+
+// Deploy phases
+const (
+	PhaseDeploy         = "Deploy"
+	PhaseWaitForHealthy = "WaitForHealthy"
+)
+
+// Metrics receives the deployers counters and histograms, e.g. to export them to Prometheus
+// Implementations must be safe to call concurrently and should never block the deploy
+type Metrics interface {
+	IncCounter(name string, labels map[string]string)
+	ObserveHistogram(name string, value float64, labels map[string]string)
+}
+
+// Nop ignores every metric, it is the default
+type Nop struct{}
+
+// IncCounter does nothing
+func (Nop) IncCounter(string, map[string]string) {}
+
+// ObserveHistogram does nothing
+func (Nop) ObserveHistogram(string, float64, map[string]string) {}
+
+// Memory keeps every metric in memory keyed by Key
+type Memory struct {
+	mu sync.Mutex
+
+	Counters   map[string]float64
+	Histograms map[string][]float64
+}
+
+// NewMemory returns an empty Memory
+func NewMemory() *Memory {
+	return &Memory{Counters: map[string]float64{}, Histograms: map[string][]float64{}}
+}
+
+// IncCounter adds one to the counter
+func (m *Memory) IncCounter(name string, labels map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Counters[Key(name, labels)]++
+}
+
+// ObserveHistogram records the value
+func (m *Memory) ObserveHistogram(name string, value float64, labels map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := Key(name, labels)
+	m.Histograms[key] = append(m.Histograms[key], value)
+}
+
+// Counter returns the counters value
+func (m *Memory) Counter(name string, labels map[string]string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Counters[Key(name, labels)]
+}
+
+// Observations returns the values recorded by the histogram
+func (m *Memory) Observations(name string, labels map[string]string) []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Histograms[Key(name, labels)]
+}
+
+// Key returns the metric in the Prometheus text format with its labels sorted,
+// e.g. odin_deploys_total{config="development",outcome="success"}
+func Key(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+
+	keys := []string{}
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%v=%q", k, labels[k]))
+	}
+
+	return fmt.Sprintf("%v{%v}", name, strings.Join(pairs, ","))
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Key(t *testing.T) {
+	assert.Equal(t, "odin_deploys_total", Key(DeploysTotal, nil))
+	assert.Equal(t,
+		`odin_deploys_total{config="development",outcome="success",project="coinbase/deploy-test"}`,
+		Key(DeploysTotal, map[string]string{"project": "coinbase/deploy-test", "outcome": "success", "config": "development"}),
+	)
+}
+
+func Test_Memory(t *testing.T) {
+	m := NewMemory()
+	labels := map[string]string{"outcome": OutcomeSuccess}
+
+	m.IncCounter(DeploysTotal, labels)
+	m.IncCounter(DeploysTotal, labels)
+	m.ObserveHistogram(DeployDuration, 1.5, labels)
+
+	assert.Equal(t, float64(2), m.Counter(DeploysTotal, labels))
+	assert.Equal(t, float64(0), m.Counter(DeploysTotal, map[string]string{"outcome": OutcomeTimeout}))
+	assert.Equal(t, []float64{1.5}, m.Observations(DeployDuration, labels))
+
+	// Nop satisfies Metrics
+	var _ Metrics = Nop{}
+}
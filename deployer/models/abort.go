@@ -0,0 +1,237 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Abort
+//////////
+
+// AbortInput is the release of an interrupted execution to clean up
+type AbortInput struct {
+	Release
+}
+
+// AbortResult is what Abort cleaned up
+type AbortResult struct {
+	ProjectName *string `json:"project_name,omitempty"`
+	ConfigName  *string `json:"config_name,omitempty"`
+	ReleaseID   *string `json:"release_id,omitempty"`
+	UUID        *string `json:"uuid,omitempty"`
+
+	CleanedUp *FailureCleanUp `json:"cleaned_up"`
+
+	// The release still held the project config lock, and it was released
+	Unlocked bool `json:"unlocked"`
+}
+
+// ValidateAbort validates the release to abort
+func (release *Release) ValidateAbort() error {
+	if is.EmptyStr(release.ProjectName) {
+		return fmt.Errorf("ProjectName must be defined")
+	}
+
+	if is.EmptyStr(release.ConfigName) {
+		return fmt.Errorf("ConfigName must be defined")
+	}
+
+	if is.EmptyStr(release.ReleaseID) {
+		return fmt.Errorf("ReleaseID must be defined")
+	}
+
+	if is.EmptyStr(release.UUID) {
+		return fmt.Errorf("UUID must be defined")
+	}
+
+	if is.EmptyStr(release.Bucket) {
+		return fmt.Errorf("Bucket must be defined")
+	}
+
+	return release.ValidateDeployRoleARN()
+}
+
+// Abort cleans up after a release whose execution was stopped mid-deploy, like CleanUpFailure and ReleaseLockFailure.
+// The new ASGs, launch templates and configurations tagged with the UUID are deleted, the previous ASGs resume
+// their suspended processes and the locks are released if the release still holds them. It is a no-op for a
+// release that is already clean. Abort refuses a release with a RUNNING execution, and one whose clean up needs
+// state only the execution had: an InstanceRefresh or in place deploy, or a DNS cutover
+func (release *Release) Abort(s3c aws.S3API, asgc aws.ASGAPI, ec2c aws.EC2API, cwc aws.CWAPI, r53c aws.Route53API, sfnc aws.SFNAPI, dynamodbc aws.DynamoDBAPI, lockTableName string, stateMachineArn *string) (*AbortResult, error) {
+	live, err := release.liveReleaseIDs(sfnc, stateMachineArn)
+	if err != nil {
+		return nil, fmt.Errorf("Abort cannot check executions: %v", err.Error())
+	}
+
+	if live[*release.ReleaseID] {
+		return nil, fmt.Errorf("Abort release %v has a RUNNING execution, stop it first", *release.ReleaseID)
+	}
+
+	aborted, err := release.abortedRelease(s3c)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := aborted.validateAbortable(s3c, asgc, r53c); err != nil {
+		return nil, err
+	}
+
+	if err := aborted.UnsuccessfulTearDown(asgc, ec2c, cwc); err != nil {
+		return nil, err
+	}
+
+	if err := aborted.resumePreviousASGs(asgc); err != nil {
+		return nil, err
+	}
+
+	unlocked, err := aborted.abortLocks(s3c, dynamodbc, lockTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AbortResult{
+		ProjectName: aborted.ProjectName,
+		ConfigName:  aborted.ConfigName,
+		ReleaseID:   aborted.ReleaseID,
+		UUID:        aborted.UUID,
+		CleanedUp:   aborted.cleanedUp,
+		Unlocked:    unlocked,
+	}, nil
+}
+
+// abortedRelease returns the release uploaded to S3 with the inputs UUID, it has the services and locks to clean up.
+// A release that was never uploaded has only its tagged resources and the project config lock cleaned up
+func (release *Release) abortedRelease(s3c aws.S3API) (*Release, error) {
+	aborted := &Release{}
+	if err := s3.GetStruct(s3c, release.Bucket, release.ReleasePath(), aborted); err != nil {
+		switch err.(type) {
+		case *s3.NotFoundError:
+			aborted = &Release{}
+		default:
+			return nil, err
+		}
+	}
+
+	aborted.AwsRegion = release.AwsRegion
+	aborted.AwsAccountID = release.AwsAccountID
+	aborted.Bucket = release.Bucket
+	aborted.ProjectName = release.ProjectName
+	aborted.ConfigName = release.ConfigName
+	aborted.ReleaseID = release.ReleaseID
+	aborted.UUID = release.UUID
+	aborted.DeployRoleARN = release.DeployRoleARN
+
+	aborted.SetDefaults()
+	return aborted, nil
+}
+
+// validateAbortable errors if cleaning up the release would need state only its execution had
+func (release *Release) validateAbortable(s3c aws.S3API, asgc aws.ASGAPI, r53c aws.Route53API) error {
+	if release.IsInstanceRefresh() {
+		return fmt.Errorf("Abort cannot restore the launch configurations of the %v deploy strategy", DeployInstanceRefresh)
+	}
+
+	if release.DNS != nil {
+		current, _, err := release.dnsRecords(r53c)
+		if err != nil {
+			return err
+		}
+
+		if current != nil {
+			return fmt.Errorf("Abort cannot revert DNS %v, it was cut over to release %v", to.Strs(release.DNS.RecordName), *release.ReleaseID)
+		}
+	}
+
+	var current DeployResult
+	if err := s3.GetStruct(s3c, release.Bucket, release.CurrentDeployResultPath(), &current); err != nil {
+		switch err.(type) {
+		case *s3.NotFoundError:
+			return nil // First release of the project config
+		default:
+			return err
+		}
+	}
+
+	serving := map[string]bool{}
+	for _, service := range current.Services {
+		if service != nil && service.AutoScalingGroupName != nil {
+			serving[*service.AutoScalingGroupName] = true
+		}
+	}
+
+	// An in place deploy tags the serving ASGs with its UUID
+	asgs, err := asg.ForProjectConfigReleaseUUID(asgc, release.ProjectName, release.ConfigName, release.UUID)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range asgs {
+		if serving[to.Strs(group.AutoScalingGroupName)] {
+			return fmt.Errorf("Abort ASG %v is serving the current release, it was updated in place", *group.AutoScalingGroupName)
+		}
+	}
+
+	return nil
+}
+
+// resumePreviousASGs resumes the processes Deploy suspended on each services previous ASG
+func (release *Release) resumePreviousASGs(asgc aws.ASGAPI) error {
+	prevASGs, err := asg.ForProjectConfigNotReleaseIDServiceMap(asgc, release.ProjectName, release.ConfigName, release.ReleaseID)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedServiceNames(release) {
+		prev := prevASGs[name]
+		if prev == nil {
+			continue
+		}
+
+		if err := asg.ResumeProcesses(asgc, prev.AutoScalingGroupName, release.Services[name].suspendProcesses()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// abortLocks releases the project config, MutexGroup and ProjectConcurrency locks if the release still holds the
+// project config lock, and removes the halt flag. It returns true if the locks were held
+func (release *Release) abortLocks(s3c aws.S3API, dynamodbc aws.DynamoDBAPI, lockTableName string) (bool, error) {
+	release.RemoveHalt(s3c)
+
+	var lock s3.Lock
+	if err := s3.GetStruct(s3c, release.Bucket, release.RootLockPath(), &lock); err != nil {
+		switch err.(type) {
+		case *s3.NotFoundError:
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	if lock.UUID != *release.UUID {
+		return false, nil // Already released, possibly grabbed by another release
+	}
+
+	locker := release.Locker(s3c, dynamodbc)
+	if err := release.unlockConfigLocks(s3c, locker, lockTableName); err != nil {
+		return false, err
+	}
+
+	if release.ProjectConcurrency != nil {
+		// Which slot the release held is lost with its execution, the slots of other releases refuse to be released
+		for slot := 0; slot < *release.ProjectConcurrency; slot++ {
+			if err := release.releaseProjectSlot(locker, lockTableName, slot); err != nil {
+				fmt.Printf("IGNORED: ProjectConcurrency slot %v: %v \n", slot, err.Error())
+			}
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockAbortRelease(t *testing.T) (*Release, *mocks.MockClients) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+	awsc.ASG.TrackCreated = true
+
+	// The client uploads the release before starting the execution
+	assert.NoError(t, s3.PutStruct(awsc.S3, release.Bucket, release.ReleasePath(), release))
+
+	locker := release.Locker(awsc.S3, awsc.DynamoDB)
+	assert.NoError(t, release.GrabLocks(awsc.S3, locker, "locks"))
+
+	return release, awsc
+}
+
+func mockAbortInput(release *Release) *AbortInput {
+	input := &AbortInput{}
+	input.AwsRegion = release.AwsRegion
+	input.AwsAccountID = release.AwsAccountID
+	input.Bucket = release.Bucket
+	input.ProjectName = release.ProjectName
+	input.ConfigName = release.ConfigName
+	input.ReleaseID = release.ReleaseID
+	input.UUID = release.UUID
+	return input
+}
+
+func abort(input *AbortInput, awsc *mocks.MockClients) (*AbortResult, error) {
+	return input.Abort(awsc.S3, awsc.ASG, awsc.EC2, awsc.CW, awsc.Route53, awsc.SFN, awsc.DynamoDB, "locks", to.Strp("arn:sm"))
+}
+
+func Test_Release_ValidateAbort(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	input := mockAbortInput(release)
+	assert.NoError(t, input.ValidateAbort())
+
+	input.UUID = nil
+	assert.EqualError(t, input.ValidateAbort(), "UUID must be defined")
+
+	input = mockAbortInput(release)
+	input.ReleaseID = to.Strp("")
+	assert.EqualError(t, input.ValidateAbort(), "ReleaseID must be defined")
+}
+
+func Test_Release_Abort_After_Deploy(t *testing.T) {
+	release, awsc := mockAbortRelease(t)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	release.UpdateWithResources(resources)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	created := *release.Services["web"].CreatedASG
+	assert.NotNil(t, awsc.ASG.SuspendedProcesses["project-config-web-old-release"])
+
+	// The execution was stopped
+	result, err := abort(mockAbortInput(release), awsc)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{created}, to.StrSlice(result.CleanedUp.AutoScalingGroups))
+	assert.True(t, result.Unlocked)
+
+	// The old fleet is kept and scales again
+	assert.Equal(t, 1, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Nil(t, awsc.ASG.SuspendedProcesses["project-config-web-old-release"])
+
+	// The lock is released
+	other := MockRelease(t)
+	other.ReleaseID = to.Strp("other-release")
+	MockPrepareRelease(other)
+	assert.NoError(t, other.GrabLocks(awsc.S3, other.Locker(awsc.S3, awsc.DynamoDB), "locks"))
+
+	// Aborting again is a no-op and never releases the other releases lock
+	result, err = abort(mockAbortInput(release), awsc)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(result.CleanedUp.AutoScalingGroups))
+	assert.False(t, result.Unlocked)
+	assert.Equal(t, 1, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, *other.UUID, awsc.DynamoDB.Locks[*release.RootLockPath()])
+}
+
+func Test_Release_Abort_Before_Deploy(t *testing.T) {
+	release, awsc := mockAbortRelease(t)
+
+	result, err := abort(mockAbortInput(release), awsc)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(result.CleanedUp.AutoScalingGroups))
+	assert.Equal(t, 0, len(result.CleanedUp.LaunchConfigurations))
+	assert.True(t, result.Unlocked)
+	assert.Equal(t, 0, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, 0, len(awsc.DynamoDB.Locks))
+}
+
+func Test_Release_Abort_Refuses(t *testing.T) {
+	t.Run("running", func(t *testing.T) {
+		release, awsc := mockAbortRelease(t)
+		awsc.SFN.AddExecution("arn:this", release.ExecutionPrefix()+"this", "RUNNING", map[string]string{"release_id": *release.ReleaseID})
+
+		_, err := abort(mockAbortInput(release), awsc)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "has a RUNNING execution")
+		assert.Equal(t, *release.UUID, awsc.DynamoDB.Locks[*release.RootLockPath()])
+	})
+
+	t.Run("in place", func(t *testing.T) {
+		release, awsc := mockAbortRelease(t)
+		assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+		// The ASG tagged with the UUID is the one serving
+		current := &DeployResult{Services: map[string]*ServiceResult{
+			"web": &ServiceResult{AutoScalingGroupName: release.Services["web"].CreatedASG},
+		}}
+		assert.NoError(t, s3.PutStruct(awsc.S3, release.Bucket, release.CurrentDeployResultPath(), current))
+
+		_, err := abort(mockAbortInput(release), awsc)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "is serving the current release")
+		assert.Equal(t, 0, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	})
+
+	t.Run("instance refresh", func(t *testing.T) {
+		release := MockRelease(t)
+		release.DeployStrategy = to.Strp(DeployInstanceRefresh)
+		MockPrepareRelease(release)
+		awsc := MockAwsClients(release)
+		assert.NoError(t, s3.PutStruct(awsc.S3, release.Bucket, release.ReleasePath(), release))
+
+		_, err := abort(mockAbortInput(release), awsc)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), DeployInstanceRefresh)
+	})
+}
@@ -0,0 +1,245 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/ssm"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Alerts
+//////////
+
+// ALERT_FORMATS are the formats of the messages published to the NotificationTopicARN
+// "json" is the Notification, "text" a human readable summary and "slack" a Slack message payload
+var ALERT_FORMATS = []string{"json", "text", "slack"}
+
+// maxAlertErrorLength is the most characters of the error in a summary
+const maxAlertErrorLength = 500
+
+// ssmWebhookPrefix marks an AlertWebhookURL that is an SSM parameter storing the URL
+const ssmWebhookPrefix = "ssm:"
+
+// failureStates clean up after a failure, they are never the state that failed
+var failureStates = []string{"DetachForFailure", "CleanUpFailure", "CancelRefresh", "ReleaseLockFailure", "NotifyFailure", "NotifyFailureDirty"}
+
+// ValidateAlerts validates the AlertFormat and AlertWebhookURL
+func (release *Release) ValidateAlerts() error {
+	if release.AlertFormat != nil && !containsStr(ALERT_FORMATS, *release.AlertFormat) {
+		return fmt.Errorf("AlertFormat %q must be one of %v", *release.AlertFormat, ALERT_FORMATS)
+	}
+
+	if release.AlertWebhookURL == nil {
+		return nil
+	}
+
+	url := *release.AlertWebhookURL
+	switch {
+	case strings.HasPrefix(url, ssmWebhookPrefix) && len(url) > len(ssmWebhookPrefix):
+		return nil
+	case strings.HasPrefix(url, "https://") && len(url) > len("https://"):
+		return nil
+	}
+
+	return fmt.Errorf("AlertWebhookURL must be an https:// URL or an ssm: parameter")
+}
+
+// alertFormat returns the AlertFormat, a bad format is "json" so a failure caused by it still alerts
+func (release *Release) alertFormat() string {
+	if release.AlertFormat == nil || !containsStr(ALERT_FORMATS, *release.AlertFormat) {
+		return "json"
+	}
+	return *release.AlertFormat
+}
+
+// FailedState returns the state the release failed in. The execution path only records the states that
+// completed, so it is the state after the last one to complete, or that state if it was polling
+func (release *Release) FailedState() string {
+	last := ""
+	for _, state := range release.ExecutionPath {
+		if !containsStr(failureStates, state) {
+			last = state
+		}
+	}
+
+	switch last {
+	case "":
+		return "Validate"
+	case "Validate":
+		if release.ValidateOnly {
+			return "ValidateResources"
+		}
+		return "Lock"
+	case "Lock":
+		return "ValidateResources"
+	case "ValidateResources":
+		return "PreDeployHook"
+	case "PreDeployHook":
+		return "Deploy"
+	case "Deploy":
+		if release.IsInstanceRefresh() {
+			return "CheckRefresh"
+		}
+		if release.Canaried != nil && !*release.Canaried {
+			return "CheckCanary"
+		}
+		return "CheckHealthy"
+	case "CheckCanary":
+		if release.Canaried != nil && !*release.Canaried {
+			return "CheckCanary"
+		}
+		return "CheckHealthy"
+	case "CheckHealthy":
+		if release.Healthy != nil && *release.Healthy {
+			return "SmokeTest"
+		}
+		return "CheckHealthy"
+	case "SmokeTest":
+		return "CutoverDNS"
+	case "CutoverDNS":
+		return "Soak"
+	}
+
+	return last
+}
+
+// executionsURL returns the console page of the state machines executions
+func executionsURL(region *string, stateMachineArn *string) *string {
+	if region == nil || stateMachineArn == nil {
+		return nil
+	}
+
+	s := fmt.Sprintf("https://console.aws.amazon.com/states/home?region=%v#/statemachines/view/%v", *region, *stateMachineArn)
+	return &s
+}
+
+// Summary returns the notification as human readable lines
+func (n *Notification) Summary() string {
+	lines := []string{fmt.Sprintf("Deploy %v: %v/%v release %v", n.Event, to.Strs(n.ProjectName), to.Strs(n.ConfigName), to.Strs(n.ReleaseID))}
+
+	if n.FailedState != nil {
+		lines = append(lines, fmt.Sprintf("State: %v", *n.FailedState))
+	}
+
+	if n.Error != nil {
+		lines = append(lines, fmt.Sprintf("Error: %v", truncate(fmt.Sprintf("%v: %v", to.Strs(n.Error.Error), to.Strs(n.Error.Cause)), maxAlertErrorLength)))
+	}
+
+	if len(n.Path) > 0 {
+		lines = append(lines, fmt.Sprintf("Path: %v", strings.Join(n.Path, " -> ")))
+
+		if n.Path[len(n.Path)-1] == "FailureDirty" {
+			lines = append(lines, "Resources were left behind and must be cleaned up")
+		}
+	}
+
+	if n.ExecutionsURL != nil {
+		lines = append(lines, fmt.Sprintf("Execution: %v", *n.ExecutionsURL))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// SlackMessage returns the notification as a Slack message payload
+func (n *Notification) SlackMessage() (string, error) {
+	lines := strings.Split(n.Summary(), "\n")
+	lines[0] = fmt.Sprintf("*%v*", lines[0])
+
+	raw, err := json.Marshal(map[string]string{"text": strings.Join(lines, "\n")})
+	if err != nil {
+		return "", err
+	}
+
+	return string(raw), nil
+}
+
+// message returns the notification in the releases AlertFormat
+func (release *Release) message(n *Notification) (string, error) {
+	switch release.alertFormat() {
+	case "text":
+		return n.Summary(), nil
+	case "slack":
+		return n.SlackMessage()
+	}
+
+	raw, err := json.Marshal(n)
+	if err != nil {
+		return "", err
+	}
+
+	return string(raw), nil
+}
+
+// Alert publishes the failure ending in endState, FailureClean or FailureDirty, to the NotificationTopicARN and posts
+// it to the AlertWebhookURL as a Slack message. The release may have failed Validate, so both are attempted and the first error returned
+func (release *Release) Alert(snsc aws.SNSAPI, ssmc aws.SSMAPI, httpc aws.HTTPAPI, stateMachineArn *string, endState string) error {
+	n := release.notification(NotifyFailed, endState)
+	n.FailedState = to.Strp(release.FailedState())
+	n.ExecutionsURL = executionsURL(release.AwsRegion, stateMachineArn)
+
+	publishErr := release.publish(snsc, n)
+	if err := release.postWebhook(ssmc, httpc, n); err != nil {
+		return err
+	}
+
+	return publishErr
+}
+
+// postWebhook posts the notification as a Slack message to the AlertWebhookURL
+func (release *Release) postWebhook(ssmc aws.SSMAPI, httpc aws.HTTPAPI, n *Notification) error {
+	if release.AlertWebhookURL == nil {
+		return nil
+	}
+
+	// Failed releases may not be validated
+	if err := release.ValidateAlerts(); err != nil {
+		return err
+	}
+
+	url := release.AlertWebhookURL
+	if strings.HasPrefix(*url, ssmWebhookPrefix) {
+		value, err := ssm.GetSecureParameter(ssmc, to.Strp(strings.TrimPrefix(*url, ssmWebhookPrefix)))
+		if err != nil {
+			return fmt.Errorf("AlertWebhookURL %v", err.Error())
+		}
+		url = value
+	}
+
+	body, err := n.SlackMessage()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, *url, bytes.NewBufferString(body))
+	if err != nil {
+		return fmt.Errorf("AlertWebhookURL is not a valid URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpc.Do(req)
+	if err != nil {
+		// The error includes the URL which is a secret
+		return fmt.Errorf("AlertWebhookURL request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("AlertWebhookURL responded %v", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
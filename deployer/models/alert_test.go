@@ -0,0 +1,141 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/step/bifrost"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidateAlerts(t *testing.T) {
+	r := MockRelease(t)
+	assert.NoError(t, r.ValidateAlerts())
+
+	r.AlertFormat = to.Strp("slack")
+	r.AlertWebhookURL = to.Strp("https://hooks.example.com/odin")
+	assert.NoError(t, r.ValidateAlerts())
+
+	r.AlertWebhookURL = to.Strp("ssm:/odin/webhook")
+	assert.NoError(t, r.ValidateAlerts())
+
+	r.AlertWebhookURL = to.Strp("http://hooks.example.com/odin")
+	assert.Error(t, r.ValidateAlerts())
+
+	r.AlertWebhookURL = to.Strp("ssm:")
+	assert.Error(t, r.ValidateAlerts())
+
+	r.AlertWebhookURL = nil
+	r.AlertFormat = to.Strp("xml")
+	assert.Error(t, r.ValidateAlerts())
+}
+
+func Test_Release_FailedState(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+
+	assert.Equal(t, "Validate", r.FailedState())
+
+	r.ExecutionPath = []string{"Validate"}
+	assert.Equal(t, "Lock", r.FailedState())
+
+	r.ExecutionPath = []string{"Validate", "Lock", "ValidateResources", "PreDeployHook"}
+	assert.Equal(t, "Deploy", r.FailedState())
+
+	// Polling states failed if they were the last to complete
+	r.ExecutionPath = []string{"Validate", "Lock", "ValidateResources", "PreDeployHook", "Deploy", "CheckHealthy", "DetachForFailure", "CleanUpFailure"}
+	assert.Equal(t, "CheckHealthy", r.FailedState())
+
+	r.Healthy = to.Boolp(true)
+	assert.Equal(t, "SmokeTest", r.FailedState())
+
+	r.ExecutionPath = []string{"Validate", "Lock", "ValidateResources", "PreDeployHook", "Deploy"}
+	assert.Equal(t, "CheckHealthy", r.FailedState())
+
+	// A canary that is not baked failed in CheckCanary
+	r.Canaried = to.Boolp(false)
+	assert.Equal(t, "CheckCanary", r.FailedState())
+
+	r.ExecutionPath = []string{"Validate", "Lock", "ValidateResources", "PreDeployHook", "Deploy", "CheckCanary"}
+	assert.Equal(t, "CheckCanary", r.FailedState())
+
+	r.Canaried = to.Boolp(true)
+	assert.Equal(t, "CheckHealthy", r.FailedState())
+
+	r.ExecutionPath = []string{"Validate"}
+	r.ValidateOnly = true
+	assert.Equal(t, "ValidateResources", r.FailedState())
+}
+
+func Test_Notification_Summary(t *testing.T) {
+	r := MockRelease(t)
+	r.ExecutionPath = []string{"Validate", "Lock"}
+	r.Error = &bifrost.ReleaseError{Error: to.Strp("HaltError"), Cause: to.Strp(strings.Repeat("x", 1000))}
+
+	n := r.notification(NotifyFailed, "FailureClean")
+	n.FailedState = to.Strp("ValidateResources")
+	n.ExecutionsURL = executionsURL(r.AwsRegion, to.Strp("arn:aws:states:us-east-1:000000:stateMachine:coinbase-odin"))
+
+	lines := strings.Split(n.Summary(), "\n")
+	assert.Equal(t, 5, len(lines))
+	assert.Equal(t, "Deploy failed: project/config release 1", lines[0])
+	assert.Equal(t, "State: ValidateResources", lines[1])
+
+	// The error is truncated
+	assert.Equal(t, len("Error: ")+maxAlertErrorLength, len(lines[2]))
+	assert.True(t, strings.HasSuffix(lines[2], "..."))
+
+	assert.Equal(t, "Path: Validate -> Lock -> FailureClean", lines[3])
+	assert.Equal(t, "Execution: https://console.aws.amazon.com/states/home?region=us-east-1#/statemachines/view/arn:aws:states:us-east-1:000000:stateMachine:coinbase-odin", lines[4])
+
+	slack, err := n.SlackMessage()
+	assert.NoError(t, err)
+
+	var payload map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(slack), &payload))
+	assert.True(t, strings.HasPrefix(payload["text"], "*Deploy failed: project/config release 1*\nState: ValidateResources\n"))
+}
+
+func Test_Release_Alert(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	awsc := MockAwsClients(r)
+
+	// Nothing to alert
+	assert.NoError(t, r.Alert(awsc.SNS, awsc.SSM, awsc.HTTP, nil, "FailureClean"))
+	assert.Equal(t, 0, len(awsc.SNS.PublishInputs))
+	assert.Equal(t, 0, len(awsc.HTTP.Requests))
+
+	// A bad format falls back to JSON so the failure is still published
+	r.NotificationTopicARN = to.Strp("arn:aws:sns:us-east-1:000000:deploys")
+	r.AlertFormat = to.Strp("xml")
+	assert.NoError(t, r.Alert(awsc.SNS, awsc.SSM, awsc.HTTP, nil, "FailureClean"))
+
+	var n Notification
+	assert.NoError(t, json.Unmarshal([]byte(awsc.SNS.Messages()[0]), &n))
+	assert.Equal(t, NotifyFailed, n.Event)
+	assert.Equal(t, "Validate", *n.FailedState)
+
+	// The webhook must respond with success
+	r.AlertFormat = nil
+	r.AlertWebhookURL = to.Strp("https://hooks.example.com/odin")
+	awsc.HTTP.SetStatus("https://hooks.example.com/odin", 500)
+	err := r.Alert(awsc.SNS, awsc.SSM, awsc.HTTP, nil, "FailureClean")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "AlertWebhookURL responded 500")
+	assert.Equal(t, 2, len(awsc.SNS.PublishInputs))
+
+	// The webhook URL is never in an error
+	r.AlertWebhookURL = to.Strp("https://hooks.example.com/unknown")
+	err = r.Alert(awsc.SNS, awsc.SSM, awsc.HTTP, nil, "FailureClean")
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "hooks.example.com")
+
+	r.AlertWebhookURL = to.Strp("ssm:/odin/missing")
+	err = r.Alert(awsc.SNS, awsc.SSM, awsc.HTTP, nil, "FailureClean")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "SSM parameter /odin/missing not found")
+	assert.True(t, *awsc.SSM.GetParameterInputs[0].WithDecryption)
+}
@@ -9,13 +9,18 @@ import (
 
 // AutoScalingConfig struct
 type AutoScalingConfig struct {
-	MinSize                *int64    `json:"min_size,omitempty"`
-	MaxSize                *int64    `json:"max_size,omitempty"`
-	MaxTerminations        *int64    `json:"max_terms,omitempty"`
-	DefaultCooldown        *int64    `json:"default_cooldown,omitempty"`
-	HealthCheckGracePeriod *int64    `json:"health_check_grace_period,omitempty"`
-	Spread                 *float64  `json:"spread,omitempty"`
-	Policies               []*Policy `json:"policies,omitempty"`
+	MinSize         *int64 `json:"min_size,omitempty"`
+	MaxSize         *int64 `json:"max_size,omitempty"`
+	DesiredCapacity *int64 `json:"desired_capacity,omitempty"` // Overrides the previous services capacity
+	MaxTerminations *int64 `json:"max_terms,omitempty"`
+	// Crash loop is detected when terminations per instance is greater than this
+	MaxTerminationsPerInstance *float64  `json:"max_terms_per_instance,omitempty"`
+	DefaultCooldown            *int64    `json:"default_cooldown,omitempty"`
+	HealthCheckGracePeriod     *int64    `json:"health_check_grace_period,omitempty"`
+	Spread                     *float64  `json:"spread,omitempty"`
+	Policies                   []*Policy `json:"policies,omitempty"`
+
+	ScheduledActions []*ScheduledAction `json:"scheduled_actions,omitempty"`
 
 	Strategy *string `json:"strategy,omitempty"`
 }
@@ -46,10 +51,22 @@ func (a *AutoScalingConfig) ValidateAttributes() error {
 		return fmt.Errorf("Autoscaling MinSize is Greater than MaxSize")
 	}
 
+	if a.DesiredCapacity != nil && (*a.DesiredCapacity < *a.MinSize || *a.DesiredCapacity > *a.MaxSize) {
+		return fmt.Errorf("Autoscaling DesiredCapacity %v must be between MinSize %v and MaxSize %v", *a.DesiredCapacity, *a.MinSize, *a.MaxSize)
+	}
+
 	if *a.Spread < 0 || *a.Spread > 1 {
 		return fmt.Errorf("Spread must be between 0 and 1")
 	}
 
+	if a.MaxTerminationsPerInstance != nil && *a.MaxTerminationsPerInstance <= 0 {
+		return fmt.Errorf("MaxTerminationsPerInstance must be greater than 0")
+	}
+
+	if a.DefaultCooldown != nil && *a.DefaultCooldown < 0 {
+		return fmt.Errorf("DefaultCooldown must not be negative")
+	}
+
 	policyNames := []*string{}
 
 	for _, p := range a.Policies {
@@ -68,6 +85,10 @@ func (a *AutoScalingConfig) ValidateAttributes() error {
 		return fmt.Errorf("Policy Names not Unique")
 	}
 
+	if err := a.validateScheduledActions(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -13,6 +13,24 @@ func Test_Autoscaling_Valid(t *testing.T) {
 	assert.NoError(t, asg.ValidateAttributes())
 }
 
+func Test_Autoscaling_DesiredCapacity(t *testing.T) {
+	asg := &AutoScalingConfig{MinSize: to.Int64p(2), MaxSize: to.Int64p(5)}
+	asg.SetDefaults(nil, nil)
+	assert.NoError(t, asg.ValidateAttributes())
+
+	asg.DesiredCapacity = to.Int64p(2)
+	assert.NoError(t, asg.ValidateAttributes())
+
+	asg.DesiredCapacity = to.Int64p(5)
+	assert.NoError(t, asg.ValidateAttributes())
+
+	asg.DesiredCapacity = to.Int64p(1)
+	assert.Error(t, asg.ValidateAttributes())
+
+	asg.DesiredCapacity = to.Int64p(6)
+	assert.Error(t, asg.ValidateAttributes())
+}
+
 func Test_PolicyNames_Uniq(t *testing.T) {
 	asg := &AutoScalingConfig{
 		Policies: []*Policy{
@@ -48,3 +66,19 @@ func Test_Autoscaling_HealthCheckGracePeriod(t *testing.T) {
 	asg.SetDefaults(nil, to.Intp(2000))
 	assert.Equal(t, *asg.HealthCheckGracePeriod, int64(100))
 }
+
+func Test_Autoscaling_DesiredCapacity_CreateInput(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].Autoscaling.MinSize = to.Int64p(2)
+	release.Services["web"].Autoscaling.MaxSize = to.Int64p(10)
+	release.Services["web"].Autoscaling.DesiredCapacity = to.Int64p(4)
+	release.Services["web"].Autoscaling.Spread = to.Float64p(0)
+	MockPrepareRelease(release)
+	assert.NoError(t, release.Services["web"].Validate())
+
+	// The ASG can grow to MaxSize after the deploy
+	input := release.Services["web"].createInput()
+	assert.Equal(t, int64(2), *input.MinSize)
+	assert.Equal(t, int64(4), *input.DesiredCapacity)
+	assert.Equal(t, int64(10), *input.MaxSize)
+}
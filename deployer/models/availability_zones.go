@@ -0,0 +1,81 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/coinbase/odin/aws/subnet"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Availability Zones
+//////////
+
+// validateAvailabilityZones validates the AvailabilityZones filter
+func (service *Service) validateAvailabilityZones() error {
+	if service.AvailabilityZones == nil {
+		return nil
+	}
+
+	if len(service.AvailabilityZones) == 0 {
+		return fmt.Errorf("AvailabilityZones must not be empty if defined")
+	}
+
+	if !is.UniqueStrp(service.AvailabilityZones) {
+		return fmt.Errorf("AvailabilityZones must be unique")
+	}
+
+	for _, az := range service.AvailabilityZones {
+		if is.EmptyStr(az) {
+			return fmt.Errorf("AvailabilityZones must not contain empty values")
+		}
+	}
+
+	return nil
+}
+
+// filterSubnets returns the subnets in the services AvailabilityZones, all subnets without a filter.
+// The ASG balances capacity evenly across the availability zones of the subnets it is given
+func (service *Service) filterSubnets(subnets []*subnet.Subnet) []*subnet.Subnet {
+	if service.AvailabilityZones == nil {
+		return subnets
+	}
+
+	filtered := []*subnet.Subnet{}
+	for _, sn := range subnets {
+		if sn != nil && sn.AvailabilityZone != nil && containsStrp(service.AvailabilityZones, *sn.AvailabilityZone) {
+			filtered = append(filtered, sn)
+		}
+	}
+
+	return filtered
+}
+
+// validateSubnetAvailabilityZones errors if the filter leaves no subnets, or an availability zone
+// has no subnet so capacity would not be spread across every chosen zone
+func (sr *ServiceResources) validateSubnetAvailabilityZones(service *Service) error {
+	if service.AvailabilityZones == nil {
+		return nil
+	}
+
+	if len(sr.Subnets) == 0 {
+		return fmt.Errorf("AvailabilityZones %v leave no subnets", to.StrSlice(service.AvailabilityZones))
+	}
+
+	for _, az := range service.AvailabilityZones {
+		found := false
+		for _, sn := range sr.Subnets {
+			if sn != nil && sn.AvailabilityZone != nil && *sn.AvailabilityZone == *az {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("AvailabilityZone %v has no subnet", *az)
+		}
+	}
+
+	return nil
+}
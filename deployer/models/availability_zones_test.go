@@ -0,0 +1,89 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockAvailabilityZonesRelease(t *testing.T, azs ...string) (*Release, *mocks.MockClients) {
+	release := MockRelease(t)
+	release.Subnets = []*string{to.Strp("private-subnet-a"), to.Strp("private-subnet-b"), to.Strp("private-subnet-c")}
+	if len(azs) > 0 {
+		release.Services["web"].AvailabilityZones = aws.StringSlice(azs)
+	}
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	awsc.EC2.DescribeSubnetsResp = nil
+	awsc.EC2.AddSubnetInAZ("private-subnet-a", "subnet-a", "us-east-1a")
+	awsc.EC2.AddSubnetInAZ("private-subnet-b", "subnet-b", "us-east-1b")
+	awsc.EC2.AddSubnetInAZ("private-subnet-c", "subnet-c", "us-east-1c")
+
+	return release, awsc
+}
+
+func Test_Service_validateAvailabilityZones(t *testing.T) {
+	service := &Service{}
+	assert.NoError(t, service.validateAvailabilityZones())
+
+	service.AvailabilityZones = aws.StringSlice([]string{"us-east-1a", "us-east-1b"})
+	assert.NoError(t, service.validateAvailabilityZones())
+
+	service.AvailabilityZones = []*string{}
+	assert.Error(t, service.validateAvailabilityZones())
+
+	service.AvailabilityZones = aws.StringSlice([]string{"us-east-1a", "us-east-1a"})
+	assert.Error(t, service.validateAvailabilityZones())
+
+	service.AvailabilityZones = aws.StringSlice([]string{""})
+	assert.Error(t, service.validateAvailabilityZones())
+}
+
+func Test_Release_AvailabilityZones_FiltersSubnets(t *testing.T) {
+	release, awsc := mockAvailabilityZonesRelease(t, "us-east-1a", "us-east-1c")
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+
+	service := release.Services["web"]
+	assert.Equal(t, []string{"subnet-a", "subnet-c"}, to.StrSlice(service.Resources.Subnets))
+	assert.Equal(t, "subnet-a,subnet-c", *service.createInput().VPCZoneIdentifier)
+}
+
+func Test_Release_AvailabilityZones_AllSubnetsWithoutFilter(t *testing.T) {
+	release, awsc := mockAvailabilityZonesRelease(t)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+
+	assert.Equal(t, "subnet-a,subnet-b,subnet-c", *release.Services["web"].createInput().VPCZoneIdentifier)
+}
+
+func Test_Release_AvailabilityZones_NoSubnets(t *testing.T) {
+	release, awsc := mockAvailabilityZonesRelease(t, "us-west-2a")
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "leave no subnets")
+
+	// Every chosen zone needs a subnet to spread capacity across it
+	release, awsc = mockAvailabilityZonesRelease(t, "us-east-1a", "us-west-2a")
+
+	resources, err = release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "us-west-2a has no subnet")
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/coinbase/step/utils/to"
+)
+
+// BLOCK_DEVICE_VOLUME_TYPES are the EBS volume types a block device can have
+var BLOCK_DEVICE_VOLUME_TYPES = []string{"standard", "gp2", "gp3", "io1", "io2", "st1", "sc1"}
+
+// IOPS can only be set on these volume types and must be set on the io types
+var iopsVolumeTypes = map[string]bool{"gp3": false, "io1": true, "io2": true}
+
+// ebsIOPSRanges are the IOPS each volume type supports, and ebsIOPSPerGiB the most IOPS per GiB of volume size
+var ebsIOPSRanges = map[string][2]int64{"gp3": {3000, 16000}, "io1": {100, 64000}, "io2": {100, 64000}}
+var ebsIOPSPerGiB = map[string]int64{"gp3": 500, "io1": 50, "io2": 500}
+
+// gp3 throughput is in MiB/s, and at most a quarter of the IOPS
+const (
+	minGP3Throughput         = 125
+	maxGP3Throughput         = 1000
+	gp3IOPSPerThroughput     = 4
+	defaultGP3IOPS           = 3000
+	rootEBSDeviceNameDefault = "/dev/xvda" // The launch configuration default
+)
+
+// BlockDevice is an extra EBS volume attached to each instance
+type BlockDevice struct {
+	DeviceName          *string `json:"device_name,omitempty"`
+	VolumeSize          *int64  `json:"volume_size,omitempty"` // GiB
+	VolumeType          *string `json:"volume_type,omitempty"` // default gp2
+	IOPS                *int64  `json:"iops,omitempty"`
+	Encrypted           *bool   `json:"encrypted,omitempty"`
+	DeleteOnTermination *bool   `json:"delete_on_termination,omitempty"` // default true
+}
+
+// ValidateAttributes validates attributes
+func (bd *BlockDevice) ValidateAttributes() error {
+	if bd.DeviceName == nil || *bd.DeviceName == "" {
+		return fmt.Errorf("device_name must be defined")
+	}
+
+	if bd.VolumeSize == nil || *bd.VolumeSize < 1 || *bd.VolumeSize > 16384 {
+		return fmt.Errorf("BlockDevice(%v) volume_size must be between 1 and 16384", *bd.DeviceName)
+	}
+
+	volumeType := bd.volumeType()
+	if !containsStr(BLOCK_DEVICE_VOLUME_TYPES, volumeType) {
+		return fmt.Errorf("BlockDevice(%v) volume_type must be one of %v", *bd.DeviceName, BLOCK_DEVICE_VOLUME_TYPES)
+	}
+
+	required, supported := iopsVolumeTypes[volumeType]
+	if bd.IOPS != nil && !supported {
+		return fmt.Errorf("BlockDevice(%v) iops is not supported by volume_type %v", *bd.DeviceName, volumeType)
+	}
+
+	if bd.IOPS == nil && required {
+		return fmt.Errorf("BlockDevice(%v) iops must be defined for volume_type %v", *bd.DeviceName, volumeType)
+	}
+
+	if bd.IOPS != nil && *bd.IOPS < 1 {
+		return fmt.Errorf("BlockDevice(%v) iops must be positive", *bd.DeviceName)
+	}
+
+	return nil
+}
+
+func (bd *BlockDevice) volumeType() string {
+	if bd.VolumeType == nil {
+		return "gp2"
+	}
+	return *bd.VolumeType
+}
+
+// validateBlockDevices validates each block device and that no device name is used twice,
+// including the root ebs_device_name
+func (service *Service) validateBlockDevices() error {
+	if err := service.validateRootVolume(); err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	if service.EBSVolumeSize != nil {
+		root := rootEBSDeviceNameDefault
+		if service.EBSDeviceName != nil {
+			root = *service.EBSDeviceName
+		}
+		names[root] = true
+	}
+
+	for _, bd := range service.BlockDevices {
+		if bd == nil {
+			return fmt.Errorf("BlockDevice is nil")
+		}
+
+		if err := bd.ValidateAttributes(); err != nil {
+			return err
+		}
+
+		if names[*bd.DeviceName] {
+			return fmt.Errorf("BlockDevice(%v) device_name is not unique", *bd.DeviceName)
+		}
+		names[*bd.DeviceName] = true
+	}
+
+	return nil
+}
+
+// validateRootVolume validates the root ebs volume type, IOPS and throughput
+func (service *Service) validateRootVolume() error {
+	volumeType := "gp2"
+	if service.EBSVolumeType != nil {
+		volumeType = *service.EBSVolumeType
+	}
+
+	if !containsStr(BLOCK_DEVICE_VOLUME_TYPES, volumeType) {
+		return fmt.Errorf("ebs_volume_type must be one of %v", BLOCK_DEVICE_VOLUME_TYPES)
+	}
+
+	if service.EBSVolumeSize == nil {
+		if service.EBSIOPS != nil || service.EBSThroughput != nil {
+			return fmt.Errorf("ebs_iops and ebs_throughput require ebs_volume_size")
+		}
+		return nil
+	}
+
+	required, supported := iopsVolumeTypes[volumeType]
+	if service.EBSIOPS != nil && !supported {
+		return fmt.Errorf("ebs_iops is not supported by ebs_volume_type %v", volumeType)
+	}
+
+	if service.EBSIOPS == nil && required {
+		return fmt.Errorf("ebs_iops must be defined for ebs_volume_type %v", volumeType)
+	}
+
+	iops := int64(defaultGP3IOPS)
+	if service.EBSIOPS != nil {
+		iops = *service.EBSIOPS
+		limits := ebsIOPSRanges[volumeType]
+		if iops < limits[0] || iops > limits[1] {
+			return fmt.Errorf("ebs_iops must be between %v and %v for ebs_volume_type %v", limits[0], limits[1], volumeType)
+		}
+
+		// Every gp3 volume has the baseline IOPS whatever its size
+		baseline := volumeType == "gp3" && iops <= defaultGP3IOPS
+		if perGiB := ebsIOPSPerGiB[volumeType]; !baseline && iops > perGiB**service.EBSVolumeSize {
+			return fmt.Errorf("ebs_iops %v is more than %v per GiB of ebs_volume_size %v", iops, perGiB, *service.EBSVolumeSize)
+		}
+	}
+
+	if service.EBSThroughput == nil {
+		return nil
+	}
+
+	if volumeType != "gp3" {
+		return fmt.Errorf("ebs_throughput is not supported by ebs_volume_type %v", volumeType)
+	}
+
+	throughput := *service.EBSThroughput
+	if throughput < minGP3Throughput || throughput > maxGP3Throughput {
+		return fmt.Errorf("ebs_throughput must be between %v and %v MiB/s for ebs_volume_type gp3", minGP3Throughput, maxGP3Throughput)
+	}
+
+	if throughput*gp3IOPSPerThroughput > iops {
+		return fmt.Errorf("ebs_throughput %v MiB/s needs at least %v ebs_iops", throughput, throughput*gp3IOPSPerThroughput)
+	}
+
+	return nil
+}
+
+// blockDeviceMappings returns the launch configuration mappings for BlockDevices
+func (service *Service) blockDeviceMappings() []*autoscaling.BlockDeviceMapping {
+	mappings := []*autoscaling.BlockDeviceMapping{}
+	for _, bd := range service.BlockDevices {
+		deleteOnTermination := bd.DeleteOnTermination
+		if deleteOnTermination == nil {
+			deleteOnTermination = to.Boolp(true)
+		}
+
+		mappings = append(mappings, &autoscaling.BlockDeviceMapping{
+			DeviceName: bd.DeviceName,
+			Ebs: &autoscaling.Ebs{
+				VolumeSize:          bd.VolumeSize,
+				VolumeType:          to.Strp(bd.volumeType()),
+				Iops:                bd.IOPS,
+				Encrypted:           bd.Encrypted,
+				DeleteOnTermination: deleteOnTermination,
+			},
+		})
+	}
+
+	return mappings
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BlockDevice_ValidateAttributes(t *testing.T) {
+	assert.NoError(t, (&BlockDevice{DeviceName: to.Strp("/dev/sdf"), VolumeSize: to.Int64p(10)}).ValidateAttributes())
+	assert.NoError(t, (&BlockDevice{DeviceName: to.Strp("/dev/sdf"), VolumeSize: to.Int64p(10), VolumeType: to.Strp("gp3"), IOPS: to.Int64p(4000)}).ValidateAttributes())
+
+	assert.Error(t, (&BlockDevice{VolumeSize: to.Int64p(10)}).ValidateAttributes())
+	assert.Error(t, (&BlockDevice{DeviceName: to.Strp("/dev/sdf")}).ValidateAttributes())
+	assert.Error(t, (&BlockDevice{DeviceName: to.Strp("/dev/sdf"), VolumeSize: to.Int64p(10), VolumeType: to.Strp("magnetic")}).ValidateAttributes())
+
+	// IOPS are only supported on gp3 and io volumes and required on io volumes
+	err := (&BlockDevice{DeviceName: to.Strp("/dev/sdf"), VolumeSize: to.Int64p(10), VolumeType: to.Strp("gp2"), IOPS: to.Int64p(100)}).ValidateAttributes()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "iops is not supported by volume_type gp2")
+
+	assert.Error(t, (&BlockDevice{DeviceName: to.Strp("/dev/sdf"), VolumeSize: to.Int64p(10), VolumeType: to.Strp("io1")}).ValidateAttributes())
+}
+
+func Test_Release_ValidateResources_BlockDevices(t *testing.T) {
+	release := MockBlockDevicesRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+
+	// Duplicate device names
+	release.Services["web"].BlockDevices[1].DeviceName = to.Strp("/dev/sdf")
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not unique")
+
+	// Including the root ebs device
+	release.Services["web"].BlockDevices[1].DeviceName = to.Strp("/dev/xvda")
+	assert.Error(t, release.ValidateResources(resources))
+}
+
+func Test_Service_BlockDevices_LaunchConfiguration(t *testing.T) {
+	release := MockBlockDevicesRelease(t)
+	MockPrepareRelease(release)
+
+	mappings := release.Services["web"].createLaunchConfigurationInput().BlockDeviceMappings
+	assert.Equal(t, 3, len(mappings))
+
+	// The root ebs device is first
+	assert.Equal(t, "/dev/xvda", *mappings[0].DeviceName)
+
+	assert.Equal(t, "/dev/sdf", *mappings[1].DeviceName)
+	assert.Equal(t, int64(500), *mappings[1].Ebs.VolumeSize)
+	assert.Equal(t, "gp3", *mappings[1].Ebs.VolumeType)
+	assert.True(t, *mappings[1].Ebs.Encrypted)
+	assert.True(t, *mappings[1].Ebs.DeleteOnTermination)
+	assert.Nil(t, mappings[1].Ebs.Iops)
+
+	assert.Equal(t, int64(3000), *mappings[2].Ebs.Iops)
+	assert.False(t, *mappings[2].Ebs.DeleteOnTermination)
+}
+
+func Test_Release_CreateResources_BlockDevices_LaunchTemplate(t *testing.T) {
+	release := MockBlockDevicesRelease(t)
+	release.Services["web"].InstanceTypes = mockInstanceTypes("m5.large", "c5.large")
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.Equal(t, 1, len(awsc.EC2.CreateLaunchTemplateInputs))
+
+	mappings := awsc.EC2.CreateLaunchTemplateInputs[0].LaunchTemplateData.BlockDeviceMappings
+	assert.Equal(t, 3, len(mappings))
+	assert.Equal(t, "/dev/sdg", *mappings[2].DeviceName)
+	assert.Equal(t, "io2", *mappings[2].Ebs.VolumeType)
+	assert.Equal(t, int64(3000), *mappings[2].Ebs.Iops)
+	assert.False(t, *mappings[2].Ebs.DeleteOnTermination)
+	assert.True(t, *mappings[1].Ebs.Encrypted)
+}
+
+func Test_Service_ValidateRootVolume(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	service := release.Services["web"]
+	assert.NoError(t, service.validateRootVolume())
+
+	// A valid gp3 root volume
+	service.EBSVolumeType = to.Strp("gp3")
+	service.EBSIOPS = to.Int64p(6000)
+	service.EBSThroughput = to.Int64p(500)
+	assert.NoError(t, service.validateRootVolume())
+
+	// The baseline gp3 IOPS support 750 MiB/s
+	service.EBSIOPS = nil
+	service.EBSThroughput = to.Int64p(750)
+	assert.NoError(t, service.validateRootVolume())
+
+	service.EBSThroughput = to.Int64p(800)
+	err := service.validateRootVolume()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ebs_throughput 800 MiB/s needs at least 3200 ebs_iops")
+
+	service.EBSIOPS = to.Int64p(16000)
+	service.EBSThroughput = to.Int64p(1001)
+	err = service.validateRootVolume()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ebs_throughput must be between 125 and 1000 MiB/s")
+
+	service.EBSThroughput = to.Int64p(124)
+	assert.Error(t, service.validateRootVolume())
+
+	service.EBSThroughput = nil
+	service.EBSIOPS = to.Int64p(16001)
+	err = service.validateRootVolume()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ebs_iops must be between 3000 and 16000")
+
+	// 20 GiB supports at most 10000 IOPS
+	service.EBSIOPS = to.Int64p(16000)
+	service.EBSVolumeSize = to.Int64p(20)
+	err = service.validateRootVolume()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ebs_iops 16000 is more than 500 per GiB of ebs_volume_size 20")
+
+	// Throughput is gp3 only, IOPS gp3 and io only
+	service.EBSVolumeSize = to.Int64p(120)
+	service.EBSVolumeType = to.Strp("gp2")
+	service.EBSIOPS = nil
+	service.EBSThroughput = to.Int64p(250)
+	assert.Error(t, service.validateRootVolume())
+
+	service.EBSThroughput = nil
+	service.EBSIOPS = to.Int64p(3000)
+	assert.Error(t, service.validateRootVolume())
+
+	service.EBSVolumeType = to.Strp("io2")
+	service.EBSIOPS = nil
+	assert.Error(t, service.validateRootVolume())
+
+	service.EBSVolumeType = to.Strp("magnetic")
+	assert.Error(t, service.validateRootVolume())
+
+	// Without a root volume size there is no root volume to tune
+	service.EBSVolumeType = to.Strp("gp3")
+	service.EBSVolumeSize = nil
+	service.EBSThroughput = to.Int64p(250)
+	assert.Error(t, service.validateRootVolume())
+}
+
+func Test_Release_ValidateResources_RootVolume_Throughput(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].EBSVolumeType = to.Strp("gp3")
+	release.Services["web"].EBSThroughput = to.Int64p(2000)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ebs_throughput must be between 125 and 1000 MiB/s")
+}
+
+func Test_Release_CreateResources_RootVolume_GP3(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].InstanceTypes = mockInstanceTypes("m5.large", "c5.large")
+	release.Services["web"].EBSVolumeType = to.Strp("gp3")
+	release.Services["web"].EBSIOPS = to.Int64p(4000)
+	release.Services["web"].EBSThroughput = to.Int64p(500)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.Equal(t, 1, len(awsc.EC2.CreateLaunchTemplateInputs))
+
+	root := awsc.EC2.CreateLaunchTemplateInputs[0].LaunchTemplateData.BlockDeviceMappings[0]
+	assert.Equal(t, "/dev/xvda", *root.DeviceName)
+	assert.Equal(t, int64(120), *root.Ebs.VolumeSize)
+	assert.Equal(t, "gp3", *root.Ebs.VolumeType)
+	assert.Equal(t, int64(4000), *root.Ebs.Iops)
+	assert.Equal(t, int64(500), *root.Ebs.Throughput)
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/step/utils/to"
+)
+
+// CanaryConfig launches a percentage of a services instances first, and only scales to
+// the full count after the canary instances have been healthy for the bake duration
+type CanaryConfig struct {
+	Percentage   *int `json:"percentage,omitempty"`    // Percent of the target capacity, at least 1 instance
+	BakeDuration *int `json:"bake_duration,omitempty"` // Seconds the canary must stay healthy
+
+	// Controlled by the deployer
+	HealthyAt *time.Time `json:"healthy_at,omitempty"`
+	Baked     bool       `json:"baked,omitempty"`
+}
+
+// ValidateAttributes validates attributes
+func (c *CanaryConfig) ValidateAttributes() error {
+	if c.Percentage == nil || *c.Percentage < 1 || *c.Percentage > 99 {
+		return fmt.Errorf("Canary percentage must be between 1 and 99")
+	}
+
+	if c.BakeDuration != nil && (*c.BakeDuration < 0 || *c.BakeDuration > 172800) {
+		return fmt.Errorf("Canary bake_duration must be between 0 and 172800 (48 hours)")
+	}
+
+	return nil
+}
+
+// bakeDuration is the seconds the canary must stay healthy
+func (c *CanaryConfig) bakeDuration() int64 {
+	if c.BakeDuration == nil {
+		return 0
+	}
+	return int64(*c.BakeDuration)
+}
+
+// setCanaryGraceDefault defaults the grace period to leave the canary time to bake before the timeout
+func (service *Service) setCanaryGraceDefault() {
+	if service.Canary == nil || service.Autoscaling.HealthCheckGracePeriod != nil || service.release.Timeout == nil {
+		return
+	}
+
+	service.Autoscaling.HealthCheckGracePeriod = to.Int64p(max(0, int64(*service.release.Timeout)-service.Canary.bakeDuration()))
+}
+
+// validateCanaryBake checks the canary can pass its grace period and bake before the release times out
+func (service *Service) validateCanaryBake() error {
+	if service.release == nil || service.release.Timeout == nil || service.Autoscaling == nil || service.Autoscaling.HealthCheckGracePeriod == nil {
+		return nil
+	}
+
+	grace := *service.Autoscaling.HealthCheckGracePeriod
+	if service.Canary.bakeDuration()+grace > int64(*service.release.Timeout) {
+		return fmt.Errorf("Canary bake_duration %v plus health_check_grace_period %v is more than the timeout %v", service.Canary.bakeDuration(), grace, *service.release.Timeout)
+	}
+
+	return nil
+}
+
+// WipeControlledValues wipes values that are controlled by the deployer
+func (c *CanaryConfig) WipeControlledValues() {
+	c.HealthyAt = nil
+	c.Baked = false
+}
+
+// canarying returns true until the services canary is baked
+func (service *Service) canarying() bool {
+	return service.Canary != nil && !service.Canary.Baked
+}
+
+// canarySize is the number of instances in the canary
+func (service *Service) canarySize() int64 {
+	return max(1, percent(service.strategy.TargetCapacity(), float64(*service.Canary.Percentage)/100))
+}
+
+// UpdateCanary sets Baked once all canary instances are healthy for the bake duration
+// A HaltError is returned if a canary instance is terminating
+func (service *Service) UpdateCanary(asgc aws.ASGAPI, ec2c aws.EC2API, elbc aws.ELBAPI, albc aws.ALBAPI) error {
+	if !service.canarying() {
+		return nil
+	}
+
+	all, _, err := asg.GetInstances(asgc, service.CreatedASG)
+	if err != nil {
+		return err // This might retry
+	}
+
+	if err := service.removeSpotInterruptions(ec2c, all); err != nil {
+		return err // This might retry
+	}
+
+	if len(all.TerminatingIDs()) > 0 {
+		err := fmt.Errorf("Canary failed %v, terminating instances %v", *service.ServiceName, strings.Join(all.TerminatingIDs(), ","))
+		return &HaltError{err} // This will immediately stop deploying
+	}
+
+	all, err = service.mergeLBInstances(elbc, albc, all)
+	if err != nil {
+		return err // This might retry
+	}
+
+	if int64(len(all.HealthyIDs())) < service.canarySize() {
+		// Bake time restarts if the canary becomes unhealthy
+		service.Canary.HealthyAt = nil
+		return nil
+	}
+
+	if service.Canary.HealthyAt == nil {
+		service.Canary.HealthyAt = to.Timep(time.Now())
+	}
+
+	service.Canary.Baked = !time.Now().Before(service.Canary.HealthyAt.Add(time.Duration(service.Canary.bakeDuration()) * time.Second))
+
+	return nil
+}
+
+// UpdateCanary updates the canary of every created service
+func (release *Release) UpdateCanary(asgc aws.ASGAPI, ec2c aws.EC2API, elbc aws.ELBAPI, albc aws.ALBAPI) error {
+	for _, service := range release.Services {
+		if service.waitingOnDependencies() {
+			continue
+		}
+
+		if err := service.UpdateCanary(asgc, ec2c, elbc, albc); err != nil {
+			return err
+		}
+	}
+
+	release.SetCanaried()
+
+	return nil
+}
+
+// SetCanaried sets Canaried, false while any services canary is not baked
+func (release *Release) SetCanaried() {
+	canaried := true
+	for _, service := range release.Services {
+		if service != nil && service.canarying() {
+			canaried = false
+		}
+	}
+
+	release.Canaried = to.Boolp(canaried)
+}
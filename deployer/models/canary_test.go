@@ -0,0 +1,114 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Canary_ValidateAttributes(t *testing.T) {
+	canary := &CanaryConfig{Percentage: to.Intp(10)}
+	assert.NoError(t, canary.ValidateAttributes())
+
+	canary = &CanaryConfig{}
+	assert.Error(t, canary.ValidateAttributes())
+
+	canary = &CanaryConfig{Percentage: to.Intp(100)}
+	assert.Error(t, canary.ValidateAttributes())
+
+	canary = &CanaryConfig{Percentage: to.Intp(10), BakeDuration: to.Intp(-1)}
+	assert.Error(t, canary.ValidateAttributes())
+}
+
+func Test_Canary_CreateInput_Size(t *testing.T) {
+	release := MockCanaryRelease(t)
+	release.Services["web"].Autoscaling.MinSize = to.Int64p(20)
+	release.Services["web"].Autoscaling.MaxSize = to.Int64p(40)
+	release.Services["web"].Canary.Percentage = to.Intp(10)
+	MockPrepareRelease(release)
+
+	service := release.Services["web"]
+	input := service.createInput()
+
+	// 10% of the target capacity of 30 (20 + 50% spread)
+	assert.Equal(t, int64(3), *input.MinSize)
+	assert.Equal(t, int64(3), *input.DesiredCapacity)
+
+	// Never less than one instance
+	service.Canary.Percentage = to.Intp(1)
+	assert.Equal(t, int64(1), service.canarySize())
+}
+
+func Test_Canary_UpdateCanary_Bakes(t *testing.T) {
+	release := MockCanaryRelease(t)
+	release.Services["web"].Canary.BakeDuration = to.Intp(60)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+
+	service := release.Services["web"]
+	service.CreatedASG = to.Strp("asg")
+
+	assert.NoError(t, release.UpdateCanary(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB))
+	assert.NotNil(t, service.Canary.HealthyAt)
+	assert.False(t, service.Canary.Baked)
+
+	// Healthy instances are not scaled until the canary has baked
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, service.Healthy)
+	assert.Nil(t, awsc.ASG.UpdateAutoScalingGroupLastInput)
+
+	service.Canary.BakeDuration = to.Intp(0)
+	assert.NoError(t, release.UpdateCanary(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB))
+	assert.True(t, service.Canary.Baked)
+
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, service.Healthy)
+}
+
+func Test_Canary_UpdateCanary_Unhealthy(t *testing.T) {
+	release := MockCanaryRelease(t)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0].Instances = mocks.MakeMockASGInstances(0, 1, 0)
+
+	service := release.Services["web"]
+	service.CreatedASG = to.Strp("asg")
+
+	assert.NoError(t, release.UpdateCanary(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB))
+	assert.Nil(t, service.Canary.HealthyAt)
+	assert.False(t, service.Canary.Baked)
+}
+
+func Test_Canary_UpdateCanary_Terminating(t *testing.T) {
+	release := MockCanaryRelease(t)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0].Instances = mocks.MakeMockASGInstances(0, 0, 1)
+
+	release.Services["web"].CreatedASG = to.Strp("asg")
+
+	err := release.UpdateCanary(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB)
+	assert.Error(t, err)
+	assert.IsType(t, &HaltError{}, err)
+}
+
+func Test_Canary_Bake_Fits_Timeout(t *testing.T) {
+	release := MockCanaryRelease(t)
+	release.Timeout = to.Intp(600)
+	release.Services["web"].Canary.BakeDuration = to.Intp(200)
+	release.Services["web"].Autoscaling.HealthCheckGracePeriod = nil
+	MockPrepareRelease(release)
+
+	// The grace period defaults to leave the canary time to bake
+	service := release.Services["web"]
+	assert.Equal(t, int64(400), *service.Autoscaling.HealthCheckGracePeriod)
+	assert.NoError(t, service.Validate())
+
+	service.Autoscaling.HealthCheckGracePeriod = to.Int64p(500)
+	assert.Error(t, service.Validate())
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/cr"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Capacity Reservation
+//////////
+
+// CAPACITY_RESERVATION_PREFERENCES are the capacity_reservation values that are not a specific reservation
+var CAPACITY_RESERVATION_PREFERENCES = []string{"open", "none"}
+
+var capacityReservationID = regexp.MustCompile(`^cr-[0-9a-f]+$`)
+var capacityReservationGroupARN = regexp.MustCompile(`^arn:[^:]+:resource-groups:[^:]*:[0-9]*:group/.+$`)
+
+// capacityReservationPreference returns "open" or "none" if the capacity_reservation is not a specific reservation
+func (service *Service) capacityReservationPreference() *string {
+	if service.CapacityReservation == nil {
+		return nil
+	}
+
+	preference := strings.ToLower(*service.CapacityReservation)
+	if !containsStr(CAPACITY_RESERVATION_PREFERENCES, preference) {
+		return nil
+	}
+
+	return &preference
+}
+
+// capacityReservationID returns the capacity reservation the service targets
+func (service *Service) capacityReservationID() *string {
+	if service.CapacityReservation == nil || !capacityReservationID.MatchString(*service.CapacityReservation) {
+		return nil
+	}
+
+	return service.CapacityReservation
+}
+
+// findCapacityReservation returns the capacity reservation the service targets, nil if it is not found
+func (service *Service) findCapacityReservation(ec2c aws.EC2API) (*ec2.CapacityReservation, error) {
+	id := service.capacityReservationID()
+	if id == nil {
+		return nil, nil
+	}
+
+	return cr.Find(ec2c, id)
+}
+
+// validateCapacityReservation validates the capacity_reservation
+func (service *Service) validateCapacityReservation() error {
+	if service.CapacityReservation == nil {
+		return nil
+	}
+
+	reservation := *service.CapacityReservation
+	if service.capacityReservationPreference() == nil && service.capacityReservationID() == nil && !capacityReservationGroupARN.MatchString(reservation) {
+		return fmt.Errorf("CapacityReservation %q must be one of %v, a capacity reservation ID or a resource group ARN", reservation, CAPACITY_RESERVATION_PREFERENCES)
+	}
+
+	// Spot instances do not launch into capacity reservations
+	if service.spot() || service.SpotPrice != nil {
+		return fmt.Errorf("CapacityReservation cannot be used with spot or spot_price")
+	}
+
+	return nil
+}
+
+// capacityReservationSpecification returns the launch template capacity reservation of the service
+func (service *Service) capacityReservationSpecification() *ec2.LaunchTemplateCapacityReservationSpecificationRequest {
+	if service.CapacityReservation == nil {
+		return nil
+	}
+
+	if preference := service.capacityReservationPreference(); preference != nil {
+		return &ec2.LaunchTemplateCapacityReservationSpecificationRequest{CapacityReservationPreference: preference}
+	}
+
+	target := &ec2.CapacityReservationTarget{}
+	if id := service.capacityReservationID(); id != nil {
+		target.CapacityReservationId = id
+	} else {
+		target.CapacityReservationResourceGroupArn = service.CapacityReservation
+	}
+
+	return &ec2.LaunchTemplateCapacityReservationSpecificationRequest{CapacityReservationTarget: target}
+}
+
+// validateCapacityReservation errors if the capacity reservation the service targets does not exist,
+// is not active, or does not match the services instance types and availability zones
+func (sr *ServiceResources) validateCapacityReservation(service *Service) error {
+	id := service.capacityReservationID()
+	if id == nil {
+		return nil
+	}
+
+	reservation := sr.CapacityReservation
+	if reservation == nil {
+		return fmt.Errorf("CapacityReservation %v not found", *id)
+	}
+
+	if state := to.Strs(reservation.State); state != ec2.CapacityReservationStateActive {
+		return fmt.Errorf("CapacityReservation %v is %v not %v", *id, state, ec2.CapacityReservationStateActive)
+	}
+
+	instanceType := to.Strs(reservation.InstanceType)
+	if !containsStrp(service.instanceTypeNames(), instanceType) {
+		return fmt.Errorf("CapacityReservation %v is for instance type %v not %v", *id, instanceType, strings.Join(to.StrSlice(service.instanceTypeNames()), ","))
+	}
+
+	az := to.Strs(reservation.AvailabilityZone)
+	for _, sn := range sr.Subnets {
+		if sn != nil && to.Strs(sn.AvailabilityZone) != az {
+			return fmt.Errorf("CapacityReservation %v is in %v but subnet %v is in %v", *id, az, to.Strs(sn.SubnetID), to.Strs(sn.AvailabilityZone))
+		}
+	}
+
+	return nil
+}
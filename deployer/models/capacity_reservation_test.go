@@ -0,0 +1,96 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Service_ValidateCapacityReservation(t *testing.T) {
+	for _, reservation := range []string{"open", "None", "cr-0123456789abcdef0", "arn:aws:resource-groups:us-east-1:000000000000:group/reservations"} {
+		service := &Service{CapacityReservation: to.Strp(reservation)}
+		assert.NoError(t, service.validateCapacityReservation(), reservation)
+	}
+
+	for _, reservation := range []string{"targeted", "cr-XYZ", "arn:aws:ec2:us-east-1:000000000000:capacity-reservation/cr-1234"} {
+		service := &Service{CapacityReservation: to.Strp(reservation)}
+		assert.Error(t, service.validateCapacityReservation(), reservation)
+	}
+
+	service := &Service{CapacityReservation: to.Strp("open"), SpotPrice: to.Strp("0.1")}
+	assert.Error(t, service.validateCapacityReservation())
+}
+
+func Test_Release_ValidateResources_CapacityReservation(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].CapacityReservation = to.Strp("cr-1234")
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	awsc.EC2.AddCapacityReservation("cr-1234", "t2.small", "us-east-1a", "active")
+	awsc.EC2.AddCapacityReservation("cr-5678", "m5.large", "us-east-1a", "active")
+	awsc.EC2.AddCapacityReservation("cr-9abc", "t2.small", "us-east-1b", "active")
+	awsc.EC2.AddCapacityReservation("cr-def0", "t2.small", "us-east-1a", "expired")
+
+	validate := func(id string) error {
+		release.Services["web"].CapacityReservation = to.Strp(id)
+		resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+		assert.NoError(t, err)
+		return release.ValidateResources(resources)
+	}
+
+	assert.NoError(t, validate("cr-1234"))
+	assert.NoError(t, validate("open"))
+
+	err := validate("cr-4321")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+
+	err = validate("cr-5678")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "instance type m5.large")
+
+	err = validate("cr-9abc")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is in us-east-1b")
+
+	err = validate("cr-def0")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is expired")
+}
+
+func Test_Release_CreateResources_CapacityReservation_Targeted(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].CapacityReservation = to.Strp("cr-1234")
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// Launch configurations have no capacity reservations
+	assert.Equal(t, 0, len(awsc.ASG.CreateLaunchConfigurationInputs))
+	assert.Equal(t, 1, len(awsc.EC2.CreateLaunchTemplateInputs))
+
+	spec := awsc.EC2.CreateLaunchTemplateInputs[0].LaunchTemplateData.CapacityReservationSpecification
+	assert.Equal(t, "cr-1234", *spec.CapacityReservationTarget.CapacityReservationId)
+	assert.Nil(t, spec.CapacityReservationPreference)
+
+	input := awsc.ASG.CreateAutoScalingGroupInputs[0]
+	assert.Nil(t, input.LaunchConfigurationName)
+	assert.Nil(t, input.MixedInstancesPolicy)
+	assert.Equal(t, *release.Services["web"].ServiceID(), *input.LaunchTemplate.LaunchTemplateName)
+}
+
+func Test_Release_CreateResources_CapacityReservation_Open(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].CapacityReservation = to.Strp("Open")
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	spec := awsc.EC2.CreateLaunchTemplateInputs[0].LaunchTemplateData.CapacityReservationSpecification
+	assert.Equal(t, "open", *spec.CapacityReservationPreference)
+	assert.Nil(t, spec.CapacityReservationTarget)
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Service Dependencies
+//////////
+
+// ValidateDependsOn validates every services DependsOn names a different service and has no cycles
+func (release *Release) ValidateDependsOn() error {
+	for _, name := range sortedServiceNames(release) {
+		service := release.Services[name]
+		if service == nil || len(service.DependsOn) == 0 {
+			continue
+		}
+
+		if release.IsInstanceRefresh() {
+			return fmt.Errorf("DependsOn cannot be used with the %v deploy strategy", DeployInstanceRefresh)
+		}
+
+		if !is.UniqueStrp(service.DependsOn) {
+			return fmt.Errorf("Non Unique DependsOn for %v", name)
+		}
+
+		if service.Canary != nil {
+			return fmt.Errorf("Service %v with DependsOn cannot have a Canary", name)
+		}
+
+		for _, dep := range service.DependsOn {
+			switch {
+			case dep == nil || *dep == name:
+				return fmt.Errorf("Service %v cannot depend on itself", name)
+			case release.Services[*dep] == nil:
+				return fmt.Errorf("Service %v depends on unknown service %v", name, *dep)
+			}
+		}
+	}
+
+	visited := map[string]bool{}
+	for _, name := range sortedServiceNames(release) {
+		if cycle := release.dependencyCycle(name, []string{}, visited); cycle != nil {
+			return fmt.Errorf("DependsOn cycle %v", strings.Join(cycle, " -> "))
+		}
+	}
+
+	return nil
+}
+
+// dependencyCycle returns the services of a cycle reachable from name, path are the services depending on it
+func (release *Release) dependencyCycle(name string, path []string, visited map[string]bool) []string {
+	for i, n := range path {
+		if n == name {
+			return append(path[i:], name)
+		}
+	}
+
+	if visited[name] {
+		return nil
+	}
+
+	path = append(path, name)
+	service := release.Services[name]
+	if service != nil {
+		deps := to.StrSlice(service.DependsOn)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if cycle := release.dependencyCycle(dep, path, visited); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	visited[name] = true
+	return nil
+}
+
+// waitingOnDependencies returns true if the service is not created until the services it depends on are healthy
+func (service *Service) waitingOnDependencies() bool {
+	return len(service.DependsOn) > 0 && service.CreatedASG == nil
+}
+
+// dependenciesHealthy returns true if every service the service depends on is healthy
+func (release *Release) dependenciesHealthy(service *Service) bool {
+	for _, dep := range service.DependsOn {
+		s := release.Services[to.Strs(dep)]
+		if s == nil || !s.Healthy {
+			return false
+		}
+	}
+
+	return true
+}
+
+// createDependentServices creates the waiting services whose dependencies are all healthy.
+// A service that fails to create halts the deploy
+func (release *Release) createDependentServices(asgc aws.ASGAPI, ec2c aws.EC2API, cwc aws.CWAPI, albc aws.ALBAPI) error {
+	ready := map[string]bool{}
+	for name, service := range release.Services {
+		if service.waitingOnDependencies() && release.dependenciesHealthy(service) {
+			ready[name] = true
+		}
+	}
+
+	if len(ready) == 0 {
+		return nil
+	}
+
+	err := release.forEachService(func(service *Service) error {
+		if !ready[*service.ServiceName] {
+			return nil
+		}
+
+		return service.CreateResources(asgc, ec2c, cwc, albc)
+	})
+
+	if err != nil {
+		return &HaltError{fmt.Errorf("Creating dependent service %v", err.Error())}
+	}
+
+	return nil
+}
+
+// WaitingOnDependencies returns true if any service has not been created because of its dependencies
+func (release *Release) WaitingOnDependencies() bool {
+	for _, service := range release.Services {
+		if service != nil && service.waitingOnDependencies() {
+			return true
+		}
+	}
+
+	return false
+}
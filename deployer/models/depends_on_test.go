@@ -0,0 +1,97 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidateDependsOn(t *testing.T) {
+	release := mockParallelRelease(t, "api", "worker")
+	assert.NoError(t, release.ValidateDependsOn())
+
+	release.Services["api"].DependsOn = []*string{to.Strp("web")}
+	release.Services["worker"].DependsOn = []*string{to.Strp("api"), to.Strp("web")}
+	assert.NoError(t, release.ValidateDependsOn())
+
+	release.Services["worker"].DependsOn = []*string{to.Strp("api"), to.Strp("api")}
+	assert.Error(t, release.ValidateDependsOn())
+
+	release.Services["worker"].DependsOn = []*string{to.Strp("worker")}
+	assert.EqualError(t, release.ValidateDependsOn(), "Service worker cannot depend on itself")
+
+	release.Services["worker"].DependsOn = []*string{to.Strp("missing")}
+	assert.EqualError(t, release.ValidateDependsOn(), "Service worker depends on unknown service missing")
+
+	release.Services["worker"].DependsOn = []*string{to.Strp("api")}
+	release.Services["worker"].Canary = &CanaryConfig{Percentage: to.Intp(50)}
+	assert.Error(t, release.ValidateDependsOn())
+
+	release.Services["worker"].Canary = nil
+	release.DeployStrategy = to.Strp(DeployInstanceRefresh)
+	assert.Error(t, release.ValidateDependsOn())
+}
+
+func Test_Release_ValidateDependsOn_Cycle(t *testing.T) {
+	release := mockParallelRelease(t, "api", "worker")
+	release.Services["web"].DependsOn = []*string{to.Strp("worker")}
+	release.Services["api"].DependsOn = []*string{to.Strp("web")}
+	release.Services["worker"].DependsOn = []*string{to.Strp("api")}
+
+	assert.EqualError(t, release.ValidateDependsOn(), "DependsOn cycle api -> web -> worker -> api")
+
+	awsc := MockAwsClients(release)
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "DependsOn cycle")
+}
+
+func Test_Release_DependsOn_Ordering(t *testing.T) {
+	release := mockParallelRelease(t, "api")
+	release.Services["api"].DependsOn = []*string{to.Strp("web")}
+
+	awsc := MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// Only web is created by Deploy
+	assert.Equal(t, 1, len(awsc.ASG.CreateAutoScalingGroupInputs))
+	assert.Equal(t, *release.Services["web"].ServiceID(), *awsc.ASG.CreateAutoScalingGroupInputs[0].AutoScalingGroupName)
+	assert.Nil(t, release.Services["api"].CreatedASG)
+	assert.True(t, release.WaitingOnDependencies())
+
+	// Once web is healthy api is created, but is not yet healthy
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, release.Services["web"].Healthy)
+	assert.False(t, *release.Healthy)
+
+	assert.Equal(t, 2, len(awsc.ASG.CreateAutoScalingGroupInputs))
+	assert.Equal(t, *release.Services["api"].ServiceID(), *awsc.ASG.CreateAutoScalingGroupInputs[1].AutoScalingGroupName)
+	assert.False(t, release.WaitingOnDependencies())
+
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, *release.Healthy)
+	assert.Equal(t, 2, len(awsc.ASG.CreateAutoScalingGroupInputs))
+}
+
+func Test_Release_DependsOn_Waits_For_Unhealthy_Dependency(t *testing.T) {
+	release := mockParallelRelease(t, "api")
+	release.Services["api"].DependsOn = []*string{to.Strp("web")}
+
+	awsc := MockAwsClients(release)
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	release.UpdateWithResources(resources)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	awsc.ALB.SetTargetHealth("web-elb-target", "InstanceId1", "unhealthy")
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, *release.Healthy)
+
+	// api is not created until web is healthy
+	assert.Equal(t, 1, len(awsc.ASG.CreateAutoScalingGroupInputs))
+	assert.True(t, release.WaitingOnDependencies())
+}
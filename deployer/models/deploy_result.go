@@ -0,0 +1,120 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/odin/aws/lt"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/to"
+)
+
+// DeployResult is written by a successful release and describes what it deployed
+type DeployResult struct {
+	ProjectName    *string                   `json:"project_name,omitempty"`
+	ConfigName     *string                   `json:"config_name,omitempty"`
+	ReleaseID      *string                   `json:"release_id,omitempty"`
+	ReleaseUUID    *string                   `json:"release_uuid,omitempty"`
+	IdempotencyKey *string                   `json:"idempotency_key,omitempty"`
+	Services       map[string]*ServiceResult `json:"services,omitempty"`
+}
+
+// ServiceResult is what was deployed for a service
+type ServiceResult struct {
+	AutoScalingGroupName    *string   `json:"autoscaling_group_name,omitempty"`
+	LaunchConfigurationName *string   `json:"launch_configuration_name,omitempty"`
+	LaunchTemplateName      *string   `json:"launch_template_name,omitempty"`
+	LaunchTemplateVersion   *int64    `json:"launch_template_version,omitempty"`
+	LoadBalancerNames       []*string `json:"load_balancer_names,omitempty"`
+	TargetGroupARNs         []*string `json:"target_group_arns,omitempty"`
+	InstanceIDs             []*string `json:"instance_ids,omitempty"`
+}
+
+// DeployResultPath returns the path of the deploy result of the release with the UUID
+func (release *Release) DeployResultPath() *string {
+	s := fmt.Sprintf("%v/results/%v", *release.RootDir(), to.Strs(release.UUID))
+	return &s
+}
+
+// CreateDeployResult returns the ASGs, launch templates, load balancers and instances of the release
+func (release *Release) CreateDeployResult(asgc aws.ASGAPI, ec2c aws.EC2API) (*DeployResult, error) {
+	result := &DeployResult{
+		ProjectName:    release.ProjectName,
+		ConfigName:     release.ConfigName,
+		ReleaseID:      release.ReleaseID,
+		ReleaseUUID:    release.UUID,
+		IdempotencyKey: release.IdempotencyKey,
+		Services:       map[string]*ServiceResult{},
+	}
+
+	for name, service := range release.Services {
+		if service == nil || service.CreatedASG == nil {
+			continue
+		}
+
+		instances, group, err := asg.GetInstances(asgc, service.CreatedASG)
+		if err != nil {
+			return nil, err
+		}
+
+		ids := instances.InstanceIDs()
+		sort.Strings(ids)
+
+		sr := &ServiceResult{AutoScalingGroupName: service.CreatedASG}
+		for _, id := range ids {
+			sr.InstanceIDs = append(sr.InstanceIDs, to.Strp(id))
+		}
+
+		if service.Resources != nil {
+			sr.LoadBalancerNames = service.Resources.ELBs
+			sr.TargetGroupARNs = service.targetGroupArns()
+		}
+
+		switch {
+		case service.sharedLaunchTemplate():
+			sr.LaunchTemplateName = service.launchTemplateName()
+			sr.LaunchTemplateVersion = service.CreatedLaunchTemplateVersion
+		case service.launchTemplate():
+			sr.LaunchTemplateName = service.ServiceID()
+			if sr.LaunchTemplateVersion, err = lt.LatestVersion(ec2c, sr.LaunchTemplateName); err != nil {
+				return nil, err
+			}
+		default:
+			sr.LaunchConfigurationName = group.LaunchConfigurationName
+		}
+
+		result.Services[name] = sr
+	}
+
+	return result, nil
+}
+
+// WriteDeployResult writes the deploy result of the release to S3, as the current release, and sets it as the releases Result
+// It must be called after the previous ASGs are torn down, so only this releases resources are left
+func (release *Release) WriteDeployResult(s3c aws.S3API, asgc aws.ASGAPI, ec2c aws.EC2API) error {
+	result, err := release.CreateDeployResult(asgc, ec2c)
+	if err != nil {
+		return err
+	}
+
+	if err := s3.PutStruct(s3c, release.Bucket, release.DeployResultPath(), result); err != nil {
+		return err
+	}
+
+	// Prune never deletes the resources of the current release
+	if err := s3.PutStruct(s3c, release.Bucket, release.CurrentDeployResultPath(), result); err != nil {
+		return err
+	}
+
+	// A later release with the same key returns this result rather than deploying again
+	if release.IdempotencyKey != nil {
+		if err := s3.PutStruct(s3c, release.Bucket, release.IdempotencyResultPath(), result); err != nil {
+			return err
+		}
+	}
+
+	release.Result = result
+	return nil
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_WriteDeployResult(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].InstanceTypes = mockInstanceTypes("m5.large", "c5.large")
+	MockPrepareRelease(r)
+
+	awsc := MockAwsClients(r)
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// Only the new ASG is left after the tear down
+	group := mocks.MakeMockASG(*r.Services["web"].CreatedASG, *r.ProjectName, *r.ConfigName, "web", *r.ReleaseID)
+	group.Instances = mocks.MakeMockASGInstances(2, 0, 0)
+	awsc.ASG.DescribeAutoScalingGroupsPageResp = nil
+	awsc.ASG.AddASG(group)
+
+	assert.NoError(t, r.WriteDeployResult(awsc.S3, awsc.ASG, awsc.EC2))
+
+	var result DeployResult
+	assert.NoError(t, s3.GetStruct(awsc.S3, r.Bucket, r.DeployResultPath(), &result))
+	assert.Equal(t, r.Result, &result)
+
+	var current DeployResult
+	assert.NoError(t, s3.GetStruct(awsc.S3, r.Bucket, r.CurrentDeployResultPath(), &current))
+	assert.Equal(t, r.Result, &current)
+
+	assert.Equal(t, *r.UUID, *result.ReleaseUUID)
+	assert.Equal(t, *r.ReleaseID, *result.ReleaseID)
+
+	web := result.Services["web"]
+	assert.Equal(t, *r.Services["web"].CreatedASG, *web.AutoScalingGroupName)
+	assert.Equal(t, *r.Services["web"].ServiceID(), *web.LaunchTemplateName)
+	assert.Equal(t, int64(1), *web.LaunchTemplateVersion)
+	assert.Nil(t, web.LaunchConfigurationName)
+	assert.Equal(t, r.Services["web"].Resources.TargetGroups, web.TargetGroupARNs)
+	assert.Equal(t, 2, len(web.InstanceIDs))
+}
+
+func Test_DeployResult_Serialization(t *testing.T) {
+	result := &DeployResult{
+		ProjectName: to.Strp("project"),
+		ConfigName:  to.Strp("config"),
+		ReleaseID:   to.Strp("release"),
+		ReleaseUUID: to.Strp("uuid"),
+		Services: map[string]*ServiceResult{
+			"web": &ServiceResult{
+				AutoScalingGroupName:  to.Strp("project-config-web-release"),
+				LaunchTemplateName:    to.Strp("project-config-web-release"),
+				LaunchTemplateVersion: to.Int64p(1),
+				LoadBalancerNames:     []*string{to.Strp("elb")},
+				TargetGroupARNs:       []*string{to.Strp("arn:tg")},
+				InstanceIDs:           []*string{to.Strp("i-1"), to.Strp("i-2")},
+			},
+		},
+	}
+
+	raw, err := json.Marshal(result)
+	assert.NoError(t, err)
+	assert.Contains(t, string(raw), `"launch_template_version":1`)
+
+	var parsed DeployResult
+	assert.NoError(t, json.Unmarshal(raw, &parsed))
+	assert.Equal(t, result, &parsed)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Deploy Role
+//////////
+
+// DefaultDeployRole is the role the deployer assumes in the release account
+const DefaultDeployRole = "coinbase-odin-assumed"
+
+// deployRoleARNRegex matches an IAM role ARN capturing its account and role name with path
+var deployRoleARNRegex = regexp.MustCompile(`^arn:aws:iam::(\d{12}):role/([\w+=,.@/-]+)$`)
+
+// DeployRole returns the name of the role the AWS clients assume in the release account
+func (release *Release) DeployRole() *string {
+	if release.DeployRoleARN == nil {
+		return to.Strp(DefaultDeployRole)
+	}
+
+	match := deployRoleARNRegex.FindStringSubmatch(*release.DeployRoleARN)
+	if match == nil {
+		return to.Strp(DefaultDeployRole)
+	}
+
+	return to.Strp(match[2])
+}
+
+// ValidateDeployRoleARN validates the DeployRoleARN is a role in the release account
+func (release *Release) ValidateDeployRoleARN() error {
+	if release.DeployRoleARN == nil {
+		return nil
+	}
+
+	match := deployRoleARNRegex.FindStringSubmatch(*release.DeployRoleARN)
+	if match == nil {
+		return fmt.Errorf("DeployRoleARN %v must be an IAM role ARN", *release.DeployRoleARN)
+	}
+
+	if match[1] != to.Strs(release.AwsAccountID) {
+		return fmt.Errorf("DeployRoleARN %v must be in the aws_account_id %v", *release.DeployRoleARN, to.Strs(release.AwsAccountID))
+	}
+
+	return nil
+}
+
+// ValidateDeployRole checks the DeployRoleARN can be assumed, stsc must be created with the DeployRole
+func (release *Release) ValidateDeployRole(stsc aws.STSAPI) error {
+	if release.DeployRoleARN == nil {
+		return nil
+	}
+
+	out, err := stsc.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return fmt.Errorf("DeployRoleARN %v cannot be assumed %v", *release.DeployRoleARN, err.Error())
+	}
+
+	if to.Strs(out.Account) != to.Strs(release.AwsAccountID) {
+		return fmt.Errorf("DeployRoleARN %v assumed into account %v not %v", *release.DeployRoleARN, to.Strs(out.Account), to.Strs(release.AwsAccountID))
+	}
+
+	return nil
+}
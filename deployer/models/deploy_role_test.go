@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_DeployRole(t *testing.T) {
+	release := MockRelease(t)
+	assert.Equal(t, DefaultDeployRole, *release.DeployRole())
+	assert.NoError(t, release.ValidateDeployRoleARN())
+
+	release.AwsAccountID = to.Strp("123456789012")
+	release.DeployRoleARN = to.Strp("arn:aws:iam::123456789012:role/odin/deployer")
+	assert.Equal(t, "odin/deployer", *release.DeployRole())
+	assert.NoError(t, release.ValidateDeployRoleARN())
+}
+
+func Test_Release_ValidateDeployRoleARN_Errors(t *testing.T) {
+	release := MockRelease(t)
+	release.AwsAccountID = to.Strp("123456789012")
+
+	release.DeployRoleARN = to.Strp("deployer")
+	assert.Error(t, release.ValidateDeployRoleARN())
+
+	release.DeployRoleARN = to.Strp("arn:aws:iam::123456789012:user/deployer")
+	assert.Error(t, release.ValidateDeployRoleARN())
+
+	// The role must be in the account resources are deployed to
+	release.DeployRoleARN = to.Strp("arn:aws:iam::210987654321:role/deployer")
+	assert.Error(t, release.ValidateDeployRoleARN())
+}
+
+func Test_Release_ValidateDeployRole(t *testing.T) {
+	release := MockRelease(t)
+	awsc := MockAwsClients(release)
+	release.AwsAccountID = to.Strp("123456789012")
+
+	// Nothing is assumed without a DeployRoleARN
+	assert.NoError(t, release.ValidateDeployRole(awsc.STS))
+	assert.Equal(t, 0, len(awsc.STS.GetCallerIdentityInputs))
+
+	release.DeployRoleARN = to.Strp("arn:aws:iam::123456789012:role/deployer")
+	assert.NoError(t, release.ValidateDeployRole(awsc.STSClient(nil, release.AwsAccountID, release.DeployRole())))
+
+	awsc.STS.DenyRole("deployer")
+	err := release.ValidateDeployRole(awsc.STSClient(nil, release.AwsAccountID, release.DeployRole()))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be assumed")
+}
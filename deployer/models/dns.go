@@ -0,0 +1,236 @@
+package models
+
+import (
+	"fmt"
+
+	aws_route53 "github.com/aws/aws-sdk-go/service/route53"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/route53"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// DNS Cutover
+//////////
+
+// dnsWeight is the weight of the new releases record, previous records are set to 0
+const dnsWeight = 100
+
+// DNS moves traffic to the new fleet with a weighted CNAME record once it is healthy
+type DNS struct {
+	HostedZoneID *string `json:"hosted_zone_id,omitempty"`
+	RecordName   *string `json:"record_name,omitempty"`
+	Service      *string `json:"service,omitempty"` // The records target is this services first ELB
+	TTL          *int64  `json:"ttl,omitempty"`
+
+	// Controlled
+	Target          *string           `json:"target,omitempty"`
+	PreviousRecords []*route53.Record `json:"previous_records,omitempty"`
+}
+
+// SetDefaults assigns default values
+func (dns *DNS) SetDefaults() {
+	if dns.TTL == nil {
+		dns.TTL = to.Int64p(60)
+	}
+}
+
+// WipeControlledValues wipes values that are controlled by the deployer
+func (dns *DNS) WipeControlledValues() {
+	dns.Target = nil
+	dns.PreviousRecords = nil
+}
+
+// ValidateDNS validates the DNS block
+func (release *Release) ValidateDNS() error {
+	dns := release.DNS
+	if dns == nil {
+		return nil
+	}
+
+	if is.EmptyStr(dns.HostedZoneID) || is.EmptyStr(dns.RecordName) || is.EmptyStr(dns.Service) {
+		return fmt.Errorf("DNS hosted_zone_id, record_name and service must be defined")
+	}
+
+	service, ok := release.Services[*dns.Service]
+	if !ok || service == nil {
+		return fmt.Errorf("DNS service %q not found", *dns.Service)
+	}
+
+	if len(service.ELBs) == 0 {
+		return fmt.Errorf("DNS service %q must have an ELB", *dns.Service)
+	}
+
+	if dns.TTL != nil && (*dns.TTL < 0 || *dns.TTL > 86400) {
+		return fmt.Errorf("DNS ttl must be between 0 and 86400")
+	}
+
+	return nil
+}
+
+// dnsTarget is the DNS name of the DNS services first ELB. The ELB must not be attached to the services
+// previous ASG, both releases would be behind the same name and the cutover would not move any traffic
+func (release *Release) dnsTarget(resources *ReleaseResources) (*string, error) {
+	sr := resources.ServiceResources[*release.DNS.Service]
+	if sr == nil || len(sr.ELBs) == 0 || sr.ELBs[0] == nil || is.EmptyStr(sr.ELBs[0].DNSName) {
+		return nil, fmt.Errorf("DNS service %q ELB DNS name not found", *release.DNS.Service)
+	}
+
+	target := sr.ELBs[0]
+	if sr.PrevASG != nil && target.LoadBalancerName != nil && containsStrp(sr.PrevASG.LoadBalancerNames, *target.LoadBalancerName) {
+		return nil, fmt.Errorf("DNS service %q ELB %v is shared with the previous ASG %v, the cutover would not move traffic", *release.DNS.Service, *target.LoadBalancerName, to.Strs(sr.PrevASG.AutoScalingGroupName))
+	}
+
+	return target.DNSName, nil
+}
+
+func (release *Release) dnsRecord() *route53.Record {
+	return &route53.Record{
+		Name:          release.DNS.RecordName,
+		SetIdentifier: release.ReleaseID,
+		Target:        release.DNS.Target,
+		Weight:        to.Int64p(dnsWeight),
+		TTL:           release.DNS.TTL,
+	}
+}
+
+// dnsRecords splits the weighted records into this releases record and the others
+func (release *Release) dnsRecords(r53c aws.Route53API) (*route53.Record, []*route53.Record, error) {
+	records, err := route53.FindWeightedRecords(r53c, release.DNS.HostedZoneID, release.DNS.RecordName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var current *route53.Record
+	others := []*route53.Record{}
+	for _, record := range records {
+		if to.Strs(record.SetIdentifier) == to.Strs(release.ReleaseID) {
+			current = record
+		} else {
+			others = append(others, record)
+		}
+	}
+
+	return current, others, nil
+}
+
+// DNSPreviousRecordsPath is where CutoverDNS saves the previous records before it sets their weights to 0
+func (release *Release) DNSPreviousRecordsPath() *string {
+	s := fmt.Sprintf("%v/dns_previous_records", *release.ReleaseDir())
+	return &s
+}
+
+// CutoverDNS points the weighted record at the new fleet and sets the previous records weights to 0.
+// The previous records are saved to S3 before the change so a failure can restore them, a retried or caught
+// state only has its input. If this releases record exists an earlier attempt already cut over, so the
+// records are not changed again and the saved records are kept
+func (release *Release) CutoverDNS(s3c aws.S3API, r53c aws.Route53API) error {
+	if release.DNS == nil {
+		return nil
+	}
+
+	current, previous, err := release.dnsRecords(r53c)
+	if err != nil {
+		return err
+	}
+
+	if current != nil {
+		saved, err := release.savedPreviousRecords(s3c)
+		if err != nil {
+			return fmt.Errorf("DNS record %v is cut over but its previous records are not saved %v", to.Strs(release.DNS.RecordName), err.Error())
+		}
+
+		release.DNS.PreviousRecords = saved
+		return nil
+	}
+
+	if err := s3.PutStruct(s3c, release.Bucket, release.DNSPreviousRecordsPath(), previous); err != nil {
+		return err
+	}
+
+	changes := []*aws_route53.Change{release.dnsRecord().Upsert()}
+	for _, record := range previous {
+		off := *record
+		off.Weight = to.Int64p(0)
+		changes = append(changes, off.Upsert())
+	}
+
+	if err := route53.Change(r53c, release.DNS.HostedZoneID, fmt.Sprintf("odin cutover %v", to.Strs(release.ReleaseID)), changes); err != nil {
+		return err
+	}
+
+	release.DNS.PreviousRecords = previous
+	return nil
+}
+
+// CleanUpDNS deletes every weighted record for the name except the new releases
+func (release *Release) CleanUpDNS(r53c aws.Route53API) error {
+	if release.DNS == nil {
+		return nil
+	}
+
+	_, previous, err := release.dnsRecords(r53c)
+	if err != nil {
+		return err
+	}
+
+	changes := []*aws_route53.Change{}
+	for _, record := range previous {
+		changes = append(changes, record.Delete())
+	}
+
+	return route53.Change(r53c, release.DNS.HostedZoneID, fmt.Sprintf("odin cleanup %v", to.Strs(release.ReleaseID)), changes)
+}
+
+// savedPreviousRecords returns the previous records CutoverDNS saved
+func (release *Release) savedPreviousRecords(s3c aws.S3API) ([]*route53.Record, error) {
+	var previous []*route53.Record
+	if err := s3.GetStruct(s3c, release.Bucket, release.DNSPreviousRecordsPath(), &previous); err != nil {
+		return nil, err
+	}
+
+	return previous, nil
+}
+
+// RevertDNS deletes the new releases record and restores the previous records weights
+// A release that failed in CutoverDNS has no PreviousRecords, so the records CutoverDNS saved are restored
+func (release *Release) RevertDNS(s3c aws.S3API, r53c aws.Route53API) error {
+	if release.DNS == nil {
+		return nil
+	}
+
+	current, _, err := release.dnsRecords(r53c)
+	if err != nil {
+		return err
+	}
+
+	if current == nil {
+		return nil // Never cut over or already reverted
+	}
+
+	previous := release.DNS.PreviousRecords
+	if previous == nil {
+		previous, err = release.savedPreviousRecords(s3c)
+		if err != nil {
+			return fmt.Errorf("DNS previous records of %v not found %v", to.Strs(release.DNS.RecordName), err.Error())
+		}
+	}
+
+	changes := []*aws_route53.Change{current.Delete()}
+	for _, record := range previous {
+		changes = append(changes, record.Upsert())
+	}
+
+	return route53.Change(r53c, release.DNS.HostedZoneID, fmt.Sprintf("odin revert %v", to.Strs(release.ReleaseID)), changes)
+}
+
+// RevertTraffic moves traffic back to the previous release, restoring the DNS records and listener rules
+func (release *Release) RevertTraffic(s3c aws.S3API, r53c aws.Route53API, albc aws.ALBAPI) error {
+	if err := release.RevertDNS(s3c, r53c); err != nil {
+		return err
+	}
+
+	return release.RevertListenerRules(albc)
+}
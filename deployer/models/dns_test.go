@@ -0,0 +1,112 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockDNSRelease(t *testing.T) *Release {
+	release := MockRelease(t)
+	release.DNS = &DNS{
+		HostedZoneID: to.Strp("zone"),
+		RecordName:   to.Strp("web.example.com"),
+		Service:      to.Strp("web"),
+	}
+	MockPrepareRelease(release)
+	return release
+}
+
+func Test_Release_ValidateDNS(t *testing.T) {
+	release := mockDNSRelease(t)
+	assert.NoError(t, release.ValidateDNS())
+	assert.Equal(t, int64(60), *release.DNS.TTL)
+
+	release.DNS.Service = to.Strp("missing")
+	assert.Error(t, release.ValidateDNS())
+
+	release = mockDNSRelease(t)
+	release.Services["web"].ELBs = nil
+	assert.Error(t, release.ValidateDNS())
+
+	release = mockDNSRelease(t)
+	release.DNS.HostedZoneID = nil
+	assert.Error(t, release.ValidateDNS())
+
+	release = mockDNSRelease(t)
+	release.DNS.TTL = to.Int64p(-1)
+	assert.Error(t, release.ValidateDNS())
+}
+
+func Test_Release_DNS_Target(t *testing.T) {
+	release := mockDNSRelease(t)
+	awsc := MockAwsClients(release)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+
+	release.UpdateWithResources(resources)
+	assert.Equal(t, "web-elb.elb.amazonaws.com", *release.DNS.Target)
+}
+
+func Test_Release_DNS_Target_Shared_ELB(t *testing.T) {
+	release := mockDNSRelease(t)
+	awsc := MockAwsClients(release)
+
+	// The previous ASG is behind the same ELB so the cutover would not move traffic
+	prev := awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0]
+	prev.LoadBalancerNames = []*string{to.Strp("web-elb")}
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is shared with the previous ASG")
+}
+
+func Test_Release_RevertDNS_BeforeCutover(t *testing.T) {
+	release := mockDNSRelease(t)
+	awsc := MockAwsClients(release)
+	awsc.Route53.AddWeightedRecord("zone", "web.example.com.", "old-release", "old-elb", 100)
+
+	// Failing before the cutover leaves the previous record alone
+	assert.NoError(t, release.RevertDNS(awsc.S3, awsc.Route53))
+	assert.Equal(t, 0, len(awsc.Route53.ChangeResourceRecordSetsInputs))
+
+	release.DNS.Target = to.Strp("web-elb")
+	assert.NoError(t, release.CutoverDNS(awsc.S3, awsc.Route53))
+	assert.Equal(t, map[string]int64{"old-release": 0, *release.ReleaseID: 100}, awsc.Route53.WeightedRecords("zone", "web.example.com."))
+
+	// Reverting twice is safe
+	assert.NoError(t, release.RevertDNS(awsc.S3, awsc.Route53))
+	assert.NoError(t, release.RevertDNS(awsc.S3, awsc.Route53))
+	assert.Equal(t, map[string]int64{"old-release": 100}, awsc.Route53.WeightedRecords("zone", "web.example.com."))
+}
+
+func Test_Release_CutoverDNS_Retried(t *testing.T) {
+	release := mockDNSRelease(t)
+	release.DNS.Target = to.Strp("web-elb")
+	awsc := MockAwsClients(release)
+	awsc.Route53.AddWeightedRecord("zone", "web.example.com.", "old-release", "old-elb", 60)
+	awsc.Route53.AddWeightedRecord("zone", "web.example.com.", "older-release", "older-elb", 40)
+
+	assert.NoError(t, release.CutoverDNS(awsc.S3, awsc.Route53))
+	assert.Equal(t, 1, len(awsc.Route53.ChangeResourceRecordSetsInputs))
+
+	// A retry has the states input, the records are not changed again and the saved weights are kept
+	release.DNS.PreviousRecords = nil
+	assert.NoError(t, release.CutoverDNS(awsc.S3, awsc.Route53))
+	assert.Equal(t, 1, len(awsc.Route53.ChangeResourceRecordSetsInputs))
+	assert.Equal(t, 2, len(release.DNS.PreviousRecords))
+	for _, record := range release.DNS.PreviousRecords {
+		assert.NotEqual(t, int64(0), *record.Weight)
+	}
+
+	// A caught state also has its input, the saved weights are restored
+	release.DNS.PreviousRecords = nil
+	assert.NoError(t, release.RevertDNS(awsc.S3, awsc.Route53))
+	assert.Equal(t, map[string]int64{"old-release": 60, "older-release": 40}, awsc.Route53.WeightedRecords("zone", "web.example.com."))
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/alb"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Drain
+//////////
+
+// drainDuration is the seconds detached instances take to deregister from the services
+// ELBs and target groups, the largest connection draining timeout or deregistration delay capped by DrainTimeout
+func (service *Service) drainDuration(sr *ServiceResources) int {
+	drain := 0
+	for _, lb := range sr.ELBs {
+		if lb != nil && lb.ConnectionDrainingTimeout > drain {
+			drain = lb.ConnectionDrainingTimeout
+		}
+	}
+
+	for _, tg := range sr.TargetGroups {
+		if tg != nil && tg.DeregistrationDelay > drain {
+			drain = tg.DeregistrationDelay
+		}
+	}
+
+	if service.DrainTimeout != nil && *service.DrainTimeout < drain {
+		drain = *service.DrainTimeout
+	}
+
+	return drain
+}
+
+// ValidateDrainFirst validates DrainFirst
+func (release *Release) ValidateDrainFirst() error {
+	if release.DrainFirst && release.IsSkipDetachStep() {
+		return fmt.Errorf("DrainFirst cannot be used with the SkipDetach DetachStrategy, instances are never deregistered")
+	}
+
+	return nil
+}
+
+// CheckDrained returns a DrainError while any instance of the previous ASGs is draining from its services target groups
+func (release *Release) CheckDrained(asgc aws.ASGAPI, albc aws.ALBAPI) error {
+	if !release.DrainFirst || release.InPlace || release.IsInstanceRefresh() {
+		return nil
+	}
+
+	asgs, err := asg.ForProjectConfigNOTReleaseID(asgc, release.ProjectName, release.ConfigName, release.ReleaseID)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range asgs {
+		service := release.Services[to.Strs(a.ServiceName())]
+		if service == nil || service.Resources == nil {
+			continue
+		}
+
+		ids := a.InstanceIDs()
+		if len(ids) == 0 {
+			continue
+		}
+
+		for _, tg := range service.Resources.TargetGroups {
+			draining, err := alb.DrainingInstances(albc, tg, ids)
+			if err != nil {
+				return err
+			}
+
+			if len(draining) > 0 {
+				return DrainError{fmt.Sprintf("asg %s has instances draining from %s %s", *a.ServiceID(), *tg, strings.Join(draining, ","))}
+			}
+		}
+	}
+
+	return nil
+}
+
+// DrainError is returned while instances are draining, cleanup retries until they have drained
+type DrainError struct {
+	Cause string
+}
+
+func (e DrainError) Error() string {
+	return fmt.Sprintf("DrainError: %v", e.Cause)
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_FetchResources_Stores_WaitForDrain(t *testing.T) {
+	// Mock target groups have a 30 second deregistration delay
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	awsc := MockAwsClients(r)
+	_, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.Equal(t, 30, *r.WaitForDrain)
+
+	// DrainTimeout caps the wait
+	r = MockRelease(t)
+	r.Services["web"].DrainTimeout = to.Intp(10)
+	MockPrepareRelease(r)
+	awsc = MockAwsClients(r)
+	_, err = r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, *r.WaitForDrain)
+
+	// Nothing drains if instances are not detached
+	r = MockRelease(t)
+	r.DetachStrategy = to.Strp("SkipDetach")
+	MockPrepareRelease(r)
+	awsc = MockAwsClients(r)
+	_, err = r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, *r.WaitForDrain)
+}
+
+func Test_Release_FetchResources_WaitForDrain_ELB_ConnectionDraining(t *testing.T) {
+	// The ELB drains for longer than the target group deregistration delay
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	awsc := MockAwsClients(r)
+	awsc.ELB.ConnectionDrainingTimeouts = map[string]int64{"web-elb": 60}
+	_, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.Equal(t, 60, *r.WaitForDrain)
+
+	// The target group deregistration delay is longer
+	r = MockRelease(t)
+	MockPrepareRelease(r)
+	awsc = MockAwsClients(r)
+	awsc.ELB.ConnectionDrainingTimeouts = map[string]int64{"web-elb": 20}
+	_, err = r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.Equal(t, 30, *r.WaitForDrain)
+}
+
+func Test_Service_DrainTimeout_Validate(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].DrainTimeout = to.Intp(0)
+	MockPrepareRelease(r)
+	assert.NoError(t, r.ValidateServices())
+
+	r.Services["web"].DrainTimeout = to.Intp(-1)
+	assert.Error(t, r.ValidateServices())
+
+	r.Services["web"].DrainTimeout = to.Intp(3601)
+	assert.Error(t, r.ValidateServices())
+}
+
+func Test_Release_ValidateDrainFirst(t *testing.T) {
+	r := MockRelease(t)
+	r.DrainFirst = true
+	MockPrepareRelease(r)
+	assert.NoError(t, r.ValidateDrainFirst())
+
+	// Instances are never deregistered so would never drain
+	r.DetachStrategy = to.Strp("SkipDetach")
+	assert.Error(t, r.ValidateDrainFirst())
+}
+
+func Test_Release_DrainFirst_Waits_Before_TearDown(t *testing.T) {
+	r := MockRelease(t)
+	r.DrainFirst = true
+	MockPrepareRelease(r)
+	awsc := MockAwsClients(r)
+
+	resources, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	r.UpdateWithResources(resources)
+
+	// The previous instance is still draining from the target group
+	awsc.ALB.SetTargetHealth("web-elb-target", "InstanceId1", "draining")
+	err = r.CheckDrained(awsc.ASG, awsc.ALB)
+	assert.Error(t, err)
+	assert.IsType(t, DrainError{}, err)
+	assert.Contains(t, err.Error(), "draining from web-elb-target InstanceId1")
+	assert.Equal(t, 0, len(awsc.ASG.UpdateAutoScalingGroupInputs))
+	assert.Equal(t, 0, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+
+	// Once drained the previous ASG is scaled to zero then deleted
+	awsc.ALB.SetTargetHealth("web-elb-target", "InstanceId1", "unused")
+	assert.NoError(t, r.CheckDrained(awsc.ASG, awsc.ALB))
+	assert.NoError(t, r.SuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+
+	assert.Equal(t, 1, len(awsc.ASG.UpdateAutoScalingGroupInputs))
+	assert.Equal(t, int64(0), *awsc.ASG.UpdateAutoScalingGroupInputs[0].DesiredCapacity)
+	assert.Equal(t, 1, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, *awsc.ASG.UpdateAutoScalingGroupInputs[0].AutoScalingGroupName, *awsc.ASG.DeleteAutoScalingGroupInputs[0].AutoScalingGroupName)
+}
+
+func Test_Release_CheckDrained_Without_DrainFirst(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	awsc := MockAwsClients(r)
+
+	resources, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	r.UpdateWithResources(resources)
+
+	// Draining is not checked and the ASG is deleted without scaling to zero
+	awsc.ALB.SetTargetHealth("web-elb-target", "InstanceId1", "draining")
+	assert.NoError(t, r.CheckDrained(awsc.ASG, awsc.ALB))
+	assert.NoError(t, r.SuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+	assert.Equal(t, 0, len(awsc.ASG.UpdateAutoScalingGroupInputs))
+	assert.Equal(t, 1, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/coinbase/odin/aws"
+	s3s "github.com/coinbase/step/aws/s3"
+)
+
+// Event log markers
+const (
+	EventStart   = "start"
+	EventSuccess = "success"
+	EventError   = "error"
+)
+
+// Event is one object of the releases event log
+type Event struct {
+	Time  time.Time `json:"time"`
+	State string    `json:"state"`
+	Event string    `json:"event"`
+	Error *string   `json:"error,omitempty"`
+}
+
+// EventLogDir returns the prefix of the event log, each event is one object under it
+func (release *Release) EventLogDir() *string {
+	s := fmt.Sprintf("%v/events/", *release.ReleaseDir())
+	return &s
+}
+
+// eventPath returns the path of the event, keys sort in the order the events happened
+func (release *Release) eventPath(event *Event) *string {
+	s := fmt.Sprintf("%v%020d-%v-%v.json", *release.EventLogDir(), event.Time.UnixNano(), event.State, event.Event)
+	return &s
+}
+
+// PutEvent writes the event to the event log in S3
+// Each event is its own object so writing one never reads or rewrites the log
+func (release *Release) PutEvent(s3c aws.S3API, event *Event) error {
+	// A release that failed before it was validated may have no path
+	if release.Bucket == nil || release.AwsAccountID == nil || release.ProjectName == nil || release.ConfigName == nil || release.ReleaseID == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return s3s.Put(s3c, release.Bucket, release.eventPath(event), &raw)
+}
+
+// lastEventPath returns the path of the last event in the event log, nil if nothing is logged
+func (release *Release) lastEventPath(s3c aws.S3API) (*string, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: release.Bucket,
+		Prefix: release.EventLogDir(),
+	}
+
+	var last *string
+	for {
+		out, err := s3c.ListObjectsV2(input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range out.Contents {
+			if obj.Key != nil && (last == nil || *obj.Key > *last) {
+				last = obj.Key
+			}
+		}
+
+		if out.IsTruncated == nil || !*out.IsTruncated || out.NextContinuationToken == nil {
+			return last, nil
+		}
+		input.ContinuationToken = out.NextContinuationToken
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_PutEvent(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	s3c := &mocks.S3Client{}
+
+	start := time.Now()
+	assert.NoError(t, release.PutEvent(s3c, &Event{Time: start, State: "Validate", Event: EventStart}))
+	assert.NoError(t, release.PutEvent(s3c, &Event{Time: start.Add(time.Second), State: "Validate", Event: EventSuccess}))
+
+	// Each event is its own object, the log is never read
+	keys := s3c.PutObjectKeys()
+	assert.Equal(t, 2, len(keys))
+	assert.True(t, strings.HasPrefix(keys[0], *release.EventLogDir()))
+	assert.Regexp(t, `"event":"start"`, s3c.GetObjectResp[keys[0]].Body)
+	assert.Regexp(t, `"event":"success"`, s3c.GetObjectResp[keys[1]].Body)
+
+	last, err := release.lastEventPath(s3c)
+	assert.NoError(t, err)
+	assert.Equal(t, keys[1], *last)
+
+	// A release without a path is not logged
+	release.ProjectName = nil
+	assert.NoError(t, release.PutEvent(s3c, &Event{Time: time.Now(), State: "Validate", Event: EventStart}))
+	assert.Equal(t, 2, len(s3c.PutObjectInputs))
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/bifrost"
+	"github.com/coinbase/step/utils/to"
+)
+
+// FailureReport is written by a failed release and describes what ran, why it failed and what was rolled back
+type FailureReport struct {
+	ProjectName *string `json:"project_name,omitempty"`
+	ConfigName  *string `json:"config_name,omitempty"`
+	ReleaseID   *string `json:"release_id,omitempty"`
+	ReleaseUUID *string `json:"release_uuid,omitempty"`
+
+	// Path is the ExecutionPath of the release ending with CleanUpFailure, states that loop are recorded once
+	Path  []string              `json:"path"`
+	Error *bifrost.ReleaseError `json:"error,omitempty"`
+
+	// CleanedUp is what CleanUpFailure deleted of the release
+	CleanedUp *FailureCleanUp `json:"cleaned_up"`
+
+	// PreviousFleetIntact is true if every services previous ASG still has its desired capacity InService
+	PreviousFleetIntact bool                      `json:"previous_fleet_intact"`
+	PreviousFleet       map[string]*PreviousFleet `json:"previous_fleet"`
+}
+
+// FailureCleanUp is the resources of the release deleted by CleanUpFailure
+type FailureCleanUp struct {
+	AutoScalingGroups    []*string `json:"autoscaling_groups"`
+	LaunchTemplates      []*string `json:"launch_templates"`
+	LaunchConfigurations []*string `json:"launch_configurations"`
+}
+
+// PreviousFleet is the state of a services previous ASG after the clean up
+type PreviousFleet struct {
+	AutoScalingGroupName *string `json:"autoscaling_group_name,omitempty"`
+	DesiredCapacity      *int64  `json:"desired_capacity,omitempty"`
+	InService            int     `json:"in_service"`
+	Intact               bool    `json:"intact"`
+}
+
+func newFailureCleanUp() *FailureCleanUp {
+	return &FailureCleanUp{
+		AutoScalingGroups:    []*string{},
+		LaunchTemplates:      []*string{},
+		LaunchConfigurations: []*string{},
+	}
+}
+
+// FailureReportPath returns the path of the failure report of the release with the UUID
+func (release *Release) FailureReportPath() *string {
+	s := fmt.Sprintf("%v/failures/%v", *release.RootDir(), to.Strs(release.UUID))
+	return &s
+}
+
+// CreateFailureReport returns the path, error and clean up of the release, and the state of the previous ASGs
+func (release *Release) CreateFailureReport(asgc aws.ASGAPI) (*FailureReport, error) {
+	cleanedUp := release.cleanedUp
+	if cleanedUp == nil {
+		cleanedUp = newFailureCleanUp()
+	}
+
+	report := &FailureReport{
+		ProjectName:         release.ProjectName,
+		ConfigName:          release.ConfigName,
+		ReleaseID:           release.ReleaseID,
+		ReleaseUUID:         release.UUID,
+		Path:                append(append([]string{}, release.ExecutionPath...), "CleanUpFailure"),
+		Error:               release.Error,
+		CleanedUp:           cleanedUp,
+		PreviousFleetIntact: true,
+		PreviousFleet:       map[string]*PreviousFleet{},
+	}
+
+	// Listing the project config avoids one describe call per service
+	previous, err := asg.ForProjectConfigNOTReleaseID(asgc, release.ProjectName, release.ConfigName, release.ReleaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string]*asg.ASG{}
+	for _, group := range previous {
+		groups[to.Strs(group.AutoScalingGroupName)] = group
+	}
+
+	for name, service := range release.Services {
+		if service == nil || service.Resources == nil || service.Resources.PrevASG == nil {
+			continue
+		}
+
+		fleet := &PreviousFleet{AutoScalingGroupName: service.Resources.PrevASG}
+		if group, ok := groups[*service.Resources.PrevASG]; ok {
+			fleet.DesiredCapacity = group.DesiredCapacity
+			fleet.InService = len(group.LifecycleStateIDs("InService"))
+			fleet.Intact = group.DesiredCapacity != nil && *group.DesiredCapacity > 0 && int64(fleet.InService) >= *group.DesiredCapacity
+		}
+
+		if !fleet.Intact {
+			report.PreviousFleetIntact = false
+		}
+
+		report.PreviousFleet[name] = fleet
+	}
+
+	return report, nil
+}
+
+// WriteFailureReport writes the failure report of the release to S3 and sets it as the releases FailureReport
+// It must be called after UnsuccessfulTearDown, which records what it deleted
+func (release *Release) WriteFailureReport(s3c aws.S3API, asgc aws.ASGAPI) error {
+	report, err := release.CreateFailureReport(asgc)
+	if err != nil {
+		return err
+	}
+
+	if err := s3.PutStruct(s3c, release.Bucket, release.FailureReportPath(), report); err != nil {
+		return err
+	}
+
+	release.FailureReport = report
+	return nil
+}
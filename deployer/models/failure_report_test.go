@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/bifrost"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_WriteFailureReport_Works(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+	awsc.ASG.TrackCreated = true
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	release.ExecutionPath = []string{"Validate", "Lock", "Deploy", "CheckHealthy"}
+	release.Error = &bifrost.ReleaseError{Error: to.Strp("HaltError"), Cause: to.Strp("Timeout")}
+
+	assert.NoError(t, release.UnsuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+	assert.NoError(t, release.WriteFailureReport(awsc.S3, awsc.ASG))
+
+	report := release.FailureReport
+	assert.Equal(t, []string{"Validate", "Lock", "Deploy", "CheckHealthy", "CleanUpFailure"}, report.Path)
+	assert.Equal(t, "Timeout", *report.Error.Cause)
+
+	// The launch configuration is deleted with its ASG so is not listed again
+	webID := *release.Services["web"].ServiceID()
+	assert.Equal(t, []string{webID}, to.StrSlice(report.CleanedUp.AutoScalingGroups))
+	assert.Equal(t, 0, len(report.CleanedUp.LaunchTemplates))
+	assert.Equal(t, 0, len(report.CleanedUp.LaunchConfigurations))
+
+	assert.True(t, report.PreviousFleetIntact)
+	assert.Equal(t, "project-config-web-old-release", *report.PreviousFleet["web"].AutoScalingGroupName)
+	assert.Equal(t, int64(1), *report.PreviousFleet["web"].DesiredCapacity)
+	assert.Equal(t, 1, report.PreviousFleet["web"].InService)
+
+	var written FailureReport
+	assert.NoError(t, s3.GetStruct(awsc.S3, release.Bucket, release.FailureReportPath(), &written))
+	assert.Equal(t, report, &written)
+}
+
+func Test_Release_CreateFailureReport_PreviousFleet_Gone(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	release.Services["web"].Resources = &ServiceResourceNames{PrevASG: to.Strp("project-config-web-gone-release")}
+
+	report, err := release.CreateFailureReport(awsc.ASG)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"CleanUpFailure"}, report.Path)
+	assert.Equal(t, 0, len(report.CleanedUp.AutoScalingGroups))
+
+	assert.False(t, report.PreviousFleetIntact)
+	assert.False(t, report.PreviousFleet["web"].Intact)
+	assert.Nil(t, report.PreviousFleet["web"].DesiredCapacity)
+}
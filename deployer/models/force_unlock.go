@@ -0,0 +1,145 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/errors"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Force Unlock
+//////////
+
+// TakeOverStaleLock releases the project config lock left behind by a release whose execution is
+// no longer running, so GrabLocks can be retried. Only an execution of another release of this
+// project config can hold the lock, if one is RUNNING the lock is live and a LockExistsError is returned
+func (release *Release) TakeOverStaleLock(s3c aws.S3API, sfnc aws.SFNAPI, locker Locker, lockTableName string, stateMachineArn *string) error {
+	var lock s3.Lock
+	if err := s3.GetStruct(s3c, release.Bucket, release.RootLockPath(), &lock); err != nil {
+		switch err.(type) {
+		case *s3.NotFoundError:
+			return &errors.LockExistsError{fmt.Sprintf("ForceUnlock cannot find the lock at %v", *release.RootLockPath())}
+		default:
+			return &errors.LockExistsError{err.Error()}
+		}
+	}
+
+	if lock.UUID == "" || lock.UUID == *release.UUID {
+		return nil // Nothing to take over
+	}
+
+	running, err := release.runningExecution(sfnc, stateMachineArn)
+	if err != nil {
+		return &errors.LockExistsError{fmt.Sprintf("ForceUnlock cannot check executions: %v", err.Error())}
+	}
+
+	if running != nil {
+		return &errors.LockExistsError{fmt.Sprintf("ForceUnlock lock is held by running execution %v", *running)}
+	}
+
+	// Release the generic lock first, the S3 lock is how the holder is found
+	if err := locker.ReleaseLock(lockTableName, *release.RootLockPath(), lock.UUID); err != nil {
+		return &errors.LockExistsError{err.Error()}
+	}
+
+	if err := s3.ReleaseLock(s3c, release.Bucket, release.RootLockPath(), lock.UUID); err != nil {
+		return &errors.LockExistsError{err.Error()}
+	}
+
+	return nil
+}
+
+// runningExecution returns the ARN of a RUNNING execution deploying another release of this project config
+func (release *Release) runningExecution(sfnc aws.SFNAPI, stateMachineArn *string) (*string, error) {
+	var running *string
+	err := forEachRunningExecution(sfnc, stateMachineArn, func(item *sfn.ExecutionListItem) (bool, error) {
+		arn, err := release.otherReleaseExecution(sfnc, item)
+		running = arn
+		return arn != nil, err
+	})
+
+	return running, err
+}
+
+// forEachRunningExecution calls fn with the RUNNING executions of the deployer state machine and of its
+// rollback state machine, both deploy releases. It stops at the first error or when fn returns true
+func forEachRunningExecution(sfnc aws.SFNAPI, stateMachineArn *string, fn func(*sfn.ExecutionListItem) (bool, error)) error {
+	arns := []*string{stateMachineArn}
+	if stateMachineArn != nil {
+		arns = append(arns, to.Strp(*stateMachineArn+RollbackStateMachineSuffix))
+	}
+
+	for _, arn := range arns {
+		input := &sfn.ListExecutionsInput{
+			StateMachineArn: arn,
+			StatusFilter:    to.Strp(sfn.ExecutionStatusRunning),
+		}
+
+		for {
+			out, err := sfnc.ListExecutions(input)
+			if err != nil {
+				return err
+			}
+
+			for _, item := range out.Executions {
+				stop, err := fn(item)
+				if err != nil || stop {
+					return err
+				}
+			}
+
+			if out.NextToken == nil {
+				break
+			}
+			input.NextToken = out.NextToken
+		}
+	}
+
+	return nil
+}
+
+// otherReleaseExecution returns the executions ARN if it is RUNNING a different release of this project config
+func (release *Release) otherReleaseExecution(sfnc aws.SFNAPI, item *sfn.ExecutionListItem) (*string, error) {
+	releaseID, err := release.runningReleaseID(sfnc, item)
+	if err != nil || releaseID == nil {
+		return nil, err
+	}
+
+	if *releaseID == to.Strs(release.ReleaseID) {
+		return nil, nil // This release
+	}
+
+	return item.ExecutionArn, nil
+}
+
+// runningReleaseID returns the release ID of the execution if it is RUNNING a release of this project config
+func (release *Release) runningReleaseID(sfnc aws.SFNAPI, item *sfn.ExecutionListItem) (*string, error) {
+	prefix := release.ExecutionPrefix()
+	if item.Name == nil || len(*item.Name) < len(prefix) || (*item.Name)[0:len(prefix)] != prefix {
+		return nil, nil
+	}
+
+	exec, err := sfnc.DescribeExecution(&sfn.DescribeExecutionInput{ExecutionArn: item.ExecutionArn})
+	if err != nil {
+		return nil, err
+	}
+
+	if to.Strs(exec.Status) != sfn.ExecutionStatusRunning {
+		return nil, nil
+	}
+
+	var other struct {
+		ReleaseID *string `json:"release_id"`
+	}
+
+	if err := json.Unmarshal([]byte(to.Strs(exec.Input)), &other); err != nil {
+		return nil, fmt.Errorf("execution %v input: %v", *item.ExecutionArn, err.Error())
+	}
+
+	return to.Strp(to.Strs(other.ReleaseID)), nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/errors"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockStaleLockRelease(t *testing.T) (*Release, *mocks.MockClients) {
+	release := MockRelease(t)
+	release.ForceUnlock = true
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	// A dead execution left the lock behind
+	awsc.S3.AddGetObject(*release.RootLockPath(), `{"uuid": "dead"}`, nil)
+	awsc.DynamoDB.AddLock(*release.RootLockPath(), "dead")
+
+	// This execution is also running
+	awsc.SFN.AddExecution("arn:this", release.ExecutionPrefix()+"this", "RUNNING", map[string]string{"release_id": *release.ReleaseID})
+
+	return release, awsc
+}
+
+func Test_Release_TakeOverStaleLock(t *testing.T) {
+	release, awsc := mockStaleLockRelease(t)
+	awsc.SFN.AddExecution("arn:dead", release.ExecutionPrefix()+"dead", "FAILED", map[string]string{"release_id": "dead"})
+	locker := release.Locker(awsc.S3, awsc.DynamoDB)
+
+	assert.Error(t, release.GrabLocks(awsc.S3, locker, "locks"))
+	assert.NoError(t, release.TakeOverStaleLock(awsc.S3, awsc.SFN, locker, "locks", to.Strp("arn:sm")))
+	assert.NoError(t, release.GrabLocks(awsc.S3, locker, "locks"))
+	assert.Equal(t, *release.UUID, awsc.DynamoDB.Locks[*release.RootLockPath()])
+}
+
+func Test_Release_TakeOverStaleLock_Running(t *testing.T) {
+	release, awsc := mockStaleLockRelease(t)
+	awsc.SFN.AddExecution("arn:live", release.ExecutionPrefix()+"live", "RUNNING", map[string]string{"release_id": "live"})
+
+	// Executions of other project configs are ignored
+	awsc.SFN.AddExecution("arn:other", "deploy-other-config-other", "RUNNING", map[string]string{"release_id": "other"})
+	locker := release.Locker(awsc.S3, awsc.DynamoDB)
+
+	err := release.TakeOverStaleLock(awsc.S3, awsc.SFN, locker, "locks", to.Strp("arn:sm"))
+	assert.Error(t, err)
+	assert.IsType(t, &errors.LockExistsError{}, err)
+	assert.Regexp(t, "held by running execution arn:live", err.Error())
+	assert.Equal(t, "dead", awsc.DynamoDB.Locks[*release.RootLockPath()])
+	assert.Equal(t, 1, len(awsc.SFN.DescribeExecutionInputs))
+}
+
+func Test_Release_TakeOverStaleLock_NoLock(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+	locker := release.Locker(awsc.S3, awsc.DynamoDB)
+
+	err := release.TakeOverStaleLock(awsc.S3, awsc.SFN, locker, "locks", to.Strp("arn:sm"))
+	assert.IsType(t, &errors.LockExistsError{}, err)
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/alarms"
+	"github.com/coinbase/step/utils/is"
+)
+
+// validateHealthAlarms validates the health alarms
+func (service *Service) validateHealthAlarms() error {
+	if len(service.HealthAlarms) == 0 {
+		return nil
+	}
+
+	// DescribeAlarms accepts at most 100 names
+	if len(service.HealthAlarms) > 100 {
+		return fmt.Errorf("HealthAlarms must have at most 100 alarms")
+	}
+
+	if !is.UniqueStrp(service.HealthAlarms) {
+		return fmt.Errorf("Non Unique HealthAlarms")
+	}
+
+	for _, name := range service.HealthAlarms {
+		if is.EmptyStr(name) {
+			return fmt.Errorf("HealthAlarms cannot be empty")
+		}
+	}
+
+	return nil
+}
+
+// checkHealthAlarms returns a HaltError if any health alarm is in the ALARM state
+// The service is not healthy until every health alarm is OK, e.g. INSUFFICIENT_DATA keeps waiting
+func (service *Service) checkHealthAlarms(cwc aws.CWAPI) error {
+	if len(service.HealthAlarms) == 0 {
+		return nil
+	}
+
+	states, err := alarms.States(cwc, service.HealthAlarms)
+	if err != nil {
+		return err // This might retry
+	}
+
+	alarming := []string{}
+	for name, state := range states {
+		if state == "ALARM" {
+			alarming = append(alarming, name)
+		}
+	}
+
+	if len(alarming) > 0 {
+		sort.Strings(alarming)
+		err := fmt.Errorf("Health alarms in ALARM state %v, %v", *service.ServiceName, strings.Join(alarming, ","))
+		return &HaltError{err} // This will immediately stop deploying
+	}
+
+	for _, name := range service.HealthAlarms {
+		if states[*name] != "OK" {
+			// INSUFFICIENT_DATA alarms are not healthy yet
+			service.Healthy = false
+		}
+	}
+
+	return nil
+}
+
+// ValidateAlarms errors if a health or soak alarm does not exist
+// A missing alarm is never OK so the deploy would only fail once it timed out
+func (release *Release) ValidateAlarms(cwc aws.CWAPI) error {
+	for _, name := range sortedServiceNames(release) {
+		if _, err := alarms.States(cwc, release.Services[name].HealthAlarms); err != nil {
+			return fmt.Errorf("%v Service(%v) HealthAlarms %v", release.ErrorPrefix(), name, err)
+		}
+	}
+
+	if _, err := alarms.States(cwc, release.SoakAlarms); err != nil {
+		return fmt.Errorf("%v SoakAlarms %v", release.ErrorPrefix(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Service_ValidateHealthAlarms(t *testing.T) {
+	service := &Service{}
+	assert.NoError(t, service.validateHealthAlarms())
+
+	service.HealthAlarms = []*string{to.Strp("web-5xx"), to.Strp("web-latency")}
+	assert.NoError(t, service.validateHealthAlarms())
+
+	service.HealthAlarms = []*string{to.Strp("web-5xx"), to.Strp("web-5xx")}
+	assert.Error(t, service.validateHealthAlarms())
+
+	service.HealthAlarms = []*string{to.Strp("")}
+	assert.Error(t, service.validateHealthAlarms())
+}
+
+func Test_Release_UpdateHealthy_HealthAlarms(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].HealthAlarms = []*string{to.Strp("web-5xx")}
+	MockPrepareRelease(r)
+
+	awsc := MockAwsClients(r)
+	awsc.CW.AddAlarmStates("web-5xx", "INSUFFICIENT_DATA", "OK", "ALARM")
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// Insufficient data keeps waiting
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, *r.Healthy)
+
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, *r.Healthy)
+
+	err := r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP)
+	assert.Error(t, err)
+	assert.IsType(t, &HaltError{}, err)
+	assert.Contains(t, err.Error(), "web-5xx")
+}
+
+func Test_Release_UpdateHealthy_HealthAlarms_Missing(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].HealthAlarms = []*string{to.Strp("missing")}
+	MockPrepareRelease(r)
+
+	awsc := MockAwsClients(r)
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// Missing alarms error so CheckHealthy retries
+	err := r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP)
+	assert.Error(t, err)
+	_, halt := err.(*HaltError)
+	assert.False(t, halt)
+}
+
+func Test_Release_ValidateAlarms(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].HealthAlarms = []*string{to.Strp("web-5xx")}
+	r.SoakAlarms = []*string{to.Strp("soak-5xx")}
+	MockPrepareRelease(r)
+
+	awsc := MockAwsClients(r)
+	err := r.ValidateAlarms(awsc.CW)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "HealthAlarms")
+
+	awsc.CW.AddAlarm("web-5xx", "INSUFFICIENT_DATA")
+	err = r.ValidateAlarms(awsc.CW)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "SoakAlarms")
+
+	awsc.CW.AddAlarm("soak-5xx", "OK")
+	assert.NoError(t, r.ValidateAlarms(awsc.CW))
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"fmt"
+)
+
+//////////
+// Health Check Type
+//////////
+
+// HEALTH_CHECK_TYPES are the ways an ASG can decide an instance is unhealthy and replace it
+var HEALTH_CHECK_TYPES = []string{"EC2", "ELB"}
+
+// validateHealthCheckType validates the health_check_type
+func (service *Service) validateHealthCheckType() error {
+	if service.HealthCheckType == nil {
+		return nil
+	}
+
+	if !containsStr(HEALTH_CHECK_TYPES, *service.HealthCheckType) {
+		return fmt.Errorf("HealthCheckType %q must be one of %v", *service.HealthCheckType, HEALTH_CHECK_TYPES)
+	}
+
+	return nil
+}
+
+// validateHealthCheckType errors if the ELB health check type has no ELB or target group to check
+func (sr *ServiceResources) validateHealthCheckType(service *Service) error {
+	if service.HealthCheckType == nil || *service.HealthCheckType != "ELB" {
+		return nil
+	}
+
+	if len(sr.ELBs) == 0 && len(sr.TargetGroups) == 0 {
+		return fmt.Errorf("HealthCheckType ELB requires elbs or target_groups")
+	}
+
+	return nil
+}
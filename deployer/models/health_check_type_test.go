@@ -0,0 +1,77 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Service_validateHealthCheckType(t *testing.T) {
+	service := &Service{}
+	assert.NoError(t, service.validateHealthCheckType())
+
+	for _, hct := range []string{"EC2", "ELB"} {
+		service.HealthCheckType = to.Strp(hct)
+		assert.NoError(t, service.validateHealthCheckType())
+	}
+
+	for _, hct := range []string{"elb", "ALB", ""} {
+		service.HealthCheckType = to.Strp(hct)
+		assert.Error(t, service.validateHealthCheckType())
+	}
+}
+
+func Test_Release_HealthCheckType_And_DefaultCooldown_CreateResources(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(r *Release) {
+		r.Services["web"].HealthCheckType = to.Strp("EC2")
+		r.Services["web"].Autoscaling.DefaultCooldown = to.Int64p(120)
+	})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	created := *release.Services["web"].CreatedASG
+	assert.Equal(t, "EC2", awsc.ASG.HealthCheckTypes[created])
+	assert.Equal(t, int64(120), awsc.ASG.DefaultCooldowns[created])
+}
+
+func Test_Release_HealthCheckType_Default(t *testing.T) {
+	// The mock service has an ELB and a target group
+	release, awsc := mockSuspendProcessesRelease(t, func(*Release) {})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	created := *release.Services["web"].CreatedASG
+	assert.Equal(t, "ELB", awsc.ASG.HealthCheckTypes[created])
+	assert.Equal(t, int64(10), awsc.ASG.DefaultCooldowns[created])
+}
+
+func Test_Release_HealthCheckType_ValidateResources(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].HealthCheckType = to.Strp("ELB")
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+
+	// Without a load balancer there is nothing for the ELB health check to use
+	release.Services["web"].ELBs = nil
+	release.Services["web"].TargetGroups = nil
+
+	resources, err = release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "HealthCheckType ELB requires elbs or target_groups")
+}
+
+func Test_AutoScalingConfig_DefaultCooldown(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].Autoscaling.DefaultCooldown = to.Int64p(-1)
+	MockPrepareRelease(release)
+
+	assert.Error(t, release.Services["web"].Autoscaling.ValidateAttributes())
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Health Poll Backoff
+//////////
+
+// defaultHealthPollInterval and defaultHealthPollMaxInterval are used when only one is set
+const defaultHealthPollInterval = 15
+const defaultHealthPollMaxInterval = 300
+
+// backoffHealthPolls returns true if health checks back off instead of polling on a fixed interval
+func (release *Release) backoffHealthPolls() bool {
+	return release.HealthPollInterval != nil || release.HealthPollMaxInterval != nil
+}
+
+func (release *Release) healthPollInterval() int {
+	if release.HealthPollInterval == nil {
+		return defaultHealthPollInterval
+	}
+	return *release.HealthPollInterval
+}
+
+func (release *Release) healthPollMaxInterval() int {
+	if release.HealthPollMaxInterval == nil {
+		return defaultHealthPollMaxInterval
+	}
+	return *release.HealthPollMaxInterval
+}
+
+// healthPollWait returns the seconds most health checks, and soak checks, wait
+func (release *Release) healthPollWait() int {
+	if release.backoffHealthPolls() {
+		// Most health checks wait the max interval
+		return release.healthPollMaxInterval()
+	}
+	return *release.WaitForHealthy
+}
+
+// ValidateHealthPolls validates HealthPollInterval and HealthPollMaxInterval
+func (release *Release) ValidateHealthPolls() error {
+	if !release.backoffHealthPolls() {
+		return nil
+	}
+
+	interval, maxInterval := release.healthPollInterval(), release.healthPollMaxInterval()
+
+	if interval < 1 {
+		return fmt.Errorf("HealthPollInterval %v must be greater than 0", interval)
+	}
+
+	if maxInterval < interval || maxInterval > defaultHealthPollMaxInterval {
+		return fmt.Errorf("HealthPollMaxInterval %v must be between HealthPollInterval %v and %v", maxInterval, interval, defaultHealthPollMaxInterval)
+	}
+
+	return nil
+}
+
+// phaseRemaining returns the seconds left before PhaseTimedOut errors, or -1 if the release has not started
+func (release *Release) phaseRemaining() int {
+	if release.Timeout == nil || release.StartedAt == nil {
+		return -1
+	}
+
+	deadline := release.StartedAt.Add(time.Duration(release.deployTimeout()+release.launchExtension()) * time.Second)
+	if release.CapacityReachedAt != nil {
+		deadline = release.CapacityReachedAt.Add(time.Duration(release.healthyTimeout()+release.launchExtension()) * time.Second)
+	}
+
+	return int(time.Until(deadline).Seconds())
+}
+
+// BackoffHealthPoll doubles WaitForHealthy after an unhealthy check, up to the HealthPollMaxInterval.
+// The wait never passes the end of the phase, so the release still times out on time
+func (release *Release) BackoffHealthPoll() {
+	if !release.backoffHealthPolls() {
+		return
+	}
+
+	polls := 1
+	if release.HealthPolls != nil {
+		polls = *release.HealthPolls + 1
+	}
+	release.HealthPolls = to.Intp(polls)
+
+	wait, maxInterval := release.healthPollInterval(), release.healthPollMaxInterval()
+	for i := 0; i < polls && wait < maxInterval; i++ {
+		wait *= 2
+	}
+
+	if wait > maxInterval {
+		wait = maxInterval
+	}
+
+	// Check once more right as the phase times out
+	if remaining := release.phaseRemaining(); remaining >= 0 && wait > remaining+1 {
+		wait = remaining + 1
+	}
+
+	release.WaitForHealthy = to.Intp(wait)
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidateHealthPolls(t *testing.T) {
+	release := MockRelease(t)
+	assert.NoError(t, release.ValidateHealthPolls())
+
+	release.HealthPollInterval = to.Intp(5)
+	assert.NoError(t, release.ValidateHealthPolls())
+	assert.Equal(t, 300, release.healthPollMaxInterval())
+
+	release.HealthPollMaxInterval = to.Intp(4)
+	assert.Error(t, release.ValidateHealthPolls())
+
+	release.HealthPollMaxInterval = to.Intp(301)
+	assert.Error(t, release.ValidateHealthPolls())
+
+	release.HealthPollInterval = to.Intp(0)
+	release.HealthPollMaxInterval = to.Intp(60)
+	assert.Error(t, release.ValidateHealthPolls())
+}
+
+func Test_Release_BackoffHealthPoll(t *testing.T) {
+	release := MockRelease(t)
+	release.Timeout = to.Intp(600)
+	release.SetDefaults()
+
+	// Fixed interval without backoff
+	release.BackoffHealthPoll()
+	assert.Equal(t, 15, *release.WaitForHealthy)
+	assert.Nil(t, release.HealthPolls)
+
+	release.HealthPollInterval = to.Intp(5)
+	release.HealthPollMaxInterval = to.Intp(30)
+	release.SetDefaults()
+	assert.Equal(t, 5, *release.WaitForHealthy)
+
+	waits := []int{}
+	for i := 0; i < 4; i++ {
+		release.BackoffHealthPoll()
+		waits = append(waits, *release.WaitForHealthy)
+	}
+	assert.Equal(t, []int{10, 20, 30, 30}, waits)
+
+	// Never waits past the end of the phase
+	release.StartedAt = to.Timep(time.Now().Add(-590 * time.Second))
+	release.BackoffHealthPoll()
+	assert.True(t, *release.WaitForHealthy <= 11)
+}
+
+func Test_Release_BackoffHealthPoll_LongHealthyTimeout(t *testing.T) {
+	release := MockRelease(t)
+	release.Timeout = to.Intp(7800)
+	release.HealthyTimeout = to.Intp(7200)
+	release.HealthPollInterval = to.Intp(5)
+	release.HealthPollMaxInterval = to.Intp(300)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	awsc.ALB.SetTargetHealth("web-elb-target", "InstanceId1", "unhealthy")
+
+	now := time.Now()
+	release.StartedAt = to.Timep(now.Add(-600 * time.Second))
+	release.CapacityReachedAt = to.Timep(now)
+
+	// Each wait moves the phase start back instead of sleeping
+	elapsed := 0
+	for release.PhaseTimedOut() == nil {
+		release.SetDefaults()
+		assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+		assert.False(t, *release.Healthy)
+		release.BackoffHealthPoll()
+
+		wait := time.Duration(*release.WaitForHealthy) * time.Second
+		elapsed += *release.WaitForHealthy
+		release.StartedAt = to.Timep(release.StartedAt.Add(-wait))
+		release.CapacityReachedAt = to.Timep(release.CapacityReachedAt.Add(-wait))
+	}
+
+	// A fixed 60 second interval would describe target health 120 times
+	assert.True(t, awsc.ALB.Calls("DescribeTargetHealth") < 40, "%v calls", awsc.ALB.Calls("DescribeTargetHealth"))
+	assert.True(t, elapsed <= 7200+2, "%v seconds", elapsed)
+}
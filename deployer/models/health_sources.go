@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Health Sources
+//////////
+
+// HealthSourceASG counts an instance healthy once the ASG has it Healthy and InService
+const HealthSourceASG = "asg"
+
+// HealthSourceELB counts an instance healthy once it is InService in every ELB
+const HealthSourceELB = "elb"
+
+// HealthSourceTargetGroup counts an instance healthy once it is healthy in every target group
+const HealthSourceTargetGroup = "target_group"
+
+// HEALTH_SOURCES are where an instances health is read from, an instance is healthy only if all agree
+var HEALTH_SOURCES = []string{HealthSourceASG, HealthSourceELB, HealthSourceTargetGroup}
+
+// validateHealthSources validates the health_sources
+func (service *Service) validateHealthSources() error {
+	if service.HealthSources == nil {
+		return nil
+	}
+
+	if len(service.HealthSources) == 0 {
+		return fmt.Errorf("HealthSources must have at least one of %v", HEALTH_SOURCES)
+	}
+
+	if !is.UniqueStrp(service.HealthSources) {
+		return fmt.Errorf("Non Unique HealthSources")
+	}
+
+	for _, source := range service.HealthSources {
+		switch {
+		case source == nil || !containsStr(HEALTH_SOURCES, *source):
+			return fmt.Errorf("HealthSources %q must be one of %v", to.Strs(source), HEALTH_SOURCES)
+		case *source == HealthSourceELB && len(service.ELBs) == 0:
+			return fmt.Errorf("HealthSources %v requires elbs", HealthSourceELB)
+		case *source == HealthSourceTargetGroup && len(service.TargetGroups) == 0:
+			return fmt.Errorf("HealthSources %v requires target_groups", HealthSourceTargetGroup)
+		}
+	}
+
+	return nil
+}
+
+// healthSource returns true if the instances health is read from source, by default it is read from all of them
+func (service *Service) healthSource(source string) bool {
+	if service.HealthSources == nil {
+		return true
+	}
+
+	return containsStrp(service.HealthSources, source)
+}
+
+// ignoreASGHealth leaves the health of the instances to the load balancers if the ASG is not a health source.
+// Terminating instances are still terminating
+func (service *Service) ignoreASGHealth(all aws.Instances) {
+	if service.healthSource(HealthSourceASG) {
+		return
+	}
+
+	all.SetHealthy(all.UnhealthyIDs())
+}
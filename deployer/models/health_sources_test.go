@@ -0,0 +1,79 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Service_ValidateHealthSources(t *testing.T) {
+	release := MockRelease(t)
+	service := release.Services["web"]
+	assert.NoError(t, service.validateHealthSources())
+
+	service.HealthSources = []*string{to.Strp("asg"), to.Strp("target_group")}
+	assert.NoError(t, service.validateHealthSources())
+
+	service.HealthSources = []*string{}
+	assert.Error(t, service.validateHealthSources())
+
+	service.HealthSources = []*string{to.Strp("asg"), to.Strp("asg")}
+	assert.Error(t, service.validateHealthSources())
+
+	service.HealthSources = []*string{to.Strp("ec2")}
+	assert.Error(t, service.validateHealthSources())
+
+	service.TargetGroups = nil
+	service.HealthSources = []*string{to.Strp("target_group")}
+	assert.EqualError(t, service.validateHealthSources(), "HealthSources target_group requires target_groups")
+}
+
+func Test_Release_UpdateHealthy_HealthSources(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].ELBs = nil
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	release.UpdateWithResources(resources)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// InService in the ASG but not yet healthy in the target group is not counted
+	awsc.ALB.SetTargetHealth("web-elb-target", "InstanceId1", "initial")
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, *release.Healthy)
+	assert.Equal(t, 0, *release.Services["web"].HealthReport.Healthy)
+
+	// Healthy in the target group but still launching in the ASG is not counted
+	awsc.ALB.SetTargetHealth("web-elb-target", "InstanceId1", "healthy")
+	awsc.ASG.SetLifecycleState("InstanceId1", "Pending")
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, *release.Healthy)
+
+	// Counted once both agree
+	awsc.ASG.SetLifecycleState("InstanceId1", "InService")
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, *release.Healthy)
+	assert.Equal(t, 1, *release.Services["web"].HealthReport.Healthy)
+
+	// Only the target group
+	release.Services["web"].HealthSources = []*string{to.Strp("target_group")}
+	awsc.ASG.SetLifecycleState("InstanceId1", "Pending")
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, *release.Healthy)
+
+	// Only the ASG
+	release.Services["web"].HealthSources = []*string{to.Strp("asg")}
+	awsc.ASG.SetLifecycleState("InstanceId1", "InService")
+	awsc.ALB.SetTargetHealth("web-elb-target", "InstanceId1", "unhealthy")
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, *release.Healthy)
+
+	// Terminating instances are never healthy
+	release.Services["web"].HealthSources = []*string{to.Strp("target_group")}
+	awsc.ALB.SetTargetHealth("web-elb-target", "InstanceId1", "healthy")
+	awsc.ASG.SetLifecycleState("InstanceId1", "Terminating")
+	assert.Error(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Idempotency
+//////////
+
+var idempotencyKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,128}$`)
+
+// ValidateIdempotencyKey validates the IdempotencyKey
+func (release *Release) ValidateIdempotencyKey() error {
+	if release.IdempotencyKey == nil {
+		return nil
+	}
+
+	if !idempotencyKeyRegex.MatchString(*release.IdempotencyKey) {
+		return fmt.Errorf("IdempotencyKey must match %v", idempotencyKeyRegex.String())
+	}
+
+	return nil
+}
+
+// IdempotencyResultPath returns the path of the deploy result of the successful release with the IdempotencyKey
+func (release *Release) IdempotencyResultPath() *string {
+	if release.IdempotencyKey == nil {
+		return nil
+	}
+
+	s := fmt.Sprintf("%v/idempotency/%v", *release.RootDir(), *release.IdempotencyKey)
+	return &s
+}
+
+// ReplayIdempotentResult returns true if a release with the IdempotencyKey already succeeded,
+// setting its deploy result as this releases Result so nothing is deployed again
+func (release *Release) ReplayIdempotentResult(s3c aws.S3API) (bool, error) {
+	if release.IdempotencyKey == nil {
+		return false, nil
+	}
+
+	var result DeployResult
+	if err := s3.GetStruct(s3c, release.Bucket, release.IdempotencyResultPath(), &result); err != nil {
+		switch err.(type) {
+		case *s3.NotFoundError:
+			// No release with the key has succeeded, a running one fails to grab the lock
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	if to.Strs(result.ReleaseID) != to.Strs(release.ReleaseID) {
+		return false, fmt.Errorf("IdempotencyKey %v was used by release %v", *release.IdempotencyKey, to.Strs(result.ReleaseID))
+	}
+
+	release.Result = &result
+	release.Replayed = true
+	release.Success = to.Boolp(true)
+	return true, nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidateIdempotencyKey(t *testing.T) {
+	r := MockRelease(t)
+	assert.NoError(t, r.ValidateIdempotencyKey())
+
+	r.IdempotencyKey = to.Strp("build-1234.sha_abc")
+	assert.NoError(t, r.ValidateIdempotencyKey())
+
+	r.IdempotencyKey = to.Strp("")
+	assert.Error(t, r.ValidateIdempotencyKey())
+
+	r.IdempotencyKey = to.Strp("../lock")
+	assert.Error(t, r.ValidateIdempotencyKey())
+}
+
+func Test_Release_WriteDeployResult_IdempotencyKey(t *testing.T) {
+	r := MockRelease(t)
+	r.IdempotencyKey = to.Strp("build-1")
+	MockPrepareRelease(r)
+	awsc := MockAwsClients(r)
+
+	assert.NoError(t, r.WriteDeployResult(awsc.S3, awsc.ASG, awsc.EC2))
+
+	var result DeployResult
+	assert.NoError(t, s3.GetStruct(awsc.S3, r.Bucket, r.IdempotencyResultPath(), &result))
+	assert.Equal(t, "build-1", *result.IdempotencyKey)
+	assert.Equal(t, *r.UUID, *result.ReleaseUUID)
+
+	// Without a key nothing more is written
+	r = MockRelease(t)
+	MockPrepareRelease(r)
+	awsc = MockAwsClients(r)
+	assert.NoError(t, r.WriteDeployResult(awsc.S3, awsc.ASG, awsc.EC2))
+	assert.Nil(t, r.IdempotencyResultPath())
+	assert.Nil(t, r.Result.IdempotencyKey)
+}
+
+func Test_Release_ReplayIdempotentResult(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	awsc := MockAwsClients(r)
+
+	// Without a key nothing is replayed
+	replayed, err := r.ReplayIdempotentResult(awsc.S3)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+
+	// No release with the key has succeeded
+	r.IdempotencyKey = to.Strp("build-1")
+	replayed, err = r.ReplayIdempotentResult(awsc.S3)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Nil(t, r.Result)
+
+	assert.NoError(t, s3.PutStruct(awsc.S3, r.Bucket, r.IdempotencyResultPath(), &DeployResult{
+		ReleaseID:      r.ReleaseID,
+		ReleaseUUID:    to.Strp("prior-uuid"),
+		IdempotencyKey: r.IdempotencyKey,
+	}))
+
+	replayed, err = r.ReplayIdempotentResult(awsc.S3)
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.True(t, r.Replayed)
+	assert.True(t, *r.Success)
+	assert.Equal(t, "prior-uuid", *r.Result.ReleaseUUID)
+
+	// The key was used by a different release
+	r = MockRelease(t)
+	MockPrepareRelease(r)
+	r.IdempotencyKey = to.Strp("build-1")
+	r.ReleaseID = to.Strp("2")
+	assert.NoError(t, s3.PutStruct(awsc.S3, r.Bucket, r.IdempotencyResultPath(), &DeployResult{ReleaseID: to.Strp("1")}))
+
+	replayed, err = r.ReplayIdempotentResult(awsc.S3)
+	assert.Error(t, err)
+	assert.False(t, replayed)
+	assert.Contains(t, err.Error(), "IdempotencyKey build-1 was used by release 1")
+}
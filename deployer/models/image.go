@@ -0,0 +1,110 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/instance"
+	"github.com/coinbase/odin/aws/ssm"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Image
+//////////
+
+// ssmImagePrefix marks an ami read from an SSM parameter, e.g. ssm:/odin/project/ami
+const ssmImagePrefix = "ssm:"
+
+// ssmImageParameter returns the SSM parameter name if the ami is an ssm: reference
+func (release *Release) ssmImageParameter() *string {
+	if release.Image == nil || !strings.HasPrefix(*release.Image, ssmImagePrefix) {
+		return nil
+	}
+
+	return to.Strp(strings.TrimPrefix(*release.Image, ssmImagePrefix))
+}
+
+// ResolveImage replaces an ssm: ami with the AMI ID stored in the parameter,
+// ValidateResources then checks the AMI exists and can be deployed
+func (release *Release) ResolveImage(ssmc aws.SSMAPI) error {
+	name := release.ssmImageParameter()
+	if name == nil {
+		return nil
+	}
+
+	if *name == "" {
+		return fmt.Errorf("AMI %v must name an SSM parameter", *release.Image)
+	}
+
+	id, err := ssm.GetParameter(ssmc, name)
+	if err != nil {
+		return fmt.Errorf("AMI %v %v", *release.Image, err.Error())
+	}
+
+	if !strings.HasPrefix(*id, "ami-") {
+		return fmt.Errorf("AMI %v value %v is not an AMI ID", *release.Image, *id)
+	}
+
+	release.Image = id
+	return nil
+}
+
+// describeInstanceTypes returns the description of each instance type the service launches
+func (service *Service) describeInstanceTypes(ec2c aws.EC2API) (map[string]*ec2.InstanceTypeInfo, error) {
+	names := []*string{}
+	for _, name := range service.instanceTypeNames() {
+		if name != nil {
+			names = append(names, name)
+		}
+	}
+
+	return instance.DescribeTypes(ec2c, names)
+}
+
+// validateImageArchitecture errors if an instance type cannot boot the AMI,
+// e.g. an arm64 AMI on an x86_64 instance type would never become healthy
+func (sr *ServiceResources) validateImageArchitecture(service *Service) error {
+	if sr.Image == nil {
+		return nil
+	}
+
+	for _, name := range to.StrSlice(service.instanceTypeNames()) {
+		it := sr.InstanceTypes[name]
+		if it == nil {
+			return fmt.Errorf("InstanceType %v not found", name)
+		}
+
+		if arch := sr.Image.Architecture; arch != nil && it.ProcessorInfo != nil {
+			if !containsStrp(it.ProcessorInfo.SupportedArchitectures, *arch) {
+				return fmt.Errorf("Image %v architecture %v is not supported by InstanceType %v (%v)",
+					to.Strs(sr.Image.ImageID), *arch, name, strings.Join(to.StrSlice(it.ProcessorInfo.SupportedArchitectures), ","))
+			}
+		}
+
+		if requiresEna(it) && !sr.Image.EnaSupport {
+			return fmt.Errorf("Image %v does not support ENA which is required by InstanceType %v", to.Strs(sr.Image.ImageID), name)
+		}
+
+		if virt := sr.Image.VirtualizationType; virt != nil && len(it.SupportedVirtualizationTypes) > 0 {
+			if !containsStrp(it.SupportedVirtualizationTypes, *virt) {
+				return fmt.Errorf("Image %v virtualization type %v is not supported by InstanceType %v (%v)",
+					to.Strs(sr.Image.ImageID), *virt, name, strings.Join(to.StrSlice(it.SupportedVirtualizationTypes), ","))
+			}
+		}
+	}
+
+	return nil
+}
+
+// requiresEna returns true if the instance type only has ENA networking, e.g. every nitro instance type.
+// Instances of it launched from an image without the ENA driver fail to boot
+func requiresEna(it *ec2.InstanceTypeInfo) bool {
+	if to.Strs(it.Hypervisor) == ec2.InstanceTypeHypervisorNitro {
+		return true
+	}
+
+	return it.NetworkInfo != nil && to.Strs(it.NetworkInfo.EnaSupport) == ec2.EnaSupportRequired
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ResolveImage(t *testing.T) {
+	release := MockRelease(t)
+	ssmc := &mocks.SSMClient{}
+	ssmc.AddParameter("/odin/ami", "ami-123456")
+
+	// IDs and Name tags are not resolved
+	assert.NoError(t, release.ResolveImage(ssmc))
+	assert.Equal(t, "ubuntu", *release.Image)
+	assert.Equal(t, 0, len(ssmc.GetParameterInputs))
+
+	release.Image = to.Strp("ssm:/odin/ami")
+	assert.NoError(t, release.ResolveImage(ssmc))
+	assert.Equal(t, "ami-123456", *release.Image)
+	assert.Equal(t, "/odin/ami", *ssmc.GetParameterInputs[0].Name)
+}
+
+func Test_Release_ResolveImage_Errors(t *testing.T) {
+	release := MockRelease(t)
+	ssmc := &mocks.SSMClient{}
+	ssmc.AddParameter("/odin/name", "ubuntu")
+
+	release.Image = to.Strp("ssm:/odin/missing")
+	err := release.ResolveImage(ssmc)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+
+	release.Image = to.Strp("ssm:")
+	assert.Error(t, release.ResolveImage(ssmc))
+
+	release.Image = to.Strp("ssm:/odin/name")
+	assert.Error(t, release.ResolveImage(ssmc))
+}
+
+func Test_Release_ValidateResources_ImageArchitecture(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	awsc.EC2.AddInstanceType("m6g.large", []string{"arm64"}, []string{"hvm"})
+	awsc.EC2.AddInstanceType("t1.micro", []string{"i386", "x86_64"}, []string{"hvm", "paravirtual"})
+
+	validate := func(instanceType string, arch string, virt string) error {
+		release.Services["web"].InstanceType = to.Strp(instanceType)
+		awsc.EC2.SetImageArchitecture("ami-123456", arch, virt)
+		resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+		assert.NoError(t, err)
+		return release.ValidateResources(resources)
+	}
+
+	assert.NoError(t, validate("t2.small", "x86_64", "hvm"))
+	assert.NoError(t, validate("m6g.large", "arm64", "hvm"))
+	assert.NoError(t, validate("t1.micro", "x86_64", "paravirtual"))
+
+	err := validate("t2.small", "arm64", "hvm")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "architecture arm64 is not supported by InstanceType t2.small")
+
+	err = validate("m6g.large", "x86_64", "hvm")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "architecture x86_64 is not supported by InstanceType m6g.large")
+
+	err = validate("t2.small", "x86_64", "paravirtual")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "virtualization type paravirtual is not supported")
+}
+
+func Test_Release_ValidateResources_ImageArchitecture_MixedInstances(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].InstanceTypes = []*InstanceTypeOverride{
+		&InstanceTypeOverride{InstanceType: to.Strp("m5.large")},
+		&InstanceTypeOverride{InstanceType: to.Strp("m6g.large")},
+	}
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	awsc.EC2.AddInstanceType("m6g.large", []string{"arm64"}, []string{"hvm"})
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "InstanceType m6g.large")
+}
+
+func Test_Release_ValidateResources_ImageEnaSupport(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	awsc.EC2.AddInstanceType("m5.large", []string{"x86_64"}, []string{"hvm"})
+	awsc.EC2.SetInstanceTypeNetworking("m5.large", "nitro", "required")
+	awsc.EC2.AddInstanceType("c4.large", []string{"x86_64"}, []string{"hvm"})
+	awsc.EC2.SetInstanceTypeNetworking("c4.large", "xen", "unsupported")
+	awsc.EC2.AddInstanceType("i3.large", []string{"x86_64"}, []string{"hvm"})
+	awsc.EC2.SetInstanceTypeNetworking("i3.large", "xen", "required")
+
+	validate := func(instanceType string, ena bool) error {
+		release.Services["web"].InstanceType = to.Strp(instanceType)
+		awsc.EC2.SetImageEnaSupport("ami-123456", ena)
+		resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+		assert.NoError(t, err)
+		return release.ValidateResources(resources)
+	}
+
+	assert.NoError(t, validate("m5.large", true))
+	assert.NoError(t, validate("c4.large", true))
+	assert.NoError(t, validate("c4.large", false))
+
+	// Nitro instance types cannot boot images without the ENA driver
+	err := validate("m5.large", false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Image ami-123456 does not support ENA which is required by InstanceType m5.large")
+
+	err = validate("i3.large", false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "InstanceType i3.large")
+}
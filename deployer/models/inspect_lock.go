@@ -0,0 +1,163 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Inspect Lock
+//////////
+
+// uuidTimeLayout is the time in a release UUID, RFC3339 with the colons replaced by dashes
+const uuidTimeLayout = "2006-01-02T15-04-05Z"
+
+// LockHolder is the release holding a project config lock
+type LockHolder struct {
+	Held bool    `json:"held"`
+	UUID *string `json:"uuid,omitempty"`
+
+	// When the holder was validated, from its UUID
+	StartedAt *time.Time `json:"started_at,omitempty"`
+
+	// The RUNNING execution holding the lock, nil if none is found as the lock is stale
+	ReleaseID    *string `json:"release_id,omitempty"`
+	ExecutionArn *string `json:"execution_arn,omitempty"`
+
+	// The last state in the holders event log, e.g. CheckHealthy while it waits on instances
+	State *string `json:"state,omitempty"`
+}
+
+// InspectLock returns who holds the project config lock. Every lock backend also writes the UUID of the
+// holder to the S3 root lock, so it is read from there. The lock is never grabbed or released
+func (release *Release) InspectLock(s3c aws.S3API, sfnc aws.SFNAPI, stateMachineArn *string) (*LockHolder, error) {
+	var lock s3.Lock
+	if err := s3.GetStruct(s3c, release.Bucket, release.RootLockPath(), &lock); err != nil {
+		switch err.(type) {
+		case *s3.NotFoundError:
+			return &LockHolder{Held: false}, nil
+		default:
+			return nil, err
+		}
+	}
+
+	if lock.UUID == "" {
+		return &LockHolder{Held: false}, nil
+	}
+
+	holder := &LockHolder{Held: true, UUID: to.Strp(lock.UUID), StartedAt: uuidTime(lock.UUID)}
+	if stateMachineArn == nil {
+		return holder, nil
+	}
+
+	if err := release.findLockExecution(s3c, sfnc, stateMachineArn, holder); err != nil {
+		return nil, err
+	}
+
+	if holder.ReleaseID == nil {
+		return holder, nil
+	}
+
+	state, err := release.lastLoggedState(s3c, holder.ReleaseID)
+	if err != nil {
+		return nil, err
+	}
+	holder.State = state
+
+	return holder, nil
+}
+
+// findLockExecution sets the release and execution of the RUNNING execution whose release lock has the holders UUID
+func (release *Release) findLockExecution(s3c aws.S3API, sfnc aws.SFNAPI, stateMachineArn *string, holder *LockHolder) error {
+	return forEachRunningExecution(sfnc, stateMachineArn, func(item *sfn.ExecutionListItem) (bool, error) {
+		releaseID, err := release.runningReleaseID(sfnc, item)
+		if err != nil {
+			return false, err
+		}
+
+		if releaseID == nil || *releaseID == "" {
+			return false, nil
+		}
+
+		var lock s3.Lock
+		if err := s3.GetStruct(s3c, release.Bucket, release.otherRelease(releaseID).ReleaseLockPath(), &lock); err != nil {
+			switch err.(type) {
+			case *s3.NotFoundError:
+				return false, nil // Not yet validated
+			default:
+				return false, err
+			}
+		}
+
+		if lock.UUID == *holder.UUID {
+			holder.ReleaseID = releaseID
+			holder.ExecutionArn = item.ExecutionArn
+			return true, nil
+		}
+
+		return false, nil
+	})
+}
+
+// lastLoggedState returns the state of the last event in the event log of the release, nil if nothing is logged
+func (release *Release) lastLoggedState(s3c aws.S3API, releaseID *string) (*string, error) {
+	other := release.otherRelease(releaseID)
+	other.Bucket = release.Bucket
+
+	path, err := other.lastEventPath(s3c)
+	if err != nil {
+		return nil, err
+	}
+
+	if path == nil {
+		return nil, nil
+	}
+
+	raw, err := s3.Get(s3c, release.Bucket, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var event Event
+	if err := json.Unmarshal(*raw, &event); err != nil {
+		return nil, fmt.Errorf("event log of release %v: %v", *releaseID, err.Error())
+	}
+
+	if event.State == "" {
+		return nil, nil
+	}
+
+	return to.Strp(event.State), nil
+}
+
+// otherRelease returns a release of this project config with the ID, to find its paths
+func (release *Release) otherRelease(releaseID *string) *Release {
+	other := &Release{}
+	other.AwsAccountID = release.AwsAccountID
+	other.ProjectName = release.ProjectName
+	other.ConfigName = release.ConfigName
+	other.ReleaseID = releaseID
+	return other
+}
+
+// uuidTime returns the time in a release UUID, nil if it has none
+func uuidTime(uuid string) *time.Time {
+	tf := strings.TrimPrefix(uuid, "release-")
+	if len(tf) < len(uuidTimeLayout) {
+		return nil
+	}
+
+	t, err := time.Parse(uuidTimeLayout, tf[:len(uuidTimeLayout)])
+	if err != nil {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockHeldLockRelease(t *testing.T) (*Release, *mocks.MockClients) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	uuid := `{"uuid": "release-2026-10-14T10-00-00Z-abcdefg"}`
+	awsc.S3.AddGetObject(*release.RootLockPath(), uuid, nil)
+	awsc.S3.AddGetObject(*release.otherRelease(to.Strp("live")).ReleaseLockPath(), uuid, nil)
+
+	live := release.otherRelease(to.Strp("live"))
+	live.Bucket = release.Bucket
+	at := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	for i, state := range []string{"Lock", "Lock", "CheckHealthy", "CheckHealthy"} {
+		event := &Event{Time: at.Add(time.Duration(i) * time.Second), State: state, Event: EventSuccess}
+		assert.NoError(t, live.PutEvent(awsc.S3, event))
+	}
+	awsc.S3.PutObjectInputs = nil
+
+	return release, awsc
+}
+
+func Test_Release_InspectLock(t *testing.T) {
+	release, awsc := mockHeldLockRelease(t)
+	awsc.SFN.AddExecution("arn:live", release.ExecutionPrefix()+"live", "RUNNING", map[string]string{"release_id": "live"})
+	awsc.SFN.AddExecution("arn:other", release.ExecutionPrefix()+"other", "RUNNING", map[string]string{"release_id": "other"})
+
+	holder, err := release.InspectLock(awsc.S3, awsc.SFN, to.Strp("arn:sm"))
+	assert.NoError(t, err)
+	assert.True(t, holder.Held)
+	assert.Equal(t, "release-2026-10-14T10-00-00Z-abcdefg", *holder.UUID)
+	assert.Equal(t, time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC), *holder.StartedAt)
+	assert.Equal(t, "live", *holder.ReleaseID)
+	assert.Equal(t, "arn:live", *holder.ExecutionArn)
+	assert.Equal(t, "CheckHealthy", *holder.State)
+
+	// The lock is only read
+	assert.Equal(t, 0, len(awsc.S3.PutObjectInputs))
+	assert.Equal(t, 0, len(awsc.DynamoDB.Locks))
+}
+
+func Test_Release_InspectLock_Stale(t *testing.T) {
+	release, awsc := mockHeldLockRelease(t)
+	awsc.SFN.AddExecution("arn:live", release.ExecutionPrefix()+"live", "FAILED", map[string]string{"release_id": "live"})
+
+	holder, err := release.InspectLock(awsc.S3, awsc.SFN, to.Strp("arn:sm"))
+	assert.NoError(t, err)
+	assert.True(t, holder.Held)
+	assert.Equal(t, "release-2026-10-14T10-00-00Z-abcdefg", *holder.UUID)
+	assert.Nil(t, holder.ReleaseID)
+	assert.Nil(t, holder.State)
+
+	// Without the state machine only the lock is read
+	holder, err = release.InspectLock(awsc.S3, awsc.SFN, nil)
+	assert.NoError(t, err)
+	assert.True(t, holder.Held)
+	assert.NotNil(t, holder.StartedAt)
+	assert.Nil(t, holder.ExecutionArn)
+}
+
+func Test_Release_InspectLock_NotHeld(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	holder, err := release.InspectLock(awsc.S3, awsc.SFN, to.Strp("arn:sm"))
+	assert.NoError(t, err)
+	assert.False(t, holder.Held)
+	assert.Nil(t, holder.UUID)
+	assert.Nil(t, holder.StartedAt)
+
+	// A released lock is not held
+	awsc.S3.AddGetObject(*release.RootLockPath(), `{}`, nil)
+	holder, err = release.InspectLock(awsc.S3, awsc.SFN, to.Strp("arn:sm"))
+	assert.NoError(t, err)
+	assert.False(t, holder.Held)
+
+	awsc.S3.AddGetObject(*release.RootLockPath(), "", fmt.Errorf("AccessDenied"))
+	_, err = release.InspectLock(awsc.S3, awsc.SFN, to.Strp("arn:sm"))
+	assert.Error(t, err)
+}
+
+func Test_UUIDTime(t *testing.T) {
+	assert.Equal(t, time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC), *uuidTime("release-2026-10-14T10-00-00Z-abcdefg"))
+	assert.Nil(t, uuidTime("dead"))
+	assert.Nil(t, uuidTime("release-not-a-time-at-all-really"))
+}
@@ -0,0 +1,238 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/odin/aws/lc"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Instance Refresh
+//////////
+
+// Deploy strategies
+const (
+	DeployBlueGreen       = "BlueGreen"       // Create new ASGs and delete the old ones once healthy
+	DeployInstanceRefresh = "InstanceRefresh" // Replace the instances of the live ASGs
+)
+
+// defaultRefreshMinHealthyPercentage is the AWS default
+const defaultRefreshMinHealthyPercentage = 90
+
+// IsInstanceRefresh returns true if the release refreshes the instances of the live ASGs
+func (release *Release) IsInstanceRefresh() bool {
+	return to.Strs(release.DeployStrategy) == DeployInstanceRefresh
+}
+
+// refreshMinHealthyPercentage is the percent of each ASG kept in service during the refresh
+func (release *Release) refreshMinHealthyPercentage() int64 {
+	if release.RefreshMinHealthyPercentage == nil {
+		return defaultRefreshMinHealthyPercentage
+	}
+	return int64(*release.RefreshMinHealthyPercentage)
+}
+
+// ValidateDeployStrategy validates the DeployStrategy and what an instance refresh supports
+func (release *Release) ValidateDeployStrategy() error {
+	switch to.Strs(release.DeployStrategy) {
+	case DeployBlueGreen:
+		return nil
+	case DeployInstanceRefresh:
+		// validated below
+	default:
+		return fmt.Errorf("DeployStrategy must be either '%v' or '%v'", DeployBlueGreen, DeployInstanceRefresh)
+	}
+
+	if p := release.RefreshMinHealthyPercentage; p != nil && (*p < 0 || *p > 100) {
+		return fmt.Errorf("RefreshMinHealthyPercentage must be between 0 and 100")
+	}
+
+	// The live ASGs keep serving so there is no traffic to move, or new release to soak before cleanup
+	if release.DNS != nil {
+		return fmt.Errorf("DeployStrategy %v cannot be used with dns", DeployInstanceRefresh)
+	}
+
+	if release.InPlaceUpdates {
+		return fmt.Errorf("DeployStrategy %v cannot be used with in_place_updates", DeployInstanceRefresh)
+	}
+
+	if len(release.SoakAlarms) > 0 {
+		return fmt.Errorf("DeployStrategy %v cannot be used with soak_alarms", DeployInstanceRefresh)
+	}
+
+	for _, name := range sortedServiceNames(release) {
+		service := release.Services[name]
+		if service.Canary != nil {
+			return fmt.Errorf("DeployStrategy %v cannot be used with the canary of %v", DeployInstanceRefresh, name)
+		}
+
+		if service.mixedInstances() {
+			return fmt.Errorf("DeployStrategy %v cannot be used with the instance_types or spot of %v", DeployInstanceRefresh, name)
+		}
+
+		if service.launchTemplate() {
+			return fmt.Errorf("DeployStrategy %v cannot be used with the capacity_reservation of %v", DeployInstanceRefresh, name)
+		}
+	}
+
+	return nil
+}
+
+// validateRefreshResources checks every service has a live ASG with a launch configuration to refresh
+func (release *Release) validateRefreshResources(resources *ReleaseResources) error {
+	if !release.IsInstanceRefresh() {
+		return nil
+	}
+
+	for _, name := range sortedServiceNames(release) {
+		prev := resources.PreviousASGs[name]
+		if prev == nil {
+			return fmt.Errorf("DeployStrategy %v requires a deployed ASG for %v", DeployInstanceRefresh, name)
+		}
+
+		if prev.LaunchConfigurationName == nil {
+			return fmt.Errorf("DeployStrategy %v requires the ASG %v to use a launch configuration", DeployInstanceRefresh, to.Strs(prev.AutoScalingGroupName))
+		}
+	}
+
+	return nil
+}
+
+// StartRefresh moves the live ASG in CreatedASG onto a new launch configuration and starts refreshing its instances
+func (service *Service) StartRefresh(asgc aws.ASGAPI) error {
+	if err := service.createLaunchConfiguration(asgc); err != nil {
+		return err
+	}
+
+	if err := asg.SetLaunchConfiguration(asgc, service.CreatedASG, service.ServiceID()); err != nil {
+		return err
+	}
+
+	id, err := asg.StartInstanceRefresh(asgc, service.CreatedASG, to.Int64p(service.release.refreshMinHealthyPercentage()))
+	if err != nil {
+		return err
+	}
+
+	service.RefreshID = id
+	service.RefreshStatus = nil
+
+	return nil
+}
+
+// UpdateRefreshed sets Refreshed once every services instance refresh is successful
+// A failed or cancelled refresh, or reaching the Timeout, is a HaltError
+func (release *Release) UpdateRefreshed(asgc aws.ASGAPI) error {
+	if release.Timeout != nil && release.StartedAt != nil {
+		if time.Now().After(release.StartedAt.Add(time.Duration(*release.Timeout) * time.Second)) {
+			return &HaltError{fmt.Errorf("Timeout: %vs reached before the instance refresh completed", *release.Timeout)}
+		}
+	}
+
+	err := release.forEachService(func(service *Service) error {
+		return service.updateRefreshed(asgc)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	refreshed := true
+	for _, service := range release.Services {
+		refreshed = refreshed && to.Strs(service.RefreshStatus) == "Successful"
+	}
+
+	release.Refreshed = &refreshed
+
+	return nil
+}
+
+func (service *Service) updateRefreshed(asgc aws.ASGAPI) error {
+	if service.RefreshID == nil {
+		return &HaltError{fmt.Errorf("%v instance refresh was not started", service.errorPrefix())}
+	}
+
+	refresh, err := asg.DescribeInstanceRefresh(asgc, service.CreatedASG, service.RefreshID)
+	if err != nil {
+		return err // This might retry
+	}
+
+	service.RefreshStatus = refresh.Status
+
+	switch to.Strs(refresh.Status) {
+	case "Pending", "InProgress", "Successful":
+		return nil
+	}
+
+	return &HaltError{fmt.Errorf("%v instance refresh %v %v %v", service.errorPrefix(), *service.RefreshID, to.Strs(refresh.Status), to.Strs(refresh.StatusReason))}
+}
+
+// CancelRefresh stops the instance refreshes and moves the live ASGs back to their previous
+// launch configuration, the ASGs are never deleted
+func (release *Release) CancelRefresh(asgc aws.ASGAPI) error {
+	return release.forEachService(func(service *Service) error {
+		return service.cancelRefresh(asgc)
+	})
+}
+
+func (service *Service) cancelRefresh(asgc aws.ASGAPI) error {
+	if service.CreatedASG == nil || service.PreviousLaunchConfiguration == nil {
+		return nil // Deploy never reached this service
+	}
+
+	if err := asg.CancelInstanceRefresh(asgc, service.CreatedASG); err != nil {
+		return err
+	}
+
+	if err := asg.SetLaunchConfiguration(asgc, service.CreatedASG, service.PreviousLaunchConfiguration); err != nil {
+		return err
+	}
+
+	// The new launch configuration is detached, instances it launched keep running
+	if to.Strs(service.ServiceID()) == to.Strs(service.PreviousLaunchConfiguration) {
+		return nil
+	}
+
+	return lc.TeardownIfExists(asgc, service.ServiceID())
+}
+
+// PromoteRefreshed moves the refreshed ASGs to this release and deletes their previous launch configurations
+func (release *Release) PromoteRefreshed(asgc aws.ASGAPI) error {
+	return release.forEachService(func(service *Service) error {
+		_, group, err := asg.GetInstances(asgc, service.CreatedASG)
+		if err != nil {
+			return err
+		}
+
+		tags, removed := service.inPlaceTags(group.Tags())
+		if err := group.UpdateTags(asgc, tags, removed); err != nil {
+			return err
+		}
+
+		return lc.TeardownIfExists(asgc, service.PreviousLaunchConfiguration)
+	})
+}
+
+// withoutRefreshedASGs removes the live ASGs this release refreshed
+func (release *Release) withoutRefreshedASGs(asgs []*asg.ASG) []*asg.ASG {
+	if !release.IsInstanceRefresh() {
+		return asgs
+	}
+
+	refreshed := []string{}
+	for _, service := range release.Services {
+		refreshed = append(refreshed, to.Strs(service.CreatedASG))
+	}
+
+	kept := []*asg.ASG{}
+	for _, group := range asgs {
+		if !containsStr(refreshed, to.Strs(group.AutoScalingGroupName)) {
+			kept = append(kept, group)
+		}
+	}
+
+	return kept
+}
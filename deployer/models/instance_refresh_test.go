@@ -0,0 +1,148 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockRefreshRelease(t *testing.T) (*Release, *mocks.MockClients) {
+	return mockSuspendProcessesRelease(t, func(r *Release) {
+		r.DeployStrategy = to.Strp(DeployInstanceRefresh)
+	})
+}
+
+func Test_Release_ValidateDeployStrategy(t *testing.T) {
+	release := MockRelease(t)
+	release.SetDefaults()
+	assert.NoError(t, release.ValidateDeployStrategy())
+	assert.Equal(t, DeployBlueGreen, *release.DeployStrategy)
+	assert.False(t, release.IsInstanceRefresh())
+
+	release.DeployStrategy = to.Strp("Rolling")
+	assert.Error(t, release.ValidateDeployStrategy())
+
+	release.DeployStrategy = to.Strp(DeployInstanceRefresh)
+	assert.NoError(t, release.ValidateDeployStrategy())
+	assert.Equal(t, int64(90), release.refreshMinHealthyPercentage())
+
+	release.RefreshMinHealthyPercentage = to.Intp(101)
+	assert.Error(t, release.ValidateDeployStrategy())
+
+	release.RefreshMinHealthyPercentage = to.Intp(50)
+	assert.NoError(t, release.ValidateDeployStrategy())
+	assert.Equal(t, int64(50), release.refreshMinHealthyPercentage())
+
+	release.InPlaceUpdates = true
+	assert.Error(t, release.ValidateDeployStrategy())
+	release.InPlaceUpdates = false
+
+	release.Services["web"].InstanceTypes = mockInstanceTypes("c5.large", "c4.large")
+	assert.Error(t, release.ValidateDeployStrategy())
+}
+
+func Test_Release_ValidateResources_Refresh_NoPreviousASG(t *testing.T) {
+	release := MockRelease(t)
+	release.DeployStrategy = to.Strp(DeployInstanceRefresh)
+	MockPrepareRelease(release)
+
+	resources := &ReleaseResources{PreviousASGs: map[string]*asg.ASG{}}
+
+	err := release.validateRefreshResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "requires a deployed ASG")
+}
+
+func Test_Release_Refresh_CreateResources(t *testing.T) {
+	release, awsc := mockRefreshRelease(t)
+	service := release.Services["web"]
+
+	assert.Equal(t, "project-config-web-old-release", *service.CreatedASG)
+	assert.Equal(t, "project-config-web-old-release", *service.PreviousLaunchConfiguration)
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// No ASG is created, the live ASG is moved onto the new launch configuration
+	assert.Equal(t, 0, len(awsc.ASG.CreateAutoScalingGroupInputs))
+	assert.Equal(t, 1, len(awsc.ASG.CreateLaunchConfigurationInputs))
+	assert.Equal(t, *service.ServiceID(), *awsc.ASG.UpdateAutoScalingGroupLastInput.LaunchConfigurationName)
+
+	assert.Equal(t, 1, len(awsc.ASG.StartInstanceRefreshInputs))
+	input := awsc.ASG.StartInstanceRefreshInputs[0]
+	assert.Equal(t, "project-config-web-old-release", *input.AutoScalingGroupName)
+	assert.Equal(t, int64(90), *input.Preferences.MinHealthyPercentage)
+	assert.NotNil(t, service.RefreshID)
+}
+
+func Test_Release_UpdateRefreshed(t *testing.T) {
+	release, awsc := mockRefreshRelease(t)
+	awsc.ASG.InstanceRefreshStatuses = map[string][]string{
+		"project-config-web-old-release": []string{"Pending", "InProgress", "Successful"},
+	}
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	for _, status := range []string{"Pending", "InProgress"} {
+		assert.NoError(t, release.UpdateRefreshed(awsc.ASG))
+		assert.False(t, *release.Refreshed)
+		assert.Equal(t, status, *release.Services["web"].RefreshStatus)
+	}
+
+	assert.NoError(t, release.UpdateRefreshed(awsc.ASG))
+	assert.True(t, *release.Refreshed)
+}
+
+func Test_Release_UpdateRefreshed_Failed(t *testing.T) {
+	release, awsc := mockRefreshRelease(t)
+	awsc.ASG.InstanceRefreshStatuses = map[string][]string{
+		"project-config-web-old-release": []string{"Failed"},
+	}
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	err := release.UpdateRefreshed(awsc.ASG)
+	assert.Error(t, err)
+	assert.IsType(t, &HaltError{}, err)
+}
+
+func Test_Release_CancelRefresh(t *testing.T) {
+	release, awsc := mockRefreshRelease(t)
+	service := release.Services["web"]
+	awsc.ASG.TrackCreated = true
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.NoError(t, release.UnsuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+
+	// The live ASG is restored and kept, the new launch configuration is deleted
+	assert.Equal(t, 1, len(awsc.ASG.CancelInstanceRefreshInputs))
+	assert.Equal(t, "project-config-web-old-release", *awsc.ASG.UpdateAutoScalingGroupLastInput.LaunchConfigurationName)
+	assert.Equal(t, 0, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, 1, len(awsc.ASG.DeleteLaunchConfigurationInputs))
+	assert.Equal(t, *service.ServiceID(), *awsc.ASG.DeleteLaunchConfigurationInputs[0].LaunchConfigurationName)
+
+	// Cancelling again is a no-op
+	assert.NoError(t, release.CancelRefresh(awsc.ASG))
+}
+
+func Test_Release_SuccessfulTearDown_Refresh(t *testing.T) {
+	release, awsc := mockRefreshRelease(t)
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.NoError(t, release.SuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+
+	// The refreshed ASG is moved to this release and its previous launch configuration deleted
+	assert.Equal(t, 0, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, 1, len(awsc.ASG.DeleteLaunchConfigurationInputs))
+	assert.Equal(t, "project-config-web-old-release", *awsc.ASG.DeleteLaunchConfigurationInputs[0].LaunchConfigurationName)
+
+	tagged := map[string]string{}
+	for _, input := range awsc.ASG.CreateOrUpdateTagsInputs {
+		for _, tag := range input.Tags {
+			tagged[*tag.Key] = *tag.Value
+		}
+	}
+	assert.Equal(t, *release.ReleaseID, tagged["ReleaseID"])
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Keep Previous Releases
+//////////
+
+// maxKeepPreviousReleases bounds how many previous releases ASGs are retained at zero
+const maxKeepPreviousReleases = 10
+
+// ValidateKeepPreviousReleases validates the KeepPreviousReleases
+func (release *Release) ValidateKeepPreviousReleases() error {
+	if release.KeepPreviousReleases == nil {
+		return nil
+	}
+
+	keep := *release.KeepPreviousReleases
+	if keep < 0 || keep > maxKeepPreviousReleases {
+		return fmt.Errorf("KeepPreviousReleases must be between 0 and %v", maxKeepPreviousReleases)
+	}
+
+	// A retained ASG pinned to a shared launch template version needs the version kept
+	for _, name := range sortedServiceNames(release) {
+		service := release.Services[name]
+		if service.sharedLaunchTemplate() && *service.LaunchTemplateRetention <= int64(keep) {
+			return fmt.Errorf("Service(%v) launch_template_retention must be greater than KeepPreviousReleases %v", name, keep)
+		}
+	}
+
+	return nil
+}
+
+func (release *Release) keepPreviousReleases() int {
+	if release.KeepPreviousReleases == nil {
+		return 0
+	}
+	return *release.KeepPreviousReleases
+}
+
+// validateASGAccountLimits errors if the account cannot fit the new ASGs, retained ASGs count towards the limit
+func (release *Release) validateASGAccountLimits(asgc aws.ASGAPI) error {
+	if release.keepPreviousReleases() == 0 {
+		return nil
+	}
+
+	limits, err := asgc.DescribeAccountLimits(&autoscaling.DescribeAccountLimitsInput{})
+	if err != nil {
+		return err
+	}
+
+	retained, err := asg.ForProjectConfigRetained(asgc, release.ProjectName, release.ConfigName)
+	if err != nil {
+		return err
+	}
+
+	if limits.MaxNumberOfAutoScalingGroups == nil || limits.NumberOfAutoScalingGroups == nil {
+		return nil
+	}
+
+	max := *limits.MaxNumberOfAutoScalingGroups
+	count := *limits.NumberOfAutoScalingGroups
+	created := int64(len(release.Services))
+
+	if count+created > max {
+		return fmt.Errorf(
+			"%v KeepPreviousReleases account has %v of its %v ASGs, %v retained by this project config, with no room for %v new ASGs",
+			release.ErrorPrefix(), count, max, len(retained), created,
+		)
+	}
+
+	return nil
+}
+
+// splitPreviousASGs returns the previous ASGs to retain at zero and the previous and retained ASGs to delete.
+// The previous ASGs are the newest release, then the retained ASGs by when they were retained
+func (release *Release) splitPreviousASGs(asgc aws.ASGAPI, previous []*asg.ASG) ([]*asg.ASG, []*asg.ASG, error) {
+	retained, err := asg.ForProjectConfigRetained(asgc, release.ProjectName, release.ConfigName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, group := range retained {
+		if err := release.validSuccessASG(group); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	sort.SliceStable(retained, func(i, j int) bool {
+		return to.Strs(retained[i].RetainedAt()) > to.Strs(retained[j].RetainedAt())
+	})
+
+	kept := map[string]bool{}
+	for _, group := range append(append([]*asg.ASG{}, previous...), retained...) {
+		if len(kept) == release.keepPreviousReleases() {
+			break
+		}
+		kept[to.Strs(group.ReleaseID())] = true
+	}
+
+	retain := []*asg.ASG{}
+	remove := []*asg.ASG{}
+	for _, group := range previous {
+		if kept[to.Strs(group.ReleaseID())] {
+			retain = append(retain, group)
+		} else {
+			remove = append(remove, group)
+		}
+	}
+
+	for _, group := range retained {
+		if !kept[to.Strs(group.ReleaseID())] {
+			remove = append(remove, group)
+		}
+	}
+
+	return retain, remove, nil
+}
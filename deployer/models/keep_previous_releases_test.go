@@ -0,0 +1,121 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidateKeepPreviousReleases(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	assert.NoError(t, release.ValidateKeepPreviousReleases())
+
+	release.KeepPreviousReleases = to.Intp(0)
+	assert.NoError(t, release.ValidateKeepPreviousReleases())
+
+	release.KeepPreviousReleases = to.Intp(10)
+	assert.NoError(t, release.ValidateKeepPreviousReleases())
+
+	release.KeepPreviousReleases = to.Intp(-1)
+	assert.Error(t, release.ValidateKeepPreviousReleases())
+
+	release.KeepPreviousReleases = to.Intp(11)
+	assert.Error(t, release.ValidateKeepPreviousReleases())
+
+	// A shared launch template must keep the versions of the retained ASGs
+	release.KeepPreviousReleases = to.Intp(2)
+	release.Services["web"].LaunchTemplateRetention = to.Int64p(2)
+	err := release.ValidateKeepPreviousReleases()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "launch_template_retention must be greater than KeepPreviousReleases")
+
+	release.Services["web"].LaunchTemplateRetention = to.Int64p(3)
+	assert.NoError(t, release.ValidateKeepPreviousReleases())
+}
+
+func Test_Release_SuccessfulTearDown_KeepPreviousReleases(t *testing.T) {
+	release := MockRelease(t)
+	release.KeepPreviousReleases = to.Intp(1)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	// old-release is live, older-release was retained by old-release
+	awsc.ASG.AddRetainedRuntimeResources(*release.ProjectName, *release.ConfigName, "web", "older-release", "2026-01-01T00:00:00Z")
+
+	assert.NoError(t, release.SuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+
+	// The second oldest release is deleted
+	assert.Equal(t, 1, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, "project-config-web-older-release", *awsc.ASG.DeleteAutoScalingGroupInputs[0].AutoScalingGroupName)
+
+	// The previous release is retained at zero
+	assert.Equal(t, 1, len(awsc.ASG.UpdateAutoScalingGroupInputs))
+	input := awsc.ASG.UpdateAutoScalingGroupInputs[0]
+	assert.Equal(t, "project-config-web-old-release", *input.AutoScalingGroupName)
+	assert.Equal(t, int64(0), *input.MinSize)
+	assert.Equal(t, int64(0), *input.MaxSize)
+	assert.Equal(t, int64(0), *input.DesiredCapacity)
+
+	assert.Equal(t, 1, len(awsc.ASG.CreateOrUpdateTagsInputs))
+	assert.Equal(t, "RetainedAt", *awsc.ASG.CreateOrUpdateTagsInputs[0].Tags[0].Key)
+	assert.Equal(t, "project-config-web-old-release", *awsc.ASG.CreateOrUpdateTagsInputs[0].Tags[0].ResourceId)
+}
+
+func Test_Release_SuccessfulTearDown_KeepPreviousReleases_Order(t *testing.T) {
+	release := MockRelease(t)
+	release.KeepPreviousReleases = to.Intp(2)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	awsc.ASG.AddRetainedRuntimeResources(*release.ProjectName, *release.ConfigName, "web", "oldest-release", "2026-01-01T00:00:00Z")
+	awsc.ASG.AddRetainedRuntimeResources(*release.ProjectName, *release.ConfigName, "web", "older-release", "2026-01-02T00:00:00Z")
+
+	assert.NoError(t, release.SuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+
+	// old-release and the most recently retained release are kept
+	assert.Equal(t, 1, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, "project-config-web-oldest-release", *awsc.ASG.DeleteAutoScalingGroupInputs[0].AutoScalingGroupName)
+	assert.Equal(t, 1, len(awsc.ASG.UpdateAutoScalingGroupInputs))
+	assert.Equal(t, "project-config-web-old-release", *awsc.ASG.UpdateAutoScalingGroupInputs[0].AutoScalingGroupName)
+}
+
+func Test_Release_SuccessfulTearDown_KeepPreviousReleases_Unset(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	// Without KeepPreviousReleases retained ASGs are deleted with the previous ASGs
+	awsc.ASG.AddRetainedRuntimeResources(*release.ProjectName, *release.ConfigName, "web", "older-release", "2026-01-01T00:00:00Z")
+
+	assert.NoError(t, release.SuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+	assert.Equal(t, 2, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, 0, len(awsc.ASG.UpdateAutoScalingGroupInputs))
+}
+
+func Test_Release_FetchResources_KeepPreviousReleases_AccountLimits(t *testing.T) {
+	release := MockRelease(t)
+	release.KeepPreviousReleases = to.Intp(1)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+	awsc.ASG.AddRetainedRuntimeResources(*release.ProjectName, *release.ConfigName, "web", "older-release", "2026-01-01T00:00:00Z")
+
+	_, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	awsc.ASG.DescribeAccountLimitsOutput = &autoscaling.DescribeAccountLimitsOutput{
+		MaxNumberOfAutoScalingGroups: to.Int64p(2),
+		NumberOfAutoScalingGroups:    to.Int64p(2),
+	}
+
+	_, err = release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "account has 2 of its 2 ASGs, 1 retained by this project config, with no room for 1 new ASGs")
+
+	// The limits are only checked when ASGs are retained
+	release.KeepPreviousReleases = nil
+	_, err = release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/kp"
+)
+
+//////////
+// Key Pair
+//////////
+
+// validateKeyName validates the key_name, instances launch without a key pair if it is omitted
+func (service *Service) validateKeyName() error {
+	if service.KeyName != nil && *service.KeyName == "" {
+		return fmt.Errorf("KeyName cannot be empty, omit it to launch without a key pair")
+	}
+
+	return nil
+}
+
+// findKeyPair returns the key pair named by the key_name, or nil if it does not exist
+func (service *Service) findKeyPair(ec2c aws.EC2API) (*ec2.KeyPairInfo, error) {
+	if service.KeyName == nil {
+		return nil, nil
+	}
+
+	return kp.Find(ec2c, service.KeyName)
+}
+
+// validateKeyPair validates the key pair named by the key_name exists in the region
+func (sr *ServiceResources) validateKeyPair(service *Service) error {
+	if service.KeyName == nil || sr.KeyPair != nil {
+		return nil
+	}
+
+	return fmt.Errorf("KeyPair %v Not Found", *service.KeyName)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Service_ValidateKeyName(t *testing.T) {
+	assert.NoError(t, (&Service{}).validateKeyName())
+	assert.NoError(t, (&Service{KeyName: to.Strp("deploy-key")}).validateKeyName())
+	assert.Error(t, (&Service{KeyName: to.Strp("")}).validateKeyName())
+}
+
+func Test_Release_ValidateResources_KeyPair(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	awsc.EC2.AddKeyPair("deploy-key")
+
+	validate := func(name *string) error {
+		release.Services["web"].KeyName = name
+		resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+		assert.NoError(t, err)
+		return release.ValidateResources(resources)
+	}
+
+	assert.NoError(t, validate(nil))
+	assert.NoError(t, validate(to.Strp("deploy-key")))
+
+	err := validate(to.Strp("missing-key"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "KeyPair missing-key Not Found")
+}
+
+func Test_Release_CreateResources_KeyPair_LaunchConfiguration(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+
+	// No key pair by default
+	assert.Nil(t, release.Services["web"].createLaunchConfigurationInput().KeyName)
+
+	release.Services["web"].KeyName = to.Strp("deploy-key")
+	awsc := MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	assert.Equal(t, 1, len(awsc.ASG.CreateLaunchConfigurationInputs))
+	assert.Equal(t, "deploy-key", *awsc.ASG.CreateLaunchConfigurationInputs[0].KeyName)
+}
+
+func Test_Release_CreateResources_KeyPair_LaunchTemplate(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].InstanceTypes = mockInstanceTypes("m5.large", "c5.large")
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.Nil(t, awsc.EC2.CreateLaunchTemplateInputs[0].LaunchTemplateData.KeyName)
+
+	release = MockRelease(t)
+	release.Services["web"].InstanceTypes = mockInstanceTypes("m5.large", "c5.large")
+	release.Services["web"].KeyName = to.Strp("deploy-key")
+	MockPrepareRelease(release)
+
+	awsc = MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.Equal(t, "deploy-key", *awsc.EC2.CreateLaunchTemplateInputs[0].LaunchTemplateData.KeyName)
+}
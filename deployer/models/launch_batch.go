@@ -0,0 +1,81 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Launch Batches
+//////////
+
+// defaultLaunchBatchTimeout is the seconds a launch batch has to become healthy without a LaunchBatchTimeout
+const defaultLaunchBatchTimeout = 600
+
+// validateLaunchBatch validates the LaunchBatchSize and LaunchBatchTimeout
+func (service *Service) validateLaunchBatch() error {
+	if service.LaunchBatchSize == nil {
+		if service.LaunchBatchTimeout != nil {
+			return fmt.Errorf("LaunchBatchTimeout requires a LaunchBatchSize")
+		}
+		return nil
+	}
+
+	if *service.LaunchBatchSize < 1 {
+		return fmt.Errorf("LaunchBatchSize must be at least 1")
+	}
+
+	if service.release != nil && service.release.IsInstanceRefresh() {
+		return fmt.Errorf("LaunchBatchSize cannot be used with the %v deploy strategy", DeployInstanceRefresh)
+	}
+
+	if service.LaunchBatchTimeout != nil && *service.LaunchBatchTimeout < 1 {
+		return fmt.Errorf("LaunchBatchTimeout must be at least 1")
+	}
+
+	if service.LaunchBatchTimeout != nil && service.release != nil && service.release.Timeout != nil && *service.LaunchBatchTimeout > *service.release.Timeout {
+		return fmt.Errorf("LaunchBatchTimeout must be at most Timeout")
+	}
+
+	return nil
+}
+
+// launchBatchTimeout is the seconds each launch batch has to become healthy
+func (service *Service) launchBatchTimeout() time.Duration {
+	if service.LaunchBatchTimeout == nil {
+		return defaultLaunchBatchTimeout * time.Second
+	}
+	return time.Duration(*service.LaunchBatchTimeout) * time.Second
+}
+
+// launchBatch limits the strategies min size and desired capacity so at most LaunchBatchSize instances launch at once.
+// The desired capacity is only raised, by up to LaunchBatchSize, once every instance of the current batch is healthy.
+// A batch that is not healthy within the LaunchBatchTimeout halts the deploy
+func (service *Service) launchBatch(group *asg.ASG, all aws.Instances, minSize, desiredCapacity int64) (int64, int64, error) {
+	if service.LaunchBatchSize == nil || group.DesiredCapacity == nil {
+		return minSize, desiredCapacity, nil
+	}
+
+	current := *group.DesiredCapacity
+	if desiredCapacity <= current {
+		return minSize, desiredCapacity, nil
+	}
+
+	if int64(len(all.HealthyIDs())) < current {
+		if service.LaunchBatchAt != nil && time.Since(*service.LaunchBatchAt) > service.launchBatchTimeout() {
+			return 0, 0, &HaltError{fmt.Errorf("%v launch batch of %v instances not healthy within %v", service.errorPrefix(), current, service.launchBatchTimeout())}
+		}
+
+		// Wait for the batch before launching more
+		return min(minSize, current), current, nil
+	}
+
+	desiredCapacity = min(desiredCapacity, current+*service.LaunchBatchSize)
+	service.LaunchBatchAt = to.Timep(time.Now())
+
+	return min(minSize, desiredCapacity), desiredCapacity, nil
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Service_ValidateLaunchBatch(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	service := r.Services["web"]
+	assert.NoError(t, service.validateLaunchBatch())
+
+	service.LaunchBatchTimeout = to.Intp(*r.Timeout)
+	assert.Error(t, service.validateLaunchBatch())
+
+	service.LaunchBatchSize = to.Int64p(10)
+	assert.NoError(t, service.validateLaunchBatch())
+
+	service.LaunchBatchTimeout = to.Intp(*r.Timeout + 1)
+	assert.Error(t, service.validateLaunchBatch())
+
+	service.LaunchBatchTimeout = nil
+	service.LaunchBatchSize = to.Int64p(0)
+	assert.Error(t, service.validateLaunchBatch())
+
+	service.LaunchBatchSize = to.Int64p(10)
+	r.DeployStrategy = to.Strp(DeployInstanceRefresh)
+	assert.Error(t, service.validateLaunchBatch())
+}
+
+// mockLaunchBatchRelease is a release without load balancers whose web service launches 10 instances in batches of 4
+func mockLaunchBatchRelease(t *testing.T) (*Release, *mocks.MockClients) {
+	r := MockRelease(t)
+	r.Services["web"].ELBs = nil
+	r.Services["web"].TargetGroups = nil
+	r.Services["web"].LaunchBatchSize = to.Int64p(4)
+
+	spread := 0.0
+	r.Services["web"].Autoscaling.MinSize = to.Int64p(10)
+	r.Services["web"].Autoscaling.MaxSize = to.Int64p(10)
+	r.Services["web"].Autoscaling.Spread = &spread
+	MockPrepareRelease(r)
+	assert.NoError(t, r.ValidateServices())
+
+	awsc := MockAwsClients(r)
+	awsc.ASG = &mocks.ASGClient{TrackCreated: true, TrackCapacity: true}
+	awsc.ASG.AddPreviousRuntimeResources("project", "config", "web", "old-release")
+
+	return r, awsc
+}
+
+// createdCapacities returns the desired capacities the created ASG was updated to
+func createdCapacities(r *Release, awsc *mocks.MockClients) []int64 {
+	capacities := []int64{}
+	for _, input := range awsc.ASG.UpdateAutoScalingGroupInputs {
+		if *input.AutoScalingGroupName == *r.Services["web"].CreatedASG && input.DesiredCapacity != nil {
+			capacities = append(capacities, *input.DesiredCapacity)
+		}
+	}
+	return capacities
+}
+
+func Test_Release_LaunchBatchSize_Climbs_In_Batches(t *testing.T) {
+	r, awsc := mockLaunchBatchRelease(t)
+
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.Equal(t, int64(4), *awsc.ASG.CreateAutoScalingGroupInputs[0].DesiredCapacity)
+	assert.Equal(t, int64(4), *awsc.ASG.CreateAutoScalingGroupInputs[0].MinSize)
+
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, *r.Healthy)
+	assert.Equal(t, []int64{8}, createdCapacities(r, awsc))
+
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, *r.Healthy)
+	assert.Equal(t, []int64{8, 10}, createdCapacities(r, awsc))
+
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, *r.Healthy)
+	assert.Equal(t, []int64{8, 10}, createdCapacities(r, awsc))
+}
+
+func Test_Release_LaunchBatchSize_Waits_For_Batch(t *testing.T) {
+	r, awsc := mockLaunchBatchRelease(t)
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// One instance of the batch is still launching
+	created := awsc.ASG.DescribeAutoScalingGroupsPageResp[len(awsc.ASG.DescribeAutoScalingGroupsPageResp)-1].Resp.AutoScalingGroups[0]
+	created.Instances[3].LifecycleState = to.Strp("Pending")
+
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.Equal(t, []int64{}, createdCapacities(r, awsc))
+
+	// The batch has not become healthy in time
+	r.Services["web"].LaunchBatchAt = to.Timep(time.Now().Add(-11 * time.Minute))
+	err := r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP)
+	assert.Error(t, err)
+	assert.IsType(t, &HaltError{}, err)
+	assert.Contains(t, err.Error(), "launch batch of 4 instances not healthy within 10m0s")
+
+	// Once it is healthy the next batch launches
+	created.Instances[3].LifecycleState = to.Strp("InService")
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.Equal(t, []int64{8}, createdCapacities(r, awsc))
+}
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/odin/aws/instance"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Launch Extension
+//////////
+
+// launchingStates are the lifecycle states of instances still being provisioned
+var launchingStates = []string{
+	autoscaling.LifecycleStatePending,
+	autoscaling.LifecycleStatePendingWait,
+	autoscaling.LifecycleStatePendingProceed,
+}
+
+// ValidateLaunchExtension validates the LaunchExtensionMax
+func (release *Release) ValidateLaunchExtension() error {
+	if release.LaunchExtensionMax == nil {
+		return nil
+	}
+
+	if *release.LaunchExtensionMax < 0 {
+		return fmt.Errorf("LaunchExtensionMax must be at least 0")
+	}
+
+	if *release.Timeout+*release.LaunchExtensionMax > 172800 {
+		return fmt.Errorf("Timeout %v + LaunchExtensionMax %v must be at most 2 days", *release.Timeout, *release.LaunchExtensionMax)
+	}
+
+	return nil
+}
+
+// launchExtension is the seconds the Timeout and its phases have been extended
+func (release *Release) launchExtension() int {
+	if release.LaunchExtension == nil {
+		return 0
+	}
+	return *release.LaunchExtension
+}
+
+// IsHalt errors if the Timeout, extended by any LaunchExtension, is reached or the halt flag is found
+func (release *Release) IsHalt(s3c aws.S3API) error {
+	return release.withLaunchExtension(func() error {
+		if err := release.Release.TimedOut(); err != nil {
+			return TimeoutError{err.Error()}
+		}
+
+		return release.Release.IsHalt(s3c)
+	})
+}
+
+// withLaunchExtension calls fn with the Timeout extended by any LaunchExtension
+func (release *Release) withLaunchExtension(fn func() error) error {
+	if release.LaunchExtension == nil || release.Timeout == nil {
+		return fn()
+	}
+
+	timeout := release.Timeout
+	release.Timeout = to.Intp(*timeout + *release.LaunchExtension)
+	defer func() { release.Timeout = timeout }()
+
+	return fn()
+}
+
+// timeoutRemaining is the seconds left before the Timeout or the current phase times out
+func (release *Release) timeoutRemaining() int {
+	deadline := release.StartedAt.Add(time.Duration(*release.Timeout+release.launchExtension()) * time.Second)
+	remaining := int(time.Until(deadline).Seconds())
+
+	if phase := release.phaseRemaining(); phase < remaining {
+		return phase
+	}
+
+	return remaining
+}
+
+// recordLaunches records how long each instance took from launch to InService, and how many are still launching
+func (service *Service) recordLaunches(ec2c aws.EC2API, group *asg.ASG) error {
+	service.launchesPending = 0
+	service.launchesProgressed = false
+
+	if service.release == nil || service.release.LaunchExtensionMax == nil {
+		return nil
+	}
+
+	for _, state := range launchingStates {
+		service.launchesPending += len(group.LifecycleStateIDs(state))
+	}
+
+	newIDs := []string{}
+	for _, id := range group.LifecycleStateIDs(autoscaling.LifecycleStateInService) {
+		if _, ok := service.LaunchDurations[id]; !ok {
+			newIDs = append(newIDs, id)
+		}
+	}
+
+	if len(newIDs) == 0 {
+		return nil
+	}
+
+	launchTimes, err := instance.LaunchTimes(ec2c, newIDs)
+	if err != nil {
+		return err
+	}
+
+	if service.LaunchDurations == nil {
+		service.LaunchDurations = map[string]int{}
+	}
+
+	for id, launchTime := range launchTimes {
+		service.LaunchDurations[id] = int(time.Since(launchTime).Seconds())
+		service.launchesProgressed = true
+	}
+
+	return nil
+}
+
+// extendForLaunches extends the Timeout while instances are launching and reaching InService, so slow
+// provisioning does not leave the health checks without time. The remaining time is extended to what
+// the slowest instance took to reach InService, up to the LaunchExtensionMax. Launches have stalled,
+// and are not extended, if no instance has reached InService within the time the slowest took
+func (release *Release) extendForLaunches() {
+	if release.LaunchExtensionMax == nil || release.Timeout == nil || release.StartedAt == nil {
+		return
+	}
+
+	pending, progressed, slowest := 0, false, 0
+	for _, service := range release.Services {
+		pending += service.launchesPending
+		progressed = progressed || service.launchesProgressed
+		for _, d := range service.LaunchDurations {
+			if d > slowest {
+				slowest = d
+			}
+		}
+	}
+
+	now := time.Now()
+	if progressed {
+		release.LaunchProgressAt = to.Timep(now)
+	}
+
+	if pending == 0 || release.LaunchProgressAt == nil {
+		return
+	}
+
+	if now.After(release.LaunchProgressAt.Add(time.Duration(slowest) * time.Second)) {
+		return
+	}
+
+	release.extendTimeout(slowest)
+}
+
+// extendForGrace extends the Timeout while instances are inside their health check grace period, so an
+// instance launched late still has the rest of its grace period to become healthy, up to the LaunchExtensionMax
+func (release *Release) extendForGrace() {
+	if release.LaunchExtensionMax == nil || release.Timeout == nil || release.StartedAt == nil {
+		return
+	}
+
+	longest := 0
+	for _, service := range release.Services {
+		if service.graceRemaining > longest {
+			longest = service.graceRemaining
+		}
+	}
+
+	if longest == 0 {
+		return
+	}
+
+	release.extendTimeout(longest)
+}
+
+// extendTimeout extends the Timeout so at least seconds remain, up to the LaunchExtensionMax
+func (release *Release) extendTimeout(seconds int) {
+	remaining := release.timeoutRemaining()
+	if remaining >= seconds {
+		return
+	}
+
+	extension := release.launchExtension() + seconds - remaining
+	if extension > *release.LaunchExtensionMax {
+		extension = *release.LaunchExtensionMax
+	}
+
+	release.LaunchExtension = &extension
+}
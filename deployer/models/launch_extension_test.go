@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidateLaunchExtension(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	assert.NoError(t, release.ValidateLaunchExtension())
+
+	release.LaunchExtensionMax = to.Intp(600)
+	assert.NoError(t, release.ValidateLaunchExtension())
+
+	release.LaunchExtensionMax = to.Intp(-1)
+	assert.Error(t, release.ValidateLaunchExtension())
+
+	release.LaunchExtensionMax = to.Intp(172800)
+	assert.Error(t, release.ValidateLaunchExtension())
+}
+
+func Test_Release_IsHalt_LaunchExtension(t *testing.T) {
+	release := MockRelease(t)
+	release.Timeout = to.Intp(600)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	release.StartedAt = to.Timep(time.Now().Add(-700 * time.Second))
+	assert.Error(t, release.IsHalt(awsc.S3))
+	assert.Regexp(t, "DeployTimeout", release.PhaseTimedOut())
+
+	// The extension moves both the Timeout and the phase deadline, the Timeout is unchanged
+	release.LaunchExtension = to.Intp(200)
+	assert.NoError(t, release.IsHalt(awsc.S3))
+	assert.NoError(t, release.PhaseTimedOut())
+	assert.Equal(t, 600, *release.Timeout)
+
+	release.WipeControlledValues()
+	assert.Nil(t, release.LaunchExtension)
+}
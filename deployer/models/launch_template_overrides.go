@@ -0,0 +1,44 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/coinbase/odin/aws/lt"
+)
+
+// MANAGED_LAUNCH_TEMPLATE_FIELDS are the launch template data fields Odin sets from the release, they cannot be overridden
+var MANAGED_LAUNCH_TEMPLATE_FIELDS = []string{
+	"ImageId",
+	"InstanceType",
+	"UserData",
+	"IamInstanceProfile",
+	"SecurityGroupId", "SecurityGroupIds",
+	"SecurityGroup", "SecurityGroups",
+	"NetworkInterface", "NetworkInterfaces",
+}
+
+// validateLaunchTemplateOverrides validates the launch_template_overrides do not set managed fields and can be sent
+func (service *Service) validateLaunchTemplateOverrides() error {
+	if len(service.LaunchTemplateOverrides) == 0 {
+		return nil
+	}
+
+	fields := []string{}
+	for field := range service.LaunchTemplateOverrides {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		if containsStr(MANAGED_LAUNCH_TEMPLATE_FIELDS, field) {
+			return fmt.Errorf("LaunchTemplateOverrides cannot override %v, it is managed by Odin", field)
+		}
+	}
+
+	if err := (&lt.Input{}).SetOverrides(service.LaunchTemplateOverrides); err != nil {
+		return fmt.Errorf("LaunchTemplateOverrides %v", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidateResources_LaunchTemplateOverrides(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	release.Services["web"].LaunchTemplateOverrides = map[string]interface{}{"DisableApiStop": true}
+	assert.NoError(t, release.ValidateResources(resources))
+
+	// Fields Odin manages cannot be overridden
+	for _, field := range []string{"ImageId", "SecurityGroupId", "SecurityGroups", "UserData"} {
+		release.Services["web"].LaunchTemplateOverrides = map[string]interface{}{field: "override"}
+		err = release.ValidateResources(resources)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot override "+field)
+	}
+
+	release.Services["web"].LaunchTemplateOverrides = map[string]interface{}{"CpuOptions": map[string]interface{}{}}
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "LaunchTemplateOverrides")
+}
+
+func Test_Release_CreateResources_LaunchTemplateOverrides(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].EnableDetailedMonitoring = to.Boolp(true)
+	release.Services["web"].LaunchTemplateOverrides = map[string]interface{}{
+		"MaintenanceOptions": map[string]interface{}{"AutoRecovery": "disabled"},
+		"Monitoring":         map[string]interface{}{"Enabled": false},
+	}
+	MockPrepareRelease(release)
+
+	// The overrides are only supported by launch templates
+	service := release.Services["web"]
+	assert.True(t, service.launchTemplate())
+
+	awsc := MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// The override sets a field Odin does not support
+	data := awsc.EC2.LaunchTemplateData[*service.ServiceID()]
+	assert.Equal(t, "disabled", data["MaintenanceOptions.AutoRecovery"])
+
+	// First class fields take precedence
+	assert.Equal(t, "true", data["Monitoring.Enabled"])
+	assert.Equal(t, *service.InstanceType, data["InstanceType"])
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/lt"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Launch Template Versions
+//////////
+
+// maxLaunchTemplateVersions is the AWS limit of versions per launch template
+const maxLaunchTemplateVersions = 10000
+
+// sharedLaunchTemplate is true if the service adds a version to its project config service launch template
+// rather than creating a launch template per release
+func (service *Service) sharedLaunchTemplate() bool {
+	return service.LaunchTemplateRetention != nil
+}
+
+// launchTemplateName returns the shared "project-config-service" launch template, or the release ServiceID
+func (service *Service) launchTemplateName() *string {
+	if !service.sharedLaunchTemplate() {
+		return service.ServiceID()
+	}
+
+	if service.ProjectName() == nil || service.ConfigName() == nil || service.ServiceName == nil {
+		return nil
+	}
+
+	return to.Strp(fmt.Sprintf("%v-%v-%v", *service.ProjectName(), *service.ConfigName(), *service.ServiceName))
+}
+
+// launchTemplateVersion pins the ASG to the version this release created on a shared launch template
+func (service *Service) launchTemplateVersion() *string {
+	if !service.sharedLaunchTemplate() || service.CreatedLaunchTemplateVersion == nil {
+		return to.Strp("$Latest")
+	}
+
+	return to.Strp(fmt.Sprintf("%v", *service.CreatedLaunchTemplateVersion))
+}
+
+// validateLaunchTemplateRetention validates LaunchTemplateRetention
+func (service *Service) validateLaunchTemplateRetention() error {
+	if !service.sharedLaunchTemplate() {
+		return nil
+	}
+
+	if *service.LaunchTemplateRetention < 1 || *service.LaunchTemplateRetention > maxLaunchTemplateVersions {
+		return fmt.Errorf("LaunchTemplateRetention must be between 1 and %v", maxLaunchTemplateVersions)
+	}
+
+	return nil
+}
+
+// countLaunchTemplateVersions returns the number of versions of the shared launch template, 0 if it does not exist
+func (service *Service) countLaunchTemplateVersions(ec2c aws.EC2API) (int, error) {
+	if !service.sharedLaunchTemplate() {
+		return 0, nil
+	}
+
+	latest, err := lt.LatestVersion(ec2c, service.launchTemplateName())
+	if err != nil || latest == nil {
+		return 0, err
+	}
+
+	versions, _, err := lt.Versions(ec2c, service.launchTemplateName())
+	if err != nil {
+		return 0, err
+	}
+
+	return len(versions), nil
+}
+
+// validateLaunchTemplateVersions validates the release can add a version to the shared launch template
+func (sr *ServiceResources) validateLaunchTemplateVersions(service *Service) error {
+	if sr.LaunchTemplateVersions+1 > maxLaunchTemplateVersions {
+		return fmt.Errorf("%v launch template %v has %v versions, the limit is %v", service.errorPrefix(), to.Strs(service.launchTemplateName()), sr.LaunchTemplateVersions, maxLaunchTemplateVersions)
+	}
+
+	return nil
+}
+
+// createLaunchTemplateVersion creates the shared launch template or adds a version to it
+func (service *Service) createLaunchTemplateVersion(ec2c aws.EC2API, input *lt.Input) error {
+	latest, err := lt.LatestVersion(ec2c, input.LaunchTemplateName)
+	if err != nil {
+		return err
+	}
+
+	if latest == nil {
+		if err := input.Create(ec2c); err != nil {
+			return err
+		}
+
+		service.CreatedLaunchTemplateVersion = to.Int64p(1)
+		return nil
+	}
+
+	version, err := input.CreateVersion(ec2c, service.ReleaseID())
+	if err != nil {
+		return err
+	}
+
+	service.CreatedLaunchTemplateVersion = version
+	return nil
+}
+
+// launchTemplateSpecification is the services launch template
+func (service *Service) launchTemplateSpecification() *autoscaling.LaunchTemplateSpecification {
+	return &autoscaling.LaunchTemplateSpecification{
+		LaunchTemplateName: service.launchTemplateName(),
+		Version:            service.launchTemplateVersion(),
+	}
+}
+
+// PruneLaunchTemplateVersions makes this releases versions the default of the shared launch templates
+// and deletes the versions beyond each services LaunchTemplateRetention
+func (release *Release) PruneLaunchTemplateVersions(ec2c aws.EC2API) error {
+	for _, name := range sortedServiceNames(release) {
+		service := release.Services[name]
+		if !service.sharedLaunchTemplate() || service.CreatedLaunchTemplateVersion == nil {
+			continue
+		}
+
+		if err := lt.SetDefaultVersion(ec2c, service.launchTemplateName(), service.CreatedLaunchTemplateVersion); err != nil {
+			return err
+		}
+
+		if _, err := lt.PruneVersions(ec2c, service.launchTemplateName(), int(*service.LaunchTemplateRetention)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockLaunchTemplateRelease(t *testing.T, versions int64) (*Release, *mocks.MockClients) {
+	release := MockRelease(t)
+	release.Services["web"].LaunchTemplateRetention = to.Int64p(2)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	if versions > 0 {
+		awsc.EC2.AddLaunchTemplate("project-config-web", versions)
+	}
+
+	return release, awsc
+}
+
+func Test_Service_validateLaunchTemplateRetention(t *testing.T) {
+	service := &Service{}
+	assert.NoError(t, service.validateLaunchTemplateRetention())
+	assert.False(t, service.launchTemplate())
+
+	service.LaunchTemplateRetention = to.Int64p(5)
+	assert.NoError(t, service.validateLaunchTemplateRetention())
+	assert.True(t, service.launchTemplate())
+
+	service.LaunchTemplateRetention = to.Int64p(0)
+	assert.Error(t, service.validateLaunchTemplateRetention())
+
+	service.LaunchTemplateRetention = to.Int64p(maxLaunchTemplateVersions + 1)
+	assert.Error(t, service.validateLaunchTemplateRetention())
+}
+
+func Test_Release_LaunchTemplateRetention_Creates_Template(t *testing.T) {
+	release, awsc := mockLaunchTemplateRelease(t, 0)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	assert.Equal(t, 1, len(awsc.EC2.CreateLaunchTemplateInputs))
+	assert.Equal(t, 0, len(awsc.EC2.CreateLaunchTemplateVersionInputs))
+	assert.Equal(t, "project-config-web", *awsc.EC2.CreateLaunchTemplateInputs[0].LaunchTemplateName)
+
+	// A failed release cannot find the shared template by its release tags
+	for _, tag := range awsc.EC2.CreateLaunchTemplateInputs[0].TagSpecifications[0].Tags {
+		assert.NotEqual(t, "ReleaseUUID", *tag.Key)
+	}
+
+	spec := awsc.ASG.CreateAutoScalingGroupInputs[0].LaunchTemplate
+	assert.Equal(t, "project-config-web", *spec.LaunchTemplateName)
+	assert.Equal(t, "1", *spec.Version)
+	assert.Nil(t, awsc.ASG.CreateAutoScalingGroupInputs[0].LaunchConfigurationName)
+}
+
+func Test_Release_LaunchTemplateRetention_Adds_Version(t *testing.T) {
+	release, awsc := mockLaunchTemplateRelease(t, 5)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	assert.Equal(t, 1, len(awsc.EC2.CreateLaunchTemplateInputs)) // The added template
+	assert.Equal(t, 1, len(awsc.EC2.CreateLaunchTemplateVersionInputs))
+	assert.Equal(t, int64(6), *release.Services["web"].CreatedLaunchTemplateVersion)
+	assert.Equal(t, "6", *awsc.ASG.CreateAutoScalingGroupInputs[0].LaunchTemplate.Version)
+
+	// A failed release keeps the shared template, the version is pruned by a later release
+	assert.NoError(t, release.UnsuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+	assert.Equal(t, 0, len(awsc.EC2.DeleteLaunchTemplateInputs))
+}
+
+func Test_Release_LaunchTemplateRetention_Prunes_Versions(t *testing.T) {
+	release, awsc := mockLaunchTemplateRelease(t, 5)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.NoError(t, release.SuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+
+	// This releases version is the default and only the newest 2 versions are kept
+	assert.Equal(t, int64(6), awsc.EC2.DefaultLaunchTemplateVersions["project-config-web"])
+	assert.Equal(t, []int64{5, 6}, awsc.EC2.LaunchTemplateVersions["project-config-web"])
+	assert.Equal(t, 0, len(awsc.EC2.DeleteLaunchTemplateInputs))
+}
+
+func Test_Release_LaunchTemplateRetention_ValidateResources(t *testing.T) {
+	release, awsc := mockLaunchTemplateRelease(t, 3)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, resources.ServiceResources["web"].LaunchTemplateVersions)
+	assert.NoError(t, release.ValidateResources(resources))
+
+	// The version limit is reached
+	release, awsc = mockLaunchTemplateRelease(t, maxLaunchTemplateVersions)
+
+	resources, err = release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the limit is 10000")
+}
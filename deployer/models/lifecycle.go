@@ -2,10 +2,14 @@ package models
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
 	"github.com/coinbase/odin/aws/iam"
+	"github.com/coinbase/odin/aws/instance"
 	"github.com/coinbase/odin/aws/sns"
 	"github.com/coinbase/step/utils/is"
 	"github.com/coinbase/step/utils/to"
@@ -15,6 +19,7 @@ import (
 type LifeCycleHook struct {
 	Transistion      *string `json:"transition,omitempty"`
 	SNS              *string `json:"sns,omitempty"`
+	SQS              *string `json:"sqs,omitempty"`
 	Role             *string `json:"role,omitempty"`
 	HeartbeatTimeout *int64  `json:"heartbeat_timeout,omitempty"`
 
@@ -63,6 +68,10 @@ func (lc *LifeCycleHook) SetDefaults(region *string, accountID *string, name str
 	if lc.SNS != nil && lc.NotificationTargetARN == nil {
 		lc.NotificationTargetARN = to.Strp(fmt.Sprintf("arn:aws:sns:%v:%v:%v", *region, *accountID, *lc.SNS))
 	}
+
+	if lc.SQS != nil && lc.NotificationTargetARN == nil {
+		lc.NotificationTargetARN = to.Strp(fmt.Sprintf("arn:aws:sqs:%v:%v:%v", *region, *accountID, *lc.SQS))
+	}
 }
 
 // ValidateAttributes validates attributes
@@ -80,9 +89,72 @@ func (lc *LifeCycleHook) ValidateAttributes() error {
 		return fmt.Errorf("Lifecycle NotificationTargetARN nil")
 	}
 
+	if lc.SNS != nil && lc.SQS != nil {
+		return fmt.Errorf("Lifecycle can only notify one of SNS or SQS")
+	}
+
 	if *lc.Transistion != "autoscaling:EC2_INSTANCE_LAUNCHING" && *lc.Transistion != "autoscaling:EC2_INSTANCE_TERMINATING" {
 		return fmt.Errorf("Transistion must equal either 'autoscaling:EC2_INSTANCE_LAUNCHING' or 'autoscaling:EC2_INSTANCE_TERMINATING'")
 	}
 
 	return nil
 }
+
+// defaultHeartbeatTimeout is the AWS default seconds an instance waits in a lifecycle hook
+const defaultHeartbeatTimeout = 3600
+
+// launchHeartbeat returns the longest heartbeat timeout of the launching hooks, false if there are none
+func launchHeartbeat(hooks map[string]*LifeCycleHook) (int64, bool) {
+	heartbeat, found := int64(0), false
+	for _, lc := range hooks {
+		if lc == nil || to.Strs(lc.Transistion) != "autoscaling:EC2_INSTANCE_LAUNCHING" {
+			continue
+		}
+
+		timeout := int64(defaultHeartbeatTimeout)
+		if lc.HeartbeatTimeout != nil {
+			timeout = *lc.HeartbeatTimeout
+		}
+
+		if timeout > heartbeat {
+			heartbeat = timeout
+		}
+		found = true
+	}
+
+	return heartbeat, found
+}
+
+// checkLifecycleWait halts if an instance has waited in a launching hook longer than its heartbeat.
+// The hook would have timed out so the instance is stuck
+func (service *Service) checkLifecycleWait(ec2c aws.EC2API, group *asg.ASG) error {
+	heartbeat, ok := launchHeartbeat(service.LifeCycleHooks())
+	if !ok {
+		return nil
+	}
+
+	waitingIDs := group.LifecycleStateIDs(autoscaling.LifecycleStatePendingWait)
+	if len(waitingIDs) == 0 {
+		return nil
+	}
+
+	launchTimes, err := instance.LaunchTimes(ec2c, waitingIDs)
+	if err != nil {
+		return err // This might retry
+	}
+
+	stuck := []string{}
+	for _, id := range waitingIDs {
+		launchTime, ok := launchTimes[id]
+		if ok && time.Since(launchTime) > time.Duration(heartbeat)*time.Second {
+			stuck = append(stuck, id)
+		}
+	}
+
+	if len(stuck) > 0 {
+		err := fmt.Errorf("Instances %v in %v longer than lifecycle heartbeat %vs, %v", to.Strs(service.ServiceName), autoscaling.LifecycleStatePendingWait, heartbeat, strings.Join(stuck, ","))
+		return &HaltError{err} // This will immediately stop deploying
+	}
+
+	return nil
+}
@@ -2,7 +2,9 @@ package models
 
 import (
 	"testing"
+	"time"
 
+	"github.com/coinbase/odin/aws/mocks"
 	"github.com/coinbase/step/utils/to"
 	"github.com/stretchr/testify/assert"
 )
@@ -17,3 +19,68 @@ func Test_Lifecycle_Valid(t *testing.T) {
 	lc.SetDefaults(to.Strp("region"), to.Strp("accountID"), "name")
 	assert.NoError(t, lc.ValidateAttributes())
 }
+
+func Test_Lifecycle_SQS(t *testing.T) {
+	lc := &LifeCycleHook{
+		Transistion: to.Strp("autoscaling:EC2_INSTANCE_LAUNCHING"),
+		Role:        to.Strp("role"),
+		SQS:         to.Strp("queue"),
+	}
+
+	lc.SetDefaults(to.Strp("region"), to.Strp("accountID"), "name")
+	assert.NoError(t, lc.ValidateAttributes())
+	assert.Equal(t, "arn:aws:sqs:region:accountID:queue", to.Strs(lc.NotificationTargetARN))
+
+	lc.SNS = to.Strp("sns")
+	assert.Error(t, lc.ValidateAttributes())
+}
+
+func mockServiceLifecycleRelease(t *testing.T) *Release {
+	release := MockRelease(t)
+	release.Services["web"].LifeCycleHooksVal = map[string]*LifeCycleHook{
+		"LaunchHook": &LifeCycleHook{
+			Transistion:      to.Strp("autoscaling:EC2_INSTANCE_LAUNCHING"),
+			Role:             to.Strp("sqs_role"),
+			SQS:              to.Strp("queue"),
+			HeartbeatTimeout: to.Int64p(600),
+		},
+	}
+	MockPrepareRelease(release)
+	return release
+}
+
+func Test_Service_LifeCycleHooks_Merged(t *testing.T) {
+	release := mockServiceLifecycleRelease(t)
+	service := release.Services["web"]
+
+	hooks := service.LifeCycleHooks()
+	assert.Equal(t, 2, len(hooks))
+	assert.NotNil(t, hooks["TermHook"])
+	assert.Equal(t, "arn:aws:sqs:us-east-1:000000:queue", to.Strs(hooks["LaunchHook"].NotificationTargetARN))
+	assert.Equal(t, 1, len(release.LifeCycleHooks))
+
+	specs := service.LifeCycleHookSpecs()
+	assert.Equal(t, 2, len(specs))
+}
+
+func Test_Service_UpdateHealthy_LifecycleWaitStuck(t *testing.T) {
+	release := mockServiceLifecycleRelease(t)
+	awsc := MockAwsClients(release)
+
+	service := release.Services["web"]
+	service.CreatedASG = to.Strp("asg")
+
+	instances := mocks.MakeMockASGWaitingInstances(1)
+	awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0].Instances = instances
+
+	// Waiting within the heartbeat is still launching
+	awsc.EC2.AddInstance("WaitingInstanceId1", time.Now().Add(-5*time.Minute))
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, service.Healthy)
+
+	// Waiting longer than the heartbeat is stuck
+	awsc.EC2.AddInstance("WaitingInstanceId1", time.Now().Add(-15*time.Minute))
+	err := release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP)
+	assert.Error(t, err)
+	assert.IsType(t, &HaltError{}, err)
+}
@@ -0,0 +1,322 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/alb"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Listener Rule Cutover
+//////////
+
+var listenerARN = regexp.MustCompile(`^arn:[^:]+:elasticloadbalancing:[^:]*:[0-9]*:listener/app/.+$`)
+
+// defaultMaxRulesPerListener is the default AWS limit of rules per ALB listener, not counting the default rule
+const defaultMaxRulesPerListener = 100
+
+// ListenerRule is an ALB listener rule, e.g. routing a path, that is cut over between a blue and green target group.
+// Each release is only attached to the target group the rule does not forward to, and the rule forwards to it on cutover
+type ListenerRule struct {
+	ListenerArn  *string   `json:"listener_arn,omitempty"`
+	Priority     *int64    `json:"priority,omitempty"`
+	TargetGroups []*string `json:"target_groups,omitempty"` // Two of the services target groups
+
+	// Controlled
+	RuleArn                *string         `json:"rule_arn,omitempty"`
+	TargetGroupArn         *string         `json:"target_group_arn,omitempty"`          // The releases target group
+	PreviousTargetGroupArn *string         `json:"previous_target_group_arn,omitempty"` // The other, served by the previous release
+	PreviousActions        []*elbv2.Action `json:"previous_actions,omitempty"`
+}
+
+// WipeControlledValues wipes values that are controlled by the deployer
+func (lr *ListenerRule) WipeControlledValues() {
+	lr.RuleArn = nil
+	lr.TargetGroupArn = nil
+	lr.PreviousTargetGroupArn = nil
+	lr.PreviousActions = nil
+}
+
+// priority is the rules priority as ELBv2 describes it
+func (lr *ListenerRule) priority() *string {
+	if lr.Priority == nil {
+		return nil
+	}
+	return to.Strp(fmt.Sprintf("%v", *lr.Priority))
+}
+
+// validateListenerRule validates the listener_rule
+func (service *Service) validateListenerRule() error {
+	lr := service.ListenerRule
+	if lr == nil {
+		return nil
+	}
+
+	if lr.ListenerArn == nil || !listenerARN.MatchString(*lr.ListenerArn) {
+		return fmt.Errorf("ListenerRule listener_arn must be an application load balancer listener ARN")
+	}
+
+	if lr.Priority == nil || *lr.Priority < 1 || *lr.Priority > 50000 {
+		return fmt.Errorf("ListenerRule priority must be between 1 and 50000")
+	}
+
+	if len(lr.TargetGroups) != 2 || !is.UniqueStrp(lr.TargetGroups) {
+		return fmt.Errorf("ListenerRule target_groups must be two different target groups")
+	}
+
+	for _, name := range lr.TargetGroups {
+		if name == nil || !containsStrp(service.TargetGroups, *name) {
+			return fmt.Errorf("ListenerRule target_groups %v must be in the services target_groups", to.Strs(name))
+		}
+	}
+
+	if service.release != nil && service.release.IsInstanceRefresh() {
+		return fmt.Errorf("ListenerRule cannot be used with the %v deploy strategy", DeployInstanceRefresh)
+	}
+
+	if service.release != nil && service.release.InPlace {
+		return fmt.Errorf("ListenerRule cannot be used with in place updates")
+	}
+
+	return nil
+}
+
+// ValidateMaxRulesPerListener validates MaxRulesPerListener
+func (release *Release) ValidateMaxRulesPerListener() error {
+	if limit := release.MaxRulesPerListener; limit != nil && *limit < 1 {
+		return fmt.Errorf("MaxRulesPerListener must be greater than 0")
+	}
+
+	return nil
+}
+
+// maxRulesPerListener returns the accounts limit of rules per listener
+func (release *Release) maxRulesPerListener() int {
+	if release == nil || release.MaxRulesPerListener == nil {
+		return defaultMaxRulesPerListener
+	}
+	return *release.MaxRulesPerListener
+}
+
+// ValidateListenerCapacity counts the rules of each listener the release has listener rules on, and errors if
+// adding the rules that are not on the listener yet would exceed the limit of rules per listener
+func (release *Release) ValidateListenerCapacity(albc aws.ALBAPI) error {
+	arns := []string{}
+	priorities := map[string][]string{}
+	for _, name := range sortedServiceNames(release) {
+		service := release.Services[name]
+		if service == nil || service.ListenerRule == nil || service.ListenerRule.ListenerArn == nil {
+			continue
+		}
+
+		arn := *service.ListenerRule.ListenerArn
+		if _, ok := priorities[arn]; !ok {
+			arns = append(arns, arn)
+		}
+
+		priority := to.Strs(service.ListenerRule.priority())
+		if !containsStr(priorities[arn], priority) {
+			priorities[arn] = append(priorities[arn], priority)
+		}
+	}
+
+	limit := release.maxRulesPerListener()
+	for _, arn := range arns {
+		rules, err := alb.Rules(albc, to.Strp(arn))
+		if err != nil {
+			return fmt.Errorf("ListenerRule listener %v %v", arn, err.Error())
+		}
+
+		existing := []string{}
+		for _, rule := range rules {
+			if rule != nil && (rule.IsDefault == nil || !*rule.IsDefault) {
+				existing = append(existing, to.Strs(rule.Priority))
+			}
+		}
+
+		adding := 0
+		for _, priority := range priorities[arn] {
+			if !containsStr(existing, priority) {
+				adding++
+			}
+		}
+
+		if len(existing)+adding > limit {
+			return fmt.Errorf("ListenerRule listener %v has %v rules, adding %v would exceed the limit of %v rules", arn, len(existing), adding, limit)
+		}
+	}
+
+	return nil
+}
+
+// findListenerRule returns the rule the service cuts over, nil if it is not found
+func (service *Service) findListenerRule(albc aws.ALBAPI) (*elbv2.Rule, error) {
+	if service.ListenerRule == nil {
+		return nil, nil
+	}
+
+	return alb.FindRule(albc, service.ListenerRule.ListenerArn, service.ListenerRule.priority())
+}
+
+// listenerRuleTargetGroupArns returns the ARNs of the services rules target groups, nil if one is not found
+func (sr *ServiceResources) listenerRuleTargetGroupArns(service *Service) []*string {
+	arns := []*string{}
+	for _, name := range service.ListenerRule.TargetGroups {
+		for _, tg := range sr.TargetGroups {
+			if tg != nil && !is.EmptyStr(tg.TargetGroupArn) && to.Strs(tg.TargetGroupName) == to.Strs(name) {
+				arns = append(arns, tg.TargetGroupArn)
+			}
+		}
+	}
+
+	if len(arns) != len(service.ListenerRule.TargetGroups) {
+		return nil
+	}
+
+	return arns
+}
+
+// listenerRuleCutover returns the ARN of the target group the release is attached to and the rule forwards
+// to on cutover, and the other served by the previous release. It is the one the rule does not forward to now
+func (sr *ServiceResources) listenerRuleCutover(service *Service) (*string, *string) {
+	arns := sr.listenerRuleTargetGroupArns(service)
+	if arns == nil || sr.ListenerRule == nil {
+		return nil, nil
+	}
+
+	if containsStrp(alb.ForwardTargetGroups(sr.ListenerRule.Actions), *arns[0]) {
+		return arns[1], arns[0]
+	}
+
+	return arns[0], arns[1]
+}
+
+// validateListenerRule errors if the services listener rule does not exist or does not forward to a target group
+func (sr *ServiceResources) validateListenerRule(service *Service) error {
+	lr := service.ListenerRule
+	if lr == nil {
+		return nil
+	}
+
+	if sr.ListenerRule == nil {
+		return fmt.Errorf("ListenerRule priority %v not found on listener %v", *lr.Priority, *lr.ListenerArn)
+	}
+
+	forwards := alb.ForwardTargetGroups(sr.ListenerRule.Actions)
+	if len(forwards) == 0 {
+		return fmt.Errorf("ListenerRule priority %v must forward to a target group", *lr.Priority)
+	}
+
+	arns := sr.listenerRuleTargetGroupArns(service)
+	if arns == nil {
+		return fmt.Errorf("ListenerRule target groups %v not found", to.StrSlice(lr.TargetGroups))
+	}
+
+	if containsStrp(forwards, *arns[0]) && containsStrp(forwards, *arns[1]) {
+		return fmt.Errorf("ListenerRule priority %v forwards to both target_groups, it must forward to one", *lr.Priority)
+	}
+
+	return nil
+}
+
+// updateListenerRule records the rule and its actions before the deploy, so a failure can restore them
+func (service *Service) updateListenerRule(sr *ServiceResources) {
+	if service.ListenerRule == nil || sr.ListenerRule == nil {
+		return
+	}
+
+	service.ListenerRule.RuleArn = sr.ListenerRule.RuleArn
+	service.ListenerRule.TargetGroupArn, service.ListenerRule.PreviousTargetGroupArn = sr.listenerRuleCutover(service)
+	service.ListenerRule.PreviousActions = sr.ListenerRule.Actions
+}
+
+// targetGroupArns returns the target groups the new ASG is attached to and checked for health, every target group
+// of the service except the listener rules target group served by the previous release
+func (service *Service) targetGroupArns() []*string {
+	if service.Resources == nil {
+		return nil
+	}
+
+	if service.ListenerRule == nil || service.ListenerRule.PreviousTargetGroupArn == nil {
+		return service.Resources.TargetGroups
+	}
+
+	arns := []*string{}
+	for _, arn := range service.Resources.TargetGroups {
+		if to.Strs(arn) != *service.ListenerRule.PreviousTargetGroupArn {
+			arns = append(arns, arn)
+		}
+	}
+
+	return arns
+}
+
+// listenerRules returns the services with a listener rule to cut over by name
+func (release *Release) listenerRules() []*Service {
+	services := []*Service{}
+	for _, name := range sortedServiceNames(release) {
+		service := release.Services[name]
+		if service == nil || service.ListenerRule == nil || is.EmptyStr(service.ListenerRule.RuleArn) || service.ListenerRule.TargetGroupArn == nil {
+			continue
+		}
+
+		services = append(services, service)
+	}
+
+	return services
+}
+
+// CutoverListenerRules forwards each services listener rule to the releases target group only, so the rule stops
+// routing to the previous release. The other actions of the rule are unchanged
+func (release *Release) CutoverListenerRules(albc aws.ALBAPI) error {
+	for _, service := range release.listenerRules() {
+		lr := service.ListenerRule
+		actions, err := alb.RuleActions(albc, lr.RuleArn)
+		if err != nil {
+			return err
+		}
+
+		if err := alb.SetRuleActions(albc, lr.RuleArn, alb.ForwardTo(actions, lr.TargetGroupArn)); err != nil {
+			return fmt.Errorf("%v ListenerRule cutover %v", service.errorPrefix(), err.Error())
+		}
+	}
+
+	return nil
+}
+
+// RevertListenerRules restores the actions each services listener rule had before the deploy, if it was cut over
+func (release *Release) RevertListenerRules(albc aws.ALBAPI) error {
+	for _, service := range release.listenerRules() {
+		lr := service.ListenerRule
+		if len(lr.PreviousActions) == 0 {
+			continue
+		}
+
+		actions, err := alb.RuleActions(albc, lr.RuleArn)
+		if err != nil {
+			return err
+		}
+
+		// Never cut over or already reverted
+		if !forwardsOnlyTo(actions, lr.TargetGroupArn) {
+			continue
+		}
+
+		if err := alb.SetRuleActions(albc, lr.RuleArn, lr.PreviousActions); err != nil {
+			return fmt.Errorf("%v ListenerRule revert %v", service.errorPrefix(), err.Error())
+		}
+	}
+
+	return nil
+}
+
+// forwardsOnlyTo returns true if the actions forward to only the target group
+func forwardsOnlyTo(actions []*elbv2.Action, targetGroupArn *string) bool {
+	forwards := alb.ForwardTargetGroups(actions)
+	return len(forwards) == 1 && to.Strs(forwards[0]) == to.Strs(targetGroupArn)
+}
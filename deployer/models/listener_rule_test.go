@@ -0,0 +1,206 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/coinbase/odin/aws/alb"
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+const mockListenerArn = "arn:aws:elasticloadbalancing:us-east-1:000000:listener/app/web/1234/5678"
+
+func Test_Service_ValidateListenerRule(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].TargetGroups = []*string{to.Strp("web-elb-target"), to.Strp("web-blue"), to.Strp("web-green")}
+	MockPrepareRelease(release)
+	service := release.Services["web"]
+
+	service.ListenerRule = &ListenerRule{
+		ListenerArn:  to.Strp(mockListenerArn),
+		Priority:     to.Int64p(20),
+		TargetGroups: []*string{to.Strp("web-blue"), to.Strp("web-green")},
+	}
+	assert.NoError(t, service.validateListenerRule())
+
+	service.ListenerRule.TargetGroups = []*string{to.Strp("web-blue")}
+	assert.Error(t, service.validateListenerRule())
+
+	service.ListenerRule.TargetGroups = []*string{to.Strp("web-blue"), to.Strp("web-blue")}
+	assert.Error(t, service.validateListenerRule())
+
+	service.ListenerRule.TargetGroups = []*string{to.Strp("web-blue"), to.Strp("other-target")}
+	assert.Error(t, service.validateListenerRule())
+
+	service.ListenerRule.TargetGroups = []*string{to.Strp("web-blue"), to.Strp("web-green")}
+	service.ListenerRule.Priority = to.Int64p(0)
+	assert.Error(t, service.validateListenerRule())
+
+	service.ListenerRule.Priority = to.Int64p(20)
+	service.ListenerRule.ListenerArn = to.Strp("arn:aws:elasticloadbalancing:us-east-1:000000:loadbalancer/app/web/1234")
+	assert.Error(t, service.validateListenerRule())
+
+	service.ListenerRule.ListenerArn = to.Strp(mockListenerArn)
+	release.InPlace = true
+	assert.Error(t, service.validateListenerRule())
+
+	release.InPlace = false
+	release.DeployStrategy = to.Strp(DeployInstanceRefresh)
+	assert.Error(t, service.validateListenerRule())
+}
+
+// mockListenerRuleRelease routes /api to another target group and /web to web-blue, which the previous release serves
+func mockListenerRuleRelease(t *testing.T) (*Release, *mocks.MockClients) {
+	release := MockRelease(t)
+	release.Services["web"].TargetGroups = []*string{to.Strp("web-elb-target"), to.Strp("web-blue"), to.Strp("web-green")}
+	release.Services["web"].ListenerRule = &ListenerRule{
+		ListenerArn:  to.Strp(mockListenerArn),
+		Priority:     to.Int64p(20),
+		TargetGroups: []*string{to.Strp("web-blue"), to.Strp("web-green")},
+	}
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	for _, name := range []string{"web-blue", "web-green"} {
+		awsc.ALB.AddTargetGroup(mocks.MockTargetGroup{
+			Name:        name,
+			ProjectName: *release.ProjectName,
+			ConfigName:  *release.ConfigName,
+			ServiceName: "web",
+		})
+	}
+	awsc.ALB.AddListenerRule(mockListenerArn, "10", "api-target")
+	awsc.ALB.AddListenerRule(mockListenerArn, "20", "web-blue")
+
+	return release, awsc
+}
+
+func Test_Release_ValidateResources_ListenerRule(t *testing.T) {
+	release, awsc := mockListenerRuleRelease(t)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+
+	release.Services["web"].ListenerRule.Priority = to.Int64p(30)
+	resources, err = release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ListenerRule priority 30 not found")
+
+	// Forwarding to both target groups leaves nothing to cut over to
+	release.Services["web"].ListenerRule.Priority = to.Int64p(20)
+	awsc.ALB.ListenerRules[mockListenerArn][1].Actions = []*elbv2.Action{{
+		Type: to.Strp("forward"),
+		ForwardConfig: &elbv2.ForwardActionConfig{TargetGroups: []*elbv2.TargetGroupTuple{
+			{TargetGroupArn: to.Strp("web-blue"), Weight: to.Int64p(50)},
+			{TargetGroupArn: to.Strp("web-green"), Weight: to.Int64p(50)},
+		}},
+	}}
+
+	resources, err = release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "forwards to both target_groups")
+}
+
+func Test_Release_ValidateListenerCapacity(t *testing.T) {
+	release, awsc := mockListenerRuleRelease(t)
+
+	// A near full listener, the default rule is not counted
+	awsc.ALB.FillListener(mockListenerArn, 98, "other-target")
+	awsc.ALB.ListenerRules[mockListenerArn] = append(awsc.ALB.ListenerRules[mockListenerArn], &elbv2.Rule{
+		RuleArn:   to.Strp(mockListenerArn + "/rule/default"),
+		Priority:  to.Strp("default"),
+		IsDefault: to.Boolp(true),
+	})
+	assert.NoError(t, release.ValidateListenerCapacity(awsc.ALB))
+
+	// The rule is not on the full listener, so it cannot be added
+	release.Services["web"].ListenerRule.Priority = to.Int64p(30)
+	err := release.ValidateListenerCapacity(awsc.ALB)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "has 100 rules, adding 1 would exceed the limit of 100 rules")
+
+	release.MaxRulesPerListener = to.Intp(101)
+	assert.NoError(t, release.ValidateListenerCapacity(awsc.ALB))
+
+	release.MaxRulesPerListener = to.Intp(0)
+	assert.Error(t, release.ValidateMaxRulesPerListener())
+
+	release.Services["web"].ListenerRule.ListenerArn = to.Strp("missing")
+	assert.Error(t, release.ValidateListenerCapacity(awsc.ALB))
+}
+
+func Test_Release_CutoverListenerRules(t *testing.T) {
+	release, awsc := mockListenerRuleRelease(t)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+
+	// The rule forwards to web-blue, so the release is deployed to web-green
+	lr := release.Services["web"].ListenerRule
+	assert.Equal(t, mockListenerArn+"/rule/20", *lr.RuleArn)
+	assert.Equal(t, "web-green", *lr.TargetGroupArn)
+	assert.Equal(t, "web-blue", *lr.PreviousTargetGroupArn)
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.Equal(t, []string{"web-elb-target", "web-green"}, to.StrSlice(awsc.ASG.CreateAutoScalingGroupInputs[0].TargetGroupARNs))
+
+	// Reverting before the cutover changes nothing
+	assert.NoError(t, release.RevertListenerRules(awsc.ALB))
+	assert.Equal(t, 0, len(awsc.ALB.ModifyRuleInputs))
+
+	// Only the services rule forwards to the new target group
+	assert.NoError(t, release.CutoverListenerRules(awsc.ALB))
+	assert.Equal(t, 1, len(awsc.ALB.ModifyRuleInputs))
+	assert.Equal(t, *lr.RuleArn, *awsc.ALB.ModifyRuleInputs[0].RuleArn)
+
+	rules := awsc.ALB.ListenerRules[mockListenerArn]
+	assert.Equal(t, []*string{to.Strp("api-target")}, alb.ForwardTargetGroups(rules[0].Actions))
+	assert.Equal(t, []*string{to.Strp("web-green")}, alb.ForwardTargetGroups(rules[1].Actions))
+
+	// A failure forwards the rule to the previous target group again
+	assert.NoError(t, release.RevertListenerRules(awsc.ALB))
+	assert.Equal(t, 2, len(awsc.ALB.ModifyRuleInputs))
+	assert.Equal(t, []*string{to.Strp("web-blue")}, alb.ForwardTargetGroups(rules[1].Actions))
+
+	// Already reverted
+	assert.NoError(t, release.RevertListenerRules(awsc.ALB))
+	assert.Equal(t, 2, len(awsc.ALB.ModifyRuleInputs))
+
+	// The controlled values are wiped for the next release
+	release.WipeControlledValues()
+	assert.Nil(t, lr.RuleArn)
+	assert.Nil(t, lr.PreviousTargetGroupArn)
+	assert.Nil(t, lr.PreviousActions)
+}
+
+func Test_Release_CutoverListenerRules_Alternates(t *testing.T) {
+	release, awsc := mockListenerRuleRelease(t)
+
+	// After a successful cutover to web-green the next release is deployed to web-blue
+	rules := awsc.ALB.ListenerRules[mockListenerArn]
+	rules[1].Actions = alb.ForwardTo(rules[1].Actions, to.Strp("web-green"))
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+
+	lr := release.Services["web"].ListenerRule
+	assert.Equal(t, "web-blue", *lr.TargetGroupArn)
+	assert.Equal(t, "web-green", *lr.PreviousTargetGroupArn)
+	assert.Equal(t, []string{"web-elb-target", "web-blue"}, to.StrSlice(release.Services["web"].targetGroupArns()))
+
+	assert.NoError(t, release.CutoverListenerRules(awsc.ALB))
+	assert.Equal(t, []*string{to.Strp("web-blue")}, alb.ForwardTargetGroups(rules[1].Actions))
+}
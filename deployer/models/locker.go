@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/aws/dynamodb"
+	"github.com/coinbase/step/aws/s3"
+)
+
+//////////
+// Lock Backends
+//////////
+
+// LOCK_BACKENDS are the supported values of a releases lock_backend
+var LOCK_BACKENDS = []string{"dynamodb", "s3"}
+
+// Locker grabs and releases the project config, mutex group and project slot locks of a release.
+// The namespace is the lock table name, lockPath the lock and uuid the release holding it
+type Locker interface {
+	GrabLock(namespace string, lockPath string, uuid string, reason string) (bool, error)
+	ReleaseLock(namespace string, lockPath string, uuid string) error
+}
+
+// S3Locker holds locks as lock files in the release bucket, this is how odin has always held
+// the S3 lock. It reads then writes the file so two releases grabbing at once can both succeed.
+// The namespace is ignored as every lock path is unique in the bucket
+type S3Locker struct {
+	s3c    aws.S3API
+	bucket *string
+}
+
+// NewS3Locker returns a locker using the bucket
+func NewS3Locker(s3c aws.S3API, bucket *string) *S3Locker {
+	return &S3Locker{s3c, bucket}
+}
+
+// GrabLock creates the lock file if it does not exist for another UUID
+func (l *S3Locker) GrabLock(namespace string, lockPath string, uuid string, reason string) (bool, error) {
+	return s3.GrabLock(l.s3c, l.bucket, &lockPath, uuid)
+}
+
+// ReleaseLock deletes the lock file if it is held by the UUID
+func (l *S3Locker) ReleaseLock(namespace string, lockPath string, uuid string) error {
+	return s3.ReleaseLock(l.s3c, l.bucket, &lockPath, uuid)
+}
+
+// DynamoDBLocker holds locks as items in the lock table keyed by the lock path. A conditional PutItem
+// grabs the lock and a conditional DeleteItem releases it, so only one release can ever hold it
+type DynamoDBLocker struct {
+	locker *dynamodb.DynamoDBLocker
+}
+
+// NewDynamoDBLocker returns a locker using the lock table
+func NewDynamoDBLocker(dynamodbc aws.DynamoDBAPI) *DynamoDBLocker {
+	return &DynamoDBLocker{dynamodb.NewDynamoDBLocker(dynamodbc)}
+}
+
+// GrabLock puts the lock item unless it exists for another UUID
+func (l *DynamoDBLocker) GrabLock(namespace string, lockPath string, uuid string, reason string) (bool, error) {
+	return l.locker.GrabLock(namespace, lockPath, uuid, reason)
+}
+
+// ReleaseLock deletes the lock item if it is held by the UUID
+func (l *DynamoDBLocker) ReleaseLock(namespace string, lockPath string, uuid string) error {
+	return l.locker.ReleaseLock(namespace, lockPath, uuid)
+}
+
+// Locker returns the locker for the releases lock_backend, DynamoDB by default
+func (release *Release) Locker(s3c aws.S3API, dynamodbc aws.DynamoDBAPI) Locker {
+	if release.LockBackend != nil && *release.LockBackend == "s3" {
+		return NewS3Locker(s3c, release.Bucket)
+	}
+
+	return NewDynamoDBLocker(dynamodbc)
+}
+
+// ValidateLockBackend validates the lock backend
+func (release *Release) ValidateLockBackend() error {
+	if release.LockBackend == nil {
+		return nil
+	}
+
+	if !containsStr(LOCK_BACKENDS, *release.LockBackend) {
+		return fmt.Errorf("LockBackend is %v but must be in %v", *release.LockBackend, LOCK_BACKENDS)
+	}
+
+	return nil
+}
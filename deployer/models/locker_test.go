@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_Locker(t *testing.T) {
+	release := MockRelease(t)
+	awsc := MockAwsClients(release)
+
+	assert.IsType(t, &DynamoDBLocker{}, release.Locker(awsc.S3, awsc.DynamoDB))
+
+	release.LockBackend = to.Strp("s3")
+	assert.IsType(t, &S3Locker{}, release.Locker(awsc.S3, awsc.DynamoDB))
+
+	release.LockBackend = to.Strp("etcd")
+	assert.Error(t, release.ValidateLockBackend())
+}
+
+func Test_S3Locker_GrabLock(t *testing.T) {
+	release := MockRelease(t)
+	awsc := MockAwsClients(release)
+	locker := NewS3Locker(awsc.S3, release.Bucket)
+
+	grabbed, err := locker.GrabLock("", "lock", "uuid", "")
+	assert.NoError(t, err)
+	assert.True(t, grabbed)
+
+	grabbed, err = locker.GrabLock("", "lock", "other", "")
+	assert.NoError(t, err)
+	assert.False(t, grabbed)
+
+	assert.Error(t, locker.ReleaseLock("", "lock", "other"))
+	assert.NoError(t, locker.ReleaseLock("", "lock", "uuid"))
+}
+
+func Test_DynamoDBLocker_GrabLock(t *testing.T) {
+	release := MockRelease(t)
+	awsc := MockAwsClients(release)
+	locker := NewDynamoDBLocker(awsc.DynamoDB)
+
+	grabbed, err := locker.GrabLock("locks", "lock", "uuid", "")
+	assert.NoError(t, err)
+	assert.True(t, grabbed)
+
+	grabbed, err = locker.GrabLock("locks", "lock", "other", "")
+	assert.NoError(t, err)
+	assert.False(t, grabbed)
+
+	assert.Error(t, locker.ReleaseLock("locks", "lock", "other"))
+	assert.NoError(t, locker.ReleaseLock("locks", "lock", "uuid"))
+	assert.Equal(t, 0, len(awsc.DynamoDB.Locks))
+}
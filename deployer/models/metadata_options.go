@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/coinbase/step/utils/to"
+)
+
+// METADATA_HTTP_TOKENS are the values of http_tokens, required enforces IMDSv2
+var METADATA_HTTP_TOKENS = []string{"optional", "required"}
+
+// METADATA_HTTP_ENDPOINTS are the values of http_endpoint
+var METADATA_HTTP_ENDPOINTS = []string{"enabled", "disabled"}
+
+// InstanceMetadataOptions configures the instance metadata service of each instance
+type InstanceMetadataOptions struct {
+	HttpTokens              *string `json:"http_tokens,omitempty"` // default required
+	HttpPutResponseHopLimit *int64  `json:"http_put_response_hop_limit,omitempty"`
+	HttpEndpoint            *string `json:"http_endpoint,omitempty"`
+}
+
+// ValidateAttributes validates attributes
+func (mo *InstanceMetadataOptions) ValidateAttributes() error {
+	if mo.HttpTokens != nil && !containsStr(METADATA_HTTP_TOKENS, *mo.HttpTokens) {
+		return fmt.Errorf("InstanceMetadataOptions http_tokens must be one of %v", METADATA_HTTP_TOKENS)
+	}
+
+	if mo.HttpEndpoint != nil && !containsStr(METADATA_HTTP_ENDPOINTS, *mo.HttpEndpoint) {
+		return fmt.Errorf("InstanceMetadataOptions http_endpoint must be one of %v", METADATA_HTTP_ENDPOINTS)
+	}
+
+	if hops := mo.HttpPutResponseHopLimit; hops != nil && (*hops < 1 || *hops > 64) {
+		return fmt.Errorf("InstanceMetadataOptions http_put_response_hop_limit must be between 1 and 64")
+	}
+
+	return nil
+}
+
+// validateInstanceMetadataOptions validates the instance_metadata_options
+func (service *Service) validateInstanceMetadataOptions() error {
+	if service.InstanceMetadataOptions == nil {
+		return nil
+	}
+
+	return service.InstanceMetadataOptions.ValidateAttributes()
+}
+
+// metadataOptions are the launch configuration metadata options, instances require IMDSv2
+// tokens unless http_tokens is optional
+func (service *Service) metadataOptions() *autoscaling.InstanceMetadataOptions {
+	mo := service.InstanceMetadataOptions
+	if mo == nil {
+		mo = &InstanceMetadataOptions{}
+	}
+
+	tokens := mo.HttpTokens
+	if tokens == nil {
+		tokens = to.Strp("required")
+	}
+
+	return &autoscaling.InstanceMetadataOptions{
+		HttpTokens:              tokens,
+		HttpPutResponseHopLimit: mo.HttpPutResponseHopLimit,
+		HttpEndpoint:            mo.HttpEndpoint,
+	}
+}
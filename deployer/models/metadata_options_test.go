@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstanceMetadataOptions_ValidateAttributes(t *testing.T) {
+	assert.NoError(t, (&InstanceMetadataOptions{}).ValidateAttributes())
+	assert.NoError(t, (&InstanceMetadataOptions{HttpTokens: to.Strp("optional"), HttpPutResponseHopLimit: to.Int64p(64), HttpEndpoint: to.Strp("enabled")}).ValidateAttributes())
+
+	assert.Error(t, (&InstanceMetadataOptions{HttpTokens: to.Strp("sometimes")}).ValidateAttributes())
+	assert.Error(t, (&InstanceMetadataOptions{HttpEndpoint: to.Strp("on")}).ValidateAttributes())
+	assert.Error(t, (&InstanceMetadataOptions{HttpPutResponseHopLimit: to.Int64p(0)}).ValidateAttributes())
+	assert.Error(t, (&InstanceMetadataOptions{HttpPutResponseHopLimit: to.Int64p(65)}).ValidateAttributes())
+}
+
+func Test_Release_ValidateResources_InstanceMetadataOptions(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	release.Services["web"].InstanceMetadataOptions = &InstanceMetadataOptions{HttpPutResponseHopLimit: to.Int64p(2)}
+	assert.NoError(t, release.ValidateResources(resources))
+
+	release.Services["web"].InstanceMetadataOptions.HttpPutResponseHopLimit = to.Int64p(100)
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "http_put_response_hop_limit")
+}
+
+func Test_Service_InstanceMetadataOptions_LaunchConfiguration(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+
+	// IMDSv2 is required by default
+	options := release.Services["web"].createLaunchConfigurationInput().MetadataOptions
+	assert.Equal(t, "required", *options.HttpTokens)
+	assert.Nil(t, options.HttpPutResponseHopLimit)
+	assert.Nil(t, options.HttpEndpoint)
+
+	release.Services["web"].InstanceMetadataOptions = &InstanceMetadataOptions{
+		HttpTokens:              to.Strp("optional"),
+		HttpPutResponseHopLimit: to.Int64p(3),
+		HttpEndpoint:            to.Strp("enabled"),
+	}
+
+	options = release.Services["web"].createLaunchConfigurationInput().MetadataOptions
+	assert.Equal(t, "optional", *options.HttpTokens)
+	assert.Equal(t, int64(3), *options.HttpPutResponseHopLimit)
+	assert.Equal(t, "enabled", *options.HttpEndpoint)
+}
+
+func Test_Release_CreateResources_InstanceMetadataOptions_LaunchTemplate(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].InstanceTypes = mockInstanceTypes("m5.large", "c5.large")
+	release.Services["web"].InstanceMetadataOptions = &InstanceMetadataOptions{HttpPutResponseHopLimit: to.Int64p(2)}
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.Equal(t, 1, len(awsc.EC2.LaunchTemplateMetadataOptions))
+
+	options := awsc.EC2.LaunchTemplateMetadataOptions[*release.Services["web"].ServiceID()]
+	assert.Equal(t, "required", *options.HttpTokens)
+	assert.Equal(t, int64(2), *options.HttpPutResponseHopLimit)
+}
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/instance"
+	"github.com/coinbase/odin/aws/lt"
+	"github.com/coinbase/odin/aws/subnet"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+// InstanceTypeOverride is an instance type the ASG can launch with a mixed instances policy
+type InstanceTypeOverride struct {
+	InstanceType     *string `json:"instance_type,omitempty"`
+	WeightedCapacity *int64  `json:"weighted_capacity,omitempty"` // Capacity units an instance of this type counts for
+}
+
+// ValidateAttributes validates attributes
+func (o *InstanceTypeOverride) ValidateAttributes() error {
+	if is.EmptyStr(o.InstanceType) {
+		return fmt.Errorf("InstanceTypes instance_type must be defined")
+	}
+
+	// The ASGs capacity would be in capacity units while the deploy counts healthy instances
+	if o.WeightedCapacity != nil && *o.WeightedCapacity != 1 {
+		return fmt.Errorf("InstanceTypes %v weighted_capacity must be 1, other weights are not supported", *o.InstanceType)
+	}
+
+	return nil
+}
+
+// mixedInstances returns true if the ASG is created with a mixed instances policy
+func (service *Service) mixedInstances() bool {
+	return len(service.InstanceTypes) > 0 || service.spot()
+}
+
+// launchTemplate returns true if the ASG launches with a launch template rather than a launch configuration,
+// capacity reservations, host tenancy, network interfaces, private DNS name options and launch template overrides
+// are only supported by launch templates
+func (service *Service) launchTemplate() bool {
+	return service.mixedInstances() || service.CapacityReservation != nil || service.sharedLaunchTemplate() || service.hostTenancy() ||
+		len(service.NetworkInterfaces) > 0 || service.PrivateDnsNameOptions != nil || len(service.LaunchTemplateOverrides) > 0
+}
+
+// validateInstanceTypes validates the mixed instances policy overrides
+func (service *Service) validateInstanceTypes() error {
+	seen := map[string]bool{}
+	weighted := 0
+	for _, o := range service.InstanceTypes {
+		if o == nil {
+			return fmt.Errorf("InstanceTypes cannot contain nil")
+		}
+
+		if err := o.ValidateAttributes(); err != nil {
+			return err
+		}
+
+		if seen[*o.InstanceType] {
+			return fmt.Errorf("InstanceTypes %v must be unique", *o.InstanceType)
+		}
+		seen[*o.InstanceType] = true
+
+		if o.WeightedCapacity != nil {
+			weighted++
+		}
+	}
+
+	if weighted != 0 && weighted != len(service.InstanceTypes) {
+		return fmt.Errorf("InstanceTypes weighted_capacity must be set on all or none of the instance types")
+	}
+
+	return nil
+}
+
+// instanceTypeNames returns the names of all instance types the service launches
+func (service *Service) instanceTypeNames() []*string {
+	if len(service.InstanceTypes) == 0 {
+		return []*string{service.InstanceType}
+	}
+
+	names := []*string{}
+	for _, o := range service.InstanceTypes {
+		names = append(names, o.InstanceType)
+	}
+	return names
+}
+
+// validateInstanceTypeOfferings checks every instance type is offered in the availability zones of the subnets,
+// an instance type not offered where the subnets live would only fail in Deploy
+func (service *Service) validateInstanceTypeOfferings(ec2c aws.EC2API, subnets []*subnet.Subnet) error {
+	azs := []*string{}
+	seen := map[string]bool{}
+	for _, sn := range subnets {
+		if sn.AvailabilityZone == nil || seen[*sn.AvailabilityZone] {
+			continue
+		}
+		seen[*sn.AvailabilityZone] = true
+		azs = append(azs, sn.AvailabilityZone)
+	}
+
+	if len(azs) == 0 {
+		return nil
+	}
+
+	missing, err := instance.MissingOfferings(ec2c, service.instanceTypeNames(), azs)
+	if err != nil {
+		return err
+	}
+
+	field := "InstanceType"
+	if len(service.InstanceTypes) > 0 {
+		field = "InstanceTypes"
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%v %v not offered in %v", service.errorPrefix(), field, strings.Join(missing, ","))
+	}
+
+	return nil
+}
+
+// mixedInstancesPolicy overrides the instance type of the services launch template with InstanceTypes
+// and distributes spot and on demand instances
+func (service *Service) mixedInstancesPolicy() *autoscaling.MixedInstancesPolicy {
+	overrides := []*autoscaling.LaunchTemplateOverrides{}
+	for _, o := range service.InstanceTypes {
+		override := &autoscaling.LaunchTemplateOverrides{InstanceType: o.InstanceType}
+		if o.WeightedCapacity != nil {
+			override.WeightedCapacity = to.Strp(fmt.Sprintf("%v", *o.WeightedCapacity))
+		}
+		overrides = append(overrides, override)
+	}
+
+	policy := &autoscaling.MixedInstancesPolicy{
+		LaunchTemplate: &autoscaling.LaunchTemplate{
+			LaunchTemplateSpecification: service.launchTemplateSpecification(),
+		},
+	}
+
+	if len(overrides) > 0 {
+		policy.LaunchTemplate.Overrides = overrides
+	}
+
+	if service.spot() {
+		policy.InstancesDistribution = service.instancesDistribution()
+	}
+
+	return policy
+}
+
+// createLaunchTemplate creates a launch template with the values of the services launch configuration
+func (service *Service) createLaunchTemplate(ec2c aws.EC2API) error {
+	input := lt.FromLaunchConfig(service.createLaunchConfigurationInput().CreateLaunchConfigurationInput)
+	input.LaunchTemplateName = service.launchTemplateName()
+	input.SetPlacementGroup(service.PlacementGroupName)
+	input.SetCapacityReservation(service.capacityReservationSpecification())
+	input.SetHostResourceGroup(service.HostResourceGroupArn)
+	input.SetNetworkInterfaces(service.networkInterfaceSpecifications())
+	service.setPrivateDnsNameOptions(input)
+
+	// First class fields take precedence over the overrides
+	if err := input.SetOverrides(service.LaunchTemplateOverrides); err != nil {
+		return err
+	}
+
+	for key, value := range service.tags() {
+		input.AddTag(key, value)
+	}
+
+	input.AddTag("ProjectName", service.ProjectName())
+	input.AddTag("ConfigName", service.ConfigName())
+	input.AddTag("ServiceName", service.ServiceName)
+
+	// A shared launch template outlives the release, its versions are pruned by CleanUpSuccess
+	if service.sharedLaunchTemplate() {
+		return service.createLaunchTemplateVersion(ec2c, input)
+	}
+
+	// Tagged so a failed release can find it if its ASG was never created
+	input.AddTag("ReleaseID", service.ReleaseID())
+	input.AddTag("ReleaseUUID", service.ReleaseUUID())
+
+	if err := input.Create(ec2c); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockInstanceTypes(types ...string) []*InstanceTypeOverride {
+	overrides := []*InstanceTypeOverride{}
+	for _, it := range types {
+		overrides = append(overrides, &InstanceTypeOverride{InstanceType: to.Strp(it)})
+	}
+	return overrides
+}
+
+func Test_Service_ValidateInstanceTypes(t *testing.T) {
+	service := &Service{InstanceTypes: mockInstanceTypes("m5.large", "c5.large")}
+	assert.NoError(t, service.validateInstanceTypes())
+
+	service.InstanceTypes[0].WeightedCapacity = to.Int64p(1)
+	assert.Error(t, service.validateInstanceTypes())
+
+	service.InstanceTypes[1].WeightedCapacity = to.Int64p(1)
+	assert.NoError(t, service.validateInstanceTypes())
+
+	// Weights are capacity units, but the deploy counts healthy instances
+	service.InstanceTypes[1].WeightedCapacity = to.Int64p(2)
+	assert.Error(t, service.validateInstanceTypes())
+
+	service.InstanceTypes[1].WeightedCapacity = to.Int64p(0)
+	assert.Error(t, service.validateInstanceTypes())
+
+	service = &Service{InstanceTypes: mockInstanceTypes("m5.large", "m5.large")}
+	assert.Error(t, service.validateInstanceTypes())
+
+	service = &Service{InstanceTypes: mockInstanceTypes("")}
+	assert.Error(t, service.validateInstanceTypes())
+}
+
+func Test_Release_CreateResources_MixedInstances(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].InstanceTypes = mockInstanceTypes("m5.large", "c5.large")
+	release.Services["web"].InstanceTypes[0].WeightedCapacity = to.Int64p(1)
+	release.Services["web"].InstanceTypes[1].WeightedCapacity = to.Int64p(1)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.Equal(t, 1, len(awsc.EC2.CreateLaunchTemplateInputs))
+
+	service := release.Services["web"]
+	assert.Equal(t, *service.ServiceID(), *awsc.EC2.CreateLaunchTemplateInputs[0].LaunchTemplateName)
+
+	input := service.createInput()
+	assert.Nil(t, input.LaunchConfigurationName)
+
+	lt := input.MixedInstancesPolicy.LaunchTemplate
+	assert.Equal(t, *service.ServiceID(), *lt.LaunchTemplateSpecification.LaunchTemplateName)
+	assert.Equal(t, 2, len(lt.Overrides))
+	assert.Equal(t, "m5.large", *lt.Overrides[0].InstanceType)
+	assert.Equal(t, "1", *lt.Overrides[0].WeightedCapacity)
+}
+
+func Test_Release_FetchResources_MixedInstances_NotOffered(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].InstanceTypes = mockInstanceTypes("m5.large", "p3.16xlarge")
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	awsc.EC2.UnofferedInstanceTypes = []string{"p3.16xlarge"}
+
+	_, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "p3.16xlarge/us-east-1a")
+}
+
+func Test_Release_FetchResources_InstanceType_NotOffered(t *testing.T) {
+	release := MockRelease(t)
+	release.Subnets = []*string{to.Strp("subnet-1"), to.Strp("subnet-2")}
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	awsc.EC2.AddSubnetInAZ("private-subnet", "subnet-2", "us-east-1b")
+
+	// Offered in one of the subnets availability zones is not enough
+	awsc.EC2.InstanceTypeOfferings = map[string][]string{"t2.small": []string{"us-east-1a"}}
+
+	_, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "InstanceType not offered in t2.small/us-east-1b")
+
+	awsc.EC2.InstanceTypeOfferings["t2.small"] = []string{"us-east-1a", "us-east-1b"}
+	_, err = release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+}
+
+func Test_Service_CreateInput_SingleInstanceType(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+
+	input := release.Services["web"].createInput()
+	assert.Nil(t, input.MixedInstancesPolicy)
+	assert.Equal(t, *release.Services["web"].ServiceID(), *input.LaunchConfigurationName)
+}
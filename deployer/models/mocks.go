@@ -23,7 +23,17 @@ func MockPrepareRelease(release *Release) {
 		release.SetUserData(to.Strp("#cloud_config"))
 	}
 
-	release.UserDataSHA256 = to.Strp(to.SHA256Str(release.UserData()))
+	release.UserDataSHA256 = mockUserDataSHA256(release)
+}
+
+// mockUserDataSHA256 is the SHA of the rendered user data, or of the template if it does not render
+func mockUserDataSHA256(release *Release) *string {
+	rendered, err := release.RenderUserData(release.UserData())
+	if err != nil {
+		return to.Strp(to.SHA256Str(release.UserData()))
+	}
+
+	return to.Strp(to.SHA256Str(rendered))
 }
 
 // MockAwsClients mocks
@@ -65,7 +75,7 @@ func AddReleaseS3Objects(awsc *mocks.MockClients, release *Release) {
 	}
 
 	awsc.S3.AddGetObject(*release.UserDataPath(), *release.UserData(), nil)
-	release.UserDataSHA256 = to.Strp(to.SHA256Str(release.UserData()))
+	release.UserDataSHA256 = mockUserDataSHA256(release)
 
 	raw, _ := json.Marshal(release)
 	awsc.S3.AddGetObject(*release.ReleasePath(), string(raw), nil)
@@ -172,3 +182,25 @@ func MockRelease(t *testing.T) *Release {
 
 	return &r
 }
+
+// MockCanaryRelease mocks a release with a canary on the web service
+func MockCanaryRelease(t *testing.T) *Release {
+	r := MockRelease(t)
+	r.Services["web"].Canary = &CanaryConfig{
+		Percentage:   to.Intp(50),
+		BakeDuration: to.Intp(0),
+	}
+
+	return r
+}
+
+// MockBlockDevicesRelease returns a release whose web service attaches extra EBS volumes
+func MockBlockDevicesRelease(t *testing.T) *Release {
+	r := MockRelease(t)
+	r.Services["web"].BlockDevices = []*BlockDevice{
+		&BlockDevice{DeviceName: to.Strp("/dev/sdf"), VolumeSize: to.Int64p(500), VolumeType: to.Strp("gp3"), Encrypted: to.Boolp(true)},
+		&BlockDevice{DeviceName: to.Strp("/dev/sdg"), VolumeSize: to.Int64p(100), VolumeType: to.Strp("io2"), IOPS: to.Int64p(3000), DeleteOnTermination: to.Boolp(false)},
+	}
+
+	return r
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/errors"
+)
+
+//////////
+// Mutex Group
+//////////
+
+var mutexGroupRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// ValidateMutexGroup validates the MutexGroup
+func (release *Release) ValidateMutexGroup() error {
+	if release.MutexGroup == nil {
+		return nil
+	}
+
+	if !mutexGroupRegex.MatchString(*release.MutexGroup) {
+		return fmt.Errorf("MutexGroup must match %v", mutexGroupRegex.String())
+	}
+
+	return nil
+}
+
+// MutexLockPath is the lock shared by every project config in the account with the same MutexGroup
+func (release *Release) MutexLockPath() *string {
+	if release.MutexGroup == nil {
+		return nil
+	}
+
+	s := fmt.Sprintf("%v/_mutex_groups/%v/lock", *release.AwsAccountID, *release.MutexGroup)
+	return &s
+}
+
+// grabConfigLocks grabs the project config locks then the MutexGroup lock. If the MutexGroup
+// lock is held by another release the project config lock is released before returning
+func (release *Release) grabConfigLocks(s3c aws.S3API, locker Locker, lockTableName string) error {
+	if err := release.Release.GrabLocks(s3c, locker, lockTableName); err != nil {
+		return err
+	}
+
+	if release.MutexGroup == nil {
+		return nil
+	}
+
+	grabbed, err := locker.GrabLock(lockTableName, *release.MutexLockPath(), *release.UUID, "")
+
+	// Check grabbed first because there are errors that can be thrown before anything is created
+	if !grabbed {
+		if unlockErr := release.Release.UnlockRoot(s3c, locker, lockTableName); unlockErr != nil {
+			return &errors.LockError{unlockErr.Error()}
+		}
+
+		if err != nil {
+			return &errors.LockExistsError{err.Error()}
+		}
+
+		return &errors.LockExistsError{fmt.Sprintf("MutexGroup %v Lock Already Exists at %v:%v", *release.MutexGroup, lockTableName, *release.MutexLockPath())}
+	}
+
+	// Error if MAYBE grabbed the lock, ReleaseLockFailure will try to unlock
+	if err != nil {
+		return &errors.LockError{err.Error()}
+	}
+
+	return nil
+}
+
+// unlockConfigLocks releases the project config lock and the MutexGroup lock
+func (release *Release) unlockConfigLocks(s3c aws.S3API, locker Locker, lockTableName string) error {
+	if err := release.Release.UnlockRoot(s3c, locker, lockTableName); err != nil {
+		return err
+	}
+
+	if release.MutexGroup == nil {
+		return nil
+	}
+
+	return locker.ReleaseLock(lockTableName, *release.MutexLockPath(), *release.UUID)
+}
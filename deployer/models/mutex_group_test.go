@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidateMutexGroup(t *testing.T) {
+	release := MockRelease(t)
+	assert.NoError(t, release.ValidateMutexGroup())
+	assert.Nil(t, release.MutexLockPath())
+
+	release.MutexGroup = to.Strp("shared-tg.v1")
+	assert.NoError(t, release.ValidateMutexGroup())
+	assert.Equal(t, "000000/_mutex_groups/shared-tg.v1/lock", *release.MutexLockPath())
+
+	for _, group := range []string{"", "a/b", "a b"} {
+		release.MutexGroup = to.Strp(group)
+		assert.Error(t, release.ValidateMutexGroup(), group)
+	}
+}
+
+func Test_Release_GrabLocks_MutexGroup(t *testing.T) {
+	release := MockRelease(t)
+	release.MutexGroup = to.Strp("shared-tg")
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	locker := release.Locker(awsc.S3, awsc.DynamoDB)
+	assert.NoError(t, release.GrabLocks(awsc.S3, locker, "locks"))
+	assert.Equal(t, *release.UUID, awsc.DynamoDB.Locks[*release.MutexLockPath()])
+
+	// Another config in the same mutex group cannot grab it
+	other := MockRelease(t)
+	other.ConfigName = to.Strp("other")
+	other.MutexGroup = to.Strp("shared-tg")
+	MockPrepareRelease(other)
+
+	assert.Error(t, other.GrabLocks(awsc.S3, locker, "locks"))
+	_, held := awsc.DynamoDB.Locks[*other.RootLockPath()]
+	assert.False(t, held)
+
+	// A release with another UUID cannot release the locks
+	stolen := MockRelease(t)
+	stolen.MutexGroup = to.Strp("shared-tg")
+	MockPrepareRelease(stolen)
+	stolen.UUID = to.Strp("stolen")
+
+	assert.Error(t, stolen.UnlockRoot(awsc.S3, locker, "locks"))
+	assert.Equal(t, *release.UUID, awsc.DynamoDB.Locks[*release.RootLockPath()])
+	assert.Equal(t, *release.UUID, awsc.DynamoDB.Locks[*release.MutexLockPath()])
+
+	assert.NoError(t, release.UnlockRoot(awsc.S3, locker, "locks"))
+	assert.Equal(t, 0, len(awsc.DynamoDB.Locks))
+}
@@ -0,0 +1,239 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/sg"
+	"github.com/coinbase/odin/aws/subnet"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Network Interfaces
+//////////
+
+// maxNetworkInterfaces is the most network interfaces odin attaches to an instance,
+// smaller instance types support fewer
+const maxNetworkInterfaces = 8
+
+// NetworkInterface is a network interface of each instance. Device index 0 is the primary interface,
+// it is launched in the release subnets with the services security groups
+type NetworkInterface struct {
+	DeviceIndex *int64 `json:"device_index,omitempty"`
+
+	// Subnet is the name tag or ID of the subnet of a secondary interface
+	Subnet *string `json:"subnet,omitempty"`
+
+	// SecurityGroups are the name tags of the security groups of a secondary interface
+	SecurityGroups []*string `json:"security_groups,omitempty"`
+
+	AssociatePublicIpAddress *bool `json:"associate_public_ip_address,omitempty"`
+}
+
+// NetworkInterfaceResources are the found subnet and security groups of a secondary network interface
+type NetworkInterfaceResources struct {
+	DeviceIndex    *int64
+	Subnet         *subnet.Subnet
+	SecurityGroups []*sg.SecurityGroup
+}
+
+// NetworkInterfaceNames are the IDs of the subnet and security groups of a secondary network interface
+type NetworkInterfaceNames struct {
+	DeviceIndex    *int64    `json:"device_index,omitempty"`
+	Subnet         *string   `json:"subnet,omitempty"`
+	SecurityGroups []*string `json:"security_groups,omitempty"`
+}
+
+// secondaryNetworkInterfaces returns the network interfaces other than device index 0
+func (service *Service) secondaryNetworkInterfaces() []*NetworkInterface {
+	secondary := []*NetworkInterface{}
+	for _, ni := range service.NetworkInterfaces {
+		if ni != nil && ni.DeviceIndex != nil && *ni.DeviceIndex != 0 {
+			secondary = append(secondary, ni)
+		}
+	}
+	return secondary
+}
+
+// associatePublicIPAddress returns if the primary network interface is assigned a public IP
+func (service *Service) associatePublicIPAddress() *bool {
+	for _, ni := range service.NetworkInterfaces {
+		if ni != nil && ni.DeviceIndex != nil && *ni.DeviceIndex == 0 && ni.AssociatePublicIpAddress != nil {
+			return ni.AssociatePublicIpAddress
+		}
+	}
+	return service.AssociatePublicIpAddress
+}
+
+// validateNetworkInterfaces validates the network_interfaces
+func (service *Service) validateNetworkInterfaces() error {
+	if len(service.NetworkInterfaces) == 0 {
+		return nil
+	}
+
+	if len(service.NetworkInterfaces) > maxNetworkInterfaces {
+		return fmt.Errorf("NetworkInterfaces can have at most %v interfaces", maxNetworkInterfaces)
+	}
+
+	seen := map[int64]bool{}
+	for _, ni := range service.NetworkInterfaces {
+		if ni == nil {
+			return fmt.Errorf("NetworkInterfaces cannot contain nil")
+		}
+
+		if ni.DeviceIndex == nil || *ni.DeviceIndex < 0 {
+			return fmt.Errorf("NetworkInterfaces device_index must be defined and not negative")
+		}
+
+		index := *ni.DeviceIndex
+		if seen[index] {
+			return fmt.Errorf("NetworkInterfaces device_index %v must be unique", index)
+		}
+		seen[index] = true
+
+		if index == 0 {
+			if err := service.validatePrimaryNetworkInterface(ni); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if is.EmptyStr(ni.Subnet) {
+			return fmt.Errorf("NetworkInterfaces device_index %v must define a subnet", index)
+		}
+
+		if len(ni.SecurityGroups) == 0 {
+			return fmt.Errorf("NetworkInterfaces device_index %v must define security_groups", index)
+		}
+
+		// EC2 only assigns a public IP to the primary interface of an instance with one interface
+		if ni.AssociatePublicIpAddress != nil {
+			return fmt.Errorf("NetworkInterfaces device_index %v cannot set associate_public_ip_address", index)
+		}
+	}
+
+	public := service.associatePublicIPAddress()
+	if len(service.secondaryNetworkInterfaces()) > 0 && public != nil && *public {
+		return fmt.Errorf("NetworkInterfaces associate_public_ip_address cannot be true with more than one network interface")
+	}
+
+	return nil
+}
+
+// validatePrimaryNetworkInterface errors if the device index 0 interface conflicts with the service
+func (service *Service) validatePrimaryNetworkInterface(ni *NetworkInterface) error {
+	if ni.Subnet != nil {
+		return fmt.Errorf("NetworkInterfaces device_index 0 cannot define a subnet, it is launched in the release subnets")
+	}
+
+	if len(ni.SecurityGroups) > 0 {
+		return fmt.Errorf("NetworkInterfaces device_index 0 cannot define security_groups, it uses the services security_groups")
+	}
+
+	if ni.AssociatePublicIpAddress != nil && service.AssociatePublicIpAddress != nil {
+		return fmt.Errorf("NetworkInterfaces device_index 0 and the service cannot both define associate_public_ip_address")
+	}
+
+	return nil
+}
+
+// findNetworkInterfaces finds the subnet and security groups of each secondary network interface
+func (service *Service) findNetworkInterfaces(ec2c aws.EC2API) ([]*NetworkInterfaceResources, error) {
+	found := []*NetworkInterfaceResources{}
+	for _, ni := range service.secondaryNetworkInterfaces() {
+		subnets, err := subnet.Find(ec2c, []*string{ni.Subnet})
+		if err != nil {
+			return nil, fmt.Errorf("NetworkInterfaces device_index %v subnet %v: %v", *ni.DeviceIndex, *ni.Subnet, err.Error())
+		}
+
+		sgs, err := sg.Find(ec2c, ni.SecurityGroups)
+		if err != nil {
+			return nil, err
+		}
+
+		found = append(found, &NetworkInterfaceResources{
+			DeviceIndex:    ni.DeviceIndex,
+			Subnet:         subnets[0],
+			SecurityGroups: sgs,
+		})
+	}
+
+	return found, nil
+}
+
+// validateNetworkInterfaces errors if a secondary interfaces subnet or security groups cannot be used by the service.
+// An instance and its network interfaces must be in one availability zone, so every release subnet must be in it
+func (sr *ServiceResources) validateNetworkInterfaces(service *Service) error {
+	for _, ni := range sr.NetworkInterfaces {
+		if err := ValidateSubnet(service, ni.Subnet); err != nil {
+			return err
+		}
+
+		for _, group := range ni.SecurityGroups {
+			if err := ValidateSecurityGroup(service, group); err != nil {
+				return err
+			}
+		}
+
+		az := to.Strs(ni.Subnet.AvailabilityZone)
+		for _, sn := range sr.Subnets {
+			if sn != nil && to.Strs(sn.AvailabilityZone) != az {
+				return fmt.Errorf("NetworkInterfaces device_index %v subnet is in %v but subnet %v is in %v", *ni.DeviceIndex, az, to.Strs(sn.SubnetID), to.Strs(sn.AvailabilityZone))
+			}
+		}
+	}
+
+	return nil
+}
+
+// networkInterfaceNames returns the IDs of the subnet and security groups of each secondary network interface
+func (sr *ServiceResources) networkInterfaceNames() []*NetworkInterfaceNames {
+	if len(sr.NetworkInterfaces) == 0 {
+		return nil
+	}
+
+	names := []*NetworkInterfaceNames{}
+	for _, ni := range sr.NetworkInterfaces {
+		sgs := []*string{}
+		for _, group := range ni.SecurityGroups {
+			if group != nil && !is.EmptyStr(group.GroupID) {
+				sgs = append(sgs, group.GroupID)
+			}
+		}
+
+		var subnetID *string
+		if ni.Subnet != nil {
+			subnetID = ni.Subnet.SubnetID
+		}
+
+		names = append(names, &NetworkInterfaceNames{
+			DeviceIndex:    ni.DeviceIndex,
+			Subnet:         subnetID,
+			SecurityGroups: uniqueStrps(sgs),
+		})
+	}
+
+	return names
+}
+
+// networkInterfaceSpecifications returns the secondary network interfaces of the launch template
+func (service *Service) networkInterfaceSpecifications() []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
+	if service.Resources == nil {
+		return nil
+	}
+
+	specs := []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{}
+	for _, ni := range service.Resources.NetworkInterfaces {
+		specs = append(specs, &ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+			DeviceIndex:         ni.DeviceIndex,
+			SubnetId:            ni.Subnet,
+			Groups:              ni.SecurityGroups,
+			DeleteOnTermination: to.Boolp(true),
+		})
+	}
+
+	return specs
+}
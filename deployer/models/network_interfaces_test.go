@@ -0,0 +1,148 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockSecondaryInterface(index int64, subnet string) *NetworkInterface {
+	return &NetworkInterface{
+		DeviceIndex:    to.Int64p(index),
+		Subnet:         to.Strp(subnet),
+		SecurityGroups: []*string{to.Strp("eni-sg")},
+	}
+}
+
+func mockNetworkInterfacesRelease(t *testing.T) (*Release, *mocks.MockClients) {
+	release := MockRelease(t)
+	release.Services["web"].NetworkInterfaces = []*NetworkInterface{
+		&NetworkInterface{DeviceIndex: to.Int64p(0)},
+		mockSecondaryInterface(1, "subnet-2"),
+	}
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	awsc.EC2.AddSubnetInAZ("eni-subnet", "subnet-2", "us-east-1a")
+	awsc.EC2.AddSecurityGroup("eni-sg", *release.ProjectName, *release.ConfigName, "web", nil)
+	return release, awsc
+}
+
+func Test_Service_validateNetworkInterfaces(t *testing.T) {
+	service := &Service{}
+	assert.NoError(t, service.validateNetworkInterfaces())
+	assert.False(t, service.launchTemplate())
+
+	service.NetworkInterfaces = []*NetworkInterface{
+		&NetworkInterface{DeviceIndex: to.Int64p(0)},
+		mockSecondaryInterface(1, "subnet-2"),
+	}
+	assert.NoError(t, service.validateNetworkInterfaces())
+	assert.True(t, service.launchTemplate())
+
+	// The primary interface is launched in the release subnets
+	service.NetworkInterfaces[0].Subnet = to.Strp("subnet-3")
+	err := service.validateNetworkInterfaces()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "device_index 0 cannot define a subnet")
+
+	service.NetworkInterfaces[0].Subnet = nil
+	service.NetworkInterfaces[0].SecurityGroups = []*string{to.Strp("other-sg")}
+	assert.Error(t, service.validateNetworkInterfaces())
+
+	service.NetworkInterfaces[0].SecurityGroups = nil
+	service.NetworkInterfaces[0].AssociatePublicIpAddress = to.Boolp(false)
+	service.AssociatePublicIpAddress = to.Boolp(false)
+	assert.Error(t, service.validateNetworkInterfaces())
+
+	service.AssociatePublicIpAddress = nil
+	assert.NoError(t, service.validateNetworkInterfaces())
+	assert.False(t, *service.associatePublicIPAddress())
+
+	// Instances with more than one interface cannot have a public IP
+	service.NetworkInterfaces[0].AssociatePublicIpAddress = to.Boolp(true)
+	assert.Error(t, service.validateNetworkInterfaces())
+	service.NetworkInterfaces[0].AssociatePublicIpAddress = nil
+
+	service.NetworkInterfaces[1].AssociatePublicIpAddress = to.Boolp(true)
+	assert.Error(t, service.validateNetworkInterfaces())
+	service.NetworkInterfaces[1].AssociatePublicIpAddress = nil
+
+	// Secondary interfaces need a subnet and security groups
+	service.NetworkInterfaces[1].Subnet = nil
+	assert.Error(t, service.validateNetworkInterfaces())
+	service.NetworkInterfaces[1].Subnet = to.Strp("subnet-2")
+
+	service.NetworkInterfaces[1].SecurityGroups = nil
+	assert.Error(t, service.validateNetworkInterfaces())
+	service.NetworkInterfaces[1].SecurityGroups = []*string{to.Strp("eni-sg")}
+
+	service.NetworkInterfaces = append(service.NetworkInterfaces, mockSecondaryInterface(1, "subnet-3"))
+	err = service.validateNetworkInterfaces()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "must be unique")
+
+	service.NetworkInterfaces = []*NetworkInterface{&NetworkInterface{}}
+	assert.Error(t, service.validateNetworkInterfaces())
+
+	service.NetworkInterfaces = []*NetworkInterface{nil}
+	assert.Error(t, service.validateNetworkInterfaces())
+}
+
+func Test_Release_NetworkInterfaces_Two_ENIs(t *testing.T) {
+	release, awsc := mockNetworkInterfacesRelease(t)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+
+	names := release.Services["web"].Resources.NetworkInterfaces
+	assert.Equal(t, 1, len(names))
+	assert.Equal(t, "subnet-2", *names[0].Subnet)
+	assert.Equal(t, []string{"group-id"}, to.StrSlice(names[0].SecurityGroups))
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	service := release.Services["web"]
+	assert.Equal(t, 0, len(awsc.ASG.CreateLaunchConfigurationInputs))
+	assert.Equal(t, 1, len(awsc.EC2.CreateLaunchTemplateInputs))
+	assert.Nil(t, awsc.EC2.CreateLaunchTemplateInputs[0].LaunchTemplateData.SecurityGroupIds)
+
+	interfaces := awsc.EC2.LaunchTemplateNetworkInterfaces[*service.ServiceID()]
+	assert.Equal(t, 2, len(interfaces))
+
+	// The services security groups move to the primary interface
+	assert.Equal(t, int64(0), *interfaces[0].DeviceIndex)
+	assert.Nil(t, interfaces[0].SubnetId)
+	assert.Equal(t, service.Resources.SecurityGroups, interfaces[0].Groups)
+
+	assert.Equal(t, int64(1), *interfaces[1].DeviceIndex)
+	assert.Equal(t, "subnet-2", *interfaces[1].SubnetId)
+	assert.Equal(t, []string{"group-id"}, to.StrSlice(interfaces[1].Groups))
+	assert.True(t, *interfaces[1].DeleteOnTermination)
+}
+
+func Test_Release_NetworkInterfaces_Other_AZ(t *testing.T) {
+	release, awsc := mockNetworkInterfacesRelease(t)
+	awsc.EC2.AddSubnetInAZ("eni-subnet", "subnet-3", "us-east-1b")
+	release.Services["web"].NetworkInterfaces[1].Subnet = to.Strp("subnet-3")
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "device_index 1 subnet is in us-east-1b but subnet subnet-1 is in us-east-1a")
+}
+
+func Test_Release_NetworkInterfaces_Not_Found(t *testing.T) {
+	release, awsc := mockNetworkInterfacesRelease(t)
+	release.Services["web"].NetworkInterfaces[1].Subnet = to.Strp("subnet-9")
+
+	_, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "device_index 1 subnet subnet-9")
+}
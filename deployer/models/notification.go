@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/sns"
+	"github.com/coinbase/step/bifrost"
+)
+
+// Notification events published to the releases NotificationTopicARN
+const (
+	NotifyStarted = "started"
+	NotifyHealthy = "healthy"
+	NotifyFailed  = "failed"
+)
+
+// Notification is the JSON message published at each deploy lifecycle transition
+type Notification struct {
+	Event       string                `json:"event"`
+	ProjectName *string               `json:"project_name,omitempty"`
+	ConfigName  *string               `json:"config_name,omitempty"`
+	ReleaseID   *string               `json:"release_id,omitempty"`
+	ReleaseUUID *string               `json:"release_uuid,omitempty"`
+	Path        []string              `json:"path"`
+	Error       *bifrost.ReleaseError `json:"error,omitempty"`
+
+	// FailedState and ExecutionsURL are only set for failed events
+	FailedState   *string `json:"failed_state,omitempty"`
+	ExecutionsURL *string `json:"executions_url,omitempty"`
+}
+
+// ValidateNotificationTopic checks the topic is in the releases account and region
+func (release *Release) ValidateNotificationTopic() error {
+	if release.NotificationTopicARN == nil {
+		return nil
+	}
+
+	prefix := fmt.Sprintf("arn:aws:sns:%v:%v:", *release.AwsRegion, *release.AwsAccountID)
+	if !strings.HasPrefix(*release.NotificationTopicARN, prefix) || len(*release.NotificationTopicARN) == len(prefix) {
+		return fmt.Errorf("NotificationTopicARN must be an SNS topic in %v", prefix)
+	}
+
+	return nil
+}
+
+// Notify publishes the event with the execution path ending in state
+func (release *Release) Notify(snsc aws.SNSAPI, event string, state string) error {
+	return release.publish(snsc, release.notification(event, state))
+}
+
+// notification returns the event with the execution path ending in state
+func (release *Release) notification(event string, state string) *Notification {
+	return &Notification{
+		Event:       event,
+		ProjectName: release.ProjectName,
+		ConfigName:  release.ConfigName,
+		ReleaseID:   release.ReleaseID,
+		ReleaseUUID: release.UUID,
+		Path:        append(append([]string{}, release.ExecutionPath...), state),
+		Error:       release.Error,
+	}
+}
+
+// publish publishes the notification to the NotificationTopicARN in the AlertFormat
+func (release *Release) publish(snsc aws.SNSAPI, n *Notification) error {
+	if release.NotificationTopicARN == nil || release.AwsRegion == nil || release.AwsAccountID == nil {
+		return nil
+	}
+
+	// Failed releases may not be validated
+	if err := release.ValidateNotificationTopic(); err != nil {
+		return err
+	}
+
+	message, err := release.message(n)
+	if err != nil {
+		return err
+	}
+
+	return sns.Publish(snsc, release.NotificationTopicARN, message)
+}
+
+// RecordPath appends the state to the execution path
+// States that loop, e.g. CheckHealthy, are only recorded the first time to keep the release small
+func (release *Release) RecordPath(state string) {
+	if containsStr(release.ExecutionPath, state) {
+		return
+	}
+
+	release.ExecutionPath = append(release.ExecutionPath, state)
+}
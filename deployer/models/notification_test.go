@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidateNotificationTopic(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	assert.NoError(t, r.ValidateNotificationTopic())
+
+	r.NotificationTopicARN = to.Strp("arn:aws:sns:us-east-1:000000:deploys")
+	assert.NoError(t, r.ValidateNotificationTopic())
+
+	r.NotificationTopicARN = to.Strp("arn:aws:sns:us-east-1:111111:deploys")
+	assert.Error(t, r.ValidateNotificationTopic())
+
+	r.NotificationTopicARN = to.Strp("arn:aws:sns:us-east-1:000000:")
+	assert.Error(t, r.ValidateNotificationTopic())
+}
+
+func Test_Release_Notify(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	awsc := MockAwsClients(r)
+
+	// No topic no notification
+	assert.NoError(t, r.Notify(awsc.SNS, NotifyStarted, "Lock"))
+	assert.Equal(t, 0, len(awsc.SNS.PublishInputs))
+
+	r.NotificationTopicARN = to.Strp("arn:aws:sns:us-east-1:000000:deploys")
+	r.RecordPath("Validate")
+	r.RecordPath("Validate")
+	assert.NoError(t, r.Notify(awsc.SNS, NotifyStarted, "Lock"))
+	assert.Equal(t, 1, len(awsc.SNS.PublishInputs))
+
+	var n Notification
+	assert.NoError(t, json.Unmarshal([]byte(awsc.SNS.Messages()[0]), &n))
+	assert.Equal(t, NotifyStarted, n.Event)
+	assert.Equal(t, []string{"Validate", "Lock"}, n.Path)
+	assert.Equal(t, []string{"Validate"}, r.ExecutionPath)
+
+	// Topics outside the releases account are never published to
+	r.NotificationTopicARN = to.Strp("arn:aws:sns:us-east-1:111111:deploys")
+	assert.Error(t, r.Notify(awsc.SNS, NotifyFailed, "FailureClean"))
+	assert.Equal(t, 1, len(awsc.SNS.PublishInputs))
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+)
+
+//////////
+// Parallel Services
+//////////
+
+// ValidateMaxParallelServices validates MaxParallelServices
+func (release *Release) ValidateMaxParallelServices() error {
+	if release.MaxParallelServices != nil && *release.MaxParallelServices < 1 {
+		return fmt.Errorf("MaxParallelServices must be at least 1")
+	}
+
+	return nil
+}
+
+// parallelServices is the number of services worked on at once, unlimited by default
+func (release *Release) parallelServices() int {
+	if release.MaxParallelServices != nil && *release.MaxParallelServices < len(release.Services) {
+		return *release.MaxParallelServices
+	}
+
+	return len(release.Services)
+}
+
+// forEachService calls fn for every service using a pool of parallelServices workers.
+// Every service is called even if one fails. The returned error is the first HaltError by service name,
+// so a retryable error from one service cannot hide another service halting the release, else the first error
+func (release *Release) forEachService(fn func(*Service) error) error {
+	names := sortedServiceNames(release)
+	errs := make([]error, len(names))
+
+	workers := release.parallelServices()
+	if workers < 1 {
+		return nil
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = fn(release.Services[names[i]])
+			}
+		}()
+	}
+
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var first error
+	for _, err := range errs {
+		if _, ok := err.(*HaltError); ok {
+			return err
+		}
+
+		if first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
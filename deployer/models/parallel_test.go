@@ -0,0 +1,122 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockParallelRelease(t *testing.T, names ...string) *Release {
+	release := MockRelease(t)
+
+	raw, err := json.Marshal(release.Services["web"])
+	assert.NoError(t, err)
+
+	for _, name := range names {
+		var service Service
+		assert.NoError(t, json.Unmarshal(raw, &service))
+		release.Services[name] = &service
+	}
+
+	MockPrepareRelease(release)
+	return release
+}
+
+func Test_Release_ValidateMaxParallelServices(t *testing.T) {
+	release := MockRelease(t)
+	assert.NoError(t, release.ValidateMaxParallelServices())
+
+	release.MaxParallelServices = to.Intp(1)
+	assert.NoError(t, release.ValidateMaxParallelServices())
+
+	release.MaxParallelServices = to.Intp(0)
+	assert.Error(t, release.ValidateMaxParallelServices())
+}
+
+func Test_Release_forEachService_Bounded(t *testing.T) {
+	release := mockParallelRelease(t, "a", "b", "c", "d")
+	release.MaxParallelServices = to.Intp(2)
+
+	var mu sync.Mutex
+	running, peak, called := 0, 0, 0
+
+	err := release.forEachService(func(service *Service) error {
+		mu.Lock()
+		running++
+		called++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, called)
+	assert.Equal(t, 2, peak)
+}
+
+func Test_Release_forEachService_Errors(t *testing.T) {
+	release := mockParallelRelease(t, "a", "b")
+
+	var mu sync.Mutex
+	called := 0
+
+	err := release.forEachService(func(service *Service) error {
+		mu.Lock()
+		called++
+		mu.Unlock()
+
+		if *service.ServiceName == "web" || *service.ServiceName == "b" {
+			return fmt.Errorf("failed %v", *service.ServiceName)
+		}
+		return nil
+	})
+
+	// Every service is still called and the first error by name is returned
+	assert.Equal(t, 3, called)
+	assert.EqualError(t, err, "failed b")
+}
+
+func Test_Release_forEachService_HaltError(t *testing.T) {
+	release := mockParallelRelease(t, "a", "b")
+
+	err := release.forEachService(func(service *Service) error {
+		switch *service.ServiceName {
+		case "a":
+			return fmt.Errorf("throttled a")
+		case "web":
+			return &HaltError{fmt.Errorf("halted web")}
+		}
+		return nil
+	})
+
+	// The HaltError is returned even though a retryable error comes first by name
+	assert.IsType(t, &HaltError{}, err)
+	assert.EqualError(t, err, "halted web")
+}
+
+func Test_Release_CreateResources_Parallel(t *testing.T) {
+	release := mockParallelRelease(t, "api", "worker")
+
+	awsc := MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	for name, service := range release.Services {
+		assert.True(t, strings.HasSuffix(to.Strs(service.CreatedASG), fmt.Sprintf("-%v", name)))
+	}
+
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+}
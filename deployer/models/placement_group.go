@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Placement Group
+//////////
+
+// burstableInstanceType matches the t instance families that cannot launch in a cluster placement group
+var burstableInstanceType = regexp.MustCompile(`^t\d`)
+
+// isClusterPlacementGroup returns true if the service launches into a cluster placement group
+func (service *Service) isClusterPlacementGroup() bool {
+	return service.PlacementGroupName != nil && to.Strs(service.PlacementGroupStrategy) == "cluster"
+}
+
+// validatePlacementGroupInstanceTypes errors if an instance type is not supported by the placement group strategy
+func (service *Service) validatePlacementGroupInstanceTypes() error {
+	if !service.isClusterPlacementGroup() {
+		return nil
+	}
+
+	for _, instanceType := range service.instanceTypeNames() {
+		if burstableInstanceType.MatchString(to.Strs(instanceType)) {
+			return fmt.Errorf("PlacementGroupStrategy 'cluster' does not support the burstable instance type %v", to.Strs(instanceType))
+		}
+	}
+
+	return nil
+}
+
+// validatePlacementGroupZones errors if a cluster placement group would span availability zones
+func (sr *ServiceResources) validatePlacementGroupZones(service *Service) error {
+	if !service.isClusterPlacementGroup() {
+		return nil
+	}
+
+	azs := map[string]bool{}
+	for _, sn := range sr.Subnets {
+		if sn != nil && sn.AvailabilityZone != nil {
+			azs[*sn.AvailabilityZone] = true
+		}
+	}
+
+	if len(azs) > 1 {
+		return fmt.Errorf("PlacementGroupStrategy 'cluster' cannot span availability zones, the subnets are in %v zones", len(azs))
+	}
+
+	return nil
+}
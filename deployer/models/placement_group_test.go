@@ -0,0 +1,99 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockClusterPlacementGroupRelease(t *testing.T, azs ...string) (*Release, *mocks.MockClients) {
+	release := MockRelease(t)
+	release.Subnets = []*string{to.Strp("private-subnet-a"), to.Strp("private-subnet-b")}
+
+	service := release.Services["web"]
+	service.InstanceType = to.Strp("c5n.large") // burstable types cannot launch in a cluster
+	service.PlacementGroupName = to.Strp("odin/project/config/low-latency")
+	service.PlacementGroupStrategy = to.Strp("cluster")
+	if len(azs) > 0 {
+		service.AvailabilityZones = aws.StringSlice(azs)
+	}
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	awsc.EC2.AddPlacementGroup("odin/project/config/low-latency", "cluster")
+	awsc.EC2.DescribeSubnetsResp = nil
+	awsc.EC2.AddSubnetInAZ("private-subnet-a", "subnet-a", "us-east-1a")
+	awsc.EC2.AddSubnetInAZ("private-subnet-b", "subnet-b", "us-east-1b")
+
+	return release, awsc
+}
+
+func Test_Service_validatePlacementGroupInstanceTypes(t *testing.T) {
+	service := &Service{InstanceType: to.Strp("t3.micro")}
+	assert.NoError(t, service.validatePlacementGroupInstanceTypes())
+
+	service.PlacementGroupName = to.Strp("pg")
+	service.PlacementGroupStrategy = to.Strp("spread")
+	assert.NoError(t, service.validatePlacementGroupInstanceTypes())
+
+	service.PlacementGroupStrategy = to.Strp("cluster")
+	assert.Error(t, service.validatePlacementGroupInstanceTypes())
+
+	service.InstanceType = to.Strp("c5n.18xlarge")
+	assert.NoError(t, service.validatePlacementGroupInstanceTypes())
+
+	service.InstanceTypes = mockInstanceTypes("c5.large", "t3a.large")
+	assert.Error(t, service.validatePlacementGroupInstanceTypes())
+}
+
+func Test_Release_PlacementGroup_Cluster_MultipleAZs(t *testing.T) {
+	release, awsc := mockClusterPlacementGroupRelease(t)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot span availability zones")
+}
+
+func Test_Release_PlacementGroup_Cluster_SingleAZ(t *testing.T) {
+	release, awsc := mockClusterPlacementGroupRelease(t, "us-east-1a")
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+
+	// The existing placement group is used
+	assert.Equal(t, 1, len(awsc.EC2.PlacementGroups))
+	assert.Equal(t, "odin/project/config/low-latency", *release.Services["web"].createInput().PlacementGroup)
+}
+
+func Test_Release_PlacementGroup_WrongStrategy(t *testing.T) {
+	release, awsc := mockClusterPlacementGroupRelease(t, "us-east-1a")
+	awsc.EC2.PlacementGroups = nil
+	awsc.EC2.AddPlacementGroup("odin/project/config/low-latency", "spread")
+
+	_, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid strategy")
+}
+
+func Test_Release_PlacementGroup_LaunchTemplate(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(r *Release) {
+		r.Services["web"].InstanceTypes = mockInstanceTypes("c5.large", "c4.large")
+		r.Services["web"].PlacementGroupName = to.Strp("odin/project/config/web")
+		r.Services["web"].PlacementGroupStrategy = to.Strp("partition")
+		r.Services["web"].PlacementGroupPartitionCount = to.Int64p(3)
+	})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	assert.Equal(t, 1, len(awsc.EC2.CreateLaunchTemplateInputs))
+	placement := awsc.EC2.CreateLaunchTemplateInputs[0].LaunchTemplateData.Placement
+	assert.Equal(t, "odin/project/config/web", *placement.GroupName)
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Plan
+//////////
+
+// Plan is what a deploy of a validated release would do, it is returned by a dry run
+type Plan struct {
+	ProjectName *string `json:"project_name,omitempty"`
+	ConfigName  *string `json:"config_name,omitempty"`
+	ReleaseID   *string `json:"release_id,omitempty"`
+
+	// The live ASGs are updated instead of creating new ones
+	InPlace bool `json:"in_place"`
+
+	Services map[string]*ServicePlan `json:"services"`
+
+	// Number of instances launched across all services
+	InstanceCount int64 `json:"instance_count"`
+}
+
+// ServicePlan is what a deploy would do for one service
+type ServicePlan struct {
+	CreateASG   *string `json:"create_asg,omitempty"`   // ASG that would be created
+	UpdateASG   *string `json:"update_asg,omitempty"`   // Live ASG that would be updated in place
+	PreviousASG *string `json:"previous_asg,omitempty"` // ASG that would be replaced
+
+	ELBs         []*string `json:"elbs,omitempty"`
+	TargetGroups []*string `json:"target_group_arns,omitempty"`
+
+	MinSize         *int64 `json:"min_size,omitempty"`
+	MaxSize         *int64 `json:"max_size,omitempty"`
+	DesiredCapacity *int64 `json:"desired_capacity,omitempty"`
+
+	// Number of instances launched including the spread
+	InstanceCount int64 `json:"instance_count"`
+}
+
+// Plan returns what deploying the release would do. The release must have been validated
+// and updated with its resources, nothing is created
+func (release *Release) Plan() *Plan {
+	plan := &Plan{
+		ProjectName: release.ProjectName,
+		ConfigName:  release.ConfigName,
+		ReleaseID:   release.ReleaseID,
+		InPlace:     release.InPlace,
+		Services:    map[string]*ServicePlan{},
+	}
+
+	for name, service := range release.Services {
+		sp := service.plan()
+		plan.Services[name] = sp
+		plan.InstanceCount += sp.InstanceCount
+	}
+
+	return plan
+}
+
+func (service *Service) plan() *ServicePlan {
+	sp := &ServicePlan{
+		MinSize:         service.Autoscaling.MinSize,
+		MaxSize:         service.Autoscaling.MaxSize,
+		DesiredCapacity: to.Int64p(service.strategy.DesiredCapacity()),
+	}
+
+	if service.Resources != nil {
+		sp.ELBs = service.Resources.ELBs
+		sp.TargetGroups = service.targetGroupArns()
+	}
+
+	if service.release.InPlace {
+		// Instances are not replaced by an in place update
+		sp.UpdateASG = service.CreatedASG
+		return sp
+	}
+
+	if service.release.IsInstanceRefresh() {
+		// The instances of the live ASG are replaced in place
+		sp.UpdateASG = service.CreatedASG
+		sp.InstanceCount = service.strategy.DesiredCapacity()
+		return sp
+	}
+
+	sp.CreateASG = service.ServiceID()
+	if service.Resources != nil {
+		sp.PreviousASG = service.Resources.PrevASG
+	}
+	sp.InstanceCount = service.strategy.TargetCapacity()
+
+	return sp
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_Plan_InPlace(t *testing.T) {
+	release, _, resources := mockInPlaceRelease(t, func(r *Release) {
+		r.Services["web"].Tags["custom"] = to.Strp("changed")
+	})
+
+	release.UpdateWithResources(resources)
+	release.SetDefaults()
+
+	plan := release.Plan()
+	assert.True(t, plan.InPlace)
+	assert.Equal(t, int64(0), plan.InstanceCount)
+
+	web := plan.Services["web"]
+	assert.Nil(t, web.CreateASG)
+	assert.Equal(t, "project-config-web-old-release", to.Strs(web.UpdateASG))
+}
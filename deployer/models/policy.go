@@ -12,6 +12,14 @@ import (
 
 const cpuScaleDown = "cpu_scale_down"
 const cpuScaleUp = "cpu_scale_up"
+const targetTracking = "target_tracking"
+
+// TARGET_TRACKING_METRICS are the predefined metrics a target_tracking policy can track
+var TARGET_TRACKING_METRICS = []string{
+	autoscaling.MetricTypeAsgaverageCpuutilization,
+	autoscaling.MetricTypeAsgaverageNetworkIn,
+	autoscaling.MetricTypeAsgaverageNetworkOut,
+}
 
 // Policy struct
 type Policy struct {
@@ -24,6 +32,11 @@ type Policy struct {
 	PeriodVal            *int64   `json:"period,omitempty"`
 	EvaluationPeriodsVal *int64   `json:"evaluation_periods,omitempty"`
 	CooldownVal          *int64   `json:"cooldown,omitempty"`
+
+	// target_tracking keeps Metric at TargetValue, AWS owns its alarms
+	MetricVal      *string  `json:"metric,omitempty"`
+	TargetValueVal *float64 `json:"target_value,omitempty"`
+	DisableScaleIn bool     `json:"disable_scale_in,omitempty"`
 }
 
 func (a *Policy) Name() *string {
@@ -91,8 +104,21 @@ func (a *Policy) Cooldown() *int64 {
 	return to.Int64p(60)
 }
 
+// Metric returns the tracked metric
+func (a *Policy) Metric() *string {
+	if a.MetricVal != nil {
+		return a.MetricVal
+	}
+	return to.Strp(autoscaling.MetricTypeAsgaverageCpuutilization)
+}
+
 // Create attempts to create alarm and policy
 func (a *Policy) Create(asgc aws.ASGAPI, cwc aws.CWAPI, asgName *string) error {
+	if *a.Type == targetTracking {
+		// The alarms are created by AWS
+		_, err := a.createPutScalingPolicyInput(asgName).Create(asgc)
+		return err
+	}
 
 	policyInput := a.createPutScalingPolicyInput(asgName)
 	output, err := policyInput.Create(asgc)
@@ -116,6 +142,10 @@ func (a *Policy) ValidateAttributes() error {
 		return fmt.Errorf("Policy(?): Type nil")
 	}
 
+	if *a.Type == targetTracking {
+		return a.validateTargetTracking()
+	}
+
 	if *a.Type != cpuScaleDown && *a.Type != cpuScaleUp {
 		return fmt.Errorf("Policy(%v): Unsupported Type %v", *a.Name(), *a.Type)
 	}
@@ -131,6 +161,22 @@ func (a *Policy) ValidateAttributes() error {
 	return nil
 }
 
+func (a *Policy) validateTargetTracking() error {
+	if !containsStr(TARGET_TRACKING_METRICS, *a.Metric()) {
+		return fmt.Errorf("Policy(%v): Metric is %v but must be in %v", *a.Name(), *a.Metric(), TARGET_TRACKING_METRICS)
+	}
+
+	if a.TargetValueVal == nil || *a.TargetValueVal <= 0 {
+		return fmt.Errorf("Policy(%v): TargetValue must be greater than 0", *a.Name())
+	}
+
+	if err := a.createPutScalingPolicyInput(to.Strp("asgName")).Validate(); err != nil {
+		return fmt.Errorf("Policy(%v): %v", *a.Name(), err.Error())
+	}
+
+	return nil
+}
+
 // SetDefaults assigns default values
 func (a *Policy) SetDefaults(serviceID *string) error {
 	a.serviceID = serviceID
@@ -169,6 +215,21 @@ func (a *Policy) createMetricAlarmInput(asgName *string, policyARN *string) *ala
 }
 
 func (a *Policy) createPutScalingPolicyInput(asgName *string) *alarms.PolicyInput {
+	if *a.Type == targetTracking {
+		return &alarms.PolicyInput{&autoscaling.PutScalingPolicyInput{
+			AutoScalingGroupName: asgName,
+			PolicyName:           a.Name(),
+			PolicyType:           to.Strp("TargetTrackingScaling"),
+			TargetTrackingConfiguration: &autoscaling.TargetTrackingConfiguration{
+				PredefinedMetricSpecification: &autoscaling.PredefinedMetricSpecification{
+					PredefinedMetricType: a.Metric(),
+				},
+				TargetValue:    a.TargetValueVal,
+				DisableScaleIn: to.Boolp(a.DisableScaleIn),
+			},
+		}}
+	}
+
 	return &alarms.PolicyInput{&autoscaling.PutScalingPolicyInput{
 		AutoScalingGroupName: asgName,
 		PolicyName:           a.Name(),
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/to"
+)
+
+// PolicyDocument is an account level policy that every release must comply with
+// It is maintained separately from releases and uploaded to PolicyDocumentPath
+type PolicyDocument struct {
+	AllowedInstanceTypes []*string `json:"allowed_instance_types,omitempty"`
+	RequiredTags         []*string `json:"required_tags,omitempty"`
+	MaxCapacity          *int64    `json:"max_capacity,omitempty"` // Max size of each service
+	AllowedSubnets       []*string `json:"allowed_subnets,omitempty"`
+}
+
+// policyCheck returns all the violations of one part of the policy
+type policyCheck func(policy *PolicyDocument, release *Release) []string
+
+// policyChecks are evaluated in order, new policies are added here
+var policyChecks = []policyCheck{
+	checkAllowedInstanceTypes,
+	checkRequiredTags,
+	checkMaxCapacity,
+	checkAllowedSubnets,
+}
+
+// PolicyDocumentPath returns the path of the account policy document
+func (release *Release) PolicyDocumentPath() *string {
+	s := fmt.Sprintf("%v/_policy_document", *release.AwsAccountID)
+	return &s
+}
+
+// DeployerPolicyDocumentPath returns the path of the policy document every release of the deployer must comply with
+func (release *Release) DeployerPolicyDocumentPath() *string {
+	return to.Strp("_policy_document")
+}
+
+// ValidatePolicyDocument downloads the deployer and account policy documents and evaluates the release against them
+// If no policy has been uploaded the release is valid
+func (release *Release) ValidatePolicyDocument(s3c aws.S3API) error {
+	for _, path := range []*string{release.DeployerPolicyDocumentPath(), release.PolicyDocumentPath()} {
+		var policy PolicyDocument
+		if err := s3.GetStruct(s3c, release.Bucket, path, &policy); err != nil {
+			switch err.(type) {
+			case *s3.NotFoundError:
+				continue
+			default:
+				return fmt.Errorf("Error Getting PolicyDocument with %v", err.Error())
+			}
+		}
+
+		if err := policy.Evaluate(release); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Evaluate returns a single error with all policy violations
+func (policy *PolicyDocument) Evaluate(release *Release) error {
+	violations := []string{}
+	for _, check := range policyChecks {
+		violations = append(violations, check(policy, release)...)
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("PolicyDocument violations: %v", strings.Join(violations, ", "))
+}
+
+// sortedServiceNames makes violations deterministic
+func sortedServiceNames(release *Release) []string {
+	names := []string{}
+	for name, service := range release.Services {
+		if service == nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func checkAllowedInstanceTypes(policy *PolicyDocument, release *Release) []string {
+	if len(policy.AllowedInstanceTypes) == 0 {
+		return nil
+	}
+
+	violations := []string{}
+	for _, name := range sortedServiceNames(release) {
+		service := release.Services[name]
+		instanceTypes := []*string{service.InstanceType}
+		if service.mixedInstances() {
+			instanceTypes = append(instanceTypes, service.instanceTypeNames()...)
+		}
+
+		for _, instanceTypep := range instanceTypes {
+			instanceType := to.Strs(instanceTypep)
+			if !containsStrp(policy.AllowedInstanceTypes, instanceType) {
+				violations = append(violations, fmt.Sprintf("Service(%v) instance type %q not allowed", name, instanceType))
+			}
+		}
+	}
+
+	return violations
+}
+
+// checkRequiredTags reports each required tag missing from both the release and the service tags
+func checkRequiredTags(policy *PolicyDocument, release *Release) []string {
+	violations := []string{}
+	for _, name := range sortedServiceNames(release) {
+		tags := release.Services[name].tags()
+		for _, tag := range policy.RequiredTags {
+			if tag == nil {
+				continue
+			}
+
+			if value, ok := tags[*tag]; !ok || value == nil {
+				violations = append(violations, fmt.Sprintf("Service(%v) missing required tag %q", name, *tag))
+			}
+		}
+	}
+
+	return violations
+}
+
+func checkMaxCapacity(policy *PolicyDocument, release *Release) []string {
+	if policy.MaxCapacity == nil {
+		return nil
+	}
+
+	violations := []string{}
+	for _, name := range sortedServiceNames(release) {
+		as := release.Services[name].Autoscaling
+		if as == nil || as.MaxSize == nil {
+			continue
+		}
+
+		if *as.MaxSize > *policy.MaxCapacity {
+			violations = append(violations, fmt.Sprintf("Service(%v) max size %v greater than max capacity %v", name, *as.MaxSize, *policy.MaxCapacity))
+		}
+	}
+
+	return violations
+}
+
+func checkAllowedSubnets(policy *PolicyDocument, release *Release) []string {
+	if len(policy.AllowedSubnets) == 0 {
+		return nil
+	}
+
+	violations := []string{}
+	for _, subnet := range release.Subnets {
+		if !containsStrp(policy.AllowedSubnets, to.Strs(subnet)) {
+			violations = append(violations, fmt.Sprintf("subnet %q not allowed", to.Strs(subnet)))
+		}
+	}
+
+	return violations
+}
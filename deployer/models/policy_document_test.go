@@ -0,0 +1,119 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PolicyDocument_Evaluate_Works(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+
+	policy := &PolicyDocument{
+		AllowedInstanceTypes: []*string{to.Strp("t2.small")},
+		RequiredTags:         []*string{to.Strp("custom")},
+		MaxCapacity:          to.Int64p(10),
+		AllowedSubnets:       []*string{to.Strp("private-subnet")},
+	}
+
+	assert.NoError(t, policy.Evaluate(r))
+}
+
+func Test_PolicyDocument_Evaluate_AggregatesViolations(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	r.Services["web"].Autoscaling.MaxSize = to.Int64p(100)
+
+	policy := &PolicyDocument{
+		AllowedInstanceTypes: []*string{to.Strp("m5.large")},
+		RequiredTags:         []*string{to.Strp("team")},
+		MaxCapacity:          to.Int64p(10),
+		AllowedSubnets:       []*string{to.Strp("public-subnet")},
+	}
+
+	err := policy.Evaluate(r)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `Service(web) instance type "t2.small" not allowed`)
+	assert.Contains(t, err.Error(), `Service(web) missing required tag "team"`)
+	assert.Contains(t, err.Error(), "Service(web) max size 100 greater than max capacity 10")
+	assert.Contains(t, err.Error(), `subnet "private-subnet" not allowed`)
+}
+
+func Test_PolicyDocument_Evaluate_MixedInstanceTypes(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].InstanceTypes = mockInstanceTypes("t2.small", "m5.large")
+	MockPrepareRelease(r)
+
+	policy := &PolicyDocument{
+		AllowedInstanceTypes: []*string{to.Strp("t2.small")},
+	}
+
+	err := policy.Evaluate(r)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `Service(web) instance type "m5.large" not allowed`)
+}
+
+func Test_Release_Validate_PolicyDocument(t *testing.T) {
+	r := MockRelease(t)
+	awsc := MockAwsClients(r)
+	r.ReleaseSHA256 = to.SHA256Struct(r)
+	MockPrepareRelease(r)
+
+	// Without a policy document the release is valid
+	assert.NoError(t, r.Validate(awsc.S3, awsc.KMS))
+
+	assert.NoError(t, s3.PutStruct(awsc.S3, r.Bucket, r.PolicyDocumentPath(), &PolicyDocument{
+		AllowedInstanceTypes: []*string{to.Strp("m5.large")},
+		RequiredTags:         []*string{to.Strp("team")},
+	}))
+
+	err := r.Validate(awsc.S3, awsc.KMS)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "instance type")
+	assert.Contains(t, err.Error(), "required tag")
+}
+
+func Test_PolicyDocument_Evaluate_RequiredTags_ReleaseTags(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+
+	policy := &PolicyDocument{
+		RequiredTags: []*string{to.Strp("CostCenter"), to.Strp("Team")},
+	}
+
+	err := policy.Evaluate(r)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `Service(web) missing required tag "CostCenter"`)
+	assert.Contains(t, err.Error(), `Service(web) missing required tag "Team"`)
+
+	// Release tags are on every service
+	r.Tags = map[string]*string{"CostCenter": to.Strp("1234")}
+	err = policy.Evaluate(r)
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "CostCenter")
+	assert.Contains(t, err.Error(), `Service(web) missing required tag "Team"`)
+
+	r.Services["web"].Tags["Team"] = to.Strp("payments")
+	assert.NoError(t, policy.Evaluate(r))
+}
+
+func Test_Release_Validate_DeployerPolicyDocument(t *testing.T) {
+	r := MockRelease(t)
+	awsc := MockAwsClients(r)
+	r.ReleaseSHA256 = to.SHA256Struct(r)
+	MockPrepareRelease(r)
+
+	assert.NoError(t, s3.PutStruct(awsc.S3, r.Bucket, r.DeployerPolicyDocumentPath(), &PolicyDocument{
+		RequiredTags: []*string{to.Strp("CostCenter")},
+	}))
+
+	err := r.Validate(awsc.S3, awsc.KMS)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `missing required tag "CostCenter"`)
+
+	r.Tags = map[string]*string{"CostCenter": to.Strp("1234")}
+	assert.NoError(t, r.Validate(awsc.S3, awsc.KMS))
+}
@@ -29,3 +29,45 @@ func Test_Policy_Name_Prefix(t *testing.T) {
 	pol.NameVal = to.Strp("boom")
 	assert.Equal(t, *pol.Name(), "service_id-cpu_scale_down-boom")
 }
+
+func Test_Policy_TargetTracking(t *testing.T) {
+	pol := &Policy{
+		Type:           to.Strp("target_tracking"),
+		TargetValueVal: to.Float64p(60),
+	}
+
+	pol.SetDefaults(to.Strp("service_id"))
+	assert.NoError(t, pol.ValidateAttributes())
+
+	input := pol.createPutScalingPolicyInput(to.Strp("asg"))
+	assert.Equal(t, "TargetTrackingScaling", *input.PolicyType)
+	assert.Equal(t, "ASGAverageCPUUtilization", *input.TargetTrackingConfiguration.PredefinedMetricSpecification.PredefinedMetricType)
+	assert.Equal(t, 60.0, *input.TargetTrackingConfiguration.TargetValue)
+
+	pol.MetricVal = to.Strp("ALBRequestCountPerTarget")
+	assert.Error(t, pol.ValidateAttributes())
+
+	pol.MetricVal = nil
+	pol.TargetValueVal = nil
+	assert.Error(t, pol.ValidateAttributes())
+}
+
+func Test_Policy_TargetTracking_Create(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].Autoscaling.Policies = []*Policy{
+		&Policy{Type: to.Strp("target_tracking"), TargetValueVal: to.Float64p(60)},
+	}
+	MockPrepareRelease(release)
+	assert.NoError(t, release.Services["web"].Validate())
+
+	awsc := MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	assert.Equal(t, 1, len(awsc.ASG.PutScalingPolicyInputs))
+	input := awsc.ASG.PutScalingPolicyInputs[0]
+	assert.Equal(t, *release.Services["web"].CreatedASG, *input.AutoScalingGroupName)
+	assert.Equal(t, 60.0, *input.TargetTrackingConfiguration.TargetValue)
+
+	// AWS creates the target tracking alarms
+	assert.Equal(t, 0, len(awsc.CW.PutMetricAlarmInputs))
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/lambda"
+	"github.com/coinbase/step/utils/is"
+)
+
+//////////
+// Pre Deploy Hook
+//////////
+
+// PreDeployHookInput is the release metadata the PreDeployHook Lambda is invoked with
+type PreDeployHookInput struct {
+	ProjectName  *string  `json:"project_name,omitempty"`
+	ConfigName   *string  `json:"config_name,omitempty"`
+	ReleaseID    *string  `json:"release_id,omitempty"`
+	ReleaseUUID  *string  `json:"release_uuid,omitempty"`
+	AwsAccountID *string  `json:"aws_account_id,omitempty"`
+	AwsRegion    *string  `json:"aws_region,omitempty"`
+	Image        *string  `json:"ami,omitempty"`
+	Services     []string `json:"services"`
+}
+
+// PreDeployHookResponse is the PreDeployHook Lambdas response, the release is only deployed if allow is true
+type PreDeployHookResponse struct {
+	Allow   bool    `json:"allow"`
+	Message *string `json:"message,omitempty"`
+}
+
+// ValidatePreDeployHook checks the hook is a Lambda function ARN
+func (release *Release) ValidatePreDeployHook() error {
+	if release.PreDeployHook == nil {
+		return nil
+	}
+
+	if is.EmptyStr(release.PreDeployHook) || !strings.HasPrefix(*release.PreDeployHook, "arn:aws:lambda:") || !strings.Contains(*release.PreDeployHook, ":function:") {
+		return fmt.Errorf("PreDeployHook must be a Lambda function ARN")
+	}
+
+	return nil
+}
+
+// RunPreDeployHook invokes the PreDeployHook with the release metadata,
+// an error, a non 2xx response or a response that does not allow the release stops the deploy
+func (release *Release) RunPreDeployHook(lambdac aws.LambdaAPI) error {
+	if release.PreDeployHook == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(&PreDeployHookInput{
+		ProjectName:  release.ProjectName,
+		ConfigName:   release.ConfigName,
+		ReleaseID:    release.ReleaseID,
+		ReleaseUUID:  release.UUID,
+		AwsAccountID: release.AwsAccountID,
+		AwsRegion:    release.AwsRegion,
+		Image:        release.Image,
+		Services:     sortedServiceNames(release),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	raw, err := lambda.Invoke(lambdac, release.PreDeployHook, payload)
+	if err != nil {
+		return fmt.Errorf("PreDeployHook %v", err.Error())
+	}
+
+	var resp PreDeployHookResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return fmt.Errorf("PreDeployHook response %v", err.Error())
+	}
+
+	if !resp.Allow {
+		if is.EmptyStr(resp.Message) {
+			return fmt.Errorf("PreDeployHook denied the release")
+		}
+		return fmt.Errorf("PreDeployHook denied the release: %v", *resp.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidatePreDeployHook(t *testing.T) {
+	release := MockRelease(t)
+	assert.NoError(t, release.ValidatePreDeployHook())
+
+	release.PreDeployHook = to.Strp("arn:aws:lambda:us-east-1:000000:function:gate")
+	assert.NoError(t, release.ValidatePreDeployHook())
+
+	release.PreDeployHook = to.Strp("")
+	assert.Error(t, release.ValidatePreDeployHook())
+
+	release.PreDeployHook = to.Strp("gate")
+	assert.Error(t, release.ValidatePreDeployHook())
+
+	release.PreDeployHook = to.Strp("arn:aws:sns:us-east-1:000000:gate")
+	assert.Error(t, release.ValidatePreDeployHook())
+}
+
+func Test_Release_RunPreDeployHook(t *testing.T) {
+	hook := "arn:aws:lambda:us-east-1:000000:function:gate"
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	// No hook is not invoked
+	assert.NoError(t, release.RunPreDeployHook(awsc.Lambda))
+	assert.Equal(t, 0, len(awsc.Lambda.InvokeInputs))
+
+	release.PreDeployHook = to.Strp(hook)
+	awsc.Lambda.AddAllow(hook)
+	assert.NoError(t, release.RunPreDeployHook(awsc.Lambda))
+
+	var input PreDeployHookInput
+	assert.NoError(t, json.Unmarshal(awsc.Lambda.InvokeInputs[0].Payload, &input))
+	assert.Equal(t, "project", *input.ProjectName)
+	assert.Equal(t, "config", *input.ConfigName)
+	assert.Equal(t, "1", *input.ReleaseID)
+	assert.Equal(t, *release.UUID, *input.ReleaseUUID)
+	assert.Equal(t, []string{"web"}, input.Services)
+	assert.Equal(t, "RequestResponse", *awsc.Lambda.InvokeInputs[0].InvocationType)
+
+	awsc.Lambda.AddDeny(hook, "change freeze")
+	err := release.RunPreDeployHook(awsc.Lambda)
+	assert.Error(t, err)
+	assert.Equal(t, "PreDeployHook denied the release: change freeze", err.Error())
+
+	awsc.Lambda.AddInvokeResponse(hook, 200, "not json")
+	assert.Error(t, release.RunPreDeployHook(awsc.Lambda))
+
+	awsc.Lambda.AddInvokeResponse(hook, 403, `{"allow": true}`)
+	assert.Error(t, release.RunPreDeployHook(awsc.Lambda))
+
+	awsc.Lambda.InvokeResp[hook].StatusCode = to.Int64p(200)
+	awsc.Lambda.InvokeResp[hook].FunctionError = to.Strp("Unhandled")
+	assert.Error(t, release.RunPreDeployHook(awsc.Lambda))
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Previous Fleet Preflight
+//////////
+
+// ValidatePreviousFleetHealthyPercent validates the PreviousFleetHealthyPercent
+func (release *Release) ValidatePreviousFleetHealthyPercent() error {
+	if release.PreviousFleetHealthyPercent == nil {
+		return nil
+	}
+
+	if *release.PreviousFleetHealthyPercent < 1 || *release.PreviousFleetHealthyPercent > 100 {
+		return fmt.Errorf("PreviousFleetHealthyPercent must be between 1 and 100")
+	}
+
+	return nil
+}
+
+// validatePreviousFleetHealthy errors if fewer than PreviousFleetHealthyPercent of the services previous
+// ASGs desired capacity is healthy. The fleet is already degraded so people should investigate before deploying.
+// It is opt-in because a deploy is often how a degraded fleet is recovered
+func (release *Release) validatePreviousFleetHealthy(service *Service, sr *ServiceResources) error {
+	if release.PreviousFleetHealthyPercent == nil || sr.PrevASG == nil {
+		return nil
+	}
+
+	prev := sr.PrevASG
+	if prev.DesiredCapacity == nil || *prev.DesiredCapacity == 0 {
+		return nil
+	}
+
+	healthy := int64(len(prev.HealthyIDs()))
+	if healthy*100 >= *prev.DesiredCapacity*int64(*release.PreviousFleetHealthyPercent) {
+		return nil
+	}
+
+	return fmt.Errorf("%v previous ASG %v has %v of %v instances healthy, less than PreviousFleetHealthyPercent %v%%",
+		service.errorPrefix(), to.Strs(prev.AutoScalingGroupName), healthy, *prev.DesiredCapacity, *release.PreviousFleetHealthyPercent)
+}
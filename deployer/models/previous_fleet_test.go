@@ -0,0 +1,61 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidatePreviousFleetHealthyPercent(t *testing.T) {
+	r := MockRelease(t)
+	assert.NoError(t, r.ValidatePreviousFleetHealthyPercent())
+
+	r.PreviousFleetHealthyPercent = to.Intp(100)
+	assert.NoError(t, r.ValidatePreviousFleetHealthyPercent())
+
+	r.PreviousFleetHealthyPercent = to.Intp(0)
+	assert.Error(t, r.ValidatePreviousFleetHealthyPercent())
+
+	r.PreviousFleetHealthyPercent = to.Intp(101)
+	assert.Error(t, r.ValidatePreviousFleetHealthyPercent())
+}
+
+func Test_Release_ValidateResources_PreviousFleetHealthy(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+
+	awsc := MockAwsClients(r)
+	old := awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0]
+	old.DesiredCapacity = to.Int64p(4)
+	old.Instances = mocks.MakeMockASGInstances(3, 1, 0)
+
+	resources, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	// Off by default so a degraded fleet can be recovered
+	assert.NoError(t, r.ValidateResources(resources))
+
+	r.PreviousFleetHealthyPercent = to.Intp(75)
+	assert.NoError(t, r.ValidateResources(resources))
+
+	r.PreviousFleetHealthyPercent = to.Intp(80)
+	err = r.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "has 3 of 4 instances healthy, less than PreviousFleetHealthyPercent 80%")
+}
+
+func Test_Release_ValidateResources_PreviousFleetHealthy_NoPreviousFleet(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	r.PreviousFleetHealthyPercent = to.Intp(100)
+
+	// The first deploy has nothing to check
+	awsc := MockAwsClients(r)
+	awsc.ASG = &mocks.ASGClient{}
+
+	resources, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, r.ValidateResources(resources))
+}
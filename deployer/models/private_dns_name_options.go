@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coinbase/odin/aws/lt"
+)
+
+// PRIVATE_DNS_HOSTNAME_TYPES are the values of hostname_type, ip-name by default
+var PRIVATE_DNS_HOSTNAME_TYPES = []string{"ip-name", "resource-name"}
+
+// resourceNameUnsupportedRegionPrefixes are the regions instances cannot be named after their instance ID
+var resourceNameUnsupportedRegionPrefixes = []string{"cn-"}
+
+// PrivateDnsNameOptions configures the hostname of each instance and which DNS records resolve its resource name
+type PrivateDnsNameOptions struct {
+	HostnameType                    *string `json:"hostname_type,omitempty"`
+	EnableResourceNameDnsARecord    *bool   `json:"enable_resource_name_dns_a_record,omitempty"`
+	EnableResourceNameDnsAAAARecord *bool   `json:"enable_resource_name_dns_aaaa_record,omitempty"`
+}
+
+// ValidateAttributes validates attributes, region is where the instances launch
+func (pd *PrivateDnsNameOptions) ValidateAttributes(region *string) error {
+	if pd.HostnameType == nil && pd.EnableResourceNameDnsARecord == nil && pd.EnableResourceNameDnsAAAARecord == nil {
+		return fmt.Errorf("PrivateDnsNameOptions cannot be empty")
+	}
+
+	if pd.HostnameType == nil {
+		return nil
+	}
+
+	if !containsStr(PRIVATE_DNS_HOSTNAME_TYPES, *pd.HostnameType) {
+		return fmt.Errorf("PrivateDnsNameOptions hostname_type must be one of %v", PRIVATE_DNS_HOSTNAME_TYPES)
+	}
+
+	if *pd.HostnameType != "resource-name" || region == nil {
+		return nil
+	}
+
+	for _, prefix := range resourceNameUnsupportedRegionPrefixes {
+		if strings.HasPrefix(*region, prefix) {
+			return fmt.Errorf("PrivateDnsNameOptions hostname_type resource-name is not supported in %v", *region)
+		}
+	}
+
+	return nil
+}
+
+// validatePrivateDnsNameOptions validates the private_dns_name_options
+func (service *Service) validatePrivateDnsNameOptions() error {
+	if service.PrivateDnsNameOptions == nil {
+		return nil
+	}
+
+	var region *string
+	if service.release != nil {
+		region = service.release.AwsRegion
+	}
+
+	return service.PrivateDnsNameOptions.ValidateAttributes(region)
+}
+
+// setPrivateDnsNameOptions sets the private DNS name options on the launch template, the SDK has no
+// fields for them so they are sent as launch template data query parameters
+func (service *Service) setPrivateDnsNameOptions(input *lt.Input) {
+	pd := service.PrivateDnsNameOptions
+	if pd == nil {
+		return
+	}
+
+	if pd.HostnameType != nil {
+		input.SetData("PrivateDnsNameOptions.HostnameType", *pd.HostnameType)
+	}
+
+	if pd.EnableResourceNameDnsARecord != nil {
+		input.SetData("PrivateDnsNameOptions.EnableResourceNameDnsARecord", fmt.Sprintf("%v", *pd.EnableResourceNameDnsARecord))
+	}
+
+	if pd.EnableResourceNameDnsAAAARecord != nil {
+		input.SetData("PrivateDnsNameOptions.EnableResourceNameDnsAAAARecord", fmt.Sprintf("%v", *pd.EnableResourceNameDnsAAAARecord))
+	}
+}
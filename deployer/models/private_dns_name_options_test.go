@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PrivateDnsNameOptions_ValidateAttributes(t *testing.T) {
+	region := to.Strp("us-east-1")
+	assert.NoError(t, (&PrivateDnsNameOptions{HostnameType: to.Strp("ip-name")}).ValidateAttributes(region))
+	assert.NoError(t, (&PrivateDnsNameOptions{HostnameType: to.Strp("resource-name"), EnableResourceNameDnsARecord: to.Boolp(true)}).ValidateAttributes(region))
+	assert.NoError(t, (&PrivateDnsNameOptions{EnableResourceNameDnsAAAARecord: to.Boolp(false)}).ValidateAttributes(region))
+
+	assert.Error(t, (&PrivateDnsNameOptions{}).ValidateAttributes(region))
+	assert.Error(t, (&PrivateDnsNameOptions{HostnameType: to.Strp("instance-id")}).ValidateAttributes(region))
+
+	// resource-name hostnames are not supported in every region
+	err := (&PrivateDnsNameOptions{HostnameType: to.Strp("resource-name")}).ValidateAttributes(to.Strp("cn-north-1"))
+	assert.EqualError(t, err, "PrivateDnsNameOptions hostname_type resource-name is not supported in cn-north-1")
+	assert.NoError(t, (&PrivateDnsNameOptions{HostnameType: to.Strp("ip-name")}).ValidateAttributes(to.Strp("cn-north-1")))
+}
+
+func Test_Service_ValidateAttributes_PrivateDnsNameOptions(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].PrivateDnsNameOptions = &PrivateDnsNameOptions{HostnameType: to.Strp("resource-name")}
+	MockPrepareRelease(release)
+	assert.NoError(t, release.Services["web"].ValidateAttributes())
+
+	// The region is the releases
+	release.AwsRegion = to.Strp("cn-northwest-1")
+	err := release.Services["web"].ValidateAttributes()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "resource-name is not supported in cn-northwest-1")
+}
+
+func Test_Release_CreateResources_PrivateDnsNameOptions_ResourceName(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].PrivateDnsNameOptions = &PrivateDnsNameOptions{
+		HostnameType:                 to.Strp("resource-name"),
+		EnableResourceNameDnsARecord: to.Boolp(true),
+	}
+	MockPrepareRelease(release)
+
+	// The options are only supported by launch templates
+	service := release.Services["web"]
+	assert.True(t, service.launchTemplate())
+
+	awsc := MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.Equal(t, 1, len(awsc.EC2.CreateLaunchTemplateInputs))
+
+	data := awsc.EC2.LaunchTemplateData[*service.ServiceID()]
+	assert.Equal(t, "resource-name", data["PrivateDnsNameOptions.HostnameType"])
+	assert.Equal(t, "true", data["PrivateDnsNameOptions.EnableResourceNameDnsARecord"])
+	_, ok := data["PrivateDnsNameOptions.EnableResourceNameDnsAAAARecord"]
+	assert.False(t, ok)
+
+	// The launch template still has the launch configuration values
+	assert.Equal(t, *service.InstanceType, data["InstanceType"])
+	assert.Equal(t, *service.ServiceID(), *awsc.ASG.CreateAutoScalingGroupInputs[0].LaunchTemplate.LaunchTemplateName)
+}
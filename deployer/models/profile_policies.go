@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coinbase/odin/aws/iam"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Required Profile Policies
+//////////
+
+// ValidateRequiredProfilePolicies validates RequiredProfilePolicies are unique IAM policy ARNs
+func (release *Release) ValidateRequiredProfilePolicies() error {
+	seen := map[string]bool{}
+	for _, arn := range release.RequiredProfilePolicies {
+		if arn == nil || !strings.HasPrefix(*arn, "arn:aws:iam::") || !strings.Contains(*arn, ":policy/") {
+			return fmt.Errorf("RequiredProfilePolicies %q is not an IAM policy ARN", to.Strs(arn))
+		}
+
+		if seen[*arn] {
+			return fmt.Errorf("RequiredProfilePolicies %q is duplicated", *arn)
+		}
+		seen[*arn] = true
+	}
+
+	return nil
+}
+
+func (service *Service) requiredProfilePolicies() []*string {
+	if service.release == nil {
+		return nil
+	}
+
+	return service.release.RequiredProfilePolicies
+}
+
+// validateRequiredProfilePolicies checks the profiles roles have every required managed policy attached
+func (service *Service) validateRequiredProfilePolicies(profile *iam.Profile) error {
+	required := service.requiredProfilePolicies()
+	if len(required) == 0 {
+		return nil
+	}
+
+	if profile == nil {
+		return fmt.Errorf("Iam Profile must be defined with RequiredProfilePolicies")
+	}
+
+	attached := map[string]bool{}
+	for _, arn := range profile.PolicyARNs {
+		attached[arn] = true
+	}
+
+	missing := []string{}
+	for _, arn := range required {
+		if !attached[*arn] {
+			missing = append(missing, *arn)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("Iam Profile %v missing required policies %v", to.Strs(service.Profile), strings.Join(missing, ", "))
+	}
+
+	return nil
+}
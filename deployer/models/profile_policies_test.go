@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/iam"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidateRequiredProfilePolicies(t *testing.T) {
+	release := MockRelease(t)
+	assert.NoError(t, release.ValidateRequiredProfilePolicies())
+
+	release.RequiredProfilePolicies = []*string{to.Strp("arn:aws:iam::000000000000:policy/path/name")}
+	assert.NoError(t, release.ValidateRequiredProfilePolicies())
+
+	release.RequiredProfilePolicies = []*string{to.Strp("arn:aws:iam::aws:policy/a"), to.Strp("arn:aws:iam::aws:policy/a")}
+	assert.Error(t, release.ValidateRequiredProfilePolicies())
+
+	release.RequiredProfilePolicies = []*string{to.Strp("arn:aws:iam::aws:role/a")}
+	assert.Error(t, release.ValidateRequiredProfilePolicies())
+
+	release.RequiredProfilePolicies = []*string{nil}
+	assert.Error(t, release.ValidateRequiredProfilePolicies())
+}
+
+func Test_Service_validateRequiredProfilePolicies(t *testing.T) {
+	release := MockRelease(t)
+	release.RequiredProfilePolicies = []*string{to.Strp("arn:aws:iam::aws:policy/a"), to.Strp("arn:aws:iam::aws:policy/b")}
+	MockPrepareRelease(release)
+	service := release.Services["web"]
+
+	profile := &iam.Profile{PolicyARNs: []string{"arn:aws:iam::aws:policy/a", "arn:aws:iam::aws:policy/b"}}
+	assert.NoError(t, service.validateRequiredProfilePolicies(profile))
+
+	profile.PolicyARNs = []string{"arn:aws:iam::aws:policy/a"}
+	assert.EqualError(t, service.validateRequiredProfilePolicies(profile), "Iam Profile web-profile missing required policies arn:aws:iam::aws:policy/b")
+
+	// A service without a profile cannot have the policies
+	assert.Error(t, service.validateRequiredProfilePolicies(nil))
+
+	release.RequiredProfilePolicies = nil
+	assert.NoError(t, service.validateRequiredProfilePolicies(nil))
+}
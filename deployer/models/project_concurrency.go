@@ -0,0 +1,96 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/errors"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Project Concurrency
+//////////
+
+// maxProjectConcurrency bounds the slots Lock tries to grab
+const maxProjectConcurrency = 100
+
+// ValidateProjectConcurrency validates the ProjectConcurrency
+func (release *Release) ValidateProjectConcurrency() error {
+	if release.ProjectConcurrency == nil {
+		return nil
+	}
+
+	if *release.ProjectConcurrency < 1 || *release.ProjectConcurrency > maxProjectConcurrency {
+		return fmt.Errorf("ProjectConcurrency must be between 1 and %v", maxProjectConcurrency)
+	}
+
+	return nil
+}
+
+// ProjectSlotLockPath is the lock of one of the slots shared by every config of the project in the account
+func (release *Release) ProjectSlotLockPath(slot int) *string {
+	s := fmt.Sprintf("%v/_project_concurrency/%v/%v/lock", *release.AwsAccountID, *release.ProjectName, slot)
+	return &s
+}
+
+// GrabLocks grabs the project config and MutexGroup locks then a ProjectConcurrency slot. If every
+// slot is held by another config of the project the other locks are released before returning
+func (release *Release) GrabLocks(s3c aws.S3API, locker Locker, lockTableName string) error {
+	if err := release.grabConfigLocks(s3c, locker, lockTableName); err != nil {
+		return err
+	}
+
+	release.ProjectConcurrencySlot = nil
+	if release.ProjectConcurrency == nil {
+		return nil
+	}
+
+	for slot := 0; slot < *release.ProjectConcurrency; slot++ {
+		grabbed, err := locker.GrabLock(lockTableName, *release.ProjectSlotLockPath(slot), *release.UUID, "")
+
+		if grabbed {
+			release.ProjectConcurrencySlot = to.Intp(slot)
+		}
+
+		// Error if MAYBE grabbed the slot, release it here as a failed Lock does not return the slot
+		if err != nil {
+			if unlockErr := release.releaseProjectSlot(locker, lockTableName, slot); unlockErr != nil {
+				return &errors.LockError{unlockErr.Error()}
+			}
+			return &errors.LockError{err.Error()}
+		}
+
+		if grabbed {
+			return nil
+		}
+	}
+
+	if err := release.unlockConfigLocks(s3c, locker, lockTableName); err != nil {
+		return &errors.LockError{err.Error()}
+	}
+
+	return &errors.LockExistsError{fmt.Sprintf(
+		"ProjectConcurrency all %v slots of project %v are held by other deploys, retry when one finishes",
+		*release.ProjectConcurrency,
+		*release.ProjectName,
+	)}
+}
+
+// UnlockRoot releases the project config and MutexGroup locks and the ProjectConcurrency slot
+func (release *Release) UnlockRoot(s3c aws.S3API, locker Locker, lockTableName string) error {
+	if err := release.unlockConfigLocks(s3c, locker, lockTableName); err != nil {
+		return err
+	}
+
+	if release.ProjectConcurrencySlot == nil {
+		return nil
+	}
+
+	return release.releaseProjectSlot(locker, lockTableName, *release.ProjectConcurrencySlot)
+}
+
+// releaseProjectSlot releases the slot if it is free or held by this release
+func (release *Release) releaseProjectSlot(locker Locker, lockTableName string, slot int) error {
+	return locker.ReleaseLock(lockTableName, *release.ProjectSlotLockPath(slot), *release.UUID)
+}
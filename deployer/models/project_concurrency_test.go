@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/errors"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockProjectConcurrencyRelease(t *testing.T, configName string, concurrency int) *Release {
+	release := MockRelease(t)
+	release.ConfigName = to.Strp(configName)
+	release.ProjectConcurrency = to.Intp(concurrency)
+	MockPrepareRelease(release)
+	release.UUID = to.Strp(configName + "-uuid")
+	return release
+}
+
+func Test_Release_ValidateProjectConcurrency(t *testing.T) {
+	release := MockRelease(t)
+	assert.NoError(t, release.ValidateProjectConcurrency())
+
+	for _, concurrency := range []int{1, 5, 100} {
+		release.ProjectConcurrency = to.Intp(concurrency)
+		assert.NoError(t, release.ValidateProjectConcurrency())
+	}
+
+	for _, concurrency := range []int{-1, 0, 101} {
+		release.ProjectConcurrency = to.Intp(concurrency)
+		assert.Error(t, release.ValidateProjectConcurrency())
+	}
+
+	assert.Equal(t, "000000/_project_concurrency/project/2/lock", *release.ProjectSlotLockPath(2))
+}
+
+func Test_Release_GrabLocks_ProjectConcurrency(t *testing.T) {
+	first := mockProjectConcurrencyRelease(t, "first", 2)
+	second := mockProjectConcurrencyRelease(t, "second", 2)
+	third := mockProjectConcurrencyRelease(t, "third", 2)
+
+	awsc := MockAwsClients(first)
+	locker := first.Locker(awsc.S3, awsc.DynamoDB)
+
+	assert.NoError(t, first.GrabLocks(awsc.S3, locker, "locks"))
+	assert.Equal(t, 0, *first.ProjectConcurrencySlot)
+
+	assert.NoError(t, second.GrabLocks(awsc.S3, locker, "locks"))
+	assert.Equal(t, 1, *second.ProjectConcurrencySlot)
+
+	// The cap is reached
+	err := third.GrabLocks(awsc.S3, locker, "locks")
+	assert.IsType(t, &errors.LockExistsError{}, err)
+	assert.Contains(t, err.Error(), "retry")
+	assert.Nil(t, third.ProjectConcurrencySlot)
+
+	// The config lock of the third is released
+	_, held := awsc.DynamoDB.Locks[*third.RootLockPath()]
+	assert.False(t, held)
+
+	// Once a deploy finishes its slot is free
+	assert.NoError(t, first.UnlockRoot(awsc.S3, locker, "locks"))
+	_, held = awsc.DynamoDB.Locks[*first.ProjectSlotLockPath(0)]
+	assert.False(t, held)
+
+	assert.NoError(t, third.GrabLocks(awsc.S3, locker, "locks"))
+	assert.Equal(t, 0, *third.ProjectConcurrencySlot)
+
+	assert.NoError(t, second.UnlockRoot(awsc.S3, locker, "locks"))
+	assert.NoError(t, third.UnlockRoot(awsc.S3, locker, "locks"))
+	assert.Equal(t, 0, len(awsc.DynamoDB.Locks))
+}
@@ -0,0 +1,270 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/odin/aws/lt"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Prune
+//////////
+
+// maxPruneConcurrency and maxPruneDeletesPerSecond bound the deletes so pruning cannot exhaust the accounts API quotas
+const maxPruneConcurrency = 20
+const maxPruneDeletesPerSecond = 50
+
+// PruneInput is the project config to prune
+type PruneInput struct {
+	Release
+
+	// List what would be deleted without deleting it
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Concurrency is how many orphans are deleted at once, default 1. DeletesPerSecond limits
+	// how many deletes are started each second, default unlimited. Throttled deletes are retried by the SDK
+	Concurrency      *int `json:"concurrency,omitempty"`
+	DeletesPerSecond *int `json:"deletes_per_second,omitempty"`
+}
+
+// PruneResult is what was, or with DryRun would be, deleted
+type PruneResult struct {
+	ProjectName      *string `json:"project_name,omitempty"`
+	ConfigName       *string `json:"config_name,omitempty"`
+	CurrentReleaseID *string `json:"current_release_id,omitempty"`
+	DryRun           bool    `json:"dry_run"`
+
+	// Deleting an ASG also deletes its alarms, its launch configuration or template and its load balancer attachments
+	AutoScalingGroups []*string `json:"autoscaling_groups"`
+
+	// Launch templates whose ASG was never created
+	LaunchTemplates []*string `json:"launch_templates"`
+}
+
+// CurrentDeployResultPath returns the path of the deploy result of the last successful release
+func (release *Release) CurrentDeployResultPath() *string {
+	s := fmt.Sprintf("%v/results/current", *release.RootDir())
+	return &s
+}
+
+// ValidatePrune validates the project config to prune
+func (release *Release) ValidatePrune() error {
+	if is.EmptyStr(release.ProjectName) {
+		return fmt.Errorf("ProjectName must be defined")
+	}
+
+	if is.EmptyStr(release.ConfigName) {
+		return fmt.Errorf("ConfigName must be defined")
+	}
+
+	if is.EmptyStr(release.Bucket) {
+		return fmt.Errorf("Bucket must be defined")
+	}
+
+	return release.ValidateDeployRoleARN()
+}
+
+// ValidatePruneLimits validates Concurrency and DeletesPerSecond
+func (input *PruneInput) ValidatePruneLimits() error {
+	if input.Concurrency != nil && (*input.Concurrency < 1 || *input.Concurrency > maxPruneConcurrency) {
+		return fmt.Errorf("Concurrency must be between 1 and %v", maxPruneConcurrency)
+	}
+
+	if input.DeletesPerSecond != nil && (*input.DeletesPerSecond < 1 || *input.DeletesPerSecond > maxPruneDeletesPerSecond) {
+		return fmt.Errorf("DeletesPerSecond must be between 1 and %v", maxPruneDeletesPerSecond)
+	}
+
+	return nil
+}
+
+// concurrency is the number of orphans deleted at once
+func (input *PruneInput) concurrency() int {
+	if input.Concurrency == nil {
+		return 1
+	}
+	return *input.Concurrency
+}
+
+// deleteAll calls every delete using a pool of concurrency workers, starting at most DeletesPerSecond each second.
+// Every delete is called even if one fails, the returned error is the first in order
+func (input *PruneInput) deleteAll(deletes []func() error) error {
+	errs := make([]error, len(deletes))
+
+	var tick <-chan time.Time
+	if input.DeletesPerSecond != nil {
+		ticker := time.NewTicker(time.Second / time.Duration(*input.DeletesPerSecond))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < input.concurrency(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = deletes[i]()
+			}
+		}()
+	}
+
+	for i := range deletes {
+		if tick != nil && i > 0 {
+			<-tick
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Prune deletes the ASGs and launch templates of the project config left by releases that died before they were cleaned up.
+// Resources of the current release, the last one to succeed, and of releases with a RUNNING execution are never deleted
+func (input *PruneInput) Prune(s3c aws.S3API, asgc aws.ASGAPI, ec2c aws.EC2API, cwc aws.CWAPI, sfnc aws.SFNAPI, stateMachineArn *string) (*PruneResult, error) {
+	release := &input.Release
+
+	var current DeployResult
+	if err := s3.GetStruct(s3c, release.Bucket, release.CurrentDeployResultPath(), &current); err != nil {
+		switch err.(type) {
+		case *s3.NotFoundError:
+			return nil, fmt.Errorf("Prune cannot find the current release at %v", *release.CurrentDeployResultPath())
+		default:
+			return nil, err
+		}
+	}
+
+	if current.ReleaseID == nil {
+		return nil, fmt.Errorf("Prune current release has no ReleaseID")
+	}
+
+	// Resources are listed before the executions, anything listed was created by
+	// an execution that has already started so is found if it is still running
+	asgs, err := asg.ForProjectConfigNOTReleaseID(asgc, release.ProjectName, release.ConfigName, current.ReleaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := lt.ForProjectConfig(ec2c, release.ProjectName, release.ConfigName)
+	if err != nil {
+		return nil, err
+	}
+
+	live, err := release.liveReleaseIDs(sfnc, stateMachineArn)
+	if err != nil {
+		return nil, fmt.Errorf("Prune cannot check executions: %v", err.Error())
+	}
+
+	result := &PruneResult{
+		ProjectName:       release.ProjectName,
+		ConfigName:        release.ConfigName,
+		CurrentReleaseID:  current.ReleaseID,
+		DryRun:            input.DryRun,
+		AutoScalingGroups: []*string{},
+		LaunchTemplates:   []*string{},
+	}
+
+	orphans := []*asg.ASG{}
+	deletedWithASG := map[string]bool{}
+	for _, group := range asgs {
+		if err := release.validPruneASG(group, current.ReleaseID); err != nil {
+			return nil, err
+		}
+
+		if live[*group.ReleaseID()] {
+			continue
+		}
+
+		orphans = append(orphans, group)
+		result.AutoScalingGroups = append(result.AutoScalingGroups, group.AutoScalingGroupName)
+		if group.LaunchTemplateName != nil {
+			deletedWithASG[*group.LaunchTemplateName] = true
+		}
+	}
+
+	names := []string{}
+	for name, releaseID := range templates {
+		// Shared launch templates have no release, their versions are pruned by the current release
+		if releaseID == nil || *releaseID == *current.ReleaseID || live[*releaseID] || deletedWithASG[name] {
+			continue
+		}
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		result.LaunchTemplates = append(result.LaunchTemplates, to.Strp(name))
+	}
+
+	if input.DryRun {
+		return result, nil
+	}
+
+	deletes := []func() error{}
+	for _, group := range orphans {
+		group := group
+		deletes = append(deletes, func() error { return group.Teardown(asgc, ec2c, cwc) })
+	}
+
+	for _, name := range result.LaunchTemplates {
+		name := name
+		deletes = append(deletes, func() error { return lt.Teardown(ec2c, name) })
+	}
+
+	if err := input.deleteAll(deletes); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// validPruneASG errors if the ASG is not an ASG of a previous release of the project config
+func (release *Release) validPruneASG(group *asg.ASG, currentReleaseID *string) error {
+	if to.Strs(group.ProjectName()) != *release.ProjectName || to.Strs(group.ConfigName()) != *release.ConfigName {
+		return fmt.Errorf("Prune ASG %v is not in the project config", to.Strs(group.AutoScalingGroupName))
+	}
+
+	if group.ReleaseID() == nil {
+		return fmt.Errorf("Prune ASG %v has no ReleaseID", to.Strs(group.AutoScalingGroupName))
+	}
+
+	if *group.ReleaseID() == *currentReleaseID {
+		return fmt.Errorf("Prune ASG %v is in the current release", to.Strs(group.AutoScalingGroupName))
+	}
+
+	return nil
+}
+
+// liveReleaseIDs returns the release IDs of the RUNNING executions of this project config
+func (release *Release) liveReleaseIDs(sfnc aws.SFNAPI, stateMachineArn *string) (map[string]bool, error) {
+	live := map[string]bool{}
+	err := forEachRunningExecution(sfnc, stateMachineArn, func(item *sfn.ExecutionListItem) (bool, error) {
+		releaseID, err := release.runningReleaseID(sfnc, item)
+		if releaseID != nil {
+			live[*releaseID] = true
+		}
+		return false, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return live, nil
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockPruneRelease(t *testing.T) (*Release, *mocks.MockClients) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	// old-release is the current release
+	assert.NoError(t, s3.PutStruct(awsc.S3, release.Bucket, release.CurrentDeployResultPath(), &DeployResult{ReleaseID: to.Strp("old-release")}))
+	return release, awsc
+}
+
+func addReleaseLaunchTemplate(awsc *mocks.MockClients, release *Release, name string, releaseID *string) {
+	tags := []*ec2.Tag{
+		&ec2.Tag{Key: to.Strp("ProjectName"), Value: release.ProjectName},
+		&ec2.Tag{Key: to.Strp("ConfigName"), Value: release.ConfigName},
+	}
+
+	if releaseID != nil {
+		tags = append(tags, &ec2.Tag{Key: to.Strp("ReleaseID"), Value: releaseID})
+	}
+
+	awsc.EC2.CreateLaunchTemplate(&ec2.CreateLaunchTemplateInput{
+		LaunchTemplateName: to.Strp(name),
+		TagSpecifications:  []*ec2.TagSpecification{&ec2.TagSpecification{Tags: tags}},
+	})
+}
+
+func Test_Release_Prune_NoCurrentRelease(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	_, err := (&PruneInput{Release: *release, DryRun: true}).Prune(awsc.S3, awsc.ASG, awsc.EC2, awsc.CW, awsc.SFN, to.Strp("arn"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot find the current release")
+}
+
+func Test_Release_Prune_NoOrphans(t *testing.T) {
+	release, awsc := mockPruneRelease(t)
+	addReleaseLaunchTemplate(awsc, release, "shared", nil)
+	addReleaseLaunchTemplate(awsc, release, "current", to.Strp("old-release"))
+
+	result, err := (&PruneInput{Release: *release}).Prune(awsc.S3, awsc.ASG, awsc.EC2, awsc.CW, awsc.SFN, to.Strp("arn"))
+	assert.NoError(t, err)
+	assert.Equal(t, "old-release", *result.CurrentReleaseID)
+	assert.Equal(t, 0, len(result.AutoScalingGroups))
+	assert.Equal(t, 0, len(result.LaunchTemplates))
+
+	assert.Equal(t, 0, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, 0, len(awsc.EC2.DeleteLaunchTemplateInputs))
+}
+
+func Test_Release_Prune_Orphans(t *testing.T) {
+	release, awsc := mockPruneRelease(t)
+
+	// A dead release left an ASG and a launch template whose ASG was never created
+	awsc.ASG.AddPreviousRuntimeResources(*release.ProjectName, *release.ConfigName, "web", "dead-release")
+	addReleaseLaunchTemplate(awsc, release, "dead-template", to.Strp("dead-template-release"))
+
+	// A running release is not an orphan
+	awsc.ASG.AddPreviousRuntimeResources(*release.ProjectName, *release.ConfigName, "web", "live-release")
+	addReleaseLaunchTemplate(awsc, release, "live-template", to.Strp("live-release"))
+	awsc.SFN.AddExecution("arn:live", release.ExecutionPrefix()+"live", "RUNNING", map[string]string{"release_id": "live-release"})
+
+	// Dry run lists the orphans without deleting them
+	result, err := (&PruneInput{Release: *release, DryRun: true}).Prune(awsc.S3, awsc.ASG, awsc.EC2, awsc.CW, awsc.SFN, to.Strp("arn"))
+	assert.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, []string{"project-config-web-dead-release"}, to.StrSlice(result.AutoScalingGroups))
+	assert.Equal(t, []string{"dead-template"}, to.StrSlice(result.LaunchTemplates))
+	assert.Equal(t, 0, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, 0, len(awsc.EC2.DeleteLaunchTemplateInputs))
+
+	result, err = (&PruneInput{Release: *release}).Prune(awsc.S3, awsc.ASG, awsc.EC2, awsc.CW, awsc.SFN, to.Strp("arn"))
+	assert.NoError(t, err)
+	assert.False(t, result.DryRun)
+
+	assert.Equal(t, 1, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, "project-config-web-dead-release", *awsc.ASG.DeleteAutoScalingGroupInputs[0].AutoScalingGroupName)
+
+	assert.Equal(t, 1, len(awsc.EC2.DeleteLaunchTemplateInputs))
+	assert.Equal(t, "dead-template", *awsc.EC2.DeleteLaunchTemplateInputs[0].LaunchTemplateName)
+}
+
+func Test_Release_ValidatePrune(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	assert.NoError(t, release.ValidatePrune())
+
+	release.ConfigName = nil
+	assert.Error(t, release.ValidatePrune())
+}
+
+func Test_PruneInput_ValidatePruneLimits(t *testing.T) {
+	input := &PruneInput{}
+	assert.NoError(t, input.ValidatePruneLimits())
+
+	input.Concurrency = to.Intp(0)
+	assert.Error(t, input.ValidatePruneLimits())
+
+	input.Concurrency = to.Intp(5)
+	input.DeletesPerSecond = to.Intp(maxPruneDeletesPerSecond + 1)
+	assert.Error(t, input.ValidatePruneLimits())
+
+	input.DeletesPerSecond = to.Intp(10)
+	assert.NoError(t, input.ValidatePruneLimits())
+}
+
+func Test_PruneInput_DeleteAll_Bounded(t *testing.T) {
+	input := &PruneInput{Concurrency: to.Intp(3)}
+
+	var mu sync.Mutex
+	running, most, calls := 0, 0, 0
+	deletes := []func() error{}
+	for i := 0; i < 10; i++ {
+		deletes = append(deletes, func() error {
+			mu.Lock()
+			running++
+			calls++
+			if running > most {
+				most = running
+			}
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	assert.NoError(t, input.deleteAll(deletes))
+	assert.Equal(t, 10, calls)
+	assert.True(t, most > 1)
+	assert.True(t, most <= 3)
+}
+
+func Test_PruneInput_DeleteAll_Rate_Limited(t *testing.T) {
+	input := &PruneInput{Concurrency: to.Intp(5), DeletesPerSecond: to.Intp(20)}
+
+	deletes := []func() error{}
+	for i := 0; i < 5; i++ {
+		deletes = append(deletes, func() error { return nil })
+	}
+
+	// Every delete, even after one fails, is started no faster than 20 a second
+	deletes[1] = func() error { return fmt.Errorf("failed") }
+
+	start := time.Now()
+	assert.Error(t, input.deleteAll(deletes))
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/instance"
+)
+
+//////////
+// Readiness Check
+//////////
+
+// ReadinessCheck is an HTTP request to each new instances private IP that must respond with
+// ExpectedStatus before the instance is counted healthy
+type ReadinessCheck struct {
+	Path           *string `json:"path,omitempty"` // default /ready
+	Port           *int64  `json:"port,omitempty"`
+	ExpectedStatus *int    `json:"expected_status,omitempty"` // default 200
+}
+
+// path returns the request path, default /ready
+func (rc *ReadinessCheck) path() string {
+	if rc.Path == nil {
+		return "/ready"
+	}
+	return *rc.Path
+}
+
+// expectedStatus returns the ready status code, default 200
+func (rc *ReadinessCheck) expectedStatus() int {
+	if rc.ExpectedStatus == nil {
+		return http.StatusOK
+	}
+	return *rc.ExpectedStatus
+}
+
+// url returns the readiness URL of the instance with the private IP
+func (rc *ReadinessCheck) url(ip string) string {
+	return fmt.Sprintf("http://%v:%v%v", ip, *rc.Port, rc.path())
+}
+
+// ready returns true if the instance responds with the expected status
+func (rc *ReadinessCheck) ready(httpc aws.HTTPAPI, ip string) bool {
+	req, err := http.NewRequest(http.MethodGet, rc.url(ip), nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := httpc.Do(req)
+	if err != nil {
+		return false // Not listening yet
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode == rc.expectedStatus()
+}
+
+// validateReadinessCheck validates the ReadinessCheck
+func (service *Service) validateReadinessCheck() error {
+	rc := service.ReadinessCheck
+	if rc == nil {
+		return nil
+	}
+
+	if rc.Port == nil || *rc.Port < 1 || *rc.Port > 65535 {
+		return fmt.Errorf("ReadinessCheck port must be between 1 and 65535")
+	}
+
+	if !strings.HasPrefix(rc.path(), "/") {
+		return fmt.Errorf("ReadinessCheck path must start with /")
+	}
+
+	if rc.expectedStatus() < 100 || rc.expectedStatus() > 599 {
+		return fmt.Errorf("ReadinessCheck expected_status must be a HTTP status code")
+	}
+
+	return nil
+}
+
+// setNotReady marks the healthy instances that do not pass the ReadinessCheck as pending
+func (service *Service) setNotReady(ec2c aws.EC2API, httpc aws.HTTPAPI, all aws.Instances) error {
+	rc := service.ReadinessCheck
+	if rc == nil {
+		return nil
+	}
+
+	healthyIDs := all.HealthyIDs()
+	if len(healthyIDs) == 0 {
+		return nil
+	}
+
+	ips, err := instance.PrivateIPs(ec2c, healthyIDs)
+	if err != nil {
+		return err
+	}
+
+	// Checked concurrently as an instance that does not respond waits for the client timeout
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	notReady := []string{}
+	for _, id := range healthyIDs {
+		ip, ok := ips[id]
+		if !ok {
+			notReady = append(notReady, id)
+			continue
+		}
+
+		wg.Add(1)
+		go func(id string, ip string) {
+			defer wg.Done()
+			if !rc.ready(httpc, ip) {
+				mu.Lock()
+				notReady = append(notReady, id)
+				mu.Unlock()
+			}
+		}(id, ip)
+	}
+	wg.Wait()
+
+	all.SetNotReady(notReady)
+
+	return nil
+}
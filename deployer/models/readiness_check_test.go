@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Service_validateReadinessCheck(t *testing.T) {
+	service := &Service{}
+	assert.NoError(t, service.validateReadinessCheck())
+
+	service.ReadinessCheck = &ReadinessCheck{Port: to.Int64p(8080)}
+	assert.NoError(t, service.validateReadinessCheck())
+	assert.Equal(t, "http://10.0.0.1:8080/ready", service.ReadinessCheck.url("10.0.0.1"))
+
+	service.ReadinessCheck = &ReadinessCheck{Port: to.Int64p(8080), Path: to.Strp("/health"), ExpectedStatus: to.Intp(204)}
+	assert.NoError(t, service.validateReadinessCheck())
+	assert.Equal(t, "http://10.0.0.1:8080/health", service.ReadinessCheck.url("10.0.0.1"))
+
+	service.ReadinessCheck = &ReadinessCheck{}
+	assert.Error(t, service.validateReadinessCheck())
+
+	service.ReadinessCheck = &ReadinessCheck{Port: to.Int64p(70000)}
+	assert.Error(t, service.validateReadinessCheck())
+
+	service.ReadinessCheck = &ReadinessCheck{Port: to.Int64p(8080), Path: to.Strp("ready")}
+	assert.Error(t, service.validateReadinessCheck())
+
+	service.ReadinessCheck = &ReadinessCheck{Port: to.Int64p(8080), ExpectedStatus: to.Intp(2000)}
+	assert.Error(t, service.validateReadinessCheck())
+}
+
+func Test_Release_UpdateHealthy_ReadinessCheck(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].ReadinessCheck = &ReadinessCheck{Port: to.Int64p(8080)}
+	MockPrepareRelease(r)
+
+	awsc := MockAwsClients(r)
+
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// Healthy in the ASG and load balancers but without a private IP
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, *r.Healthy)
+	assert.Equal(t, 1, *r.Services["web"].HealthReport.Pending)
+
+	// Not listening yet
+	awsc.EC2.SetPrivateIP("InstanceId1", "10.0.0.1")
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, *r.Healthy)
+	assert.Equal(t, []string{"http://10.0.0.1:8080/ready"}, awsc.HTTP.Requests)
+
+	awsc.HTTP.SetStatus("http://10.0.0.1:8080/ready", 503)
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, *r.Healthy)
+
+	awsc.HTTP.SetStatus("http://10.0.0.1:8080/ready", 200)
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, *r.Healthy)
+	assert.Equal(t, 0, *r.Services["web"].HealthReport.Pending)
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/coinbase/odin/aws"
 	"github.com/coinbase/step/aws/s3"
@@ -14,30 +15,171 @@ import (
 type Release struct {
 	bifrost.Release
 
+	// SchemaVersion is the version of the release format, releases without one are LegacySchemaVersion
+	SchemaVersion *int `json:"schema_version,omitempty"`
+
 	SafeRelease bool `json:"safe_release,omitempty"`
 
+	// If set the deployer assumes this role in the release account instead of coinbase-odin-assumed
+	DeployRoleARN *string `json:"deploy_role_arn,omitempty"`
+
+	// If set CleanUpSuccess keeps the ASGs of this many previous releases scaled to zero rather than deleting them
+	KeepPreviousReleases *int `json:"keep_previous_releases,omitempty"`
+
+	// Set by the Rollback state when it replaces this release with the release in the rollback plan
+	Rollback          bool    `json:"rollback,omitempty"`
+	RollbackReleaseID *string `json:"rollback_release_id,omitempty"`
+
+	// If set a release that only changes tags or autoscaling policies updates the live ASGs
+	InPlaceUpdates bool `json:"in_place_updates,omitempty"`
+	InPlace        bool `json:"in_place,omitempty"`
+
+	// DeployStrategy can be "BlueGreen"(default) | "InstanceRefresh", a refresh replaces the instances
+	// of the live ASGs keeping RefreshMinHealthyPercentage (default 90) of them in service
+	DeployStrategy              *string `json:"deploy_strategy,omitempty"`
+	RefreshMinHealthyPercentage *int    `json:"refresh_min_healthy_percentage,omitempty"`
+	Refreshed                   *bool   `json:"refreshed,omitempty"`
+
+	// If set the release stops with ValidationSuccess after ValidateResources, nothing is locked or deployed
+	ValidateOnly bool `json:"validate_only"`
+
+	// If set ValidateResources checks each services timings fit within the Timeout
+	ValidateTimeBudget bool `json:"validate_time_budget,omitempty"`
+
+	// If set ValidateResources fails unless this percentage of each previous ASGs desired capacity
+	// is healthy, so a deploy does not proceed over, and mask, an already degraded fleet
+	PreviousFleetHealthyPercent *int `json:"previous_fleet_healthy_percent,omitempty"`
+
+	// If set this Lambda function ARN is invoked with the release metadata after the lock is grabbed,
+	// the release is only deployed if it responds {"allow": true}
+	PreDeployHook *string `json:"pre_deploy_hook,omitempty"`
+
+	// If set this Lambda function ARN is invoked with the new fleet of every service once it is healthy,
+	// before the DNS cutover and while the old fleet still serves traffic, the release fails unless it responds {"passed": true}
+	SmokeTest *string `json:"smoke_test,omitempty"`
+
+	// LockBackend is where the project config lock is held "dynamodb"(default) | "s3"
+	LockBackend *string `json:"lock_backend,omitempty"`
+
+	// If set Lock also grabs a lock shared by every project config in the account with the same MutexGroup,
+	// e.g. configs that share a target group can never deploy at the same time
+	MutexGroup *string `json:"mutex_group,omitempty"`
+
+	// If set Lock also takes one of this many slots shared by every config of the project in the account,
+	// capping how many of them deploy at once. ProjectConcurrencySlot is the slot taken
+	ProjectConcurrency     *int `json:"project_concurrency,omitempty"`
+	ProjectConcurrencySlot *int `json:"project_concurrency_slot,omitempty"`
+
+	// If set a release with the same key that already succeeded is not deployed again, its result is returned
+	// and the release ends with ReplaySuccess. Replayed is true for such a release
+	IdempotencyKey *string `json:"idempotency_key,omitempty"`
+	Replayed       bool    `json:"replayed"`
+
+	// If set Lock takes over the project config lock when the execution holding it is no longer running
+	ForceUnlock bool `json:"force_unlock,omitempty"`
+
+	// If set a JSON notification is published when the deploy starts, is healthy or fails
+	NotificationTopicARN *string  `json:"notification_topic_arn,omitempty"`
+	ExecutionPath        []string `json:"execution_path,omitempty"`
+
+	// AlertFormat is the format of the published notifications "json"(default) | "text" | "slack".
+	// If set a failure is also posted as a Slack message to AlertWebhookURL, an https:// URL or ssm: parameter
+	AlertFormat     *string `json:"alert_format,omitempty"`
+	AlertWebhookURL *string `json:"alert_webhook_url,omitempty"`
+
 	Subnets []*string `json:"subnets,omitempty"`
 
+	// Tags are added to every services ASG, launch template and instances, a services tags override them
+	Tags map[string]*string `json:"tags,omitempty"`
+
 	Image *string `json:"ami,omitempty"`
 
 	userdata       *string // Not serialized
 	UserDataSHA256 *string `json:"user_data_sha256,omitempty"`
 
+	// If set the user data is rendered as a Go template of the release variables before its SHA is checked
+	UserDataTemplate bool `json:"user_data_template,omitempty"`
+
 	// LifeCycleHooks
 	LifeCycleHooks map[string]*LifeCycleHook `json:"lifecycle,omitempty"`
 
 	// Maintain a Log to look at what has happened
 	Healthy *bool `json:"healthy,omitempty"`
 
+	// Canaried is false until every services canary is baked, Deploy sets it
+	Canaried *bool `json:"canaried,omitempty"`
+
 	WaitForHealthy *int `json:"wait_for_healthy,omitempty"`
 
+	// HealthPollInterval is the seconds between the first health checks, doubled after each unhealthy
+	// check up to HealthPollMaxInterval. Without either health checks are on a fixed interval
+	HealthPollInterval    *int `json:"health_poll_interval,omitempty"`
+	HealthPollMaxInterval *int `json:"health_poll_max_interval,omitempty"`
+	HealthPolls           *int `json:"health_polls,omitempty"`
+
+	// DeployTimeout is the seconds to reach desired capacity, HealthyTimeout the seconds after for
+	// instances to pass health checks. A phase not set is given what the other leaves of the Timeout
+	DeployTimeout     *int       `json:"deploy_timeout,omitempty"`
+	HealthyTimeout    *int       `json:"healthy_timeout,omitempty"`
+	WaitForDeploy     *int       `json:"wait_for_deploy,omitempty"`
+	CapacityReachedAt *time.Time `json:"capacity_reached_at,omitempty"`
+
+	// LaunchExtensionMax is the most seconds the Timeout and its phases are extended while instances are
+	// still launching and reaching InService. LaunchExtension is the seconds extended so far
+	LaunchExtensionMax *int       `json:"launch_extension_max,omitempty"`
+	LaunchExtension    *int       `json:"launch_extension,omitempty"`
+	LaunchProgressAt   *time.Time `json:"launch_progress_at,omitempty"`
+
+	// DeployedAt is when Deploy created the resources and the release started waiting for them to be healthy
+	DeployedAt *time.Time `json:"deployed_at,omitempty"`
+
+	// StaggerHealthChecks offsets the start of each services health checks to smooth AWS API calls
+	StaggerHealthChecks  bool       `json:"stagger_health_checks,omitempty"`
+	HealthCheckStartedAt *time.Time `json:"health_check_started_at,omitempty"`
+
+	// MaxSecurityGroupsPerENI is the accounts limit of security groups per network interface, default 5
+	MaxSecurityGroupsPerENI *int `json:"max_security_groups_per_eni,omitempty"`
+
+	// MaxRulesPerListener is the accounts limit of rules per ALB listener, not counting the default rule, default 100
+	MaxRulesPerListener *int `json:"max_rules_per_listener,omitempty"`
+
 	// AWS Service is Downloaded
 	Services map[string]*Service `json:"services,omitempty"` // Downloaded From S3
 
+	// Result is what a successful release deployed, also written to DeployResultPath
+	Result *DeployResult `json:"result,omitempty"`
+
+	// FailureReport is what a failed release ran, its error and what was cleaned up, also written to FailureReportPath
+	FailureReport *FailureReport  `json:"failure_report,omitempty"`
+	cleanedUp     *FailureCleanUp // Not serialized
+
+	// MaxParallelServices limits how many services are deployed and health checked at once, default unlimited
+	MaxParallelServices *int `json:"max_parallel_services,omitempty"`
+
+	// DNS moves traffic with a weighted Route53 record after the release is healthy
+	DNS *DNS `json:"dns,omitempty"`
+
+	// RequiredProfilePolicies are managed policy ARNs every services instance profile must have attached
+	RequiredProfilePolicies []*string `json:"required_profile_policies,omitempty"`
+
 	// DetachStrategy can be "Detach"(default) | "SkipDetach" || "SkipDetachCheck"
 	DetachStrategy *string `json:"detach_strategy,omitempty"`
 
 	WaitForDetach *int `json:"wait_for_detach,omitempty"`
+
+	// WaitForDrain is the seconds cleanup waits after detaching for instances to deregister
+	WaitForDrain *int `json:"wait_for_drain,omitempty"`
+
+	// DrainFirst makes cleanup wait until the previous instances finish draining from their target groups,
+	// then scale the previous ASGs to zero before deleting them
+	DrainFirst bool `json:"drain_first,omitempty"`
+
+	// Soak watches SoakAlarms for SoakDuration seconds after the release is healthy
+	// before the previous release is removed, an alarm rolls back the release
+	SoakDuration  *int       `json:"soak_duration,omitempty"`
+	SoakAlarms    []*string  `json:"soak_alarms,omitempty"`
+	SoakStartedAt *time.Time `json:"soak_started_at,omitempty"`
+	Soaked        *bool      `json:"soaked,omitempty"`
 }
 
 //////////
@@ -54,14 +196,72 @@ func (release *Release) UserDataPath() *string {
 // Setters
 //////////
 
+// WipeControlledValues wipes values that are controlled by the deployer
+func (release *Release) WipeControlledValues() {
+	release.Release.WipeControlledValues()
+	release.SoakStartedAt = nil
+	release.HealthCheckStartedAt = nil
+	release.CapacityReachedAt = nil
+	release.LaunchExtension = nil
+	release.LaunchProgressAt = nil
+	release.HealthPolls = nil
+	release.Result = nil
+	release.FailureReport = nil
+	release.DeployedAt = nil
+	release.Soaked = nil
+	release.Canaried = nil
+	release.Refreshed = nil
+	release.InPlace = false
+	release.Replayed = false
+	release.Rollback = false
+	release.RollbackReleaseID = nil
+	release.ExecutionPath = nil
+
+	if release.DNS != nil {
+		release.DNS.WipeControlledValues()
+	}
+
+	for _, service := range release.Services {
+		if service == nil {
+			continue
+		}
+
+		service.SpotInterruptedIDs = nil
+		service.LaunchDurations = nil
+		service.LaunchBatchAt = nil
+		service.CreatedLaunchTemplateVersion = nil
+		service.RefreshID = nil
+		service.RefreshStatus = nil
+		service.PreviousLaunchConfiguration = nil
+		service.PreviousInPlace = nil
+		service.HealthCheckOffset = nil
+
+		if service.Canary != nil {
+			service.Canary.WipeControlledValues()
+		}
+
+		if service.ListenerRule != nil {
+			service.ListenerRule.WipeControlledValues()
+		}
+	}
+}
+
 // SetDefaultsWithUserData sets the default values including userdata fetched from S3
-func (release *Release) SetDefaultsWithUserData(s3c aws.S3API) error {
+func (release *Release) SetDefaultsWithUserData(s3c aws.S3API, kmsc aws.KMSAPI) error {
 	release.SetDefaults()
 	err := release.DownloadUserData(s3c)
 	if err != nil {
 		return err
 	}
 
+	if err := release.decryptUserData(kmsc); err != nil {
+		return err
+	}
+
+	if err := release.renderUserData(); err != nil {
+		return err
+	}
+
 	for _, service := range release.Services {
 		if service != nil {
 			service.SetUserData(release.UserData())
@@ -76,25 +276,40 @@ func (release *Release) SetDefaults() {
 	// Overwrite WaitForHealthy to be Min 15 seconds, Max 5 minutes
 	waitForHealthy := 120
 
+	if release.SchemaVersion == nil {
+		release.SchemaVersion = to.Intp(LegacySchemaVersion)
+	}
+
 	if release.Timeout == nil {
 		release.Timeout = to.Intp(600)
 	}
 
+	release.WaitForDeploy = to.Intp(release.waitForDeploy())
+
 	switch {
-	case *release.Timeout < 1800:
+	case release.healthyTimeout() < 1800:
 		// Under 30 mins check every 15 seconds
 		waitForHealthy = 15
-	case *release.Timeout < 7200:
+	case release.healthyTimeout() < 7200:
 		// Under 2 hour check every 60 seconds
 		waitForHealthy = 60
 	}
 
+	if release.backoffHealthPolls() {
+		// CheckHealthy backs off from the first interval
+		waitForHealthy = release.healthPollInterval()
+	}
+
 	release.WaitForHealthy = to.Intp(waitForHealthy)
 
 	if release.WaitForDetach == nil {
 		release.WaitForDetach = to.Intp(0)
 	}
 
+	if release.WaitForDrain == nil {
+		release.WaitForDrain = to.Intp(0)
+	}
+
 	if release.Healthy == nil {
 		release.Healthy = to.Boolp(false)
 	}
@@ -107,6 +322,14 @@ func (release *Release) SetDefaults() {
 		release.DetachStrategy = to.Strp("Detach")
 	}
 
+	if release.DeployStrategy == nil {
+		release.DeployStrategy = to.Strp(DeployBlueGreen)
+	}
+
+	if release.DNS != nil {
+		release.DNS.SetDefaults()
+	}
+
 	for name, lc := range release.LifeCycleHooks {
 		if lc != nil {
 			lc.SetDefaults(release.AwsRegion, release.AwsAccountID, name)
@@ -125,7 +348,12 @@ func (release *Release) SetDefaults() {
 //////////
 
 // Validate returns
-func (release *Release) Validate(s3c aws.S3API) error {
+func (release *Release) Validate(s3c aws.S3API, kmsc aws.KMSAPI) error {
+	// A release in an unsupported format fails before its other fields are read
+	if err := release.ValidateSchemaVersion(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
 	if err := release.Release.Validate(s3c, &Release{}); err != nil {
 		return err
 	}
@@ -136,12 +364,29 @@ func (release *Release) Validate(s3c aws.S3API) error {
 		return fmt.Errorf("%v Max timeout is 172800 (48 hours)", release.ErrorPrefix())
 	}
 
-	if (5.0/float64(*release.WaitForHealthy))*(float64(*release.Timeout)) > 10000.0 {
-		// There are 5 state transitions per health check
-		// (5/WaitForHealthy) * Timeout is about equal to the max state transistions
+	waitForHealthy := release.healthPollWait()
+	if (6.0/float64(waitForHealthy))*(float64(*release.Timeout)) > 10000.0 {
+		// There are 6 state transitions per health check, including the Canaried? choice
+		// (6/WaitForHealthy) * Timeout is about equal to the max state transistions
 		// Due to limitations on StepFucntions History Events the max state transistions is about 10k
-		// So (5/WaitForHealthy) * Timeout < 10k as a rule of thumb
-		return fmt.Errorf("%v Rule of Thumb (5/WaitForHealthy) * Timeout < 10k", release.ErrorPrefix())
+		// So (6/WaitForHealthy) * Timeout < 10k as a rule of thumb
+		return fmt.Errorf("%v Rule of Thumb (6/WaitForHealthy) * Timeout < 10k", release.ErrorPrefix())
+	}
+
+	if err := release.ValidateDeployRoleARN(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidatePhaseTimeouts(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateLaunchExtension(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateHealthPolls(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
 	}
 
 	// DetachStrategy
@@ -155,11 +400,87 @@ func (release *Release) Validate(s3c aws.S3API) error {
 		return fmt.Errorf("%v %v", release.ErrorPrefix(), "DetachStrategy must be either 'Detach', 'SkipDetach', 'SkipDetachCheck'")
 	}
 
+	if err := release.ValidateDrainFirst(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidatePreviousFleetHealthyPercent(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateLockBackend(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateMutexGroup(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateProjectConcurrency(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateIdempotencyKey(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateKeepPreviousReleases(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateNotificationTopic(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateAlerts(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateMaxParallelServices(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateMaxSecurityGroupsPerENI(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateMaxRulesPerListener(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateRequiredProfilePolicies(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateDNS(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateSoak(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidatePreDeployHook(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateSmokeTest(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateDeployStrategy(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
 	if release.Image == nil {
 		return fmt.Errorf("%v %v", release.ErrorPrefix(), "AMI image must be provided")
 	}
 
-	if err := release.ValidateUserDataSHA(s3c); err != nil {
+	if err := release.ValidateUserDataSHA(s3c, kmsc); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateUserDataSize(); err != nil {
 		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
 	}
 
@@ -167,19 +488,34 @@ func (release *Release) Validate(s3c aws.S3API) error {
 		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
 	}
 
+	if err := release.ValidatePolicyDocument(s3c); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
 	return nil
 }
 
 // ValidateUserDataSHA validates the userdata has the correct SHA for the release
-func (release *Release) ValidateUserDataSHA(s3c aws.S3API) error {
+// KMS encrypted user data is decrypted first, so the SHA is of the plaintext
+func (release *Release) ValidateUserDataSHA(s3c aws.S3API, kmsc aws.KMSAPI) error {
 	if is.EmptyStr(release.UserDataSHA256) {
 		return fmt.Errorf("UserDataSHA256 must be defined")
 	}
 
-	err := release.DownloadUserData(s3c)
+	// A rollback release has the previous user data, it is uploaded once the release holds the lock
+	if !release.Rollback {
+		if err := release.DownloadUserData(s3c); err != nil {
+			return fmt.Errorf("Error Getting UserData with %v", err.Error())
+		}
+	}
 
-	if err != nil {
-		return fmt.Errorf("Error Getting UserData with %v", err.Error())
+	if err := release.decryptUserData(kmsc); err != nil {
+		return err
+	}
+
+	// The SHA is of the rendered template, or of the user data as is if it is not a template
+	if err := release.renderUserData(); err != nil {
+		return err
 	}
 
 	userdataSha := to.SHA256Str(release.UserData())
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/step/aws/s3"
+)
+
+//////////
+// In Place Updates
+//////////
+
+// InPlacePrevious is what the live ASG had before it was updated in place, restored if the release fails
+type InPlacePrevious struct {
+	ServiceID *string            `json:"service_id,omitempty"` // Names the previous policies
+	Tags      map[string]*string `json:"tags,omitempty"`
+	Policies  []*Policy          `json:"policies,omitempty"`
+}
+
+// DetectInPlace sets InPlace if every service only changed its tags or autoscaling policies
+// compared to the currently deployed release. These changes are applied to the live ASGs
+// instead of replacing them with new ASGs
+func (release *Release) DetectInPlace(s3c aws.S3API, resources *ReleaseResources) error {
+	release.InPlace = false
+
+	if !release.InPlaceUpdates || len(resources.PreviousASGs) == 0 {
+		return nil
+	}
+
+	for name := range release.Services {
+		if resources.PreviousASGs[name] == nil {
+			// New services need a new ASG
+			return nil
+		}
+	}
+
+	previousRelease, err := release.fetchPreviousRelease(s3c, resources)
+	if err != nil {
+		switch err.(type) {
+		case *s3.NotFoundError:
+			// Without the previous release the diff is unknown so replace everything
+			return nil
+		default:
+			return err // All other errors return
+		}
+	}
+
+	updates, replacements := release.inPlaceDiff(previousRelease)
+	release.InPlace = len(updates) > 0 && len(replacements) == 0
+
+	if !release.InPlace {
+		return nil
+	}
+
+	// Recorded before anything is changed so a failed update can be reverted
+	for name, service := range release.Services {
+		prevService := previousRelease.Services[name]
+		service.PreviousInPlace = &InPlacePrevious{
+			ServiceID: prevService.ServiceID(),
+			Tags:      resources.PreviousASGs[name].Tags(),
+			Policies:  prevService.Autoscaling.Policies,
+		}
+	}
+
+	return nil
+}
+
+// RevertInPlace restores the policies and tags of every live ASG updated in place
+// It can be called again if it fails part way through
+func (release *Release) RevertInPlace(asgc aws.ASGAPI, cwc aws.CWAPI) error {
+	if !release.InPlace {
+		return nil
+	}
+
+	for _, name := range sortedServiceNames(release) {
+		if err := release.Services[name].RevertInPlace(asgc, cwc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RevertInPlace recreates the previous autoscaling policies and restores the previous tags of the live ASG
+// Restoring the ReleaseID tag moves the ASG back to the previous release
+func (service *Service) RevertInPlace(asgc aws.ASGAPI, cwc aws.CWAPI) error {
+	previous := service.PreviousInPlace
+	if previous == nil {
+		return nil
+	}
+
+	_, group, err := asg.GetInstances(asgc, service.CreatedASG)
+	if err != nil {
+		return err
+	}
+
+	if err := group.TeardownPolicies(asgc, cwc); err != nil {
+		return err
+	}
+
+	for _, policy := range previous.Policies {
+		policy.SetDefaults(previous.ServiceID)
+		if err := policy.Create(asgc, cwc, service.CreatedASG); err != nil {
+			return err
+		}
+	}
+
+	// Tags with the aws: prefix are reserved so are never changed
+	tags := map[string]*string{}
+	for key, value := range previous.Tags {
+		if !strings.HasPrefix(key, "aws:") {
+			tags[key] = value
+		}
+	}
+
+	removed := []string{}
+	for key := range group.Tags() {
+		if _, ok := tags[key]; ok || strings.HasPrefix(key, "aws:") {
+			continue
+		}
+		removed = append(removed, key)
+	}
+
+	sort.Strings(removed)
+
+	return group.UpdateTags(asgc, tags, removed)
+}
+
+// inPlaceDiff returns the changes that can be updated in place and
+// the changes that require replacing the ASGs
+func (release *Release) inPlaceDiff(previousRelease *Release) ([]string, []string) {
+	updates := []string{}
+	replacements := []string{}
+
+	if diffJSON(release.Image, previousRelease.Image) {
+		replacements = append(replacements, "ami")
+	}
+
+	if diffJSON(release.UserDataSHA256, previousRelease.UserDataSHA256) {
+		replacements = append(replacements, "user_data")
+	}
+
+	if res := safeUnorderedStrList(release.Subnets, previousRelease.Subnets); res != nil {
+		replacements = append(replacements, "subnets")
+	}
+
+	if diffJSON(release.LifeCycleHooks, previousRelease.LifeCycleHooks) {
+		replacements = append(replacements, "lifecycle")
+	}
+
+	if res := safeUnorderedStrList(serviceMapKeys(release.Services), serviceMapKeys(previousRelease.Services)); res != nil {
+		replacements = append(replacements, "services")
+	}
+
+	if diffJSON(release.Tags, previousRelease.Tags) {
+		updates = append(updates, "tags")
+	}
+
+	for _, name := range sortedServiceNames(release) {
+		service := release.Services[name]
+		prevService := previousRelease.Services[name]
+		if prevService == nil {
+			continue // Caught by services
+		}
+
+		if service.launchDefinition() != prevService.launchDefinition() {
+			replacements = append(replacements, fmt.Sprintf("%v.launch", name))
+		}
+
+		if diffJSON(service.Tags, prevService.Tags) {
+			updates = append(updates, fmt.Sprintf("%v.tags", name))
+		}
+
+		if diffJSON(service.Autoscaling.Policies, prevService.Autoscaling.Policies) {
+			updates = append(updates, fmt.Sprintf("%v.policies", name))
+		}
+	}
+
+	return updates, replacements
+}
+
+func diffJSON(a interface{}, b interface{}) bool {
+	rawA, _ := json.Marshal(a)
+	rawB, _ := json.Marshal(b)
+	return string(rawA) != string(rawB)
+}
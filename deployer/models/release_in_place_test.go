@@ -0,0 +1,214 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockInPlaceRelease(t *testing.T, change func(*Release)) (*Release, *mocks.MockClients, *ReleaseResources) {
+	release := MockRelease(t)
+	release.InPlaceUpdates = true
+	change(release)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+
+	previousRelease := MockRelease(t)
+	previousRelease.ReleaseID = to.Strp("old-release")
+	AddReleaseS3Objects(awsc, previousRelease)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	assert.NoError(t, release.DetectInPlace(awsc.S3, resources))
+
+	return release, awsc, resources
+}
+
+func Test_Release_DetectInPlace_TagsOnly(t *testing.T) {
+	release, awsc, resources := mockInPlaceRelease(t, func(r *Release) {
+		r.Services["web"].Tags["custom"] = to.Strp("changed")
+	})
+
+	assert.True(t, release.InPlace)
+
+	release.UpdateWithResources(resources)
+	assert.Equal(t, "project-config-web-old-release", to.Strs(release.Services["web"].CreatedASG))
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	assert.Equal(t, 1, len(awsc.ASG.CreateOrUpdateTagsInputs))
+	tags := map[string]string{}
+	for _, tag := range awsc.ASG.CreateOrUpdateTagsInputs[0].Tags {
+		assert.Equal(t, "project-config-web-old-release", to.Strs(tag.ResourceId))
+		tags[*tag.Key] = to.Strs(tag.Value)
+	}
+
+	assert.Equal(t, "changed", tags["custom"])
+	assert.Equal(t, "1", tags["ReleaseID"])
+	assert.Equal(t, 0, len(awsc.ASG.DeleteTagsInputs))
+}
+
+func Test_Release_DetectInPlace_ReleaseTags(t *testing.T) {
+	release, awsc, resources := mockInPlaceRelease(t, func(r *Release) {
+		r.Tags = map[string]*string{"team": to.Strp("deploy")}
+	})
+
+	assert.True(t, release.InPlace)
+
+	release.UpdateWithResources(resources)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	tags := map[string]string{}
+	for _, tag := range awsc.ASG.CreateOrUpdateTagsInputs[0].Tags {
+		tags[*tag.Key] = to.Strs(tag.Value)
+	}
+
+	assert.Equal(t, "deploy", tags["team"])
+	assert.Equal(t, "tag", tags["custom"])
+}
+
+func Test_Release_DetectInPlace_TagsRemoved(t *testing.T) {
+	release, awsc, resources := mockInPlaceRelease(t, func(r *Release) {
+		r.Services["web"].Tags = map[string]*string{"other": to.Strp("tag")}
+	})
+
+	assert.True(t, release.InPlace)
+
+	// Tag on the live ASG that is no longer in the release
+	awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0].Tags = append(
+		awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0].Tags,
+		&autoscaling.TagDescription{Key: to.Strp("custom"), Value: to.Strp("tag")},
+	)
+
+	release.UpdateWithResources(resources)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	assert.Equal(t, 1, len(awsc.ASG.DeleteTagsInputs))
+	assert.Equal(t, 1, len(awsc.ASG.DeleteTagsInputs[0].Tags))
+	assert.Equal(t, "custom", *awsc.ASG.DeleteTagsInputs[0].Tags[0].Key)
+}
+
+func Test_Release_DetectInPlace_AlarmsOnly(t *testing.T) {
+	release, awsc, resources := mockInPlaceRelease(t, func(r *Release) {
+		r.Services["web"].Autoscaling.Policies[0].ThresholdVal = to.Float64p(50)
+	})
+
+	assert.True(t, release.InPlace)
+
+	release.UpdateWithResources(resources)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// Previous policy is deleted and both policies recreated on the live ASG
+	assert.Equal(t, 1, len(awsc.ASG.DeletePolicyInputs))
+	assert.Equal(t, "VeryEmbeddedPolicy", *awsc.ASG.DeletePolicyInputs[0].PolicyName)
+
+	assert.Equal(t, 2, len(awsc.ASG.PutScalingPolicyInputs))
+	for _, input := range awsc.ASG.PutScalingPolicyInputs {
+		assert.Equal(t, "project-config-web-old-release", *input.AutoScalingGroupName)
+	}
+
+	// The live ASG must survive a failure
+	assert.NoError(t, release.UnsuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+}
+
+func Test_Release_DetectInPlace_FullReplacement(t *testing.T) {
+	release, _, _ := mockInPlaceRelease(t, func(r *Release) {
+		r.Services["web"].Tags["custom"] = to.Strp("changed")
+		r.Services["web"].InstanceType = to.Strp("c5.large")
+	})
+
+	assert.False(t, release.InPlace)
+
+	// Nothing changed is a normal release
+	release, _, _ = mockInPlaceRelease(t, func(r *Release) {})
+	assert.False(t, release.InPlace)
+
+	// Not enabled
+	release, _, _ = mockInPlaceRelease(t, func(r *Release) {
+		r.InPlaceUpdates = false
+		r.Services["web"].Tags["custom"] = to.Strp("changed")
+	})
+	assert.False(t, release.InPlace)
+}
+
+func Test_Release_inPlaceDiff(t *testing.T) {
+	release := MockRelease(t)
+	previousRelease := MockRelease(t)
+	release.SetDefaults()
+	previousRelease.SetDefaults()
+
+	release.Services["web"].Tags["custom"] = to.Strp("changed")
+	release.Services["web"].Autoscaling.Policies = nil
+	release.Services["web"].EBSVolumeSize = to.Int64p(200)
+	release.Image = to.Strp("other")
+
+	updates, replacements := release.inPlaceDiff(previousRelease)
+	assert.Equal(t, []string{"web.tags", "web.policies"}, updates)
+	assert.Equal(t, []string{"ami", "web.launch"}, replacements)
+}
+
+func Test_Release_RevertInPlace(t *testing.T) {
+	release, awsc, resources := mockInPlaceRelease(t, func(r *Release) {
+		r.Services["web"].Tags["custom"] = to.Strp("changed")
+		r.Services["web"].Autoscaling.Policies[0].ThresholdVal = to.Float64p(50)
+	})
+
+	assert.True(t, release.InPlace)
+
+	previous := release.Services["web"].PreviousInPlace
+	assert.Equal(t, "old-release", to.Strs(previous.Tags["ReleaseID"]))
+	assert.Equal(t, 2, len(previous.Policies))
+
+	release.UpdateWithResources(resources)
+
+	// The policies are torn down then the new policies fail to be created
+	awsc.ASG.AddThrottles("PutScalingPolicy", 1)
+	assert.Error(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.Equal(t, 1, len(awsc.ASG.DeletePolicyInputs))
+	assert.Equal(t, 0, len(awsc.ASG.CreateOrUpdateTagsInputs))
+
+	assert.NoError(t, release.UnsuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+
+	// The previous policies are recreated on the live ASG
+	assert.Equal(t, 2, len(awsc.ASG.PutScalingPolicyInputs))
+	for _, input := range awsc.ASG.PutScalingPolicyInputs {
+		assert.Equal(t, "project-config-web-old-release", *input.AutoScalingGroupName)
+	}
+
+	// The live ASG is moved back to the previous release
+	assert.Equal(t, 1, len(awsc.ASG.CreateOrUpdateTagsInputs))
+	tags := map[string]string{}
+	for _, tag := range awsc.ASG.CreateOrUpdateTagsInputs[0].Tags {
+		tags[*tag.Key] = to.Strs(tag.Value)
+	}
+
+	assert.Equal(t, "old-release", tags["ReleaseID"])
+	assert.NotEqual(t, "changed", tags["custom"])
+}
+
+func Test_Release_RevertInPlace_Tags_Added(t *testing.T) {
+	release, awsc, resources := mockInPlaceRelease(t, func(r *Release) {
+		r.Services["web"].Tags["added"] = to.Strp("tag")
+	})
+
+	assert.True(t, release.InPlace)
+
+	release.UpdateWithResources(resources)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// The update tagged the live ASG
+	awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0].Tags = append(
+		awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0].Tags,
+		&autoscaling.TagDescription{Key: to.Strp("added"), Value: to.Strp("tag")},
+	)
+
+	assert.NoError(t, release.RevertInPlace(awsc.ASG, awsc.CW))
+
+	assert.Equal(t, 1, len(awsc.ASG.DeleteTagsInputs))
+	assert.Equal(t, "added", *awsc.ASG.DeleteTagsInputs[0].Tags[0].Key)
+}
@@ -14,4 +14,8 @@ func Test_Parsing_Errors(t *testing.T) {
 	assert.NoError(t, json.Unmarshal([]byte(`{"release_id" : "1"}`), &r))
 
 	assert.Error(t, json.Unmarshal([]byte(`{"release_ids" : "1"}`), &r))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"subnets" : "private-subnet"}`), &r))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"services" : {"web" : {"availability_zones" : "us-east-1a"}}}`), &r))
 }
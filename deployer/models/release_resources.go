@@ -2,10 +2,15 @@ package models
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/coinbase/odin/aws"
 	"github.com/coinbase/odin/aws/ami"
 	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/odin/aws/lc"
+	"github.com/coinbase/odin/aws/lt"
 	"github.com/coinbase/odin/aws/subnet"
+	"github.com/coinbase/step/utils/to"
 )
 
 type ReleaseResources struct {
@@ -61,6 +66,14 @@ func (release *Release) FetchResources(asgc aws.ASGAPI, ec2 aws.EC2API, elbc aws
 		}
 	}
 
+	for _, service := range release.Services {
+		for _, lc := range service.LifeCycleHooksVal {
+			if err := lc.FetchResources(iamc, snsc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	for _, prevASG := range resources.PreviousASGs {
 		// This grabs the first previous ASGs release ID
 		resources.PreviousReleaseID = prevASG.ReleaseID()
@@ -68,6 +81,7 @@ func (release *Release) FetchResources(asgc aws.ASGAPI, ec2 aws.EC2API, elbc aws
 	}
 
 	slowStartDuration := 0
+	drainDuration := 0
 	for name, service := range release.Services {
 		sr, err := service.FetchResources(ec2, elbc, albc, iamc)
 		if err != nil {
@@ -83,20 +97,55 @@ func (release *Release) FetchResources(asgc aws.ASGAPI, ec2 aws.EC2API, elbc aws
 			}
 		}
 
-		sr.Subnets = subnets
+		sr.Subnets = service.selectSubnets(service.filterSubnets(subnets))
+
+		if err := service.validateInstanceTypeOfferings(ec2, sr.Subnets); err != nil {
+			return nil, err
+		}
 		sr.Image = im
 		sr.PrevASG = resources.PreviousASGs[name]
 
+		if drain := service.drainDuration(sr); drain > drainDuration {
+			drainDuration = drain
+		}
+
 		resources.ServiceResources[name] = sr
 	}
 
+	if err := release.validateASGAccountLimits(asgc); err != nil {
+		return nil, err
+	}
+
 	release.WaitForDetach = &slowStartDuration
 
+	if release.IsSkipDetachStep() {
+		// Instances are never deregistered so there is nothing to drain
+		drainDuration = 0
+	}
+
+	if len(resources.PreviousASGs) == 0 {
+		// The first release of a project config has no previous instances to drain
+		drainDuration = 0
+	}
+	release.WaitForDrain = &drainDuration
+
 	return &resources, nil
 }
 
 // ValidateResources returns
 func (release *Release) ValidateResources(resources *ReleaseResources) error {
+	if err := release.ValidateTags(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.validateRefreshResources(resources); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
+	if err := release.ValidateDependsOn(); err != nil {
+		return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+	}
+
 	// Fetch Service
 	for name, service := range release.Services {
 		sr := resources.ServiceResources[name]
@@ -106,7 +155,49 @@ func (release *Release) ValidateResources(resources *ReleaseResources) error {
 		if err := sr.Validate(service); err != nil {
 			return err
 		}
+
+		if err := release.validatePreviousFleetHealthy(service, sr); err != nil {
+			return err
+		}
+
+		if release.ValidateTimeBudget {
+			if err := release.validateTimeBudget(service, sr); err != nil {
+				return err
+			}
+		}
 	}
+
+	if release.DNS != nil {
+		if _, err := release.dnsTarget(resources); err != nil {
+			return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+		}
+	}
+
+	return nil
+}
+
+// validateTimeBudget checks the health check grace period, target group drain and soak
+// can all complete before the release times out
+func (release *Release) validateTimeBudget(service *Service, sr *ServiceResources) error {
+	grace := 0
+	if service.Autoscaling != nil && service.Autoscaling.HealthCheckGracePeriod != nil {
+		grace = int(*service.Autoscaling.HealthCheckGracePeriod)
+	}
+
+	drain := service.drainDuration(sr)
+
+	soak := 0
+	if release.SoakDuration != nil {
+		soak = *release.SoakDuration
+	}
+
+	if total := grace + drain + soak; total > *release.Timeout {
+		return fmt.Errorf(
+			"%v Service(%v) time budget health_check_grace_period %v + deregistration_delay %v + soak_duration %v = %v is greater than timeout %v",
+			release.ErrorPrefix(), *service.ServiceName, grace, drain, soak, total, *release.Timeout,
+		)
+	}
+
 	return nil
 }
 
@@ -122,9 +213,23 @@ func (release *Release) UpdateWithResources(resources *ReleaseResources) {
 		}
 		if sr.PrevASG != nil {
 			service.PreviousDesiredCapacity = sr.PrevASG.DesiredCapacity
+
+			if release.InPlace || release.IsInstanceRefresh() {
+				// Deploy updates the previous ASG instead of creating one
+				service.CreatedASG = sr.PrevASG.AutoScalingGroupName
+			}
+
+			if release.IsInstanceRefresh() {
+				service.PreviousLaunchConfiguration = sr.PrevASG.LaunchConfigurationName
+			}
 		}
 
 		service.Resources = sr.ToServiceResourceNames()
+		service.updateListenerRule(sr)
+	}
+
+	if release.DNS != nil {
+		release.DNS.Target, _ = release.dnsTarget(resources)
 	}
 }
 
@@ -133,35 +238,72 @@ func (release *Release) UpdateWithResources(resources *ReleaseResources) {
 //////////
 
 // CreateResources returns
-func (release *Release) CreateResources(asgc aws.ASGAPI, cwc aws.CWAPI) error {
-	for _, service := range release.Services {
-		err := service.CreateResources(asgc, cwc)
-		if err != nil {
-			return err
+func (release *Release) CreateResources(asgc aws.ASGAPI, ec2c aws.EC2API, cwc aws.CWAPI, albc aws.ALBAPI) error {
+	// Services are created in parallel, a failure still creates the other services
+	// so that CleanUpFailure removes every new ASG
+	return release.forEachService(func(service *Service) error {
+		if release.InPlace {
+			return service.UpdateInPlace(asgc, cwc)
 		}
-	}
 
-	return nil
+		if release.IsInstanceRefresh() {
+			return service.StartRefresh(asgc)
+		}
+
+		if service.waitingOnDependencies() {
+			// Created by CheckHealthy once its dependencies are healthy
+			return nil
+		}
+
+		return service.CreateResources(asgc, ec2c, cwc, albc)
+	})
 }
 
 //////////
 // Healthy Resources
 //////////
 
-// UpdateHealthy will try set the Healthy attribute
+// UpdateHealthy will try set the Healthy attribute, creating the services waiting on dependencies that became healthy
 // First Error is a Halting Error, Second Error is a Retry Error
-func (release *Release) UpdateHealthy(asgc aws.ASGAPI, elbc aws.ELBAPI, albc aws.ALBAPI) error {
+func (release *Release) UpdateHealthy(asgc aws.ASGAPI, ec2c aws.EC2API, elbc aws.ELBAPI, albc aws.ALBAPI, cwc aws.CWAPI, httpc aws.HTTPAPI) error {
 	healthy := true
 
-	for _, service := range release.Services {
+	if release.HealthCheckStartedAt == nil {
+		release.HealthCheckStartedAt = to.Timep(time.Now())
+	}
 
-		if err := service.UpdateHealthy(asgc, elbc, albc); err != nil {
-			return err
+	err := release.forEachService(func(service *Service) error {
+		if !service.healthCheckStarted(release.HealthCheckStartedAt) {
+			// Staggered service is not checked yet
+			service.Healthy = false
+			return nil
 		}
 
+		if service.waitingOnDependencies() {
+			service.Healthy = false
+			return nil
+		}
+
+		return service.UpdateHealthy(asgc, ec2c, elbc, albc, cwc, httpc)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := release.createDependentServices(asgc, ec2c, cwc, albc); err != nil {
+		return err
+	}
+
+	release.extendForLaunches()
+	release.extendForGrace()
+
+	for _, service := range release.Services {
 		healthy = healthy && service.Healthy // Healthy if all services are healthy
 	}
 
+	release.updateCapacityReachedAt()
+
 	release.Healthy = &healthy
 
 	return nil
@@ -194,6 +336,14 @@ func (release *Release) DetachForSuccess(asgc aws.ASGAPI) error {
 		return err
 	}
 
+	// The refreshed ASGs are still tagged with the previous release but must not be detached
+	asgs = release.withoutRefreshedASGs(asgs)
+
+	if len(asgs) == 0 {
+		// The first release of a project config has no previous ASGs to detach
+		return nil
+	}
+
 	// Validate Correct ASG
 	for _, asg := range asgs {
 		if err := release.validSuccessASG(asg); err != nil {
@@ -209,7 +359,14 @@ func (release *Release) DetachForSuccess(asgc aws.ASGAPI) error {
 }
 
 // SuccessfulTearDown returns
-func (release *Release) SuccessfulTearDown(asgc aws.ASGAPI, cwc aws.CWAPI) error {
+func (release *Release) SuccessfulTearDown(asgc aws.ASGAPI, ec2c aws.EC2API, cwc aws.CWAPI) error {
+	if release.IsInstanceRefresh() {
+		// The refreshed ASGs must be moved to this release before the previous releases ASGs are deleted
+		if err := release.PromoteRefreshed(asgc); err != nil {
+			return err
+		}
+	}
+
 	// Tear down all resources in NOT in this release
 	asgs, err := asg.ForProjectConfigNOTReleaseID(asgc, release.ProjectName, release.ConfigName, release.ReleaseID)
 
@@ -217,6 +374,9 @@ func (release *Release) SuccessfulTearDown(asgc aws.ASGAPI, cwc aws.CWAPI) error
 		return err
 	}
 
+	// The refreshed ASGs are kept even if their new tags are not yet listed
+	asgs = release.withoutRefreshedASGs(asgs)
+
 	// Validate Correct ASG
 	for _, asg := range asgs {
 		if err := release.validSuccessASG(asg); err != nil {
@@ -224,14 +384,39 @@ func (release *Release) SuccessfulTearDown(asgc aws.ASGAPI, cwc aws.CWAPI) error
 		}
 	}
 
+	retain, remove, err := release.splitPreviousASGs(asgc, asgs)
+	if err != nil {
+		return err
+	}
+
+	// Retained ASGs are kept scaled to zero for a manual rollback
+	for _, asg := range retain {
+		if release.IsSkipDetachStep() {
+			if err := asg.Detach(asgc); err != nil {
+				return err
+			}
+		}
+
+		if err := asg.Retain(asgc, time.Now()); err != nil {
+			return err
+		}
+	}
+
 	// Delete all Previous Resources
-	for _, asg := range asgs {
-		if err := asg.Teardown(asgc, cwc); err != nil {
+	for _, asg := range remove {
+		if release.DrainFirst {
+			// The drained instances terminate through their lifecycle hooks before the group is deleted
+			if err := asg.ScaleToZero(asgc); err != nil {
+				return err
+			}
+		}
+
+		if err := asg.Teardown(asgc, ec2c, cwc); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return release.PruneLaunchTemplateVersions(ec2c)
 }
 
 // ResetDesiredCapacity resets the ASGs to the desired capacity that would exist without `spread`
@@ -257,7 +442,8 @@ func (release *Release) ResetDesiredCapacity(asgc aws.ASGAPI) error {
 
 // DetachForFailure detach new ASGs
 func (release *Release) DetachForFailure(asgc aws.ASGAPI) error {
-	if release.IsSkipDetachStep() {
+	// In place updates and instance refreshes never detach the live ASGs
+	if release.IsSkipDetachStep() || release.InPlace || release.IsInstanceRefresh() {
 		return nil
 	}
 
@@ -293,9 +479,25 @@ func (release *Release) DetachForFailure(asgc aws.ASGAPI) error {
 }
 
 // UnsuccessfulTearDown deletes the services we were trying to create because :(
-func (release *Release) UnsuccessfulTearDown(asgc aws.ASGAPI, cwc aws.CWAPI) error {
-	// Tear down all resources in this release
-	asgs, err := asg.ForProjectConfigReleaseID(asgc, release.ProjectName, release.ConfigName, release.ReleaseID)
+func (release *Release) UnsuccessfulTearDown(asgc aws.ASGAPI, ec2c aws.EC2API, cwc aws.CWAPI) error {
+	if release.InPlace {
+		// The live ASGs were updated in place so must not be deleted, DetachForFailure
+		// already reverted them and this retries it if that failed
+		return release.RevertInPlace(asgc, cwc)
+	}
+
+	if release.IsInstanceRefresh() {
+		// The live ASGs are being refreshed so must not be deleted
+		return release.CancelRefresh(asgc)
+	}
+
+	if release.UUID == nil {
+		return fmt.Errorf("UUID must be defined to find the releases resources")
+	}
+
+	// Tear down everything tagged with this release, Deploy may have failed, or crashed, part way through
+	// so what was created is found in AWS rather than in the release
+	asgs, err := asg.ForProjectConfigReleaseUUID(asgc, release.ProjectName, release.ConfigName, release.UUID)
 	if err != nil {
 		return err
 	}
@@ -306,11 +508,58 @@ func (release *Release) UnsuccessfulTearDown(asgc aws.ASGAPI, cwc aws.CWAPI) err
 		}
 	}
 
-	// Delete all Resources for this release
+	// Delete all Resources for this release, recording them for the failure report
+	cleanedUp := newFailureCleanUp()
+	release.cleanedUp = cleanedUp
+
+	deletedWithASG := []*string{}
 	for _, asg := range asgs {
-		if err := asg.Teardown(asgc, cwc); err != nil {
+		if err := asg.Teardown(asgc, ec2c, cwc); err != nil {
+			return err
+		}
+
+		cleanedUp.AutoScalingGroups = append(cleanedUp.AutoScalingGroups, asg.AutoScalingGroupName)
+		deletedWithASG = append(deletedWithASG, asg.LaunchTemplateName, asg.LaunchConfigurationName)
+	}
+
+	// Launch templates and configurations created for ASGs that were never created
+	templates, err := lt.ForReleaseUUID(ec2c, release.UUID)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range templates {
+		if containsStrp(deletedWithASG, *name) {
+			continue
+		}
+
+		if err := lt.Teardown(ec2c, name); err != nil {
 			return err
 		}
+
+		cleanedUp.LaunchTemplates = append(cleanedUp.LaunchTemplates, name)
+	}
+
+	for _, name := range sortedServiceNames(release) {
+		serviceID := release.Services[name].ServiceID()
+		if serviceID == nil || containsStrp(deletedWithASG, *serviceID) {
+			continue
+		}
+
+		exists, err := lc.Exists(asgc, serviceID)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			continue
+		}
+
+		if err := lc.Teardown(asgc, serviceID); err != nil {
+			return err
+		}
+
+		cleanedUp.LaunchConfigurations = append(cleanedUp.LaunchConfigurations, serviceID)
 	}
 
 	return nil
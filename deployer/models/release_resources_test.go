@@ -1,8 +1,14 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/odin/aws/lt"
+	"github.com/coinbase/odin/aws/mocks"
 	"github.com/coinbase/step/utils/to"
 	"github.com/stretchr/testify/assert"
 )
@@ -55,23 +61,68 @@ func Test_Release_FetchResources_Stores_WaitForDetach(t *testing.T) {
 }
 
 func Test_Release_CreateResources_Works(t *testing.T) {
-	// func (release *Release) CreateResources(asgc aws.ASGAPI, cwc aws.CWAPI) error {
+	// func (release *Release) CreateResources(asgc aws.ASGAPI, cwc aws.CWAPI, albc aws.ALBAPI) error {
 	r := MockRelease(t)
 	MockPrepareRelease(r)
 
 	awsc := MockAwsClients(r)
-	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.CW))
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
 }
 
 func Test_Release_UpdateHealthy_Works(t *testing.T) {
-	// func (release *Release) UpdateHealthy(asgc aws.ASGAPI, elbc aws.ELBAPI, albc aws.ALBAPI) error {
+	// func (release *Release) UpdateHealthy(asgc aws.ASGAPI, ec2c aws.EC2API, elbc aws.ELBAPI, albc aws.ALBAPI, cwc aws.CWAPI) error {
 	r := MockRelease(t)
 	MockPrepareRelease(r)
 
 	awsc := MockAwsClients(r)
 
-	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.CW))
-	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.ELB, awsc.ALB))
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+}
+
+func Test_Release_UpdateHealthy_MultipleLoadBalancers(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].ELBs = []*string{to.Strp("web-elb"), to.Strp("web-internal-elb")}
+	r.Services["web"].TargetGroups = []*string{to.Strp("web-elb-target"), to.Strp("web-internal-target")}
+	MockPrepareRelease(r)
+
+	awsc := MockAwsClients(r)
+	awsc.ELB.AddELB("web-internal-elb", *r.ProjectName, *r.ConfigName, "web")
+	awsc.ALB.AddTargetGroup(mocks.MockTargetGroup{
+		Name:        "web-internal-target",
+		ProjectName: *r.ProjectName,
+		ConfigName:  *r.ConfigName,
+		ServiceName: "web",
+	})
+
+	resources, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, r.ValidateResources(resources))
+	r.UpdateWithResources(resources)
+
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// The new ASG is attached to every load balancer
+	input := awsc.ASG.CreateAutoScalingGroupInputs[0]
+	assert.Equal(t, []string{"web-elb", "web-internal-elb"}, to.StrSlice(input.LoadBalancerNames))
+	assert.Equal(t, []string{"web-elb-target", "web-internal-target"}, to.StrSlice(input.TargetGroupARNs))
+
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, *r.Healthy)
+
+	// Unhealthy in any one of them is unhealthy
+	awsc.ALB.SetTargetHealth("web-internal-target", "InstanceId1", "unhealthy")
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, *r.Healthy)
+
+	awsc.ALB.SetTargetHealth("web-internal-target", "InstanceId1", "healthy")
+	awsc.ELB.SetInstanceHealth("web-internal-elb", "InstanceId1", "OutOfService")
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, *r.Healthy)
+
+	awsc.ELB.SetInstanceHealth("web-internal-elb", "InstanceId1", "InService")
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, *r.Healthy)
 }
 
 func Test_Release_SuccessfulTearDown_Works(t *testing.T) {
@@ -80,7 +131,26 @@ func Test_Release_SuccessfulTearDown_Works(t *testing.T) {
 	MockPrepareRelease(r)
 
 	awsc := MockAwsClients(r)
-	assert.NoError(t, r.SuccessfulTearDown(awsc.ASG, awsc.CW))
+	assert.NoError(t, r.SuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+}
+
+func Test_Release_First_Release_No_Previous_ASGs(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+
+	awsc := MockAwsClients(r)
+	awsc.ASG = &mocks.ASGClient{}
+
+	resources, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.Nil(t, resources.PreviousReleaseID)
+	assert.Equal(t, 0, *r.WaitForDrain)
+
+	// There is nothing to detach or delete
+	assert.NoError(t, r.DetachForSuccess(awsc.ASG))
+	assert.NoError(t, r.SuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+	assert.Equal(t, 0, len(awsc.ASG.DetachLoadBalancersInputs))
+	assert.Equal(t, 0, len(awsc.ASG.DeleteAutoScalingGroupInputs))
 }
 
 func Test_Release_UnsuccessfulTearDown_Works(t *testing.T) {
@@ -89,7 +159,63 @@ func Test_Release_UnsuccessfulTearDown_Works(t *testing.T) {
 	MockPrepareRelease(r)
 
 	awsc := MockAwsClients(r)
-	assert.NoError(t, r.UnsuccessfulTearDown(awsc.ASG, awsc.CW))
+	assert.NoError(t, r.UnsuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+}
+
+func Test_Release_UnsuccessfulTearDown_PartiallyCreated(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+	awsc.ASG.TrackCreated = true
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+
+	// The worker service uses a launch template and fails after it is created
+	raw, err := json.Marshal(release.Services["web"])
+	assert.NoError(t, err)
+	var worker Service
+	assert.NoError(t, json.Unmarshal(raw, &worker))
+	worker.InstanceTypes = mockInstanceTypes("m5.large", "c5.large")
+	release.Services["worker"] = &worker
+	release.SetDefaults()
+
+	awsc.ASG.CreateAutoScalingGroupErrors = map[string]error{"worker": fmt.Errorf("worker failed")}
+	assert.Error(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	webID := *release.Services["web"].ServiceID()
+	workerID := *release.Services["worker"].ServiceID()
+	assert.Equal(t, 1, len(awsc.ASG.CreateAutoScalingGroupInputs))
+	assert.Equal(t, webID, *awsc.ASG.CreateAutoScalingGroupInputs[0].AutoScalingGroupName)
+
+	// Cleanup only has what is in AWS, as if the deployer crashed
+	failed := MockRelease(t)
+	failed.UUID = release.UUID
+	failed.Services["worker"] = &Service{}
+	failed.CreatedAt = release.CreatedAt
+	failed.SetDefaults()
+
+	assert.NoError(t, failed.UnsuccessfulTearDown(awsc.ASG, awsc.EC2, awsc.CW))
+
+	assert.Equal(t, 1, len(awsc.ASG.DeleteAutoScalingGroupInputs))
+	assert.Equal(t, webID, *awsc.ASG.DeleteAutoScalingGroupInputs[0].AutoScalingGroupName)
+
+	assert.Equal(t, 1, len(awsc.ASG.DeleteLaunchConfigurationInputs))
+	assert.Equal(t, webID, *awsc.ASG.DeleteLaunchConfigurationInputs[0].LaunchConfigurationName)
+
+	assert.Equal(t, 1, len(awsc.EC2.DeleteLaunchTemplateInputs))
+	assert.Equal(t, workerID, *awsc.EC2.DeleteLaunchTemplateInputs[0].LaunchTemplateName)
+
+	// Everything is gone, the previous release is untouched
+	asgs, err := asg.ForProjectConfigReleaseUUID(awsc.ASG, release.ProjectName, release.ConfigName, release.UUID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(asgs))
+
+	templates, err := lt.ForReleaseUUID(awsc.EC2, release.UUID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(templates))
 }
 
 func Test_Release_ResetDesiredCapacity_Works(t *testing.T) {
@@ -116,3 +242,121 @@ func Test_Release_ResetDesiredCapacity_Works(t *testing.T) {
 	assert.Equal(t, int64(6), *awsc.ASG.UpdateAutoScalingGroupLastInput.DesiredCapacity)
 
 }
+
+func Test_Release_StaggerHealthChecks_Offsets(t *testing.T) {
+	r := MockRelease(t)
+	r.StaggerHealthChecks = true
+	for _, name := range []string{"api", "worker", "cron"} {
+		var service Service
+		raw, _ := json.Marshal(r.Services["web"])
+		assert.NoError(t, json.Unmarshal(raw, &service))
+		r.Services[name] = &service
+	}
+	MockPrepareRelease(r)
+
+	offsets := map[int]bool{}
+	for _, service := range r.Services {
+		assert.NotNil(t, service.HealthCheckOffset)
+		assert.True(t, *service.HealthCheckOffset >= 0)
+		assert.True(t, *service.HealthCheckOffset < *r.WaitForHealthy)
+		offsets[*service.HealthCheckOffset] = true
+	}
+
+	// Services do not all start at the same offset
+	assert.True(t, len(offsets) > 1)
+	assert.NoError(t, r.ValidateServices())
+
+	// The offsets are controlled, so a release cannot set them
+	r.WipeControlledValues()
+	for _, service := range r.Services {
+		assert.Nil(t, service.HealthCheckOffset)
+	}
+}
+
+func Test_Release_StaggerHealthChecks_SkipsUnstartedService(t *testing.T) {
+	r := MockRelease(t)
+	r.StaggerHealthChecks = true
+	MockPrepareRelease(r)
+
+	awsc := MockAwsClients(r)
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	r.Services["web"].HealthCheckOffset = to.Intp(10)
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, *r.Healthy)
+	assert.False(t, r.Services["web"].Healthy)
+
+	r.HealthCheckStartedAt = to.Timep(time.Now().Add(-10 * time.Second))
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, *r.Healthy)
+}
+
+func Test_Release_UpdateHealthy_HealthCheckGracePeriod(t *testing.T) {
+	r := MockRelease(t)
+	r.Timeout = to.Intp(600)
+	r.Services["web"].Autoscaling.HealthCheckGracePeriod = to.Int64p(300)
+	MockPrepareRelease(r)
+
+	awsc := MockAwsClients(r)
+	awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0].Instances = mocks.MakeMockASGInstances(1, 2, 0)
+
+	// InstanceId2 is warming up, InstanceId3 launched before the grace period
+	awsc.EC2.AddInstance("InstanceId2", time.Now().Add(-10*time.Second))
+	awsc.EC2.AddInstance("InstanceId3", time.Now().Add(-600*time.Second))
+
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+
+	report := r.Services["web"].HealthReport
+	assert.Equal(t, 1, *report.Healthy)
+	assert.Equal(t, 1, *report.Pending)
+	assert.Equal(t, 3, *report.Launching)
+
+	// Without a LaunchExtensionMax the Timeout is not extended
+	assert.Nil(t, r.LaunchExtension)
+}
+
+func Test_Release_UpdateHealthy_HealthCheckGracePeriod_Extends_Timeout(t *testing.T) {
+	r := MockRelease(t)
+	r.Timeout = to.Intp(600)
+	r.LaunchExtensionMax = to.Intp(100)
+	r.Services["web"].Autoscaling.HealthCheckGracePeriod = to.Int64p(300)
+	MockPrepareRelease(r)
+	r.StartedAt = to.Timep(time.Now().Add(-500 * time.Second))
+
+	awsc := MockAwsClients(r)
+	awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0].Instances = mocks.MakeMockASGInstances(0, 1, 0)
+
+	// InstanceId1 launched late and has about 200 seconds of grace left, more than the 100 remaining
+	awsc.EC2.AddInstance("InstanceId1", time.Now().Add(-100*time.Second))
+
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+
+	// The extension is capped at the LaunchExtensionMax
+	assert.Equal(t, 100, *r.LaunchExtension)
+}
+
+func Test_Release_ValidateResources_TimeBudget(t *testing.T) {
+	r := MockRelease(t)
+	r.ValidateTimeBudget = true
+	r.Timeout = to.Intp(600)
+	MockPrepareRelease(r)
+	awsc := MockAwsClients(r)
+
+	resources, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	// 10 grace + 30 drain + 0 soak
+	assert.NoError(t, r.ValidateResources(resources))
+
+	// 10 grace + 30 drain + 570 soak
+	r.SoakDuration = to.Intp(570)
+	err = r.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "health_check_grace_period 10 + deregistration_delay 30 + soak_duration 570 = 610 is greater than timeout 600")
+
+	// Not validated unless configured
+	r.ValidateTimeBudget = false
+	assert.NoError(t, r.ValidateResources(resources))
+}
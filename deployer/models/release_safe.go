@@ -3,6 +3,7 @@ package models
 import (
 	"fmt"
 
+	"github.com/coinbase/odin/aws/iam"
 	"github.com/coinbase/step/aws"
 	"github.com/coinbase/step/aws/s3"
 	"github.com/coinbase/step/bifrost"
@@ -27,6 +28,24 @@ func (release *Release) ValidateSafeRelease(s3c aws.S3API, resources *ReleaseRes
 		return nil
 	}
 
+	previousRelease, err := release.fetchPreviousRelease(s3c, resources)
+	if err != nil {
+		switch err.(type) {
+		case *s3.NotFoundError:
+			// No lock to release
+			return fmt.Errorf("SafeRelease Error: Cannot find previous release s3://%v/%v", *previousRelease.Bucket, *previousRelease.ReleasePath())
+		default:
+			return err // All other errors return
+		}
+	}
+
+	// return an error for valid services
+	return release.validateSafeRelease(previousRelease)
+}
+
+// fetchPreviousRelease downloads the release of the currently deployed ASGs
+// The returned release is always scaffolded so errors can reference its paths
+func (release *Release) fetchPreviousRelease(s3c aws.S3API, resources *ReleaseResources) (*Release, error) {
 	// Scaffold Previous Release
 	previousRelease := Release{
 		Release: bifrost.Release{
@@ -48,21 +67,14 @@ func (release *Release) ValidateSafeRelease(s3c aws.S3API, resources *ReleaseRes
 	)
 
 	if err != nil {
-		switch err.(type) {
-		case *s3.NotFoundError:
-			// No lock to release
-			return fmt.Errorf("SafeRelease Error: Cannot find previous release s3://%v/%v", *previousRelease.Bucket, *previousRelease.ReleasePath())
-		default:
-			return err // All other errors return
-		}
+		return &previousRelease, err
 	}
 
 	// Set Defaults for comparison
 	previousRelease.Release.SetDefaults(release.AwsRegion, release.AwsAccountID, "coinbase-odin-")
 	previousRelease.SetDefaults()
 
-	// return an error for valid services
-	return release.validateSafeRelease(&previousRelease)
+	return &previousRelease, nil
 }
 
 type SafeReleaseError struct {
@@ -179,7 +191,8 @@ func validateSafeService(serviceName string, service *Service, prevService *Serv
 		srse.SecurityGroups = fmt.Errorf("SafeRelease Error(%v): SecurityGroups different %v", serviceName, *res)
 	}
 
-	if res := safeStr(service.Profile, prevService.Profile); res != nil {
+	// The same profile can be referenced by its name or ARN
+	if res := safeStr(profileName(service.Profile), profileName(prevService.Profile)); res != nil {
 		srse.Profile = fmt.Errorf("SafeRelease Error(%v): Profile different %v", serviceName, *res)
 	}
 
@@ -249,6 +262,15 @@ func validateSafeAutoscaling(srse *SafeReleaseServiceError, serviceName string,
 ////
 // Utils
 ////
+
+// profileName returns the name of an instance profile referenced by name or ARN
+func profileName(profile *string) *string {
+	if profile == nil {
+		return nil
+	}
+	return to.Strp(iam.ProfileName(*profile))
+}
+
 func safeStr(s1 *string, s2 *string) *string {
 	if s1 == nil && s2 == nil {
 		return nil
@@ -80,6 +80,11 @@ func Test_Release_validateSafeRelease_Service(t *testing.T) {
 	release.Services["web"].Profile = to.Strp("not")
 
 	validateSafeErrorTest(t, release, "Profile")
+
+	// The same profile by its ARN is safe
+	release = MockRelease(t)
+	release.Services["web"].Profile = to.Strp("arn:aws:iam::000000000000:instance-profile/odin/project/config/web/web-profile")
+	assert.NoError(t, release.validateSafeRelease(MockRelease(t)))
 }
 
 func Test_Release_validateSafeRelease_Autoscaling(t *testing.T) {
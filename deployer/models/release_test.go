@@ -14,7 +14,7 @@ func Test_Release_Validate_Works(t *testing.T) {
 
 	MockPrepareRelease(r)
 
-	assert.NoError(t, r.Validate(awsc.S3))
+	assert.NoError(t, r.Validate(awsc.S3, awsc.KMS))
 }
 
 func Test_Release_ValidateServices_Works(t *testing.T) {
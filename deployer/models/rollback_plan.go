@@ -0,0 +1,256 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+// RollbackStateMachineSuffix names the rollback state machine of a deployer, e.g. coinbase-odin-rollback
+const RollbackStateMachineSuffix = "-rollback"
+
+// RollbackPlan is written by every successful release and holds
+// the release it replaced so that it can be deployed again
+type RollbackPlan struct {
+	ReleaseID         *string                     `json:"release_id,omitempty"`          // Release that wrote the plan
+	PreviousReleaseID *string                     `json:"previous_release_id,omitempty"` // Release to roll back to
+	Services          map[string]*RollbackService `json:"services,omitempty"`
+
+	Previous *ReleaseRecord `json:"previous,omitempty"` // The full release to roll back to
+}
+
+// ReleaseRecord is the full document of a successful release with its user data as uploaded
+type ReleaseRecord struct {
+	Release  *Release `json:"release,omitempty"`
+	UserData *string  `json:"user_data,omitempty"`
+}
+
+// RollbackService is the previous definition of a service
+type RollbackService struct {
+	AutoScalingGroupName    *string `json:"autoscaling_group_name,omitempty"`
+	LaunchConfigurationName *string `json:"launch_configuration_name,omitempty"`
+
+	MinSize         *int64 `json:"min_size,omitempty"`
+	MaxSize         *int64 `json:"max_size,omitempty"`
+	DesiredCapacity *int64 `json:"desired_capacity,omitempty"`
+}
+
+// RollbackPlanPath returns the path for the project config rollback plan
+func (release *Release) RollbackPlanPath() *string {
+	s := fmt.Sprintf("%v/rollback_plan", *release.RootDir())
+	return &s
+}
+
+// LastReleasePath returns the path for the record of the last successful release of the project config
+func (release *Release) LastReleasePath() *string {
+	s := fmt.Sprintf("%v/last_release", *release.RootDir())
+	return &s
+}
+
+// CreateRollbackPlan returns the plan to return to the release of the previous ASGs
+func (release *Release) CreateRollbackPlan(asgs []*asg.ASG) *RollbackPlan {
+	plan := &RollbackPlan{
+		ReleaseID: release.ReleaseID,
+		Services:  map[string]*RollbackService{},
+	}
+
+	for _, group := range asgs {
+		if group.ServiceName() == nil {
+			continue
+		}
+
+		plan.PreviousReleaseID = group.ReleaseID()
+		plan.Services[*group.ServiceName()] = &RollbackService{
+			AutoScalingGroupName:    group.AutoScalingGroupName,
+			LaunchConfigurationName: group.LaunchConfigurationName,
+			MinSize:                 group.MinSize,
+			MaxSize:                 group.MaxSize,
+			DesiredCapacity:         group.DesiredCapacity,
+		}
+	}
+
+	return plan
+}
+
+// WriteRollbackPlan writes the rollback plan for the release this release is replacing,
+// then records this release as the last successful release for the next plan
+// It must be called before the previous ASGs are torn down
+func (release *Release) WriteRollbackPlan(s3c aws.S3API, asgc aws.ASGAPI) error {
+	var last ReleaseRecord
+	if err := s3.GetStruct(s3c, release.Bucket, release.LastReleasePath(), &last); err != nil {
+		switch err.(type) {
+		case *s3.NotFoundError:
+			// The first release of the project config
+		default:
+			return err
+		}
+	}
+
+	// A retry once this release is recorded has already written its plan
+	if last.Release != nil && to.Strs(last.Release.ReleaseID) == to.Strs(release.ReleaseID) {
+		return nil
+	}
+
+	asgs, err := asg.ForProjectConfigNOTReleaseID(asgc, release.ProjectName, release.ConfigName, release.ReleaseID)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to roll back to, e.g. the first release or the previous ASGs are already deleted
+	if len(asgs) > 0 {
+		for _, group := range asgs {
+			if err := release.validSuccessASG(group); err != nil {
+				return err
+			}
+		}
+
+		plan := release.CreateRollbackPlan(asgs)
+
+		previous, err := release.previousReleaseRecord(s3c, plan.PreviousReleaseID, &last)
+		if err != nil {
+			return err
+		}
+
+		plan.Previous = previous
+
+		if err := s3.PutStruct(s3c, release.Bucket, release.RollbackPlanPath(), plan); err != nil {
+			return err
+		}
+	}
+
+	userdata, err := s3.GetStr(s3c, release.Bucket, release.UserDataPath())
+	if err != nil {
+		return err
+	}
+
+	return s3.PutStruct(s3c, release.Bucket, release.LastReleasePath(), &ReleaseRecord{release, userdata})
+}
+
+// previousReleaseRecord returns the record of the previous release
+// Releases recorded before last_release was written fall back to the uploaded release document
+func (release *Release) previousReleaseRecord(s3c aws.S3API, previousReleaseID *string, last *ReleaseRecord) (*ReleaseRecord, error) {
+	if last.Release != nil && to.Strs(last.Release.ReleaseID) == to.Strs(previousReleaseID) {
+		return last, nil
+	}
+
+	if previousReleaseID == nil {
+		return nil, nil
+	}
+
+	// Use this release to find the paths of the previous release
+	finder := *release
+	finder.ReleaseID = previousReleaseID
+
+	var previous Release
+	if err := s3.GetStruct(s3c, release.Bucket, finder.ReleasePath(), &previous); err != nil {
+		switch err.(type) {
+		case *s3.NotFoundError:
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	userdata, err := s3.GetStr(s3c, release.Bucket, finder.UserDataPath())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReleaseRecord{&previous, userdata}, nil
+}
+
+// RollbackRelease returns the previous release recorded in the rollback plan with its user data set
+func (release *Release) RollbackRelease(s3c aws.S3API) (*Release, error) {
+	var plan RollbackPlan
+	if err := s3.GetStruct(s3c, release.Bucket, release.RollbackPlanPath(), &plan); err != nil {
+		return nil, err
+	}
+
+	if plan.Previous == nil || plan.Previous.Release == nil {
+		return nil, fmt.Errorf("rollback plan has no previous release")
+	}
+
+	previous := plan.Previous.Release
+	if to.Strs(previous.ProjectName) != to.Strs(release.ProjectName) || to.Strs(previous.ConfigName) != to.Strs(release.ConfigName) {
+		return nil, fmt.Errorf("rollback release %v is for a different project config", to.Strs(previous.ReleaseID))
+	}
+
+	previous.SetUserData(plan.Previous.UserData)
+
+	return previous, nil
+}
+
+// PrepareRollback replaces what this release deploys with the release in the rollback plan
+// Everything but the identity of this release is restored from the previous release, and
+// UserDataSHA256 is taken from the previous user data rather than validated against an uploaded artifact
+// Nothing is written, the user data is uploaded by UploadRollbackUserData once the release holds the lock
+func (release *Release) PrepareRollback(s3c aws.S3API, kmsc aws.KMSAPI) error {
+	if is.EmptyStr(release.ProjectName) || is.EmptyStr(release.ConfigName) || is.EmptyStr(release.AwsAccountID) || is.EmptyStr(release.Bucket) {
+		return fmt.Errorf("%v rollback requires project_name, config_name, aws_account_id and bucket", release.ErrorPrefix())
+	}
+
+	previous, err := release.RollbackRelease(s3c)
+	if err != nil {
+		switch err.(type) {
+		case *s3.NotFoundError:
+			return fmt.Errorf("%v no previous release to roll back to, %v", release.ErrorPrefix(), err.Error())
+		default:
+			return fmt.Errorf("%v %v", release.ErrorPrefix(), err.Error())
+		}
+	}
+
+	rolled := *previous
+	rolled.WipeControlledValues()
+
+	// The identity and the request of this release are kept
+	rolled.Release = release.Release
+	rolled.Timeout = previous.Timeout
+	rolled.Rollback = true
+	rolled.RollbackReleaseID = previous.ReleaseID
+	rolled.ValidateOnly = release.ValidateOnly
+	rolled.IdempotencyKey = release.IdempotencyKey
+	rolled.ForceUnlock = release.ForceUnlock
+
+	*release = rolled
+
+	// The copy stays encrypted, the SHA is of the plaintext as ValidateUserDataSHA checks
+	plaintext, err := DecryptUserData(kmsc, previous.UserData())
+	if err != nil {
+		return err
+	}
+
+	// Rendered for this release as the variables, e.g. ReleaseID, differ from the previous release
+	rendered, err := release.RenderUserData(plaintext)
+	if err != nil {
+		return err
+	}
+
+	release.SetUserData(previous.UserData())
+	release.UserDataSHA256 = to.Strp(to.SHA256Str(rendered))
+
+	return nil
+}
+
+// UploadRollbackUserData writes the previous user data of a rollback release to this releases user data path
+// Validate has no side effects, so the user data is only written once the release holds the lock
+func (release *Release) UploadRollbackUserData(s3c aws.S3API) error {
+	if !release.Rollback {
+		return nil
+	}
+
+	previous, err := release.RollbackRelease(s3c)
+	if err != nil {
+		return err
+	}
+
+	// Another release could have succeeded and rewritten the plan since Validate
+	if to.Strs(previous.ReleaseID) != to.Strs(release.RollbackReleaseID) {
+		return fmt.Errorf("rollback plan is now for release %v not %v", to.Strs(previous.ReleaseID), to.Strs(release.RollbackReleaseID))
+	}
+
+	return s3.PutStr(s3c, release.Bucket, release.UserDataPath(), previous.UserData())
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/aws/s3"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_WriteRollbackPlan(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	awsc := MockAwsClients(r)
+
+	assert.NoError(t, r.WriteRollbackPlan(awsc.S3, awsc.ASG))
+
+	var plan RollbackPlan
+	assert.NoError(t, s3.GetStruct(awsc.S3, r.Bucket, r.RollbackPlanPath(), &plan))
+
+	assert.Equal(t, *r.ReleaseID, *plan.ReleaseID)
+	assert.Equal(t, "old-release", *plan.PreviousReleaseID)
+	assert.Equal(t, "project-config-web-old-release", *plan.Services["web"].AutoScalingGroupName)
+	assert.Equal(t, int64(1), *plan.Services["web"].DesiredCapacity)
+
+	// The previous release was never recorded or uploaded
+	assert.Nil(t, plan.Previous)
+
+	// This release is recorded for the next plan
+	var last ReleaseRecord
+	assert.NoError(t, s3.GetStruct(awsc.S3, r.Bucket, r.LastReleasePath(), &last))
+	assert.Equal(t, *r.ReleaseID, *last.Release.ReleaseID)
+	assert.Equal(t, *r.UserData(), *last.UserData)
+}
+
+func Test_Release_WriteRollbackPlan_Records_Previous_Release(t *testing.T) {
+	previous := MockRelease(t)
+	previous.ReleaseID = to.Strp("old-release")
+	previous.SafeRelease = true
+	previous.SoakDuration = to.Intp(600)
+	previous.SetUserData(to.Strp("#old_cloud_config"))
+	MockPrepareRelease(previous)
+	previous.WaitForHealthy = to.Intp(30)
+	awsc := MockAwsClients(previous)
+
+	// The previous release recorded itself when it succeeded
+	assert.NoError(t, s3.PutStruct(awsc.S3, previous.Bucket, previous.LastReleasePath(), &ReleaseRecord{previous, previous.UserData()}))
+
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	AddReleaseS3Objects(awsc, r)
+
+	assert.NoError(t, r.WriteRollbackPlan(awsc.S3, awsc.ASG))
+
+	rollback, err := r.RollbackRelease(awsc.S3)
+	assert.NoError(t, err)
+
+	// Every field of the previous release is restored
+	assert.Equal(t, "old-release", *rollback.ReleaseID)
+	assert.Equal(t, "#old_cloud_config", *rollback.UserData())
+	assert.Equal(t, *previous.Image, *rollback.Image)
+	assert.True(t, rollback.SafeRelease)
+	assert.Equal(t, 30, *rollback.WaitForHealthy)
+	assert.Equal(t, 600, *rollback.SoakDuration)
+	assert.Equal(t, "t2.small", *rollback.Services["web"].InstanceType)
+
+	// A retry of CleanUpSuccess keeps the plan
+	assert.NoError(t, r.WriteRollbackPlan(awsc.S3, awsc.ASG))
+
+	rollback, err = r.RollbackRelease(awsc.S3)
+	assert.NoError(t, err)
+	assert.Equal(t, "old-release", *rollback.ReleaseID)
+}
+
+func Test_Release_RollbackRelease_Uploaded_Release(t *testing.T) {
+	previous := MockRelease(t)
+	previous.ReleaseID = to.Strp("old-release")
+	previous.SetUserData(to.Strp("#old_cloud_config"))
+	MockPrepareRelease(previous)
+	awsc := MockAwsClients(previous)
+
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	AddReleaseS3Objects(awsc, r)
+
+	assert.NoError(t, r.WriteRollbackPlan(awsc.S3, awsc.ASG))
+
+	// Without a record the uploaded release document is used
+	rollback, err := r.RollbackRelease(awsc.S3)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "old-release", *rollback.ReleaseID)
+	assert.Equal(t, "#old_cloud_config", *rollback.UserData())
+	assert.Equal(t, *previous.Image, *rollback.Image)
+	assert.Equal(t, "t2.small", *rollback.Services["web"].InstanceType)
+}
+
+func Test_Release_PrepareRollback(t *testing.T) {
+	previous := MockRelease(t)
+	previous.ReleaseID = to.Strp("old-release")
+	previous.SetUserData(to.Strp("#old_cloud_config"))
+	MockPrepareRelease(previous)
+	previous.WaitForHealthy = to.Intp(30)
+	awsc := MockAwsClients(previous)
+
+	assert.NoError(t, s3.PutStruct(awsc.S3, previous.Bucket, previous.RollbackPlanPath(), &RollbackPlan{
+		PreviousReleaseID: previous.ReleaseID,
+		Previous:          &ReleaseRecord{previous, previous.UserData()},
+	}))
+
+	r := MockMinimalRelease(t)
+	r.ReleaseID = to.Strp("rollback")
+	r.Bucket = previous.Bucket
+	r.Rollback = true
+	r.Services = nil
+
+	assert.NoError(t, r.PrepareRollback(awsc.S3, awsc.KMS))
+
+	// This releases identity with the previous definition
+	assert.Equal(t, "rollback", *r.ReleaseID)
+	assert.Equal(t, "old-release", *r.RollbackReleaseID)
+	assert.Equal(t, 30, *r.WaitForHealthy)
+	assert.Equal(t, "t2.small", *r.Services["web"].InstanceType)
+	assert.Equal(t, "#old_cloud_config", *r.UserData())
+
+	// Nothing is written until the release holds the lock
+	_, err := s3.GetStr(awsc.S3, r.Bucket, r.UserDataPath())
+	assert.Error(t, err)
+
+	assert.NoError(t, r.UploadRollbackUserData(awsc.S3))
+
+	userdata, err := s3.GetStr(awsc.S3, r.Bucket, r.UserDataPath())
+	assert.NoError(t, err)
+	assert.Equal(t, "#old_cloud_config", *userdata)
+}
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Scheduled Actions
+//////////
+
+// cronFields are the minimum and maximum of each field of a recurrence
+// minute hour day-of-month month day-of-week
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day-of-month", 1, 31},
+	{"month", 1, 12},
+	{"day-of-week", 0, 6},
+}
+
+// ScheduledAction changes the size of the ASG on a cron recurrence (UTC)
+type ScheduledAction struct {
+	Name            *string `json:"name,omitempty"`
+	Recurrence      *string `json:"recurrence,omitempty"`
+	MinSize         *int64  `json:"min_size,omitempty"`
+	MaxSize         *int64  `json:"max_size,omitempty"`
+	DesiredCapacity *int64  `json:"desired_capacity,omitempty"`
+}
+
+// ValidateAttributes validates attributes
+func (a *ScheduledAction) ValidateAttributes() error {
+	if is.EmptyStr(a.Name) {
+		return fmt.Errorf("ScheduledAction name must be defined")
+	}
+
+	if a.Recurrence == nil {
+		return fmt.Errorf("ScheduledAction(%v): recurrence must be defined", *a.Name)
+	}
+
+	if err := validateRecurrence(*a.Recurrence); err != nil {
+		return fmt.Errorf("ScheduledAction(%v): recurrence %q %v", *a.Name, *a.Recurrence, err.Error())
+	}
+
+	if a.MinSize == nil && a.MaxSize == nil && a.DesiredCapacity == nil {
+		return fmt.Errorf("ScheduledAction(%v): one of min_size, max_size or desired_capacity must be defined", *a.Name)
+	}
+
+	for _, size := range []*int64{a.MinSize, a.MaxSize, a.DesiredCapacity} {
+		if size != nil && *size < 0 {
+			return fmt.Errorf("ScheduledAction(%v): sizes must be positive", *a.Name)
+		}
+	}
+
+	if a.MinSize != nil && a.MaxSize != nil && *a.MinSize > *a.MaxSize {
+		return fmt.Errorf("ScheduledAction(%v): min_size is greater than max_size", *a.Name)
+	}
+
+	if a.DesiredCapacity != nil {
+		if (a.MinSize != nil && *a.DesiredCapacity < *a.MinSize) || (a.MaxSize != nil && *a.DesiredCapacity > *a.MaxSize) {
+			return fmt.Errorf("ScheduledAction(%v): desired_capacity must be between min_size and max_size", *a.Name)
+		}
+	}
+
+	return nil
+}
+
+// validateRecurrence checks a five field cron expression of numbers, *, ranges, lists and steps
+func validateRecurrence(recurrence string) error {
+	fields := strings.Fields(recurrence)
+	if len(fields) != len(cronFields) {
+		return fmt.Errorf("must have %v fields", len(cronFields))
+	}
+
+	for i, field := range fields {
+		cf := cronFields[i]
+		for _, part := range strings.Split(field, ",") {
+			if err := validateCronPart(part, cf.min, cf.max); err != nil {
+				return fmt.Errorf("%v %v", cf.name, err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateCronPart(part string, min, max int) error {
+	rng := part
+	if i := strings.Index(part, "/"); i >= 0 {
+		rng = part[:i]
+		step, err := strconv.Atoi(part[i+1:])
+		if err != nil || step < 1 {
+			return fmt.Errorf("has invalid step %q", part)
+		}
+	}
+
+	if rng == "*" {
+		return nil
+	}
+
+	bounds := strings.Split(rng, "-")
+	if len(bounds) > 2 {
+		return fmt.Errorf("has invalid range %q", part)
+	}
+
+	values := []int{}
+	for _, b := range bounds {
+		v, err := strconv.Atoi(b)
+		if err != nil || v < min || v > max {
+			return fmt.Errorf("value %q must be between %v and %v", b, min, max)
+		}
+		values = append(values, v)
+	}
+
+	if len(values) == 2 && values[0] > values[1] {
+		return fmt.Errorf("has invalid range %q", part)
+	}
+
+	return nil
+}
+
+// Create puts the scheduled action on the ASG
+func (a *ScheduledAction) Create(asgc aws.ASGAPI, asgName *string) error {
+	_, err := asgc.PutScheduledUpdateGroupAction(&autoscaling.PutScheduledUpdateGroupActionInput{
+		AutoScalingGroupName: asgName,
+		ScheduledActionName:  a.Name,
+		Recurrence:           a.Recurrence,
+		MinSize:              a.MinSize,
+		MaxSize:              a.MaxSize,
+		DesiredCapacity:      a.DesiredCapacity,
+	})
+
+	return err
+}
+
+// validateScheduledActions validates each scheduled action and their names are unique
+func (a *AutoScalingConfig) validateScheduledActions() error {
+	names := []*string{}
+	for _, action := range a.ScheduledActions {
+		if action == nil {
+			return fmt.Errorf("ScheduledAction nil")
+		}
+
+		if err := action.ValidateAttributes(); err != nil {
+			return err
+		}
+
+		names = append(names, action.Name)
+	}
+
+	if !is.UniqueStrp(names) {
+		return fmt.Errorf("ScheduledAction names not unique")
+	}
+
+	return nil
+}
+
+// createScheduledActions puts the scheduled actions on the created ASG, they are deleted with it
+func (service *Service) createScheduledActions(asgc aws.ASGAPI) error {
+	for _, action := range service.Autoscaling.ScheduledActions {
+		if err := action.Create(asgc, service.CreatedASG); err != nil {
+			return fmt.Errorf("ScheduledAction(%v): %v", to.Strs(action.Name), err.Error())
+		}
+	}
+
+	return nil
+}
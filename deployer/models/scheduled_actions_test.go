@@ -0,0 +1,67 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ScheduledAction_ValidateAttributes(t *testing.T) {
+	action := &ScheduledAction{
+		Name:       to.Strp("morning"),
+		Recurrence: to.Strp("0 8 * * 1-5"),
+		MinSize:    to.Int64p(4),
+		MaxSize:    to.Int64p(10),
+	}
+	assert.NoError(t, action.ValidateAttributes())
+
+	action.DesiredCapacity = to.Int64p(11)
+	assert.Error(t, action.ValidateAttributes())
+
+	action.DesiredCapacity = to.Int64p(6)
+	assert.NoError(t, action.ValidateAttributes())
+
+	action.MinSize = to.Int64p(11)
+	assert.Error(t, action.ValidateAttributes())
+
+	action = &ScheduledAction{Name: to.Strp("empty"), Recurrence: to.Strp("0 8 * * *")}
+	assert.Error(t, action.ValidateAttributes())
+
+	action = &ScheduledAction{Recurrence: to.Strp("0 8 * * *"), MinSize: to.Int64p(1)}
+	assert.Error(t, action.ValidateAttributes())
+}
+
+func Test_ScheduledAction_validateRecurrence(t *testing.T) {
+	for _, valid := range []string{"* * * * *", "0 8 * * 1-5", "*/15 0,12 1 1-12/2 0", "30 23 31 12 6"} {
+		assert.NoError(t, validateRecurrence(valid), valid)
+	}
+
+	for _, invalid := range []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 7", "5-1 * * * *", "*/0 * * * *", "a * * * *", "1-2-3 * * * *"} {
+		assert.Error(t, validateRecurrence(invalid), invalid)
+	}
+}
+
+func Test_ScheduledActions_Create(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].Autoscaling.ScheduledActions = []*ScheduledAction{
+		&ScheduledAction{Name: to.Strp("morning"), Recurrence: to.Strp("0 8 * * *"), MinSize: to.Int64p(3)},
+		&ScheduledAction{Name: to.Strp("night"), Recurrence: to.Strp("0 20 * * *"), MinSize: to.Int64p(1)},
+	}
+	MockPrepareRelease(release)
+	assert.NoError(t, release.Services["web"].Validate())
+
+	awsc := MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	inputs := awsc.ASG.PutScheduledUpdateGroupActionInputs
+	assert.Equal(t, 2, len(inputs))
+	assert.Equal(t, *release.Services["web"].CreatedASG, *inputs[0].AutoScalingGroupName)
+	assert.Equal(t, "morning", *inputs[0].ScheduledActionName)
+	assert.Equal(t, "0 8 * * *", *inputs[0].Recurrence)
+	assert.Equal(t, int64(3), *inputs[0].MinSize)
+
+	// Names must be unique
+	release.Services["web"].Autoscaling.ScheduledActions[1].Name = to.Strp("morning")
+	assert.Error(t, release.Services["web"].Validate())
+}
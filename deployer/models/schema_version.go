@@ -0,0 +1,39 @@
+package models
+
+import (
+	"fmt"
+)
+
+//////////
+// Schema Version
+//////////
+
+// LegacySchemaVersion is the schema version of releases written before schema_version existed
+const LegacySchemaVersion = 1
+
+// MinSchemaVersion and MaxSchemaVersion are the release schema versions this deployer supports
+const MinSchemaVersion = 1
+const MaxSchemaVersion = 1
+
+// schemaVersion returns the SchemaVersion, releases without one are the legacy version
+func (release *Release) schemaVersion() int {
+	if release.SchemaVersion == nil {
+		return LegacySchemaVersion
+	}
+	return *release.SchemaVersion
+}
+
+// ValidateSchemaVersion errors if this deployer cannot read the releases schema version
+func (release *Release) ValidateSchemaVersion() error {
+	version := release.schemaVersion()
+
+	if version < MinSchemaVersion {
+		return fmt.Errorf("SchemaVersion %v is no longer supported, migrate the release to schema_version %v", version, MaxSchemaVersion)
+	}
+
+	if version > MaxSchemaVersion {
+		return fmt.Errorf("SchemaVersion %v is newer than the max supported %v, upgrade the deployer or write the release with schema_version %v", version, MaxSchemaVersion, MaxSchemaVersion)
+	}
+
+	return nil
+}
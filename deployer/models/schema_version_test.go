@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidateSchemaVersion(t *testing.T) {
+	release := MockRelease(t)
+
+	// Releases without a schema version are the legacy version
+	release.SchemaVersion = nil
+	assert.NoError(t, release.ValidateSchemaVersion())
+	assert.Equal(t, LegacySchemaVersion, release.schemaVersion())
+
+	release.SchemaVersion = to.Intp(MaxSchemaVersion)
+	assert.NoError(t, release.ValidateSchemaVersion())
+
+	release.SchemaVersion = to.Intp(MinSchemaVersion - 1)
+	err := release.ValidateSchemaVersion()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no longer supported, migrate")
+
+	release.SchemaVersion = to.Intp(MaxSchemaVersion + 1)
+	err = release.ValidateSchemaVersion()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "upgrade the deployer")
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"fmt"
+)
+
+//////////
+// Security Groups
+//////////
+
+// defaultMaxSecurityGroupsPerENI is the default AWS limit of security groups that can be attached to a
+// network interface, every instance type launched by odin has a single primary ENI so this is the limit
+// for the service. AWS raises the limit per account up to highestMaxSecurityGroupsPerENI
+const defaultMaxSecurityGroupsPerENI = 5
+const highestMaxSecurityGroupsPerENI = 16
+
+// ValidateMaxSecurityGroupsPerENI validates MaxSecurityGroupsPerENI
+func (release *Release) ValidateMaxSecurityGroupsPerENI() error {
+	if limit := release.MaxSecurityGroupsPerENI; limit != nil && (*limit < 1 || *limit > highestMaxSecurityGroupsPerENI) {
+		return fmt.Errorf("MaxSecurityGroupsPerENI must be between 1 and %v", highestMaxSecurityGroupsPerENI)
+	}
+
+	return nil
+}
+
+// maxSecurityGroupsPerENI returns the accounts limit of security groups per network interface
+func (release *Release) maxSecurityGroupsPerENI() int {
+	if release == nil || release.MaxSecurityGroupsPerENI == nil {
+		return defaultMaxSecurityGroupsPerENI
+	}
+	return *release.MaxSecurityGroupsPerENI
+}
+
+// validateSecurityGroupsLimit errors if the service has more security groups than the account allows per instance
+func (service *Service) validateSecurityGroupsLimit(count int) error {
+	if limit := service.release.maxSecurityGroupsPerENI(); count > limit {
+		return fmt.Errorf("Security Groups has %v groups, more than the limit of %v per instance", count, limit)
+	}
+
+	return nil
+}
@@ -1,7 +1,10 @@
 package models
 
 import (
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,6 +15,7 @@ import (
 	"github.com/coinbase/odin/aws/asg"
 	"github.com/coinbase/odin/aws/elb"
 	"github.com/coinbase/odin/aws/iam"
+	"github.com/coinbase/odin/aws/instance"
 	"github.com/coinbase/odin/aws/lc"
 	"github.com/coinbase/odin/aws/pg"
 	"github.com/coinbase/odin/aws/sg"
@@ -19,6 +23,13 @@ import (
 	"github.com/coinbase/step/utils/to"
 )
 
+// minMaxInstanceLifetime and maxMaxInstanceLifetime are the AWS bounds in seconds
+// of an ASG's maximum instance lifetime, one day and one year
+const (
+	minMaxInstanceLifetime = 86400
+	maxMaxInstanceLifetime = 31536000
+)
+
 // HealthReport is built to make log lines like:
 // web: .....|.
 // gray targets, red terminated, yellow unhealthy, green healthy
@@ -28,6 +39,7 @@ type HealthReport struct {
 	Healthy        *int     `json:"healthy,omitempty"`         // Number of instances that are healthy
 	Launching      *int     `json:"launching,omitempty"`       // Number of instances that have been created
 	Terminating    *int     `json:"terminating,omitempty"`     // Number of instances that are Terminating
+	Pending        *int     `json:"pending,omitempty"`         // Number of instances inside the health check grace period
 	TerminatingIDs []string `json:"terminating_ids,omitempty"` // Instance IDs that are Terminating
 
 	DesiredCapacity *int64 `json:"desired_capacity,omitempty"` // The current desired capacity goal
@@ -51,11 +63,27 @@ type Service struct {
 	SecurityGroups []*string          `json:"security_groups,omitempty"`
 	Tags           map[string]*string `json:"tags,omitempty"`
 
+	// Health check overrides per target group name
+	TargetGroupHealth map[string]*TargetGroupHealth `json:"target_group_health,omitempty"`
+
 	// Create Resources
 	InstanceType *string            `json:"instance_type,omitempty"`
 	Autoscaling  *AutoScalingConfig `json:"autoscaling,omitempty"`
 	SpotPrice    *string            `json:"spot_price,omitempty"`
 
+	// Mixed instances policy overrides of InstanceType
+	InstanceTypes []*InstanceTypeOverride `json:"instance_types,omitempty"`
+
+	// Spot instances above an on demand base capacity
+	OnDemandBaseCapacity   *int64  `json:"on_demand_base_capacity,omitempty"`
+	SpotAllocationStrategy *string `json:"spot_allocation_strategy,omitempty"`
+
+	// Canary deploys a percentage of instances before the full count
+	Canary *CanaryConfig `json:"canary,omitempty"`
+
+	// Lifecycle hooks for only this service, they override release hooks with the same name
+	LifeCycleHooksVal map[string]*LifeCycleHook `json:"lifecycle,omitempty"`
+
 	// Strategy contains all the information about how to scale
 	strategy *Strategy
 
@@ -64,6 +92,13 @@ type Service struct {
 	EBSVolumeType *string `json:"ebs_volume_type,omitempty"`
 	EBSDeviceName *string `json:"ebs_device_name,omitempty"`
 
+	// Root volume performance, IOPS for gp3 and io volumes and throughput in MiB/s for gp3
+	EBSIOPS       *int64 `json:"ebs_iops,omitempty"`
+	EBSThroughput *int64 `json:"ebs_throughput,omitempty"`
+
+	// Extra EBS volumes
+	BlockDevices []*BlockDevice `json:"block_devices,omitempty"`
+
 	// Placement Group
 	PlacementGroupName           *string `json:"placement_group_name,omitempty"`
 	PlacementGroupPartitionCount *int64  `json:"placement_group_partition_count,omitempty"`
@@ -72,9 +107,68 @@ type Service struct {
 	// Dedicated tenancy or neighbors allowed
 	PlacementTenancy *string `json:"placement_tenancy,omitempty"`
 
+	// The host resource group the instances of a host tenancy service launch onto
+	HostResourceGroupArn *string `json:"host_resource_group_arn,omitempty"`
+
+	// Instance metadata service options, IMDSv2 tokens are required by default
+	InstanceMetadataOptions *InstanceMetadataOptions `json:"instance_metadata_options,omitempty"`
+
+	// One minute CloudWatch instance metrics, five minute metrics by default
+	EnableDetailedMonitoring *bool `json:"enable_detailed_monitoring,omitempty"`
+
+	// The EC2 key pair the instances launch with, none by default
+	KeyName *string `json:"key_name,omitempty"`
+
+	// The instance hostname type and resource name DNS records, only supported by launch templates
+	PrivateDnsNameOptions *PrivateDnsNameOptions `json:"private_dns_name_options,omitempty"`
+
+	// The ALB listener rule forwarded to the services target group on cutover, for path based routing
+	ListenerRule *ListenerRule `json:"listener_rule,omitempty"`
+
+	// Launch template data query parameters merged into the launch template, for fields Odin does not support yet
+	LaunchTemplateOverrides map[string]interface{} `json:"launch_template_overrides,omitempty"`
+
+	// CapacityReservation is "open", "none", a capacity reservation ID or a resource group ARN
+	CapacityReservation *string `json:"capacity_reservation,omitempty"`
+
+	// Only deploy into the release subnets in these availability zones
+	AvailabilityZones []*string `json:"availability_zones,omitempty"`
+
+	// Only deploy into the release subnets it selects, by tags or IDs
+	SubnetSelection *SubnetSelection `json:"subnet_selection,omitempty"`
+
+	// Scaling processes suspended on the new and previous ASGs during the deploy, null defaults to
+	// AZRebalance and ReplaceUnhealthy and [] suspends nothing
+	SuspendProcesses []*string `json:"suspend_processes"`
+
+	// Order the new ASG terminates instances in when scaling in, null keeps ClosestToNextInstanceHour
+	TerminationPolicies []*string `json:"termination_policies,omitempty"`
+
+	// EC2 or ELB, null uses ELB if the service has ELBs or target groups otherwise EC2
+	HealthCheckType *string `json:"health_check_type,omitempty"`
+
+	// Where instance health is read from: asg, elb and target_group, null reads from all of them
+	HealthSources []*string `json:"health_sources,omitempty"`
+
+	// Seconds an instance can be in service before the new ASG replaces it, null or 0 disables it
+	MaxInstanceLifetime *int64 `json:"max_instance_lifetime,omitempty"`
+
+	// Pre-initialized instances kept next to the new ASG
+	WarmPool *WarmPool `json:"warm_pool,omitempty"`
+
+	// If set every release adds a version to one launch template per project config service,
+	// and CleanUpSuccess keeps this many of its newest versions
+	LaunchTemplateRetention *int64 `json:"launch_template_retention,omitempty"`
+
+	// An HTTP endpoint on each new instance that must respond before it is counted healthy
+	ReadinessCheck *ReadinessCheck `json:"readiness_check,omitempty"`
+
 	// Network
 	AssociatePublicIpAddress *bool `json:"associate_public_ip_address,omitempty"`
 
+	// NetworkInterfaces attaches secondary network interfaces, e.g. in another subnet, to each instance
+	NetworkInterfaces []*NetworkInterface `json:"network_interfaces,omitempty"`
+
 	// Found Resources
 	Resources *ServiceResourceNames `json:"resources,omitempty"`
 
@@ -82,9 +176,57 @@ type Service struct {
 	CreatedASG              *string `json:"created_asg,omitempty"`
 	PreviousDesiredCapacity *int64  `json:"previous_desired_capacity,omitempty"`
 
+	// The version of the shared launch template created by this release
+	CreatedLaunchTemplateVersion *int64 `json:"created_launch_template_version,omitempty"`
+
+	// The instance refresh of the live ASG and the launch configuration it replaced
+	RefreshID                   *string `json:"refresh_id,omitempty"`
+	RefreshStatus               *string `json:"refresh_status,omitempty"`
+	PreviousLaunchConfiguration *string `json:"previous_launch_configuration,omitempty"`
+
+	// The policies and tags of the live ASG before it was updated in place
+	PreviousInPlace *InPlacePrevious `json:"previous_in_place,omitempty"`
+
+	// Services that must be healthy before this service is created
+	DependsOn []*string `json:"depends_on,omitempty"`
+
+	// Seconds after health checks start before this service is checked, controlled when StaggerHealthChecks is set
+	HealthCheckOffset *int `json:"health_check_offset,omitempty"`
+
+	// Percent of the desired capacity that must be healthy, rounded up, instead of the strategies target
+	MinHealthyPercentage *int `json:"min_healthy_percentage,omitempty"`
+
+	// CloudWatch alarms that must be OK for the service to be healthy
+	HealthAlarms []*string `json:"health_alarms,omitempty"`
+
+	// Max seconds cleanup waits for detached instances to drain from their target groups
+	DrainTimeout *int `json:"drain_timeout,omitempty"`
+
+	// LaunchBatchSize is the most instances launched at once, the desired capacity is raised by at most that
+	// many once every launched instance is healthy. Each batch fails if not healthy within LaunchBatchTimeout seconds
+	LaunchBatchSize    *int64     `json:"launch_batch_size,omitempty"`
+	LaunchBatchTimeout *int       `json:"launch_batch_timeout,omitempty"`
+	LaunchBatchAt      *time.Time `json:"launch_batch_at,omitempty"`
+
 	// What is Healthy
 	HealthReport *HealthReport `json:"healthy_report,omitempty"`
 	Healthy      bool
+
+	// All instances seen terminating during the release
+	TerminatedIDs []string `json:"terminated_ids,omitempty"`
+
+	// All instances reclaimed by spot interruptions during the release
+	SpotInterruptedIDs []string `json:"spot_interrupted_ids,omitempty"`
+
+	// Seconds each instance took from launch to InService, measured with a LaunchExtensionMax
+	LaunchDurations map[string]int `json:"launch_durations,omitempty"`
+
+	// The instances still launching and if any reached InService in the last check
+	launchesPending    int
+	launchesProgressed bool
+
+	// Seconds until the last pending instance is out of its health check grace period
+	graceRemaining int
 }
 
 //////////
@@ -140,6 +282,7 @@ func (service *Service) Subnets() []*string {
 func (service *Service) UserData() *string {
 	templateARGs := []string{}
 	templateARGs = append(templateARGs, "{{RELEASE_ID}}", to.Strs(service.ReleaseID()))
+	templateARGs = append(templateARGs, "{{RELEASE_UUID}}", to.Strs(service.ReleaseUUID()))
 	templateARGs = append(templateARGs, "{{PROJECT_NAME}}", to.Strs(service.ProjectName()))
 	templateARGs = append(templateARGs, "{{CONFIG_NAME}}", to.Strs(service.ConfigName()))
 	templateARGs = append(templateARGs, "{{SERVICE_NAME}}", to.Strs(service.ServiceName))
@@ -161,9 +304,22 @@ func (service *Service) SetUserData(userdata *string) {
 	service.userdata = userdata
 }
 
-// LifeCycleHooks returns
+// LifeCycleHooks returns the release lifecycle hooks merged with the services
 func (service *Service) LifeCycleHooks() map[string]*LifeCycleHook {
-	return service.release.LifeCycleHooks
+	if len(service.LifeCycleHooksVal) == 0 {
+		return service.release.LifeCycleHooks
+	}
+
+	hooks := map[string]*LifeCycleHook{}
+	for name, lc := range service.release.LifeCycleHooks {
+		hooks[name] = lc
+	}
+
+	for name, lc := range service.LifeCycleHooksVal {
+		hooks[name] = lc
+	}
+
+	return hooks
 }
 
 // SubnetIds returns
@@ -208,22 +364,69 @@ func (service *Service) SetDefaults(release *Release, serviceName string) {
 		}
 	}
 
+	service.setCanaryGraceDefault()
+
 	service.Autoscaling.SetDefaults(service.ServiceID(), service.release.Timeout)
 
+	for name, lc := range service.LifeCycleHooksVal {
+		if lc != nil {
+			lc.SetDefaults(service.release.AwsRegion, service.release.AwsAccountID, name)
+		}
+	}
+
+	if service.release.StaggerHealthChecks && service.HealthCheckOffset == nil && service.ServiceID() != nil && service.release.WaitForHealthy != nil {
+		service.HealthCheckOffset = to.Intp(healthCheckOffset(*service.ServiceID(), *service.release.WaitForHealthy))
+	}
+
 	service.strategy = NewStrategy(service.Autoscaling, service.PreviousDesiredCapacity)
 }
 
+// healthCheckOffset returns a jittered offset less than the wait between health checks
+// so staggering never delays a release by more than one health check
+func healthCheckOffset(serviceID string, waitForHealthy int) int {
+	if waitForHealthy <= 1 {
+		return 0
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(serviceID))
+	return int(h.Sum32() % uint32(waitForHealthy))
+}
+
+// healthCheckStarted returns true if the services offset has passed since health checks started
+func (service *Service) healthCheckStarted(startedAt *time.Time) bool {
+	if service.HealthCheckOffset == nil || startedAt == nil {
+		return true
+	}
+
+	return !time.Now().Before(startedAt.Add(time.Duration(*service.HealthCheckOffset) * time.Second))
+}
+
+// targetHealthy is the number of healthy instances the service needs. With MinHealthyPercentage it is
+// that percent of the desired capacity rounded up, so 95% of 3 instances is 3, and never less than 1
+func (service *Service) targetHealthy() int64 {
+	if service.MinHealthyPercentage == nil {
+		return service.strategy.TargetHealthy()
+	}
+
+	dc := service.strategy.DesiredCapacity()
+	pct := int64(*service.MinHealthyPercentage)
+	return max(1, (dc*pct+99)/100)
+}
+
 // setHealthy sets the health state from the instances
 func (service *Service) setHealthy(group *asg.ASG, instances aws.Instances) {
 	healthy := instances.HealthyIDs()
 	terming := instances.TerminatingIDs()
+	pending := instances.PendingIDs()
 
 	service.HealthReport = &HealthReport{
-		TargetHealthy:  to.Int64p(service.strategy.TargetHealthy()),
+		TargetHealthy:  to.Int64p(service.targetHealthy()),
 		TargetLaunched: to.Int64p(service.strategy.TargetCapacity()),
 		Healthy:        to.Intp(len(healthy)),
 		Terminating:    to.Intp(len(terming)),
 		TerminatingIDs: terming,
+		Pending:        to.Intp(len(pending)),
 		Launching:      to.Intp(len(instances)),
 
 		DesiredCapacity: group.DesiredCapacity,
@@ -232,7 +435,7 @@ func (service *Service) setHealthy(group *asg.ASG, instances aws.Instances) {
 
 	// The Service is Healthy if
 	// the number of instances that are healthy is greater than or equal to the target
-	service.Healthy = int64(len(healthy)) >= service.strategy.TargetHealthy()
+	service.Healthy = int64(len(healthy)) >= service.targetHealthy()
 }
 
 //////////
@@ -278,6 +481,18 @@ func (service *Service) ValidateAttributes() error {
 		return fmt.Errorf("InstanceType must be defined")
 	}
 
+	if err := service.validateInstanceTypes(); err != nil {
+		return err
+	}
+
+	if err := service.validateSpot(); err != nil {
+		return err
+	}
+
+	if err := service.validateHealthAlarms(); err != nil {
+		return err
+	}
+
 	if service.Autoscaling == nil {
 		return fmt.Errorf("Autoscaling must be defined")
 	}
@@ -295,6 +510,39 @@ func (service *Service) ValidateAttributes() error {
 		return fmt.Errorf("Security Group must be unique")
 	}
 
+	// Profile can be the instance profiles name or ARN
+	if service.Profile != nil {
+		if err := iam.ValidateProfile(*service.Profile); err != nil {
+			return err
+		}
+	}
+
+	if service.HealthCheckOffset != nil && service.release != nil && service.release.WaitForHealthy != nil {
+		if *service.HealthCheckOffset < 0 || *service.HealthCheckOffset > *service.release.WaitForHealthy {
+			return fmt.Errorf("HealthCheckOffset must be between 0 and WaitForHealthy")
+		}
+	}
+
+	if service.MinHealthyPercentage != nil && (*service.MinHealthyPercentage < 1 || *service.MinHealthyPercentage > 100) {
+		return fmt.Errorf("MinHealthyPercentage must be between 1 and 100")
+	}
+
+	if service.DrainTimeout != nil && (*service.DrainTimeout < 0 || *service.DrainTimeout > 3600) {
+		return fmt.Errorf("DrainTimeout must be between 0 and 3600")
+	}
+
+	if err := service.validateLaunchBatch(); err != nil {
+		return err
+	}
+
+	if lifetime := service.MaxInstanceLifetime; lifetime != nil && *lifetime != 0 && (*lifetime < minMaxInstanceLifetime || *lifetime > maxMaxInstanceLifetime) {
+		return fmt.Errorf("MaxInstanceLifetime must be 0 or between %v and %v", minMaxInstanceLifetime, maxMaxInstanceLifetime)
+	}
+
+	if err := service.validateSecurityGroupsLimit(len(service.SecurityGroups)); err != nil {
+		return err
+	}
+
 	if !is.UniqueStrp(service.ELBs) {
 		// Non unique string in ELBs or nil value
 		return fmt.Errorf("Non Unique ELBs")
@@ -305,17 +553,132 @@ func (service *Service) ValidateAttributes() error {
 		return fmt.Errorf("Non Unique TargetGroups")
 	}
 
+	for name, health := range service.TargetGroupHealth {
+		if !containsStrp(service.TargetGroups, name) {
+			return fmt.Errorf("TargetGroupHealth(%v) target group not in TargetGroups", name)
+		}
+
+		if health == nil {
+			return fmt.Errorf("TargetGroupHealth(%v) is nil", name)
+		}
+
+		if err := health.ValidateAttributes(); err != nil {
+			return fmt.Errorf("TargetGroupHealth(%v) %v", name, err.Error())
+		}
+	}
+
+	if service.Canary != nil {
+		if err := service.Canary.ValidateAttributes(); err != nil {
+			return err
+		}
+
+		if err := service.validateCanaryBake(); err != nil {
+			return err
+		}
+	}
+
 	if err := service.validatePlacementGroupAttributes(); err != nil {
 		return err
 	}
 
-	if service.PlacementTenancy != nil && !(*service.PlacementTenancy == "default" || *service.PlacementTenancy == "dedicated") {
-		return fmt.Errorf("Placement tenancy must be unset or set to 'default' or 'dedicated'.")
+	if err := service.validatePlacementGroupInstanceTypes(); err != nil {
+		return err
+	}
+
+	if err := service.validateAvailabilityZones(); err != nil {
+		return err
+	}
+
+	if err := service.validateSubnetSelection(); err != nil {
+		return err
+	}
+
+	if err := service.validateSuspendProcesses(); err != nil {
+		return err
+	}
+
+	if err := service.validateWarmPool(); err != nil {
+		return err
+	}
+
+	if err := service.validateReadinessCheck(); err != nil {
+		return err
+	}
+
+	if err := service.validateHealthCheckType(); err != nil {
+		return err
+	}
+
+	if err := service.validateHealthSources(); err != nil {
+		return err
+	}
+
+	if err := service.validateLaunchTemplateRetention(); err != nil {
+		return err
+	}
+
+	if err := service.validateCapacityReservation(); err != nil {
+		return err
+	}
+
+	if err := service.validateKeyName(); err != nil {
+		return err
+	}
+
+	if err := service.validatePrivateDnsNameOptions(); err != nil {
+		return err
+	}
+
+	if err := service.validateListenerRule(); err != nil {
+		return err
+	}
+
+	if err := service.validatePlacementTenancy(); err != nil {
+		return err
+	}
+
+	if err := service.validateNetworkInterfaces(); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+func containsStrp(strs []*string, str string) bool {
+	for _, s := range strs {
+		if s != nil && *s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueStrps removes duplicate values keeping the original order
+func uniqueStrps(strs []*string) []*string {
+	if strs == nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	unique := []*string{}
+	for _, str := range strs {
+		if str == nil {
+			// nil values are left to fail validation
+			unique = append(unique, str)
+			continue
+		}
+
+		if seen[*str] {
+			continue
+		}
+
+		seen[*str] = true
+		unique = append(unique, str)
+	}
+
+	return unique
+}
+
 func (service *Service) validatePlacementGroupAttributes() error {
 	// if PlacementGroupName is not nil, then there must be a Strategy either cluster | spread | partition
 	// if the strategy is partition then there must be a partition count
@@ -380,6 +743,39 @@ func (service *Service) FetchResources(ec2 aws.EC2API, elbc aws.ELBAPI, albc aws
 		}
 	}
 
+	// A missing capacity reservation fails ValidateResources
+	reservation, err := service.findCapacityReservation(ec2)
+	if err != nil {
+		return nil, err
+	}
+
+	// A missing key pair fails ValidateResources
+	keyPair, err := service.findKeyPair(ec2)
+	if err != nil {
+		return nil, err
+	}
+
+	// A missing listener rule fails ValidateResources
+	listenerRule, err := service.findListenerRule(albc)
+	if err != nil {
+		return nil, err
+	}
+
+	launchTemplateVersions, err := service.countLaunchTemplateVersions(ec2)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceTypes, err := service.describeInstanceTypes(ec2)
+	if err != nil {
+		return nil, err
+	}
+
+	networkInterfaces, err := service.findNetworkInterfaces(ec2)
+	if err != nil {
+		return nil, err
+	}
+
 	// FETCH IAM
 	var iamProfile *iam.Profile
 	if service.Profile != nil {
@@ -387,6 +783,12 @@ func (service *Service) FetchResources(ec2 aws.EC2API, elbc aws.ELBAPI, albc aws
 		if err != nil {
 			return nil, err
 		}
+
+		if len(service.requiredProfilePolicies()) > 0 {
+			if err := iamProfile.FetchPolicyARNs(iamc); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return &ServiceResources{
@@ -394,6 +796,13 @@ func (service *Service) FetchResources(ec2 aws.EC2API, elbc aws.ELBAPI, albc aws
 		ELBs:           elbs,
 		TargetGroups:   targetGroups,
 		Profile:        iamProfile,
+
+		CapacityReservation:    reservation,
+		KeyPair:                keyPair,
+		ListenerRule:           listenerRule,
+		LaunchTemplateVersions: launchTemplateVersions,
+		InstanceTypes:          instanceTypes,
+		NetworkInterfaces:      networkInterfaces,
 	}, nil
 }
 
@@ -402,11 +811,16 @@ func (service *Service) FetchResources(ec2 aws.EC2API, elbc aws.ELBAPI, albc aws
 //////////
 
 // CreateResources creates the ASG and Launch configuration for the service
-func (service *Service) CreateResources(asgc aws.ASGAPI, cwc aws.CWAPI) error {
+func (service *Service) CreateResources(asgc aws.ASGAPI, ec2c aws.EC2API, cwc aws.CWAPI, albc aws.ALBAPI) error {
 
-	err := service.createLaunchConfiguration(asgc)
-	if err != nil {
-		return err
+	if service.launchTemplate() {
+		if err := service.createLaunchTemplate(ec2c); err != nil {
+			return err
+		}
+	} else {
+		if err := service.createLaunchConfiguration(asgc); err != nil {
+			return err
+		}
 	}
 
 	createdASG, err := service.createASG(asgc)
@@ -416,11 +830,24 @@ func (service *Service) CreateResources(asgc aws.ASGAPI, cwc aws.CWAPI) error {
 	}
 
 	service.CreatedASG = createdASG.AutoScalingGroupName
+	service.LaunchBatchAt = to.Timep(time.Now())
+
+	if err := service.suspendDeployProcesses(asgc); err != nil {
+		return err
+	}
 
 	if err := service.createAutoScalingPolicies(asgc, cwc); err != nil {
 		return err
 	}
 
+	if err := service.createScheduledActions(asgc); err != nil {
+		return err
+	}
+
+	if err := service.createWarmPool(asgc); err != nil {
+		return err
+	}
+
 	service.setHealthy(createdASG, aws.Instances{})
 
 	if err := service.createMetricsCollection(asgc); err != nil {
@@ -430,23 +857,149 @@ func (service *Service) CreateResources(asgc aws.ASGAPI, cwc aws.CWAPI) error {
 	return nil
 }
 
+// odinTags are managed by odin and never removed by an in place update
+var odinTags = []string{"ProjectName", "ConfigName", "ServiceName", "ReleaseID", "ReleaseId", "ReleaseUUID", "Name"}
+
+// UpdateInPlace recreates the autoscaling policies and updates the tags of the live ASG in CreatedASG
+// Updating the ReleaseID tag moves the ASG to this release
+func (service *Service) UpdateInPlace(asgc aws.ASGAPI, cwc aws.CWAPI) error {
+	_, group, err := asg.GetInstances(asgc, service.CreatedASG)
+	if err != nil {
+		return err
+	}
+
+	if err := group.TeardownPolicies(asgc, cwc); err != nil {
+		return err
+	}
+
+	if err := service.createAutoScalingPolicies(asgc, cwc); err != nil {
+		return err
+	}
+
+	tags, removed := service.inPlaceTags(group.Tags())
+	if err := group.UpdateTags(asgc, tags, removed); err != nil {
+		return err
+	}
+
+	if service.Canary != nil {
+		// No instances are launched so there is nothing to canary
+		service.Canary.Baked = true
+	}
+
+	service.setHealthy(group, aws.Instances{})
+
+	return nil
+}
+
+// inPlaceTags returns the tags to set and the tag keys to remove from the live ASG
+func (service *Service) inPlaceTags(current map[string]*string) (map[string]*string, []string) {
+	tags := service.tags()
+	tags["ReleaseID"] = service.ReleaseID()
+	tags["ReleaseUUID"] = service.ReleaseUUID()
+
+	removed := []string{}
+	for key := range current {
+		if _, ok := tags[key]; ok || containsStr(odinTags, key) || strings.HasPrefix(key, "aws:") {
+			continue
+		}
+		removed = append(removed, key)
+	}
+
+	sort.Strings(removed)
+
+	return tags, removed
+}
+
+// launchDefinition returns the service as JSON without tags, autoscaling policies or
+// values controlled by the deployer. If it changes the service must be replaced
+func (service *Service) launchDefinition() string {
+	s := *service
+	s.Tags = nil
+	s.Resources = nil
+	s.CreatedASG = nil
+	s.CreatedLaunchTemplateVersion = nil
+	s.PreviousDesiredCapacity = nil
+	s.HealthCheckOffset = nil
+	s.HealthReport = nil
+	s.Healthy = false
+	s.TerminatedIDs = nil
+	s.SpotInterruptedIDs = nil
+	s.RefreshID = nil
+	s.RefreshStatus = nil
+	s.PreviousLaunchConfiguration = nil
+
+	if service.Autoscaling != nil {
+		as := *service.Autoscaling
+		as.Policies = nil
+		s.Autoscaling = &as
+	}
+
+	raw, _ := json.Marshal(s)
+	return string(raw)
+}
+
+// targetGroupHealthByArn returns the target_group_health keyed by the found target group ARN
+func (service *Service) targetGroupHealthByArn() map[string]*TargetGroupHealth {
+	byArn := map[string]*TargetGroupHealth{}
+	if service.Resources == nil || len(service.Resources.TargetGroups) != len(service.TargetGroups) {
+		return byArn
+	}
+
+	// Resources.TargetGroups are found in the same order as TargetGroups
+	for i, name := range service.TargetGroups {
+		health, ok := service.TargetGroupHealth[to.Strs(name)]
+		if !ok || service.Resources.TargetGroups[i] == nil {
+			continue
+		}
+
+		byArn[*service.Resources.TargetGroups[i]] = health
+	}
+
+	return byArn
+}
+
 func (service *Service) createInput() *asg.Input {
 	input := &asg.Input{&autoscaling.CreateAutoScalingGroupInput{}}
 
 	input.AutoScalingGroupName = service.ServiceID()
-	input.LaunchConfigurationName = service.ServiceID()
+
+	switch {
+	case service.mixedInstances():
+		input.MixedInstancesPolicy = service.mixedInstancesPolicy()
+	case service.launchTemplate():
+		input.LaunchTemplate = service.launchTemplateSpecification()
+	default:
+		input.LaunchConfigurationName = service.ServiceID()
+	}
 
 	// Adjusted by strategy
 	input.MinSize = service.strategy.InitialMinSize()
 	input.DesiredCapacity = service.strategy.InitialDesiredCapacity()
 
+	if service.canarying() {
+		input.MinSize = to.Int64p(min(*input.MinSize, service.canarySize()))
+		input.DesiredCapacity = to.Int64p(service.canarySize())
+	}
+
+	if batch := service.LaunchBatchSize; batch != nil {
+		input.DesiredCapacity = to.Int64p(min(*input.DesiredCapacity, *batch))
+		input.MinSize = to.Int64p(min(*input.MinSize, *input.DesiredCapacity))
+	}
+
 	// Unchanging values from AutoScalingConfig
 	input.MaxSize = service.Autoscaling.MaxSize
 	input.DefaultCooldown = service.Autoscaling.DefaultCooldown
 	input.HealthCheckGracePeriod = service.Autoscaling.HealthCheckGracePeriod
 
+	// Empty health check type is defaulted by SetDefaults
+	input.HealthCheckType = service.HealthCheckType
+
+	if service.MaxInstanceLifetime != nil && *service.MaxInstanceLifetime != 0 {
+		input.MaxInstanceLifetime = service.MaxInstanceLifetime
+	}
+
 	input.LoadBalancerNames = service.Resources.ELBs
-	input.TargetGroupARNs = service.Resources.TargetGroups
+	input.TargetGroupARNs = service.targetGroupArns()
 
 	input.VPCZoneIdentifier = service.SubnetIds()
 	input.LifecycleHookSpecificationList = service.LifeCycleHookSpecs()
@@ -455,7 +1008,10 @@ func (service *Service) createInput() *asg.Input {
 		input.PlacementGroup = service.PlacementGroupName
 	}
 
-	for key, value := range service.Tags {
+	// Empty policies are defaulted by SetDefaults
+	input.TerminationPolicies = service.TerminationPolicies
+
+	for key, value := range service.tags() {
 		input.AddTag(key, value)
 	}
 
@@ -473,7 +1029,7 @@ func (service *Service) createInput() *asg.Input {
 
 func (service *Service) createAutoScalingPolicies(asgc aws.ASGAPI, cwc aws.CWAPI) error {
 	for _, policy := range service.Autoscaling.Policies {
-		if err := policy.Create(asgc, cwc, service.ServiceID()); err != nil {
+		if err := policy.Create(asgc, cwc, service.CreatedASG); err != nil {
 			return err
 		}
 	}
@@ -493,6 +1049,11 @@ func (service *Service) createASG(asgc aws.ASGAPI) (*asg.ASG, error) {
 
 func (service *Service) createLaunchConfigurationInput() *lc.LaunchConfigInput {
 	input := &lc.LaunchConfigInput{&autoscaling.CreateLaunchConfigurationInput{}}
+
+	if service.EnableDetailedMonitoring != nil {
+		input.InstanceMonitoring = &autoscaling.InstanceMonitoring{Enabled: service.EnableDetailedMonitoring}
+	}
+
 	input.SetDefaults()
 
 	input.LaunchConfigurationName = service.ServiceID()
@@ -503,17 +1064,24 @@ func (service *Service) createLaunchConfigurationInput() *lc.LaunchConfigInput {
 		input.IamInstanceProfile = service.Resources.Profile
 	}
 	input.InstanceType = service.InstanceType
+	input.KeyName = service.KeyName
 
-	input.AssociatePublicIpAddress = service.AssociatePublicIpAddress
+	input.AssociatePublicIpAddress = service.associatePublicIPAddress()
 
 	input.UserData = to.Base64p(service.UserData())
 
-	input.AddBlockDevice(service.EBSVolumeSize, service.EBSVolumeType, service.EBSDeviceName)
+	input.AddBlockDevice(service.EBSVolumeSize, service.EBSVolumeType, service.EBSDeviceName, service.EBSIOPS, service.EBSThroughput)
+	input.BlockDeviceMappings = append(input.BlockDeviceMappings, service.blockDeviceMappings()...)
 
-	input.SpotPrice = service.SpotPrice
+	if !service.spot() {
+		// Spot ASGs set the max price in the instances distribution
+		input.SpotPrice = service.SpotPrice
+	}
 
 	input.PlacementTenancy = service.PlacementTenancy
 
+	input.MetadataOptions = service.metadataOptions()
+
 	return input
 }
 
@@ -560,49 +1128,176 @@ func (he *HaltError) Error() string {
 
 // UpdateHealthy updates the health status of the service
 // This might cause a Halt Error which will force the release to stop
-func (service *Service) UpdateHealthy(asgc aws.ASGAPI, elbc aws.ELBAPI, albc aws.ALBAPI) error {
+func (service *Service) UpdateHealthy(asgc aws.ASGAPI, ec2c aws.EC2API, elbc aws.ELBAPI, albc aws.ALBAPI, cwc aws.CWAPI, httpc aws.HTTPAPI) error {
 	all, group, err := asg.GetInstances(asgc, service.CreatedASG)
 	if err != nil {
 		return err // This might retry
 	}
 
+	if err := service.removeSpotInterruptions(ec2c, all); err != nil {
+		return err // This might retry
+	}
+
+	if err := service.checkLifecycleWait(ec2c, group); err != nil {
+		return err
+	}
+
+	if err := service.recordLaunches(ec2c, group); err != nil {
+		return err // This might retry
+	}
+
 	// Early exit and Halt if there are instances Terminating
 	if service.strategy.ReachedMaxTerminations(all) {
 		err := fmt.Errorf("Found terming instances %v, %v", *service.ServiceName, strings.Join(all.TerminatingIDs(), ","))
 		return &HaltError{err} // This will immediately stop deploying
 	}
 
+	if service.reachedCrashLoop(all) {
+		err := fmt.Errorf("Crash loop detected %v, %v instances terminated %v", *service.ServiceName, len(service.TerminatedIDs), strings.Join(service.TerminatedIDs, ","))
+		return &HaltError{err} // This will immediately stop deploying
+	}
+
+	service.ignoreASGHealth(all)
+
 	// Fetch All the instances
+	all, err = service.mergeLBInstances(elbc, albc, all)
+	if err != nil {
+		return err // This might retry
+	}
+
+	if err := service.setPending(ec2c, all); err != nil {
+		return err // This might retry
+	}
+
+	if err := service.setNotReady(ec2c, httpc, all); err != nil {
+		return err // This might retry
+	}
+
+	// Set the Healthy Value
+	service.setHealthy(group, all) // TODO: maybe use the new min and dc
+
+	if err := service.checkHealthAlarms(cwc); err != nil {
+		return err
+	}
+
+	if service.canarying() {
+		// Do not scale until the canary is baked
+		service.Healthy = false
+		return nil
+	}
+
+	// Use the strategy to calculate the new values of min_size and desired_capacity
+	min, dc := service.strategy.CalculateMinDesired(all)
+
+	min, dc, err = service.launchBatch(group, all, min, dc)
+	if err != nil {
+		return err
+	}
+
+	if err := service.SafeSetMinDesiredCapacity(asgc, group, min, dc); err != nil {
+		return fmt.Errorf("Setting Min and Desired Capacity Error for %v: %v", *service.ServiceName, err.Error())
+	}
+
+	return nil
+}
+
+// setPending marks unhealthy instances launched within the autoscaling health check grace period as pending
+// The grace period is from each instances launch time as instances come up staggered
+func (service *Service) setPending(ec2c aws.EC2API, all aws.Instances) error {
+	service.graceRemaining = 0
+
+	if service.Autoscaling == nil || service.Autoscaling.HealthCheckGracePeriod == nil || *service.Autoscaling.HealthCheckGracePeriod == 0 {
+		return nil
+	}
+
+	unhealthyIDs := all.UnhealthyIDs()
+	if len(unhealthyIDs) == 0 {
+		return nil
+	}
+
+	launchTimes, err := instance.LaunchTimes(ec2c, unhealthyIDs)
+	if err != nil {
+		return err
+	}
+
+	grace := time.Duration(*service.Autoscaling.HealthCheckGracePeriod) * time.Second
+	pendingIDs := []string{}
+	for id, launchTime := range launchTimes {
+		remaining := int(time.Until(launchTime.Add(grace)).Seconds())
+		if remaining <= 0 {
+			continue
+		}
+
+		pendingIDs = append(pendingIDs, id)
+		if remaining > service.graceRemaining {
+			service.graceRemaining = remaining
+		}
+	}
+
+	all.SetPending(pendingIDs)
+
+	return nil
+}
+
+// mergeLBInstances merges the health of the instances in the services ELBs and Target Groups
+func (service *Service) mergeLBInstances(elbc aws.ELBAPI, albc aws.ALBAPI, all aws.Instances) (aws.Instances, error) {
 	for _, checkELB := range service.Resources.ELBs {
+		if !service.healthSource(HealthSourceELB) {
+			continue
+		}
+
 		elbInstances, err := elb.GetInstances(elbc, checkELB, all.InstanceIDs())
 		if err != nil {
-			return err // This might retry
+			return nil, err // This might retry
 		}
 
 		all = all.MergeInstances(elbInstances)
 	}
 
-	for _, checkTG := range service.Resources.TargetGroups {
+	healthByArn := service.targetGroupHealthByArn()
+	for _, checkTG := range service.targetGroupArns() {
+		if health, ok := healthByArn[to.Strs(checkTG)]; ok {
+			tg, err := alb.FindHealthCheck(albc, checkTG)
+			if err != nil {
+				return nil, err // This might retry
+			}
+
+			if !health.Matches(tg) {
+				return nil, fmt.Errorf("TargetGroup %v health check does not match target_group_health: %v", *checkTG, strings.Join(health.Mismatches(tg), ", "))
+			}
+		}
+
+		if !service.healthSource(HealthSourceTargetGroup) {
+			continue
+		}
+
 		tgInstances, err := alb.GetInstances(albc, checkTG, all.InstanceIDs())
 
 		if err != nil {
-			return err // This might retry
+			return nil, err // This might retry
 		}
 
 		all = all.MergeInstances(tgInstances)
 	}
 
-	// Set the Healthy Value
-	service.setHealthy(group, all) // TODO: maybe use the new min and dc
+	return all, nil
+}
 
-	// Use the strategy to calculate the new values of min_size and desired_capacity
-	min, dc := service.strategy.CalculateMinDesired(all)
+// reachedCrashLoop records the terminating instances and returns true if
+// the terminations per instance slot is greater than MaxTerminationsPerInstance
+func (service *Service) reachedCrashLoop(instances aws.Instances) bool {
+	for _, id := range instances.TerminatingIDs() {
+		if !containsStr(service.TerminatedIDs, id) {
+			service.TerminatedIDs = append(service.TerminatedIDs, id)
+		}
+	}
 
-	if err := service.SafeSetMinDesiredCapacity(asgc, group, min, dc); err != nil {
-		return fmt.Errorf("Setting Min and Desired Capacity Error for %v: %v", *service.ServiceName, err.Error())
+	if service.Autoscaling.MaxTerminationsPerInstance == nil {
+		return false
 	}
 
-	return nil
+	slots := float64(max(service.strategy.TargetCapacity(), 1))
+	return float64(len(service.TerminatedIDs))/slots > *service.Autoscaling.MaxTerminationsPerInstance
 }
 
 //////////
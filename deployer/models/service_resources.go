@@ -3,6 +3,8 @@ package models
 import (
 	"fmt"
 
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/coinbase/odin/aws"
 	"github.com/coinbase/odin/aws/alb"
 	"github.com/coinbase/odin/aws/ami"
@@ -40,6 +42,19 @@ type ServiceResources struct {
 	ELBs           []*elb.LoadBalancer
 	TargetGroups   []*alb.TargetGroup
 	Subnets        []*subnet.Subnet
+
+	CapacityReservation *ec2.CapacityReservation
+	KeyPair             *ec2.KeyPairInfo
+	ListenerRule        *elbv2.Rule
+
+	// Descriptions of the instance types the service launches by name
+	InstanceTypes map[string]*ec2.InstanceTypeInfo
+
+	// Number of versions of the services shared launch template
+	LaunchTemplateVersions int
+
+	// Secondary network interfaces
+	NetworkInterfaces []*NetworkInterfaceResources
 }
 
 // ServiceResourceNames struct
@@ -51,6 +66,8 @@ type ServiceResourceNames struct {
 	ELBs           []*string `json:"elbs,omitempty"`
 	TargetGroups   []*string `json:"target_group_arns,omitempty"`
 	Subnets        []*string `json:"subnets,omitempty"`
+
+	NetworkInterfaces []*NetworkInterfaceNames `json:"network_interfaces,omitempty"`
 }
 
 // ToServiceResourceNames returns
@@ -78,6 +95,7 @@ func (sr *ServiceResources) ToServiceResourceNames() *ServiceResourceNames {
 
 		sgs = append(sgs, sg.GroupID)
 	}
+	sgs = uniqueStrps(sgs)
 
 	elbs := []*string{}
 	for _, elb := range sr.ELBs {
@@ -114,6 +132,8 @@ func (sr *ServiceResources) ToServiceResourceNames() *ServiceResourceNames {
 		ELBs:           elbs,
 		TargetGroups:   tgs,
 		Subnets:        subnets,
+
+		NetworkInterfaces: sr.networkInterfaceNames(),
 	}
 }
 
@@ -124,15 +144,43 @@ func (sr *ServiceResources) Validate(service *Service) error {
 		return err
 	}
 
+	if err := service.validateBlockDevices(); err != nil {
+		return err
+	}
+
+	if err := service.validateInstanceMetadataOptions(); err != nil {
+		return err
+	}
+
+	if err := service.validateLaunchTemplateOverrides(); err != nil {
+		return err
+	}
+
+	if err := service.validateTerminationPolicies(); err != nil {
+		return err
+	}
+
+	if err := sr.validateHealthCheckType(service); err != nil {
+		return err
+	}
+
 	if err := ValidateImage(service, sr.Image); err != nil {
 		return err
 	}
 
+	if err := sr.validateImageArchitecture(service); err != nil {
+		return err
+	}
+
 	// Now the Easy Validations are over time to validate Tags and Paths
 	if err := ValidateIAMProfile(service, sr.Profile); err != nil {
 		return err
 	}
 
+	if err := service.validateRequiredProfilePolicies(sr.Profile); err != nil {
+		return err
+	}
+
 	if err := ValidatePrevASG(service, sr.PrevASG); err != nil {
 		return err
 	}
@@ -161,6 +209,102 @@ func (sr *ServiceResources) Validate(service *Service) error {
 		}
 	}
 
+	if err := sr.validateNetworkInterfaces(service); err != nil {
+		return err
+	}
+
+	if err := sr.validatePlacementGroupZones(service); err != nil {
+		return err
+	}
+
+	if err := sr.validateCapacityReservation(service); err != nil {
+		return err
+	}
+
+	if err := sr.validateKeyPair(service); err != nil {
+		return err
+	}
+
+	if err := sr.validateListenerRule(service); err != nil {
+		return err
+	}
+
+	if err := sr.validateLaunchTemplateVersions(service); err != nil {
+		return err
+	}
+
+	if err := sr.validateTargetGroupHealth(service); err != nil {
+		return err
+	}
+
+	if err := sr.validateHealthCheckIngress(service); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateHealthCheckIngress errors if the services security groups do not let its load balancers
+// reach the health check port, otherwise instances never become healthy and CheckHealthy times out.
+// Load balancers without security groups, e.g. NLBs, are not checked
+func (sr *ServiceResources) validateHealthCheckIngress(service *Service) error {
+	for _, lb := range sr.ELBs {
+		if lb == nil || lb.HealthCheckPort == nil || len(lb.SecurityGroups) == 0 {
+			continue
+		}
+
+		if !sr.allowsIngress(*lb.HealthCheckPort, lb.SecurityGroups) {
+			return fmt.Errorf("SecurityGroups do not allow ELB(%v) security groups %v to reach health check port %v", to.Strs(lb.LoadBalancerName), to.StrSlice(lb.SecurityGroups), *lb.HealthCheckPort)
+		}
+	}
+
+	for _, tg := range sr.TargetGroups {
+		if tg == nil || len(tg.LoadBalancerSecurityGroups) == 0 {
+			continue
+		}
+
+		port, ok := tg.HealthCheckPortNumber()
+		if !ok {
+			continue
+		}
+
+		if !sr.allowsIngress(port, tg.LoadBalancerSecurityGroups) {
+			return fmt.Errorf("SecurityGroups do not allow TargetGroup(%v) load balancer security groups %v to reach health check port %v", to.Strs(tg.TargetGroupName), to.StrSlice(tg.LoadBalancerSecurityGroups), port)
+		}
+	}
+
+	return nil
+}
+
+// allowsIngress returns true if any of the services security groups allow the port from the sources
+func (sr *ServiceResources) allowsIngress(port int64, sourceGroupIDs []*string) bool {
+	for _, group := range sr.SecurityGroups {
+		if group != nil && group.AllowsIngress(port, sourceGroupIDs) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateTargetGroupHealth errors if a target group does not have its target_group_health health check
+// Target groups are shared with the previous release, so they must be changed outside of a deploy
+func (sr *ServiceResources) validateTargetGroupHealth(service *Service) error {
+	for _, tg := range sr.TargetGroups {
+		if tg == nil {
+			continue
+		}
+
+		health, ok := service.TargetGroupHealth[to.Strs(tg.TargetGroupName)]
+		if !ok || health == nil {
+			continue
+		}
+
+		if err := health.ValidateTargetGroup(tg); err != nil {
+			return fmt.Errorf("TargetGroupHealth(%v) %v", to.Strs(tg.TargetGroupName), err.Error())
+		}
+	}
+
 	return nil
 }
 
@@ -177,6 +321,14 @@ func (sr *ServiceResources) validateAttributes(service *Service) error {
 		return fmt.Errorf("Security Group Not Found actual %v expected %v", to.StrSlice(names.SecurityGroups), to.StrSlice(service.SecurityGroups))
 	}
 
+	if len(names.SecurityGroups) != len(sr.SecurityGroups) {
+		return fmt.Errorf("Security Groups contain duplicate group ids %v", to.StrSlice(names.SecurityGroups))
+	}
+
+	if err := service.validateSecurityGroupsLimit(len(names.SecurityGroups)); err != nil {
+		return err
+	}
+
 	if len(service.ELBs) != len(sr.ELBs) {
 		return fmt.Errorf("ELB Not Found actual %v expected %v", to.StrSlice(names.ELBs), to.StrSlice(service.ELBs))
 	}
@@ -185,6 +337,20 @@ func (sr *ServiceResources) validateAttributes(service *Service) error {
 		return fmt.Errorf("TargetGroup Not Found actual %v expected %v", to.StrSlice(names.TargetGroups), to.StrSlice(service.TargetGroups))
 	}
 
+	if service.AvailabilityZones != nil {
+		if err := sr.validateSubnetAvailabilityZones(service); err != nil {
+			return err
+		}
+	}
+
+	if service.SubnetSelection != nil {
+		return sr.validateSelectedSubnets(service)
+	}
+
+	if service.AvailabilityZones != nil {
+		return nil
+	}
+
 	if len(service.Subnets()) != len(sr.Subnets) {
 		return fmt.Errorf("Subnets Not Found actual %v expected %v", to.StrSlice(names.Subnets), to.StrSlice(service.Subnets()))
 	}
@@ -222,3 +222,41 @@ func Test_Service_ValidateTargetGroup(t *testing.T) {
 		ServiceNameTag: to.Strp("servicename"),
 	}))
 }
+
+func Test_Release_FetchResources_Profile_NameOrARN(t *testing.T) {
+	for _, profile := range []string{"web-profile", "arn:aws:iam::000000000000:instance-profile/odin/project/config/web/web-profile"} {
+		r := MockRelease(t)
+		r.Services["web"].Profile = to.Strp(profile)
+		MockPrepareRelease(r)
+		assert.NoError(t, r.ValidateServices())
+
+		awsc := MockAwsClients(r)
+		resources, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+		assert.NoError(t, err)
+		assert.NoError(t, r.ValidateResources(resources))
+
+		// The launch configuration uses the profiles ARN
+		r.UpdateWithResources(resources)
+		assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+		assert.Equal(t, "arn:aws:iam::000000000000:instance-profile/odin/project/config/web/web-profile", *awsc.ASG.CreateLaunchConfigurationInputs[0].IamInstanceProfile)
+	}
+}
+
+func Test_Release_FetchResources_Profile_NotFound(t *testing.T) {
+	for _, profile := range []string{"missing-profile", "arn:aws:iam::000000000000:instance-profile/odin/project/config/web/missing-profile"} {
+		r := MockRelease(t)
+		r.Services["web"].Profile = to.Strp(profile)
+		MockPrepareRelease(r)
+
+		awsc := MockAwsClients(r)
+		_, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "Not Found")
+	}
+
+	// A malformed ARN fails validation before any resources are fetched
+	r := MockRelease(t)
+	r.Services["web"].Profile = to.Strp("arn:aws:iam::000000000000:role/web-profile")
+	MockPrepareRelease(r)
+	assert.Error(t, r.ValidateServices())
+}
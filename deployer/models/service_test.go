@@ -32,6 +32,30 @@ func Test_Service_CreateInput_HealthCheckGracePeriod(t *testing.T) {
 	assert.Equal(t, *input.HealthCheckGracePeriod, int64(10))
 }
 
+func Test_Service_DetailedMonitoring_LaunchConfiguration(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+
+	// Off by default
+	assert.False(t, *release.Services["web"].createLaunchConfigurationInput().InstanceMonitoring.Enabled)
+
+	release.Services["web"].EnableDetailedMonitoring = to.Boolp(true)
+	assert.True(t, *release.Services["web"].createLaunchConfigurationInput().InstanceMonitoring.Enabled)
+}
+
+func Test_Release_CreateResources_DetailedMonitoring_LaunchTemplate(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].InstanceTypes = mockInstanceTypes("m5.large", "c5.large")
+	release.Services["web"].EnableDetailedMonitoring = to.Boolp(true)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	monitoring := awsc.EC2.LaunchTemplateMonitoring[*release.Services["web"].ServiceID()]
+	assert.True(t, *monitoring.Enabled)
+}
+
 func Test_Service_PlacementgroupValidation(t *testing.T) {
 	// bad strat
 	service := Service{
@@ -111,6 +135,87 @@ func Test_Service_CapacityValues(t *testing.T) {
 	assert.EqualValues(t, 36, service.strategy.TargetCapacity())  // The number of launched instances
 }
 
+func Test_Service_MinHealthyPercentage_TargetHealthy(t *testing.T) {
+	service := &Service{
+		Autoscaling: &AutoScalingConfig{
+			MinSize: to.Int64p(int64(3)),
+			MaxSize: to.Int64p(int64(10)),
+		},
+	}
+
+	service.SetDefaults(&Release{}, "asd")
+	assert.EqualValues(t, 3, service.strategy.DesiredCapacity())
+	assert.Equal(t, service.strategy.TargetHealthy(), service.targetHealthy())
+
+	// Rounded up so 95% of 3 instances is all 3
+	service.MinHealthyPercentage = to.Intp(95)
+	assert.EqualValues(t, 3, service.targetHealthy())
+
+	service.MinHealthyPercentage = to.Intp(50)
+	assert.EqualValues(t, 2, service.targetHealthy())
+
+	service.MinHealthyPercentage = to.Intp(1)
+	assert.EqualValues(t, 1, service.targetHealthy())
+
+	service.MinHealthyPercentage = to.Intp(100)
+	assert.EqualValues(t, 3, service.targetHealthy())
+}
+
+func Test_Service_MinHealthyPercentage_Validate(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	service := r.Services["web"]
+
+	service.MinHealthyPercentage = to.Intp(95)
+	assert.NoError(t, r.ValidateServices())
+
+	service.MinHealthyPercentage = to.Intp(0)
+	assert.Error(t, r.ValidateServices())
+
+	service.MinHealthyPercentage = to.Intp(101)
+	assert.Error(t, r.ValidateServices())
+}
+
+func Test_Service_MaxInstanceLifetime_Validate(t *testing.T) {
+	r := MockRelease(t)
+	MockPrepareRelease(r)
+	service := r.Services["web"]
+
+	for _, lifetime := range []int64{0, 86400, 604800, 31536000} {
+		service.MaxInstanceLifetime = to.Int64p(lifetime)
+		assert.NoError(t, r.ValidateServices())
+	}
+
+	for _, lifetime := range []int64{-1, 1, 86399, 31536001} {
+		service.MaxInstanceLifetime = to.Int64p(lifetime)
+		err := r.ValidateServices()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "MaxInstanceLifetime")
+	}
+}
+
+func Test_Release_MaxInstanceLifetime_CreateResources(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(r *Release) {
+		r.Services["web"].MaxInstanceLifetime = to.Int64p(604800)
+	})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	created := *release.Services["web"].CreatedASG
+	assert.Equal(t, int64(604800), awsc.ASG.MaxInstanceLifetimes[created])
+}
+
+func Test_Release_MaxInstanceLifetime_Disabled(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(r *Release) {
+		r.Services["web"].MaxInstanceLifetime = to.Int64p(0)
+	})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	_, ok := awsc.ASG.MaxInstanceLifetimes[*release.Services["web"].CreatedASG]
+	assert.False(t, ok)
+}
+
 func Test_Service_SafeSetMinDesiredCapacity_Works(t *testing.T) {
 	awsc := mocks.MockAWS()
 	service := &Service{}
@@ -129,3 +234,34 @@ func Test_Service_SafeSetMinDesiredCapacity_Works(t *testing.T) {
 	assert.Equal(t, int64(3), *awsc.ASG.UpdateAutoScalingGroupLastInput.DesiredCapacity)
 	assert.Equal(t, int64(2), *awsc.ASG.UpdateAutoScalingGroupLastInput.MinSize)
 }
+
+func Test_Service_SecurityGroups_Duplicates(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].SecurityGroups = []*string{to.Strp("web-sg"), to.Strp("web-sg")}
+	MockPrepareRelease(r)
+
+	err := r.ValidateServices()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Security Group must be unique")
+}
+
+func Test_Service_SecurityGroups_OverLimit(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].SecurityGroups = []*string{}
+	for i := 0; i <= defaultMaxSecurityGroupsPerENI; i++ {
+		r.Services["web"].SecurityGroups = append(r.Services["web"].SecurityGroups, to.Strp(fmt.Sprintf("sg-%v", i)))
+	}
+	MockPrepareRelease(r)
+
+	err := r.ValidateServices()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "limit of 5")
+
+	// Accounts can raise the limit
+	r.MaxSecurityGroupsPerENI = to.Intp(10)
+	assert.NoError(t, r.ValidateMaxSecurityGroupsPerENI())
+	assert.NoError(t, r.ValidateServices())
+
+	r.MaxSecurityGroupsPerENI = to.Intp(17)
+	assert.Error(t, r.ValidateMaxSecurityGroupsPerENI())
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/odin/aws/instance"
+	"github.com/coinbase/odin/aws/lambda"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Smoke Test
+//////////
+
+// SmokeTestInput is what the SmokeTest Lambda is invoked with, the release metadata and the new fleet of each service
+type SmokeTestInput struct {
+	ProjectName  *string             `json:"project_name,omitempty"`
+	ConfigName   *string             `json:"config_name,omitempty"`
+	ReleaseID    *string             `json:"release_id,omitempty"`
+	ReleaseUUID  *string             `json:"release_uuid,omitempty"`
+	AwsAccountID *string             `json:"aws_account_id,omitempty"`
+	AwsRegion    *string             `json:"aws_region,omitempty"`
+	Services     []*SmokeTestService `json:"services"`
+}
+
+// SmokeTestService is the new ASG of a service and its healthy instances
+type SmokeTestService struct {
+	ServiceName       *string              `json:"service_name,omitempty"`
+	AutoScalingGroup  *string              `json:"autoscaling_group,omitempty"`
+	LoadBalancerNames []*string            `json:"elbs"`
+	TargetGroupARNs   []*string            `json:"target_group_arns"`
+	Instances         []*SmokeTestInstance `json:"instances"`
+}
+
+// SmokeTestInstance is a healthy instance of the new fleet
+type SmokeTestInstance struct {
+	InstanceID *string `json:"instance_id,omitempty"`
+	PrivateIP  *string `json:"private_ip,omitempty"`
+}
+
+// SmokeTestResponse is the SmokeTest Lambdas response, the release only continues if passed is true
+type SmokeTestResponse struct {
+	Passed  bool    `json:"passed"`
+	Message *string `json:"message,omitempty"`
+}
+
+// ValidateSmokeTest checks the smoke test is a Lambda function ARN
+func (release *Release) ValidateSmokeTest() error {
+	if release.SmokeTest == nil {
+		return nil
+	}
+
+	if is.EmptyStr(release.SmokeTest) || !strings.HasPrefix(*release.SmokeTest, "arn:aws:lambda:") || !strings.Contains(*release.SmokeTest, ":function:") {
+		return fmt.Errorf("SmokeTest must be a Lambda function ARN")
+	}
+
+	return nil
+}
+
+// RunSmokeTest invokes the SmokeTest with the new fleet of every service once it is healthy and before the old fleet
+// is removed, an error, a non 2xx response or a response that did not pass fails the release
+func (release *Release) RunSmokeTest(lambdac aws.LambdaAPI, asgc aws.ASGAPI, ec2c aws.EC2API) error {
+	if release.SmokeTest == nil {
+		return nil
+	}
+
+	input := &SmokeTestInput{
+		ProjectName:  release.ProjectName,
+		ConfigName:   release.ConfigName,
+		ReleaseID:    release.ReleaseID,
+		ReleaseUUID:  release.UUID,
+		AwsAccountID: release.AwsAccountID,
+		AwsRegion:    release.AwsRegion,
+		Services:     []*SmokeTestService{},
+	}
+
+	for _, name := range sortedServiceNames(release) {
+		fleet, err := release.Services[name].smokeTestService(asgc, ec2c)
+		if err != nil {
+			return fmt.Errorf("SmokeTest %v", err.Error())
+		}
+		input.Services = append(input.Services, fleet)
+	}
+
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	raw, err := lambda.Invoke(lambdac, release.SmokeTest, payload)
+	if err != nil {
+		return fmt.Errorf("SmokeTest %v", err.Error())
+	}
+
+	var resp SmokeTestResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return fmt.Errorf("SmokeTest response %v", err.Error())
+	}
+
+	if !resp.Passed {
+		if is.EmptyStr(resp.Message) {
+			return fmt.Errorf("SmokeTest failed")
+		}
+		return fmt.Errorf("SmokeTest failed: %v", *resp.Message)
+	}
+
+	return nil
+}
+
+// smokeTestService returns the new ASG of the service with its healthy instances and their private IPs
+func (service *Service) smokeTestService(asgc aws.ASGAPI, ec2c aws.EC2API) (*SmokeTestService, error) {
+	fleet := &SmokeTestService{
+		ServiceName:       service.ServiceName,
+		AutoScalingGroup:  service.CreatedASG,
+		LoadBalancerNames: []*string{},
+		TargetGroupARNs:   []*string{},
+		Instances:         []*SmokeTestInstance{},
+	}
+
+	if service.Resources != nil {
+		fleet.LoadBalancerNames = append(fleet.LoadBalancerNames, service.Resources.ELBs...)
+		fleet.TargetGroupARNs = append(fleet.TargetGroupARNs, service.Resources.TargetGroups...)
+	}
+
+	all, _, err := asg.GetInstances(asgc, service.CreatedASG)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := all.HealthyIDs()
+	if len(ids) == 0 {
+		return fleet, nil
+	}
+	sort.Strings(ids)
+
+	ips, err := instance.PrivateIPs(ec2c, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		i := &SmokeTestInstance{InstanceID: to.Strp(id)}
+		if ip, ok := ips[id]; ok {
+			i.PrivateIP = to.Strp(ip)
+		}
+		fleet.Instances = append(fleet.Instances, i)
+	}
+
+	return fleet, nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidateSmokeTest(t *testing.T) {
+	release := MockRelease(t)
+	assert.NoError(t, release.ValidateSmokeTest())
+
+	release.SmokeTest = to.Strp("arn:aws:lambda:us-east-1:000000:function:smoke")
+	assert.NoError(t, release.ValidateSmokeTest())
+
+	for _, arn := range []string{"", "smoke", "arn:aws:sns:us-east-1:000000:smoke"} {
+		release.SmokeTest = to.Strp(arn)
+		assert.Error(t, release.ValidateSmokeTest(), arn)
+	}
+}
+
+func Test_Release_RunSmokeTest(t *testing.T) {
+	smoke := "arn:aws:lambda:us-east-1:000000:function:smoke"
+	release, awsc := mockSuspendProcessesRelease(t, func(r *Release) {})
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	awsc.EC2.SetPrivateIP("InstanceId1", "10.0.0.1")
+
+	// No smoke test is not invoked
+	assert.NoError(t, release.RunSmokeTest(awsc.Lambda, awsc.ASG, awsc.EC2))
+	assert.Equal(t, 0, len(awsc.Lambda.InvokeInputs))
+
+	release.SmokeTest = to.Strp(smoke)
+	awsc.Lambda.AddInvokeResponse(smoke, 200, `{"passed": true}`)
+	assert.NoError(t, release.RunSmokeTest(awsc.Lambda, awsc.ASG, awsc.EC2))
+
+	// The Lambda is given the new fleet
+	var input SmokeTestInput
+	assert.NoError(t, json.Unmarshal(awsc.Lambda.InvokeInputs[0].Payload, &input))
+	assert.Equal(t, "project", *input.ProjectName)
+	assert.Equal(t, "1", *input.ReleaseID)
+	assert.Equal(t, 1, len(input.Services))
+
+	web := input.Services[0]
+	assert.Equal(t, "web", *web.ServiceName)
+	assert.Equal(t, *release.Services["web"].CreatedASG, *web.AutoScalingGroup)
+	assert.Equal(t, []string{"web-elb"}, to.StrSlice(web.LoadBalancerNames))
+	assert.Equal(t, 1, len(web.TargetGroupARNs))
+	assert.Equal(t, 1, len(web.Instances))
+	assert.Equal(t, "InstanceId1", *web.Instances[0].InstanceID)
+	assert.Equal(t, "10.0.0.1", *web.Instances[0].PrivateIP)
+
+	awsc.Lambda.AddInvokeResponse(smoke, 200, `{"passed": false, "message": "checkout returned 500"}`)
+	err := release.RunSmokeTest(awsc.Lambda, awsc.ASG, awsc.EC2)
+	assert.Error(t, err)
+	assert.Equal(t, "SmokeTest failed: checkout returned 500", err.Error())
+
+	awsc.Lambda.AddInvokeResponse(smoke, 200, `{}`)
+	assert.EqualError(t, release.RunSmokeTest(awsc.Lambda, awsc.ASG, awsc.EC2), "SmokeTest failed")
+
+	awsc.Lambda.AddInvokeResponse(smoke, 200, "not json")
+	assert.Error(t, release.RunSmokeTest(awsc.Lambda, awsc.ASG, awsc.EC2))
+
+	awsc.Lambda.AddInvokeResponse(smoke, 500, `{"passed": true}`)
+	assert.Error(t, release.RunSmokeTest(awsc.Lambda, awsc.ASG, awsc.EC2))
+}
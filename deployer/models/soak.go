@@ -0,0 +1,100 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/alarms"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Soak
+//////////
+
+// ValidateSoak validates the soak attributes
+func (release *Release) ValidateSoak() error {
+	if release.SoakDuration != nil && (*release.SoakDuration < 0 || *release.SoakDuration > 172800) {
+		return fmt.Errorf("SoakDuration must be between 0 and 172800 (48 hours)")
+	}
+
+	if len(release.SoakAlarms) > 0 && !is.UniqueStrp(release.SoakAlarms) {
+		return fmt.Errorf("Non Unique SoakAlarms")
+	}
+
+	// Soak, Soaked? and WaitForSoak loop every WaitForHealthy like the health checks,
+	// so the soak has the same limit on Step Functions History Events as the Timeout
+	if release.SoakDuration != nil && release.WaitForHealthy != nil {
+		if (5.0/float64(release.healthPollWait()))*(float64(*release.SoakDuration)) > 10000.0 {
+			return fmt.Errorf("Rule of Thumb (5/WaitForHealthy) * SoakDuration < 10k")
+		}
+	}
+
+	return nil
+}
+
+// UpdateSoaked sets Soaked once the soak duration has passed
+// If any soak alarm is in the ALARM state a HaltError is returned
+func (release *Release) UpdateSoaked(cwc aws.CWAPI) error {
+	if release.SoakStartedAt == nil {
+		release.SoakStartedAt = to.Timep(time.Now())
+	}
+
+	states, err := alarms.States(cwc, release.SoakAlarms)
+	if err != nil {
+		return err // This might retry
+	}
+
+	alarming := []string{}
+	for name, state := range states {
+		if state == "ALARM" {
+			alarming = append(alarming, name)
+		}
+	}
+
+	if len(alarming) > 0 {
+		err := fmt.Errorf("Soak alarms in ALARM state %v", strings.Join(alarming, ","))
+		return &HaltError{err} // This will immediately roll back
+	}
+
+	soakDuration := 0
+	if release.SoakDuration != nil {
+		soakDuration = *release.SoakDuration
+	}
+
+	soaked := !time.Now().Before(release.SoakStartedAt.Add(time.Duration(soakDuration) * time.Second))
+	release.Soaked = &soaked
+
+	return nil
+}
+
+// CheckSoakHealthy runs the CheckHealthy checks again while soaking
+// If the release degrades a HaltError is returned so the new release is torn down
+func (release *Release) CheckSoakHealthy(asgc aws.ASGAPI, ec2c aws.EC2API, elbc aws.ELBAPI, albc aws.ALBAPI, cwc aws.CWAPI, httpc aws.HTTPAPI) error {
+	if release.SoakDuration == nil || *release.SoakDuration == 0 {
+		return nil // No soak, the release was just checked healthy
+	}
+
+	if err := release.UpdateHealthy(asgc, ec2c, elbc, albc, cwc, httpc); err != nil {
+		return err
+	}
+
+	unhealthy := []string{}
+	for name, service := range release.Services {
+		if !service.Healthy {
+			unhealthy = append(unhealthy, name)
+		}
+	}
+
+	if len(unhealthy) > 0 {
+		sort.Strings(unhealthy)
+		err := fmt.Errorf("Services unhealthy during soak %v", strings.Join(unhealthy, ","))
+		return &HaltError{err} // This will immediately roll back
+	}
+
+	return nil
+}
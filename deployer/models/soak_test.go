@@ -0,0 +1,78 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ValidateSoak(t *testing.T) {
+	r := MockRelease(t)
+	assert.NoError(t, r.ValidateSoak())
+
+	r.SoakDuration = to.Intp(-1)
+	assert.Error(t, r.ValidateSoak())
+
+	r.SoakDuration = to.Intp(600)
+	r.SoakAlarms = []*string{to.Strp("a"), to.Strp("a")}
+	assert.Error(t, r.ValidateSoak())
+
+	// The soak polls every WaitForHealthy
+	r.SoakAlarms = nil
+	r.WaitForHealthy = to.Intp(15)
+	r.SoakDuration = to.Intp(30000)
+	assert.NoError(t, r.ValidateSoak())
+
+	r.SoakDuration = to.Intp(172800)
+	err := r.ValidateSoak()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Rule of Thumb")
+}
+
+func Test_Release_UpdateSoaked(t *testing.T) {
+	cwc := &mocks.CWClient{}
+	cwc.AddAlarm("errors", "OK")
+
+	r := MockRelease(t)
+	r.SoakDuration = to.Intp(600)
+	r.SoakAlarms = []*string{to.Strp("errors")}
+
+	assert.NoError(t, r.UpdateSoaked(cwc))
+	assert.False(t, *r.Soaked)
+
+	r.SoakStartedAt = to.Timep(time.Now().Add(-601 * time.Second))
+	assert.NoError(t, r.UpdateSoaked(cwc))
+	assert.True(t, *r.Soaked)
+
+	cwc.AddAlarm("errors", "ALARM")
+	err := r.UpdateSoaked(cwc)
+	assert.Error(t, err)
+	assert.IsType(t, &HaltError{}, err)
+
+	// Missing alarms error
+	r.SoakAlarms = []*string{to.Strp("unknown")}
+	assert.Error(t, r.UpdateSoaked(cwc))
+}
+
+func Test_Release_CheckSoakHealthy(t *testing.T) {
+	r, awsc := mockSuspendProcessesRelease(t, func(*Release) {})
+
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, *r.Healthy)
+
+	// Instances go out of service once soaking
+	awsc.ELB.OutOfServiceAfter = map[string]int{"web-elb": 0}
+
+	// No soak, nothing is checked
+	assert.NoError(t, r.CheckSoakHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+
+	r.SoakDuration = to.Intp(600)
+	err := r.CheckSoakHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP)
+	assert.Error(t, err)
+	assert.IsType(t, &HaltError{}, err)
+	assert.Regexp(t, "Services unhealthy during soak web", err.Error())
+}
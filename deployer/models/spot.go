@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/instance"
+	"github.com/coinbase/step/utils/to"
+)
+
+// SPOT_ALLOCATION_STRATEGIES are the allowed spot allocation strategies
+var SPOT_ALLOCATION_STRATEGIES = []string{"capacity-optimized", "lowest-price"}
+
+// spot returns true if the ASG runs spot instances above the on demand base capacity
+func (service *Service) spot() bool {
+	return service.OnDemandBaseCapacity != nil
+}
+
+// validateSpot validates the spot attributes
+func (service *Service) validateSpot() error {
+	if !service.spot() {
+		if service.SpotAllocationStrategy != nil {
+			return fmt.Errorf("SpotAllocationStrategy requires OnDemandBaseCapacity")
+		}
+		return nil
+	}
+
+	if *service.OnDemandBaseCapacity < 0 {
+		return fmt.Errorf("OnDemandBaseCapacity must be greater than or equal to 0")
+	}
+
+	if service.Autoscaling != nil && service.Autoscaling.MaxSize != nil && *service.OnDemandBaseCapacity > *service.Autoscaling.MaxSize {
+		return fmt.Errorf("OnDemandBaseCapacity must be less than or equal to max_size")
+	}
+
+	if service.SpotAllocationStrategy != nil && !containsStr(SPOT_ALLOCATION_STRATEGIES, *service.SpotAllocationStrategy) {
+		return fmt.Errorf("SpotAllocationStrategy must be one of %v", SPOT_ALLOCATION_STRATEGIES)
+	}
+
+	return nil
+}
+
+// instancesDistribution launches the on demand base capacity then only spot instances
+func (service *Service) instancesDistribution() *autoscaling.InstancesDistribution {
+	strategy := service.SpotAllocationStrategy
+	if strategy == nil {
+		strategy = to.Strp("capacity-optimized")
+	}
+
+	return &autoscaling.InstancesDistribution{
+		OnDemandBaseCapacity:                service.OnDemandBaseCapacity,
+		OnDemandPercentageAboveBaseCapacity: to.Int64p(0),
+		SpotAllocationStrategy:              strategy,
+		SpotMaxPrice:                        service.SpotPrice,
+	}
+}
+
+// removeSpotInterruptions removes terminating instances reclaimed by a spot interruption
+// so they are not counted as deploy failures. The ASG replaces them within the timeout
+func (service *Service) removeSpotInterruptions(ec2c aws.EC2API, all aws.Instances) error {
+	if !service.spot() || len(all.TerminatingIDs()) == 0 {
+		return nil
+	}
+
+	interrupted, err := instance.SpotInterrupted(ec2c, all.TerminatingIDs())
+	if err != nil {
+		return err
+	}
+
+	all.Remove(interrupted)
+
+	for _, id := range interrupted {
+		if !containsStr(service.SpotInterruptedIDs, id) {
+			service.SpotInterruptedIDs = append(service.SpotInterruptedIDs, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Service_ValidateSpot(t *testing.T) {
+	service := &Service{}
+	assert.NoError(t, service.validateSpot())
+
+	service.SpotAllocationStrategy = to.Strp("lowest-price")
+	assert.Error(t, service.validateSpot())
+
+	service.OnDemandBaseCapacity = to.Int64p(1)
+	assert.NoError(t, service.validateSpot())
+
+	service.SpotAllocationStrategy = to.Strp("cheapest")
+	assert.Error(t, service.validateSpot())
+
+	service.SpotAllocationStrategy = nil
+	service.OnDemandBaseCapacity = to.Int64p(-1)
+	assert.Error(t, service.validateSpot())
+
+	service.OnDemandBaseCapacity = to.Int64p(5)
+	service.Autoscaling = &AutoScalingConfig{MaxSize: to.Int64p(4)}
+	assert.Error(t, service.validateSpot())
+}
+
+func Test_Service_CreateInput_Spot(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].OnDemandBaseCapacity = to.Int64p(1)
+	release.Services["web"].SpotPrice = to.Strp("0.10")
+	MockPrepareRelease(release)
+
+	service := release.Services["web"]
+	input := service.createInput()
+	assert.Nil(t, input.LaunchConfigurationName)
+	assert.Nil(t, input.MixedInstancesPolicy.LaunchTemplate.Overrides)
+
+	distribution := input.MixedInstancesPolicy.InstancesDistribution
+	assert.Equal(t, int64(1), *distribution.OnDemandBaseCapacity)
+	assert.Equal(t, int64(0), *distribution.OnDemandPercentageAboveBaseCapacity)
+	assert.Equal(t, "capacity-optimized", *distribution.SpotAllocationStrategy)
+	assert.Equal(t, "0.10", *distribution.SpotMaxPrice)
+
+	// The max price is not set on the launch template
+	assert.Nil(t, service.createLaunchConfigurationInput().SpotPrice)
+}
+
+func Test_Release_UpdateHealthy_SpotInterruption(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].OnDemandBaseCapacity = to.Int64p(0)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+	awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0].Instances = mocks.MakeMockASGInstances(1, 0, 1)
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// Terminating instance that was not a spot interruption halts
+	err := release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP)
+	assert.Error(t, err)
+	assert.IsType(t, &HaltError{}, err)
+
+	awsc.EC2.AddSpotInterruption("InstanceId2")
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.Equal(t, []string{"InstanceId2"}, release.Services["web"].SpotInterruptedIDs)
+	assert.Equal(t, 0, *release.Services["web"].HealthReport.Terminating)
+}
@@ -52,6 +52,10 @@ func NewStrategy(autoscaling *AutoScalingConfig, previousDesiredCapacity *int64)
 		s.maxTerminations = *autoscaling.MaxTerminations
 	}
 
+	if autoscaling.DesiredCapacity != nil {
+		s.desiredCapacity = autoscaling.DesiredCapacity
+	}
+
 	// Define the Strategy properties
 	switch s.name {
 	case "OneThenAllWithCanary":
@@ -85,6 +89,7 @@ type Strategy struct {
 	maxTerminations         int64
 	spread                  float64
 	previousDesiredCapacity *int64 // This can be nil
+	desiredCapacity         *int64 // This can be nil, it replaces previousDesiredCapacity
 
 	// For Percent and Increment types
 	// This is the number of steps used to rollout all instances
@@ -121,6 +126,11 @@ func (strategy *Strategy) DesiredCapacity() int64 {
 	minSize := strategy.minSize
 	maxSize := strategy.maxSize
 	previousDesiredCapacity := strategy.previousDesiredCapacity
+	// A configured desired capacity is used instead of the previous services capacity
+	if strategy.desiredCapacity != nil {
+		previousDesiredCapacity = strategy.desiredCapacity
+	}
+
 	pc := int64(-1)
 	if previousDesiredCapacity != nil {
 		pc = *previousDesiredCapacity
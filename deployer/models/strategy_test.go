@@ -33,6 +33,29 @@ func Test_Strategy_DesiredCapacity(t *testing.T) {
 	assert.EqualValues(t, 3, simpleStrategy(1, 3, to.Int64p(3), nil).DesiredCapacity())
 }
 
+func Test_Strategy_DesiredCapacity_Configured(t *testing.T) {
+	strategy := func(dc *int64, previous *int64) *Strategy {
+		return NewStrategy(
+			&AutoScalingConfig{
+				MinSize:         to.Int64p(2),
+				MaxSize:         to.Int64p(10),
+				DesiredCapacity: dc,
+				Spread:          to.Float64p(0),
+				Strategy:        to.Strp("AllAtOnce"),
+			},
+			previous,
+		)
+	}
+
+	assert.EqualValues(t, 4, strategy(to.Int64p(4), nil).DesiredCapacity())
+	assert.EqualValues(t, 4, strategy(to.Int64p(4), to.Int64p(8)).DesiredCapacity())
+	assert.EqualValues(t, 8, strategy(nil, to.Int64p(8)).DesiredCapacity())
+
+	// Healthy at the desired capacity not the max size
+	assert.EqualValues(t, 4, strategy(to.Int64p(4), nil).TargetHealthy())
+	assert.EqualValues(t, 4, strategy(to.Int64p(4), nil).TargetCapacity())
+}
+
 func Test_Strategy_TargetCapacity(t *testing.T) {
 	assert.EqualValues(t, 1, simpleStrategy(1, 1, to.Int64p(1), to.Float64p(1)).TargetCapacity())
 	assert.EqualValues(t, 3, simpleStrategy(1, 3, to.Int64p(2), to.Float64p(1)).TargetCapacity())
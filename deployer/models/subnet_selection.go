@@ -0,0 +1,128 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coinbase/odin/aws/subnet"
+	"github.com/coinbase/step/utils/is"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Subnet Selection
+//////////
+
+// SubnetSelection picks the subnets of a service out of the release subnets, e.g. one tier
+// of an account with public, private and secure subnets in each availability zone
+type SubnetSelection struct {
+	// Select the release subnets with all of these tags
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// Select these release subnets
+	SubnetIDs []*string `json:"subnet_ids,omitempty"`
+
+	// The selected subnets must span at least two availability zones unless this is true
+	AllowSingleAZ bool `json:"allow_single_az,omitempty"`
+}
+
+// validateSubnetSelection validates the SubnetSelection
+func (service *Service) validateSubnetSelection() error {
+	sel := service.SubnetSelection
+	if sel == nil {
+		return nil
+	}
+
+	if len(sel.Tags) == 0 && len(sel.SubnetIDs) == 0 {
+		return fmt.Errorf("SubnetSelection must define tags or subnet_ids")
+	}
+
+	if len(sel.Tags) > 0 && len(sel.SubnetIDs) > 0 {
+		return fmt.Errorf("SubnetSelection cannot define both tags and subnet_ids")
+	}
+
+	for key := range sel.Tags {
+		if key == "" {
+			return fmt.Errorf("SubnetSelection tags must not have an empty key")
+		}
+	}
+
+	if !is.UniqueStrp(sel.SubnetIDs) {
+		return fmt.Errorf("SubnetSelection subnet_ids must be unique")
+	}
+
+	for _, id := range sel.SubnetIDs {
+		if !strings.HasPrefix(*id, "subnet-") {
+			return fmt.Errorf("SubnetSelection subnet_ids %v is not a subnet ID", *id)
+		}
+	}
+
+	return nil
+}
+
+// selectSubnets returns the subnets picked by the SubnetSelection, all subnets without one
+func (service *Service) selectSubnets(subnets []*subnet.Subnet) []*subnet.Subnet {
+	sel := service.SubnetSelection
+	if sel == nil {
+		return subnets
+	}
+
+	selected := []*subnet.Subnet{}
+	for _, sn := range subnets {
+		if sn == nil {
+			continue
+		}
+
+		if len(sel.SubnetIDs) > 0 && !containsStrp(sel.SubnetIDs, to.Strs(sn.SubnetID)) {
+			continue
+		}
+
+		if !sn.HasTags(sel.Tags) {
+			continue
+		}
+
+		selected = append(selected, sn)
+	}
+
+	return selected
+}
+
+// validateSelectedSubnets errors if a selected subnet ID is not a release subnet,
+// nothing is selected, or the selected subnets are in one availability zone
+func (sr *ServiceResources) validateSelectedSubnets(service *Service) error {
+	sel := service.SubnetSelection
+	if sel == nil {
+		return nil
+	}
+
+	for _, id := range sel.SubnetIDs {
+		found := false
+		for _, sn := range sr.Subnets {
+			if sn != nil && to.Strs(sn.SubnetID) == *id {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("SubnetSelection subnet %v is not one of the release subnets", *id)
+		}
+	}
+
+	if len(sr.Subnets) == 0 {
+		return fmt.Errorf("SubnetSelection selects none of the release subnets")
+	}
+
+	azs := map[string]bool{}
+	for _, sn := range sr.Subnets {
+		if sn != nil && sn.AvailabilityZone != nil {
+			azs[*sn.AvailabilityZone] = true
+		}
+	}
+
+	if len(azs) < 2 && !sel.AllowSingleAZ {
+		return fmt.Errorf("SubnetSelection subnets span %v availability zone, at least 2 are required unless allow_single_az is true", len(azs))
+	}
+
+	return nil
+}
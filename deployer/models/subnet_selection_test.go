@@ -0,0 +1,114 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockSubnetTiersRelease has a public, private and secure subnet in each of two availability zones
+func mockSubnetTiersRelease(t *testing.T, sel *SubnetSelection) (*Release, *mocks.MockClients) {
+	release := MockRelease(t)
+	release.Subnets = []*string{}
+	release.Services["web"].SubnetSelection = sel
+
+	awsc := MockAwsClients(release)
+	awsc.EC2.DescribeSubnetsResp = nil
+	for _, tier := range []string{"public", "private", "secure"} {
+		for _, az := range []string{"a", "b"} {
+			name := tier + "-subnet-" + az
+			release.Subnets = append(release.Subnets, to.Strp(name))
+			awsc.EC2.AddSubnetWithTags(name, "subnet-"+tier+"-"+az, "us-east-1"+az, map[string]string{"Tier": tier})
+		}
+	}
+
+	MockPrepareRelease(release)
+	return release, awsc
+}
+
+func Test_Service_validateSubnetSelection(t *testing.T) {
+	service := &Service{}
+	assert.NoError(t, service.validateSubnetSelection())
+
+	service.SubnetSelection = &SubnetSelection{Tags: map[string]string{"Tier": "private"}}
+	assert.NoError(t, service.validateSubnetSelection())
+
+	service.SubnetSelection = &SubnetSelection{SubnetIDs: []*string{to.Strp("subnet-1"), to.Strp("subnet-2")}}
+	assert.NoError(t, service.validateSubnetSelection())
+
+	for _, sel := range []*SubnetSelection{
+		&SubnetSelection{},
+		&SubnetSelection{AllowSingleAZ: true},
+		&SubnetSelection{Tags: map[string]string{"Tier": "private"}, SubnetIDs: []*string{to.Strp("subnet-1")}},
+		&SubnetSelection{Tags: map[string]string{"": "private"}},
+		&SubnetSelection{SubnetIDs: []*string{to.Strp("subnet-1"), to.Strp("subnet-1")}},
+		&SubnetSelection{SubnetIDs: []*string{to.Strp("private-subnet")}},
+	} {
+		service.SubnetSelection = sel
+		assert.Error(t, service.validateSubnetSelection())
+	}
+}
+
+func Test_Release_SubnetSelection_Tags(t *testing.T) {
+	release, awsc := mockSubnetTiersRelease(t, &SubnetSelection{Tags: map[string]string{"Tier": "private"}})
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+
+	service := release.Services["web"]
+	assert.Equal(t, []string{"subnet-private-a", "subnet-private-b"}, to.StrSlice(service.Resources.Subnets))
+	assert.Equal(t, "subnet-private-a,subnet-private-b", *service.createInput().VPCZoneIdentifier)
+}
+
+func Test_Release_SubnetSelection_Tags_With_AvailabilityZones(t *testing.T) {
+	release, awsc := mockSubnetTiersRelease(t, &SubnetSelection{Tags: map[string]string{"Tier": "secure"}, AllowSingleAZ: true})
+	release.Services["web"].AvailabilityZones = []*string{to.Strp("us-east-1b")}
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+
+	assert.Equal(t, []string{"subnet-secure-b"}, to.StrSlice(release.Services["web"].Resources.Subnets))
+}
+
+func Test_Release_SubnetSelection_SubnetIDs(t *testing.T) {
+	release, awsc := mockSubnetTiersRelease(t, &SubnetSelection{
+		SubnetIDs: []*string{to.Strp("subnet-public-a"), to.Strp("subnet-public-b")},
+	})
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+
+	assert.Equal(t, []string{"subnet-public-a", "subnet-public-b"}, to.StrSlice(release.Services["web"].Resources.Subnets))
+}
+
+func Test_Release_SubnetSelection_Errors(t *testing.T) {
+	validate := func(sel *SubnetSelection) error {
+		release, awsc := mockSubnetTiersRelease(t, sel)
+		resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+		assert.NoError(t, err)
+		return release.ValidateResources(resources)
+	}
+
+	err := validate(&SubnetSelection{SubnetIDs: []*string{to.Strp("subnet-private-a"), to.Strp("subnet-other")}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "subnet-other is not one of the release subnets")
+
+	err = validate(&SubnetSelection{Tags: map[string]string{"Tier": "dmz"}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "selects none of the release subnets")
+
+	// One availability zone must be allowed explicitly
+	err = validate(&SubnetSelection{SubnetIDs: []*string{to.Strp("subnet-private-a")}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "at least 2 are required")
+
+	assert.NoError(t, validate(&SubnetSelection{SubnetIDs: []*string{to.Strp("subnet-private-a")}, AllowSingleAZ: true}))
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Suspend Processes
+//////////
+
+// defaultSuspendProcesses would terminate instances while the old and new ASGs are being counted
+var defaultSuspendProcesses = []string{"AZRebalance", "ReplaceUnhealthy"}
+
+// suspendableProcesses are the scaling processes a deploy can suspend,
+// Launch and Terminate are needed to scale the new and old ASGs
+var suspendableProcesses = []string{
+	"AddToLoadBalancer",
+	"AlarmNotification",
+	"AZRebalance",
+	"HealthCheck",
+	"InstanceRefresh",
+	"ReplaceUnhealthy",
+	"ScheduledActions",
+}
+
+// suspendProcesses returns the processes suspended during the deploy, defaults to AZRebalance and ReplaceUnhealthy
+func (service *Service) suspendProcesses() []*string {
+	if service.SuspendProcesses == nil {
+		processes := []*string{}
+		for _, process := range defaultSuspendProcesses {
+			processes = append(processes, to.Strp(process))
+		}
+		return processes
+	}
+
+	return service.SuspendProcesses
+}
+
+// validateSuspendProcesses validates SuspendProcesses
+func (service *Service) validateSuspendProcesses() error {
+	seen := map[string]bool{}
+	for _, process := range service.SuspendProcesses {
+		if process == nil {
+			return fmt.Errorf("SuspendProcesses must not contain null")
+		}
+
+		if !containsStr(suspendableProcesses, *process) {
+			return fmt.Errorf("SuspendProcesses %v must be one of %v", *process, suspendableProcesses)
+		}
+
+		if seen[*process] {
+			return fmt.Errorf("SuspendProcesses must be unique")
+		}
+		seen[*process] = true
+	}
+
+	return nil
+}
+
+// suspendDeployProcesses suspends the processes on the new ASG and on the previous ASG so it does not churn while being drained
+func (service *Service) suspendDeployProcesses(asgc aws.ASGAPI) error {
+	if err := asg.SuspendProcesses(asgc, service.CreatedASG, service.suspendProcesses()); err != nil {
+		return err
+	}
+
+	if service.Resources == nil || service.Resources.PrevASG == nil {
+		return nil
+	}
+
+	return asg.SuspendProcesses(asgc, service.Resources.PrevASG, service.suspendProcesses())
+}
+
+// ResumeProcesses resumes the processes suspended on the new ASGs during the deploy
+func (release *Release) ResumeProcesses(asgc aws.ASGAPI) error {
+	if release.InPlace || release.IsInstanceRefresh() {
+		// Nothing is suspended on an in place update or instance refresh
+		return nil
+	}
+
+	return release.forEachService(func(service *Service) error {
+		if service.CreatedASG == nil {
+			return nil
+		}
+
+		return asg.ResumeProcesses(asgc, service.CreatedASG, service.suspendProcesses())
+	})
+}
+
+// ResumePreviousProcesses resumes the processes suspended on the previous ASGs after a failed deploy
+func (release *Release) ResumePreviousProcesses(asgc aws.ASGAPI) error {
+	if release.InPlace || release.IsInstanceRefresh() {
+		return nil
+	}
+
+	return release.forEachService(func(service *Service) error {
+		if service.Resources == nil || service.Resources.PrevASG == nil {
+			return nil
+		}
+
+		return asg.ResumeProcesses(asgc, service.Resources.PrevASG, service.suspendProcesses())
+	})
+}
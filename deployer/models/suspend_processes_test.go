@@ -0,0 +1,84 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockSuspendProcessesRelease(t *testing.T, change func(*Release)) (*Release, *mocks.MockClients) {
+	release := MockRelease(t)
+	change(release)
+	MockPrepareRelease(release)
+
+	awsc := MockAwsClients(release)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+
+	return release, awsc
+}
+
+func Test_Service_validateSuspendProcesses(t *testing.T) {
+	service := &Service{}
+	assert.NoError(t, service.validateSuspendProcesses())
+	assert.Equal(t, []string{"AZRebalance", "ReplaceUnhealthy"}, to.StrSlice(service.suspendProcesses()))
+
+	service.SuspendProcesses = []*string{}
+	assert.NoError(t, service.validateSuspendProcesses())
+	assert.Equal(t, 0, len(service.suspendProcesses()))
+
+	service.SuspendProcesses = []*string{to.Strp("AZRebalance"), to.Strp("ScheduledActions")}
+	assert.NoError(t, service.validateSuspendProcesses())
+
+	service.SuspendProcesses = []*string{to.Strp("AZRebalance"), to.Strp("AZRebalance")}
+	assert.Error(t, service.validateSuspendProcesses())
+
+	// The deploy needs to launch and terminate instances
+	service.SuspendProcesses = []*string{to.Strp("Launch")}
+	assert.Error(t, service.validateSuspendProcesses())
+
+	service.SuspendProcesses = []*string{to.Strp("Terminate")}
+	assert.Error(t, service.validateSuspendProcesses())
+
+	service.SuspendProcesses = []*string{nil}
+	assert.Error(t, service.validateSuspendProcesses())
+}
+
+func Test_Release_SuspendProcesses_Success(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(*Release) {})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	created := *release.Services["web"].CreatedASG
+	assert.Equal(t, []string{"AZRebalance", "ReplaceUnhealthy"}, awsc.ASG.SuspendedProcesses[created])
+	assert.Equal(t, []string{"AZRebalance", "ReplaceUnhealthy"}, awsc.ASG.SuspendedProcesses["project-config-web-old-release"])
+
+	assert.NoError(t, release.ResumeProcesses(awsc.ASG))
+	assert.Nil(t, awsc.ASG.SuspendedProcesses[created])
+}
+
+func Test_Release_SuspendProcesses_Failure(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(r *Release) {
+		r.Services["web"].SuspendProcesses = []*string{to.Strp("AZRebalance")}
+	})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.Equal(t, []string{"AZRebalance"}, awsc.ASG.SuspendedProcesses["project-config-web-old-release"])
+
+	assert.NoError(t, release.ResumePreviousProcesses(awsc.ASG))
+	assert.Nil(t, awsc.ASG.SuspendedProcesses["project-config-web-old-release"])
+}
+
+func Test_Release_SuspendProcesses_None(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(r *Release) {
+		r.Services["web"].SuspendProcesses = []*string{}
+	})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.Equal(t, 0, len(awsc.ASG.SuspendedProcesses))
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coinbase/odin/aws/asg"
+)
+
+//////////
+// Tags
+//////////
+
+// maxASGTags is the AWS limit on tags per ASG
+const maxASGTags = 50
+
+// tags returns the release tags overridden by the services tags
+func (service *Service) tags() map[string]*string {
+	tags := map[string]*string{}
+	if service.release != nil {
+		for key, value := range service.release.Tags {
+			tags[key] = value
+		}
+	}
+
+	for key, value := range service.Tags {
+		tags[key] = value
+	}
+
+	return tags
+}
+
+// reservedTag is true for the tags odin manages, the tag of retained ASGs and the aws: prefix reserved by AWS
+func reservedTag(key string) bool {
+	return containsStr(odinTags, key) || key == asg.RetainedAtTag || strings.HasPrefix(key, "aws:")
+}
+
+func validateTags(tags map[string]*string) error {
+	keys := []string{}
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key == "" {
+			return fmt.Errorf("Tags keys must not be empty")
+		}
+
+		if reservedTag(key) {
+			return fmt.Errorf("Tags key %q is reserved", key)
+		}
+
+		if tags[key] == nil {
+			return fmt.Errorf("Tags %q value must be defined", key)
+		}
+	}
+
+	return nil
+}
+
+// ValidateTags rejects tags odin uses internally and more tags than an ASG can have
+func (release *Release) ValidateTags() error {
+	if err := validateTags(release.Tags); err != nil {
+		return err
+	}
+
+	for _, name := range sortedServiceNames(release) {
+		service := release.Services[name]
+		if err := validateTags(service.Tags); err != nil {
+			return fmt.Errorf("Service %v %v", name, err.Error())
+		}
+
+		// odin adds its own tags to every ASG, all of odinTags except the legacy ReleaseId
+		if total := len(service.tags()) + len(odinTags) - 1; total > maxASGTags {
+			return fmt.Errorf("Service %v has %v tags, an ASG can have at most %v", name, total, maxASGTags)
+		}
+	}
+
+	return nil
+}
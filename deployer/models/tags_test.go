@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateTags(t *testing.T) {
+	assert.NoError(t, validateTags(nil))
+	assert.NoError(t, validateTags(map[string]*string{"team": to.Strp("deploy")}))
+
+	assert.Error(t, validateTags(map[string]*string{"": to.Strp("deploy")}))
+	assert.Error(t, validateTags(map[string]*string{"team": nil}))
+
+	for _, key := range []string{"ProjectName", "ConfigName", "ServiceName", "ReleaseID", "ReleaseUUID", "Name", "aws:cloudformation:stack-id"} {
+		assert.Error(t, validateTags(map[string]*string{key: to.Strp("value")}), key)
+	}
+}
+
+func Test_Service_tags(t *testing.T) {
+	release := MockRelease(t)
+	release.Tags = map[string]*string{"team": to.Strp("deploy"), "custom": to.Strp("release")}
+	release.SetDefaults()
+
+	// Service tags override the release tags
+	tags := release.Services["web"].tags()
+	assert.Equal(t, "deploy", *tags["team"])
+	assert.Equal(t, "tag", *tags["custom"])
+}
+
+func Test_Release_ValidateTags(t *testing.T) {
+	release := MockRelease(t)
+	release.SetDefaults()
+	assert.NoError(t, release.ValidateTags())
+
+	release.Tags = map[string]*string{"ReleaseUUID": to.Strp("forged")}
+	assert.Error(t, release.ValidateTags())
+
+	release.Tags = nil
+	release.Services["web"].Tags["ReleaseID"] = to.Strp("forged")
+	assert.Error(t, release.ValidateTags())
+
+	release.Services["web"].Tags = map[string]*string{"RetainedAt": to.Strp("forged")}
+	assert.Error(t, release.ValidateTags())
+
+	// An ASG can have at most 50 tags including odins
+	release.Services["web"].Tags = map[string]*string{}
+	for i := 0; i < 44; i++ {
+		release.Services["web"].Tags[fmt.Sprintf("tag-%v", i)] = to.Strp("value")
+	}
+	assert.NoError(t, release.ValidateTags())
+
+	release.Tags = map[string]*string{"team": to.Strp("deploy")}
+	assert.Error(t, release.ValidateTags())
+}
+
+func Test_Release_ValidateResources_ReservedTags(t *testing.T) {
+	release := MockRelease(t)
+	release.Tags = map[string]*string{"ReleaseUUID": to.Strp("forged")}
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ReleaseUUID")
+}
+
+func Test_Release_CreateResources_PropagatesTags(t *testing.T) {
+	release := MockRelease(t)
+	release.Tags = map[string]*string{"team": to.Strp("deploy"), "custom": to.Strp("release")}
+	release.Services["web"].InstanceTypes = mockInstanceTypes("m5.large", "c5.large")
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, release.ValidateResources(resources))
+	release.UpdateWithResources(resources)
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// ASG tags propagate to the instances
+	tags := awsc.ASG.CreatedTags(*release.Services["web"].CreatedASG)
+	assert.Equal(t, "deploy", *tags["team"].Value)
+	assert.Equal(t, "tag", *tags["custom"].Value)
+	assert.Equal(t, *release.UUID, *tags["ReleaseUUID"].Value)
+	for key, tag := range tags {
+		assert.True(t, *tag.PropagateAtLaunch, key)
+	}
+
+	ltTags := map[string]string{}
+	for _, tag := range awsc.EC2.CreateLaunchTemplateInputs[0].TagSpecifications[0].Tags {
+		ltTags[*tag.Key] = *tag.Value
+	}
+	assert.Equal(t, "deploy", ltTags["team"])
+	assert.Equal(t, "tag", ltTags["custom"])
+	assert.Equal(t, *release.UUID, ltTags["ReleaseUUID"])
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/coinbase/odin/aws/alb"
+	"github.com/coinbase/step/utils/to"
+)
+
+// TargetGroupHealth is the health check a target group must have for the service to be deployed
+// e.g. to check one target group over HTTP and another over HTTPS during a TLS migration.
+// Target groups are shared with the previous release, so Odin validates them and never changes them
+type TargetGroupHealth struct {
+	Protocol           *string `json:"protocol,omitempty"` // HTTP | HTTPS | TCP
+	Port               *string `json:"port,omitempty"`     // Port number or "traffic-port"
+	Path               *string `json:"path,omitempty"`
+	Interval           *int64  `json:"interval,omitempty"` // Seconds between checks
+	Timeout            *int64  `json:"timeout,omitempty"`  // Seconds before a check fails
+	HealthyThreshold   *int64  `json:"healthy_threshold,omitempty"`
+	UnhealthyThreshold *int64  `json:"unhealthy_threshold,omitempty"`
+	Matcher            *string `json:"matcher,omitempty"` // HTTP codes e.g. "200" or "200-299" or "200,202"
+}
+
+// tcpHealthCheck is the health check protocol of Network Load Balancer target groups that only opens a connection
+const tcpHealthCheck = "TCP"
+
+var matcherRegex = regexp.MustCompile(`^[0-9]{3}(-[0-9]{3})?(,[0-9]{3}(-[0-9]{3})?)*$`)
+
+// ValidateAttributes validates attributes
+func (h *TargetGroupHealth) ValidateAttributes() error {
+	if len(h.Mismatches(&alb.TargetGroup{})) == 0 {
+		return fmt.Errorf("must define at least one health check value")
+	}
+
+	if h.Protocol != nil && *h.Protocol != "HTTP" && *h.Protocol != "HTTPS" && *h.Protocol != tcpHealthCheck {
+		return fmt.Errorf("protocol must be 'HTTP', 'HTTPS' or 'TCP'")
+	}
+
+	if h.isTCP() {
+		if h.Path != nil {
+			return fmt.Errorf("path cannot be defined with the 'TCP' protocol")
+		}
+
+		if h.Matcher != nil {
+			return fmt.Errorf("matcher cannot be defined with the 'TCP' protocol")
+		}
+	}
+
+	if h.Path != nil && !strings.HasPrefix(*h.Path, "/") {
+		return fmt.Errorf("path must start with '/'")
+	}
+
+	if h.Interval != nil && (*h.Interval < 5 || *h.Interval > 300) {
+		return fmt.Errorf("interval must be between 5 and 300")
+	}
+
+	if h.Timeout != nil && (*h.Timeout < 2 || *h.Timeout > 120) {
+		return fmt.Errorf("timeout must be between 2 and 120")
+	}
+
+	if h.Interval != nil && h.Timeout != nil && *h.Timeout >= *h.Interval {
+		return fmt.Errorf("timeout must be less than interval")
+	}
+
+	if h.HealthyThreshold != nil && (*h.HealthyThreshold < 2 || *h.HealthyThreshold > 10) {
+		return fmt.Errorf("healthy_threshold must be between 2 and 10")
+	}
+
+	if h.UnhealthyThreshold != nil && (*h.UnhealthyThreshold < 2 || *h.UnhealthyThreshold > 10) {
+		return fmt.Errorf("unhealthy_threshold must be between 2 and 10")
+	}
+
+	if h.Matcher != nil && !matcherRegex.MatchString(*h.Matcher) {
+		return fmt.Errorf("matcher must be HTTP codes e.g. '200', '200-299' or '200,202'")
+	}
+
+	return nil
+}
+
+// isTCP returns true if the override health checks over TCP
+func (h *TargetGroupHealth) isTCP() bool {
+	return h.Protocol != nil && *h.Protocol == tcpHealthCheck
+}
+
+// ValidateTargetGroup errors if the target group does not have the health check. TCP health checks are
+// only supported by Network Load Balancer target groups, which cannot have custom HTTP matchers
+func (h *TargetGroupHealth) ValidateTargetGroup(tg *alb.TargetGroup) error {
+	if !tg.IsNetwork() && h.isTCP() {
+		return fmt.Errorf("protocol 'TCP' requires a Network Load Balancer target group, it is %v", to.Strs(tg.Protocol))
+	}
+
+	if tg.IsNetwork() && h.Matcher != nil {
+		return fmt.Errorf("matcher cannot be defined for a Network Load Balancer target group")
+	}
+
+	if mismatches := h.Mismatches(tg); len(mismatches) > 0 {
+		return fmt.Errorf("health check does not match, change the target group outside of a deploy: %v", strings.Join(mismatches, ", "))
+	}
+
+	return nil
+}
+
+// Mismatches describes each health check value of the target group that is different from the health check
+func (h *TargetGroupHealth) Mismatches(tg *alb.TargetGroup) []string {
+	mismatches := []string{}
+
+	strs := []struct {
+		name      string
+		want, has *string
+	}{
+		{"protocol", h.Protocol, tg.HealthCheckProtocol},
+		{"port", h.Port, tg.HealthCheckPort},
+		{"path", h.Path, tg.HealthCheckPath},
+		{"matcher", h.Matcher, tg.Matcher},
+	}
+
+	for _, v := range strs {
+		if v.want != nil && *v.want != to.Strs(v.has) {
+			mismatches = append(mismatches, fmt.Sprintf("%v is %q expected %q", v.name, to.Strs(v.has), *v.want))
+		}
+	}
+
+	ints := []struct {
+		name      string
+		want, has *int64
+	}{
+		{"interval", h.Interval, tg.HealthCheckIntervalSeconds},
+		{"timeout", h.Timeout, tg.HealthCheckTimeoutSeconds},
+		{"healthy_threshold", h.HealthyThreshold, tg.HealthyThresholdCount},
+		{"unhealthy_threshold", h.UnhealthyThreshold, tg.UnhealthyThresholdCount},
+	}
+
+	for _, v := range ints {
+		if v.want != nil && (v.has == nil || *v.want != *v.has) {
+			has := "unset"
+			if v.has != nil {
+				has = fmt.Sprintf("%v", *v.has)
+			}
+			mismatches = append(mismatches, fmt.Sprintf("%v is %v expected %v", v.name, has, *v.want))
+		}
+	}
+
+	return mismatches
+}
+
+// Matches returns true if the target groups health check has the health check values
+func (h *TargetGroupHealth) Matches(tg *alb.TargetGroup) bool {
+	return len(h.Mismatches(tg)) == 0
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/coinbase/odin/aws/alb"
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TargetGroupHealth_ValidateAttributes(t *testing.T) {
+	assert.Error(t, (&TargetGroupHealth{}).ValidateAttributes())
+	assert.Error(t, (&TargetGroupHealth{Protocol: to.Strp("UDP")}).ValidateAttributes())
+	assert.Error(t, (&TargetGroupHealth{Protocol: to.Strp("HTTP"), Path: to.Strp("health")}).ValidateAttributes())
+
+	// TCP health checks only open a connection
+	assert.Error(t, (&TargetGroupHealth{Protocol: to.Strp("TCP"), Path: to.Strp("/health")}).ValidateAttributes())
+	assert.Error(t, (&TargetGroupHealth{Protocol: to.Strp("TCP"), Matcher: to.Strp("200")}).ValidateAttributes())
+	assert.NoError(t, (&TargetGroupHealth{Protocol: to.Strp("TCP"), Interval: to.Int64p(10)}).ValidateAttributes())
+
+	assert.NoError(t, (&TargetGroupHealth{Protocol: to.Strp("HTTP")}).ValidateAttributes())
+	assert.NoError(t, (&TargetGroupHealth{Protocol: to.Strp("HTTPS"), Path: to.Strp("/health")}).ValidateAttributes())
+
+	valid := &TargetGroupHealth{
+		Path:               to.Strp("/health"),
+		Interval:           to.Int64p(10),
+		Timeout:            to.Int64p(5),
+		HealthyThreshold:   to.Int64p(2),
+		UnhealthyThreshold: to.Int64p(3),
+		Matcher:            to.Strp("200-299,302"),
+	}
+	assert.NoError(t, valid.ValidateAttributes())
+
+	assert.Error(t, (&TargetGroupHealth{Interval: to.Int64p(1)}).ValidateAttributes())
+	assert.Error(t, (&TargetGroupHealth{Timeout: to.Int64p(121)}).ValidateAttributes())
+	assert.Error(t, (&TargetGroupHealth{Interval: to.Int64p(10), Timeout: to.Int64p(10)}).ValidateAttributes())
+	assert.Error(t, (&TargetGroupHealth{HealthyThreshold: to.Int64p(1)}).ValidateAttributes())
+	assert.Error(t, (&TargetGroupHealth{UnhealthyThreshold: to.Int64p(11)}).ValidateAttributes())
+	assert.Error(t, (&TargetGroupHealth{Matcher: to.Strp("ok")}).ValidateAttributes())
+}
+
+func Test_Service_TargetGroupHealth_Validate(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].TargetGroupHealth = map[string]*TargetGroupHealth{
+		"not-a-target-group": &TargetGroupHealth{Protocol: to.Strp("HTTP")},
+	}
+	MockPrepareRelease(r)
+	assert.Error(t, r.ValidateServices())
+
+	// Each override is validated on its own
+	r = MockRelease(t)
+	r.Services["web"].TargetGroupHealth = map[string]*TargetGroupHealth{
+		"web-elb-target": &TargetGroupHealth{Protocol: to.Strp("HTTP2")},
+	}
+	MockPrepareRelease(r)
+	err := r.ValidateServices()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "TargetGroupHealth(web-elb-target)")
+}
+
+func Test_Service_TargetGroupHealth_MixedProtocols(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].TargetGroups = []*string{to.Strp("web-elb-target"), to.Strp("web-tls-target")}
+	r.Services["web"].TargetGroupHealth = map[string]*TargetGroupHealth{
+		"web-elb-target": &TargetGroupHealth{Protocol: to.Strp("HTTP")},
+		"web-tls-target": &TargetGroupHealth{Protocol: to.Strp("HTTPS"), Path: to.Strp("/health")},
+	}
+	MockPrepareRelease(r)
+	assert.NoError(t, r.ValidateServices())
+
+	awsc := MockAwsClients(r)
+	awsc.ALB.AddTargetGroup(mocks.MockTargetGroup{
+		Name:        "web-tls-target",
+		ProjectName: *r.ProjectName,
+		ConfigName:  *r.ConfigName,
+		ServiceName: "web",
+	})
+
+	// The target group is not changed by the deploy, so it must already health check over HTTPS
+	resources, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	err = r.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `TargetGroupHealth(web-tls-target) health check does not match`)
+	assert.Contains(t, err.Error(), `protocol is "HTTP" expected "HTTPS"`)
+
+	tlsTarget := awsc.ALB.DescribeTargetGroupsResp["web-tls-target"].Resp.TargetGroups[0]
+	tlsTarget.HealthCheckProtocol = to.Strp("HTTPS")
+	tlsTarget.HealthCheckPath = to.Strp("/health")
+
+	resources, err = r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, r.ValidateResources(resources))
+	r.UpdateWithResources(resources)
+
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+
+	// If the target group is changed during the deploy CheckHealthy errors
+	tlsTarget.HealthCheckProtocol = to.Strp("HTTP")
+	err = r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "web-tls-target")
+}
+
+func mockHealthCheckRelease(t *testing.T) (*Release, *mocks.MockClients, *ReleaseResources) {
+	r := MockRelease(t)
+	r.Services["web"].TargetGroupHealth = map[string]*TargetGroupHealth{
+		"web-elb-target": &TargetGroupHealth{
+			Path:     to.Strp("/health"),
+			Interval: to.Int64p(10),
+			Matcher:  to.Strp("200"),
+		},
+	}
+	MockPrepareRelease(r)
+	assert.NoError(t, r.ValidateServices())
+
+	awsc := MockAwsClients(r)
+	resources, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	return r, awsc, resources
+}
+
+func Test_Service_TargetGroupHealth_Mismatch(t *testing.T) {
+	r, awsc, resources := mockHealthCheckRelease(t)
+
+	err := r.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "TargetGroupHealth(web-elb-target)")
+	assert.Contains(t, err.Error(), `path is "" expected "/health"`)
+	assert.Contains(t, err.Error(), "interval is unset expected 10")
+
+	tg := awsc.ALB.DescribeTargetGroupsResp["web-elb-target"].Resp.TargetGroups[0]
+	tg.HealthCheckPath = to.Strp("/health")
+	tg.HealthCheckIntervalSeconds = to.Int64p(30)
+	tg.Matcher = &elbv2.Matcher{HttpCode: to.Strp("200")}
+
+	resources, err = r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	err = r.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "path")
+	assert.Contains(t, err.Error(), "interval is 30 expected 10")
+
+	// Matching settings are fine
+	tg.HealthCheckIntervalSeconds = to.Int64p(10)
+	resources, err = r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, r.ValidateResources(resources))
+}
+
+func Test_Service_TargetGroupHealth_Mismatch_Without_Previous_Release(t *testing.T) {
+	r, _, resources := mockHealthCheckRelease(t)
+
+	// A first deploy does not change an existing target group either
+	resources.ServiceResources["web"].PrevASG = nil
+	err := r.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "TargetGroupHealth(web-elb-target)")
+}
+
+func Test_TargetGroupHealth_ValidateTargetGroup(t *testing.T) {
+	application := &alb.TargetGroup{Protocol: to.Strp("HTTP"), HealthCheckProtocol: to.Strp("HTTP"), Matcher: to.Strp("200")}
+	network := &alb.TargetGroup{Protocol: to.Strp("TLS"), HealthCheckProtocol: to.Strp("TCP")}
+
+	tcp := &TargetGroupHealth{Protocol: to.Strp("TCP")}
+	assert.Error(t, tcp.ValidateTargetGroup(application))
+	assert.NoError(t, tcp.ValidateTargetGroup(network))
+
+	matcher := &TargetGroupHealth{Protocol: to.Strp("HTTP"), Matcher: to.Strp("200")}
+	assert.NoError(t, matcher.ValidateTargetGroup(application))
+	assert.Error(t, matcher.ValidateTargetGroup(network))
+
+	// NLBs can also health check over HTTP
+	network.HealthCheckProtocol = to.Strp("HTTP")
+	network.HealthCheckPath = to.Strp("/health")
+	assert.NoError(t, (&TargetGroupHealth{Protocol: to.Strp("HTTP"), Path: to.Strp("/health")}).ValidateTargetGroup(network))
+}
+
+func Test_Service_TargetGroupHealth_NetworkLoadBalancer(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].TargetGroups = []*string{to.Strp("web-tcp-target")}
+	r.Services["web"].TargetGroupHealth = map[string]*TargetGroupHealth{
+		"web-tcp-target": &TargetGroupHealth{Protocol: to.Strp("HTTP"), Matcher: to.Strp("200-299")},
+	}
+	MockPrepareRelease(r)
+	assert.NoError(t, r.ValidateServices())
+
+	awsc := MockAwsClients(r)
+	awsc.ALB.AddTargetGroup(mocks.MockTargetGroup{
+		Name:        "web-tcp-target",
+		ProjectName: *r.ProjectName,
+		ConfigName:  *r.ConfigName,
+		ServiceName: "web",
+		Protocol:    "TCP",
+	})
+	awsc.ALB.AddTargetGroupLoadBalancer("web-tcp-target", 9000, "web-nlb-arn")
+
+	// NLB health checks cannot use custom HTTP matchers
+	resources, err := r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	err = r.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "TargetGroupHealth(web-tcp-target) matcher cannot be defined for a Network Load Balancer")
+
+	r.Services["web"].TargetGroupHealth["web-tcp-target"] = &TargetGroupHealth{Protocol: to.Strp("TCP")}
+	resources, err = r.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+	assert.NoError(t, r.ValidateResources(resources))
+	r.UpdateWithResources(resources)
+
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// Targets are initial until their first TCP checks pass
+	awsc.ALB.UnhealthyUntil = map[string]int{"web-tcp-target": 1}
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.False(t, *r.Healthy)
+
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.True(t, *r.Healthy)
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/resourcegroups"
+	"github.com/coinbase/odin/aws"
+)
+
+//////////
+// Tenancy
+//////////
+
+// PLACEMENT_TENANCIES are the supported values of a services placement_tenancy
+var PLACEMENT_TENANCIES = []string{"default", "dedicated", "host"}
+
+// hostTenancy returns true if the instances launch onto dedicated hosts
+func (service *Service) hostTenancy() bool {
+	return service.PlacementTenancy != nil && *service.PlacementTenancy == "host"
+}
+
+// validatePlacementTenancy validates the placement_tenancy and host_resource_group_arn
+func (service *Service) validatePlacementTenancy() error {
+	if service.PlacementTenancy != nil && !containsStr(PLACEMENT_TENANCIES, *service.PlacementTenancy) {
+		return fmt.Errorf("PlacementTenancy is %v but must be unset or in %v", *service.PlacementTenancy, PLACEMENT_TENANCIES)
+	}
+
+	if !service.hostTenancy() {
+		if service.HostResourceGroupArn != nil {
+			return fmt.Errorf("HostResourceGroupArn requires placement_tenancy host")
+		}
+		return nil
+	}
+
+	// Instances need a host to launch onto, odin does not allocate them
+	if service.HostResourceGroupArn == nil {
+		return fmt.Errorf("PlacementTenancy host requires a host_resource_group_arn")
+	}
+
+	if !capacityReservationGroupARN.MatchString(*service.HostResourceGroupArn) {
+		return fmt.Errorf("HostResourceGroupArn %v is not a resource group ARN", *service.HostResourceGroupArn)
+	}
+
+	return nil
+}
+
+// ValidateHostResourceGroups errors if the host resource group of a host tenancy service does not exist
+func (release *Release) ValidateHostResourceGroups(rgc aws.ResourceGroupsAPI) error {
+	for name, service := range release.Services {
+		if !service.hostTenancy() {
+			continue
+		}
+
+		_, err := rgc.GetGroup(&resourcegroups.GetGroupInput{Group: service.HostResourceGroupArn})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == resourcegroups.ErrCodeNotFoundException {
+			return fmt.Errorf("%v Service(%v) HostResourceGroupArn %v not found", release.ErrorPrefix(), name, *service.HostResourceGroupArn)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockHostResourceGroupArn = "arn:aws:resource-groups:us-east-1:000000:group/hosts"
+
+func Test_Service_validatePlacementTenancy(t *testing.T) {
+	service := &Service{}
+	assert.NoError(t, service.validatePlacementTenancy())
+
+	for _, tenancy := range []string{"default", "dedicated"} {
+		service.PlacementTenancy = to.Strp(tenancy)
+		assert.NoError(t, service.validatePlacementTenancy())
+	}
+
+	service.PlacementTenancy = to.Strp("shared")
+	assert.Error(t, service.validatePlacementTenancy())
+
+	// Host tenancy requires a host resource group to launch onto
+	service.PlacementTenancy = to.Strp("host")
+	err := service.validatePlacementTenancy()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "host_resource_group_arn")
+
+	service.HostResourceGroupArn = to.Strp("hosts")
+	assert.Error(t, service.validatePlacementTenancy())
+
+	service.HostResourceGroupArn = to.Strp(mockHostResourceGroupArn)
+	assert.NoError(t, service.validatePlacementTenancy())
+	assert.True(t, service.launchTemplate())
+
+	service.PlacementTenancy = to.Strp("dedicated")
+	assert.Error(t, service.validatePlacementTenancy())
+}
+
+func Test_Release_CreateResources_PlacementTenancy_Default(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(r *Release) {
+		r.Services["web"].CapacityReservation = to.Strp("open")
+	})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// The mock service is in a placement group
+	placement := awsc.EC2.LaunchTemplatePlacements[*release.Services["web"].ServiceID()]
+	assert.Nil(t, placement.Tenancy)
+	assert.Nil(t, placement.HostResourceGroupArn)
+}
+
+func Test_Release_CreateResources_PlacementTenancy_Dedicated(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(r *Release) {
+		r.Services["web"].CapacityReservation = to.Strp("open")
+		r.Services["web"].PlacementTenancy = to.Strp("dedicated")
+	})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	placement := awsc.EC2.LaunchTemplatePlacements[*release.Services["web"].ServiceID()]
+	assert.Equal(t, "dedicated", *placement.Tenancy)
+	assert.Nil(t, placement.HostResourceGroupArn)
+}
+
+func Test_Release_CreateResources_PlacementTenancy_Host(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(r *Release) {
+		r.Services["web"].PlacementTenancy = to.Strp("host")
+		r.Services["web"].HostResourceGroupArn = to.Strp(mockHostResourceGroupArn)
+	})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	// Launch configurations do not support host tenancy
+	assert.Equal(t, 0, len(awsc.ASG.CreateLaunchConfigurationInputs))
+
+	placement := awsc.EC2.LaunchTemplatePlacements[*release.Services["web"].ServiceID()]
+	assert.Equal(t, "host", *placement.Tenancy)
+	assert.Equal(t, mockHostResourceGroupArn, *placement.HostResourceGroupArn)
+}
+
+func Test_Release_ValidateHostResourceGroups(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	// Nothing to check without host tenancy
+	assert.NoError(t, release.ValidateHostResourceGroups(awsc.ResourceGroups))
+	assert.Equal(t, 0, len(awsc.ResourceGroups.GetGroupInputs))
+
+	release.Services["web"].PlacementTenancy = to.Strp("host")
+	release.Services["web"].HostResourceGroupArn = to.Strp(mockHostResourceGroupArn)
+
+	err := release.ValidateHostResourceGroups(awsc.ResourceGroups)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+
+	awsc.ResourceGroups.AddGroup(mockHostResourceGroupArn)
+	assert.NoError(t, release.ValidateHostResourceGroups(awsc.ResourceGroups))
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+)
+
+//////////
+// Termination Policies
+//////////
+
+// terminationPolicies are the AWS termination policies an ASG can use when scaling in
+var terminationPolicies = []string{
+	"Default",
+	"AllocationStrategy",
+	"ClosestToNextInstanceHour",
+	"NewestInstance",
+	"OldestInstance",
+	"OldestLaunchConfiguration",
+	"OldestLaunchTemplate",
+}
+
+// validateTerminationPolicies validates TerminationPolicies are known and match how the service launches instances
+func (service *Service) validateTerminationPolicies() error {
+	seen := map[string]bool{}
+	for _, policy := range service.TerminationPolicies {
+		if policy == nil {
+			return fmt.Errorf("TerminationPolicies must not contain null")
+		}
+
+		if !containsStr(terminationPolicies, *policy) {
+			return fmt.Errorf("TerminationPolicies %v must be one of %v", *policy, terminationPolicies)
+		}
+
+		if seen[*policy] {
+			return fmt.Errorf("TerminationPolicies must be unique")
+		}
+		seen[*policy] = true
+
+		switch *policy {
+		case "OldestLaunchConfiguration":
+			if service.launchTemplate() {
+				return fmt.Errorf("TerminationPolicies OldestLaunchConfiguration requires a launch configuration, use OldestLaunchTemplate")
+			}
+		case "OldestLaunchTemplate":
+			if !service.launchTemplate() {
+				return fmt.Errorf("TerminationPolicies OldestLaunchTemplate requires a launch template, use OldestLaunchConfiguration")
+			}
+		case "AllocationStrategy":
+			if !service.mixedInstances() {
+				return fmt.Errorf("TerminationPolicies AllocationStrategy requires instance_types")
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Service_validateTerminationPolicies(t *testing.T) {
+	service := &Service{}
+	assert.NoError(t, service.validateTerminationPolicies())
+
+	service.TerminationPolicies = []*string{to.Strp("OldestInstance"), to.Strp("Default")}
+	assert.NoError(t, service.validateTerminationPolicies())
+
+	service.TerminationPolicies = []*string{to.Strp("OldestLaunchConfiguration")}
+	assert.NoError(t, service.validateTerminationPolicies())
+
+	service.TerminationPolicies = []*string{to.Strp("Unknown")}
+	assert.Error(t, service.validateTerminationPolicies())
+
+	service.TerminationPolicies = []*string{to.Strp("NewestInstance"), to.Strp("NewestInstance")}
+	assert.Error(t, service.validateTerminationPolicies())
+
+	service.TerminationPolicies = []*string{nil}
+	assert.Error(t, service.validateTerminationPolicies())
+
+	// A launch configuration service has no launch template or allocation strategy
+	service.TerminationPolicies = []*string{to.Strp("OldestLaunchTemplate")}
+	assert.Error(t, service.validateTerminationPolicies())
+
+	service.TerminationPolicies = []*string{to.Strp("AllocationStrategy")}
+	assert.Error(t, service.validateTerminationPolicies())
+
+	service.InstanceTypes = mockInstanceTypes("m5.large", "c5.large")
+	service.TerminationPolicies = []*string{to.Strp("AllocationStrategy"), to.Strp("OldestLaunchTemplate")}
+	assert.NoError(t, service.validateTerminationPolicies())
+
+	service.TerminationPolicies = []*string{to.Strp("OldestLaunchConfiguration")}
+	assert.Error(t, service.validateTerminationPolicies())
+}
+
+func Test_Release_TerminationPolicies_CreateResources(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(r *Release) {
+		r.Services["web"].TerminationPolicies = []*string{to.Strp("OldestLaunchConfiguration"), to.Strp("OldestInstance")}
+	})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	created := *release.Services["web"].CreatedASG
+	assert.Equal(t, []string{"OldestLaunchConfiguration", "OldestInstance"}, awsc.ASG.TerminationPolicies[created])
+}
+
+func Test_Release_TerminationPolicies_Default(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(*Release) {})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	created := *release.Services["web"].CreatedASG
+	assert.Equal(t, []string{"ClosestToNextInstanceHour"}, awsc.ASG.TerminationPolicies[created])
+}
+
+func Test_Release_TerminationPolicies_ValidateResources(t *testing.T) {
+	release := MockRelease(t)
+	release.Services["web"].TerminationPolicies = []*string{to.Strp("FarthestFromHome")}
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	resources, err := release.FetchResources(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.IAM, awsc.SNS)
+	assert.NoError(t, err)
+
+	err = release.ValidateResources(resources)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "TerminationPolicies")
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+//////////
+// Timeout Classification
+//////////
+
+// Classifications of a release that timed out before it was healthy
+const (
+	TimeoutNoInstancesLaunched = "NoInstancesLaunched"
+	TimeoutInstancesUnhealthy  = "InstancesUnhealthy"
+	TimeoutPartiallyHealthy    = "PartiallyHealthy"
+)
+
+// healthCounts returns the healthy and launched instances of the services last health report
+func (service *Service) healthCounts() (healthy int, launched int) {
+	report := service.HealthReport
+	if report == nil {
+		return 0, 0
+	}
+
+	if report.Healthy != nil {
+		healthy = *report.Healthy
+	}
+
+	if report.Launching != nil {
+		launched = *report.Launching
+	}
+
+	return healthy, launched
+}
+
+// TimeoutClassification classifies why the release was not healthy from each services last health report,
+// no service launched an instance, instances launched but none were healthy, or some were healthy
+func (release *Release) TimeoutClassification() string {
+	healthy, launched := 0, 0
+	for _, service := range release.Services {
+		if service == nil {
+			continue
+		}
+
+		h, l := service.healthCounts()
+		healthy, launched = healthy+h, launched+l
+	}
+
+	switch {
+	case launched == 0:
+		return TimeoutNoInstancesLaunched
+	case healthy == 0:
+		return TimeoutInstancesUnhealthy
+	}
+
+	return TimeoutPartiallyHealthy
+}
+
+// timeoutCounts describes the healthy and launched instances of each service against its targets
+func (release *Release) timeoutCounts() string {
+	counts := []string{}
+	for _, name := range sortedServiceNames(release) {
+		service := release.Services[name]
+		if service == nil {
+			continue
+		}
+
+		var targetHealthy, targetLaunched int64
+		if report := service.HealthReport; report != nil {
+			if report.TargetHealthy != nil {
+				targetHealthy = *report.TargetHealthy
+			}
+			if report.TargetLaunched != nil {
+				targetLaunched = *report.TargetLaunched
+			}
+		}
+
+		healthy, launched := service.healthCounts()
+		counts = append(counts, fmt.Sprintf("%v %v/%v healthy %v/%v launched", name, healthy, targetHealthy, launched, targetLaunched))
+	}
+
+	return strings.Join(counts, ", ")
+}
+
+// ClassifyTimeout adds the TimeoutClassification and each services instance counts to a timeout error,
+// so it is clear if instances never launched or launched but were not healthy. Other errors are unchanged
+func (release *Release) ClassifyTimeout(err error) error {
+	timeout, ok := err.(TimeoutError)
+	if !ok {
+		return err
+	}
+
+	return TimeoutError{fmt.Sprintf("%v: %v (%v)", timeout.Cause, release.TimeoutClassification(), release.timeoutCounts())}
+}
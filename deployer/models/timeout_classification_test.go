@@ -0,0 +1,29 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_ClassifyTimeout(t *testing.T) {
+	release := mockParallelRelease(t, "api")
+	timeout := TimeoutError{"Timeout: HealthyTimeout 600s reached before healthy"}
+
+	// Other errors are unchanged
+	assert.EqualError(t, release.ClassifyTimeout(fmt.Errorf("Halt File Found")), "Halt File Found")
+	assert.NoError(t, release.ClassifyTimeout(nil))
+
+	// No health check has seen an instance
+	assert.Equal(t, TimeoutNoInstancesLaunched, release.TimeoutClassification())
+	assert.EqualError(t, release.ClassifyTimeout(timeout), "TimeoutError: Timeout: HealthyTimeout 600s reached before healthy: NoInstancesLaunched (api 0/0 healthy 0/0 launched, web 0/0 healthy 0/0 launched)")
+
+	release.Services["web"].HealthReport = &HealthReport{TargetHealthy: to.Int64p(2), TargetLaunched: to.Int64p(2), Healthy: to.Intp(0), Launching: to.Intp(2)}
+	assert.Equal(t, TimeoutInstancesUnhealthy, release.TimeoutClassification())
+
+	release.Services["api"].HealthReport = &HealthReport{TargetHealthy: to.Int64p(3), TargetLaunched: to.Int64p(3), Healthy: to.Intp(1), Launching: to.Intp(3)}
+	assert.Equal(t, TimeoutPartiallyHealthy, release.TimeoutClassification())
+	assert.EqualError(t, release.ClassifyTimeout(timeout), "TimeoutError: Timeout: HealthyTimeout 600s reached before healthy: PartiallyHealthy (api 1/3 healthy 3/3 launched, web 0/2 healthy 2/2 launched)")
+}
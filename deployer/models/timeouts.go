@@ -0,0 +1,128 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Phase Timeouts
+//////////
+
+// maxWaitForDeploy is the longest WaitForDeploy gives instances to boot before health checks
+const maxWaitForDeploy = 90
+
+// deployTimeout is the DeployTimeout, or what the HealthyTimeout leaves of the Timeout.
+// Without either phase both share the whole Timeout, as the Timeout still bounds the release
+func (release *Release) deployTimeout() int {
+	switch {
+	case release.DeployTimeout != nil:
+		return *release.DeployTimeout
+	case release.HealthyTimeout != nil:
+		return *release.Timeout - *release.HealthyTimeout
+	}
+
+	return *release.Timeout
+}
+
+// healthyTimeout is the HealthyTimeout, or what the DeployTimeout leaves of the Timeout
+func (release *Release) healthyTimeout() int {
+	switch {
+	case release.HealthyTimeout != nil:
+		return *release.HealthyTimeout
+	case release.DeployTimeout != nil:
+		return *release.Timeout - *release.DeployTimeout
+	}
+
+	return *release.Timeout
+}
+
+// waitForDeploy gives instances time to boot, but never longer than half the deploy phase
+func (release *Release) waitForDeploy() int {
+	wait := release.deployTimeout() / 2
+	if wait > maxWaitForDeploy {
+		wait = maxWaitForDeploy
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+
+	return wait
+}
+
+// ValidatePhaseTimeouts validates DeployTimeout and HealthyTimeout
+func (release *Release) ValidatePhaseTimeouts() error {
+	if release.DeployTimeout == nil && release.HealthyTimeout == nil {
+		return nil
+	}
+
+	deploy, healthy := release.deployTimeout(), release.healthyTimeout()
+
+	if deploy < 1 || healthy < 1 {
+		return fmt.Errorf("DeployTimeout %v and HealthyTimeout %v must be greater than 0", deploy, healthy)
+	}
+
+	if deploy+healthy > *release.Timeout {
+		return fmt.Errorf("DeployTimeout %v + HealthyTimeout %v is greater than Timeout %v", deploy, healthy, *release.Timeout)
+	}
+
+	return nil
+}
+
+// reachedCapacity returns true if every service has launched its target capacity
+func (release *Release) reachedCapacity() bool {
+	for _, service := range release.Services {
+		report := service.HealthReport
+		if report == nil || report.Launching == nil || report.TargetLaunched == nil {
+			return false
+		}
+
+		if int64(*report.Launching) < *report.TargetLaunched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// updateCapacityReachedAt records when the deploy phase ended and the healthy phase started
+func (release *Release) updateCapacityReachedAt() {
+	if release.CapacityReachedAt == nil && release.reachedCapacity() {
+		release.CapacityReachedAt = to.Timep(time.Now())
+	}
+}
+
+// TimeoutError is returned once the release or one of its phases times out
+type TimeoutError struct {
+	Cause string
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("TimeoutError: %v", e.Cause)
+}
+
+// PhaseTimedOut returns a TimeoutError if the release has not reached capacity within the DeployTimeout,
+// or has not been healthy within the HealthyTimeout after reaching capacity, both extended by any LaunchExtension
+func (release *Release) PhaseTimedOut() error {
+	if release.Timeout == nil || release.StartedAt == nil {
+		return nil
+	}
+
+	now := time.Now()
+
+	if release.CapacityReachedAt == nil {
+		if deploy := release.deployTimeout(); now.After(release.StartedAt.Add(time.Duration(deploy+release.launchExtension()) * time.Second)) {
+			return TimeoutError{fmt.Sprintf("Timeout: DeployTimeout %vs reached before desired capacity", deploy)}
+		}
+		return nil
+	}
+
+	if healthy := release.healthyTimeout(); now.After(release.CapacityReachedAt.Add(time.Duration(healthy+release.launchExtension()) * time.Second)) {
+		return TimeoutError{fmt.Sprintf("Timeout: HealthyTimeout %vs reached before healthy", healthy)}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_PhaseTimeouts_Defaults(t *testing.T) {
+	release := MockRelease(t)
+	release.Timeout = to.Intp(600)
+	release.SetDefaults()
+
+	// Both phases share the Timeout
+	assert.Equal(t, 600, release.deployTimeout())
+	assert.Equal(t, 600, release.healthyTimeout())
+	assert.Equal(t, 90, *release.WaitForDeploy)
+	assert.NoError(t, release.ValidatePhaseTimeouts())
+
+	// The unset phase has what is left of the Timeout
+	release.DeployTimeout = to.Intp(100)
+	release.SetDefaults()
+	assert.Equal(t, 500, release.healthyTimeout())
+	assert.Equal(t, 50, *release.WaitForDeploy)
+	assert.NoError(t, release.ValidatePhaseTimeouts())
+
+	release.DeployTimeout = nil
+	release.HealthyTimeout = to.Intp(450)
+	assert.Equal(t, 150, release.deployTimeout())
+	assert.NoError(t, release.ValidatePhaseTimeouts())
+}
+
+func Test_Release_ValidatePhaseTimeouts(t *testing.T) {
+	release := MockRelease(t)
+	release.Timeout = to.Intp(600)
+
+	release.DeployTimeout = to.Intp(600)
+	assert.Error(t, release.ValidatePhaseTimeouts())
+
+	release.DeployTimeout = to.Intp(400)
+	release.HealthyTimeout = to.Intp(400)
+	assert.Error(t, release.ValidatePhaseTimeouts())
+
+	release.DeployTimeout = to.Intp(0)
+	release.HealthyTimeout = to.Intp(400)
+	assert.Error(t, release.ValidatePhaseTimeouts())
+}
+
+func Test_Release_PhaseTimedOut(t *testing.T) {
+	release := MockRelease(t)
+	release.Timeout = to.Intp(600)
+	release.DeployTimeout = to.Intp(100)
+	release.StartedAt = to.Timep(time.Now())
+	MockPrepareRelease(release)
+
+	assert.NoError(t, release.PhaseTimedOut())
+
+	// Deploy phase ran out before reaching capacity
+	release.StartedAt = to.Timep(time.Now().Add(-200 * time.Second))
+	assert.Regexp(t, "DeployTimeout", release.PhaseTimedOut())
+
+	// The healthy phase starts at capacity
+	release.CapacityReachedAt = to.Timep(time.Now().Add(-100 * time.Second))
+	assert.NoError(t, release.PhaseTimedOut())
+
+	release.CapacityReachedAt = to.Timep(time.Now().Add(-600 * time.Second))
+	assert.Regexp(t, "HealthyTimeout", release.PhaseTimedOut())
+}
+
+func Test_Release_UpdateHealthy_CapacityReachedAt(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	release.Services["web"].CreatedASG = to.Strp("asg")
+	assert.NoError(t, release.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+	assert.NotNil(t, release.CapacityReachedAt)
+
+	release.WipeControlledValues()
+	assert.Nil(t, release.CapacityReachedAt)
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// User Data
+//////////
+
+// userDataPlaceholders are replaced per service by Service.UserData, the template keeps them as is
+var userDataPlaceholders = []string{
+	"RELEASE_ID",
+	"RELEASE_UUID",
+	"PROJECT_NAME",
+	"CONFIG_NAME",
+	"SERVICE_NAME",
+	"RELEASE_BUCKET",
+	"AWS_ACCOUNT_ID",
+	"AWS_REGION",
+	"SHARED_PROJECT_DIR",
+	"RELEASE_DIR",
+}
+
+// userDataVariables are the release variables a user data template can use, e.g. {{.ProjectName}}
+func (release *Release) userDataVariables() map[string]string {
+	return map[string]string{
+		"ProjectName":  to.Strs(release.ProjectName),
+		"ConfigName":   to.Strs(release.ConfigName),
+		"ReleaseID":    to.Strs(release.ReleaseID),
+		"AwsRegion":    to.Strs(release.AwsRegion),
+		"AwsAccountID": to.Strs(release.AwsAccountID),
+		// The UUID is generated by the deployer after the client computes the SHA,
+		// so it is left as a placeholder and replaced per service
+		"ReleaseUUID": "{{RELEASE_UUID}}",
+	}
+}
+
+// RenderUserData renders the user data as a Go template of the release variables if the release sets UserDataTemplate,
+// otherwise it is returned as is. UserDataSHA256 is the SHA of the rendered user data, an undefined variable is an error
+func (release *Release) RenderUserData(userdata *string) (*string, error) {
+	if !release.UserDataTemplate {
+		return userdata, nil
+	}
+
+	funcs := template.FuncMap{}
+	for _, name := range userDataPlaceholders {
+		placeholder := fmt.Sprintf("{{%v}}", name)
+		funcs[name] = func() string { return placeholder }
+	}
+
+	tmpl, err := template.New("userdata").Option("missingkey=error").Funcs(funcs).Parse(to.Strs(userdata))
+	if err != nil {
+		return nil, fmt.Errorf("UserData template error %v", err.Error())
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, release.userDataVariables()); err != nil {
+		return nil, fmt.Errorf("UserData template error %v", err.Error())
+	}
+
+	return to.Strp(rendered.String()), nil
+}
+
+// renderUserData replaces the releases user data with the rendered template
+func (release *Release) renderUserData() error {
+	rendered, err := release.RenderUserData(release.UserData())
+	if err != nil {
+		return err
+	}
+
+	release.SetUserData(rendered)
+	return nil
+}
+
+// maxUserDataSize is the AWS limit on the base64 encoded user data of an instance
+const maxUserDataSize = 16384
+
+// ValidateUserDataSize errors if a services base64 encoded user data is over the AWS limit.
+// It is run on the decrypted and rendered user data, with each services placeholders replaced, as Deploy sends it
+func (release *Release) ValidateUserDataSize() error {
+	for _, name := range sortedServiceNames(release) {
+		service := release.Services[name]
+		service.SetUserData(release.UserData())
+
+		size := base64.StdEncoding.EncodedLen(len(to.Strs(service.UserData())))
+		if size > maxUserDataSize {
+			return fmt.Errorf("UserData for service %v is %v bytes base64 encoded, more than the AWS limit of %v bytes", name, size, maxUserDataSize)
+		}
+	}
+
+	return nil
+}
+
+// userDataKMSPrefix marks user data as a base64 KMS ciphertext of the plaintext user data
+const userDataKMSPrefix = "kms:"
+
+// DecryptUserData returns the plaintext of "kms:<base64 ciphertext>" user data, other user data is returned as is
+func DecryptUserData(kmsc aws.KMSAPI, userdata *string) (*string, error) {
+	raw := strings.TrimSpace(to.Strs(userdata))
+	if !strings.HasPrefix(raw, userDataKMSPrefix) {
+		return userdata, nil
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(raw, userDataKMSPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("UserData KMS ciphertext is not base64 %v", err.Error())
+	}
+
+	out, err := kmsc.Decrypt(&kms.DecryptInput{CiphertextBlob: blob})
+	if err != nil {
+		return nil, fmt.Errorf("UserData KMS decrypt failed %v", err.Error())
+	}
+
+	return to.Strp(string(out.Plaintext)), nil
+}
+
+// decryptUserData replaces the releases user data with its plaintext
+func (release *Release) decryptUserData(kmsc aws.KMSAPI) error {
+	plaintext, err := DecryptUserData(kmsc, release.UserData())
+	if err != nil {
+		return err
+	}
+
+	release.SetUserData(plaintext)
+	return nil
+}
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Release_RenderUserData(t *testing.T) {
+	release := MockRelease(t)
+	release.UserDataTemplate = true
+	MockPrepareRelease(release)
+
+	rendered, err := release.RenderUserData(to.Strp("{{.ProjectName}}/{{.ConfigName}}/{{.ReleaseID}}/{{.AwsRegion}}/{{.AwsAccountID}}"))
+	assert.NoError(t, err)
+	assert.Equal(t, "project/config/1/us-east-1/000000", *rendered)
+
+	// The per service placeholders are kept for Service.UserData
+	rendered, err = release.RenderUserData(to.Strp("{{.ReleaseUUID}} {{RELEASE_ID}} {{SERVICE_NAME}}"))
+	assert.NoError(t, err)
+	assert.Equal(t, "{{RELEASE_UUID}} {{RELEASE_ID}} {{SERVICE_NAME}}", *rendered)
+
+	rendered, err = release.RenderUserData(to.Strp("#cloud_config"))
+	assert.NoError(t, err)
+	assert.Equal(t, "#cloud_config", *rendered)
+}
+
+func Test_Release_RenderUserData_Errors(t *testing.T) {
+	release := MockRelease(t)
+	release.UserDataTemplate = true
+	MockPrepareRelease(release)
+
+	_, err := release.RenderUserData(to.Strp("{{.Undefined}}"))
+	assert.Error(t, err)
+
+	_, err = release.RenderUserData(to.Strp("{{UNDEFINED}}"))
+	assert.Error(t, err)
+
+	_, err = release.RenderUserData(to.Strp("{{.ProjectName"))
+	assert.Error(t, err)
+}
+
+func Test_Release_ValidateUserDataSHA_Rendered(t *testing.T) {
+	release := MockRelease(t)
+	release.UserDataTemplate = true
+	release.SetUserData(to.Strp("#cloud_config {{.ProjectName}}"))
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	assert.NoError(t, release.ValidateUserDataSHA(awsc.S3, awsc.KMS))
+	assert.Equal(t, "#cloud_config project", *release.UserData())
+
+	// The SHA of the template is not the SHA of the rendered user data
+	release.UserDataSHA256 = to.Strp(to.SHA256Str(to.Strp("#cloud_config {{.ProjectName}}")))
+	assert.Error(t, release.ValidateUserDataSHA(awsc.S3, awsc.KMS))
+}
+
+func Test_Release_ValidateUserDataSHA_Undefined(t *testing.T) {
+	release := MockRelease(t)
+	release.UserDataTemplate = true
+	release.SetUserData(to.Strp("#cloud_config {{.Undefined}}"))
+	awsc := MockAwsClients(release)
+	release.ReleaseSHA256 = to.SHA256Struct(release)
+	MockPrepareRelease(release)
+
+	err := release.Validate(awsc.S3, awsc.KMS)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "UserData template error")
+}
+
+func Test_Release_ValidateUserDataSHA_Not_Template(t *testing.T) {
+	// Without user_data_template braces that are not Go templates, e.g. cloud-init jinja, are kept as is
+	userdata := "## template: jinja\n#cloud-config\nhostname: {{ ds.meta_data.local_hostname }} {{PROJECT_NAME}}"
+
+	release := MockRelease(t)
+	release.SetUserData(to.Strp(userdata))
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+	release.ReleaseSHA256 = to.SHA256Struct(release)
+
+	// The SHA is of the user data as uploaded
+	assert.Equal(t, to.SHA256Str(to.Strp(userdata)), *release.UserDataSHA256)
+	assert.NoError(t, release.Validate(awsc.S3, awsc.KMS))
+	assert.Equal(t, userdata, *release.UserData())
+
+	rendered, err := release.RenderUserData(to.Strp("{{.Undefined}}"))
+	assert.NoError(t, err)
+	assert.Equal(t, "{{.Undefined}}", *rendered)
+}
+
+func Test_Service_UserData_ReleaseUUID(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+
+	service := release.Services["web"]
+	service.SetUserData(to.Strp("{{RELEASE_UUID}}"))
+	assert.Equal(t, *release.UUID, *service.UserData())
+}
+
+func mockKMSUserData(awsc *mocks.MockClients, keyID string, plaintext string) string {
+	return "kms:" + base64.StdEncoding.EncodeToString(awsc.KMS.Ciphertext(keyID, plaintext))
+}
+
+func Test_Release_ValidateUserDataSHA_KMS(t *testing.T) {
+	release := MockRelease(t)
+	release.UserDataTemplate = true
+	release.SetUserData(to.Strp("#cloud_config {{.ProjectName}}"))
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+
+	awsc.KMS.AddKey("key-1")
+	awsc.S3.AddGetObject(*release.UserDataPath(), mockKMSUserData(awsc, "key-1", "#cloud_config {{.ProjectName}}"), nil)
+
+	// The SHA is of the rendered plaintext
+	assert.NoError(t, release.ValidateUserDataSHA(awsc.S3, awsc.KMS))
+	assert.Equal(t, "#cloud_config project", *release.UserData())
+	assert.Equal(t, 1, len(awsc.KMS.DecryptInputs))
+
+	// Plain user data is not decrypted
+	release.SetUserData(nil)
+	awsc.S3.AddGetObject(*release.UserDataPath(), "#cloud_config {{.ProjectName}}", nil)
+	assert.NoError(t, release.ValidateUserDataSHA(awsc.S3, awsc.KMS))
+	assert.Equal(t, 1, len(awsc.KMS.DecryptInputs))
+}
+
+func Test_Release_Validate_KMS_AccessDenied(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+	release.ReleaseSHA256 = to.SHA256Struct(release)
+
+	awsc.KMS.DenyKey("key-1")
+	awsc.S3.AddGetObject(*release.UserDataPath(), mockKMSUserData(awsc, "key-1", "#cloud_config"), nil)
+
+	err := release.Validate(awsc.S3, awsc.KMS)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "UserData KMS decrypt failed")
+	assert.Contains(t, err.Error(), "AccessDeniedException")
+}
+
+func Test_DecryptUserData_Errors(t *testing.T) {
+	awsc := mocks.MockAWS()
+
+	_, err := DecryptUserData(awsc.KMS, to.Strp("kms:not base64!"))
+	assert.Error(t, err)
+
+	// Unknown key
+	_, err = DecryptUserData(awsc.KMS, to.Strp(mockKMSUserData(awsc, "key-2", "#cloud_config")))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "UserData KMS decrypt failed")
+}
+
+func Test_Release_ValidateUserDataSize(t *testing.T) {
+	release := MockRelease(t)
+	MockPrepareRelease(release)
+
+	// 12288 bytes is exactly 16384 bytes base64 encoded
+	release.SetUserData(to.Strp(strings.Repeat("a", 12288)))
+	assert.NoError(t, release.ValidateUserDataSize())
+
+	release.SetUserData(to.Strp(strings.Repeat("a", 12289)))
+	err := release.ValidateUserDataSize()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "UserData for service web is 16388 bytes base64 encoded, more than the AWS limit of 16384 bytes")
+
+	// The services placeholders are replaced before the size is checked
+	release.SetUserData(to.Strp(strings.Repeat("a", 12280) + "{{SERVICE_NAME}}"))
+	assert.NoError(t, release.ValidateUserDataSize())
+}
+
+func Test_Release_Validate_UserDataSize_KMS(t *testing.T) {
+	release := MockRelease(t)
+	release.SetUserData(to.Strp(strings.Repeat("a", 13000)))
+	MockPrepareRelease(release)
+	awsc := MockAwsClients(release)
+	release.ReleaseSHA256 = to.SHA256Struct(release)
+
+	// The ciphertext is checked after decryption
+	awsc.KMS.AddKey("key-1")
+	awsc.S3.AddGetObject(*release.UserDataPath(), mockKMSUserData(awsc, "key-1", strings.Repeat("a", 13000)), nil)
+
+	err := release.Validate(awsc.S3, awsc.KMS)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "more than the AWS limit of 16384 bytes")
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/aws/asg"
+	"github.com/coinbase/step/utils/to"
+)
+
+//////////
+// Warm Pool
+//////////
+
+// warmPoolStates are the states warm pool instances wait in
+var warmPoolStates = []string{"Stopped", "Running"}
+
+// WarmPool keeps pre-initialized instances next to the ASG so it scales out faster
+type WarmPool struct {
+	MinSize   *int64  `json:"min_size,omitempty"`
+	PoolState *string `json:"pool_state,omitempty"` // Stopped(default) | Running
+}
+
+// poolState returns the warm pool state, default Stopped
+func (wp *WarmPool) poolState() *string {
+	if wp.PoolState == nil {
+		return to.Strp("Stopped")
+	}
+	return wp.PoolState
+}
+
+// validateWarmPool validates the WarmPool
+func (service *Service) validateWarmPool() error {
+	wp := service.WarmPool
+	if wp == nil {
+		return nil
+	}
+
+	if wp.MinSize != nil && *wp.MinSize < 0 {
+		return fmt.Errorf("WarmPool min_size must be positive")
+	}
+
+	if !containsStr(warmPoolStates, *wp.poolState()) {
+		return fmt.Errorf("WarmPool pool_state must be one of %v", warmPoolStates)
+	}
+
+	// AWS does not support warm pools on ASGs with a mixed instances policy
+	if service.mixedInstances() {
+		return fmt.Errorf("WarmPool cannot be used with instance_types or spot")
+	}
+
+	return nil
+}
+
+// createWarmPool creates the warm pool on the new ASG, it is deleted with the ASG
+func (service *Service) createWarmPool(asgc aws.ASGAPI) error {
+	if service.WarmPool == nil {
+		return nil
+	}
+
+	return asg.PutWarmPool(asgc, service.CreatedASG, service.WarmPool.MinSize, service.WarmPool.poolState())
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/coinbase/odin/aws/mocks"
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Service_validateWarmPool(t *testing.T) {
+	service := &Service{}
+	assert.NoError(t, service.validateWarmPool())
+
+	service.WarmPool = &WarmPool{}
+	assert.NoError(t, service.validateWarmPool())
+	assert.Equal(t, "Stopped", *service.WarmPool.poolState())
+
+	service.WarmPool = &WarmPool{MinSize: to.Int64p(2), PoolState: to.Strp("Running")}
+	assert.NoError(t, service.validateWarmPool())
+
+	service.WarmPool = &WarmPool{MinSize: to.Int64p(-1)}
+	assert.Error(t, service.validateWarmPool())
+
+	service.WarmPool = &WarmPool{PoolState: to.Strp("Hibernated")}
+	assert.Error(t, service.validateWarmPool())
+
+	// Warm pools do not support mixed instances
+	service.WarmPool = &WarmPool{}
+	service.InstanceTypes = mockInstanceTypes("c5.large", "c4.large")
+	assert.Error(t, service.validateWarmPool())
+}
+
+func Test_Release_WarmPool_CreateResources(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(r *Release) {
+		r.Services["web"].WarmPool = &WarmPool{MinSize: to.Int64p(2)}
+	})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+
+	assert.Equal(t, 1, len(awsc.ASG.PutWarmPoolInputs))
+	input := awsc.ASG.PutWarmPoolInputs[0]
+	assert.Equal(t, *release.Services["web"].CreatedASG, *input.AutoScalingGroupName)
+	assert.Equal(t, int64(2), *input.MinSize)
+	assert.Equal(t, "Stopped", *input.PoolState)
+}
+
+func Test_Release_WarmPool_None(t *testing.T) {
+	release, awsc := mockSuspendProcessesRelease(t, func(*Release) {})
+
+	assert.NoError(t, release.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.Equal(t, 0, len(awsc.ASG.PutWarmPoolInputs))
+}
+
+func Test_Release_UpdateHealthy_WarmPool(t *testing.T) {
+	r := MockRelease(t)
+	r.Services["web"].WarmPool = &WarmPool{MinSize: to.Int64p(2)}
+	MockPrepareRelease(r)
+
+	awsc := MockAwsClients(r)
+	group := awsc.ASG.DescribeAutoScalingGroupsPageResp[0].Resp.AutoScalingGroups[0]
+	group.Instances = append(mocks.MakeMockASGInstances(1, 0, 0), mocks.MakeMockASGWarmedInstances(2, "Stopped")...)
+
+	assert.NoError(t, r.CreateResources(awsc.ASG, awsc.EC2, awsc.CW, awsc.ALB))
+	assert.NoError(t, r.UpdateHealthy(awsc.ASG, awsc.EC2, awsc.ELB, awsc.ALB, awsc.CW, awsc.HTTP))
+
+	// The stopped warm pool instances are neither healthy nor launching
+	report := r.Services["web"].HealthReport
+	assert.Equal(t, 1, *report.Healthy)
+	assert.Equal(t, 1, *report.Launching)
+	assert.True(t, *r.Healthy)
+}
@@ -0,0 +1,73 @@
+package progress
+
+import (
+	"sync"
+)
+
+// Update is the health of a release at one poll of CheckHealthy
+type Update struct {
+	ProjectName string
+	ConfigName  string
+	ReleaseID   string
+
+	// Every service is healthy
+	Healthy bool
+
+	Services map[string]Service
+}
+
+// Service is the health of a services new ASG
+type Service struct {
+	Healthy     int   // Instances in service and healthy
+	Desired     int64 // Instances that must be healthy
+	Launching   int   // Instances launched
+	Terminating int   // Instances terminating
+}
+
+// Progress receives an Update each time CheckHealthy polls the release, e.g. to stream it to an operator
+// Implementations must be safe to call concurrently and should never block the deploy
+type Progress interface {
+	Report(Update)
+}
+
+// Nop ignores every update, it is the default
+type Nop struct{}
+
+// Report does nothing
+func (Nop) Report(Update) {}
+
+// Memory keeps every update in the order it was reported
+type Memory struct {
+	mu      sync.Mutex
+	updates []Update
+}
+
+// NewMemory returns an empty Memory
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Report records the update
+func (m *Memory) Report(u Update) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updates = append(m.updates, u)
+}
+
+// Updates returns the recorded updates
+func (m *Memory) Updates() []Update {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Update{}, m.updates...)
+}
+
+// Healthy returns the healthy count of the service at each update
+func (m *Memory) Healthy(service string) []int {
+	healthy := []int{}
+	for _, u := range m.Updates() {
+		if s, ok := u.Services[service]; ok {
+			healthy = append(healthy, s.Healthy)
+		}
+	}
+	return healthy
+}
@@ -0,0 +1,22 @@
+package progress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Memory(t *testing.T) {
+	m := NewMemory()
+	m.Report(Update{Services: map[string]Service{"web": Service{Healthy: 0, Desired: 2}}})
+	m.Report(Update{Services: map[string]Service{"web": Service{Healthy: 2, Desired: 2}}, Healthy: true})
+
+	assert.Equal(t, 2, len(m.Updates()))
+	assert.Equal(t, []int{0, 2}, m.Healthy("web"))
+	assert.Equal(t, []int{}, m.Healthy("worker"))
+	assert.True(t, m.Updates()[1].Healthy)
+
+	// Nop satisfies Progress
+	var _ Progress = Nop{}
+	var _ Progress = m
+}
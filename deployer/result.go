@@ -0,0 +1,24 @@
+package deployer
+
+import (
+	"github.com/coinbase/odin/aws"
+	"github.com/coinbase/odin/deployer/models"
+	"github.com/coinbase/step/aws/s3"
+)
+
+// FetchResult reads the DeployResult written when the release with the UUID succeeded
+func FetchResult(s3c aws.S3API, bucket *string, accountID *string, projectName *string, configName *string, releaseUUID *string) (*models.DeployResult, error) {
+	finder := &models.Release{}
+	finder.Bucket = bucket
+	finder.AwsAccountID = accountID
+	finder.ProjectName = projectName
+	finder.ConfigName = configName
+	finder.UUID = releaseUUID
+
+	var result models.DeployResult
+	if err := s3.GetStruct(s3c, bucket, finder.DeployResultPath(), &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -12,7 +12,7 @@ import (
 )
 
 func main() {
-	var arg, command string
+	var arg, arg2, arg3, arg4, command string
 	switch len(os.Args) {
 	case 1:
 		fmt.Println("Starting Lambda")
@@ -23,6 +23,21 @@ func main() {
 	case 3:
 		command = os.Args[1]
 		arg = os.Args[2]
+	case 4:
+		command = os.Args[1]
+		arg = os.Args[2]
+		arg2 = os.Args[3]
+	case 5:
+		command = os.Args[1]
+		arg = os.Args[2]
+		arg2 = os.Args[3]
+		arg3 = os.Args[4]
+	case 6:
+		command = os.Args[1]
+		arg = os.Args[2]
+		arg2 = os.Args[3]
+		arg3 = os.Args[4]
+		arg4 = os.Args[5]
 	default:
 		printUsage() // Print how to use and exit
 	}
@@ -35,6 +50,10 @@ func main() {
 
 	switch command {
 	case "json":
+		// arg "rollback" prints the RollbackStateMachine
+		if arg == "rollback" {
+			run.JSON(deployer.RollbackStateMachine())
+		}
 		run.JSON(deployer.StateMachine())
 	case "deploy":
 		// Send Configuration to the deployer
@@ -57,12 +76,48 @@ func main() {
 			fmt.Println(err.Error())
 			os.Exit(1)
 		}
+	case "rollback":
+		// Deploy the release replaced by the last successful release
+		// arg is the project name, arg2 is the config name
+		err := client.Rollback(stepFn, &arg, &arg2)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "plan":
+		// Print what deploying the release would create without changing anything
+		// arg is a filename
+		err := client.Plan(stepFn, &arg)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "prune":
+		// Delete the resources left by dead releases, arg3 "dry-run" only prints them
+		// arg is the project name, arg2 is the config name
+		err := client.Prune(stepFn, &arg, &arg2, arg3 == "dry-run")
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "abort":
+		// Clean up a release whose execution was stopped
+		// arg is the project name, arg2 is the config name, arg3 is the release id, arg4 is the release uuid
+		err := client.Abort(stepFn, &arg, &arg2, &arg3, &arg4)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
 	default:
 		printUsage() // Print how to use and exit
 	}
 }
 
 func printUsage() {
-	fmt.Println("Usage: odin <json|deploy|halt|fails> <release_file> (No args starts Lambda)")
+	fmt.Println("Usage: odin <json|deploy|plan|halt|fails> <release_file> (No args starts Lambda)")
+	fmt.Println("       odin json rollback")
+	fmt.Println("       odin rollback <project_name> <config_name>")
+	fmt.Println("       odin prune <project_name> <config_name> [dry-run]")
+	fmt.Println("       odin abort <project_name> <config_name> <release_id> <uuid>")
 	os.Exit(0)
 }